@@ -0,0 +1,71 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/rdfc"
+)
+
+var canonicalizeCommand = register(&command{
+	name:    "canonicalize",
+	summary: "print the canonical N-Quads of RDF files (RDFC-1.0)",
+	run:     runCanonicalize,
+})
+
+// the files are read as a single dataset
+func runCanonicalize(this *env, args []string) int {
+	flags := this.newFlagSet("canonicalize", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	hashName := flags.String("hash", "sha256", "hash function: sha256 or sha384")
+	maxWork := flags.Int("max-work", rdfc.DefaultMaxWork, "give up on datasets needing more N-degree hashes and permutations")
+	digest := flags.Bool("digest", false, "print the hash of the canonical N-Quads instead of the N-Quads")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	hashes := map[string]crypto.Hash{"sha256": crypto.SHA256, "sha384": crypto.SHA384}
+	hash, ok := hashes[*hashName]
+	if !ok {
+		fmt.Fprintf(this.stderr, "rdf canonicalize: unknown hash %q\n", *hashName)
+		return exitError
+	}
+
+	statements := []*model.Statement{}
+	for _, name := range inputNames(flags) {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for statement := range source.parser.Statements() {
+			statements = append(statements, statement)
+		}
+		if err := source.close(); err != nil {
+			this.report(name, err)
+			return exitCode(err)
+		}
+	}
+
+	canonical, err := rdfc.Canonicalize(statements, rdfc.Options{Hash: hash, MaxWork: *maxWork})
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf canonicalize:", err)
+		return exitError
+	}
+	if *digest {
+		h := hash.New()
+		io.WriteString(h, canonical)
+		fmt.Fprintln(this.stdout, hex.EncodeToString(h.Sum(nil)))
+		return exitOK
+	}
+	io.WriteString(this.stdout, canonical)
+	return exitOK
+}
@@ -0,0 +1,144 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var convertCommand = register(&command{
+	name:    "convert",
+	summary: "convert RDF files from one format to another",
+	run:     runConvert,
+})
+
+func runConvert(this *env, args []string) int {
+	flags := this.newFlagSet("convert", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	to := flags.String("to", "", "output format: "+formatNames()+" (default: guessed from -o, else ntriples)")
+	output := flags.String("o", "", "output file (default: standard output)")
+	canonical := flags.Bool("canonical", false, "write the literals in the canonical form of their datatype")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	target := format.NTriples
+	if *to != "" {
+		f, err := format.ByName(*to)
+		if err != nil {
+			fmt.Fprintln(this.stderr, "rdf convert:", err)
+			return exitError
+		}
+		target = f
+	} else if f, ok := format.ByExtension(*output); ok && *output != "" {
+		target = f
+	}
+
+	var out io.Writer = this.stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(this.stderr, "rdf convert:", err)
+			return exitError
+		}
+		defer file.Close()
+		out = file
+	}
+
+	// the line based formats are streamed, the others need every
+	// statement and prefix before writing
+	var w writer.Writer
+	if target.IsLineBased() {
+		w = writer.NewWriter(out, target, writer.Options{})
+	}
+	statements := []*model.Statement{}
+	namespaces := []model.Namespace{}
+
+	for _, name := range inputNames(flags) {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for statement := range source.parser.Statements() {
+			if *canonical {
+				statement = canonicalStatement(statement)
+			}
+			if w == nil {
+				statements = append(statements, statement)
+				continue
+			}
+			if err := w.Write(statement); err != nil {
+				this.report(name, err)
+				return exitError
+			}
+		}
+		if err := source.close(); err != nil {
+			if w != nil {
+				w.Close()
+			}
+			this.report(name, err)
+			return exitCode(err)
+		}
+		namespaces = mergeNamespaces(namespaces, source.parser.Namespaces())
+	}
+
+	if w == nil {
+		w = writer.NewWriter(out, target, writer.Options{Namespaces: namespaces})
+		for _, statement := range statements {
+			if err := w.Write(statement); err != nil {
+				fmt.Fprintln(this.stderr, "rdf convert:", err)
+				return exitError
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		fmt.Fprintln(this.stderr, "rdf convert:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// the statement with the canonical forms of its literals, in triple terms
+// too
+func canonicalStatement(statement *model.Statement) *model.Statement {
+	ret := *statement
+	ret.Subject, ret.Object = canonicalTerm(statement.Subject), canonicalTerm(statement.Object)
+	return &ret
+}
+
+func canonicalTerm(term model.RDFTerm) model.RDFTerm {
+	switch t := term.(type) {
+	case model.Literal:
+		return t.Canonical()
+	case model.TripleTerm:
+		t.Subject, t.Object = canonicalTerm(t.Subject), canonicalTerm(t.Object)
+		return t
+	}
+	return term
+}
+
+// adds the namespaces of next whose prefix is not yet used
+func mergeNamespaces(namespaces []model.Namespace, next []model.Namespace) []model.Namespace {
+	used := make(map[model.Prefix]struct{})
+	for _, namespace := range namespaces {
+		used[namespace.Prefix] = struct{}{}
+	}
+	for _, namespace := range next {
+		if _, ok := used[namespace.Prefix]; !ok {
+			namespaces = append(namespaces, namespace)
+			used[namespace.Prefix] = struct{}{}
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,54 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+var countCommand = register(&command{
+	name:    "count",
+	summary: "count the statements of RDF files",
+	run:     runCount,
+})
+
+func runCount(this *env, args []string) int {
+	flags := this.newFlagSet("count", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	names := inputNames(flags)
+	total := 0
+	for _, name := range names {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		count := 0
+		for range source.parser.Statements() {
+			count++
+		}
+		if err := source.close(); err != nil {
+			this.report(name, err)
+			return exitCode(err)
+		}
+		total += count
+		if len(names) > 1 {
+			fmt.Fprintf(this.stdout, "%d\t%s\n", count, displayName(name))
+		}
+	}
+	if len(names) > 1 {
+		fmt.Fprintf(this.stdout, "%d\ttotal\n", total)
+	} else {
+		fmt.Fprintln(this.stdout, total)
+	}
+	return exitOK
+}
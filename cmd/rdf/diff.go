@@ -0,0 +1,117 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var diffCommand = register(&command{
+	name:    "diff",
+	summary: "compare the statements of two RDF files",
+	run:     runDiff,
+})
+
+// the files are compared as graphs, or datasets: the order of statements
+// and the labels of blank nodes do not matter. Exits with 1 when they
+// differ.
+func runDiff(this *env, args []string) int {
+	flags := this.newFlagSet("diff", "old new")
+	in := &inputFlags{}
+	in.register(flags)
+	report := flags.String("report", "ntriples", "report format: ntriples, statements prefixed with - or +, or patch, an RDF Patch document")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	var write func(w io.Writer, removed []*model.Statement, added []*model.Statement) error
+	switch *report {
+	case "ntriples":
+		write = writeMarkedDiff
+	case "patch":
+		write = writePatch
+	default:
+		fmt.Fprintf(this.stderr, "rdf diff: unknown report format %q\n", *report)
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	graphs := [2][]*model.Statement{}
+	for i, name := range flags.Args() {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for statement := range source.parser.Statements() {
+			graphs[i] = append(graphs[i], statement)
+		}
+		if err := source.close(); err != nil {
+			this.report(name, err)
+			// a broken file cannot be compared
+			return exitError
+		}
+	}
+
+	removed, added := model.Diff(graphs[0], graphs[1])
+	if err := write(this.stdout, removed, added); err != nil {
+		fmt.Fprintln(this.stderr, "rdf diff:", err)
+		return exitError
+	}
+	if len(removed) > 0 || len(added) > 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// s p o [g] . in N-Quads syntax
+func statementLine(statement *model.Statement, labels *writer.BlankNodeLabels) string {
+	ret := writer.FormatTerm(statement.Subject, labels) + " " + writer.FormatTerm(statement.Predicate, labels) + " " + writer.FormatTerm(statement.Object, labels)
+	if statement.Context != nil {
+		ret += " " + writer.FormatTerm(statement.Context, labels)
+	}
+	return ret + " ."
+}
+
+// the removed statements prefixed with "- " then the added ones with "+ ",
+// a blank node matched in both files has the same label
+func writeMarkedDiff(w io.Writer, removed []*model.Statement, added []*model.Statement) error {
+	out := bufio.NewWriter(w)
+	labels := writer.NewBlankNodeLabels()
+	for _, statement := range removed {
+		out.WriteString("- " + statementLine(statement, labels) + "\n")
+	}
+	for _, statement := range added {
+		out.WriteString("+ " + statementLine(statement, labels) + "\n")
+	}
+	return out.Flush()
+}
+
+// a single transaction deleting then adding statements, nothing when there
+// is no change
+func writePatch(w io.Writer, removed []*model.Statement, added []*model.Statement) error {
+	if len(removed) == 0 && len(added) == 0 {
+		return nil
+	}
+	out := bufio.NewWriter(w)
+	labels := writer.NewBlankNodeLabels()
+	out.WriteString("TX .\n")
+	for _, statement := range removed {
+		out.WriteString("D " + statementLine(statement, labels) + "\n")
+	}
+	for _, statement := range added {
+		out.WriteString("A " + statementLine(statement, labels) + "\n")
+	}
+	out.WriteString("TC .\n")
+	return out.Flush()
+}
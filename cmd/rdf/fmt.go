@@ -0,0 +1,132 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var fmtCommand = register(&command{
+	name:    "fmt",
+	summary: "reformat Turtle and TriG files",
+	run:     runFmt,
+})
+
+// files are reprinted with their prefixes, subjects, predicates and objects
+// sorted, one blank line between subjects and the comments of a statement
+// above its subject
+func runFmt(this *env, args []string) int {
+	flags := this.newFlagSet("fmt", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	rewrite := flags.Bool("w", false, "write the result to the files instead of the standard output")
+	diff := flags.Bool("d", false, "print the changes as a unified diff instead of the result")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	ret := exitOK
+	for _, name := range inputNames(flags) {
+		if name == "-" && *rewrite {
+			fmt.Fprintln(this.stderr, "rdf fmt: cannot rewrite the standard input")
+			return exitError
+		}
+		if code := this.formatFile(name, in, *rewrite, *diff); code > ret {
+			ret = code
+		}
+	}
+	return ret
+}
+
+// files with syntax errors are left untouched
+func (this *env) formatFile(name string, in *inputFlags, rewrite bool, diff bool) int {
+	f, err := in.format(name)
+	if err == nil && f != format.Turtle && f != format.TriG {
+		err = fmt.Errorf("cannot format %s, only turtle and TriG", f)
+	}
+	if err != nil {
+		this.report(name, err)
+		return exitError
+	}
+	file, err := this.open(name)
+	if err != nil {
+		this.report(name, err)
+		return exitError
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		this.report(name, err)
+		return exitError
+	}
+	formatted, err := formatDocument(content, f, model.IRI(in.base))
+	if err != nil {
+		this.report(name, err)
+		return exitCode(err)
+	}
+
+	changed := !bytes.Equal(content, formatted)
+	if diff && changed {
+		io.WriteString(this.stdout, unifiedDiff(displayName(name)+".orig", displayName(name), string(content), string(formatted)))
+	}
+	if rewrite && changed {
+		info, err := os.Stat(name)
+		if err == nil {
+			err = os.WriteFile(name, formatted, info.Mode().Perm())
+		}
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+	}
+	if !diff && !rewrite {
+		this.stdout.Write(formatted)
+	}
+	return exitOK
+}
+
+// the canonical form of a turtle or TriG document, formatting it again
+// changes nothing
+func formatDocument(content []byte, f format.Format, base model.IRI) ([]byte, error) {
+	source := parser.Parse(bytes.NewReader(content), parser.Options{Format: f, Base: base, Comments: true})
+	statements := []*model.Statement{}
+	for statement := range source.Statements() {
+		statements = append(statements, statement)
+	}
+	if err := source.Err(); err != nil {
+		return nil, err
+	}
+
+	options := writer.Options{
+		Namespaces: source.Namespaces(),
+		Base:       source.Base(),
+		Sort:       true,
+		Comments:   make(map[writer.GraphSubject][]string),
+	}
+	for _, comment := range source.Comments() {
+		switch comment.Placement {
+		case parser.HeaderComment:
+			options.Header = append(options.Header, comment.Text)
+		case parser.FooterComment:
+			options.Footer = append(options.Footer, comment.Text)
+		default:
+			key := writer.GraphSubject{Graph: comment.Graph, Subject: comment.Subject}
+			options.Comments[key] = append(options.Comments[key], comment.Text)
+		}
+	}
+	out := &bytes.Buffer{}
+	if err := writer.WriteAll(writer.NewWriter(out, f, options), statements); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
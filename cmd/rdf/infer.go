@@ -0,0 +1,138 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/reason"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var inferCommand = register(&command{
+	name:    "infer",
+	summary: "print the statements entailed by RDF files under RDFS, OWL 2 RL or N3 rules",
+	run:     runInfer,
+})
+
+// the files are read as a single dataset and the inferred statements are
+// printed as soon as they are entailed, the inconsistencies at the end.
+// Rules with negations may invalidate inferences, theirs are printed at
+// the end too.
+func runInfer(this *env, args []string) int {
+	flags := this.newFlagSet("infer", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	to := flags.String("to", "nquads", "output format: "+formatNames())
+	graph := flags.String("graph", "", "the graph of the inferred statements (default: the default graph)")
+	full := flags.Bool("full", false, "apply every rule of RDFS entailment and print its axioms")
+	owl := flags.Bool("owl", false, "apply the rules of OWL 2 RL and report inconsistencies")
+	rulesFile := flags.String("rules", "", "apply the N3 rules of a file instead")
+	all := flags.Bool("all", false, "print the statements read too")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	target, err := format.ByName(*to)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf infer:", err)
+		return exitError
+	}
+
+	options := reason.Options{Full: *full}
+	if *graph != "" {
+		options.Graph = model.IRI(*graph)
+	}
+	reasoner := reason.NewRDFS(options)
+	if *owl {
+		reasoner = reason.NewOWL(options)
+	}
+	// the premises of the rules, read before inferring anything
+	var premises *store.Memory
+	if *rulesFile != "" {
+		if reasoner, err = this.readRules(*rulesFile, options); err != nil {
+			return exitCode(err)
+		}
+		premises = store.NewMemory()
+	}
+	w := writer.NewWriter(this.stdout, target, writer.Options{})
+	write := func(statements ...*model.Statement) bool {
+		for _, statement := range statements {
+			if err := w.Write(statement); err != nil {
+				fmt.Fprintln(this.stderr, "rdf infer:", err)
+				return false
+			}
+		}
+		return true
+	}
+	// the axioms come first
+	if premises == nil && !write(reasoner.Inferred()...) {
+		return exitError
+	}
+	for _, name := range inputNames(flags) {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for statement := range source.parser.Statements() {
+			if *all && !write(statement) {
+				return exitError
+			}
+			if premises != nil {
+				premises.Add(statement)
+			} else if !write(reasoner.Add(statement)...) {
+				return exitError
+			}
+		}
+		if err := source.close(); err != nil {
+			w.Close()
+			this.report(name, err)
+			return exitCode(err)
+		}
+	}
+	if premises != nil {
+		// memory stores do not fail
+		reasoner.Load(premises)
+		if !write(reasoner.Inferred()...) {
+			return exitError
+		}
+	}
+	if err := w.Close(); err != nil {
+		fmt.Fprintln(this.stderr, "rdf infer:", err)
+		return exitError
+	}
+	for _, inconsistency := range reasoner.Inconsistencies() {
+		fmt.Fprintln(this.stderr, "rdf infer:", inconsistency)
+	}
+	if len(reasoner.Inconsistencies()) > 0 {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// the reasoner of the rules of a file, errors are reported
+func (this *env) readRules(name string, options reason.Options) (*reason.Reasoner, error) {
+	file, err := this.open(name)
+	if err != nil {
+		this.report(name, err)
+		return nil, err
+	}
+	defer file.Close()
+	rules, err := parser.ParseRules(file, parser.Options{})
+	if err != nil {
+		this.report(name, err)
+		return nil, err
+	}
+	ret, err := reason.NewRules(options, rules)
+	if err != nil {
+		this.report(name, err)
+		return nil, err
+	}
+	return ret, nil
+}
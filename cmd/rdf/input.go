@@ -0,0 +1,134 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func (this *env) newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(this.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(this.stderr, "usage: rdf %s [flags] %s\n\nflags:\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parses the flags, the returned code is to be used when ok is false
+func parseFlags(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitError, false
+	}
+	return exitOK, true
+}
+
+func formatNames() string {
+	names := []string{}
+	for _, f := range format.All() {
+		names = append(names, f.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// the flags shared by the commands reading RDF files
+type inputFlags struct {
+	from string
+	base string
+}
+
+func (this *inputFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&this.from, "from", "", "input format: "+formatNames()+" (default: guessed from the file extension, else turtle)")
+	flags.StringVar(&this.base, "base", "", "base IRI used to resolve relative IRIs")
+}
+
+// the files to read, stdin when there are none
+func inputNames(flags *flag.FlagSet) []string {
+	if flags.NArg() == 0 {
+		return []string{"-"}
+	}
+	return flags.Args()
+}
+
+func (this *inputFlags) format(name string) (format.Format, error) {
+	if this.from != "" {
+		return format.ByName(this.from)
+	}
+	if f, ok := format.ByExtension(name); ok {
+		return f, nil
+	}
+	return format.Turtle, nil
+}
+
+func (this *env) open(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(this.stdin), nil
+	}
+	return os.Open(name)
+}
+
+// the name used in messages
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// an input being parsed
+type input struct {
+	name   string
+	file   io.ReadCloser
+	parser *parser.Parser
+}
+
+func (this *env) parse(name string, flags *inputFlags, options parser.Options) (*input, error) {
+	f, err := flags.format(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := this.open(name)
+	if err != nil {
+		return nil, err
+	}
+	options.Format = f
+	options.Base = model.IRI(flags.base)
+	return &input{name: name, file: file, parser: parser.Parse(file, options)}, nil
+}
+
+// to be called once the statements are read
+func (this *input) close() error {
+	this.file.Close()
+	return this.parser.Err()
+}
+
+// prints err about file name
+func (this *env) report(name string, err error) {
+	if syntaxError, ok := err.(*parser.SyntaxError); ok {
+		fmt.Fprintf(this.stderr, "%s:%d:%d: %s\n", displayName(name), syntaxError.Line, syntaxError.Col, syntaxError.Message)
+		return
+	}
+	fmt.Fprintf(this.stderr, "%s: %s\n", displayName(name), err)
+}
+
+// exit code for an error returned while reading
+func exitCode(err error) int {
+	if _, ok := err.(*parser.SyntaxError); ok {
+		return exitInvalid
+	}
+	return exitError
+}
@@ -0,0 +1,88 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+var loadCommand = register(&command{
+	name:    "load",
+	summary: "bulk load RDF files into a store directory",
+	run:     runLoad,
+})
+
+// each file is a document of its own, its blank nodes are not those of
+// the other files
+func runLoad(this *env, args []string) int {
+	flags := this.newFlagSet("load", "-db dir [file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	db := flags.String("db", "", "the store directory, created if needed")
+	workers := flags.Int("workers", 0, "the number of chunks parsed at once (default: the number of CPUs)")
+	quiet := flags.Bool("q", false, "do not report the progress on the standard error")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if *db == "" {
+		fmt.Fprintln(this.stderr, "rdf load: -db is required")
+		return exitError
+	}
+
+	target, err := store.Open(*db, store.DiskOptions{})
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf load:", err)
+		return exitError
+	}
+	total := int64(0)
+	for _, name := range inputNames(flags) {
+		f, err := in.format(name)
+		if err != nil {
+			this.report(name, err)
+			target.Close()
+			return exitError
+		}
+		file, err := this.open(name)
+		if err != nil {
+			this.report(name, err)
+			target.Close()
+			return exitError
+		}
+		options := store.LoadOptions{Format: f, Base: model.IRI(in.base), Workers: *workers}
+		if !*quiet {
+			options.Progress = func(progress store.LoadProgress) {
+				this.reportProgress(name, progress)
+			}
+		}
+		count, err := target.Load(file, options)
+		file.Close()
+		total += count
+		if err != nil {
+			this.report(name, err)
+			target.Close()
+			return exitCode(err)
+		}
+	}
+	size := target.Len()
+	if err := target.Close(); err != nil {
+		fmt.Fprintln(this.stderr, "rdf load:", err)
+		return exitError
+	}
+	fmt.Fprintf(this.stdout, "%d statements loaded, %d in the store\n", total, size)
+	return exitOK
+}
+
+// one line per file, rewritten until the file is indexed
+func (this *env) reportProgress(name string, progress store.LoadProgress) {
+	end := ""
+	if progress.Done {
+		end = "\n"
+	}
+	fmt.Fprintf(this.stderr, "\r%s: %d statements, %.1f MiB in %.1fs, %.0f statements/s%s",
+		displayName(name), progress.Statements, float64(progress.Bytes)/(1<<20), progress.Elapsed.Seconds(), progress.Rate(), end)
+}
@@ -0,0 +1,90 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// rdf is a command line tool to work with RDF files.
+//
+//	rdf <command> [flags] [file ...]
+//
+// Files are read from the standard input when none or "-" is given.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// exit codes
+const (
+	exitOK = 0
+	// the input has syntax errors, or the command found what it checks for
+	exitInvalid = 1
+	// bad usage or I/O error
+	exitError = 2
+)
+
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	summary string
+	run     func(this *env, args []string) int
+}
+
+var commands = map[string]*command{}
+
+func register(cmd *command) *command {
+	commands[cmd.name] = cmd
+	return cmd
+}
+
+func main() {
+	this := &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(this.main(os.Args[1:]))
+}
+
+func (this *env) main(args []string) int {
+	if len(args) == 0 {
+		this.usage()
+		return exitError
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" || args[0] == "-help" {
+		if len(args) > 1 {
+			if cmd, ok := commands[args[1]]; ok {
+				return cmd.run(this, []string{"-h"})
+			}
+		}
+		this.usage()
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(this.stderr, "rdf: unknown command %q\n", args[0])
+		this.usage()
+		return exitError
+	}
+	return cmd.run(this, args[1:])
+}
+
+func (this *env) usage() {
+	fmt.Fprintln(this.stderr, "usage: rdf <command> [flags] [file ...]")
+	fmt.Fprintln(this.stderr)
+	fmt.Fprintln(this.stderr, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(this.stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(this.stderr)
+	fmt.Fprintln(this.stderr, "run 'rdf help <command>' for the flags of a command")
+}
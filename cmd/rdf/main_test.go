@@ -0,0 +1,449 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runs the command line with stdin and returns the exit code, stdout and
+// stderr
+func runRdf(stdin string, args ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	this := &env{stdin: strings.NewReader(stdin), stdout: stdout, stderr: stderr}
+	code := this.main(args)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const sample = `@prefix ex: <http://ex.org/> .
+ex:s ex:p ex:o, "x" .
+`
+
+func TestConvert(t *testing.T) {
+	code, stdout, _ := runRdf(sample, "convert")
+	expected := "<http://ex.org/s> <http://ex.org/p> <http://ex.org/o> .\n<http://ex.org/s> <http://ex.org/p> \"x\" .\n"
+	if code != exitOK || stdout != expected {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	path := writeFile(t, "in.nt", expected)
+	code, stdout, _ = runRdf("", "convert", "-to", "turtle", path)
+	if code != exitOK || stdout != "<http://ex.org/s> <http://ex.org/p> <http://ex.org/o>, \"x\" .\n" {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, _ = runRdf("@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .\n<http://ex.org/s> <http://ex.org/p> 007, \"1\"^^xsd:boolean, \"x\"^^xsd:integer .\n", "convert", "-canonical")
+	if code != exitOK || stdout != `<http://ex.org/s> <http://ex.org/p> "7"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://ex.org/s> <http://ex.org/p> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<http://ex.org/s> <http://ex.org/p> "x"^^<http://www.w3.org/2001/XMLSchema#integer> .
+` {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := writeFile(t, "valid.ttl", sample)
+	invalid := writeFile(t, "invalid.ttl", "@prefix ex: <http://ex.org/> .\nex:s ex:p .\n")
+	if code, _, stderr := runRdf("", "validate", valid); code != exitOK || stderr != "" {
+		t.Errorf("exit %d, errors\n%s", code, stderr)
+	}
+	code, _, stderr := runRdf("", "validate", valid, invalid)
+	if code != exitInvalid || stderr != invalid+":2:11: unexpected '.'\n" {
+		t.Errorf("exit %d, errors\n%s", code, stderr)
+	}
+	if code, _, _ := runRdf("", "validate", "-from", "rdfxml"); code != exitError {
+		t.Errorf("unknown format: exit %d", code)
+	}
+	illTyped := writeFile(t, "ill-typed.ttl", "<http://ex.org/s> <http://ex.org/p> \"2023-02-29\"^^<http://www.w3.org/2001/XMLSchema#date> .\n")
+	if code, _, _ := runRdf("", "validate", illTyped); code != exitOK {
+		t.Errorf("ill-typed literal without -literals: exit %d", code)
+	}
+	code, _, stderr = runRdf("", "validate", "-literals", illTyped)
+	if code != exitInvalid || stderr != illTyped+":1:37: ill-typed literal: \"2023-02-29\" is not a valid xsd:date\n" {
+		t.Errorf("exit %d, errors\n%s", code, stderr)
+	}
+}
+
+func TestCountAndPrefixes(t *testing.T) {
+	if code, stdout, _ := runRdf(sample, "count"); code != exitOK || stdout != "2\n" {
+		t.Errorf("exit %d, output %q", code, stdout)
+	}
+	if code, stdout, _ := runRdf(sample, "prefixes"); code != exitOK || stdout != "@prefix ex: <http://ex.org/> .\n" {
+		t.Errorf("exit %d, output %q", code, stdout)
+	}
+	if code, _, _ := runRdf("", "unknown"); code != exitError {
+		t.Errorf("unknown command: exit %d", code)
+	}
+	// a directory opens but cannot be read
+	if code, _, stderr := runRdf("", "count", t.TempDir()); code != exitError || !strings.Contains(stderr, "is a directory") {
+		t.Errorf("directory: exit %d, errors %q", code, stderr)
+	}
+}
+
+func TestValidateReports(t *testing.T) {
+	invalid := writeFile(t, "invalid.ttl", "@prefix ex: <http://ex.org/> .\nex:s ex:p .\nex:s ex:p ex:o .\nex:s ex:p \"x .\n")
+	code, _, stderr := runRdf("", "validate", invalid)
+	expected := invalid + ":2:11: unexpected '.'\n" + invalid + ":4:11: unterminated string\n"
+	if code != exitInvalid || stderr != expected {
+		t.Errorf("exit %d, errors\n%s", code, stderr)
+	}
+
+	code, stdout, _ := runRdf("", "validate", "-report", "json", invalid)
+	report := jsonReport{}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	if code != exitInvalid || report.Valid || len(report.Files) != 1 || len(report.Files[0].Errors) != 2 || report.Files[0].Statements != 1 {
+		t.Errorf("exit %d, report\n%s", code, stdout)
+	}
+
+	valid := writeFile(t, "valid.ttl", sample)
+	code, stdout, _ = runRdf("", "validate", "-report", "junit", valid, invalid)
+	suites := junitTestSuites{}
+	if err := xml.Unmarshal([]byte(stdout), &suites); err != nil {
+		t.Fatal(err)
+	}
+	cases := suites.Suites[0].TestCases
+	if code != exitInvalid || suites.Tests != 2 || suites.Failures != 1 || cases[0].Failure != nil || cases[1].Failure == nil {
+		t.Errorf("exit %d, report\n%s", code, stdout)
+	}
+}
+
+func TestStats(t *testing.T) {
+	code, stdout, _ := runRdf(sample, "stats", "-report", "json")
+	result := jsonStats{}
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatal(err)
+	}
+	if code != exitOK || result.Statements != 2 || result.Objects != 2 || len(result.DatatypeCounts) != 1 {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, _ = runRdf(sample, "stats")
+	if code != exitOK || !strings.Contains(stdout, "\npredicates\n  2  ex:p\n") {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	doc := `# header
+@prefix ex: <http://ex.org/> .
+ex:z ex:q "b", "a" ; a ex:C . # about z
+# about a
+ex:a ex:p [ ex:r 1 ] .
+`
+	expected := `# header
+@prefix ex: <http://ex.org/> .
+
+# about a
+ex:a ex:p [ ex:r 1 ] .
+
+# about z
+ex:z a ex:C ;
+    ex:q "a", "b" .
+`
+	code, stdout, _ := runRdf(doc, "fmt")
+	if code != exitOK || stdout != expected {
+		t.Fatalf("exit %d, output\n%s", code, stdout)
+	}
+	if code, again, _ := runRdf(stdout, "fmt"); code != exitOK || again != stdout {
+		t.Errorf("formatting again changed the output\n%s", again)
+	}
+
+	path := writeFile(t, "doc.ttl", doc)
+	code, stdout, _ = runRdf("", "fmt", "-d", path)
+	if code != exitOK || !strings.HasPrefix(stdout, "--- "+path+".orig\n+++ "+path+"\n@@ -1,5 +1,9 @@\n # header\n") {
+		t.Errorf("exit %d, diff\n%s", code, stdout)
+	}
+	if code, stdout, _ := runRdf("", "fmt", "-w", path); code != exitOK || stdout != "" {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if content, _ := os.ReadFile(path); string(content) != expected {
+		t.Errorf("rewritten as\n%s", content)
+	}
+	if code, stdout, _ := runRdf("", "fmt", "-d", path); code != exitOK || stdout != "" {
+		t.Errorf("exit %d, diff of a formatted file\n%s", code, stdout)
+	}
+
+	invalid := writeFile(t, "invalid.ttl", "ex:s ex:p .\n")
+	if code, _, _ := runRdf("", "fmt", "-w", invalid); code != exitInvalid {
+		t.Errorf("syntax error: exit %d", code)
+	}
+	if content, _ := os.ReadFile(invalid); string(content) != "ex:s ex:p .\n" {
+		t.Errorf("invalid file rewritten as\n%s", content)
+	}
+	if code, _, _ := runRdf(sample, "fmt", "-from", "ntriples"); code != exitError {
+		t.Errorf("n-triples: exit %d", code)
+	}
+	if code, _, _ := runRdf(sample, "fmt", "-w"); code != exitError {
+		t.Errorf("rewriting stdin: exit %d", code)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := writeFile(t, "old.ttl", `@prefix ex: <http://ex.org/> .
+ex:s ex:p [ ex:a "1" ; ex:b "2" ], [ ex:c 3 ] .
+`)
+	same := writeFile(t, "same.nt", `_:y <http://ex.org/c> "3"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://ex.org/s> <http://ex.org/p> _:y, _:x .
+_:x <http://ex.org/a> "1" ; <http://ex.org/b> "2" .
+`)
+	changed := writeFile(t, "changed.ttl", `@prefix ex: <http://ex.org/> .
+ex:s ex:p [ ex:a "1" ; ex:b "3" ], [ ex:c 3 ] .
+ex:s ex:q ex:o .
+`)
+	if code, stdout, stderr := runRdf("", "diff", "-from", "turtle", old, same); code != exitOK || stdout != "" {
+		t.Errorf("exit %d, output\n%s%s", code, stdout, stderr)
+	}
+	code, stdout, _ := runRdf("", "diff", old, changed)
+	expected := `- _:b0 <http://ex.org/b> "2" .
++ <http://ex.org/s> <http://ex.org/q> <http://ex.org/o> .
++ _:b0 <http://ex.org/b> "3" .
+`
+	if code != exitInvalid || stdout != expected {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, _ = runRdf("", "diff", "-report", "patch", old, changed)
+	expected = `TX .
+D _:b0 <http://ex.org/b> "2" .
+A <http://ex.org/s> <http://ex.org/q> <http://ex.org/o> .
+A _:b0 <http://ex.org/b> "3" .
+TC .
+`
+	if code != exitInvalid || stdout != expected {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if code, _, _ := runRdf("", "diff", old); code != exitError {
+		t.Errorf("one file: exit %d", code)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	first := "_:a <http://ex.org/p> _:b .\n_:b <http://ex.org/q> \"x\" .\n"
+	second := "[ <http://ex.org/p> [ <http://ex.org/q> \"x\" ] ] .\n"
+	code, stdout, _ := runRdf(first, "canonicalize", "-from", "ntriples")
+	expected := "_:c14n0 <http://ex.org/q> \"x\" .\n_:c14n1 <http://ex.org/p> _:c14n0 .\n"
+	if code != exitOK || stdout != expected {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	_, digest, _ := runRdf(first, "canonicalize", "-from", "ntriples", "-digest", "-hash", "sha384")
+	_, other, _ := runRdf(second, "canonicalize", "-digest", "-hash", "sha384")
+	if len(digest) != 97 || digest != other {
+		t.Errorf("digests %q and %q", digest, other)
+	}
+	if code, _, _ := runRdf(first, "canonicalize", "-hash", "md5"); code != exitError {
+		t.Errorf("md5: exit %d", code)
+	}
+}
+
+func TestInfer(t *testing.T) {
+	input := `@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+<http://ex.org/Student> rdfs:subClassOf <http://ex.org/Person> .
+<http://ex.org/alice> a <http://ex.org/Student> .
+`
+	code, stdout, _ := runRdf(input, "infer", "-graph", "http://ex.org/inferred")
+	expected := "<http://ex.org/alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/Person> <http://ex.org/inferred> .\n"
+	if code != exitOK || stdout != expected {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, _ = runRdf(input, "infer", "-all", "-to", "ntriples")
+	if code != exitOK || strings.Count(stdout, "\n") != 3 {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if code, stdout, _ = runRdf(input, "infer", "-full"); code != exitOK || !strings.Contains(stdout, "<http://www.w3.org/2000/01/rdf-schema#Resource>") {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, stderr := runRdf(`@prefix owl: <http://www.w3.org/2002/07/owl#> .
+<http://ex.org/Cat> owl:disjointWith <http://ex.org/Dog> .
+<http://ex.org/felix> a <http://ex.org/Cat> ; owl:sameAs <http://ex.org/rex> .
+<http://ex.org/rex> a <http://ex.org/Dog> .
+`, "infer", "-owl", "-to", "ntriples")
+	if code != exitInvalid || !strings.Contains(stdout, "<http://ex.org/rex> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/Cat> .") || !strings.Contains(stderr, "cax-dw: ") {
+		t.Errorf("exit %d, output\n%s\nerrors\n%s", code, stdout, stderr)
+	}
+	if code, _, _ := runRdf("<a> <b>", "infer"); code != exitInvalid {
+		t.Errorf("syntax error: exit %d", code)
+	}
+
+	rules := filepath.Join(t.TempDir(), "rules.n3")
+	if err := os.WriteFile(rules, []byte(`@prefix : <http://ex.org/> .
+{ ?x a :Person . NOT { ?x :spouse ?y } } => { ?x a :Single } .
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ = runRdf(`<http://ex.org/a> a <http://ex.org/Person> .
+<http://ex.org/b> a <http://ex.org/Person> .
+<http://ex.org/a> <http://ex.org/spouse> <http://ex.org/b> .
+`, "infer", "-rules", rules)
+	if code != exitOK || stdout != "<http://ex.org/b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/Single> .\n" {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if code, _, stderr := runRdf("", "infer", "-rules", filepath.Join(t.TempDir(), "missing.n3")); code != exitError || !strings.Contains(stderr, "missing.n3") {
+		t.Errorf("missing rules: exit %d, errors %s", code, stderr)
+	}
+}
+
+func TestShacl(t *testing.T) {
+	shapes := filepath.Join(t.TempDir(), "shapes.ttl")
+	if err := os.WriteFile(shapes, []byte(`@prefix sh: <http://www.w3.org/ns/shacl#> .
+<http://ex.org/PersonShape> sh:targetClass <http://ex.org/Person> ;
+    sh:property [ sh:path <http://ex.org/name> ; sh:minCount 1 ] .
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ := runRdf(`<http://ex.org/a> a <http://ex.org/Person> ; <http://ex.org/name> "a" .
+<http://ex.org/b> a <http://ex.org/Person> .
+`, "shacl", "-shapes", shapes)
+	if code != exitInvalid || !strings.Contains(stdout, "sh:conforms false") || !strings.Contains(stdout, "sh:focusNode <http://ex.org/b>") || strings.Contains(stdout, "<http://ex.org/a>") {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	// the shapes are in the data
+	code, stdout, _ = runRdf(`@prefix sh: <http://www.w3.org/ns/shacl#> .
+[] sh:targetNode <http://ex.org/a> ; sh:class <http://ex.org/Person> .
+<http://ex.org/a> a <http://ex.org/Person> .
+`, "shacl", "-to", "ntriples")
+	if code != exitOK || !strings.Contains(stdout, `<http://www.w3.org/ns/shacl#conforms> "true"^^<http://www.w3.org/2001/XMLSchema#boolean>`) {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if code, _, stderr := runRdf(`@prefix sh: <http://www.w3.org/ns/shacl#> .
+[] sh:targetNode <http://ex.org/a> ; sh:minCount "one" .
+`, "shacl"); code != exitInvalid || !strings.Contains(stderr, "not an xsd:integer") {
+		t.Errorf("ill-formed shape: exit %d, errors %s", code, stderr)
+	}
+	code, stdout, _ = runRdf(`@prefix sh: <http://www.w3.org/ns/shacl#> .
+[] sh:targetClass <http://ex.org/Person> ; sh:rule [ sh:construct "CONSTRUCT { $this a <http://ex.org/Agent> } WHERE { }" ] .
+<http://ex.org/a> a <http://ex.org/Person> .
+`, "shacl", "-infer", "-to", "ntriples")
+	if code != exitOK || stdout != "<http://ex.org/a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/Agent> .\n" {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+}
+
+func TestShex(t *testing.T) {
+	schema := filepath.Join(t.TempDir(), "people.shex")
+	if err := os.WriteFile(schema, []byte(`PREFIX ex: <http://ex.org/>
+ex:Person { ex:name LITERAL ; ex:knows @ex:Person * }
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ := runRdf(`@prefix ex: <http://ex.org/> .
+ex:a ex:name "a" ; ex:knows ex:b .
+ex:b ex:name "b" .
+ex:c ex:knows ex:a .
+`, "shex", "-schema", schema, "-map", "ex:a@ex:Person, ex:c@ex:Person")
+	if code != exitInvalid || !strings.HasPrefix(stdout, "<http://ex.org/a>@<http://ex.org/Person> conformant\n") ||
+		!strings.Contains(stdout, "<http://ex.org/c>@<http://ex.org/Person> nonconformant: ") {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	code, stdout, _ = runRdf("", "shex", "-schema", schema, "-shexj")
+	if code != exitOK || !strings.Contains(stdout, `"id": "http://ex.org/Person"`) {
+		t.Errorf("exit %d, output\n%s", code, stdout)
+	}
+	if code, _, _ := runRdf("", "shex", "-schema", schema); code != exitError {
+		t.Errorf("no -map: exit %d", code)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, "db")
+	path := filepath.Join(dir, "sample.ttl")
+	if err := os.WriteFile(path, []byte(sample), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := runRdf("_:a <http://ex.org/p> _:a .\n", "load", "-db", db, "-", path)
+	if code != exitOK || stdout != "3 statements loaded, 3 in the store\n" || !strings.Contains(stderr, "statements/s") {
+		t.Errorf("exit %d, output %q, errors %q", code, stdout, stderr)
+	}
+	code, stdout, _ = runRdf("", "load", "-db", db, "-q", path)
+	if code != exitOK || stdout != "2 statements loaded, 3 in the store\n" {
+		t.Errorf("exit %d, output %q", code, stdout)
+	}
+	if code, _, _ := runRdf("<http://s> <http://p> .\n", "load", "-db", db, "-from", "ntriples"); code != exitInvalid {
+		t.Errorf("syntax error: exit %d", code)
+	}
+	if code, _, _ := runRdf("", "load", path); code != exitError {
+		t.Errorf("no -db: exit %d", code)
+	}
+}
+
+// a buffer written by a command running in the background
+type lockedBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (this *lockedBuffer) Write(p []byte) (int, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.buffer.Write(p)
+}
+
+func (this *lockedBuffer) String() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.buffer.String()
+}
+
+func TestServe(t *testing.T) {
+	path := writeFile(t, "sample.ttl", sample)
+	stderr := &lockedBuffer{}
+	this := &env{stdin: strings.NewReader(""), stdout: io.Discard, stderr: stderr}
+	exited := make(chan int)
+	go func() {
+		exited <- this.main([]string{"serve", "-addr", "127.0.0.1:0", path})
+	}()
+	var address string
+	for address == "" {
+		select {
+		case code := <-exited:
+			t.Fatalf("exit %d, errors %q", code, stderr)
+		case <-time.After(10 * time.Millisecond):
+			address = regexp.MustCompile(`http://\S+`).FindString(stderr.String())
+		}
+	}
+	response, err := http.Get(address + "?query=" + url.QueryEscape("SELECT (COUNT(*) AS ?n) { ?s ?p ?o }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if !strings.Contains(string(body), `"value": "2"`) {
+		t.Errorf("unexpected response %s", body)
+	}
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Skip("interrupts cannot be sent:", err)
+	}
+	if code := <-exited; code != exitOK {
+		t.Errorf("exit %d, errors %q", code, stderr)
+	}
+
+	if code, _, _ := runRdf("", "serve", "-addr", "127.0.0.1:-1"); code != exitError {
+		t.Errorf("invalid address: exit %d", code)
+	}
+	if code, _, _ := runRdf("", "serve", "-addr", "127.0.0.1:0", "-from", "ntriples", path); code != exitInvalid {
+		t.Errorf("syntax error: exit %d", code)
+	}
+}
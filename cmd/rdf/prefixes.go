@@ -0,0 +1,51 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var prefixesCommand = register(&command{
+	name:    "prefixes",
+	summary: "list the prefixes declared by RDF files",
+	run:     runPrefixes,
+})
+
+// prints the prefix declarations as turtle directives, prefixed by the file
+// name when there are several files
+func runPrefixes(this *env, args []string) int {
+	flags := this.newFlagSet("prefixes", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	names := inputNames(flags)
+	for _, name := range names {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for range source.parser.Statements() {
+		}
+		if err := source.close(); err != nil {
+			this.report(name, err)
+			return exitCode(err)
+		}
+		for _, namespace := range source.parser.Namespaces() {
+			if len(names) > 1 {
+				fmt.Fprintf(this.stdout, "%s:", displayName(name))
+			}
+			fmt.Fprintf(this.stdout, "@prefix %s: <%s> .\n", namespace.Prefix, writer.EscapeIRI(string(namespace.IRI)))
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,96 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nfreundl/rdf-tools/endpoint"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+var serveCommand = register(&command{
+	name:    "serve",
+	summary: "serve RDF files or a store directory over the SPARQL protocol",
+	run:     runServe,
+})
+
+// the files are added to the store, which is empty without them, and the
+// server runs until interrupted
+func runServe(this *env, args []string) int {
+	flags := this.newFlagSet("serve", "[-db dir] [file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	addr := flags.String("addr", "localhost:8080", "the address to listen on")
+	db := flags.String("db", "", "the store directory (default: the statements are kept in memory)")
+	readOnly := flags.Bool("read-only", false, "refuse updates")
+	load := flags.Bool("load", false, "let LOAD read local files")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+
+	var dataset store.Store = store.NewMemory()
+	if *db != "" {
+		disk, err := store.Open(*db, store.DiskOptions{})
+		if err != nil {
+			fmt.Fprintln(this.stderr, "rdf serve:", err)
+			return exitError
+		}
+		defer disk.Close()
+		dataset = disk
+	}
+	for _, name := range flags.Args() {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		err = store.AddAll(dataset, source.parser.Statements())
+		if closeErr := source.close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			this.report(name, err)
+			return exitCode(err)
+		}
+	}
+
+	options := endpoint.Options{ReadOnly: *readOnly}
+	if *load {
+		options.Load = sparql.LoadFile
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf serve:", err)
+		return exitError
+	}
+	server := &http.Server{Handler: endpoint.New(dataset, options)}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-interrupted:
+			server.Shutdown(context.Background())
+		case <-done:
+		}
+	}()
+	fmt.Fprintf(this.stderr, "rdf serve: %d statements, listening on http://%s%s\n", dataset.Len(), listener.Addr(), endpoint.SPARQLPath)
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		fmt.Fprintln(this.stderr, "rdf serve:", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,112 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/shacl"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var shaclCommand = register(&command{
+	name:    "shacl",
+	summary: "validate RDF files against SHACL shapes, or execute their rules",
+	run:     runShacl,
+})
+
+// the files are read as a single data graph, which holds the shapes too
+// when there is no shapes file. The report is printed, or the statements
+// the rules infer.
+func runShacl(this *env, args []string) int {
+	flags := this.newFlagSet("shacl", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	to := flags.String("to", "turtle", "output format: "+formatNames())
+	shapesFile := flags.String("shapes", "", "read the shapes from a file instead of the data")
+	infer := flags.Bool("infer", false, "print the statements inferred by the SHACL rules instead of validating")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	target, err := format.ByName(*to)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shacl:", err)
+		return exitError
+	}
+
+	namespaces := []model.Namespace{{Prefix: "sh", IRI: shacl.SH}}
+	data := store.NewMemory()
+	for _, name := range inputNames(flags) {
+		if namespaces, err = this.readGraph(name, in, data, namespaces); err != nil {
+			return exitCode(err)
+		}
+	}
+	shapesGraph := store.Store(data)
+	if *shapesFile != "" {
+		shapesGraph = store.NewMemory()
+		if namespaces, err = this.readGraph(*shapesFile, in, shapesGraph, namespaces); err != nil {
+			return exitCode(err)
+		}
+	}
+	shapes, err := shacl.NewShapes(shapesGraph)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shacl:", err)
+		return exitInvalid
+	}
+	w := writer.NewWriter(this.stdout, target, writer.Options{Namespaces: namespaces})
+	if *infer {
+		inferred, err := shapes.Infer(data)
+		if err != nil {
+			fmt.Fprintln(this.stderr, "rdf shacl:", err)
+			return exitInvalid
+		}
+		if err := writer.WriteAll(w, inferred); err != nil {
+			fmt.Fprintln(this.stderr, "rdf shacl:", err)
+			return exitError
+		}
+		return exitOK
+	}
+	// the queries of SHACL-SPARQL may fail
+	report, err := shapes.Validate(data)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shacl:", err)
+		return exitInvalid
+	}
+	statements, err := report.Statements()
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shacl:", err)
+		return exitError
+	}
+	if err := writer.WriteAll(w, statements); err != nil {
+		fmt.Fprintln(this.stderr, "rdf shacl:", err)
+		return exitError
+	}
+	if !report.Conforms {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// adds the statements of a file to a store, merging its namespaces. Errors
+// are reported.
+func (this *env) readGraph(name string, in *inputFlags, into store.Store, namespaces []model.Namespace) ([]model.Namespace, error) {
+	source, err := this.parse(name, in, parser.Options{})
+	if err != nil {
+		this.report(name, err)
+		return nil, err
+	}
+	for statement := range source.parser.Statements() {
+		into.Add(statement)
+	}
+	if err := source.close(); err != nil {
+		this.report(name, err)
+		return nil, err
+	}
+	return mergeNamespaces(namespaces, source.parser.Namespaces()), nil
+}
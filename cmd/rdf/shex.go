@@ -0,0 +1,102 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/shex"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+var shexCommand = register(&command{
+	name:    "shex",
+	summary: "validate the nodes of a shape map against a ShEx schema",
+	run:     runShex,
+})
+
+// the files are read as a single graph, and the conformance of each
+// association of the shape map is printed
+func runShex(this *env, args []string) int {
+	flags := this.newFlagSet("shex", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	schemaFile := flags.String("schema", "", "the schema, in ShExJ when its extension is .json or .shexj, in ShExC otherwise")
+	shapeMap := flags.String("map", "", "the fixed shape map, like '<node>@<shape>, ex:node@START'")
+	toShExJ := flags.Bool("shexj", false, "print the schema in ShExJ instead of validating")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if *schemaFile == "" {
+		fmt.Fprintln(this.stderr, "rdf shex: -schema is required")
+		return exitError
+	}
+	schema, err := this.readSchema(*schemaFile, model.IRI(in.base))
+	if err != nil {
+		this.report(*schemaFile, err)
+		return exitInvalid
+	}
+	if *toShExJ {
+		if err := schema.WriteShExJ(this.stdout); err != nil {
+			fmt.Fprintln(this.stderr, "rdf shex:", err)
+			return exitError
+		}
+		return exitOK
+	}
+	if *shapeMap == "" {
+		fmt.Fprintln(this.stderr, "rdf shex: -map is required")
+		return exitError
+	}
+
+	namespaces := schema.Namespaces
+	data := store.NewMemory()
+	for _, name := range inputNames(flags) {
+		if namespaces, err = this.readGraph(name, in, data, namespaces); err != nil {
+			return exitCode(err)
+		}
+	}
+	associations, err := shex.ParseShapeMap(*shapeMap, shex.Options{Namespaces: namespaces})
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shex: shape map:", err)
+		return exitError
+	}
+	results, err := schema.Validate(data, associations)
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf shex:", err)
+		return exitInvalid
+	}
+	code := exitOK
+	for _, result := range results {
+		if result.Conforms {
+			fmt.Fprintln(this.stdout, shex.FormatAssociation(result.Association), "conformant")
+			continue
+		}
+		fmt.Fprintln(this.stdout, shex.FormatAssociation(result.Association), "nonconformant:", result.Reason)
+		code = exitInvalid
+	}
+	return code
+}
+
+// relative IRIs of ShExC are resolved against base
+func (this *env) readSchema(name string, base model.IRI) (*shex.Schema, error) {
+	file, err := this.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".shexj":
+		return shex.ParseShExJ(file)
+	}
+	text, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return shex.ParseShExC(string(text), shex.Options{Base: base})
+}
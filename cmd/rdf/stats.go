@@ -0,0 +1,193 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/stats"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+var statsCommand = register(&command{
+	name:    "stats",
+	summary: "profile the content of RDF files",
+	run:     runStats,
+})
+
+// the statistics are computed over all the files together
+func runStats(this *env, args []string) int {
+	flags := this.newFlagSet("stats", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	report := flags.String("report", "table", "report format: table or json")
+	top := flags.Int("top", 0, "only list the n most frequent predicates, classes, datatypes and languages (0 for all)")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	if *report != "table" && *report != "json" {
+		fmt.Fprintf(this.stderr, "rdf stats: unknown report format %q\n", *report)
+		return exitError
+	}
+
+	collector := stats.NewCollector()
+	namespaces := []model.Namespace{}
+	for _, name := range inputNames(flags) {
+		source, err := this.parse(name, in, parser.Options{})
+		if err != nil {
+			this.report(name, err)
+			return exitError
+		}
+		for statement := range source.parser.Statements() {
+			collector.Add(statement)
+		}
+		if err := source.close(); err != nil {
+			this.report(name, err)
+			return exitCode(err)
+		}
+		namespaces = mergeNamespaces(namespaces, source.parser.Namespaces())
+	}
+
+	result := collector.Stats()
+	if *top > 0 {
+		result.PredicateCounts = truncate(result.PredicateCounts, *top)
+		result.ClassCounts = truncate(result.ClassCounts, *top)
+		result.DatatypeCounts = truncate(result.DatatypeCounts, *top)
+		result.LanguageCounts = truncate(result.LanguageCounts, *top)
+	}
+	var err error
+	if *report == "json" {
+		err = writeJSONStats(this.stdout, result)
+	} else {
+		err = writeStatsTable(this.stdout, result, namespaces)
+	}
+	if err != nil {
+		fmt.Fprintln(this.stderr, "rdf stats:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func truncate(counts []stats.Count, n int) []stats.Count {
+	if len(counts) > n {
+		return counts[:n]
+	}
+	return counts
+}
+
+// IRIs are abbreviated with the prefixes of the files
+func writeStatsTable(w io.Writer, result *stats.Stats, namespaces []model.Namespace) error {
+	lines := []struct {
+		label string
+		value int
+	}{
+		{"statements", result.Statements},
+		{"subjects", result.Subjects},
+		{"predicates", result.Predicates},
+		{"objects", result.Objects},
+		{"literals", result.Literals},
+		{"graphs", result.Graphs},
+		{"nodes", result.Nodes},
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "%-12s %10d\n", line.label, line.value)
+	}
+	fmt.Fprintf(w, "%-12s %10d  %.1f%%\n", "blank nodes", result.BlankNodes, 100*result.BlankNodeRatio())
+
+	sections := []struct {
+		title  string
+		counts []stats.Count
+	}{
+		{"predicates", result.PredicateCounts},
+		{"classes", result.ClassCounts},
+		{"datatypes", result.DatatypeCounts},
+		{"languages", result.LanguageCounts},
+	}
+	for _, section := range sections {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", section.title)
+		// the counts are sorted, the first one is the widest
+		width := len(strconv.Itoa(section.counts[0].Count))
+		for _, count := range section.counts {
+			fmt.Fprintf(w, "  %*d  %s\n", width, count.Count, countLabel(count.Term, namespaces))
+		}
+	}
+	return nil
+}
+
+func countLabel(term model.RDFTerm, namespaces []model.Namespace) string {
+	switch t := term.(type) {
+	case model.IRI:
+		return writer.AbbreviateIRI(t, namespaces)
+	case string:
+		return t
+	}
+	return writer.FormatTerm(term, writer.NewBlankNodeLabels())
+}
+
+type jsonCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type jsonStats struct {
+	Statements      int         `json:"statements"`
+	Subjects        int         `json:"subjects"`
+	Predicates      int         `json:"predicates"`
+	Objects         int         `json:"objects"`
+	Literals        int         `json:"literals"`
+	Graphs          int         `json:"graphs"`
+	Nodes           int         `json:"nodes"`
+	BlankNodes      int         `json:"blankNodes"`
+	BlankNodeRatio  float64     `json:"blankNodeRatio"`
+	PredicateCounts []jsonCount `json:"predicateCounts"`
+	ClassCounts     []jsonCount `json:"classCounts"`
+	DatatypeCounts  []jsonCount `json:"datatypeCounts"`
+	LanguageCounts  []jsonCount `json:"languageCounts"`
+}
+
+func jsonCounts(counts []stats.Count) []jsonCount {
+	ret := make([]jsonCount, 0, len(counts))
+	for _, count := range counts {
+		label := ""
+		switch t := count.Term.(type) {
+		case model.IRI:
+			label = string(t)
+		case string:
+			label = t
+		default:
+			label = writer.FormatTerm(t, writer.NewBlankNodeLabels())
+		}
+		ret = append(ret, jsonCount{Term: label, Count: count.Count})
+	}
+	return ret
+}
+
+func writeJSONStats(w io.Writer, result *stats.Stats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonStats{
+		Statements:      result.Statements,
+		Subjects:        result.Subjects,
+		Predicates:      result.Predicates,
+		Objects:         result.Objects,
+		Literals:        result.Literals,
+		Graphs:          result.Graphs,
+		Nodes:           result.Nodes,
+		BlankNodes:      result.BlankNodes,
+		BlankNodeRatio:  result.BlankNodeRatio(),
+		PredicateCounts: jsonCounts(result.PredicateCounts),
+		ClassCounts:     jsonCounts(result.ClassCounts),
+		DatatypeCounts:  jsonCounts(result.DatatypeCounts),
+		LanguageCounts:  jsonCounts(result.LanguageCounts),
+	})
+}
@@ -0,0 +1,170 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of context around the changes of a unified diff
+const diffContext = 3
+
+// a line kept (' '), deleted ('-') or inserted ('+')
+type lineEdit struct {
+	op   byte
+	line string
+}
+
+// the shortest edit script from a to b, with Myers' algorithm
+func diffLines(a, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	offset := n + m
+	v := make([]int, 2*offset+2)
+	// trace[d] holds v before the d-th round
+	trace := [][]int{}
+	rounds := 0
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				rounds = d
+				break search
+			}
+		}
+	}
+
+	// the edits are found backwards
+	ret := []lineEdit{}
+	x, y := n, m
+	for d := rounds; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		previous := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			previous = k + 1
+		}
+		previousX := v[offset+previous]
+		previousY := previousX - previous
+		for x > previousX && y > previousY {
+			ret = append(ret, lineEdit{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == previousX {
+			ret = append(ret, lineEdit{'+', b[y-1]})
+			y--
+		} else {
+			ret = append(ret, lineEdit{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ret = append(ret, lineEdit{' ', a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
+// the lines of s with their line breaks
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// the changes from a to b in the unified format, empty when they are equal
+func unifiedDiff(oldName string, newName string, a string, b string) string {
+	edits := diffLines(splitLines(a), splitLines(b))
+	// the number of lines of a and b before each edit
+	aLines := make([]int, len(edits)+1)
+	bLines := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		aLines[i+1], bLines[i+1] = aLines[i], bLines[i]
+		if edit.op != '+' {
+			aLines[i+1]++
+		}
+		if edit.op != '-' {
+			bLines[i+1]++
+		}
+	}
+
+	out := &strings.Builder{}
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		// changes separated by less than twice the context share a hunk
+		end := i
+		for j := i; j < len(edits); {
+			if edits[j].op != ' ' {
+				end = j
+				j++
+				continue
+			}
+			k := j
+			for k < len(edits) && edits[k].op == ' ' {
+				k++
+			}
+			if k == len(edits) || k-j > 2*diffContext {
+				break
+			}
+			j = k
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		stop := end + 1 + diffContext
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aLines[start], aLines[stop]), hunkRange(bLines[start], bLines[stop]))
+		for _, edit := range edits[start:stop] {
+			out.WriteByte(edit.op)
+			out.WriteString(edit.line)
+			if !strings.HasSuffix(edit.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return out.String()
+}
+
+// the lines from start to stop, numbered from 1
+func hunkRange(start int, stop int) string {
+	switch stop - start {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, stop-start)
+}
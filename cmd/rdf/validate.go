@@ -0,0 +1,231 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+var validateCommand = register(&command{
+	name:    "validate",
+	summary: "check the syntax of RDF files",
+	run:     runValidate,
+})
+
+// the outcome of the validation of a file
+type validation struct {
+	name string
+	// the file could not be read
+	err        error
+	errors     []*parser.SyntaxError
+	statements int
+	duration   time.Duration
+}
+
+// all the syntax errors of every file are reported, as file:line:col:
+// message lines on stderr or as a JSON or JUnit XML document on stdout
+func runValidate(this *env, args []string) int {
+	flags := this.newFlagSet("validate", "[file ...]")
+	in := &inputFlags{}
+	in.register(flags)
+	report := flags.String("report", "text", "report format: text, json or junit")
+	literals := flags.Bool("literals", false, "report the ill-typed literals too, whose lexical form is not one of their datatype")
+	if code, ok := parseFlags(flags, args); !ok {
+		return code
+	}
+	var write func(this *env, validations []*validation) error
+	switch *report {
+	case "text":
+		write = writeTextReport
+	case "json":
+		write = writeJSONReport
+	case "junit":
+		write = writeJUnitReport
+	default:
+		fmt.Fprintf(this.stderr, "rdf validate: unknown report format %q\n", *report)
+		return exitError
+	}
+
+	ret := exitOK
+	validations := []*validation{}
+	for _, name := range inputNames(flags) {
+		result := this.validate(name, in, *literals)
+		validations = append(validations, result)
+		if result.err != nil {
+			ret = exitError
+		} else if len(result.errors) > 0 && ret == exitOK {
+			ret = exitInvalid
+		}
+	}
+	if err := write(this, validations); err != nil {
+		fmt.Fprintln(this.stderr, "rdf validate:", err)
+		return exitError
+	}
+	return ret
+}
+
+func (this *env) validate(name string, in *inputFlags, literals bool) *validation {
+	start := time.Now()
+	ret := &validation{name: name}
+	source, err := this.parse(name, in, parser.Options{ContinueOnError: true, CheckLiterals: literals})
+	if err != nil {
+		ret.err = err
+		return ret
+	}
+	for range source.parser.Statements() {
+		ret.statements++
+	}
+	if err := source.close(); err != nil {
+		if _, ok := err.(*parser.SyntaxError); !ok {
+			// the file could not be read to the end
+			ret.err = err
+		}
+	}
+	ret.errors = source.parser.Errors()
+	ret.duration = time.Since(start)
+	return ret
+}
+
+func writeTextReport(this *env, validations []*validation) error {
+	for _, result := range validations {
+		if result.err != nil {
+			this.report(result.name, result.err)
+		}
+		for _, syntaxError := range result.errors {
+			this.report(result.name, syntaxError)
+		}
+	}
+	return nil
+}
+
+type jsonError struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+type jsonFile struct {
+	File       string      `json:"file"`
+	Valid      bool        `json:"valid"`
+	Statements int         `json:"statements"`
+	Error      string      `json:"error,omitempty"`
+	Errors     []jsonError `json:"errors"`
+}
+
+type jsonReport struct {
+	Valid bool       `json:"valid"`
+	Files []jsonFile `json:"files"`
+}
+
+func writeJSONReport(this *env, validations []*validation) error {
+	report := jsonReport{Valid: true, Files: []jsonFile{}}
+	for _, result := range validations {
+		file := jsonFile{
+			File:       displayName(result.name),
+			Valid:      result.err == nil && len(result.errors) == 0,
+			Statements: result.statements,
+			Errors:     []jsonError{},
+		}
+		if result.err != nil {
+			file.Error = result.err.Error()
+		}
+		for _, syntaxError := range result.errors {
+			file.Errors = append(file.Errors, jsonError{Line: syntaxError.Line, Column: syntaxError.Col, Message: syntaxError.Message})
+		}
+		report.Valid = report.Valid && file.Valid
+		report.Files = append(report.Files, file)
+	}
+	encoder := json.NewEncoder(this.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
+
+// one test case per file, syntax errors are failures and unreadable files
+// are errors
+func writeJUnitReport(this *env, validations []*validation) error {
+	suite := junitTestSuite{Name: "rdf validate"}
+	var total time.Duration
+	for _, result := range validations {
+		name := displayName(result.name)
+		testCase := junitTestCase{Name: name, ClassName: "rdf.validate", Time: seconds(result.duration)}
+		total += result.duration
+		if result.err != nil {
+			testCase.Error = &junitMessage{Message: result.err.Error(), Type: "IOError", Text: result.err.Error()}
+			suite.Errors++
+		} else if len(result.errors) > 0 {
+			lines := []string{}
+			for _, syntaxError := range result.errors {
+				lines = append(lines, fmt.Sprintf("%s:%d:%d: %s", name, syntaxError.Line, syntaxError.Col, syntaxError.Message))
+			}
+			message := fmt.Sprintf("%d syntax errors", len(result.errors))
+			if len(result.errors) == 1 {
+				message = "1 syntax error"
+			}
+			testCase.Failure = &junitMessage{Message: message, Type: "SyntaxError", Text: strings.Join(lines, "\n")}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	suite.Time = seconds(total)
+	report := junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Suites:   []junitTestSuite{suite},
+	}
+	io.WriteString(this.stdout, xml.Header)
+	encoder := xml.NewEncoder(this.stdout)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(this.stdout, "\n")
+	return err
+}
@@ -0,0 +1,330 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package endpoint serves a store over HTTP with the SPARQL 1.1 Protocol
+// and the SPARQL 1.1 Graph Store HTTP Protocol.
+package endpoint
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+type Options struct {
+	// updates are refused
+	ReadOnly bool
+	// reads the documents of LOAD, which is refused when nil
+	Load func(source model.IRI) ([]*model.Statement, error)
+}
+
+// Endpoint answers the queries and updates of /sparql and the requests of
+// the graph store. Reads run concurrently, changes one at a time while no
+// read runs.
+type Endpoint struct {
+	store   store.Store
+	options Options
+	lock    sync.RWMutex
+	mux     *http.ServeMux
+}
+
+// the path of queries and updates
+const SPARQLPath = "/sparql"
+
+func New(dataset store.Store, options Options) *Endpoint {
+	this := &Endpoint{store: dataset, options: options, mux: http.NewServeMux()}
+	this.mux.HandleFunc(SPARQLPath, this.sparql)
+	this.mux.HandleFunc(GraphStorePath, this.graphStore)
+	return this
+}
+
+func (this *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	this.mux.ServeHTTP(w, r)
+}
+
+// the media types of the results of SELECT and ASK and of graphs, the
+// first ones being the defaults
+var (
+	resultTypes = []struct {
+		mediaType string
+		format    sparql.ResultFormat
+	}{
+		{sparql.JSON.MediaType(), sparql.JSON},
+		{sparql.XML.MediaType(), sparql.XML},
+		{"application/json", sparql.JSON},
+		{"application/xml", sparql.XML},
+		{sparql.CSV.MediaType(), sparql.CSV},
+		{sparql.TSV.MediaType(), sparql.TSV},
+		{"text/xml", sparql.XML},
+	}
+	graphTypes = []struct {
+		mediaType string
+		format    format.Format
+	}{
+		{format.Turtle.MediaType(), format.Turtle},
+		{format.TriG.MediaType(), format.TriG},
+		{format.NTriples.MediaType(), format.NTriples},
+		{format.NQuads.MediaType(), format.NQuads},
+		{"application/x-turtle", format.Turtle},
+		{"text/plain", format.NTriples},
+	}
+)
+
+// the protocol
+
+func (this *Endpoint) sparql(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, HEAD, POST")
+		fail(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+		return
+	}
+	// the parameters of the URL, and of the body of forms
+	if err := r.ParseForm(); err != nil {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.Method != http.MethodPost {
+		if _, ok := r.Form["update"]; ok {
+			fail(w, http.StatusBadRequest, "updates must be sent with POST")
+			return
+		}
+		if query, ok := single(w, r.Form, "query"); ok {
+			this.query(w, r, query)
+		}
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		_, isQuery := r.PostForm["query"]
+		_, isUpdate := r.PostForm["update"]
+		switch {
+		case isQuery && isUpdate:
+			fail(w, http.StatusBadRequest, "both a query and an update")
+		case isUpdate:
+			if update, ok := single(w, r.PostForm, "update"); ok {
+				this.update(w, r, update)
+			}
+		default:
+			if query, ok := single(w, r.PostForm, "query"); ok {
+				this.query(w, r, query)
+			}
+		}
+	case "application/sparql-query", "application/sparql-update":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			fail(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if mediaType == "application/sparql-query" {
+			this.query(w, r, string(body))
+		} else {
+			this.update(w, r, string(body))
+		}
+	default:
+		fail(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", mediaType))
+	}
+}
+
+// the value of a parameter given once
+func single(w http.ResponseWriter, values map[string][]string, name string) (string, bool) {
+	switch len(values[name]) {
+	case 0:
+		fail(w, http.StatusBadRequest, fmt.Sprintf("missing %s", name))
+		return "", false
+	case 1:
+		return values[name][0], true
+	}
+	fail(w, http.StatusBadRequest, fmt.Sprintf("more than one %s", name))
+	return "", false
+}
+
+func fail(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintln(w, message)
+}
+
+// the message of a syntax error, with its position
+func syntaxMessage(err error) string {
+	if syntaxError, ok := err.(*parser.SyntaxError); ok {
+		return fmt.Sprintf("%d:%d: %s", syntaxError.Line, syntaxError.Col, syntaxError.Message)
+	}
+	return err.Error()
+}
+
+func iris(values []string) []model.IRI {
+	ret := make([]model.IRI, len(values))
+	for i, value := range values {
+		ret[i] = model.IRI(value)
+	}
+	return ret
+}
+
+func (this *Endpoint) query(w http.ResponseWriter, r *http.Request, text string) {
+	query, err := sparql.ParseQuery(text, sparql.Options{})
+	if err != nil {
+		fail(w, http.StatusBadRequest, syntaxMessage(err))
+		return
+	}
+	// the dataset of the protocol replaces the one of the query
+	if r.Form["default-graph-uri"] != nil || r.Form["named-graph-uri"] != nil {
+		query.From = iris(r.Form["default-graph-uri"])
+		query.FromNamed = iris(r.Form["named-graph-uri"])
+	}
+
+	// the format is chosen before evaluating
+	accept := r.Header.Get("Accept")
+	offers := []string{}
+	resultFormats := []sparql.ResultFormat{}
+	if query.Form == sparql.Select || query.Form == sparql.Ask {
+		for _, t := range resultTypes {
+			// CSV and TSV have no form for ASK
+			if query.Form == sparql.Select || (t.format != sparql.CSV && t.format != sparql.TSV) {
+				offers = append(offers, t.mediaType)
+				resultFormats = append(resultFormats, t.format)
+			}
+		}
+	} else {
+		for _, t := range graphTypes {
+			offers = append(offers, t.mediaType)
+		}
+	}
+	chosen, ok := negotiate(accept, offers)
+	if !ok {
+		fail(w, http.StatusNotAcceptable, fmt.Sprintf("no format of the results of %s is acceptable", query.Form))
+		return
+	}
+	mediaType := offers[chosen]
+
+	this.lock.RLock()
+	result, err := sparql.Evaluate(query, this.store)
+	this.lock.RUnlock()
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	body := &bytes.Buffer{}
+	if query.Form == sparql.Select || query.Form == sparql.Ask {
+		err = sparql.WriteResult(body, result, resultFormats[chosen])
+	} else {
+		err = writer.WriteAll(writer.NewWriter(body, graphTypes[chosen].format, writer.Options{Namespaces: query.Namespaces}), result.Statements)
+	}
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		mediaType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.Header().Add("Vary", "Accept")
+	if r.Method != http.MethodHead {
+		w.Write(body.Bytes())
+	}
+}
+
+func (this *Endpoint) update(w http.ResponseWriter, r *http.Request, text string) {
+	if this.options.ReadOnly {
+		fail(w, http.StatusForbidden, "updates are not allowed")
+		return
+	}
+	update, err := sparql.ParseUpdate(text, sparql.Options{})
+	if err != nil {
+		fail(w, http.StatusBadRequest, syntaxMessage(err))
+		return
+	}
+	// the dataset of the protocol replaces USING and WITH, which are then
+	// not allowed
+	using, usingNamed := r.Form["using-graph-uri"], r.Form["using-named-graph-uri"]
+	if using != nil || usingNamed != nil {
+		for _, operation := range update.Operations {
+			if modify, ok := operation.(*sparql.Modify); ok {
+				if modify.With != "" || modify.Using != nil || modify.UsingNamed != nil {
+					fail(w, http.StatusBadRequest, "using-graph-uri and using-named-graph-uri with USING or WITH")
+					return
+				}
+				modify.Using, modify.UsingNamed = iris(using), iris(usingNamed)
+			}
+		}
+	}
+	load := this.options.Load
+	if load == nil {
+		load = func(source model.IRI) ([]*model.Statement, error) {
+			return nil, fmt.Errorf("cannot load <%s>: LOAD is not allowed", source)
+		}
+	}
+	this.lock.Lock()
+	err = sparql.ApplyUpdate(update, this.store, sparql.UpdateOptions{Load: load})
+	this.lock.Unlock()
+	if _, ok := err.(*sparql.OperationError); ok {
+		fail(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// content negotiation
+
+type accepted struct {
+	mediaType string
+	quality   float64
+}
+
+// the index of the offer preferred by an Accept header, the first one when
+// the header is empty
+func negotiate(header string, offers []string) (int, bool) {
+	if strings.TrimSpace(header) == "" {
+		return 0, true
+	}
+	ranges := []accepted{}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, parameters, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := parameters["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, accepted{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(a, b int) bool {
+		return ranges[a].quality > ranges[b].quality
+	})
+	for _, r := range ranges {
+		for i, offer := range offers {
+			switch {
+			case r.mediaType == offer, r.mediaType == "*/*":
+				return i, true
+			case strings.HasSuffix(r.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r.mediaType, "*")):
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,192 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package endpoint
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+const data = `@prefix : <http://ex.org/> .
+:alice :name "Alice" ; :knows :bob .
+:bob :name "Bob" .
+:g { :alice :likes :tea }
+`
+
+func newServer(t *testing.T, options Options) *httptest.Server {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(data), parser.Options{Format: format.TriG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := store.NewMemory()
+	for _, statement := range statements {
+		dataset.Add(statement)
+	}
+	server := httptest.NewServer(New(dataset, options))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// sends a request, the body being a form when contentType is empty
+func send(t *testing.T, method, target, contentType, accept, body string) (*http.Response, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response, string(content)
+}
+
+func TestQuery(t *testing.T) {
+	server := newServer(t, Options{})
+	sparqlURL := server.URL + SPARQLPath
+	names := `SELECT ?n { ?p <http://ex.org/name> ?n } ORDER BY ?n`
+	cases := []struct {
+		method      string
+		target      string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		mediaType   string
+		contains    string
+	}{
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(names), "", "", "", 200, "application/sparql-results+json", `"value": "Alice"`},
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(names), "", "application/sparql-results+xml", "", 200, "application/sparql-results+xml", `<literal>Bob</literal>`},
+		{"POST", sparqlURL, "application/x-www-form-urlencoded", "text/csv;q=0.5, text/tab-separated-values", "query=" + url.QueryEscape(names), 200, "text/tab-separated-values; charset=utf-8", "?n\n\"Alice\"\n\"Bob\"\n"},
+		{"POST", sparqlURL, "application/sparql-query", "text/*", names, 200, "text/csv; charset=utf-8", "n\r\nAlice\r\nBob\r\n"},
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(`ASK { ?s ?p "Bob" }`), "", "text/csv, */*;q=0.1", "", 200, "application/sparql-results+json", `"boolean": true`},
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(`CONSTRUCT WHERE { ?s <http://ex.org/knows> ?o }`), "", "application/n-triples", "", 200, "application/n-triples", "<http://ex.org/alice> <http://ex.org/knows> <http://ex.org/bob> .\n"},
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(`PREFIX : <http://ex.org/> DESCRIBE :bob`), "", "", "", 200, "text/turtle; charset=utf-8", `:bob :name "Bob"`},
+		// the dataset of the protocol
+		{"GET", sparqlURL + "?default-graph-uri=http://ex.org/g&query=" + url.QueryEscape(`SELECT ?o { ?s ?p ?o }`), "", "text/csv", "", 200, "text/csv; charset=utf-8", "o\r\nhttp://ex.org/tea\r\n"},
+		{"POST", sparqlURL + "?named-graph-uri=http://ex.org/h", "application/sparql-query", "text/csv", `SELECT ?g { GRAPH ?g { } }`, 200, "text/csv; charset=utf-8", "g\r\n"},
+		// errors
+		{"GET", sparqlURL + "?query=SELECT", "", "", "", 400, "text/plain; charset=utf-8", "1:7: "},
+		{"GET", sparqlURL, "", "", "", 400, "text/plain; charset=utf-8", "missing query"},
+		{"GET", sparqlURL + "?query=ASK{}&query=ASK{}", "", "", "", 400, "text/plain; charset=utf-8", "more than one query"},
+		{"GET", sparqlURL + "?query=" + url.QueryEscape(`ASK {}`), "", "text/csv", "", 406, "text/plain; charset=utf-8", "ASK"},
+		{"GET", sparqlURL + "?update=" + url.QueryEscape(`CLEAR ALL`), "", "", "", 400, "text/plain; charset=utf-8", "POST"},
+		{"POST", sparqlURL, "text/plain", "", "ASK {}", 415, "text/plain; charset=utf-8", "text/plain"},
+		{"DELETE", sparqlURL, "", "", "", 405, "text/plain; charset=utf-8", "DELETE"},
+		{"POST", sparqlURL, "application/x-www-form-urlencoded", "", "query=ASK{}&update=CLEAR+ALL", 400, "text/plain; charset=utf-8", "both"},
+	}
+	for _, c := range cases {
+		response, body := send(t, c.method, c.target, c.contentType, c.accept, c.body)
+		if response.StatusCode != c.status || response.Header.Get("Content-Type") != c.mediaType || !strings.Contains(body, c.contains) {
+			t.Errorf("%s %s %s: %d %s\n%s", c.method, c.target, c.body, response.StatusCode, response.Header.Get("Content-Type"), body)
+		}
+	}
+
+	// the results can be parsed back
+	response, body := send(t, "GET", sparqlURL+"?query="+url.QueryEscape(names), "", "application/sparql-results+xml", "")
+	f, _ := sparql.ResultFormatByMediaType(response.Header.Get("Content-Type"))
+	result, err := sparql.ParseResult(strings.NewReader(body), f)
+	if err != nil || len(result.Solutions) != 2 {
+		t.Errorf("unexpected results %v, error %v", result, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	server := newServer(t, Options{})
+	sparqlURL := server.URL + SPARQLPath
+	count := func() string {
+		_, body := send(t, "GET", sparqlURL+"?query="+url.QueryEscape(`SELECT (COUNT(*) AS ?n) { ?s ?p ?o }`), "", "text/csv", "")
+		return strings.TrimSpace(strings.TrimPrefix(body, "n\r\n"))
+	}
+	cases := []struct {
+		target      string
+		contentType string
+		body        string
+		status      int
+		count       string
+	}{
+		{sparqlURL, "application/x-www-form-urlencoded", "update=" + url.QueryEscape(`INSERT DATA { <http://ex.org/carol> <http://ex.org/name> "Carol" }`), 204, "4"},
+		{sparqlURL, "application/sparql-update", `DELETE WHERE { ?s <http://ex.org/knows> ?o }`, 204, "3"},
+		{sparqlURL + "?using-graph-uri=http://ex.org/g", "application/sparql-update", `INSERT { ?s <http://ex.org/drinks> ?o } WHERE { ?s ?p ?o }`, 204, "4"},
+		{sparqlURL + "?using-graph-uri=http://ex.org/g", "application/sparql-update", `WITH <http://ex.org/g> DELETE { ?s ?p ?o } WHERE { ?s ?p ?o }`, 400, "4"},
+		{sparqlURL, "application/sparql-update", `DELETE DATA { ?s ?p ?o }`, 400, "4"},
+		{sparqlURL, "application/sparql-update", `CLEAR DEFAULT ; LOAD <data.ttl>`, 400, "4"},
+		{sparqlURL, "application/sparql-update", `INSERT DATA { GRAPH <http://ex.org/h> { <http://ex.org/a> <http://ex.org/b> <http://ex.org/c> } } ; CREATE GRAPH <http://ex.org/h>`, 400, "4"},
+		{sparqlURL, "application/sparql-update", `LOAD SILENT <data.ttl> ; CLEAR DEFAULT`, 204, "0"},
+	}
+	for _, c := range cases {
+		response, body := send(t, "POST", c.target, c.contentType, "", c.body)
+		if response.StatusCode != c.status {
+			t.Errorf("%s: %d instead of %d\n%s", c.body, response.StatusCode, c.status, body)
+		}
+		if got := count(); got != c.count {
+			t.Errorf("%s: %s statements in the default graph instead of %s", c.body, got, c.count)
+		}
+	}
+
+	readOnly := newServer(t, Options{ReadOnly: true})
+	if response, _ := send(t, "POST", readOnly.URL+SPARQLPath, "application/sparql-update", "", `CLEAR ALL`); response.StatusCode != 403 {
+		t.Errorf("update of a read only endpoint: %d", response.StatusCode)
+	}
+
+	failing := httptest.NewServer(New(failingStore{store.NewMemory()}, Options{}))
+	defer failing.Close()
+	if response, _ := send(t, "POST", failing.URL+SPARQLPath, "application/sparql-update", "", `INSERT DATA { <http://ex.org/a> <http://ex.org/b> <http://ex.org/c> }`); response.StatusCode != 500 {
+		t.Errorf("update of a failing store: %d", response.StatusCode)
+	}
+}
+
+type failingStore struct {
+	*store.Memory
+}
+
+func (this failingStore) Add(statement *model.Statement) error {
+	return errors.New("refused")
+}
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/sparql-results+json", "application/sparql-results+xml", "text/csv"}
+	cases := []struct {
+		header string
+		chosen int
+		ok     bool
+	}{
+		{"", 0, true},
+		{"text/csv", 2, true},
+		{"application/*;q=0.5, text/csv;q=0.8", 2, true},
+		{"application/sparql-results+xml, */*;q=0.1", 1, true},
+		{"text/csv;q=0, application/*", 0, true},
+		{"image/png, text/csv;q=0", 0, false},
+	}
+	for _, c := range cases {
+		chosen, ok := negotiate(c.header, offers)
+		if chosen != c.chosen || ok != c.ok {
+			t.Errorf("%q: %d %v instead of %d %v", c.header, chosen, ok, c.chosen, c.ok)
+		}
+	}
+}
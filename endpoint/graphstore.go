@@ -0,0 +1,218 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package endpoint
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// Graph Store Protocol
+//
+// the graphs of the store as resources, named by ?graph=iri, relative to
+// the request URL, or ?default: GET reads one, PUT replaces it, POST adds
+// to it and DELETE removes it.
+// Named graphs exist when they have statements.
+
+// the path of the graph store
+const GraphStorePath = "/rdf-graph-store"
+
+func (this *Endpoint) graphStore(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPost, http.MethodDelete:
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST, DELETE")
+		fail(w, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+		return
+	}
+	query := r.URL.Query()
+	_, isDefault := query["default"]
+	graphs := query["graph"]
+	var graph model.IRI
+	switch {
+	case isDefault && len(graphs) == 0:
+	case !isDefault && len(graphs) == 1 && graphs[0] != "":
+		graph = resolve(requestURL(r), graphs[0])
+	default:
+		fail(w, http.StatusBadRequest, "expected either ?default or one ?graph=iri")
+		return
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		this.getGraph(w, r, graph)
+		return
+	}
+	if this.options.ReadOnly {
+		fail(w, http.StatusForbidden, "changes are not allowed")
+		return
+	}
+	var statements []*model.Statement
+	if r.Method != http.MethodDelete {
+		var ok bool
+		if statements, ok = this.readGraph(w, r, graph); !ok {
+			return
+		}
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	transaction := store.NewTransaction(this.store)
+	existing, err := graphStatements(transaction, graph)
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// the default graph always exists
+	exists := graph == "" || len(existing) > 0
+	if r.Method == http.MethodDelete && !exists {
+		fail(w, http.StatusNotFound, fmt.Sprintf("no graph <%s>", graph))
+		return
+	}
+	if r.Method != http.MethodPost {
+		for _, statement := range existing {
+			if err := transaction.Remove(statement); err != nil {
+				fail(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+	}
+	for _, statement := range statements {
+		if err := transaction.Add(statement); err != nil {
+			fail(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !exists && len(statements) > 0 {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// the statements of a graph, the default one when empty
+func graphStatements(dataset store.Store, graph model.IRI) ([]*model.Statement, error) {
+	var pattern model.RDFTerm = store.DefaultGraph
+	if graph != "" {
+		pattern = graph
+	}
+	return store.All(dataset.Match(nil, nil, nil, pattern))
+}
+
+// the graph is written as triples
+func (this *Endpoint) getGraph(w http.ResponseWriter, r *http.Request, graph model.IRI) {
+	offers := []string{}
+	for _, t := range graphTypes {
+		offers = append(offers, t.mediaType)
+	}
+	chosen, ok := negotiate(r.Header.Get("Accept"), offers)
+	if !ok {
+		fail(w, http.StatusNotAcceptable, "no format of graphs is acceptable")
+		return
+	}
+	this.lock.RLock()
+	statements, err := graphStatements(this.store, graph)
+	this.lock.RUnlock()
+	if err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if graph != "" && len(statements) == 0 {
+		fail(w, http.StatusNotFound, fmt.Sprintf("no graph <%s>", graph))
+		return
+	}
+	body := &bytes.Buffer{}
+	out := writer.NewWriter(body, graphTypes[chosen].format, writer.Options{})
+	for _, statement := range statements {
+		triple := &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object}
+		if err := out.Write(triple); err != nil {
+			fail(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := out.Close(); err != nil {
+		fail(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	mediaType := offers[chosen]
+	if strings.HasPrefix(mediaType, "text/") {
+		mediaType += "; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.Header().Add("Vary", "Accept")
+	if r.Method != http.MethodHead {
+		w.Write(body.Bytes())
+	}
+}
+
+// the statements of the body, in the format of its content type, put in
+// graph whatever the graph they are in in the body
+func (this *Endpoint) readGraph(w http.ResponseWriter, r *http.Request, graph model.IRI) ([]*model.Statement, bool) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	f, ok := format.ByMediaType(mediaType)
+	if !ok {
+		for _, t := range graphTypes {
+			if t.mediaType == mediaType {
+				f, ok = t.format, true
+			}
+		}
+	}
+	if !ok {
+		fail(w, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported content type %q", mediaType))
+		return nil, false
+	}
+	base := graph
+	if base == "" {
+		base = model.IRI(requestURL(r))
+	}
+	statements, err := parser.ParseAll(r.Body, parser.Options{Format: f, Base: base})
+	if err != nil {
+		fail(w, http.StatusBadRequest, syntaxMessage(err))
+		return nil, false
+	}
+	for _, statement := range statements {
+		statement.Context = nil
+		if graph != "" {
+			statement.Context = graph
+		}
+	}
+	return statements, true
+}
+
+// resolves the IRI of a graph against the request URL, as per RFC 3986
+func resolve(base string, iri string) model.IRI {
+	reference, err := url.Parse(iri)
+	if err != nil || reference.IsAbs() {
+		return model.IRI(iri)
+	}
+	parsed, err := url.Parse(base)
+	if err != nil {
+		return model.IRI(iri)
+	}
+	return model.IRI(parsed.ResolveReference(reference).String())
+}
+
+// the absolute URL of a request, without its query
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
@@ -0,0 +1,75 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package endpoint
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphStore(t *testing.T) {
+	server := newServer(t, Options{})
+	graphURL := server.URL + GraphStorePath
+	g := graphURL + "?graph=" + url.QueryEscape("http://ex.org/g")
+	h := graphURL + "?graph=" + url.QueryEscape("http://ex.org/h")
+	// the requests run in order, each one seeing the changes of the others
+	cases := []struct {
+		method      string
+		target      string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		contains    string
+	}{
+		{"GET", g, "", "application/n-triples", "", 200, "<http://ex.org/alice> <http://ex.org/likes> <http://ex.org/tea> .\n"},
+		{"GET", graphURL + "?default", "", "", "", 200, `<http://ex.org/name> "Bob"`},
+		{"HEAD", g, "", "", "", 200, ""},
+		{"GET", h, "", "", "", 404, "http://ex.org/h"},
+		// the statements of the body go into the graph, relative IRIs resolved against it
+		{"PUT", h, "text/turtle", "", `<a> <p> "x" .`, 201, ""},
+		{"GET", h, "", "application/n-triples", "", 200, `<http://ex.org/a> <http://ex.org/p> "x" .`},
+		{"POST", h, "application/n-quads", "", `<http://ex.org/a> <http://ex.org/p> "y" <http://ex.org/other> .`, 204, ""},
+		{"GET", h, "", "application/n-triples", "", 200, `<http://ex.org/p> "y" .`},
+		{"GET", graphURL + "?graph=" + url.QueryEscape("http://ex.org/other"), "", "", "", 404, ""},
+		{"PUT", h, "text/turtle; charset=utf-8", "", `<b> <p> "z" .`, 204, ""},
+		{"GET", h, "", "text/plain", "", 200, "<http://ex.org/b> <http://ex.org/p> \"z\" .\n"},
+		{"DELETE", h, "", "", "", 204, ""},
+		{"DELETE", h, "", "", "", 404, ""},
+		{"PUT", graphURL + "?default", "application/trig", "", `<http://ex.org/c> <http://ex.org/p> 1 .`, 204, ""},
+		{"GET", graphURL + "?default", "", "application/n-triples", "", 200, "<http://ex.org/c> <http://ex.org/p> \"1\"^^<http://www.w3.org/2001/XMLSchema#integer> .\n"},
+		{"DELETE", graphURL + "?default", "", "", "", 204, ""},
+		{"GET", graphURL + "?default", "", "", "", 200, ""},
+		{"GET", g, "", "", "", 200, "tea>"},
+		// a relative graph IRI is resolved against the request URL
+		{"PUT", graphURL + "?graph=rel", "text/turtle", "", `<a> <p> "r" .`, 201, ""},
+		{"GET", graphURL + "?graph=" + url.QueryEscape(server.URL+"/rel"), "", "application/n-triples", "", 200, "<" + server.URL + "/a> <" + server.URL + "/p> \"r\" .\n"},
+		// errors
+		{"GET", graphURL, "", "", "", 400, "?default"},
+		{"GET", g + "&default", "", "", "", 400, "?default"},
+		{"GET", g, "", "image/png", "", 406, "graphs"},
+		{"PUT", g, "image/png", "", "", 415, "image/png"},
+		{"PUT", g, "text/turtle", "", "<a> <p>", 400, "1:"},
+		{"PATCH", g, "", "", "", 405, "PATCH"},
+	}
+	for _, c := range cases {
+		response, body := send(t, c.method, c.target, c.contentType, c.accept, c.body)
+		if response.StatusCode != c.status || !strings.Contains(body, c.contains) {
+			t.Errorf("%s %s %s: %d\n%s", c.method, c.target, c.body, response.StatusCode, body)
+		}
+	}
+	// the failed PUT left the graph as it was
+	if _, body := send(t, "GET", g, "", "", ""); !strings.Contains(body, "tea>") {
+		t.Errorf("graph changed by a failed request:\n%s", body)
+	}
+
+	readOnly := newServer(t, Options{ReadOnly: true})
+	for _, method := range []string{"PUT", "POST", "DELETE"} {
+		if response, _ := send(t, method, readOnly.URL+GraphStorePath+"?default", "text/turtle", "", ""); response.StatusCode != 403 {
+			t.Errorf("%s on a read only graph store: %d", method, response.StatusCode)
+		}
+	}
+}
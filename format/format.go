@@ -0,0 +1,105 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package format
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// the concrete RDF syntaxes known to the parser and writer packages
+type Format int
+
+const (
+	Turtle Format = iota
+	TriG
+	NTriples
+	NQuads
+)
+
+type description struct {
+	name       string
+	mediaType  string
+	extensions []string
+}
+
+var descriptions = map[Format]description{
+	Turtle:   {"turtle", "text/turtle", []string{".ttl"}},
+	TriG:     {"trig", "application/trig", []string{".trig"}},
+	NTriples: {"ntriples", "application/n-triples", []string{".nt"}},
+	NQuads:   {"nquads", "application/n-quads", []string{".nq"}},
+}
+
+// aliases accepted by ByName on top of the canonical names
+var aliases = map[string]Format{
+	"ttl":       Turtle,
+	"nt":        NTriples,
+	"n-triples": NTriples,
+	"nq":        NQuads,
+	"n-quads":   NQuads,
+}
+
+func All() []Format {
+	return []Format{Turtle, TriG, NTriples, NQuads}
+}
+
+func (this Format) String() string {
+	return descriptions[this].name
+}
+
+func (this Format) MediaType() string {
+	return descriptions[this].mediaType
+}
+
+func (this Format) Extension() string {
+	return descriptions[this].extensions[0]
+}
+
+// true if the format can carry named graphs
+func (this Format) IsQuads() bool {
+	return this == TriG || this == NQuads
+}
+
+// true for the line based formats
+func (this Format) IsLineBased() bool {
+	return this == NTriples || this == NQuads
+}
+
+func ByName(name string) (Format, error) {
+	name = strings.ToLower(name)
+	for _, f := range All() {
+		if descriptions[f].name == name {
+			return f, nil
+		}
+	}
+	if f, ok := aliases[name]; ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown format %q", name)
+}
+
+func ByExtension(path string) (Format, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range All() {
+		for _, candidate := range descriptions[f].extensions {
+			if candidate == ext {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// media type parameters like charset are ignored
+func ByMediaType(mediaType string) (Format, bool) {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	for _, f := range All() {
+		if descriptions[f].mediaType == mediaType {
+			return f, true
+		}
+	}
+	return 0, false
+}
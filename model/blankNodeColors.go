@@ -0,0 +1,366 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Blank node colors
+//
+// blank nodes are told apart by their surroundings: every node starts with
+// the same color, then each round hashes the color of some nodes with the
+// statements they are used in, the other blank nodes of the statements
+// being replaced by their color. Nodes which correspond in isomorphic
+// graphs always get the same colors, the converse only holds once the
+// colors stop changing for most graphs.
+//
+// The first round hashes every node, the next ones only the nodes sharing a
+// statement with a node whose color changed. When a color splits, its
+// largest part keeps it so that the nodes around that part are left alone:
+// the nodes around the other parts tell themselves apart.
+
+type colorRefiner struct {
+	// the blank nodes in order of appearance, those of the first graph
+	// first
+	nodes []RDFTerm
+	index map[RDFTerm]int
+	// the nodes of the first graph, the others are those of the second
+	first int
+	// for each statement, a hash of its terms other than blank nodes and
+	// its blank nodes in order of appearance
+	ground []uint64
+	slots  [][]int
+	// the statements using each node
+	uses [][]int
+
+	colors []uint64
+	// the number of nodes of each color in each graph
+	counts map[uint64][2]int
+	// the former colors of the nodes recolored, to undo the rounds
+	trail []colorChange
+	// the length of the trail at the end of each round of refineAll
+	rounds []int
+	// marks the nodes already collected by neighbors
+	marks []int
+	mark  int
+}
+
+type colorChange struct {
+	node  int
+	color uint64
+}
+
+// the blank nodes of statements in order of appearance, triple terms
+// included
+func blankNodesOf(statements []*Statement) []RDFTerm {
+	ret := []RDFTerm{}
+	seen := make(map[RDFTerm]bool)
+	for _, statement := range statements {
+		forEachBlankNode(statement, func(node RDFTerm) {
+			if !seen[node] {
+				seen[node] = true
+				ret = append(ret, node)
+			}
+		})
+	}
+	return ret
+}
+
+func forEachBlankNode(statement *Statement, f func(node RDFTerm)) {
+	for _, term := range []RDFTerm{statement.Subject, statement.Predicate, statement.Object, statement.Context} {
+		forEachBlankNodeOf(term, f)
+	}
+}
+
+func forEachBlankNodeOf(term RDFTerm, f func(node RDFTerm)) {
+	switch t := term.(type) {
+	case BlankNode:
+		f(t)
+	case TripleTerm:
+		forEachBlankNodeOf(t.Subject, f)
+		forEachBlankNodeOf(t.Predicate, f)
+		forEachBlankNodeOf(t.Object, f)
+	}
+}
+
+// a refiner of the blank nodes of two graphs without blank nodes in common,
+// every node having the same color
+func newColorRefiner(a []*Statement, b []*Statement) *colorRefiner {
+	aNodes := blankNodesOf(a)
+	this := &colorRefiner{
+		nodes:  append(aNodes, blankNodesOf(b)...),
+		index:  make(map[RDFTerm]int),
+		first:  len(aNodes),
+		counts: make(map[uint64][2]int),
+	}
+	for i, node := range this.nodes {
+		this.index[node] = i
+	}
+	this.uses = make([][]int, len(this.nodes))
+	for i, statement := range append(append([]*Statement{}, a...), b...) {
+		slots := []int{}
+		forEachBlankNode(statement, func(node RDFTerm) {
+			slots = append(slots, this.index[node])
+		})
+		this.ground = append(this.ground, hashString(statementKey(statement)))
+		this.slots = append(this.slots, slots)
+		for j, node := range slots {
+			if !containsNode(slots[:j], node) {
+				this.uses[node] = append(this.uses[node], i)
+			}
+		}
+	}
+	initial := hashString("_:")
+	this.colors = make([]uint64, len(this.nodes))
+	for i := range this.nodes {
+		this.colors[i] = initial
+	}
+	this.counts[initial] = [2]int{this.first, len(this.nodes) - this.first}
+	this.marks = make([]int, len(this.nodes))
+	return this
+}
+
+func containsNode(nodes []int, node int) bool {
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// refines the colors from scratch until they stop changing, keeping the
+// end of each round
+func (this *colorRefiner) refineAll() {
+	affected := make([]int, len(this.nodes))
+	for i := range affected {
+		affected[i] = i
+	}
+	for len(affected) > 0 {
+		changed := this.split(affected)
+		this.rounds = append(this.rounds, len(this.trail))
+		affected = this.neighbors(changed)
+	}
+}
+
+// refines the colors from the nodes affected by a change, false as soon as
+// a color has not as many nodes in both graphs
+func (this *colorRefiner) refine(affected []int) bool {
+	for len(affected) > 0 {
+		changed := this.split(affected)
+		// the former colors of the nodes recolored end the trail
+		former := this.trail[len(this.trail)-len(changed):]
+		for i, node := range changed {
+			if !this.balanced(this.colors[node]) || !this.balanced(former[i].color) {
+				return false
+			}
+		}
+		affected = this.neighbors(changed)
+	}
+	return true
+}
+
+func (this *colorRefiner) balanced(color uint64) bool {
+	count := this.counts[color]
+	return count[0] == count[1]
+}
+
+// true when every color has as many nodes in both graphs
+func (this *colorRefiner) allBalanced() bool {
+	for _, count := range this.counts {
+		if count[0] != count[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes the affected nodes and splits their colors by hash, the largest
+// part of a color keeping it, and returns the nodes recolored
+func (this *colorRefiner) split(affected []int) []int {
+	keys := make([]uint64, len(affected))
+	// the number of affected nodes of each color and of each key, a key
+	// telling its color too
+	inColor := make(map[uint64]int)
+	inKey := make(map[uint64]int)
+	for i, node := range affected {
+		keys[i] = combine(this.colors[node], this.signature(node))
+		inColor[this.colors[node]]++
+		inKey[keys[i]]++
+	}
+	// the part keeping each color: the largest one, the nodes which are
+	// not affected first and then the smallest key in case of a tie
+	kept := make(map[uint64]uint64)
+	size := make(map[uint64]int)
+	for color, affectedCount := range inColor {
+		count := this.counts[color]
+		if rest := count[0] + count[1] - affectedCount; rest > 0 {
+			kept[color] = color
+			size[color] = rest
+		}
+	}
+	for i, node := range affected {
+		color, key := this.colors[node], keys[i]
+		best, found := kept[color]
+		if !found || inKey[key] > size[color] || (inKey[key] == size[color] && best != color && key < best) {
+			kept[color] = key
+			size[color] = inKey[key]
+		}
+	}
+	changed, colors := []int{}, []uint64{}
+	for i, node := range affected {
+		if kept[this.colors[node]] != keys[i] {
+			changed = append(changed, node)
+			colors = append(colors, keys[i])
+		}
+	}
+	for i, node := range changed {
+		this.trail = append(this.trail, colorChange{node: node, color: this.colors[node]})
+		this.recolor(node, colors[i])
+	}
+	return changed
+}
+
+func (this *colorRefiner) recolor(node int, color uint64) {
+	side := 0
+	if node >= this.first {
+		side = 1
+	}
+	count := this.counts[this.colors[node]]
+	count[side]--
+	if count[0] == 0 && count[1] == 0 {
+		delete(this.counts, this.colors[node])
+	} else {
+		this.counts[this.colors[node]] = count
+	}
+	this.colors[node] = color
+	count = this.counts[color]
+	count[side]++
+	this.counts[color] = count
+}
+
+// gives nodes a new color
+func (this *colorRefiner) individualize(color uint64, nodes ...int) {
+	for _, node := range nodes {
+		this.trail = append(this.trail, colorChange{node: node, color: this.colors[node]})
+		this.recolor(node, color)
+	}
+}
+
+// gives back their colors to the nodes recolored after the trail had
+// length mark
+func (this *colorRefiner) undo(mark int) {
+	for len(this.trail) > mark {
+		change := this.trail[len(this.trail)-1]
+		this.recolor(change.node, change.color)
+		this.trail = this.trail[:len(this.trail)-1]
+	}
+}
+
+// the nodes sharing a statement with nodes, nodes included
+func (this *colorRefiner) neighbors(nodes []int) []int {
+	this.mark++
+	ret := []int{}
+	for _, node := range nodes {
+		for _, statement := range this.uses[node] {
+			for _, neighbor := range this.slots[statement] {
+				if this.marks[neighbor] != this.mark {
+					this.marks[neighbor] = this.mark
+					ret = append(ret, neighbor)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+var selfColor = hashString("_:self")
+
+// the statements using node, seen from node, whatever their order
+func (this *colorRefiner) signature(node int) uint64 {
+	var ret uint64
+	for _, statement := range this.uses[node] {
+		hash := this.ground[statement]
+		for _, other := range this.slots[statement] {
+			if other == node {
+				hash = combine(hash, selfColor)
+			} else {
+				hash = combine(hash, this.colors[other])
+			}
+		}
+		ret += mix(hash)
+	}
+	return ret
+}
+
+func hashString(s string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(s))
+	return hash.Sum64()
+}
+
+// the finalizer of splitmix64
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+func combine(h uint64, value uint64) uint64 {
+	return mix(h*0x9e3779b97f4a7c15 + value)
+}
+
+// the statement with its blank nodes left out
+func statementKey(statement *Statement) string {
+	return termKey(statement.Subject) + " " + termKey(statement.Predicate) + " " + termKey(statement.Object) + " " + termKey(statement.Context)
+}
+
+func termKey(term RDFTerm) string {
+	switch t := term.(type) {
+	case nil:
+		return "-"
+	case IRI:
+		return "<" + string(t) + ">"
+	case BlankNode:
+		return "_:"
+	case Literal:
+		return fmt.Sprintf("%q^^%q@%q--%q", t.Lexical, t.Datatype, t.Language, t.Direction)
+	case TripleTerm:
+		return "<<(" + strings.Join([]string{termKey(t.Subject), termKey(t.Predicate), termKey(t.Object)}, " ") + ")>>"
+	}
+	return fmt.Sprintf("%#v", term)
+}
+
+// the statement with its blank nodes replaced according to mapping
+func substitute(statement *Statement, mapping map[RDFTerm]RDFTerm) *Statement {
+	return &Statement{
+		Subject:   substituteTerm(statement.Subject, mapping),
+		Predicate: substituteTerm(statement.Predicate, mapping),
+		Object:    substituteTerm(statement.Object, mapping),
+		Context:   substituteTerm(statement.Context, mapping),
+	}
+}
+
+func substituteTerm(term RDFTerm, mapping map[RDFTerm]RDFTerm) RDFTerm {
+	switch t := term.(type) {
+	case BlankNode:
+		if image, ok := mapping[t]; ok {
+			return image
+		}
+	case TripleTerm:
+		return TripleTerm{
+			Subject:   substituteTerm(t.Subject, mapping),
+			Predicate: substituteTerm(t.Predicate, mapping),
+			Object:    substituteTerm(t.Object, mapping),
+		}
+	}
+	return term
+}
@@ -5,10 +5,37 @@
 
 package model
 
+import "sync/atomic"
+
 // BlankNodes
+//
+// blank nodes are always handled through pointers: two blank nodes are the
+// same term if and only if they are the same pointer. Labels only matter
+// inside the document they were read from.
+
+type BlankNode interface {
+	isBlankNode()
+}
+
+type LabelledBlankNode struct {
+	Label string
+}
+
+type AnonymousBlankNode struct {
+	ID uint64
+}
+
+var anonymousCounter uint64
+
+func NewAnonymousBlankNode() *AnonymousBlankNode {
+	return &AnonymousBlankNode{ID: atomic.AddUint64(&anonymousCounter, 1)}
+}
 
-type BlankNode interface{}
+func (this *LabelledBlankNode) isBlankNode() {}
 
-type LabelledBlankNode struct{}
+func (this *AnonymousBlankNode) isBlankNode() {}
 
-type AnonymousBlankNode struct{}
+func IsBlankNode(term RDFTerm) bool {
+	_, ok := term.(BlankNode)
+	return ok
+}
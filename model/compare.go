@@ -0,0 +1,101 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import (
+	"sort"
+	"strings"
+)
+
+// the rank of the kinds of terms in CompareTerms
+func termKind(term RDFTerm) int {
+	switch term.(type) {
+	case nil:
+		return 0
+	case IRI:
+		return 1
+	case *LabelledBlankNode:
+		return 2
+	case *AnonymousBlankNode:
+		return 3
+	case Literal:
+		return 4
+	case TripleTerm:
+		return 5
+	}
+	return 6
+}
+
+// CompareTerms orders terms by kind: nil (the default graph), IRIs,
+// labelled blank nodes, anonymous blank nodes, literals and triple terms,
+// then by value. It returns -1, 0 or 1 like strings.Compare.
+func CompareTerms(a, b RDFTerm) int {
+	if ka, kb := termKind(a), termKind(b); ka != kb {
+		if ka < kb {
+			return -1
+		}
+		return 1
+	}
+	switch ta := a.(type) {
+	case IRI:
+		return strings.Compare(string(ta), string(b.(IRI)))
+	case *LabelledBlankNode:
+		tb := b.(*LabelledBlankNode)
+		if c := strings.Compare(ta.Label, tb.Label); c != 0 {
+			return c
+		}
+	case *AnonymousBlankNode:
+		tb := b.(*AnonymousBlankNode)
+		switch {
+		case ta.ID < tb.ID:
+			return -1
+		case ta.ID > tb.ID:
+			return 1
+		}
+	case Literal:
+		tb := b.(Literal)
+		for _, pair := range [][2]string{
+			{ta.Lexical, tb.Lexical},
+			{string(ta.Datatype), string(tb.Datatype)},
+			{ta.Language, tb.Language},
+			{ta.Direction, tb.Direction},
+		} {
+			if c := strings.Compare(pair[0], pair[1]); c != 0 {
+				return c
+			}
+		}
+	case TripleTerm:
+		tb := b.(TripleTerm)
+		if c := CompareTerms(ta.Subject, tb.Subject); c != 0 {
+			return c
+		}
+		if c := CompareTerms(ta.Predicate, tb.Predicate); c != 0 {
+			return c
+		}
+		return CompareTerms(ta.Object, tb.Object)
+	}
+	return 0
+}
+
+// CompareStatements orders statements by graph, subject, predicate and
+// object
+func CompareStatements(a, b *Statement) int {
+	if c := CompareTerms(a.Context, b.Context); c != 0 {
+		return c
+	}
+	if c := CompareTerms(a.Subject, b.Subject); c != 0 {
+		return c
+	}
+	if c := CompareTerms(a.Predicate, b.Predicate); c != 0 {
+		return c
+	}
+	return CompareTerms(a.Object, b.Object)
+}
+
+func sortStatements(statements []*Statement) {
+	sort.SliceStable(statements, func(i, j int) bool {
+		return CompareStatements(statements[i], statements[j]) < 0
+	})
+}
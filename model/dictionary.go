@@ -0,0 +1,80 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import "sync"
+
+// the integer standing for a term in a Dictionary, 0 stands for nil, the
+// default graph
+type ID uint64
+
+// Dictionary gives each term a small integer, in order of arrival, and
+// keeps a single copy of the terms it has seen. Terms are never forgotten.
+// It is safe for concurrent use.
+type Dictionary struct {
+	lock  sync.RWMutex
+	ids   map[RDFTerm]ID
+	terms []RDFTerm
+}
+
+func NewDictionary() *Dictionary {
+	return &Dictionary{ids: make(map[RDFTerm]ID), terms: []RDFTerm{nil}}
+}
+
+// the ID of term, added to the dictionary if it is new
+func (this *Dictionary) Encode(term RDFTerm) ID {
+	if term == nil {
+		return 0
+	}
+	this.lock.RLock()
+	id, ok := this.ids[term]
+	this.lock.RUnlock()
+	if ok {
+		return id
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if id, ok := this.ids[term]; ok {
+		return id
+	}
+	id = ID(len(this.terms))
+	this.ids[term] = id
+	this.terms = append(this.terms, term)
+	return id
+}
+
+// the ID of term if it is in the dictionary
+func (this *Dictionary) Lookup(term RDFTerm) (ID, bool) {
+	if term == nil {
+		return 0, true
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	id, ok := this.ids[term]
+	return id, ok
+}
+
+// the term of id, nil when it is unknown
+func (this *Dictionary) Decode(id ID) RDFTerm {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if int(id) >= len(this.terms) {
+		return nil
+	}
+	return this.terms[id]
+}
+
+// Intern returns the copy of term kept by the dictionary, so that equal
+// terms share their strings
+func (this *Dictionary) Intern(term RDFTerm) RDFTerm {
+	return this.Decode(this.Encode(term))
+}
+
+// the number of terms
+func (this *Dictionary) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return len(this.terms) - 1
+}
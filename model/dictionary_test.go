@@ -0,0 +1,36 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import "testing"
+
+func TestDictionary(t *testing.T) {
+	dictionary := NewDictionary()
+	node := NewAnonymousBlankNode()
+	terms := []RDFTerm{IRI("http://a"), NewStringLiteral("a"), NewLangLiteral("a", "en", ""), node, &LabelledBlankNode{Label: "b"}, TripleTerm{Subject: node, Predicate: IRI("http://a"), Object: IRI("http://a")}}
+	for i, term := range terms {
+		if id := dictionary.Encode(term); id != ID(i+1) {
+			t.Errorf("%v: id %d instead of %d", term, id, i+1)
+		}
+	}
+	if id := dictionary.Encode(IRI("http://a")); id != 1 || dictionary.Len() != len(terms) {
+		t.Errorf("a known term got %d, %d terms", id, dictionary.Len())
+	}
+	// blank nodes are told apart by identity
+	if _, ok := dictionary.Lookup(&LabelledBlankNode{Label: "b"}); ok {
+		t.Errorf("another blank node with the same label is known")
+	}
+	if id, ok := dictionary.Lookup(nil); !ok || id != 0 || dictionary.Decode(0) != nil {
+		t.Errorf("nil is %d", id)
+	}
+	if dictionary.Decode(ID(len(terms)+1)) != nil {
+		t.Errorf("an unknown id was decoded")
+	}
+	for i, term := range terms {
+		if dictionary.Decode(ID(i+1)) != term || dictionary.Intern(term) != term {
+			t.Errorf("%v was not kept", term)
+		}
+	}
+}
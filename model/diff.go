@@ -0,0 +1,84 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+// Diff returns the statements of a which are not in b and the statements
+// of b which are not in a. Blank nodes are matched by their place in the
+// graphs rather than by label: the added statements use the blank nodes of
+// a matched with theirs, and their own for the others. Both lists are
+// sorted with CompareStatements.
+func Diff(a []*Statement, b []*Statement) (removed []*Statement, added []*Statement) {
+	search := newIsomorphismSearch(a, b)
+	if search.mapping() != nil {
+		return nil, nil
+	}
+	mapping := search.match()
+	inA := make(map[Statement]struct{})
+	for _, statement := range search.a {
+		inA[*statement] = struct{}{}
+	}
+	inB := make(map[Statement]struct{})
+	for _, statement := range search.b {
+		mapped := substitute(statement, mapping)
+		if _, ok := inB[*mapped]; ok {
+			continue
+		}
+		inB[*mapped] = struct{}{}
+		if _, ok := inA[*mapped]; !ok {
+			added = append(added, mapped)
+		}
+	}
+	for _, statement := range search.a {
+		if _, ok := inB[*statement]; !ok {
+			removed = append(removed, statement)
+		}
+	}
+	sortStatements(removed)
+	sortStatements(added)
+	return removed, added
+}
+
+// maps the blank nodes of b to those of a, nodes with the finest colors
+// in common first, then the remaining ones with the colors of the former
+// rounds, so that a node whose surroundings changed still goes with its
+// former self. The nodes shared by a and b are themselves. The colors are
+// undone along the way.
+func (this *isomorphismSearch) match() map[RDFTerm]RDFTerm {
+	refiner := this.refined()
+	mapping := make(map[RDFTerm]RDFTerm)
+	matched := make([]bool, len(refiner.nodes))
+	for fresh, node := range this.original {
+		mapping[fresh] = node
+		matched[refiner.index[fresh]] = true
+		matched[refiner.index[node]] = true
+	}
+	for round := len(refiner.rounds) - 1; round >= -1; round-- {
+		candidates := make(map[uint64][]int)
+		for node := 0; node < refiner.first; node++ {
+			if !matched[node] {
+				color := refiner.colors[node]
+				candidates[color] = append(candidates[color], node)
+			}
+		}
+		for node := refiner.first; node < len(refiner.nodes); node++ {
+			if matched[node] {
+				continue
+			}
+			color := refiner.colors[node]
+			if nodes := candidates[color]; len(nodes) > 0 {
+				mapping[refiner.nodes[node]] = refiner.nodes[nodes[0]]
+				matched[node], matched[nodes[0]] = true, true
+				candidates[color] = nodes[1:]
+			}
+		}
+		// back to the colors of the round before, the first colors at last
+		if round > 0 {
+			refiner.undo(refiner.rounds[round-1])
+		} else if round == 0 {
+			refiner.undo(0)
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,179 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+// Isomorphic tells whether a and b are the same graph, or dataset, up to
+// the renaming of blank nodes, and if so returns the blank nodes of b
+// corresponding to those of a. Duplicate statements are ignored.
+//
+// Blank nodes are first told apart by color refinement, when some remain
+// alike one of them is paired in turn with each candidate of b and the
+// refinement goes on from the pair, backtracking as soon as a color has not
+// as many nodes in both graphs.
+func Isomorphic(a []*Statement, b []*Statement) (bool, map[RDFTerm]RDFTerm) {
+	mapping := newIsomorphismSearch(a, b).mapping()
+	return mapping != nil, mapping
+}
+
+func dedupe(statements []*Statement) []*Statement {
+	seen := make(map[Statement]struct{})
+	ret := make([]*Statement, 0, len(statements))
+	for _, statement := range statements {
+		if _, ok := seen[*statement]; !ok {
+			seen[*statement] = struct{}{}
+			ret = append(ret, statement)
+		}
+	}
+	return ret
+}
+
+func hasBlankNode(statement *Statement) bool {
+	ret := false
+	forEachBlankNode(statement, func(RDFTerm) { ret = true })
+	return ret
+}
+
+type isomorphismSearch struct {
+	a []*Statement
+	// b with the blank nodes it shares with a replaced, so that the colors
+	// of a and b are computed apart, and the nodes replaced
+	b        []*Statement
+	bSet     map[Statement]struct{}
+	original map[RDFTerm]RDFTerm
+	// refined from scratch when first needed
+	refiner *colorRefiner
+}
+
+func newIsomorphismSearch(a []*Statement, b []*Statement) *isomorphismSearch {
+	this := &isomorphismSearch{a: dedupe(a), b: dedupe(b), bSet: make(map[Statement]struct{}), original: make(map[RDFTerm]RDFTerm)}
+	inA := make(map[RDFTerm]bool)
+	for _, node := range blankNodesOf(this.a) {
+		inA[node] = true
+	}
+	renamed := make(map[RDFTerm]RDFTerm)
+	for _, node := range blankNodesOf(this.b) {
+		if inA[node] {
+			fresh := NewAnonymousBlankNode()
+			renamed[node] = fresh
+			this.original[fresh] = node
+		}
+	}
+	if len(renamed) > 0 {
+		for i, statement := range this.b {
+			this.b[i] = substitute(statement, renamed)
+		}
+	}
+	for _, statement := range this.b {
+		this.bSet[*statement] = struct{}{}
+	}
+	return this
+}
+
+// the blank nodes of b corresponding to those of a, nil when the graphs
+// are not isomorphic
+func (this *isomorphismSearch) mapping() map[RDFTerm]RDFTerm {
+	if !this.comparable() || !this.refined().allBalanced() {
+		return nil
+	}
+	mapping := this.run(0, 0)
+	if mapping == nil {
+		return nil
+	}
+	for node, image := range mapping {
+		if previous, ok := this.original[image]; ok {
+			mapping[node] = previous
+		}
+	}
+	return mapping
+}
+
+// the same number of statements and of blank nodes, and the same
+// statements without blank nodes
+func (this *isomorphismSearch) comparable() bool {
+	if len(this.a) != len(this.b) || len(blankNodesOf(this.a)) != len(blankNodesOf(this.b)) {
+		return false
+	}
+	ground := make(map[Statement]struct{})
+	for _, statement := range this.a {
+		if !hasBlankNode(statement) {
+			ground[*statement] = struct{}{}
+		}
+	}
+	for _, statement := range this.b {
+		if hasBlankNode(statement) {
+			continue
+		}
+		if _, ok := ground[*statement]; !ok {
+			return false
+		}
+		delete(ground, *statement)
+	}
+	return len(ground) == 0
+}
+
+func (this *isomorphismSearch) refined() *colorRefiner {
+	if this.refiner == nil {
+		this.refiner = newColorRefiner(this.a, this.b)
+		this.refiner.refineAll()
+	}
+	return this.refiner
+}
+
+var individualColor = hashString("_:individual")
+
+// returns a mapping from a to b which agrees with the colors if there is
+// one, the nodes of a before from having colors of their own, nil
+// otherwise. The colors are left as they were on failure.
+func (this *isomorphismSearch) run(from int, depth int) map[RDFTerm]RDFTerm {
+	refiner := this.refiner
+	for from < refiner.first && refiner.counts[refiner.colors[from]][0] == 1 {
+		from++
+	}
+	if from == refiner.first {
+		// every color is a single node of a and of b
+		images := make(map[uint64]RDFTerm)
+		for node := refiner.first; node < len(refiner.nodes); node++ {
+			images[refiner.colors[node]] = refiner.nodes[node]
+		}
+		mapping := make(map[RDFTerm]RDFTerm)
+		for node := 0; node < refiner.first; node++ {
+			mapping[refiner.nodes[node]] = images[refiner.colors[node]]
+		}
+		if this.check(mapping) {
+			return mapping
+		}
+		return nil
+	}
+
+	// the first node of a whose color is shared is tried with every node
+	// of b of the same color
+	color := refiner.colors[from]
+	individual := combine(combine(color, individualColor), uint64(depth))
+	for candidate := refiner.first; candidate < len(refiner.nodes); candidate++ {
+		if refiner.colors[candidate] != color {
+			continue
+		}
+		mark := len(refiner.trail)
+		refiner.individualize(individual, from, candidate)
+		if refiner.refine(refiner.neighbors([]int{from, candidate})) {
+			if mapping := this.run(from+1, depth+1); mapping != nil {
+				return mapping
+			}
+		}
+		refiner.undo(mark)
+	}
+	return nil
+}
+
+// mapping turns every statement of a into a statement of b, the graphs
+// having the same size it is a bijection
+func (this *isomorphismSearch) check(mapping map[RDFTerm]RDFTerm) bool {
+	for _, statement := range this.a {
+		if _, ok := this.bSet[*substitute(statement, mapping)]; !ok {
+			return false
+		}
+	}
+	return true
+}
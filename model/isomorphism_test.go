@@ -0,0 +1,139 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import "testing"
+
+// a ring of n blank nodes linked by p
+func ring(n int, p IRI) ([]*Statement, []RDFTerm) {
+	nodes := []RDFTerm{}
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, NewAnonymousBlankNode())
+	}
+	statements := []*Statement{}
+	for i := range nodes {
+		statements = append(statements, &Statement{Subject: nodes[i], Predicate: p, Object: nodes[(i+1)%n]})
+	}
+	return statements, nodes
+}
+
+func TestIsomorphic(t *testing.T) {
+	p := IRI("http://ex.org/p")
+	x, y := &LabelledBlankNode{Label: "x"}, &LabelledBlankNode{Label: "x"}
+	a := []*Statement{
+		{Subject: IRI("http://ex.org/s"), Predicate: p, Object: x},
+		{Subject: x, Predicate: p, Object: NewStringLiteral("v")},
+		{Subject: x, Predicate: p, Object: TripleTerm{Subject: x, Predicate: p, Object: IRI("http://ex.org/o")}, Context: x},
+	}
+	b := []*Statement{
+		{Subject: y, Predicate: p, Object: TripleTerm{Subject: y, Predicate: p, Object: IRI("http://ex.org/o")}, Context: y},
+		{Subject: y, Predicate: p, Object: NewStringLiteral("v")},
+		{Subject: IRI("http://ex.org/s"), Predicate: p, Object: y},
+		{Subject: IRI("http://ex.org/s"), Predicate: p, Object: y},
+	}
+	same, mapping := Isomorphic(a, b)
+	if !same || mapping[x] != y {
+		t.Errorf("isomorphic %v, mapping %v", same, mapping)
+	}
+	b[1] = &Statement{Subject: y, Predicate: p, Object: NewStringLiteral("w")}
+	if same, _ := Isomorphic(a, b); same {
+		t.Errorf("different literals are isomorphic")
+	}
+	if same, mapping := Isomorphic(a, a); !same || mapping[x] != x {
+		t.Errorf("a graph is not isomorphic to itself: %v", mapping)
+	}
+}
+
+// rings are regular, the colors alone cannot tell their nodes apart
+func TestIsomorphicRings(t *testing.T) {
+	p := IRI("http://ex.org/p")
+	six, _ := ring(6, p)
+	other, nodes := ring(6, p)
+	// the same ring listed from another node
+	rotated := append(append([]*Statement{}, other[2:]...), other[:2]...)
+	same, mapping := Isomorphic(six, rotated)
+	if !same || len(mapping) != 6 {
+		t.Fatalf("isomorphic %v, mapping %v", same, mapping)
+	}
+	for _, statement := range six {
+		mapped := substitute(statement, mapping)
+		if mapped.Subject == mapped.Object || !contains(nodes, mapped.Subject) {
+			t.Errorf("bad mapping %v", mapping)
+		}
+	}
+	// two rings of three have the same colors as a ring of six
+	three, _ := ring(3, p)
+	another, _ := ring(3, p)
+	if same, _ := Isomorphic(six, append(three, another...)); same {
+		t.Errorf("a ring of six is two rings of three")
+	}
+}
+
+// large graphs whose colors say little
+func TestIsomorphicLarge(t *testing.T) {
+	q := IRI("http://ex.org/q")
+	alike := func() []*Statement {
+		ret := []*Statement{}
+		for i := 0; i < 5000; i++ {
+			ret = append(ret, &Statement{Subject: NewAnonymousBlankNode(), Predicate: q, Object: NewStringLiteral("1")})
+		}
+		return ret
+	}
+	if same, mapping := Isomorphic(alike(), alike()); !same || len(mapping) != 5000 {
+		t.Errorf("alike nodes: isomorphic %v, %d nodes mapped", same, len(mapping))
+	}
+	list := func() []*Statement {
+		ret := []*Statement{}
+		var rest RDFTerm = RDFNil
+		for i := 0; i < 2000; i++ {
+			node := NewAnonymousBlankNode()
+			ret = append(ret, &Statement{Subject: node, Predicate: RDFFirst, Object: NewStringLiteral("1")}, &Statement{Subject: node, Predicate: RDFRest, Object: rest})
+			rest = node
+		}
+		return ret
+	}
+	if removed, added := Diff(list(), list()); len(removed) != 0 || len(added) != 0 {
+		t.Errorf("a list differs from itself: %d removed, %d added", len(removed), len(added))
+	}
+	p := IRI("http://ex.org/p")
+	large, _ := ring(200, p)
+	half, _ := ring(100, p)
+	other, _ := ring(100, p)
+	if same, _ := Isomorphic(large, append(half, other...)); same {
+		t.Errorf("a ring of 200 is two rings of 100")
+	}
+}
+
+func contains(terms []RDFTerm, term RDFTerm) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDiff(t *testing.T) {
+	p, q := IRI("http://ex.org/p"), IRI("http://ex.org/q")
+	x, y := NewAnonymousBlankNode(), NewAnonymousBlankNode()
+	a := []*Statement{
+		{Subject: x, Predicate: p, Object: NewStringLiteral("1")},
+		{Subject: x, Predicate: q, Object: NewStringLiteral("2")},
+	}
+	b := []*Statement{
+		{Subject: y, Predicate: q, Object: NewStringLiteral("3")},
+		{Subject: y, Predicate: p, Object: NewStringLiteral("1")},
+	}
+	removed, added := Diff(a, b)
+	if len(removed) != 1 || *removed[0] != *a[1] {
+		t.Errorf("removed %v", removed)
+	}
+	if len(added) != 1 || *added[0] != (Statement{Subject: x, Predicate: q, Object: NewStringLiteral("3")}) {
+		t.Errorf("added %v", added)
+	}
+	if removed, added := Diff(a, a); len(removed) != 0 || len(added) != 0 {
+		t.Errorf("a graph differs from itself: %v %v", removed, added)
+	}
+}
@@ -0,0 +1,30 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+// Literals are values, two literals are the same term if they are ==.
+// Language and Direction are only set for rdf:langString and
+// rdf:dirLangString literals.
+type Literal struct {
+	Lexical   string
+	Datatype  IRI
+	Language  string
+	Direction string
+}
+
+func NewStringLiteral(lexical string) Literal {
+	return Literal{Lexical: lexical, Datatype: XSDString}
+}
+
+func NewTypedLiteral(lexical string, datatype IRI) Literal {
+	return Literal{Lexical: lexical, Datatype: datatype}
+}
+
+func NewLangLiteral(lexical string, language string, direction string) Literal {
+	if direction != "" {
+		return Literal{Lexical: lexical, Datatype: RDFDirLangString, Language: language, Direction: direction}
+	}
+	return Literal{Lexical: lexical, Datatype: RDFLangString, Language: language}
+}
@@ -6,6 +6,12 @@ package model
 
 type IRI string
 type Prefix string
-type A_ string
 
-const A A_ = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+// the `a` keyword of turtle
+const A IRI = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+
+// a prefix declaration, as found in turtle and TriG documents
+type Namespace struct {
+	Prefix Prefix
+	IRI    IRI
+}
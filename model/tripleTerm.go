@@ -4,4 +4,9 @@
  */
 package model
 
-type TripleTerm struct{}
+// RDF 1.2 triple terms <<( s p o )>>, they are values like literals
+type TripleTerm struct {
+	Subject   RDFTerm
+	Predicate RDFTerm
+	Object    RDFTerm
+}
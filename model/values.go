@@ -0,0 +1,569 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values
+//
+// the literals of the XSD datatypes and of rdf:langString have a value,
+// read from their lexical form. Literals whose lexical form is not one of
+// their datatype are ill-typed: they are still literals, but have no value.
+
+// the error of Value for literals whose datatype has no known value space
+var ErrUnknownDatatype = errors.New("unknown datatype")
+
+// the error of Value for ill-typed literals
+type IllTypedError struct {
+	Literal Literal
+}
+
+func (this *IllTypedError) Error() string {
+	return fmt.Sprintf("%q is not a valid %s", this.Literal.Lexical, datatypeName(this.Literal.Datatype))
+}
+
+// the value of xsd:dateTime, xsd:dateTimeStamp, xsd:date and xsd:time
+// literals. Dates have no time and times have a zero Year, Month and Day;
+// 24:00:00 is read as 00:00:00 of the next day.
+type DateTime struct {
+	Year, Month, Day int
+	Hour, Minute     int
+	Second           *big.Rat
+	// the offset of the time zone in minutes, when there is one
+	Timezone    int
+	HasTimezone bool
+}
+
+// Time returns the instant of a date and time: dates are taken at
+// midnight, times on 1972-12-31 as XSD does and values without time zone
+// in UTC. Fractions of nanoseconds are truncated.
+func (this DateTime) Time() time.Time {
+	year, month, day := this.Year, this.Month, this.Day
+	if month == 0 {
+		year, month, day = 1972, 12, 31
+	}
+	zone := time.UTC
+	if this.HasTimezone {
+		zone = time.FixedZone("", this.Timezone*60)
+	}
+	nanoseconds := new(big.Rat).Mul(this.Second, big.NewRat(1e9, 1))
+	whole := new(big.Int).Quo(nanoseconds.Num(), nanoseconds.Denom())
+	return time.Date(year, time.Month(month), day, this.Hour, this.Minute, 0, int(whole.Int64()), zone)
+}
+
+// the value of xsd:duration literals and of their derived types, a number
+// of months and a number of seconds, both negative for negative durations
+type Duration struct {
+	Months  int64
+	Seconds *big.Rat
+}
+
+// Value returns the value of a literal:
+//
+//	xsd:integer and its derived types            *big.Int
+//	xsd:decimal                                   *big.Rat
+//	xsd:double                                    float64
+//	xsd:float                                     float32
+//	xsd:boolean                                   bool
+//	xsd:dateTime, dateTimeStamp, date and time    DateTime
+//	xsd:duration, dayTimeDuration and
+//	yearMonthDuration                             Duration
+//	xsd:hexBinary and base64Binary                []byte
+//	xsd:string, normalizedString, token,
+//	language, anyURI and rdf:langString           string
+//
+// The error is an *IllTypedError for ill-typed literals and
+// ErrUnknownDatatype for the other datatypes.
+func (this Literal) Value() (interface{}, error) {
+	datatype, ok := datatypes[this.Datatype]
+	if !ok {
+		return nil, ErrUnknownDatatype
+	}
+	value, ok := datatype.parse(this.Lexical)
+	switch this.Datatype {
+	case RDFLangString:
+		ok = this.Language != ""
+	case RDFDirLangString:
+		ok = this.Language != "" && (this.Direction == "ltr" || this.Direction == "rtl")
+	}
+	if !ok {
+		return nil, &IllTypedError{Literal: this}
+	}
+	return value, nil
+}
+
+// IllTyped tells whether a literal of a known datatype has a lexical form
+// which is not one of its datatype
+func (this Literal) IllTyped() bool {
+	_, err := this.Value()
+	return err != nil && err != ErrUnknownDatatype
+}
+
+// Canonical returns the literal with the canonical lexical form of its
+// value, as "1"^^xsd:integer for "+01"^^xsd:integer, or the literal itself
+// when it is ill-typed or its datatype unknown
+func (this Literal) Canonical() Literal {
+	value, err := this.Value()
+	if err != nil {
+		return this
+	}
+	this.Lexical = datatypes[this.Datatype].format(value)
+	return this
+}
+
+// xsd:integer for XSD datatypes, <iri> for the others
+func datatypeName(datatype IRI) string {
+	switch {
+	case strings.HasPrefix(string(datatype), string(XSD)):
+		return "xsd:" + string(datatype[len(XSD):])
+	case strings.HasPrefix(string(datatype), string(RDF)):
+		return "rdf:" + string(datatype[len(RDF):])
+	}
+	return "<" + string(datatype) + ">"
+}
+
+type datatype struct {
+	// the value of a lexical form, false when the form is not valid
+	parse func(lexical string) (interface{}, bool)
+	// the canonical lexical form of a value
+	format func(value interface{}) string
+}
+
+var datatypes = map[IRI]datatype{
+	XSDInteger:                 integerDatatype(nil, nil),
+	XSD + "nonPositiveInteger": integerDatatype(nil, big.NewInt(0)),
+	XSD + "negativeInteger":    integerDatatype(nil, big.NewInt(-1)),
+	XSD + "nonNegativeInteger": integerDatatype(big.NewInt(0), nil),
+	XSD + "positiveInteger":    integerDatatype(big.NewInt(1), nil),
+	XSD + "long":               integerDatatype(big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)),
+	XSD + "int":                integerDatatype(big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)),
+	XSD + "short":              integerDatatype(big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)),
+	XSD + "byte":               integerDatatype(big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)),
+	XSD + "unsignedLong":       integerDatatype(big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)),
+	XSD + "unsignedInt":        integerDatatype(big.NewInt(0), big.NewInt(math.MaxUint32)),
+	XSD + "unsignedShort":      integerDatatype(big.NewInt(0), big.NewInt(math.MaxUint16)),
+	XSD + "unsignedByte":       integerDatatype(big.NewInt(0), big.NewInt(math.MaxUint8)),
+	XSDDecimal: {parseDecimal, func(value interface{}) string {
+		return formatDecimal(value.(*big.Rat))
+	}},
+	XSDDouble: {parseDouble, func(value interface{}) string {
+		return formatDouble(value.(float64), 64)
+	}},
+	XSDFloat: {parseFloat, func(value interface{}) string {
+		return formatDouble(float64(value.(float32)), 32)
+	}},
+	XSDBoolean: {parseBoolean, func(value interface{}) string {
+		return strconv.FormatBool(value.(bool))
+	}},
+	XSDDateTime:           {dateTimeParser(true, true, false), dateTimeFormatter(true, true)},
+	XSD + "dateTimeStamp": {dateTimeParser(true, true, true), dateTimeFormatter(true, true)},
+	XSDDate:               {dateTimeParser(true, false, false), dateTimeFormatter(true, false)},
+	XSDTime:               {dateTimeParser(false, true, false), dateTimeFormatter(false, true)},
+	XSDDuration:           {durationParser(true, true), durationFormatter("PT0S")},
+	XSDDayTimeDuration:    {durationParser(false, true), durationFormatter("PT0S")},
+	XSDYearMonthDuration:  {durationParser(true, false), durationFormatter("P0M")},
+	XSDHexBinary: {parseHexBinary, func(value interface{}) string {
+		return strings.ToUpper(hex.EncodeToString(value.([]byte)))
+	}},
+	XSDBase64Binary: {parseBase64Binary, func(value interface{}) string {
+		return base64.StdEncoding.EncodeToString(value.([]byte))
+	}},
+	XSDString:                stringDatatype(nil),
+	XSD + "normalizedString": stringDatatype(normalizedString),
+	XSD + "token":            stringDatatype(token),
+	XSD + "language":         stringDatatype(languagePattern.MatchString),
+	XSD + "anyURI":           stringDatatype(nil),
+	RDFLangString:            stringDatatype(nil),
+	RDFDirLangString:         stringDatatype(nil),
+}
+
+var (
+	integerPattern  = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	decimalPattern  = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+	doublePattern   = regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`)
+	languagePattern = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+	// years, months, days, hours, minutes and seconds, the time zone apart
+	datePattern     = `(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})`
+	timePattern     = `([0-9]{2}):([0-9]{2}):([0-9]{2}(?:\.[0-9]+)?)`
+	timezonePattern = `(Z|[+-][0-9]{2}:[0-9]{2})?`
+	durationPattern = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(?:T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+(?:\.[0-9]*)?|\.[0-9]+)S)?)?$`)
+)
+
+var ten = big.NewRat(10, 1)
+
+func integerDatatype(minimum, maximum *big.Int) datatype {
+	return datatype{
+		parse: func(lexical string) (interface{}, bool) {
+			if !integerPattern.MatchString(lexical) {
+				return nil, false
+			}
+			value, _ := new(big.Int).SetString(strings.TrimPrefix(lexical, "+"), 10)
+			if minimum != nil && value.Cmp(minimum) < 0 || maximum != nil && value.Cmp(maximum) > 0 {
+				return nil, false
+			}
+			return value, true
+		},
+		format: func(value interface{}) string {
+			return value.(*big.Int).String()
+		},
+	}
+}
+
+func parseDecimal(lexical string) (interface{}, bool) {
+	if !decimalPattern.MatchString(lexical) {
+		return nil, false
+	}
+	value, _ := new(big.Rat).SetString(strings.TrimPrefix(lexical, "+"))
+	return value, true
+}
+
+// the digits of a decimal, with at least one after the point
+func formatDecimal(value *big.Rat) string {
+	ret := decimalDigits(value)
+	if !strings.ContainsRune(ret, '.') {
+		ret += ".0"
+	}
+	return ret
+}
+
+// the shortest digits of a rational which has a finite decimal expansion,
+// without a point for integers
+func decimalDigits(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String()
+	}
+	digits := 0
+	for scaled := new(big.Rat).Set(value); !scaled.IsInt(); scaled.Mul(scaled, ten) {
+		digits++
+	}
+	return value.FloatString(digits)
+}
+
+// INF, -INF, NaN and the values too large for a float64 are infinities
+func parseDouble(lexical string) (interface{}, bool) {
+	value, ok := parseFloatBits(lexical, 64)
+	return value, ok
+}
+
+func parseFloat(lexical string) (interface{}, bool) {
+	value, ok := parseFloatBits(lexical, 32)
+	return float32(value), ok
+}
+
+func parseFloatBits(lexical string, bits int) (float64, bool) {
+	if !doublePattern.MatchString(lexical) {
+		return 0, false
+	}
+	switch lexical {
+	case "INF", "+INF":
+		return math.Inf(1), true
+	case "-INF":
+		return math.Inf(-1), true
+	case "NaN":
+		return math.NaN(), true
+	}
+	value, err := strconv.ParseFloat(lexical, bits)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return value, true
+}
+
+// the canonical form of doubles, as in 1.5E1, INF or NaN
+func formatDouble(value float64, bits int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "INF"
+	case math.IsInf(value, -1):
+		return "-INF"
+	}
+	ret := strconv.FormatFloat(value, 'E', -1, bits)
+	mantissa, exponent := ret, "0"
+	if i := strings.IndexByte(ret, 'E'); i >= 0 {
+		mantissa, exponent = ret[:i], ret[i+1:]
+	}
+	if !strings.ContainsRune(mantissa, '.') {
+		mantissa += ".0"
+	}
+	number, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(number)
+}
+
+func parseBoolean(lexical string) (interface{}, bool) {
+	switch lexical {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	}
+	return nil, false
+}
+
+// the parser of a datatype of dates and times, which have a date, a time
+// or both, and whose time zone may be required
+func dateTimeParser(hasDate, hasTime, timezoneRequired bool) func(string) (interface{}, bool) {
+	pattern := datePattern + "T" + timePattern
+	switch {
+	case !hasTime:
+		pattern = datePattern
+	case !hasDate:
+		pattern = timePattern
+	}
+	expression := regexp.MustCompile("^" + pattern + timezonePattern + "$")
+	return func(lexical string) (interface{}, bool) {
+		parts := expression.FindStringSubmatch(lexical)
+		if parts == nil {
+			return nil, false
+		}
+		ret := DateTime{Second: new(big.Rat)}
+		parts = parts[1:]
+		if hasDate {
+			if !ret.readDate(parts[0], parts[1], parts[2]) {
+				return nil, false
+			}
+			parts = parts[3:]
+		}
+		if hasTime {
+			if !ret.readTime(parts[0], parts[1], parts[2]) {
+				return nil, false
+			}
+			parts = parts[3:]
+		}
+		if !ret.readTimezone(parts[0]) || timezoneRequired && !ret.HasTimezone {
+			return nil, false
+		}
+		if ret.Hour == 24 {
+			ret.Hour = 0
+			if hasDate {
+				next := time.Date(ret.Year, time.Month(ret.Month), ret.Day+1, 0, 0, 0, 0, time.UTC)
+				ret.Year, ret.Month, ret.Day = next.Year(), int(next.Month()), next.Day()
+			}
+		}
+		return ret, true
+	}
+}
+
+// years of more than four digits have no leading zero, the days are those
+// of the month, 29 February in leap years only
+func (this *DateTime) readDate(year, month, day string) bool {
+	if digits := strings.TrimPrefix(year, "-"); len(digits) > 4 && digits[0] == '0' {
+		return false
+	}
+	var err error
+	if this.Year, err = strconv.Atoi(year); err != nil {
+		return false
+	}
+	this.Month, _ = strconv.Atoi(month)
+	this.Day, _ = strconv.Atoi(day)
+	if this.Month < 1 || this.Month > 12 {
+		return false
+	}
+	// day 0 of the next month is the last of this one
+	last := time.Date(this.Year, time.Month(this.Month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	return this.Day >= 1 && this.Day <= last
+}
+
+// 24:00:00 is the only time with 24 hours
+func (this *DateTime) readTime(hour, minute, second string) bool {
+	this.Hour, _ = strconv.Atoi(hour)
+	this.Minute, _ = strconv.Atoi(minute)
+	this.Second.SetString(second)
+	if this.Hour > 24 || this.Minute > 59 || this.Second.Cmp(big.NewRat(60, 1)) >= 0 {
+		return false
+	}
+	return this.Hour < 24 || this.Minute == 0 && this.Second.Sign() == 0
+}
+
+// time zones are between -14:00 and +14:00
+func (this *DateTime) readTimezone(timezone string) bool {
+	switch timezone {
+	case "":
+		return true
+	case "Z":
+		this.HasTimezone = true
+		return true
+	}
+	hours, _ := strconv.Atoi(timezone[1:3])
+	minutes, _ := strconv.Atoi(timezone[4:])
+	if minutes > 59 || hours*60+minutes > 14*60 {
+		return false
+	}
+	this.Timezone, this.HasTimezone = hours*60+minutes, true
+	if timezone[0] == '-' {
+		this.Timezone = -this.Timezone
+	}
+	return true
+}
+
+// the canonical form of a datatype of dates and times, the time zone is
+// kept
+func dateTimeFormatter(hasDate, hasTime bool) func(interface{}) string {
+	return func(value interface{}) string {
+		dateTime := value.(DateTime)
+		ret := ""
+		if hasDate {
+			year := fmt.Sprintf("%04d", dateTime.Year)
+			if dateTime.Year < 0 {
+				year = fmt.Sprintf("-%04d", -dateTime.Year)
+			}
+			ret = fmt.Sprintf("%s-%02d-%02d", year, dateTime.Month, dateTime.Day)
+		}
+		if hasDate && hasTime {
+			ret += "T"
+		}
+		if hasTime {
+			second := decimalDigits(dateTime.Second)
+			if dateTime.Second.Cmp(ten) < 0 {
+				second = "0" + second
+			}
+			ret += fmt.Sprintf("%02d:%02d:%s", dateTime.Hour, dateTime.Minute, second)
+		}
+		if !dateTime.HasTimezone {
+			return ret
+		}
+		if dateTime.Timezone == 0 {
+			return ret + "Z"
+		}
+		offset, sign := dateTime.Timezone, "+"
+		if offset < 0 {
+			offset, sign = -offset, "-"
+		}
+		return ret + fmt.Sprintf("%s%02d:%02d", sign, offset/60, offset%60)
+	}
+}
+
+// the parser of xsd:duration and of its derived types, which only have
+// months or only seconds
+func durationParser(hasMonths, hasSeconds bool) func(string) (interface{}, bool) {
+	return func(lexical string) (interface{}, bool) {
+		parts := durationPattern.FindStringSubmatch(lexical)
+		// P alone and a T without hours, minutes or seconds are invalid
+		if parts == nil || strings.HasSuffix(lexical, "P") || strings.HasSuffix(lexical, "T") {
+			return nil, false
+		}
+		if !hasMonths && (parts[2] != "" || parts[3] != "") || !hasSeconds && strings.Join(parts[4:], "") != "" {
+			return nil, false
+		}
+		months := new(big.Int)
+		for i, factor := range []int64{12, 1} {
+			if parts[2+i] != "" {
+				component, _ := new(big.Int).SetString(parts[2+i], 10)
+				months.Add(months, component.Mul(component, big.NewInt(factor)))
+			}
+		}
+		if !months.IsInt64() {
+			return nil, false
+		}
+		seconds := new(big.Rat)
+		for i, factor := range []int64{86400, 3600, 60, 1} {
+			if parts[4+i] != "" {
+				component, _ := new(big.Rat).SetString(parts[4+i])
+				seconds.Add(seconds, component.Mul(component, big.NewRat(factor, 1)))
+			}
+		}
+		ret := Duration{Months: months.Int64(), Seconds: seconds}
+		if parts[1] == "-" {
+			ret.Months, ret.Seconds = -ret.Months, seconds.Neg(seconds)
+		}
+		return ret, true
+	}
+}
+
+// the canonical form of durations, zero being written as zero
+func durationFormatter(zero string) func(interface{}) string {
+	return func(value interface{}) string {
+		duration := value.(Duration)
+		if duration.Months == 0 && duration.Seconds.Sign() == 0 {
+			return zero
+		}
+		ret := "P"
+		months, seconds := duration.Months, new(big.Rat).Abs(duration.Seconds)
+		if months < 0 || duration.Seconds.Sign() < 0 {
+			ret = "-P"
+		}
+		if months < 0 {
+			months = -months
+		}
+		if months/12 != 0 {
+			ret += strconv.FormatInt(months/12, 10) + "Y"
+		}
+		if months%12 != 0 {
+			ret += strconv.FormatInt(months%12, 10) + "M"
+		}
+		if seconds.Sign() == 0 {
+			return ret
+		}
+		// whole days, hours and minutes, then the rest of the seconds
+		whole := new(big.Int).Quo(seconds.Num(), seconds.Denom())
+		fraction := new(big.Rat).Sub(seconds, new(big.Rat).SetInt(whole))
+		days, rest := new(big.Int).QuoRem(whole, big.NewInt(86400), new(big.Int))
+		if days.Sign() != 0 {
+			ret += days.String() + "D"
+		}
+		if rest.Sign() == 0 && fraction.Sign() == 0 {
+			return ret
+		}
+		ret += "T"
+		total := rest.Int64()
+		if total/3600 != 0 {
+			ret += strconv.FormatInt(total/3600, 10) + "H"
+		}
+		if total%3600/60 != 0 {
+			ret += strconv.FormatInt(total%3600/60, 10) + "M"
+		}
+		if second := new(big.Rat).Add(big.NewRat(total%60, 1), fraction); second.Sign() != 0 {
+			ret += decimalDigits(second) + "S"
+		}
+		return ret
+	}
+}
+
+// an even number of hexadecimal digits, in either case
+func parseHexBinary(lexical string) (interface{}, bool) {
+	value, err := hex.DecodeString(lexical)
+	return value, err == nil
+}
+
+// spaces may separate the characters
+func parseBase64Binary(lexical string) (interface{}, bool) {
+	value, err := base64.StdEncoding.Strict().DecodeString(strings.ReplaceAll(lexical, " ", ""))
+	return value, err == nil
+}
+
+// the datatypes whose value is their lexical form, which valid checks
+// when not nil
+func stringDatatype(valid func(string) bool) datatype {
+	return datatype{
+		parse: func(lexical string) (interface{}, bool) {
+			return lexical, valid == nil || valid(lexical)
+		},
+		format: func(value interface{}) string {
+			return value.(string)
+		},
+	}
+}
+
+// without carriage returns, line feeds and tabs
+func normalizedString(lexical string) bool {
+	return !strings.ContainsAny(lexical, "\r\n\t")
+}
+
+// normalized, without leading, trailing nor consecutive spaces
+func token(lexical string) bool {
+	return normalizedString(lexical) && strings.TrimSpace(lexical) == lexical && !strings.Contains(lexical, "  ")
+}
@@ -0,0 +1,136 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCanonical(t *testing.T) {
+	for _, c := range []struct {
+		lexical   string
+		datatype  IRI
+		canonical string
+	}{
+		{"+007", XSDInteger, "7"},
+		{"-0", XSDInteger, "0"},
+		{"18446744073709551615", XSD + "unsignedLong", "18446744073709551615"},
+		{"01.50", XSDDecimal, "1.5"},
+		{"-.5", XSDDecimal, "-0.5"},
+		{"3", XSDDecimal, "3.0"},
+		{"15", XSDDouble, "1.5E1"},
+		{"-INF", XSDDouble, "-INF"},
+		{"0.1", XSDFloat, "1.0E-1"},
+		{"1", XSDBoolean, "true"},
+		{"2024-02-29T24:00:00+00:00", XSDDateTime, "2024-03-01T00:00:00Z"},
+		{"12345-01-01T10:05:07.250-05:30", XSDDateTime, "12345-01-01T10:05:07.25-05:30"},
+		{"-0044-03-15", XSDDate, "-0044-03-15"},
+		{"23:59:09.0", XSDTime, "23:59:09"},
+		{"P1Y14M2DT36H0.5S", XSDDuration, "P2Y2M3DT12H0.5S"},
+		{"-PT90M", XSDDayTimeDuration, "-PT1H30M"},
+		{"P0Y", XSDYearMonthDuration, "P0M"},
+		{"P0D", XSDDuration, "PT0S"},
+		{"0fb7", XSDHexBinary, "0FB7"},
+		{"Zm9v YmFy", XSDBase64Binary, "Zm9vYmFy"},
+		{" a ", XSDString, " a "},
+		{"x", XSD + "unknown", "x"},
+	} {
+		literal := NewTypedLiteral(c.lexical, c.datatype)
+		if literal.IllTyped() {
+			t.Errorf("%s is ill-typed", c.lexical)
+		}
+		if got := literal.Canonical(); got != NewTypedLiteral(c.canonical, c.datatype) {
+			t.Errorf("%s: canonical form %s instead of %s", c.lexical, got.Lexical, c.canonical)
+		}
+	}
+}
+
+func TestIllTyped(t *testing.T) {
+	for _, literal := range []Literal{
+		NewTypedLiteral("1.0", XSDInteger),
+		NewTypedLiteral("0x10", XSDInteger),
+		NewTypedLiteral("256", XSD+"unsignedByte"),
+		NewTypedLiteral("-1", XSD+"nonNegativeInteger"),
+		NewTypedLiteral("1e3", XSDDecimal),
+		NewTypedLiteral("inf", XSDDouble),
+		NewTypedLiteral("yes", XSDBoolean),
+		NewTypedLiteral("2023-02-29", XSDDate),
+		NewTypedLiteral("02024-01-01", XSDDate),
+		NewTypedLiteral("2024-01-01T24:00:01", XSDDateTime),
+		NewTypedLiteral("2024-01-01T10:00:00", XSD+"dateTimeStamp"),
+		NewTypedLiteral("10:00:00+14:30", XSDTime),
+		NewTypedLiteral("P", XSDDuration),
+		NewTypedLiteral("P1DT", XSDDuration),
+		NewTypedLiteral("P1Y", XSDDayTimeDuration),
+		NewTypedLiteral("PT1H", XSDYearMonthDuration),
+		NewTypedLiteral("abc", XSDHexBinary),
+		NewTypedLiteral("Zm9=", XSDBase64Binary),
+		NewTypedLiteral(" a", XSD+"token"),
+		NewTypedLiteral("a", RDFLangString),
+	} {
+		if !literal.IllTyped() {
+			t.Errorf("%q^^<%s> is well typed", literal.Lexical, literal.Datatype)
+		}
+		if _, err := literal.Value(); err == nil {
+			t.Errorf("%q^^<%s> has a value", literal.Lexical, literal.Datatype)
+		}
+	}
+	if _, err := NewTypedLiteral("a", "http://ex.org/unknown").Value(); err != ErrUnknownDatatype {
+		t.Errorf("error %v for an unknown datatype", err)
+	}
+	if _, err := NewTypedLiteral("1.5", XSDInteger).Value(); err == nil || err.Error() != `"1.5" is not a valid xsd:integer` {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	value := func(lexical string, datatype IRI) interface{} {
+		t.Helper()
+		ret, err := NewTypedLiteral(lexical, datatype).Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ret
+	}
+	if got := value("-12", XSDInteger); got.(*big.Int).Cmp(big.NewInt(-12)) != 0 {
+		t.Errorf("integer %v", got)
+	}
+	if got := value("1.25", XSDDecimal); got.(*big.Rat).Cmp(big.NewRat(5, 4)) != 0 {
+		t.Errorf("decimal %v", got)
+	}
+	if got := value("1E400", XSDDouble); !math.IsInf(got.(float64), 1) {
+		t.Errorf("double %v", got)
+	}
+	if got := value("0.1", XSDFloat); got != interface{}(float32(0.1)) {
+		t.Errorf("float %v", got)
+	}
+	if got := value("true", XSDBoolean); got != interface{}(true) {
+		t.Errorf("boolean %v", got)
+	}
+	if got := value("cafe", XSDHexBinary); !reflect.DeepEqual(got, []byte{0xca, 0xfe}) {
+		t.Errorf("hexBinary %v", got)
+	}
+	if got := value("-P1M1DT1.5S", XSDDuration).(Duration); got.Months != -1 || got.Seconds.Cmp(big.NewRat(-172803, 2)) != 0 {
+		t.Errorf("duration %v %v", got.Months, got.Seconds)
+	}
+	dateTime := value("2024-05-01T12:30:15.5+02:00", XSDDateTime).(DateTime)
+	if dateTime.Year != 2024 || dateTime.Month != 5 || dateTime.Day != 1 || dateTime.Hour != 12 || dateTime.Minute != 30 ||
+		dateTime.Second.Cmp(big.NewRat(31, 2)) != 0 || !dateTime.HasTimezone || dateTime.Timezone != 120 {
+		t.Errorf("dateTime %+v", dateTime)
+	}
+	if instant := dateTime.Time(); !instant.Equal(time.Date(2024, 5, 1, 10, 30, 15, 5e8, time.UTC)) {
+		t.Errorf("instant %v", instant)
+	}
+	if instant := value("08:00:00", XSDTime).(DateTime).Time(); !instant.Equal(time.Date(1972, 12, 31, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("time %v", instant)
+	}
+	if got, err := NewLangLiteral("chat", "fr", "").Value(); err != nil || got != "chat" {
+		t.Errorf("language string %v, %v", got, err)
+	}
+}
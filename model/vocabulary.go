@@ -0,0 +1,51 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package model
+
+// namespaces
+
+const RDF IRI = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+const RDFS IRI = "http://www.w3.org/2000/01/rdf-schema#"
+const XSD IRI = "http://www.w3.org/2001/XMLSchema#"
+
+// rdf vocabulary
+
+const RDFFirst IRI = RDF + "first"
+const RDFRest IRI = RDF + "rest"
+const RDFNil IRI = RDF + "nil"
+const RDFReifies IRI = RDF + "reifies"
+const RDFLangString IRI = RDF + "langString"
+const RDFDirLangString IRI = RDF + "dirLangString"
+
+// xsd datatypes
+
+const XSDString IRI = XSD + "string"
+const XSDBoolean IRI = XSD + "boolean"
+const XSDInteger IRI = XSD + "integer"
+const XSDDecimal IRI = XSD + "decimal"
+const XSDDouble IRI = XSD + "double"
+const XSDFloat IRI = XSD + "float"
+const XSDDateTime IRI = XSD + "dateTime"
+const XSDDate IRI = XSD + "date"
+const XSDTime IRI = XSD + "time"
+const XSDDuration IRI = XSD + "duration"
+const XSDDayTimeDuration IRI = XSD + "dayTimeDuration"
+const XSDYearMonthDuration IRI = XSD + "yearMonthDuration"
+const XSDHexBinary IRI = XSD + "hexBinary"
+const XSDBase64Binary IRI = XSD + "base64Binary"
+
+// rdfs vocabulary
+
+const RDFProperty IRI = RDF + "Property"
+const RDFSResource IRI = RDFS + "Resource"
+const RDFSClass IRI = RDFS + "Class"
+const RDFSLiteral IRI = RDFS + "Literal"
+const RDFSDatatype IRI = RDFS + "Datatype"
+const RDFSSubClassOf IRI = RDFS + "subClassOf"
+const RDFSSubPropertyOf IRI = RDFS + "subPropertyOf"
+const RDFSDomain IRI = RDFS + "domain"
+const RDFSRange IRI = RDFS + "range"
+const RDFSMember IRI = RDFS + "member"
+const RDFSContainerMembershipProperty IRI = RDFS + "ContainerMembershipProperty"
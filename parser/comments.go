@@ -0,0 +1,68 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package parser
+
+import "github.com/nfreundl/rdf-tools/model"
+
+type CommentPlacement int
+
+const (
+	// before the first statement, with the directives
+	HeaderComment CommentPlacement = iota
+	// inside a statement, before it or at the end of its last line
+	SubjectComment
+	// after the last statement
+	FooterComment
+)
+
+// comments are attached to the subject of the statement they are in or
+// which follows them, a comment at the end of the line of a statement goes
+// with that statement
+type Comment struct {
+	// with its leading #
+	Text      string
+	Line      int
+	Placement CommentPlacement
+	// the subject and graph of the statement for SubjectComment
+	Subject model.RDFTerm
+	Graph   model.RDFTerm
+}
+
+// the comments of the document in order, only collected with the Comments
+// option and only meaningful once Statements() is closed
+func (this *Parser) Comments() []*Comment {
+	return this.comments
+}
+
+// token was read after curToken
+func (this *Parser) addComment(token *Token) {
+	switch {
+	case this.curSubject != nil:
+		this.comments = append(this.comments, &Comment{Text: token.value, Line: token.line, Placement: SubjectComment, Subject: this.curSubject, Graph: this.curGraph})
+	case this.lastSubject != nil && this.curToken != nil && this.curToken.line == token.line && len(this.pendingComments) == 0:
+		this.comments = append(this.comments, &Comment{Text: token.value, Line: token.line, Placement: SubjectComment, Subject: this.lastSubject, Graph: this.lastGraph})
+	default:
+		this.pendingComments = append(this.pendingComments, token)
+	}
+}
+
+// the subject of a top level statement is known
+func (this *Parser) setSubject(subject model.RDFTerm) {
+	this.curSubject = subject
+	this.seenTriples = true
+	this.flushComments(SubjectComment, subject)
+}
+
+func (this *Parser) flushComments(placement CommentPlacement, subject model.RDFTerm) {
+	for _, token := range this.pendingComments {
+		comment := &Comment{Text: token.value, Line: token.line, Placement: placement}
+		if placement == SubjectComment {
+			comment.Subject = subject
+			comment.Graph = this.curGraph
+		}
+		this.comments = append(this.comments, comment)
+	}
+	this.pendingComments = this.pendingComments[:0]
+}
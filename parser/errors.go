@@ -0,0 +1,25 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package parser
+
+import "fmt"
+
+type SyntaxError struct {
+	Line    int
+	Col     int
+	Message string
+}
+
+func newSyntaxError(token *Token, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Line:    token.line,
+		Col:     token.col,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (this *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", this.Line, this.Col, this.Message)
+}
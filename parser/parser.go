@@ -5,269 +5,846 @@
 package parser
 
 import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/format"
 	"github.com/nfreundl/rdf-tools/model"
 )
 
+type Options struct {
+	Format format.Format
+	// relative IRIs are resolved against Base until a base directive is met
+	Base model.IRI
+	// keep going after syntax errors, the parser skips to the next '.'
+	// outside any bracket and carries on from there
+	ContinueOnError bool
+	// collect the comments, see Comments()
+	Comments bool
+	// the terms of the statements are interned in Dictionary, repeated
+	// terms then share their memory
+	Dictionary *model.Dictionary
+	// ill-typed literals, whose lexical form is not one of their datatype,
+	// are syntax errors
+	CheckLiterals bool
+}
+
 type Parser struct {
 
 	// source and target
 	source <-chan *Token
-	target chan<- *model.Statement
+	// the error of the reader, sent once the tokens are all read
+	readErrors <-chan error
+	target     chan<- *model.Statement
+	statements <-chan *model.Statement
+
+	options Options
 
 	// states
-	baseUri       model.IRI
-	namespaces    map[model.Prefix]model.IRI
-	bnodeLabels   map[string]*model.LabelledBlankNode
-	curSubject    model.RDFTerm
-	curPredicate  model.RDFTerm
-	curObject     model.RDFTerm
-	curReifier    model.RDFTerm
-	curTripleTerm model.TripleTerm
-	curGraph      model.RDFTerm
+	baseUri model.IRI
+	// set by the base directives only
+	declaredBase model.IRI
+	namespaces   map[model.Prefix]model.IRI
+	prefixes     []model.Prefix
+	bnodeLabels  map[string]*model.LabelledBlankNode
+	curToken     *Token
+	curGraph     model.RDFTerm
+	// how many brackets are open at curToken
+	depth int
+
+	// the subject of the statement being read, and of the previous one
+	curSubject  model.RDFTerm
+	lastSubject model.RDFTerm
+	lastGraph   model.RDFTerm
+	seenTriples bool
+	comments    []*Comment
+	// comments waiting for the subject of the next statement
+	pendingComments []*Token
+	// the patterns of the formula being read, rules only
+	patterns *[]*model.Statement
+
+	errors []*SyntaxError
+	// the reader failed before the end of the input
+	readErr error
 }
 
 func newParser(source <-chan *Token, target chan<- *model.Statement) *Parser {
 	return &Parser{
-		source: source,
-		target: target,
-		// all the rest is nil !
+		source:      source,
+		target:      target,
+		namespaces:  make(map[model.Prefix]model.IRI),
+		bnodeLabels: make(map[string]*model.LabelledBlankNode),
+	}
+
+}
+
+// Parse reads reader in the background. Statements are sent on
+// Statements() which is closed at the end of the input, at the first
+// syntax error or when reader fails, Err() tells which. With
+// ContinueOnError the statements which could be read are sent and Errors()
+// tells what went wrong.
+func Parse(reader io.Reader, options Options) *Parser {
+	tokens := make(chan *Token, 64)
+	statements := make(chan *model.Statement, 64)
+
+	runes, readErrors := NewRuneReader(reader, 4096, 1024, 1024)
+	tokenizer := NewTokenizer(runes, tokens)
+	tokenizer.comments = options.Comments
+	this := newParser(tokens, statements)
+	this.readErrors = readErrors
+	this.statements = statements
+	this.options = options
+	this.baseUri = options.Base
+
+	go tokenizer.run()
+	go this.run()
+	return this
+}
+
+// ParseAll reads all the statements of reader
+func ParseAll(reader io.Reader, options Options) ([]*model.Statement, error) {
+	this := Parse(reader, options)
+	ret := []*model.Statement{}
+	for statement := range this.Statements() {
+		ret = append(ret, statement)
 	}
+	return ret, this.Err()
+}
+
+func (this *Parser) Statements() <-chan *model.Statement {
+	return this.statements
+}
+
+// the error which stopped the parsing, or the first one with
+// ContinueOnError, only meaningful once Statements() is closed. The error of
+// the reader comes first, the syntax errors may only be due to it.
+func (this *Parser) Err() error {
+	if this.readErr != nil {
+		return this.readErr
+	}
+	if len(this.errors) == 0 {
+		return nil
+	}
+	return this.errors[0]
+}
+
+// all the syntax errors met, only meaningful once Statements() is closed
+func (this *Parser) Errors() []*SyntaxError {
+	return this.errors
+}
+
+// the prefixes declared in the document in declaration order, only
+// meaningful once Statements() is closed
+func (this *Parser) Namespaces() []model.Namespace {
+	ret := make([]model.Namespace, 0, len(this.prefixes))
+	for _, prefix := range this.prefixes {
+		ret = append(ret, model.Namespace{Prefix: prefix, IRI: this.namespaces[prefix]})
+	}
+	return ret
+}
 
+// the IRI of the last base directive of the document, empty without one
+func (this *Parser) Base() model.IRI {
+	return this.declaredBase
 }
 
 func (this *Parser) run() {
 	defer close(this.target)
-	val := <-this.source
-	for {
-
-		if val.tokenType == Dot {
-			this.target <- &model.Statement{
-				Subject:   this.curSubject,
-				Object:    this.curObject,
-				Predicate: this.curPredicate,
-				Context:   this.curGraph,
+	defer this.readError()
+	this.advance()
+	for this.curToken.tokenType != EOF {
+		start := this.curToken
+		err := this.statementOrError()
+		this.curSubject = nil
+		if err != nil {
+			this.errors = append(this.errors, err)
+			if !this.options.ContinueOnError {
+				// let the tokenizer finish
+				for range this.source {
+				}
+				return
 			}
-			this.curSubject = nil
-			this.curPredicate = nil
-			this.curObject = nil
-		} else if val.tokenType == SemiColumn {
-			this.target <- &model.Statement{
-				Subject:   this.curSubject,
-				Object:    this.curObject,
-				Predicate: this.curPredicate,
-				Context:   this.curGraph,
+			if this.curToken == start {
+				// make sure to move on
+				this.advance()
 			}
+			this.resync()
+		}
+	}
+	this.flushComments(FooterComment, nil)
+}
+
+// waits for the error of the reader, the tokens being all read
+func (this *Parser) readError() {
+	if this.readErrors != nil {
+		this.readErr = <-this.readErrors
+	}
+	// invalid UTF-8 is a syntax error of the document, the errors met at
+	// the end of the runes may only be due to it
+	if syntaxError, ok := this.readErr.(*SyntaxError); ok {
+		this.errors = append([]*SyntaxError{syntaxError}, this.errors...)
+	}
+}
 
-			this.curPredicate = nil
-			this.curObject = nil
-		} else if val.tokenType == Coma {
-			this.target <- &model.Statement{
-				Subject:   this.curSubject,
-				Object:    this.curObject,
-				Predicate: this.curPredicate,
-				Context:   this.curGraph,
+// skips to the next '.' outside any bracket, errors on the way are not
+// reported. Directives and the end of a TriG block are resynchronization
+// points too.
+func (this *Parser) resync() {
+	this.curGraph = nil
+	for this.curToken.tokenType != EOF {
+		switch this.curToken.tokenType {
+		case PrefixTag, BaseTag, VersionTag:
+			this.depth = 0
+			return
+		case Dot:
+			if this.depth <= 0 {
+				this.advance()
+				this.depth = 0
+				return
 			}
+		case GraphClosing:
+			if this.depth <= 0 {
+				this.advance()
+				this.depth = 0
+				return
+			}
+		}
+		this.advance()
+	}
+}
 
-			this.curObject = nil
-		} else if val.tokenType == BlankNodeClosing {
-			this.target <- &model.Statement{
-				Subject:   this.curSubject,
-				Object:    this.curObject,
-				Predicate: this.curPredicate,
-				Context:   this.curGraph,
+// syntax errors are raised as panics by the parsing functions, they are
+// recovered here
+func (this *Parser) statementOrError() (err *SyntaxError) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxError, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
 			}
-			this.curSubject = nil
-			this.curPredicate = nil
-			this.curObject = nil
-
-		} else if val.tokenType == BlankNodeOpening {
-			newBlankNode := &model.AnonymousBlankNode{}
-			if (this.curSubject != nil) && (this.curPredicate != nil) {
-				this.curObject = newBlankNode
-				this.target <- &model.Statement{
-					Subject:   this.curSubject,
-					Object:    this.curObject,
-					Predicate: this.curPredicate,
-					Context:   this.curGraph,
-				}
+			err = syntaxError
+		}
+	}()
+	this.statement()
+	return nil
+}
 
-			} else if (this.curSubject == nil) && (this.curPredicate == nil) {
-				this.curSubject = newBlankNode
+func (this *Parser) advance() {
+	val, ok := <-this.source
+	for ok && val.tokenType == CommentText {
+		this.addComment(val)
+		val, ok = <-this.source
+	}
+	if ok {
+		this.curToken = val
+	} else if this.curToken == nil || this.curToken.tokenType != EOF {
+		this.curToken = &Token{tokenType: EOF}
+	}
+	switch this.curToken.tokenType {
+	case BlankNodeOpening, CollectionOpening, GraphOpening, TripleTermOpening, ReifiedTripleOpening, AnnotationOpening:
+		this.depth++
+	case BlankNodeClosing, CollectionClosing, GraphClosing, TripleTermClosing, ReifiedTripleClosing, AnnotationClosing:
+		this.depth--
+	}
+}
 
-			} else {
-				panic("not implemented")
-			}
+func (this *Parser) fail(format string, args ...interface{}) {
+	panic(newSyntaxError(this.curToken, format, args...))
+}
 
-			this.runInsideBlankNode(newBlankNode)
+func (this *Parser) unexpected() {
+	if this.curToken.tokenType == Error {
+		this.fail("%s", this.curToken.value)
+	}
+	this.fail("unexpected %s", this.curToken)
+}
 
-		} else if val.tokenType == BlankNodeClosing {
-			panic("unexpected ]")
-		} else if val.tokenType == PNameNS {
+func (this *Parser) expect(tokenType TokenType) *Token {
+	if this.curToken.tokenType != tokenType {
+		if this.curToken.tokenType == Error {
+			this.unexpected()
+		}
+		this.fail("expected %s, found %s", tokenType, this.curToken)
+	}
+	ret := this.curToken
+	this.advance()
+	return ret
+}
 
-			if this.curSubject == nil {
-				this.curSubject = &model.PrefixedName{Prefix: val.value}
-			} else if this.curPredicate == nil {
-				this.curPredicate = &model.PrefixedName{Prefix: val.value}
-			} else {
-				this.curObject = &model.PrefixedName{Prefix: val.value}
-			}
-			//|| (val.tokenType == IRI) || (val.tokenType == A)
-
-		} else if val.tokenType == PNameLN {
-
-		} else if val.tokenType == BaseTag {
-			val = <-this.source
-			if val.tokenType == PNameNS {
-				val = <-this.source
-				if val.tokenType == IRI {
-					base := val.value
-					val = <-this.source
-					if val.tokenType == Dot {
-						this.baseUri = model.IRI(base)
-					} else {
-						panic("error")
-					}
-				} else {
-					panic("error")
-				}
-			} else {
-				panic("error")
-			}
-		} else if val.tokenType == PrefixTag {
-			val = <-this.source
-			if val.tokenType == PNameNS {
-				val = <-this.source
-				if val.tokenType == IRI {
-					base := val.value
-					val = <-this.source
-					if val.tokenType == Dot {
-						this.baseUri = model.IRI(base)
-					} else {
-						panic("error")
-					}
-				} else {
-					panic("error")
-				}
-			} else {
-				panic("error")
-			}
-		} else if val.tokenType == CollectionOpening {
-
-			if (this.curSubject == nil) && (this.curPredicate == nil) {
-				this.curSubject = &model.AnonymousBlankNode{}
-				this.runInsidePropertyList(this.curSubject.(*model.AnonymousBlankNode))
-			} else if (this.curSubject != nil) && (this.curPredicate != nil) && (this.curObject == nil) {
-				this.curObject = &model.AnonymousBlankNode{}
-				this.runInsidePropertyList(this.curObject.(*model.AnonymousBlankNode))
-			} else {
-				panic("unexpected (")
-			}
-		} else if val.tokenType == CollectionClosing {
-			panic("unexpected )")
-		} else if val.tokenType == EmptyCollection {
-			// by convention the empty collection reduces to rdfs:nil TODO source this comment
-			if (this.curSubject == nil) && (this.curPredicate == nil) {
-				this.curSubject = model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#nil")
-			} else if (this.curSubject != nil) && (this.curPredicate != nil) && (this.curObject == nil) {
-				this.curObject = model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#nil")
-			} else {
-				panic("unexpected ()")
-			}
+func (this *Parser) emit(subject model.RDFTerm, predicate model.RDFTerm, object model.RDFTerm) {
+	if this.patterns != nil {
+		*this.patterns = append(*this.patterns, &model.Statement{Subject: subject, Predicate: predicate, Object: object})
+		return
+	}
+	if dictionary := this.options.Dictionary; dictionary != nil {
+		this.target <- &model.Statement{
+			Subject:   dictionary.Intern(subject),
+			Predicate: dictionary.Intern(predicate),
+			Object:    dictionary.Intern(object),
+			Context:   dictionary.Intern(this.curGraph),
 		}
+		return
+	}
+	this.target <- &model.Statement{
+		Subject:   subject,
+		Predicate: predicate,
+		Object:    object,
+		Context:   this.curGraph,
+	}
+}
 
+func (this *Parser) statement() {
+	switch this.curToken.tokenType {
+	case Version, VersionTag, Prefix, PrefixTag, Base, BaseTag:
+		if !this.seenTriples {
+			this.flushComments(HeaderComment, nil)
+		}
+	}
+	switch this.curToken.tokenType {
+	case Version:
+		this.advance()
+		this.expect(String)
+		return
+	case VersionTag:
+		this.advance()
+		this.expect(String)
+		this.expect(Dot)
+		return
+	}
+	if this.options.Format.IsLineBased() {
+		this.ntStatement()
+		return
+	}
+	switch this.curToken.tokenType {
+	case PrefixTag:
+		this.advance()
+		this.prefixID()
+		this.expect(Dot)
+	case Prefix:
+		this.advance()
+		this.prefixID()
+	case BaseTag:
+		this.advance()
+		this.base()
+		this.expect(Dot)
+	case Base:
+		this.advance()
+		this.base()
+	default:
+		if this.options.Format == format.TriG {
+			this.block()
+		} else {
+			this.triples()
+			this.endStatement()
+		}
 	}
 }
 
-func (this *Parser) runInsideBlankNode(newBlankNode *model.AnonymousBlankNode) {
-	val := <-this.source
+func (this *Parser) prefixID() {
+	prefix := model.Prefix(strings.TrimSuffix(this.expect(PNameNS).value, ":"))
+	iri := this.resolve(this.expect(IRI).value)
+	if _, ok := this.namespaces[prefix]; !ok {
+		this.prefixes = append(this.prefixes, prefix)
+	}
+	this.namespaces[prefix] = iri
+}
 
-	var curPredicate model.RDFTerm
-	var curObject model.RDFTerm
-	for {
-		if (val.tokenType == PNameLN) || (val.tokenType == PNameNS) || (val.tokenType == IRI) || (val.tokenType == A) {
-			if curPredicate == nil {
-				curPredicate = model.A
+func (this *Parser) base() {
+	this.baseUri = this.resolve(this.expect(IRI).value)
+	this.declaredBase = this.baseUri
+}
 
-			} else if curObject == nil {
+// N-Triples and N-Quads statements
+
+func (this *Parser) ntStatement() {
+	var subject model.RDFTerm
+	switch this.curToken.tokenType {
+	case IRI:
+		subject = this.iri()
+	case BlankNodeLabel:
+		subject = this.blankNode()
+	default:
+		this.unexpected()
+	}
+	if this.curToken.tokenType != IRI {
+		this.unexpected()
+	}
+	predicate := this.iri()
+	object := this.ntObject()
+	if this.options.Format == format.NQuads {
+		switch this.curToken.tokenType {
+		case IRI:
+			this.curGraph = this.iri()
+		case BlankNodeLabel:
+			this.curGraph = this.blankNode()
+		}
+	}
+	this.expect(Dot)
+	this.emit(subject, predicate, object)
+	this.curGraph = nil
+}
 
-				curObject = model.A
+func (this *Parser) ntObject() model.RDFTerm {
+	switch this.curToken.tokenType {
+	case IRI:
+		return this.iri()
+	case BlankNodeLabel:
+		return this.blankNode()
+	case String:
+		return this.literal()
+	case TripleTermOpening:
+		this.advance()
+		subject := this.ntObject()
+		if this.curToken.tokenType != IRI {
+			this.unexpected()
+		}
+		predicate := this.iri()
+		object := this.ntObject()
+		this.expect(TripleTermClosing)
+		return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}
+	}
+	this.unexpected()
+	return nil
+}
 
-			} else {
-				panic("expecting , ; or ]")
-			}
+// TriG blocks
+
+func (this *Parser) block() {
+	switch this.curToken.tokenType {
+	case GraphOpening:
+		this.wrappedGraph(nil)
+	case Graph:
+		this.advance()
+		var label model.RDFTerm
+		switch this.curToken.tokenType {
+		case IRI, PNameLN, PNameNS:
+			label = this.iri()
+		case BlankNodeLabel, BlankNodeAnonymous:
+			label = this.blankNode()
+		default:
+			this.unexpected()
+		}
+		this.wrappedGraph(label)
+	case IRI, PNameLN, PNameNS, BlankNodeLabel, BlankNodeAnonymous:
+		subject := this.subject()
+		if this.curToken.tokenType == GraphOpening {
+			this.wrappedGraph(subject)
+		} else {
+			this.setSubject(subject)
+			this.predicateObjectList(subject)
+			this.endStatement()
+		}
+	default:
+		this.triples()
+		this.endStatement()
+	}
+}
 
-		} else if val.tokenType == Coma {
-			if (curObject == nil) || (curPredicate == nil) {
-				panic("unexpected ,")
-			}
-			this.target <- &model.Statement{
-				Subject:   newBlankNode,
-				Predicate: curPredicate,
-				Object:    curObject,
-				Context:   this.curGraph,
-			}
-			curObject = nil
+func (this *Parser) wrappedGraph(label model.RDFTerm) {
+	this.expect(GraphOpening)
+	this.curGraph = label
+	for this.curToken.tokenType != GraphClosing {
+		this.triples()
+		if this.curToken.tokenType != Dot {
+			break
+		}
+		this.endStatement()
+	}
+	this.lastSubject = this.curSubject
+	this.lastGraph = this.curGraph
+	this.curSubject = nil
+	this.expect(GraphClosing)
+	this.curGraph = nil
+}
 
-		} else if val.tokenType == SemiColumn {
-			if (curObject == nil) || (curPredicate == nil) {
-				panic("unexpected ;")
-			}
-			this.target <- &model.Statement{
-				Subject:   newBlankNode,
-				Predicate: curPredicate,
-				Object:    curObject,
-				Context:   this.curGraph,
-			}
-			curPredicate = nil
-			curObject = nil
-		} else if val.tokenType == BlankNodeClosing {
+// the '.' ending a statement
+func (this *Parser) endStatement() {
+	if this.curToken.tokenType != Dot {
+		this.expect(Dot)
+	}
+	// comments following the dot belong to the next statement, unless they
+	// are on the same line
+	this.lastSubject = this.curSubject
+	this.lastGraph = this.curGraph
+	this.curSubject = nil
+	this.advance()
+}
+
+// turtle triples
+
+func (this *Parser) triples() {
+	switch this.curToken.tokenType {
+	case BlankNodeOpening:
+		subject := this.blankNodePropertyList()
+		this.setSubject(subject)
+		if this.isVerb() {
+			this.predicateObjectList(subject)
+		}
+	case ReifiedTripleOpening:
+		subject := this.reifiedTriple()
+		this.setSubject(subject)
+		if this.isVerb() {
+			this.predicateObjectList(subject)
+		}
+	default:
+		subject := this.subject()
+		this.setSubject(subject)
+		this.predicateObjectList(subject)
+	}
+}
+
+func (this *Parser) isVerb() bool {
+	switch this.curToken.tokenType {
+	case IRI, PNameLN, PNameNS, A, Variable:
+		return true
+	}
+	return false
+}
+
+func (this *Parser) subject() model.RDFTerm {
+	switch this.curToken.tokenType {
+	case IRI, PNameLN, PNameNS:
+		return this.iri()
+	case Variable:
+		return this.variable()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		return this.blankNode()
+	case CollectionOpening, EmptyCollection:
+		return this.collection()
+	}
+	this.unexpected()
+	return nil
+}
+
+func (this *Parser) predicateObjectList(subject model.RDFTerm) {
+	this.objectList(subject, this.verb())
+	for this.curToken.tokenType == SemiColumn {
+		this.advance()
+		if this.isVerb() {
+			this.objectList(subject, this.verb())
+		}
+	}
+}
+
+func (this *Parser) verb() model.RDFTerm {
+	if this.curToken.tokenType == A {
+		this.advance()
+		return model.A
+	}
+	if this.curToken.tokenType == Variable {
+		return this.variable()
+	}
+	if !this.isVerb() {
+		this.unexpected()
+	}
+	return this.iri()
+}
+
+func (this *Parser) objectList(subject model.RDFTerm, predicate model.RDFTerm) {
+	for {
+		object := this.object()
+		this.emit(subject, predicate, object)
+		this.annotation(subject, predicate, object)
+		if this.curToken.tokenType != Coma {
 			return
+		}
+		this.advance()
+	}
+}
+
+func (this *Parser) object() model.RDFTerm {
+	switch this.curToken.tokenType {
+	case IRI, PNameLN, PNameNS:
+		return this.iri()
+	case Variable:
+		return this.variable()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		return this.blankNode()
+	case BlankNodeOpening:
+		return this.blankNodePropertyList()
+	case CollectionOpening, EmptyCollection:
+		return this.collection()
+	case String, Number, Boolean:
+		return this.literal()
+	case TripleTermOpening:
+		return this.tripleTerm()
+	case ReifiedTripleOpening:
+		return this.reifiedTriple()
+	}
+	this.unexpected()
+	return nil
+}
+
+func (this *Parser) blankNodePropertyList() model.RDFTerm {
+	this.expect(BlankNodeOpening)
+	ret := model.NewAnonymousBlankNode()
+	this.predicateObjectList(ret)
+	this.expect(BlankNodeClosing)
+	return ret
+}
+
+func (this *Parser) collection() model.RDFTerm {
+	if this.curToken.tokenType == EmptyCollection {
+		this.advance()
+		return model.RDFNil
+	}
+	this.expect(CollectionOpening)
+	var head model.RDFTerm = model.RDFNil
+	var previous model.RDFTerm
+	for this.curToken.tokenType != CollectionClosing {
+		item := this.object()
+		node := model.NewAnonymousBlankNode()
+		if previous == nil {
+			head = node
 		} else {
-			panic("not implemented")
+			this.emit(previous, model.RDFRest, node)
 		}
-		val = <-this.source
+		this.emit(node, model.RDFFirst, item)
+		previous = node
+	}
+	this.advance()
+	if previous != nil {
+		this.emit(previous, model.RDFRest, model.RDFNil)
 	}
+	return head
 }
 
-func (this *Parser) runInsidePropertyList(newBlankNode *model.AnonymousBlankNode) {
+// RDF 1.2
 
-	curElm := newBlankNode
-	val := <-this.source
+// annotations following the object of a triple
+func (this *Parser) annotation(subject model.RDFTerm, predicate model.RDFTerm, object model.RDFTerm) {
+	var reifier model.RDFTerm
 	for {
-		if val.tokenType == CollectionClosing {
-			this.target <- &model.Statement{
-				Subject:   curElm,
-				Predicate: model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"),
-				Object:    model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"),
+		switch this.curToken.tokenType {
+		case Tilde:
+			this.advance()
+			reifier = this.reifier()
+			this.emit(reifier, model.RDFReifies, model.TripleTerm{Subject: subject, Predicate: predicate, Object: object})
+		case AnnotationOpening:
+			this.advance()
+			if reifier == nil {
+				reifier = model.NewAnonymousBlankNode()
+				this.emit(reifier, model.RDFReifies, model.TripleTerm{Subject: subject, Predicate: predicate, Object: object})
+			}
+			this.predicateObjectList(reifier)
+			this.expect(AnnotationClosing)
+			reifier = nil
+		default:
+			return
+		}
+	}
+}
+
+// what follows '~', a fresh blank node when nothing is given
+func (this *Parser) reifier() model.RDFTerm {
+	switch this.curToken.tokenType {
+	case IRI, PNameLN, PNameNS:
+		return this.iri()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		return this.blankNode()
+	}
+	return model.NewAnonymousBlankNode()
+}
+
+func (this *Parser) reifiedTriple() model.RDFTerm {
+	this.expect(ReifiedTripleOpening)
+	var subject model.RDFTerm
+	switch this.curToken.tokenType {
+	case ReifiedTripleOpening:
+		subject = this.reifiedTriple()
+	case IRI, PNameLN, PNameNS:
+		subject = this.iri()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		subject = this.blankNode()
+	default:
+		this.unexpected()
+	}
+	predicate := this.verb()
+	var object model.RDFTerm
+	switch this.curToken.tokenType {
+	case ReifiedTripleOpening:
+		object = this.reifiedTriple()
+	case TripleTermOpening:
+		object = this.tripleTerm()
+	case IRI, PNameLN, PNameNS:
+		object = this.iri()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		object = this.blankNode()
+	case String, Number, Boolean:
+		object = this.literal()
+	default:
+		this.unexpected()
+	}
+	var reifier model.RDFTerm
+	if this.curToken.tokenType == Tilde {
+		this.advance()
+		reifier = this.reifier()
+	} else {
+		reifier = model.NewAnonymousBlankNode()
+	}
+	this.expect(ReifiedTripleClosing)
+	this.emit(reifier, model.RDFReifies, model.TripleTerm{Subject: subject, Predicate: predicate, Object: object})
+	return reifier
+}
+
+func (this *Parser) tripleTerm() model.RDFTerm {
+	this.expect(TripleTermOpening)
+	var subject model.RDFTerm
+	switch this.curToken.tokenType {
+	case IRI, PNameLN, PNameNS:
+		subject = this.iri()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		subject = this.blankNode()
+	default:
+		this.unexpected()
+	}
+	predicate := this.verb()
+	var object model.RDFTerm
+	switch this.curToken.tokenType {
+	case TripleTermOpening:
+		object = this.tripleTerm()
+	case IRI, PNameLN, PNameNS:
+		object = this.iri()
+	case BlankNodeLabel, BlankNodeAnonymous:
+		object = this.blankNode()
+	case String, Number, Boolean:
+		object = this.literal()
+	default:
+		this.unexpected()
+	}
+	this.expect(TripleTermClosing)
+	return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}
+}
+
+// terms
+
+// an IRI with a scheme
+var absoluteIRI = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+func (this *Parser) iri() model.IRI {
+	token := this.curToken
+	switch token.tokenType {
+	case IRI:
+		if this.options.Format.IsLineBased() && !absoluteIRI.MatchString(token.value) {
+			this.fail("relative IRI <%s>, N-Triples and N-Quads only have absolute IRIs", token.value)
+		}
+		this.advance()
+		return this.resolve(token.value)
+	case PNameLN, PNameNS:
+		separator := strings.IndexRune(token.value, ':')
+		prefix := model.Prefix(token.value[:separator])
+		namespace, ok := this.namespaces[prefix]
+		if !ok {
+			this.fail("undefined prefix %q", prefix)
+		}
+		this.advance()
+		return namespace + model.IRI(token.value[separator+1:])
+	}
+	this.unexpected()
+	return ""
+}
+
+func (this *Parser) blankNode() model.RDFTerm {
+	token := this.curToken
+	switch token.tokenType {
+	case BlankNodeAnonymous:
+		this.advance()
+		return model.NewAnonymousBlankNode()
+	case BlankNodeLabel:
+		this.advance()
+		ret, ok := this.bnodeLabels[token.value]
+		if !ok {
+			ret = &model.LabelledBlankNode{Label: token.value}
+			this.bnodeLabels[token.value] = ret
+		}
+		return ret
+	}
+	this.unexpected()
+	return nil
+}
+
+func (this *Parser) literal() model.RDFTerm {
+	token := this.curToken
+	switch token.tokenType {
+	case Number:
+		this.advance()
+		if strings.ContainsAny(token.value, "eE") {
+			return model.NewTypedLiteral(token.value, model.XSDDouble)
+		}
+		if strings.ContainsRune(token.value, '.') {
+			return model.NewTypedLiteral(token.value, model.XSDDecimal)
+		}
+		return model.NewTypedLiteral(token.value, model.XSDInteger)
+	case Boolean:
+		this.advance()
+		return model.NewTypedLiteral(token.value, model.XSDBoolean)
+	case String:
+		this.advance()
+		switch this.curToken.tokenType {
+		case LangTag:
+			language := this.curToken.value
+			direction := ""
+			if separator := strings.Index(language, "--"); separator >= 0 {
+				direction = language[separator+2:]
+				language = language[:separator]
+				if direction != "ltr" && direction != "rtl" {
+					this.fail("invalid language direction %q", direction)
+				}
 			}
-		} else if (val.tokenType == PNameLN) || (val.tokenType == PNameNS) || (val.tokenType == IRI) || (val.tokenType == A) {
-			newElm := &model.AnonymousBlankNode{}
-			this.target <- &model.Statement{
-				Subject:   curElm,
-				Predicate: model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#first"),
-				Object:    model.A, //val
+			this.advance()
+			return model.NewLangLiteral(token.value, language, direction)
+		case DoubleCaret:
+			this.advance()
+			if this.options.Format.IsLineBased() && this.curToken.tokenType != IRI {
+				this.unexpected()
 			}
-			this.target <- &model.Statement{
-				Subject:   curElm,
-				Predicate: model.IRI("http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"),
-				Object:    newElm,
+			ret := model.NewTypedLiteral(token.value, this.iri())
+			if this.options.CheckLiterals && ret.IllTyped() {
+				_, err := ret.Value()
+				panic(newSyntaxError(token, "ill-typed literal: %v", err))
 			}
-			curElm = newElm
-
-		} else {
-			panic("not implemented")
+			return ret
 		}
+		return model.NewStringLiteral(token.value)
+	}
+	this.unexpected()
+	return nil
+}
 
+// resolves iri against the current base, as per RFC 3986
+func (this *Parser) resolve(iri string) model.IRI {
+	if this.baseUri == "" {
+		return model.IRI(iri)
+	}
+	reference, err := url.Parse(iri)
+	if err != nil || reference.IsAbs() {
+		return model.IRI(iri)
 	}
+	base, err := url.Parse(string(this.baseUri))
+	if err != nil {
+		return model.IRI(iri)
+	}
+	return model.IRI(base.ResolveReference(reference).String())
 }
 
-type RuneSet map[rune]struct{}
+type runeRange struct {
+	lower rune
+	upper rune
+}
 
-func newSet(input ...rune) RuneSet {
-	ret := make(RuneSet)
+// a set of runes stored as ranges, the sets of the grammar span most of
+// the unicode planes
+type RuneSet struct {
+	ranges []runeRange
+}
+
+func newSet(input ...rune) *RuneSet {
+	ret := &RuneSet{}
 
 	for _, v := range input {
-		ret[v] = struct{}{}
+		ret.addRange(v, v)
 
 	}
 
@@ -275,48 +852,63 @@ func newSet(input ...rune) RuneSet {
 
 }
 
-func (this RuneSet) add(input ...rune) RuneSet {
+func (this *RuneSet) add(input ...rune) *RuneSet {
 
 	for _, v := range input {
-		this[v] = struct{}{}
+		this.addRange(v, v)
 
 	}
 	return this
 
 }
-func (this RuneSet) contains(testRune rune) bool {
-
-	_, ok := this[testRune]
-	return ok
 
+// Contains tells whether a rune is in the set, for the languages which
+// share the character classes of the grammars
+func (this *RuneSet) Contains(testRune rune) bool {
+	return this.contains(testRune)
 }
 
-func (this RuneSet) addRange(lower rune, inclusiveUpper rune) RuneSet {
-	for i := lower; i <= inclusiveUpper; i++ {
-		this[i] = struct{}{}
+func (this *RuneSet) contains(testRune rune) bool {
+
+	for _, r := range this.ranges {
+		if testRune >= r.lower && testRune <= r.upper {
+			return true
+		}
 	}
+	return false
+
+}
+
+func (this *RuneSet) addRange(lower rune, inclusiveUpper rune) *RuneSet {
+	this.ranges = append(this.ranges, runeRange{lower: lower, upper: inclusiveUpper})
 	return this
 }
 
-func (this RuneSet) remove(input ...rune) {
+func (this *RuneSet) remove(input ...rune) {
 	for _, v := range input {
-		delete(this, v)
+		this.removeRange(v, v)
 	}
 }
 
-func (this RuneSet) removeRange(lower rune, inclusiveUpper rune) {
-	{
-		for i := lower; i <= inclusiveUpper; i++ {
-			delete(this, i)
+func (this *RuneSet) removeRange(lower rune, inclusiveUpper rune) {
+	ranges := make([]runeRange, 0, len(this.ranges))
+	for _, r := range this.ranges {
+		if r.upper < lower || r.lower > inclusiveUpper {
+			ranges = append(ranges, r)
+			continue
+		}
+		if r.lower < lower {
+			ranges = append(ranges, runeRange{lower: r.lower, upper: lower - 1})
+		}
+		if r.upper > inclusiveUpper {
+			ranges = append(ranges, runeRange{lower: inclusiveUpper + 1, upper: r.upper})
 		}
 	}
+	this.ranges = ranges
 }
 
-func (this RuneSet) copy() RuneSet {
+func (this *RuneSet) copy() *RuneSet {
 	ret := newSet()
-
-	for elm, _ := range this {
-		ret.add(elm)
-	}
+	ret.ranges = append(ret.ranges, this.ranges...)
 	return ret
 }
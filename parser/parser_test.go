@@ -0,0 +1,362 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package parser
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+func parseString(t *testing.T, doc string, f format.Format) []*model.Statement {
+	t.Helper()
+	statements, err := ParseAll(strings.NewReader(doc), Options{Format: f})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return statements
+}
+
+func TestTurtleTerms(t *testing.T) {
+	doc := `@prefix ex: <http://ex.org/> .
+PREFIX : <http://default.org/>
+@base <http://base.org/dir/> .
+# a comment
+ex:s a ex:C ;
+	ex:p "plain", "en"@en, "typed"^^ex:t, 'single', """long
+"quoted" string""", 1, -2.5, 1E3, true ;
+	:q <relative>, _:b, [], :a.b.
+`
+	statements := parseString(t, doc, format.Turtle)
+	expected := []model.RDFTerm{
+		model.IRI("http://ex.org/C"),
+		model.NewStringLiteral("plain"),
+		model.NewLangLiteral("en", "en", ""),
+		model.NewTypedLiteral("typed", "http://ex.org/t"),
+		model.NewStringLiteral("single"),
+		model.NewStringLiteral("long\n\"quoted\" string"),
+		model.NewTypedLiteral("1", model.XSDInteger),
+		model.NewTypedLiteral("-2.5", model.XSDDecimal),
+		model.NewTypedLiteral("1E3", model.XSDDouble),
+		model.NewTypedLiteral("true", model.XSDBoolean),
+		model.IRI("http://base.org/dir/relative"),
+	}
+	if len(statements) != len(expected)+3 {
+		t.Fatalf("%d statements instead of %d", len(statements), len(expected)+3)
+	}
+	for i, object := range expected {
+		if statements[i].Object != object {
+			t.Errorf("statement %d: object %#v instead of %#v", i, statements[i].Object, object)
+		}
+	}
+	if statements[0].Predicate != model.A {
+		t.Errorf("a is %#v", statements[0].Predicate)
+	}
+	if label, ok := statements[11].Object.(*model.LabelledBlankNode); !ok || label.Label != "b" {
+		t.Errorf("expected _:b, got %#v", statements[11].Object)
+	}
+	if _, ok := statements[12].Object.(*model.AnonymousBlankNode); !ok {
+		t.Errorf("expected [], got %#v", statements[12].Object)
+	}
+	if statements[13].Object != model.IRI("http://default.org/a.b") {
+		t.Errorf("trailing dot not handled, got %#v", statements[13].Object)
+	}
+}
+
+func TestTurtleNesting(t *testing.T) {
+	doc := `@prefix : <http://ex.org/> .
+:s :p [ :q ( 1 [ :r :t ] ) ] .
+`
+	expected := `<http://ex.org/s> <http://ex.org/p> _:outer .
+_:outer <http://ex.org/q> _:l1 .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+_:l1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:l2 .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:inner .
+_:l2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+_:inner <http://ex.org/r> <http://ex.org/t> .
+`
+	statements := parseString(t, doc, format.Turtle)
+	if same, _ := model.Isomorphic(statements, parseString(t, expected, format.NTriples)); !same {
+		t.Errorf("unexpected statements %v", statements)
+	}
+}
+
+func TestSameLabelSameNode(t *testing.T) {
+	statements := parseString(t, "_:a <http://p> _:a .\n_:b <http://p> _:a .", format.Turtle)
+	if statements[0].Subject != statements[0].Object || statements[1].Object != statements[0].Subject {
+		t.Errorf("_:a is not a single node")
+	}
+	if statements[1].Subject == statements[0].Subject {
+		t.Errorf("_:a and _:b are the same node")
+	}
+}
+
+func TestRDF12(t *testing.T) {
+	doc := `PREFIX : <http://ex.org/>
+:s :p :o ~ :r {| :source :x |} .
+<< :a :b :c >> :says :d .
+:e :f <<( :g :h "l"@en--rtl )>> .
+`
+	statements := parseString(t, doc, format.Turtle)
+	if len(statements) != 6 {
+		t.Fatalf("%d statements instead of 6", len(statements))
+	}
+	reifies := statements[1]
+	expected := model.TripleTerm{Subject: model.IRI("http://ex.org/s"), Predicate: model.IRI("http://ex.org/p"), Object: model.IRI("http://ex.org/o")}
+	if reifies.Subject != model.IRI("http://ex.org/r") || reifies.Predicate != model.RDFReifies || reifies.Object != expected {
+		t.Errorf("unexpected reification %#v", reifies)
+	}
+	if statements[2].Subject != model.IRI("http://ex.org/r") {
+		t.Errorf("annotation not on the reifier")
+	}
+	if statements[3].Subject != statements[4].Subject {
+		t.Errorf("reified triple subject is not its reifier")
+	}
+	tripleTerm := statements[5].Object.(model.TripleTerm)
+	if tripleTerm.Object != model.NewLangLiteral("l", "en", "rtl") {
+		t.Errorf("unexpected literal %#v", tripleTerm.Object)
+	}
+}
+
+func TestTriG(t *testing.T) {
+	doc := `PREFIX : <http://ex.org/>
+:a :b :c .
+:g { :s :p :o . :s :p :o2 }
+GRAPH _:g { :s :p :o }
+{ :d :e :f }
+`
+	statements := parseString(t, doc, format.TriG)
+	graphs := []model.RDFTerm{nil, model.IRI("http://ex.org/g"), model.IRI("http://ex.org/g"), nil, nil}
+	if len(statements) != 5 {
+		t.Fatalf("%d statements instead of 5", len(statements))
+	}
+	for i, graph := range graphs {
+		if i == 3 {
+			if !model.IsBlankNode(statements[i].Context) {
+				t.Errorf("statement %d: graph %#v", i, statements[i].Context)
+			}
+			continue
+		}
+		if statements[i].Context != graph {
+			t.Errorf("statement %d: graph %#v instead of %#v", i, statements[i].Context, graph)
+		}
+	}
+}
+
+func TestNQuads(t *testing.T) {
+	doc := `<http://s> <http://p> "oé\n" <http://g> .
+_:s <http://p> <<( _:s <http://p> "x"^^<http://t> )>> .
+`
+	statements := parseString(t, doc, format.NQuads)
+	if statements[0].Object != model.NewStringLiteral("oé\n") || statements[0].Context != model.IRI("http://g") {
+		t.Errorf("unexpected statement %#v", statements[0])
+	}
+	if statements[1].Context != nil {
+		t.Errorf("unexpected graph %#v", statements[1].Context)
+	}
+	_, err := ParseAll(strings.NewReader("@prefix : <http://x> ."), Options{Format: format.NTriples})
+	if err == nil {
+		t.Errorf("directives are not N-Triples")
+	}
+	for _, doc := range []string{"<s> <http://p> <http://o> .", "<http://s> <http://p> \"x\"^^<t> .", "<http://s> <http://p> <http://o> <g> ."} {
+		_, err := ParseAll(strings.NewReader(doc), Options{Format: format.NQuads, Base: "http://base/"})
+		if syntaxError, ok := err.(*SyntaxError); !ok || !strings.HasPrefix(syntaxError.Message, "relative IRI") {
+			t.Errorf("%s: error %v", doc, err)
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	cases := []struct {
+		doc  string
+		line int
+		col  int
+	}{
+		{"<http://s> <http://p> <http://o>", 1, 33},
+		{"@prefix ex: <http://ex.org/> .\nex:s ex:p ex:o ;\n  ex:q .", 3, 8},
+		{"<http://s> <http://p> \"unterminated\n", 1, 23},
+		{"<http://s> <http://p> un:defined .", 1, 23},
+		{"<http://s> <http://p> <http://o> }", 1, 34},
+	}
+	for _, c := range cases {
+		_, err := ParseAll(strings.NewReader(c.doc), Options{})
+		syntaxError, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error, got %v", c.doc, err)
+			continue
+		}
+		if syntaxError.Line != c.line || syntaxError.Col != c.col {
+			t.Errorf("%q: error %v, expected at %d:%d", c.doc, syntaxError, c.line, c.col)
+		}
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	dictionary := model.NewDictionary()
+	doc := "<http://s> <http://p> <http://o>, \"o\" .\n<http://o> <http://p> <http://s> .\n"
+	statements, err := ParseAll(strings.NewReader(doc), Options{Dictionary: dictionary})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 3 || dictionary.Len() != 4 {
+		t.Fatalf("%d statements, %d terms", len(statements), dictionary.Len())
+	}
+	for _, statement := range statements {
+		for _, term := range []model.RDFTerm{statement.Subject, statement.Predicate, statement.Object} {
+			if _, ok := dictionary.Lookup(term); !ok {
+				t.Errorf("%v was not interned", term)
+			}
+		}
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	this := Parse(strings.NewReader("@prefix b: <http://b/> .\nPREFIX a: <http://a/>\n@prefix b: <http://b2/> ."), Options{})
+	for range this.Statements() {
+	}
+	namespaces := this.Namespaces()
+	if len(namespaces) != 2 || namespaces[0].Prefix != "b" || namespaces[0].IRI != "http://b2/" || namespaces[1].Prefix != "a" {
+		t.Errorf("unexpected namespaces %v", namespaces)
+	}
+}
+
+func TestComments(t *testing.T) {
+	doc := `# header
+@prefix : <http://ex.org/> .
+
+# about s
+:s :p :o ; # inside s
+	:q :o . # after s
+# about t
+:t :p :o .
+# footer
+`
+	this := Parse(strings.NewReader(doc), Options{Comments: true})
+	for range this.Statements() {
+	}
+	if err := this.Err(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []Comment{
+		{Text: "# header", Line: 1, Placement: HeaderComment},
+		{Text: "# about s", Line: 4, Placement: SubjectComment, Subject: model.IRI("http://ex.org/s")},
+		{Text: "# inside s", Line: 5, Placement: SubjectComment, Subject: model.IRI("http://ex.org/s")},
+		{Text: "# after s", Line: 6, Placement: SubjectComment, Subject: model.IRI("http://ex.org/s")},
+		{Text: "# about t", Line: 7, Placement: SubjectComment, Subject: model.IRI("http://ex.org/t")},
+		{Text: "# footer", Line: 9, Placement: FooterComment},
+	}
+	comments := this.Comments()
+	if len(comments) != len(expected) {
+		t.Fatalf("%d comments instead of %d", len(comments), len(expected))
+	}
+	for i, comment := range comments {
+		if *comment != expected[i] {
+			t.Errorf("comment %d is %+v instead of %+v", i, *comment, expected[i])
+		}
+	}
+}
+
+func TestReadError(t *testing.T) {
+	broken := errors.New("broken")
+	reader := io.MultiReader(strings.NewReader("<http://s> <http://p> <http://o> .\n<http://s> "), iotest.ErrReader(broken))
+	statements, err := ParseAll(reader, Options{ContinueOnError: true})
+	if err != broken || len(statements) != 1 {
+		t.Errorf("error %v, %d statements", err, len(statements))
+	}
+	reader = io.MultiReader(strings.NewReader("{ ?s <http://p> ?o } => { ?o <http://p> ?s } ."), iotest.ErrReader(broken))
+	if _, err := ParseRules(reader, Options{}); err != broken {
+		t.Errorf("rules: error %v", err)
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	doc := "<http://s> <http://p> \"ok\" .\n<http://s> <http://p> \"a\xff\xfeb\" .\n"
+	parser := Parse(strings.NewReader(doc), Options{ContinueOnError: true})
+	statements := 0
+	for range parser.Statements() {
+		statements++
+	}
+	syntaxError, ok := parser.Err().(*SyntaxError)
+	if !ok || syntaxError.Line != 2 || syntaxError.Col != 25 || statements != 1 {
+		t.Errorf("error %v, %d statements", parser.Err(), statements)
+	}
+	if errors := parser.Errors(); len(errors) == 0 || errors[0] != syntaxError {
+		t.Errorf("errors %v", errors)
+	}
+	// a sequence cut by the end of the input
+	if _, err := ParseAll(strings.NewReader("<http://s> <http://p> \"\xc3"), Options{}); err == nil || err.Error() != "1:24: invalid UTF-8 c3" {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	doc := `@prefix : <http://ex.org/> .
+:a :b :c .
+:d :e [ :f ] .
+:g :h :i .
+:j "k" :l ; :m :n .
+@prefix x: <http://x.org/> .
+:o :p [ :q .
+:r :s :t .
+`
+	this := Parse(strings.NewReader(doc), Options{ContinueOnError: true})
+	statements := []*model.Statement{}
+	for statement := range this.Statements() {
+		statements = append(statements, statement)
+	}
+	errors := this.Errors()
+	// the unbalanced [ hides the last line
+	positions := [][2]int{{3, 12}, {5, 4}, {7, 12}}
+	if len(errors) != len(positions) {
+		t.Fatalf("errors %v", errors)
+	}
+	for i, position := range positions {
+		if errors[i].Line != position[0] || errors[i].Col != position[1] {
+			t.Errorf("error %v expected at %v", errors[i], position)
+		}
+	}
+	if len(statements) != 2 || statements[1].Subject != model.IRI("http://ex.org/g") {
+		t.Errorf("unexpected statements %v", statements)
+	}
+	if this.Err() != errors[0] {
+		t.Errorf("Err() is not the first error")
+	}
+}
+
+func TestCheckLiterals(t *testing.T) {
+	doc := `@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+<http://s> <http://p> "12"^^xsd:byte, "x"^^<http://ex.org/unknown> .
+<http://s> <http://p>
+  "1200"^^xsd:byte .
+`
+	if _, err := ParseAll(strings.NewReader(doc), Options{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err := ParseAll(strings.NewReader(doc), Options{CheckLiterals: true})
+	if syntaxError, ok := err.(*SyntaxError); !ok || syntaxError.Line != 4 || syntaxError.Col != 3 ||
+		syntaxError.Message != `ill-typed literal: "1200" is not a valid xsd:byte` {
+		t.Errorf("error %v", err)
+	}
+}
+
+func TestSPARQLTokens(t *testing.T) {
+	this := NewSPARQLTokenizer(`SELECT ?x $y WHERE { ?x <http://p> ex:o . FILTER(?x < 3 && ?y >= -1 || !?z) } # done`)
+	expected := []string{"SELECT", "x", "y", "WHERE", "", "x", "http://p", "ex:o", "", "FILTER", "", "x", "<", "3", "&&", "y", ">=", "-1", "||", "!", "z", "", ""}
+	types := []TokenType{Name, Variable, Variable, Name, GraphOpening, Variable, IRI, PNameLN, Dot, Name, CollectionOpening, Variable, Operator, Number, Operator, Variable, Operator, Number, Operator, Operator, Variable, CollectionClosing, GraphClosing}
+	for i, value := range expected {
+		token := this.Next()
+		if token.Type() != types[i] || token.Value() != value {
+			t.Fatalf("token %d is %v instead of %v %q", i, token, types[i], value)
+		}
+	}
+	if token := this.Next(); token.Type() != EOF {
+		t.Errorf("unexpected %v", token)
+	}
+}
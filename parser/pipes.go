@@ -5,80 +5,98 @@
 package parser
 
 import (
-	"errors"
 	"fmt"
 	"io"
-
 	"unicode/utf8"
 )
 
-func NewByteSource(reader io.Reader, buffSize int, channel chan<- byte) {
+// NewByteSource sends the bytes of reader on channel and closes it. The
+// error is the one which stopped the reading, nil at the end of the input.
+func NewByteSource(reader io.Reader, buffSize int, channel chan<- byte) error {
 
 	buffer := make([]byte, buffSize)
 	defer close(channel)
 
 	for {
 		n, err := reader.Read(buffer)
+		// the bytes read come before the error, readers may return both
+		for i := 0; i < n; i++ {
+			channel <- buffer[i]
+		}
 		if err != nil {
 			if err == io.EOF {
-				return
+				return nil
 			}
-			// TODO handle this
-			panic(err)
-
+			return err
 		}
 		if n == 0 {
-			return
-		}
-		for i := 0; i < n; i++ {
-			channel <- buffer[i]
+			return nil
 		}
 
 	}
 }
 
-func NewRuneUtf8Source(source <-chan byte, target chan<- rune) {
+// NewRuneUtf8Source decodes the bytes of source on target and closes it.
+// It stops at the first invalid UTF-8 sequence, which is returned as a
+// *SyntaxError, the rest of source being read and dropped.
+func NewRuneUtf8Source(source <-chan byte, target chan<- rune) error {
 	defer close(target)
 	tmp := make([]byte, 0, 4)
+	line, col := 1, 1
 
 	for b := range source {
-		tmp = processBytes(b, tmp, target)
+		tmp = append(tmp, b)
+		for len(tmp) > 0 && utf8.FullRune(tmp) {
+			r, size := utf8.DecodeRune(tmp)
+			if r == utf8.RuneError && size == 1 {
+				for range source {
+				}
+				return invalidUTF8(tmp, line, col)
+			}
+			target <- r
+			if r == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+			tmp = tmp[:copy(tmp, tmp[size:])]
+		}
 	}
 	if len(tmp) != 0 {
-		panic(errors.New(fmt.Sprintf("Invalid UTF8 %v", tmp)))
-
+		// truncated sequence at the end of the input
+		return invalidUTF8(tmp, line, col)
 	}
-
+	return nil
 }
 
-func processBytes(r byte, tmp []byte, target chan<- rune) []byte {
-	tmp = append(tmp, r)
-	retRune, _ := utf8.DecodeRune(tmp)
-	if retRune == utf8.RuneError {
-		if len(tmp) == 4 {
-			panic(errors.New(fmt.Sprintf("Invalid UTF8 %v", tmp)))
-		} else {
-			return tmp
-		}
-	} else {
-
-		target <- retRune
-		// I want to clear without calling GC
-		// that makes a copy and changes the copy
-		// tmp = tmp[:0]
-		return tmp[:0]
-	}
-
+func invalidUTF8(sequence []byte, line int, col int) *SyntaxError {
+	return &SyntaxError{Line: line, Col: col, Message: fmt.Sprintf("invalid UTF-8 % x", sequence)}
 }
 
-func NewRuneReader(inputFile io.Reader, readerBufferSize int, byteChannelSize int, runeChannelSize int) <-chan rune {
+// NewRuneReader decodes reader in the background. The error which stopped
+// the reading or the decoding, nil at the end of the input, is sent on the
+// second channel once the runes are all sent. The errors of reader come
+// first.
+func NewRuneReader(inputFile io.Reader, readerBufferSize int, byteChannelSize int, runeChannelSize int) (<-chan rune, <-chan error) {
 	// TODO new error, buffers must be > 1
 
 	byteChan := make(chan byte, byteChannelSize)
 	runeChan := make(chan rune, runeChannelSize)
+	errChan := make(chan error, 1)
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- NewByteSource(inputFile, readerBufferSize, byteChan)
+	}()
+	go func() {
+		decodeErr := NewRuneUtf8Source(byteChan, runeChan)
+		if err := <-readErr; err != nil {
+			errChan <- err
+			return
+		}
+		errChan <- decodeErr
+	}()
 
-	go NewByteSource(inputFile, readerBufferSize, byteChan)
-	go NewRuneUtf8Source(byteChan, runeChan)
-
-	return runeChan
+	return runeChan, errChan
 }
@@ -7,6 +7,7 @@ package parser
 import (
 	"bytes"
 	"testing"
+	"testing/iotest"
 	"unicode/utf8"
 )
 
@@ -30,6 +31,22 @@ func TestUtf8Source(t *testing.T) {
 
 }
 
+// readers may return their last bytes with io.EOF
+func TestByteSourceDataWithEOF(t *testing.T) {
+	testString := "<a> <p> <b> ."
+	byteChan := make(chan byte, 1)
+
+	go NewByteSource(iotest.DataErrReader(bytes.NewReader([]byte(testString))), 64, byteChan)
+
+	y := []byte{}
+	for b := range byteChan {
+		y = append(y, b)
+	}
+	if string(y) != testString {
+		t.Errorf("read %q instead of %q", string(y), testString)
+	}
+}
+
 func TestHowUtf8Works(t *testing.T) {
 	buff := make([]byte, 4)
 	utf8.EncodeRune(buff, 'Γ')
@@ -77,3 +94,21 @@ func TestByteToUtf8(t *testing.T) {
 	}
 
 }
+
+func TestInvalidUtf8Source(t *testing.T) {
+	byteChan := make(chan byte, 16)
+	runeChan := make(chan rune, 16)
+	for _, b := range []byte("é\nab\xe2\x28cd") {
+		byteChan <- b
+	}
+	close(byteChan)
+	err := NewRuneUtf8Source(byteChan, runeChan)
+	res := []rune{}
+	for r := range runeChan {
+		res = append(res, r)
+	}
+	syntaxError, ok := err.(*SyntaxError)
+	if !ok || syntaxError.Line != 2 || syntaxError.Col != 3 || string(res) != "é\nab" {
+		t.Errorf("error %v, runes %q", err, string(res))
+	}
+}
@@ -0,0 +1,162 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package parser
+
+import (
+	"io"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// RuleVariable is a variable of a rule, named without its ? or $
+type RuleVariable string
+
+// Rule is an N3 rule: { premises } => { conclusions } .
+//
+// Its patterns are Turtle triples whose terms may be variables. The blank
+// nodes of the premises are variables too, those of the conclusions are
+// new nodes. The premises may hold groups NOT { patterns }, the rule only
+// applies when none of them matches.
+type Rule struct {
+	Premises    []*model.Statement
+	Negations   [][]*model.Statement
+	Conclusions []*model.Statement
+	// the line the rule starts at
+	Line int
+}
+
+// ParseRules reads the rules of an N3 document, which may declare
+// prefixes and bases like Turtle. The variables of the conclusions must be
+// bound by the premises outside the NOT groups. The error is a
+// *SyntaxError, or the error of reader.
+func ParseRules(reader io.Reader, options Options) ([]*Rule, error) {
+	tokens := make(chan *Token, 64)
+	runes, readErrors := NewRuneReader(reader, 4096, 1024, 1024)
+	tokenizer := NewTokenizer(runes, tokens)
+	// variables, names and operators
+	tokenizer.sparql = true
+	this := newParser(tokens, nil)
+	this.readErrors = readErrors
+	this.options = options
+	this.baseUri = options.Base
+
+	go tokenizer.run()
+	ret, err := this.rules()
+	// let the tokenizer finish
+	for range tokens {
+	}
+	this.readError()
+	if this.readErr != nil {
+		return nil, this.readErr
+	}
+	return ret, err
+}
+
+// syntax errors are recovered here, like in statementOrError
+func (this *Parser) rules() (ret []*Rule, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			syntaxError, ok := r.(*SyntaxError)
+			if !ok {
+				panic(r)
+			}
+			ret, err = nil, syntaxError
+		}
+	}()
+	this.advance()
+	for this.curToken.tokenType != EOF {
+		switch this.curToken.tokenType {
+		case PrefixTag:
+			this.advance()
+			this.prefixID()
+			this.expect(Dot)
+		case Prefix:
+			this.advance()
+			this.prefixID()
+		case BaseTag:
+			this.advance()
+			this.base()
+			this.expect(Dot)
+		case Base:
+			this.advance()
+			this.base()
+		default:
+			ret = append(ret, this.rule())
+		}
+	}
+	return ret, nil
+}
+
+func (this *Parser) rule() *Rule {
+	start := this.curToken
+	ret := &Rule{Line: start.line}
+	ret.Premises = this.formula(&ret.Negations)
+	if this.curToken.tokenType != Operator || this.curToken.value != "=>" {
+		this.fail("expected '=>', found %s", this.curToken)
+	}
+	this.advance()
+	ret.Conclusions = this.formula(nil)
+	this.expect(Dot)
+
+	bound := map[RuleVariable]struct{}{}
+	for _, premise := range ret.Premises {
+		for _, term := range []model.RDFTerm{premise.Subject, premise.Predicate, premise.Object} {
+			if variable, ok := term.(RuleVariable); ok {
+				bound[variable] = struct{}{}
+			}
+		}
+	}
+	for _, conclusion := range ret.Conclusions {
+		for _, term := range []model.RDFTerm{conclusion.Subject, conclusion.Predicate, conclusion.Object} {
+			if variable, ok := term.(RuleVariable); ok {
+				if _, found := bound[variable]; !found {
+					panic(newSyntaxError(start, "variable ?%s of the conclusions is not bound by the premises", variable))
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// the patterns between braces, whose blank nodes are their own. NOT groups
+// are only read when negations is not nil.
+func (this *Parser) formula(negations *[][]*model.Statement) []*model.Statement {
+	this.expect(GraphOpening)
+	this.bnodeLabels = make(map[string]*model.LabelledBlankNode)
+	ret := []*model.Statement{}
+	this.patterns = &ret
+	for this.curToken.tokenType != GraphClosing {
+		if negations != nil && this.curToken.tokenType == Name && strings.ToUpper(this.curToken.value) == "NOT" {
+			this.advance()
+			this.expect(GraphOpening)
+			group := []*model.Statement{}
+			this.patterns = &group
+			for this.curToken.tokenType != GraphClosing {
+				this.triples()
+				if this.curToken.tokenType != Dot {
+					break
+				}
+				this.advance()
+			}
+			this.expect(GraphClosing)
+			*negations = append(*negations, group)
+			this.patterns = &ret
+		} else {
+			this.triples()
+		}
+		if this.curToken.tokenType != Dot {
+			break
+		}
+		this.advance()
+	}
+	this.patterns = nil
+	this.expect(GraphClosing)
+	return ret
+}
+
+func (this *Parser) variable() model.RDFTerm {
+	return RuleVariable(this.expect(Variable).value)
+}
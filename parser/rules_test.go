@@ -0,0 +1,72 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+func TestParseRules(t *testing.T) {
+	doc := `@prefix : <http://ex.org/> .
+# grandparents
+{ ?x :parent ?y . ?y :parent ?z } => { ?x :grandparent ?z } .
+{ ?x a :Person ; :age ?a . NOT { ?x :spouse [] } . _:b :knows ?x } => { ?x a :Single ; :knownBy [ :age ?a ] } .
+{} => { :a :b ( 1 "c" ) } .
+`
+	rules, err := ParseRules(strings.NewReader(doc), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("%d rules instead of 3", len(rules))
+	}
+
+	grandparent := rules[0]
+	if grandparent.Line != 3 || len(grandparent.Premises) != 2 || len(grandparent.Negations) != 0 || len(grandparent.Conclusions) != 1 {
+		t.Errorf("grandparent rule %+v", grandparent)
+	}
+	conclusion := grandparent.Conclusions[0]
+	if conclusion.Subject != RuleVariable("x") || conclusion.Predicate != model.IRI("http://ex.org/grandparent") || conclusion.Object != RuleVariable("z") {
+		t.Errorf("conclusion %v", conclusion)
+	}
+
+	single := rules[1]
+	if len(single.Premises) != 3 || len(single.Negations) != 1 || len(single.Negations[0]) != 1 || len(single.Conclusions) != 3 {
+		t.Fatalf("single rule %+v", single)
+	}
+	if premise := single.Premises[0]; premise.Predicate != model.A || premise.Object != model.IRI("http://ex.org/Person") {
+		t.Errorf("premise %v", premise)
+	}
+	if !model.IsBlankNode(single.Negations[0][0].Object) || !model.IsBlankNode(single.Premises[2].Subject) {
+		t.Errorf("blank nodes %v %v", single.Negations[0][0], single.Premises[2])
+	}
+	// the nested blank node links two conclusions
+	if single.Conclusions[1].Subject != single.Conclusions[2].Object || single.Conclusions[1].Object != RuleVariable("a") {
+		t.Errorf("conclusions %v %v", single.Conclusions[1], single.Conclusions[2])
+	}
+
+	if fact := rules[2]; len(fact.Premises) != 0 || len(fact.Conclusions) != 5 {
+		t.Errorf("fact %+v", fact)
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	for doc, message := range map[string]string{
+		`{ ?x <http://p> ?y } => { ?x <http://q> ?z } .`: "1:1: variable ?z of the conclusions is not bound by the premises",
+		`{ NOT { ?x <http://p> ?y } } => { ?x a ?y } .`:  "1:1: variable ?x of the conclusions is not bound by the premises",
+		`{ ?x <http://p> ?y } { ?x <http://q> ?y } .`:    "1:22: expected '=>', found '{'",
+		`{ ?x <http://p> ?y } => { NOT { ?x a ?y } } .`:  `1:27: unexpected name "NOT"`,
+		`{ ?x ex:p ?y } => { ?x <http://q> ?y } .`:       `1:6: undefined prefix "ex"`,
+		`{ ?x <http://p> ?y } => { ?x <http://q> ?y } }`: "1:46: expected '.', found '}'",
+	} {
+		_, err := ParseRules(strings.NewReader(doc), Options{})
+		if err == nil || err.Error() != message {
+			t.Errorf("%s: error %v instead of %s", doc, err, message)
+		}
+	}
+}
@@ -12,13 +12,13 @@ var CRorLF = newSet([]rune{0x0D, 0x0A}...)
 
 var WS = newSet([]rune{0x20, 0x09, 0x0D, 0x0A}...)
 
-var HEX = newSet().addRange(0, 9).addRange('a', 'f').addRange('A', 'F')
+var HEX = newSet().addRange('0', '9').addRange('a', 'f').addRange('A', 'F')
 
 var PN_LOCAL_ESC = newSet([]rune{'_', '~', '.', '-', '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', '/', '?', '#', '@', '%'}...)
 
-var PN_CHARS_BASE = func() RuneSet {
+var PN_CHARS_BASE = func() *RuneSet {
 	ret := newSet()
-	ret.addRange('A', 'Z').addRange('A', 'Z').addRange(0xC0, 0xD6).addRange(0xD8, 0xF6).addRange(0xF8, 0x02FF)
+	ret.addRange('A', 'Z').addRange('a', 'z').addRange(0xC0, 0xD6).addRange(0xD8, 0xF6).addRange(0xF8, 0x02FF)
 	ret.addRange(0x0370, 0x037D)
 	ret.addRange(0x037F, 0x1FFF)
 	ret.addRange(0x200C, 0x200D)
@@ -27,14 +27,30 @@ var PN_CHARS_BASE = func() RuneSet {
 	ret.addRange(0x3001, 0xD7FF)
 	ret.addRange(0xF900, 0xFDCF)
 	ret.addRange(0xFDF0, 0xFFFD)
-	ret.addRange(0xFDF0, 0xFFFD)
 	ret.addRange(0x00010000, 0x000EFFFF)
 	return ret
 }()
 
 var PN_CHARS_U = PN_CHARS_BASE.copy().add('_')
 
-var PN_CHARS = PN_CHARS_U.add('-').addRange('0', '9').add(0xB7).addRange(0x0300, 0x036F).addRange(0x203F, 0x2040)
+var PN_CHARS = PN_CHARS_U.copy().add('-').addRange('0', '9').add(0xB7).addRange(0x0300, 0x036F).addRange(0x203F, 0x2040)
+
+// not terminals either, the sets used by the tokenizer loops
+
+// first character of a blank node label
+var BLANK_NODE_LABEL_START = PN_CHARS_U.copy().addRange('0', '9')
+
+// first character of a local name, PLX is handled apart
+var PN_LOCAL_START = PN_CHARS_U.copy().add(':').addRange('0', '9')
+
+// any other character of a local name, PLX is handled apart
+var PN_LOCAL_CHARS = PN_CHARS.copy().add(':')
+
+// first character of a SPARQL variable name
+var VARNAME_START = PN_CHARS_U.copy().addRange('0', '9')
+
+// any other character of a SPARQL variable name
+var VARNAME_CHARS = VARNAME_START.copy().add(0xB7).addRange(0x0300, 0x036F).addRange(0x203F, 0x2040)
 
 const HEX2 = "[0-9]|[A-F]|[a-f]"
 
@@ -4,6 +4,11 @@
  */
 package parser
 
+import (
+	"fmt"
+	"strings"
+)
+
 type TokenType int
 
 const (
@@ -37,506 +42,877 @@ const (
 	Coma
 	Dot
 	BaseTag
+	Version
+	VersionTag
+	LangTag
+	DoubleCaret
+	Tilde
+	// the value of an Error token is the error message
+	Error
+	EOF
+	// only sent when the tokenizer keeps comments, the value is the comment
+	// with its leading #
+	CommentText
+	// the tokens of SPARQL: variables without their ? or $, keywords and
+	// function names as written, operators
+	Variable
+	Name
+	Operator
 )
+
+var tokenTypeNames = map[TokenType]string{
+	Prefix:               "PREFIX",
+	Base:                 "BASE",
+	IRI:                  "IRI",
+	A:                    "'a'",
+	String:               "string",
+	BlankNodeLabel:       "blank node label",
+	BlankNodeOpening:     "'['",
+	BlankNodeClosing:     "']'",
+	BlankNodeAnonymous:   "'[]'",
+	CollectionOpening:    "'('",
+	CollectionClosing:    "')'",
+	EmptyCollection:      "'()'",
+	Boolean:              "boolean",
+	Graph:                "GRAPH",
+	GraphOpening:         "'{'",
+	GraphClosing:         "'}'",
+	TripleTermOpening:    "'<<('",
+	TripleTermClosing:    "')>>'",
+	ReifiedTripleOpening: "'<<'",
+	ReifiedTripleClosing: "'>>'",
+	AnnotationOpening:    "'{|'",
+	AnnotationClosing:    "'|}'",
+	Number:               "number",
+	PNameNS:              "prefix",
+	PNameLN:              "prefixed name",
+	PrefixTag:            "@prefix",
+	SemiColumn:           "';'",
+	Coma:                 "','",
+	Dot:                  "'.'",
+	BaseTag:              "@base",
+	Version:              "VERSION",
+	VersionTag:           "@version",
+	LangTag:              "language tag",
+	DoubleCaret:          "'^^'",
+	Tilde:                "'~'",
+	Error:                "error",
+	EOF:                  "end of input",
+	CommentText:          "comment",
+	Variable:             "variable",
+	Name:                 "name",
+	Operator:             "operator",
+}
+
+func (this TokenType) String() string {
+	if name, ok := tokenTypeNames[this]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenType(%d)", int(this))
+}
+
 const BufferSize int = 1 << 30
 
+// values are unescaped: strings, IRIs and local names hold the characters
+// they denote, blank node labels and language tags come without their
+// leading _: and @
 type Token struct {
 	value     string
 	tokenType TokenType
+	line      int
+	col       int
+}
+
+func (this *Token) Type() TokenType {
+	return this.tokenType
+}
+
+func (this *Token) Value() string {
+	return this.value
+}
+
+func (this *Token) Line() int {
+	return this.line
 }
 
-// var WS = [4]byte
+func (this *Token) Col() int {
+	return this.col
+}
+
+func (this *Token) String() string {
+	if this.value == "" {
+		return this.tokenType.String()
+	}
+	return fmt.Sprintf("%s %q", this.tokenType, this.value)
+}
 
 var QUOTES = map[byte]struct{}{'"': struct{}{}, '\'': struct{}{}}
 
+// returned by next() once the source is closed
+const eof rune = -1
+
+// a rune and the position it was read at
+type runePos struct {
+	val  rune
+	line int
+	col  int
+}
+
+// how many runes can be pushed back
+const lookBehind = 16
+
 type Tokenizer struct {
-	source    <-chan rune
-	target    chan<- *Token
-	tmp       []rune
-	toResolve []rune
-	curValue  string
+	source <-chan rune
+	target chan<- *Token
+	// the last runes read, most recent last
+	tmp []runePos
+	// runes pushed back, next one to be read last
+	toResolve []runePos
+	curValue  strings.Builder
+	// position of the next rune read from source
+	line int
+	col  int
+	// send CommentText tokens
+	comments bool
+	// read SPARQL: variables, operators and names which are not Turtle
+	// keywords
+	sparql bool
 }
 
 func NewTokenizer(source <-chan rune, target chan<- *Token) *Tokenizer {
 	return &Tokenizer{
-		source: source,
-		target: target,
-		// longest terminal literals are """ ''' <<( and )>>
-		tmp:       make([]rune, 0, 3),
-		toResolve: make([]rune, 0, 3),
-		curValue:  "",
+		source:    source,
+		target:    target,
+		tmp:       make([]runePos, 0, lookBehind),
+		toResolve: make([]runePos, 0, lookBehind),
+		line:      1,
+		col:       1,
 	}
 }
 
-func (this *Tokenizer) run() {
-	defer close(this.target)
-	val, ok := <-this.source
-	if !ok {
-		return
+// NewSPARQLTokenizer reads the tokens of a SPARQL query or update with
+// Next
+func NewSPARQLTokenizer(text string) *Tokenizer {
+	runes := []rune(text)
+	source := make(chan rune, len(runes))
+	for _, r := range runes {
+		source <- r
 	}
-	for {
+	close(source)
+	ret := NewTokenizer(source, nil)
+	ret.sparql = true
+	return ret
+}
 
-		// wild white space
+// the next token, Error tokens carry their position too
+func (this *Tokenizer) Next() *Token {
+	return this.nextToken()
+}
 
-		if WS.contains(val) {
-			val, ok = <-this.source
-			if !ok {
-				return
-			}
+func (this *Tokenizer) run() {
+	defer close(this.target)
+	for {
+		token := this.nextToken()
+		this.target <- token
+		if token.tokenType == EOF {
+			return
 		}
+	}
+}
 
-		// double quote string
-
-		if val == '"' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '"' {
-				this.curValue += string(val)
-				val = <-this.source
-
-				if val == '"' {
-					this.curValue += string(val)
-					val = <-this.source
-					//we are in a long double quote
-
-					nofConsecutiveQuotes := 0
-					for nofConsecutiveQuotes != 3 {
-						if val == '"' {
-							nofConsecutiveQuotes += 1
-						} else {
-							nofConsecutiveQuotes = 0
-						}
-
-						this.curValue += string(val)
-						val = <-this.source
-					}
-					this.target <- &Token{
-						value:     this.curValue,
-						tokenType: String,
-					}
-					this.curValue = ""
-
-				} else {
-					// empty double quote
-					this.target <- &Token{
-						value:     this.curValue,
-						tokenType: String,
-					}
-					this.curValue = ""
-				}
-			}
-
+func (this *Tokenizer) next() rune {
+	var cur runePos
+	if n := len(this.toResolve); n > 0 {
+		cur = this.toResolve[n-1]
+		this.toResolve = this.toResolve[:n-1]
+	} else {
+		val, ok := <-this.source
+		if !ok {
+			val = eof
 		}
-
-		// simple quote string
-
-		if val == '\'' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '\'' {
-				this.curValue += string(val)
-				val = <-this.source
-
-				if val == '\'' {
-					// we are in a long single quoted string
-					nofConsecutiveQuotes := 0
-					for nofConsecutiveQuotes != 3 {
-						if val == '\'' {
-							nofConsecutiveQuotes += 1
-						} else {
-							nofConsecutiveQuotes = 0
-						}
-
-						this.curValue += string(val)
-						val = <-this.source
-					}
-					this.target <- &Token{
-						value:     this.curValue,
-						tokenType: String,
-					}
-					this.curValue = ""
-				} else {
-					// empty single quote
-					this.target <- &Token{
-						value:     this.curValue,
-						tokenType: String,
-					}
-					this.curValue = ""
-				}
-			}
-
+		cur = runePos{val: val, line: this.line, col: this.col}
+		if val == '\n' {
+			this.line++
+			this.col = 1
+		} else if val != eof {
+			this.col++
 		}
+	}
+	if len(this.tmp) == lookBehind {
+		copy(this.tmp, this.tmp[1:])
+		this.tmp = this.tmp[:lookBehind-1]
+	}
+	this.tmp = append(this.tmp, cur)
+	return cur.val
+}
 
-		// blank node opening
-
-		if val == '[' {
-			this.curValue += string(val)
-			val = <-this.source
-			// skip white spaces
-			_, ok := WS[val]
-			for ok {
-
-				val = <-this.source
-				_, ok = WS[val]
-
-			}
+// pushes back the last rune read
+func (this *Tokenizer) back() {
+	n := len(this.tmp)
+	this.toResolve = append(this.toResolve, this.tmp[n-1])
+	this.tmp = this.tmp[:n-1]
+}
 
-			if val == ']' {
-				this.target <- &Token{
-					tokenType: BlankNodeAnonymous,
-				}
-				this.curValue = ""
+// position of the last rune read
+func (this *Tokenizer) lastPos() (int, int) {
+	if n := len(this.tmp); n > 0 {
+		return this.tmp[n-1].line, this.tmp[n-1].col
+	}
+	return this.line, this.col
+}
 
-			} else {
-				this.target <- &Token{
-					tokenType: BlankNodeOpening,
-				}
-				this.curValue = ""
+func (this *Tokenizer) errorf(format string, args ...interface{}) *Token {
+	return &Token{tokenType: Error, value: fmt.Sprintf(format, args...)}
+}
 
+// skips white spaces, and comments unless they are kept, and reads the next
+// token, EOF tokens are returned for ever once the source is exhausted
+func (this *Tokenizer) nextToken() *Token {
+	for {
+		val := this.next()
+		if WS.contains(val) {
+			continue
+		}
+		line, col := this.lastPos()
+		if val == '#' {
+			text := this.readComment()
+			if !this.comments {
+				continue
 			}
-
+			return &Token{tokenType: CommentText, value: text, line: line, col: col}
 		}
+		token := this.readToken(val)
+		token.line = line
+		token.col = col
+		return token
+	}
+}
 
-		// IRI or reified
+// '#' has been read
+func (this *Tokenizer) readComment() string {
+	this.curValue.Reset()
+	this.curValue.WriteRune('#')
+	val := this.next()
+	for val != '\n' && val != '\r' && val != eof {
+		this.curValue.WriteRune(val)
+		val = this.next()
+	}
+	return this.curValue.String()
+}
 
+func (this *Tokenizer) readToken(val rune) *Token {
+	if this.sparql {
+		if token := this.readSPARQLToken(val); token != nil {
+			return token
+		}
+	}
+	switch val {
+	case eof:
+		return &Token{tokenType: EOF}
+	case '"', '\'':
+		return this.readString(val)
+	case '<':
+		val = this.next()
 		if val == '<' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '<' {
-				this.curValue += string(val)
-				val = <-this.source
-				if val == '(' {
-					this.target <- &Token{tokenType: TripleTermOpening}
-					this.curValue = ""
-				} else {
-					this.target <- &Token{tokenType: ReifiedTripleOpening}
-					this.curValue = ""
-				}
-			} else {
-				// in IRi
+			val = this.next()
+			if val == '(' {
+				return &Token{tokenType: TripleTermOpening}
 			}
+			this.back()
+			return &Token{tokenType: ReifiedTripleOpening}
+		}
+		this.back()
+		return this.readIRI()
+	case '>':
+		if this.next() == '>' {
+			return &Token{tokenType: ReifiedTripleClosing}
+		}
+		this.back()
+		return this.errorf("unexpected '>'")
+	case '[':
+		val = this.next()
+		for WS.contains(val) {
+			val = this.next()
+		}
+		if val == ']' {
+			return &Token{tokenType: BlankNodeAnonymous}
+		}
+		this.back()
+		return &Token{tokenType: BlankNodeOpening}
+	case ']':
+		return &Token{tokenType: BlankNodeClosing}
+	case '(':
+		val = this.next()
+		for WS.contains(val) {
+			val = this.next()
 		}
-
-		// closing )
-
 		if val == ')' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '>' {
-				this.curValue += string(val)
-				val = <-this.source
-				if val == '>' {
-					this.target <- &Token{tokenType: TripleTermClosing}
-					this.curValue = ""
-				}
-				// else error ?
-			} else {
-				this.target <- &Token{tokenType: CollectionClosing}
-			}
+			return &Token{tokenType: EmptyCollection}
 		}
-
-		// closing >
-
-		if val == '>' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '>' {
-				this.target <- &Token{tokenType: ReifiedTripleClosing}
-				this.curValue = ""
+		this.back()
+		return &Token{tokenType: CollectionOpening}
+	case ')':
+		if this.next() == '>' {
+			if this.next() == '>' {
+				return &Token{tokenType: TripleTermClosing}
 			}
+			this.back()
 		}
-
-		// {
-
-		if val == '{' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == '|' {
-				this.target <- &Token{tokenType: AnnotationOpening}
-
-			} else {
-				this.target <- &Token{tokenType: GraphOpening}
-			}
+		this.back()
+		return &Token{tokenType: CollectionClosing}
+	case '{':
+		if this.next() == '|' {
+			return &Token{tokenType: AnnotationOpening}
 		}
-
-		// _
-
-		if val == '_' {
-			this.curValue += string(val)
-			val = <-this.source
-			if val == ':' {
-				this.curValue += string(val)
-				val = <-this.source
-				if ((val >= '0') && (val <= '9')) || PN_CHARS_U.contains(val) {
-					this.curValue += string(val)
-					val = <-this.source
-					for PN_CHARS.add('.').contains(val) {
-						this.curValue += string(val)
-						val = <-this.source
-					}
-					if this.curValue[len(this.curValue)-1] == '.' {
-						panic("blank node label cannot finish with '.'")
-					} else {
-						this.target <- &Token{value: this.curValue, tokenType: BlankNodeLabel}
-						this.curValue = ""
-					}
-
-				} else {
-					panic("")
-				}
-
-			}
-
+		this.back()
+		return &Token{tokenType: GraphOpening}
+	case '}':
+		return &Token{tokenType: GraphClosing}
+	case '|':
+		if this.next() == '}' {
+			return &Token{tokenType: AnnotationClosing}
+		}
+		this.back()
+		return this.errorf("unexpected '|'")
+	case '^':
+		if this.next() == '^' {
+			return &Token{tokenType: DoubleCaret}
 		}
+		this.back()
+		return this.errorf("unexpected '^'")
+	case '~':
+		return &Token{tokenType: Tilde}
+	case ',':
+		return &Token{tokenType: Coma}
+	case ';':
+		return &Token{tokenType: SemiColumn}
+	case '.':
+		next := this.next()
+		this.back()
+		if next >= '0' && next <= '9' {
+			return this.readNumber(val)
+		}
+		return &Token{tokenType: Dot}
+	case '@':
+		return this.readAt()
+	case '_':
+		return this.readBlankNodeLabel()
+	}
+	if val == '+' || val == '-' || (val >= '0' && val <= '9') {
+		return this.readNumber(val)
+	}
+	if val == ':' || PN_CHARS_BASE.contains(val) {
+		return this.readName(val)
+	}
+	return this.errorf("unexpected character %q", val)
+}
 
-		// numbers
-		if (val == '+') || (val == '-') || ((val >= '0') && (val <= '9')) {
-			this.curValue += string(val)
-			val = <-this.source
-			for (val >= '0') && (val <= '9') {
-				this.curValue += string(val)
-				val = <-this.source
-			}
-			if val == '.' {
-				this.curValue += string(val)
-				val = <-this.source
+// '<' has been read
+func (this *Tokenizer) readIRI() *Token {
+	this.curValue.Reset()
+	for {
+		val := this.next()
+		switch {
+		case val == '>':
+			return &Token{tokenType: IRI, value: this.curValue.String()}
+		case val == '\\':
+			escaped, err := this.readUchar()
+			if err != nil {
+				return err
 			}
-			for (val >= '0') && (val <= '9') {
-				this.curValue += string(val)
-				val = <-this.source
+			this.curValue.WriteRune(escaped)
+		case val == eof || val == '\n' || val == '\r':
+			this.back()
+			return this.errorf("unterminated IRI")
+		case val <= 0x20 || strings.ContainsRune("<\"{}|^`", val):
+			return this.errorf("invalid character %q in IRI", val)
+		default:
+			this.curValue.WriteRune(val)
+		}
+	}
+}
 
-			}
-			if (val == 'e') || (val == 'E') {
-				if (val == '+') || (val == '-') {
-					this.curValue += string(val)
-					val = <-this.source
+// '\' has been read
+func (this *Tokenizer) readUchar() (rune, *Token) {
+	val := this.next()
+	length := 0
+	if val == 'u' {
+		length = 4
+	} else if val == 'U' {
+		length = 8
+	} else {
+		this.back()
+		return 0, this.errorf("invalid escape sequence")
+	}
+	var ret rune
+	for i := 0; i < length; i++ {
+		val = this.next()
+		if !HEX.contains(val) {
+			this.back()
+			return 0, this.errorf("invalid escape sequence")
+		}
+		ret = ret<<4 | hexValue(val)
+	}
+	return ret, nil
+}
 
-				}
-				for (val >= '0') && (val <= '9') {
-					this.curValue += string(val)
-					val = <-this.source
+func hexValue(val rune) rune {
+	switch {
+	case val >= '0' && val <= '9':
+		return val - '0'
+	case val >= 'a' && val <= 'f':
+		return val - 'a' + 10
+	default:
+		return val - 'A' + 10
+	}
+}
 
-				}
+// '\' has been read, either an ECHAR or an UCHAR
+func (this *Tokenizer) readEscape() (rune, *Token) {
+	val := this.next()
+	switch val {
+	case 't':
+		return '\t', nil
+	case 'b':
+		return '\b', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 'f':
+		return '\f', nil
+	case '"', '\'', '\\':
+		return val, nil
+	case 'u', 'U':
+		this.back()
+		return this.readUchar()
+	}
+	this.back()
+	return 0, this.errorf("invalid escape sequence")
+}
 
+// the opening quote has been read
+func (this *Tokenizer) readString(quote rune) *Token {
+	this.curValue.Reset()
+	val := this.next()
+	if val == quote {
+		if this.next() == quote {
+			return this.readLongString(quote)
+		}
+		// empty string
+		this.back()
+		return &Token{tokenType: String}
+	}
+	for {
+		switch val {
+		case quote:
+			return &Token{tokenType: String, value: this.curValue.String()}
+		case '\\':
+			escaped, err := this.readEscape()
+			if err != nil {
+				return err
 			}
-			this.target <- &Token{
-				value:     this.curValue,
-				tokenType: Number,
-			}
-
+			this.curValue.WriteRune(escaped)
+		case '\n', '\r', eof:
+			this.back()
+			return this.errorf("unterminated string")
+		default:
+			this.curValue.WriteRune(val)
 		}
+		val = this.next()
+	}
+}
 
-		// prefix and possible name
-
-		// PN_CHARS_BASE ((PN_CHARS | '.')* PN_CHARS)?
-		if PN_CHARS_BASE.contains(val) {
-			this.curValue += string(val)
-			val = <-this.source
-			for PN_CHARS.add('.').contains(val) {
-				this.curValue += string(val)
-				val = <-this.source
-			}
-			if this.curValue[len(this.curValue)-1] == '.' {
-				panic("cannot end with '.'")
-			}
-			if val == ':' {
-				this.curValue += string(val)
-				val = <-this.source
-			} else {
-				panic("error")
+// the three opening quotes have been read
+func (this *Tokenizer) readLongString(quote rune) *Token {
+	nofConsecutiveQuotes := 0
+	for {
+		val := this.next()
+		if val == quote {
+			nofConsecutiveQuotes += 1
+			if nofConsecutiveQuotes == 3 {
+				return &Token{tokenType: String, value: this.curValue.String()}
 			}
-			if PN_CHARS_U.add(':').addRange('0', '9').add('%').contains(val) {
-				if PN_CHARS_U.add(':').addRange('0', '9').contains(val) {
-					this.curValue += string(val)
-					val = <-this.source
-				}
-				val = this.ifPlxEsc(val)
-
-				for PN_CHARS.add(':').add('.').addRange('0', '9').add('%').contains(val) {
-					if PN_CHARS.add(':').add('.').addRange('0', '9').contains(val) {
-						this.curValue += string(val)
-						val = <-this.source
-					}
-					val = this.ifPlxEsc(val)
-
-				}
-				if this.curValue[len(this.curValue)-1] == '.' {
-					panic("cannot end with '.'")
-				}
-				this.target <- &Token{value: this.curValue, tokenType: PNameLN}
-				this.curValue = ""
-
-			} else {
-				this.target <- &Token{value: this.curValue, tokenType: PNameNS}
-				this.curValue = ""
+			continue
+		}
+		for ; nofConsecutiveQuotes > 0; nofConsecutiveQuotes-- {
+			this.curValue.WriteRune(quote)
+		}
+		switch val {
+		case '\\':
+			escaped, err := this.readEscape()
+			if err != nil {
+				return err
 			}
-
+			this.curValue.WriteRune(escaped)
+		case eof:
+			return this.errorf("unterminated long string")
+		default:
+			this.curValue.WriteRune(val)
 		}
+	}
+}
 
-		// @ lang dir or base or prefix tag
-		// '@' [a-zA-Z]+ ('-' [a-zA-Z0-9]+)* ('--' [a-zA-Z]+)?
-		if val == '@' {
-			this.curValue += string(val)
-			val = <-this.source
-			for (val >= 'a' && val <= 'z') || (val >= 'A' && val >= 'Z') {
-				this.curValue += string(val)
-				val = <-this.source
-				if this.curValue == "@base" {
-					this.target <- &Token{tokenType: BaseTag}
-					break
-				}
-				if this.curValue == "@prefix" {
-					this.target <- &Token{tokenType: PrefixTag}
-					break
-				}
+// reads runes of chars, '.' are accepted as long as they are followed by
+// one of chars. When plx is set PLX escapes are accepted too.
+func (this *Tokenizer) readNameChars(chars *RuneSet, plx bool) *Token {
+	for {
+		val := this.next()
+		if val == '.' {
+			nofDots := 1
+			val = this.next()
+			for val == '.' {
+				nofDots++
+				val = this.next()
 			}
-			for val == '-' {
-				this.curValue += string(val)
-				val = <-this.source
-
-				if val != '-' {
-					if !(val >= 'a' && val <= 'z') || (val >= 'A' && val >= 'Z') || (val >= '0' && val <= '9') {
-						panic("must be a alphadigit")
-					}
-					for (val >= 'a' && val <= 'z') || (val >= 'A' && val >= 'Z') || (val >= '0' && val <= '9') {
-						this.curValue += string(val)
-						val = <-this.source
-					}
-				} else {
-					this.curValue += string(val)
-					val = <-this.source
-					if (val >= 'a' && val <= 'z') || (val >= 'A' && val >= 'Z') {
-						panic("alphabet is needed here")
-					}
-					for (val >= 'a' && val <= 'z') || (val >= 'A' && val >= 'Z') {
-						this.curValue += string(val)
-						val = <-this.source
-					}
-					break
+			if !chars.contains(val) && !(plx && (val == '\\' || val == '%')) {
+				// the dots belong to the next token
+				this.back()
+				for i := 0; i < nofDots; i++ {
+					this.back()
 				}
+				return nil
 			}
-		}
-
-		// collection
-
-		if val == '(' {
-			val = <-this.source
-			_, ok := WS[val]
-
-			for ok {
-				val = <-this.source
-				_, ok = WS[val]
+			for i := 0; i < nofDots; i++ {
+				this.curValue.WriteRune('.')
 			}
-			if val == ')' {
-				this.target <- &Token{tokenType: EmptyCollection}
-				val = <-this.source
-			} else {
-				this.target <- &Token{tokenType: CollectionOpening}
+		}
+		if chars.contains(val) {
+			this.curValue.WriteRune(val)
+		} else if plx && (val == '\\' || val == '%') {
+			if err := this.readPlx(val); err != nil {
+				return err
 			}
+		} else {
+			this.back()
+			return nil
 		}
+	}
+}
 
+// '\' or '%' has been read. Escaped characters are unescaped, percent
+// encoded ones are kept as is.
+func (this *Tokenizer) readPlx(val rune) *Token {
+	if val == '\\' {
+		val = this.next()
+		if !PN_LOCAL_ESC.contains(val) {
+			this.back()
+			return this.errorf("invalid escape sequence in local name")
+		}
+		this.curValue.WriteRune(val)
+		return nil
+	}
+	this.curValue.WriteRune(val)
+	for i := 0; i < 2; i++ {
+		val = this.next()
+		if !HEX.contains(val) {
+			this.back()
+			return this.errorf("invalid percent encoding in local name")
+		}
+		this.curValue.WriteRune(val)
 	}
+	return nil
+}
 
+// '_' has been read
+func (this *Tokenizer) readBlankNodeLabel() *Token {
+	if this.next() != ':' {
+		this.back()
+		return this.errorf("unexpected character '_'")
+	}
+	this.curValue.Reset()
+	val := this.next()
+	if !BLANK_NODE_LABEL_START.contains(val) {
+		this.back()
+		return this.errorf("invalid blank node label")
+	}
+	this.curValue.WriteRune(val)
+	if err := this.readNameChars(PN_CHARS, false); err != nil {
+		return err
+	}
+	return &Token{tokenType: BlankNodeLabel, value: this.curValue.String()}
 }
 
-// never complains if the following is not i th expected rtbnf'"\
-func (this *Tokenizer) ifEcharEsc(val rune) rune {
-	for val == '\\' {
-		this.curValue += string(val)
-		val = <-this.source
-		this.curValue += string(val)
-		val = <-this.source
+// reads keywords, PNAME_NS and PNAME_LN. val is ':' or in PN_CHARS_BASE
+func (this *Tokenizer) readName(val rune) *Token {
+	this.curValue.Reset()
+	if val != ':' {
+		this.curValue.WriteRune(val)
+		this.readNameChars(PN_CHARS, false)
+		if this.next() != ':' {
+			this.back()
+			return this.keyword(this.curValue.String())
+		}
+	}
+	this.curValue.WriteRune(':')
+	val = this.next()
+	if PN_LOCAL_START.contains(val) {
+		this.curValue.WriteRune(val)
+	} else if val == '\\' || val == '%' {
+		if err := this.readPlx(val); err != nil {
+			return err
+		}
+	} else {
+		this.back()
+		return &Token{tokenType: PNameNS, value: this.curValue.String()}
+	}
+	if err := this.readNameChars(PN_LOCAL_CHARS, true); err != nil {
+		return err
 	}
-	return val
+	return &Token{tokenType: PNameLN, value: this.curValue.String()}
 }
 
-// does not complains in string. complains in IRI
-func (this *Tokenizer) ifUcharrEsc(val rune) rune {
-	for val == '\\' {
-		this.curValue += string(val)
-		val = <-this.source
-		if val == 'u' {
-			this.curValue += string(val)
-			val = <-this.source
-			for i := 0; i < 4; i++ {
-				if HEX.contains(val) {
-					this.curValue += string(val)
-					val = <-this.source
-				} else {
-					panic("unexpected escaped char")
-				}
+func (this *Tokenizer) keyword(name string) *Token {
+	switch name {
+	case "a":
+		return &Token{tokenType: A}
+	case "true", "false":
+		return &Token{tokenType: Boolean, value: name}
+	}
+	switch strings.ToUpper(name) {
+	case "PREFIX":
+		return &Token{tokenType: Prefix}
+	case "BASE":
+		return &Token{tokenType: Base}
+	case "GRAPH":
+		return &Token{tokenType: Graph}
+	case "VERSION":
+		return &Token{tokenType: Version}
+	}
+	if this.sparql {
+		return &Token{tokenType: Name, value: name}
+	}
+	return this.errorf("unexpected name %q", name)
+}
 
+// the tokens which only exist in SPARQL, nil for the others
+func (this *Tokenizer) readSPARQLToken(val rune) *Token {
+	switch val {
+	case '?', '$':
+		next := this.next()
+		this.back()
+		if VARNAME_START.contains(next) {
+			this.curValue.Reset()
+			for val = this.next(); VARNAME_CHARS.contains(val); val = this.next() {
+				this.curValue.WriteRune(val)
 			}
+			this.back()
+			return &Token{tokenType: Variable, value: this.curValue.String()}
+		}
+		if val == '?' {
+			return &Token{tokenType: Operator, value: "?"}
+		}
+		return this.errorf("invalid variable")
+	case '<':
+		next := this.next()
+		if next == '=' {
+			return &Token{tokenType: Operator, value: "<="}
+		}
+		this.back()
+		if next != '<' && !this.iriAhead() {
+			return &Token{tokenType: Operator, value: "<"}
+		}
+	case '>':
+		next := this.next()
+		switch next {
+		case '=':
+			return &Token{tokenType: Operator, value: ">="}
+		case '>':
+			return &Token{tokenType: ReifiedTripleClosing}
+		}
+		this.back()
+		return &Token{tokenType: Operator, value: ">"}
+	case '|':
+		next := this.next()
+		switch next {
+		case '|':
+			return &Token{tokenType: Operator, value: "||"}
+		case '}':
+			return &Token{tokenType: AnnotationClosing}
+		}
+		this.back()
+		return &Token{tokenType: Operator, value: "|"}
+	case '^':
+		if this.next() == '^' {
+			return &Token{tokenType: DoubleCaret}
+		}
+		this.back()
+		return &Token{tokenType: Operator, value: "^"}
+	case '!':
+		if this.next() == '=' {
+			return &Token{tokenType: Operator, value: "!="}
+		}
+		this.back()
+		return &Token{tokenType: Operator, value: "!"}
+	case '&':
+		if this.next() == '&' {
+			return &Token{tokenType: Operator, value: "&&"}
+		}
+		this.back()
+		return this.errorf("unexpected character '&'")
+	case '=':
+		// the implication of N3 rules
+		if this.next() == '>' {
+			return &Token{tokenType: Operator, value: "=>"}
+		}
+		this.back()
+		return &Token{tokenType: Operator, value: "="}
+	case '*', '/':
+		return &Token{tokenType: Operator, value: string(val)}
+	case '+', '-':
+		next := this.next()
+		if next == '.' {
+			next = this.next()
+			this.back()
+		}
+		this.back()
+		if next < '0' || next > '9' {
+			return &Token{tokenType: Operator, value: string(val)}
+		}
+	}
+	return nil
+}
 
-		} else if val == 'U' {
-			this.curValue += string(val)
-			val = <-this.source
-			for i := 0; i < 8; i++ {
-				if HEX.contains(val) {
-					this.curValue += string(val)
-					val = <-this.source
-				} else {
-					panic("unexpected escaped char")
-				}
+// '<' has been read, true if an IRI follows, nothing is consumed
+func (this *Tokenizer) iriAhead() bool {
+	read := append([]runePos(nil), this.tmp...)
+	saved := len(read)
+	ret := false
+	for {
+		val := this.next()
+		read = append(read, this.tmp[len(this.tmp)-1])
+		if val == '>' {
+			ret = true
+			break
+		}
+		if val == eof || val <= 0x20 || strings.ContainsRune("<\"{}|^`", val) {
+			break
+		}
+	}
+	for i := len(read) - 1; i >= saved; i-- {
+		this.toResolve = append(this.toResolve, read[i])
+	}
+	this.tmp = append(this.tmp[:0], read[:saved]...)
+	return ret
+}
 
+// val is a sign, a digit or '.' followed by a digit
+func (this *Tokenizer) readNumber(val rune) *Token {
+	this.curValue.Reset()
+	if val == '+' || val == '-' {
+		this.curValue.WriteRune(val)
+		val = this.next()
+	}
+	nofDigits := 0
+	for val >= '0' && val <= '9' {
+		this.curValue.WriteRune(val)
+		nofDigits++
+		val = this.next()
+	}
+	if val == '.' {
+		next := this.next()
+		if next >= '0' && next <= '9' {
+			this.curValue.WriteRune(val)
+			val = next
+			for val >= '0' && val <= '9' {
+				this.curValue.WriteRune(val)
+				nofDigits++
+				val = this.next()
 			}
-
+		} else if (next == 'e' || next == 'E') && nofDigits > 0 {
+			this.curValue.WriteRune(val)
+			val = next
 		} else {
-			panic("unexpected escaped char")
+			// the dot ends the statement
+			this.back()
 		}
-
 	}
-	return val
+	if nofDigits == 0 {
+		this.back()
+		return this.errorf("invalid number")
+	}
+	if val == 'e' || val == 'E' {
+		this.curValue.WriteRune(val)
+		val = this.next()
+		if val == '+' || val == '-' {
+			this.curValue.WriteRune(val)
+			val = this.next()
+		}
+		nofDigits = 0
+		for val >= '0' && val <= '9' {
+			this.curValue.WriteRune(val)
+			nofDigits++
+			val = this.next()
+		}
+		if nofDigits == 0 {
+			this.back()
+			return this.errorf("invalid exponent")
+		}
+	}
+	this.back()
+	return &Token{tokenType: Number, value: this.curValue.String()}
 }
 
-func (this *Tokenizer) ifEcharorUcharEsc(val rune) rune {
-	for val == '\\' {
-		this.curValue += string(val)
-		val = <-this.source
-		if (val == 'u') || (val == 'U') {
-			this.curValue += string(val)
-			val = <-this.source
+func isLetter(val rune) bool {
+	return (val >= 'a' && val <= 'z') || (val >= 'A' && val <= 'Z')
+}
 
-			for HEX.contains(val) {
+func isAlphaDigit(val rune) bool {
+	return isLetter(val) || (val >= '0' && val <= '9')
+}
 
-				this.curValue += string(val)
-				val = <-this.source
-			}
-		} else {
-			this.curValue += string(val)
-			val = <-this.source
+// '@' has been read
+// '@' [a-zA-Z]+ ('-' [a-zA-Z0-9]+)* ('--' [a-zA-Z]+)?
+func (this *Tokenizer) readAt() *Token {
+	this.curValue.Reset()
+	val := this.next()
+	for isLetter(val) {
+		this.curValue.WriteRune(val)
+		val = this.next()
+	}
+	if this.curValue.Len() == 0 {
+		this.back()
+		return this.errorf("invalid language tag")
+	}
+	if val != '-' {
+		switch this.curValue.String() {
+		case "prefix":
+			this.back()
+			return &Token{tokenType: PrefixTag}
+		case "base":
+			this.back()
+			return &Token{tokenType: BaseTag}
+		case "version":
+			this.back()
+			return &Token{tokenType: VersionTag}
 		}
-
 	}
-	return val
-}
-
-// always complains
-func (this *Tokenizer) ifPlxEsc(val rune) rune {
-	for (val == '\\') || (val == '%') {
-		if val == '\\' {
-			this.curValue += string(val)
-			val = <-this.source
-			if !PN_LOCAL_ESC.contains(val) {
-				panic("")
-			}
-			this.curValue += string(val)
-			val = <-this.source
-		} else {
-			this.curValue += string(val)
-			val = <-this.source
-			if !HEX.contains(val) {
-				panic("")
+	for val == '-' {
+		val = this.next()
+		if val == '-' {
+			// direction
+			this.curValue.WriteString("--")
+			val = this.next()
+			if !isLetter(val) {
+				this.back()
+				return this.errorf("invalid language direction")
 			}
-			this.curValue += string(val)
-			val = <-this.source
-			if !HEX.contains(val) {
-				panic("")
+			for isLetter(val) {
+				this.curValue.WriteRune(val)
+				val = this.next()
 			}
-			this.curValue += string(val)
-			val = <-this.source
-
+			break
+		}
+		if !isAlphaDigit(val) {
+			this.back()
+			return this.errorf("invalid language tag")
+		}
+		this.curValue.WriteRune('-')
+		for isAlphaDigit(val) {
+			this.curValue.WriteRune(val)
+			val = this.next()
 		}
 	}
-	return val
+	this.back()
+	return &Token{tokenType: LangTag, value: this.curValue.String()}
 }
@@ -0,0 +1,38 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package rdfc
+
+import "strconv"
+
+// the identifier issuer of the specification: prefix followed by a counter,
+// in order of issue
+type issuer struct {
+	prefix string
+	issued map[string]string
+	// the existing identifiers in order of issue
+	order []string
+}
+
+func newIssuer(prefix string) *issuer {
+	return &issuer{prefix: prefix, issued: make(map[string]string)}
+}
+
+func (this *issuer) issue(existing string) string {
+	if id, ok := this.issued[existing]; ok {
+		return id
+	}
+	id := this.prefix + strconv.Itoa(len(this.order))
+	this.issued[existing] = id
+	this.order = append(this.order, existing)
+	return id
+}
+
+func (this *issuer) copy() *issuer {
+	ret := &issuer{prefix: this.prefix, issued: make(map[string]string, len(this.issued)), order: append([]string{}, this.order...)}
+	for existing, id := range this.issued {
+		ret.issued[existing] = id
+	}
+	return ret
+}
@@ -0,0 +1,379 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package rdfc implements the W3C RDF Dataset Canonicalization algorithm
+// (RDFC-1.0): the blank nodes of a dataset get labels which only depend on
+// the dataset, so that isomorphic datasets have the same canonical N-Quads.
+//
+// Blank nodes inside triple terms are handled like the blank nodes of the
+// statements holding the triple terms.
+package rdfc
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// the work allowed by default, enough for the datasets met in practice
+const DefaultMaxWork = 1000000
+
+var ErrWorkLimit = errors.New("rdfc: work limit exceeded, the dataset may be a poison graph")
+
+type Options struct {
+	// crypto.SHA256, the default, or crypto.SHA384
+	Hash crypto.Hash
+	// the number of N-degree hashes and permutations tried before giving
+	// up with ErrWorkLimit, DefaultMaxWork when 0
+	MaxWork int
+}
+
+type canonicalizer struct {
+	hash    crypto.Hash
+	maxWork int
+	work    int
+
+	statements []*model.Statement
+	// the identifiers of the blank nodes during the algorithm
+	ids map[model.RDFTerm]string
+	// the statements using each blank node
+	quads       map[string][]*model.Statement
+	firstDegree map[string]string
+	canonical   *issuer
+}
+
+// Labels returns the canonical label, c14n followed by a number, of every
+// blank node of statements, whose predicates must be IRIs
+func Labels(statements []*model.Statement, options Options) (map[model.RDFTerm]string, error) {
+	this, err := newCanonicalizer(statements, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := this.run(); err != nil {
+		return nil, err
+	}
+	ret := make(map[model.RDFTerm]string, len(this.ids))
+	for node, id := range this.ids {
+		ret[node] = this.canonical.issued[id]
+	}
+	return ret, nil
+}
+
+// Canonicalize returns the canonical N-Quads document of statements: one
+// line per distinct statement in code point order, blank nodes labelled
+// with their canonical labels. The predicates of statements must be IRIs.
+func Canonicalize(statements []*model.Statement, options Options) (string, error) {
+	labels, err := Labels(statements, options)
+	if err != nil {
+		return "", err
+	}
+	lines := []string{}
+	seen := make(map[string]struct{})
+	for _, statement := range statements {
+		line := nQuad(statement, func(node model.RDFTerm) string { return labels[node] })
+		if _, ok := seen[line]; !ok {
+			seen[line] = struct{}{}
+			lines = append(lines, line)
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, ""), nil
+}
+
+func newCanonicalizer(statements []*model.Statement, options Options) (*canonicalizer, error) {
+	this := &canonicalizer{
+		hash:        options.Hash,
+		maxWork:     options.MaxWork,
+		ids:         make(map[model.RDFTerm]string),
+		quads:       make(map[string][]*model.Statement),
+		firstDegree: make(map[string]string),
+		canonical:   newIssuer("c14n"),
+	}
+	if this.hash == 0 {
+		this.hash = crypto.SHA256
+	}
+	if this.hash != crypto.SHA256 && this.hash != crypto.SHA384 {
+		return nil, fmt.Errorf("rdfc: unsupported hash %v", this.hash)
+	}
+	if this.maxWork == 0 {
+		this.maxWork = DefaultMaxWork
+	}
+	// the algorithm works on a set of quads
+	seen := make(map[model.Statement]struct{})
+	for _, statement := range statements {
+		if _, ok := seen[*statement]; ok {
+			continue
+		}
+		seen[*statement] = struct{}{}
+		// a generalized statement has no N-Quads form to hash
+		if _, ok := statement.Predicate.(model.IRI); !ok {
+			return nil, fmt.Errorf("rdfc: the predicate %v is not an IRI", statement.Predicate)
+		}
+		this.statements = append(this.statements, statement)
+		used := make(map[string]bool)
+		for _, term := range []model.RDFTerm{statement.Subject, statement.Object, statement.Context} {
+			forEachBlankNode(term, func(node model.RDFTerm) {
+				id, ok := this.ids[node]
+				if !ok {
+					id = "n" + strconv.Itoa(len(this.ids))
+					this.ids[node] = id
+				}
+				if !used[id] {
+					used[id] = true
+					this.quads[id] = append(this.quads[id], statement)
+				}
+			})
+		}
+	}
+	return this, nil
+}
+
+func forEachBlankNode(term model.RDFTerm, f func(node model.RDFTerm)) {
+	switch t := term.(type) {
+	case model.BlankNode:
+		f(t)
+	case model.TripleTerm:
+		forEachBlankNode(t.Subject, f)
+		forEachBlankNode(t.Object, f)
+	}
+}
+
+// the canonicalization algorithm, section 4.4 of the specification
+func (this *canonicalizer) run() error {
+	ids := make([]string, 0, len(this.quads))
+	for id := range this.quads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// the nodes whose first degree hash is unique are labelled first
+	hashToIds := make(map[string][]string)
+	for _, id := range ids {
+		hash := this.hashFirstDegree(id)
+		hashToIds[hash] = append(hashToIds[hash], id)
+	}
+	hashes := sortedKeys(hashToIds)
+	for _, hash := range hashes {
+		if len(hashToIds[hash]) == 1 {
+			this.canonical.issue(hashToIds[hash][0])
+			delete(hashToIds, hash)
+		}
+	}
+
+	// the others by their N-degree hashes
+	for _, hash := range sortedKeys(hashToIds) {
+		type result struct {
+			hash   string
+			issuer *issuer
+		}
+		results := []result{}
+		for _, id := range hashToIds[hash] {
+			if _, ok := this.canonical.issued[id]; ok {
+				continue
+			}
+			temporary := newIssuer("b")
+			temporary.issue(id)
+			hash, issuer, err := this.hashNDegree(id, temporary)
+			if err != nil {
+				return err
+			}
+			results = append(results, result{hash, issuer})
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].hash < results[j].hash
+		})
+		for _, result := range results {
+			for _, existing := range result.issuer.order {
+				this.canonical.issue(existing)
+			}
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	ret := make([]string, 0, len(m))
+	for key := range m {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (this *canonicalizer) digest(s string) string {
+	hash := this.hash.New()
+	hash.Write([]byte(s))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (this *canonicalizer) countWork() error {
+	this.work++
+	if this.work > this.maxWork {
+		return ErrWorkLimit
+	}
+	return nil
+}
+
+// the hash of the statements of id, id written _:a and the other blank
+// nodes _:z
+func (this *canonicalizer) hashFirstDegree(id string) string {
+	if hash, ok := this.firstDegree[id]; ok {
+		return hash
+	}
+	lines := []string{}
+	for _, statement := range this.quads[id] {
+		lines = append(lines, nQuad(statement, func(node model.RDFTerm) string {
+			if this.ids[node] == id {
+				return "a"
+			}
+			return "z"
+		}))
+	}
+	sort.Strings(lines)
+	hash := this.digest(strings.Join(lines, ""))
+	this.firstDegree[id] = hash
+	return hash
+}
+
+// the hash of related seen from a statement at position s, o or g, the
+// predicates being IRIs as checked by newCanonicalizer
+func (this *canonicalizer) hashRelated(related string, statement *model.Statement, issuer *issuer, position string) string {
+	input := position
+	if position != "g" {
+		input += "<" + string(statement.Predicate.(model.IRI)) + ">"
+	}
+	if id, ok := this.canonical.issued[related]; ok {
+		input += "_:" + id
+	} else if id, ok := issuer.issued[related]; ok {
+		input += "_:" + id
+	} else {
+		input += this.hashFirstDegree(related)
+	}
+	return this.digest(input)
+}
+
+// the N-degree hash of id and the issuer labelling the nodes reached
+// along the chosen paths
+func (this *canonicalizer) hashNDegree(id string, pathIssuer *issuer) (string, *issuer, error) {
+	if err := this.countWork(); err != nil {
+		return "", nil, err
+	}
+	related := make(map[string][]string)
+	for _, statement := range this.quads[id] {
+		for _, component := range []struct {
+			term     model.RDFTerm
+			position string
+		}{{statement.Subject, "s"}, {statement.Object, "o"}, {statement.Context, "g"}} {
+			forEachBlankNode(component.term, func(node model.RDFTerm) {
+				if other := this.ids[node]; other != id {
+					hash := this.hashRelated(other, statement, pathIssuer, component.position)
+					related[hash] = append(related[hash], other)
+				}
+			})
+		}
+	}
+
+	data := &strings.Builder{}
+	for _, hash := range sortedKeys(related) {
+		data.WriteString(hash)
+		chosenPath := ""
+		var chosenIssuer *issuer
+		// a path is skipped as soon as it cannot be the smallest
+		worse := func(path string) bool {
+			return chosenPath != "" && len(path) >= len(chosenPath) && path > chosenPath
+		}
+		err := permute(related[hash], func(permutation []string) error {
+			if err := this.countWork(); err != nil {
+				return err
+			}
+			issuerCopy := pathIssuer.copy()
+			path := ""
+			recursion := []string{}
+			for _, node := range permutation {
+				if id, ok := this.canonical.issued[node]; ok {
+					path += "_:" + id
+				} else {
+					if _, ok := issuerCopy.issued[node]; !ok {
+						recursion = append(recursion, node)
+					}
+					path += "_:" + issuerCopy.issue(node)
+				}
+				if worse(path) {
+					return nil
+				}
+			}
+			for _, node := range recursion {
+				hash, resultIssuer, err := this.hashNDegree(node, issuerCopy)
+				if err != nil {
+					return err
+				}
+				path += "_:" + issuerCopy.issue(node) + "<" + hash + ">"
+				issuerCopy = resultIssuer
+				if worse(path) {
+					return nil
+				}
+			}
+			if chosenPath == "" || path < chosenPath {
+				chosenPath = path
+				chosenIssuer = issuerCopy
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		data.WriteString(chosenPath)
+		pathIssuer = chosenIssuer
+	}
+	return this.digest(data.String()), pathIssuer, nil
+}
+
+// calls f with every permutation of items, stops at the first error
+func permute(items []string, f func(permutation []string) error) error {
+	permutation := append([]string{}, items...)
+	var generate func(k int) error
+	generate = func(k int) error {
+		if k == len(permutation) {
+			return f(permutation)
+		}
+		for i := k; i < len(permutation); i++ {
+			permutation[k], permutation[i] = permutation[i], permutation[k]
+			if err := generate(k + 1); err != nil {
+				return err
+			}
+			permutation[k], permutation[i] = permutation[i], permutation[k]
+		}
+		return nil
+	}
+	return generate(0)
+}
+
+// the canonical N-Quads line of statement, blank nodes labelled by label
+func nQuad(statement *model.Statement, label func(node model.RDFTerm) string) string {
+	line := term(statement.Subject, label) + " " + term(statement.Predicate, label) + " " + term(statement.Object, label)
+	if statement.Context != nil {
+		line += " " + term(statement.Context, label)
+	}
+	return line + " .\n"
+}
+
+func term(t model.RDFTerm, label func(node model.RDFTerm) string) string {
+	switch t := t.(type) {
+	case model.BlankNode:
+		return "_:" + label(t)
+	case model.TripleTerm:
+		return "<<( " + term(t.Subject, label) + " " + term(t.Predicate, label) + " " + term(t.Object, label) + " )>>"
+	}
+	return writer.FormatTerm(t, nil)
+}
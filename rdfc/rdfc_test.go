@@ -0,0 +1,142 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package rdfc
+
+import (
+	"crypto"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func parseNQuads(t *testing.T, doc string) []*model.Statement {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{Format: format.NQuads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+// the examples of the specification
+func TestCanonicalize(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			// unique first degree hashes
+			`<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#r> _:e1 .
+_:e0 <http://example.com/#s> <http://example.com/#u> .
+_:e1 <http://example.com/#t> <http://example.com/#u> .
+`,
+			`<http://example.com/#p> <http://example.com/#q> _:c14n0 .
+<http://example.com/#p> <http://example.com/#r> _:c14n1 .
+_:c14n0 <http://example.com/#s> <http://example.com/#u> .
+_:c14n1 <http://example.com/#t> <http://example.com/#u> .
+`,
+		},
+		{
+			// shared first degree hashes
+			`<http://example.com/#p> <http://example.com/#q> _:e0 .
+<http://example.com/#p> <http://example.com/#q> _:e1 .
+_:e0 <http://example.com/#p> _:e2 .
+_:e1 <http://example.com/#p> _:e3 .
+_:e2 <http://example.com/#r> _:e3 .
+`,
+			`<http://example.com/#p> <http://example.com/#q> _:c14n2 .
+<http://example.com/#p> <http://example.com/#q> _:c14n3 .
+_:c14n0 <http://example.com/#r> _:c14n1 .
+_:c14n2 <http://example.com/#p> _:c14n1 .
+_:c14n3 <http://example.com/#p> _:c14n0 .
+`,
+		},
+		{
+			// the escapes of literals: ECHAR for backspaces, tabs, line
+			// feeds, form feeds, carriage returns, " and \, UCHAR for the
+			// other control characters
+			`_:e0 <http://example.com/#p> "a\tb\bc\fd\ne\rf\"g\\h\u0001i\u007Fj" .
+`,
+			`_:c14n0 <http://example.com/#p> "a\tb\bc\fd\ne\rf\"g\\h\u0001i\u007Fj" .
+`,
+		},
+	}
+	for i, c := range cases {
+		got, err := Canonicalize(parseNQuads(t, c.input), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.expected {
+			t.Errorf("case %d: got\n%s\nexpected\n%s", i, got, c.expected)
+		}
+	}
+}
+
+// relabelled and reordered datasets have the same canonical form
+func TestCanonicalizeIsomorphic(t *testing.T) {
+	a := `_:x <http://p> _:y <http://g> .
+_:y <http://p> _:z _:g .
+_:z <http://p> _:x .
+_:z <http://q> "tab\there" .
+_:g <http://p> <<( _:x <http://p> "1" )>> .
+`
+	b := `_:k <http://p> <<( _:c <http://p> "1" )>> .
+_:b <http://p> _:c .
+_:b <http://q> "tab\there" .
+_:a <http://p> _:b _:k .
+_:c <http://p> _:a <http://g> .
+_:c <http://p> _:a <http://g> .
+`
+	for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA384} {
+		first, err := Canonicalize(parseNQuads(t, a), Options{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := Canonicalize(parseNQuads(t, b), Options{Hash: hash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first != second || strings.Count(first, "\n") != 5 || !strings.Contains(first, `"tab\there"`) {
+			t.Errorf("%v: different canonical forms\n%s\n%s", hash, first, second)
+		}
+	}
+	if _, err := Canonicalize(nil, Options{Hash: crypto.MD5}); err == nil {
+		t.Errorf("MD5 was accepted")
+	}
+	node := model.NewAnonymousBlankNode()
+	generalized := []*model.Statement{{Subject: node, Predicate: node, Object: model.IRI("http://o")}}
+	if _, err := Canonicalize(generalized, Options{}); err == nil {
+		t.Errorf("a blank node predicate was accepted")
+	}
+}
+
+// every node of a clique looks the same, the number of paths to try grows
+// with the factorial of its size
+func TestWorkLimit(t *testing.T) {
+	nodes := []model.RDFTerm{}
+	for i := 0; i < 8; i++ {
+		nodes = append(nodes, model.NewAnonymousBlankNode())
+	}
+	statements := []*model.Statement{}
+	for _, s := range nodes {
+		for _, o := range nodes {
+			if s != o {
+				statements = append(statements, &model.Statement{Subject: s, Predicate: model.IRI("http://p"), Object: o})
+			}
+		}
+	}
+	if _, err := Labels(statements, Options{MaxWork: 1000}); !errors.Is(err, ErrWorkLimit) {
+		t.Errorf("unexpected error %v", err)
+	}
+	labels, err := Labels(statements[:7], Options{MaxWork: 1000})
+	if err != nil || len(labels) != 8 {
+		t.Errorf("star: %v %v", labels, err)
+	}
+}
@@ -0,0 +1,737 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// the rules of the OWL 2 RL profile, named like in its tables. Left out are
+// the rules inferring statements about every resource (eq-ref, cls-thing,
+// scm-cls...), those of datatypes, qualified cardinalities and keys, and the
+// schema rules of restrictions.
+
+const owl model.IRI = "http://www.w3.org/2002/07/owl#"
+
+const (
+	owlSameAs                = owl + "sameAs"
+	owlDifferentFrom         = owl + "differentFrom"
+	owlAllDifferent          = owl + "AllDifferent"
+	owlMembers               = owl + "members"
+	owlDistinctMembers       = owl + "distinctMembers"
+	owlFunctionalProperty    = owl + "FunctionalProperty"
+	owlInverseFunctional     = owl + "InverseFunctionalProperty"
+	owlIrreflexiveProperty   = owl + "IrreflexiveProperty"
+	owlSymmetricProperty     = owl + "SymmetricProperty"
+	owlAsymmetricProperty    = owl + "AsymmetricProperty"
+	owlTransitiveProperty    = owl + "TransitiveProperty"
+	owlPropertyChainAxiom    = owl + "propertyChainAxiom"
+	owlEquivalentProperty    = owl + "equivalentProperty"
+	owlPropertyDisjointWith  = owl + "propertyDisjointWith"
+	owlAllDisjointProperties = owl + "AllDisjointProperties"
+	owlInverseOf             = owl + "inverseOf"
+	owlSourceIndividual      = owl + "sourceIndividual"
+	owlAssertionProperty     = owl + "assertionProperty"
+	owlTargetIndividual      = owl + "targetIndividual"
+	owlTargetValue           = owl + "targetValue"
+	owlThing                 = owl + "Thing"
+	owlNothing               = owl + "Nothing"
+	owlIntersectionOf        = owl + "intersectionOf"
+	owlUnionOf               = owl + "unionOf"
+	owlComplementOf          = owl + "complementOf"
+	owlOnProperty            = owl + "onProperty"
+	owlSomeValuesFrom        = owl + "someValuesFrom"
+	owlAllValuesFrom         = owl + "allValuesFrom"
+	owlHasValue              = owl + "hasValue"
+	owlMaxCardinality        = owl + "maxCardinality"
+	owlOneOf                 = owl + "oneOf"
+	owlEquivalentClass       = owl + "equivalentClass"
+	owlDisjointWith          = owl + "disjointWith"
+	owlAllDisjointClasses    = owl + "AllDisjointClasses"
+)
+
+// the predicates whose objects are lists
+var listPredicates = []model.IRI{owlIntersectionOf, owlUnionOf, owlOneOf, owlPropertyChainAxiom, owlMembers, owlDistinctMembers}
+
+// NewOWL returns a reasoner applying the rules of OWL 2 RL, those of RDFS
+// included
+func NewOWL(options Options) *Reasoner {
+	rules := []rule{
+		rdfs2, rdfs3, rdfs5, rdfs7, rdfs9, rdfs11,
+		equality, properties, chains, negativeAssertions, classes, restrictions, schemas, lists,
+	}
+	var axioms []*model.Statement
+	if options.Full {
+		rules = append(rules, rdf1, rdfs1, rdfs4, rdfs6, rdfs8, rdfs10, rdfs12, rdfs13, containerMembership)
+		axioms = rdfsAxioms()
+	}
+	return newReasoner(options, rules, axioms)
+}
+
+// helpers of the rules
+
+func (this *Reasoner) has(subject, predicate, object model.RDFTerm) bool {
+	known, _ := this.known.Contains(&model.Statement{Subject: subject, Predicate: predicate, Object: object})
+	return known
+}
+
+func (this *Reasoner) objects(subject, predicate model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	for _, statement := range this.match(subject, predicate, nil) {
+		ret = append(ret, statement.Object)
+	}
+	return ret
+}
+
+func (this *Reasoner) subjects(predicate, object model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	for _, statement := range this.match(nil, predicate, object) {
+		ret = append(ret, statement.Subject)
+	}
+	return ret
+}
+
+func triple(subject, predicate, object model.RDFTerm) *model.Statement {
+	return &model.Statement{Subject: subject, Predicate: predicate, Object: object}
+}
+
+// the members of a list, false until the list is known up to rdf:nil
+func (this *Reasoner) list(head model.RDFTerm) ([]model.RDFTerm, bool) {
+	ret := []model.RDFTerm{}
+	visited := map[model.RDFTerm]struct{}{}
+	for node := head; node != model.RDFNil; {
+		if _, cycle := visited[node]; cycle {
+			return nil, false
+		}
+		visited[node] = struct{}{}
+		first, rest := this.objects(node, model.RDFFirst), this.objects(node, model.RDFRest)
+		if len(first) != 1 || len(rest) != 1 {
+			return nil, false
+		}
+		ret = append(ret, first[0])
+		node = rest[0]
+	}
+	return ret, true
+}
+
+// the heads of the lists a node is part of, itself included
+func (this *Reasoner) listHeads(node model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	visited := map[model.RDFTerm]struct{}{}
+	next := []model.RDFTerm{node}
+	for len(next) > 0 {
+		node, next = next[len(next)-1], next[:len(next)-1]
+		if _, ok := visited[node]; ok {
+			continue
+		}
+		visited[node] = struct{}{}
+		ret = append(ret, node)
+		next = append(next, this.subjects(model.RDFRest, node)...)
+	}
+	return ret
+}
+
+// the subjects of the statements of a predicate whose list has a member
+func (this *Reasoner) owners(predicate model.IRI, member model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	for _, node := range this.subjects(model.RDFFirst, member) {
+		for _, head := range this.listHeads(node) {
+			ret = append(ret, this.subjects(predicate, head)...)
+		}
+	}
+	return ret
+}
+
+// the statements of a list are joined again once it is complete
+func lists(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate != model.RDFFirst && statement.Predicate != model.RDFRest {
+		return
+	}
+	for _, head := range this.listHeads(statement.Subject) {
+		if _, complete := this.list(head); !complete {
+			continue
+		}
+		for _, predicate := range listPredicates {
+			for _, owner := range this.match(nil, predicate, head) {
+				for _, rules := range this.strata {
+					for _, r := range rules {
+						r(this, owner)
+					}
+				}
+			}
+		}
+	}
+}
+
+// equality: eq-sym, eq-trans, eq-rep-s, eq-rep-p, eq-rep-o, eq-diff1,
+// eq-diff2 and eq-diff3. The statements of lists are not rewritten, they
+// are the syntax of the axioms.
+func equality(this *Reasoner, statement *model.Statement) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	if p == owlSameAs && s != o {
+		this.infer(o, owlSameAs, s)
+		for _, z := range this.objects(o, owlSameAs) {
+			this.infer(s, owlSameAs, z)
+		}
+		for _, same := range this.match(s, nil, nil) {
+			if !isListStatement(same) {
+				this.infer(o, same.Predicate, same.Object)
+			}
+		}
+		for _, same := range this.match(nil, s, nil) {
+			this.infer(same.Subject, o, same.Object)
+		}
+		for _, same := range this.match(nil, nil, s) {
+			if !isListStatement(same) {
+				this.infer(same.Subject, same.Predicate, o)
+			}
+		}
+		if this.has(s, owlDifferentFrom, o) {
+			differentSame(this, s, o)
+		}
+		for _, predicate := range []model.IRI{owlMembers, owlDistinctMembers} {
+			for _, all := range this.owners(predicate, s) {
+				allDifferent(this, all)
+			}
+		}
+		return
+	}
+	if !isListStatement(statement) {
+		for _, same := range this.objects(s, owlSameAs) {
+			this.infer(same, p, o)
+		}
+		for _, same := range this.objects(o, owlSameAs) {
+			this.infer(s, p, same)
+		}
+	}
+	for _, same := range this.objects(p, owlSameAs) {
+		this.infer(s, same, o)
+	}
+	if p == owlDifferentFrom && this.has(s, owlSameAs, o) {
+		differentSame(this, s, o)
+	}
+	if p == owlMembers || p == owlDistinctMembers || (p == model.A && o == owlAllDifferent) {
+		allDifferent(this, s)
+	}
+}
+
+func isListStatement(statement *model.Statement) bool {
+	return statement.Predicate == model.RDFFirst || statement.Predicate == model.RDFRest
+}
+
+// eq-diff1, sameAs being symmetric the contradiction is reported with the
+// sameAs statement going like the differentFrom one
+func differentSame(this *Reasoner, a, b model.RDFTerm) {
+	this.inconsistent("eq-diff1", fmt.Sprintf("%s and %s are the same and different", this.format(a), this.format(b)),
+		triple(a, owlSameAs, b), triple(a, owlDifferentFrom, b))
+}
+
+// eq-diff2 with owl:members and eq-diff3 with owl:distinctMembers, the
+// members of an owl:AllDifferent which are the same
+func allDifferent(this *Reasoner, all model.RDFTerm) {
+	if !this.has(all, model.A, owlAllDifferent) {
+		return
+	}
+	for _, predicate := range []model.IRI{owlMembers, owlDistinctMembers} {
+		rule := "eq-diff2"
+		if predicate == owlDistinctMembers {
+			rule = "eq-diff3"
+		}
+		for _, head := range this.objects(all, predicate) {
+			members, _ := this.list(head)
+			for i, a := range members {
+				for _, b := range members[i+1:] {
+					if this.has(a, owlSameAs, b) {
+						this.inconsistent(rule, fmt.Sprintf("%s and %s are the same and all different", this.format(a), this.format(b)),
+							triple(a, owlSameAs, b), triple(all, predicate, head))
+					}
+				}
+			}
+		}
+	}
+}
+
+func contains(terms []model.RDFTerm, term model.RDFTerm) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+// the characteristics of properties: prp-fp, prp-ifp, prp-irp, prp-symp,
+// prp-asyp, prp-trp, prp-eqp1, prp-eqp2, prp-pdw, prp-adp, prp-inv1 and
+// prp-inv2
+func properties(this *Reasoner, statement *model.Statement) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	if p == model.A {
+		// the characteristic is declared, the statements of the property
+		// are joined with it
+		for _, existing := range this.match(nil, s, nil) {
+			characteristic(this, existing, o)
+		}
+	}
+	for _, c := range this.objects(p, model.A) {
+		characteristic(this, statement, c)
+	}
+
+	switch p {
+	case owlEquivalentProperty:
+		for _, existing := range this.match(nil, s, nil) {
+			this.infer(existing.Subject, o, existing.Object)
+		}
+		for _, existing := range this.match(nil, o, nil) {
+			this.infer(existing.Subject, s, existing.Object)
+		}
+	case owlInverseOf:
+		for _, existing := range this.match(nil, s, nil) {
+			this.infer(existing.Object, o, existing.Subject)
+		}
+		for _, existing := range this.match(nil, o, nil) {
+			this.infer(existing.Object, s, existing.Subject)
+		}
+	case owlPropertyDisjointWith:
+		for _, existing := range this.match(nil, s, nil) {
+			disjointProperties(this, "prp-pdw", existing, o)
+		}
+	}
+	for _, q := range this.objects(p, owlEquivalentProperty) {
+		this.infer(s, q, o)
+	}
+	for _, q := range this.subjects(owlEquivalentProperty, p) {
+		this.infer(s, q, o)
+	}
+	for _, q := range this.objects(p, owlInverseOf) {
+		this.infer(o, q, s)
+	}
+	for _, q := range this.subjects(owlInverseOf, p) {
+		this.infer(o, q, s)
+	}
+	for _, q := range this.objects(p, owlPropertyDisjointWith) {
+		disjointProperties(this, "prp-pdw", statement, q)
+	}
+	for _, q := range this.subjects(owlPropertyDisjointWith, p) {
+		disjointProperties(this, "prp-pdw", statement, q)
+	}
+	for _, all := range this.owners(owlMembers, p) {
+		if this.has(all, model.A, owlAllDisjointProperties) {
+			for _, head := range this.objects(all, owlMembers) {
+				members, _ := this.list(head)
+				for _, q := range members {
+					if q != p {
+						disjointProperties(this, "prp-adp", statement, q)
+					}
+				}
+			}
+		}
+	}
+	if (p == owlMembers || (p == model.A && o == owlAllDisjointProperties)) && this.has(s, model.A, owlAllDisjointProperties) {
+		for _, head := range this.objects(s, owlMembers) {
+			members, _ := this.list(head)
+			for i, a := range members {
+				for _, b := range members[i+1:] {
+					for _, existing := range this.match(nil, a, nil) {
+						disjointProperties(this, "prp-adp", existing, b)
+					}
+				}
+			}
+		}
+	}
+}
+
+// joins a statement with a characteristic of its property
+func characteristic(this *Reasoner, statement *model.Statement, c model.RDFTerm) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	switch c {
+	case owlFunctionalProperty:
+		for _, other := range this.objects(s, p) {
+			this.infer(o, owlSameAs, other)
+		}
+	case owlInverseFunctional:
+		for _, other := range this.subjects(p, o) {
+			this.infer(s, owlSameAs, other)
+		}
+	case owlIrreflexiveProperty:
+		if s == o {
+			this.inconsistent("prp-irp", fmt.Sprintf("%s is irreflexive but %s is related to itself", this.format(p), this.format(s)), statement)
+		}
+	case owlSymmetricProperty:
+		this.infer(o, p, s)
+	case owlAsymmetricProperty:
+		if this.has(o, p, s) {
+			this.inconsistent("prp-asyp", fmt.Sprintf("%s is asymmetric but relates %s and %s both ways", this.format(p), this.format(s), this.format(o)), statement, triple(o, p, s))
+		}
+	case owlTransitiveProperty:
+		for _, before := range this.subjects(p, s) {
+			this.infer(before, p, o)
+		}
+		for _, after := range this.objects(o, p) {
+			this.infer(s, p, after)
+		}
+	}
+}
+
+// prp-pdw and prp-adp, a statement of a property and a property disjoint
+// with it
+func disjointProperties(this *Reasoner, rule string, statement *model.Statement, other model.RDFTerm) {
+	if this.has(statement.Subject, other, statement.Object) {
+		this.inconsistent(rule, fmt.Sprintf("%s and %s are disjoint but both relate %s to %s",
+			this.format(statement.Predicate), this.format(other), this.format(statement.Subject), this.format(statement.Object)),
+			statement, triple(statement.Subject, other, statement.Object))
+	}
+}
+
+// prp-spo2, the statements of a chain of properties are statements of the
+// property of the chain
+func chains(this *Reasoner, statement *model.Statement) {
+	for _, axiom := range this.match(nil, owlPropertyChainAxiom, nil) {
+		chain, complete := this.list(axiom.Object)
+		if !complete || len(chain) == 0 {
+			continue
+		}
+		if statement == axiom || (statement.Predicate == owlPropertyChainAxiom && statement.Subject == axiom.Subject) {
+			// the axiom is new, every statement of the first link starts
+			// a chain
+			for _, first := range this.match(nil, chain[0], nil) {
+				extendChain(this, axiom.Subject, chain, 0, first)
+			}
+			continue
+		}
+		for i, link := range chain {
+			if link == statement.Predicate {
+				extendChain(this, axiom.Subject, chain, i, statement)
+			}
+		}
+	}
+}
+
+// infers the statements of property made by the chains going through
+// statement as their link i
+func extendChain(this *Reasoner, property model.RDFTerm, chain []model.RDFTerm, i int, statement *model.Statement) {
+	starts := []model.RDFTerm{statement.Subject}
+	for j := i - 1; j >= 0; j-- {
+		previous := map[model.RDFTerm]struct{}{}
+		for _, node := range starts {
+			for _, subject := range this.subjects(chain[j], node) {
+				previous[subject] = struct{}{}
+			}
+		}
+		starts = keys(previous)
+	}
+	ends := []model.RDFTerm{statement.Object}
+	for j := i + 1; j < len(chain); j++ {
+		next := map[model.RDFTerm]struct{}{}
+		for _, node := range ends {
+			for _, object := range this.objects(node, chain[j]) {
+				next[object] = struct{}{}
+			}
+		}
+		ends = keys(next)
+	}
+	for _, start := range starts {
+		for _, end := range ends {
+			this.infer(start, property, end)
+		}
+	}
+}
+
+func keys(set map[model.RDFTerm]struct{}) []model.RDFTerm {
+	ret := make([]model.RDFTerm, 0, len(set))
+	for term := range set {
+		ret = append(ret, term)
+	}
+	return ret
+}
+
+// prp-npa1 and prp-npa2, the statements denied by negative property
+// assertions
+func negativeAssertions(this *Reasoner, statement *model.Statement) {
+	switch statement.Predicate {
+	case owlSourceIndividual, owlAssertionProperty, owlTargetIndividual, owlTargetValue:
+		negativeAssertion(this, statement.Subject)
+		return
+	}
+	for _, assertion := range this.subjects(owlSourceIndividual, statement.Subject) {
+		if this.has(assertion, owlAssertionProperty, statement.Predicate) &&
+			(this.has(assertion, owlTargetIndividual, statement.Object) || this.has(assertion, owlTargetValue, statement.Object)) {
+			negativeAssertion(this, assertion)
+		}
+	}
+}
+
+func negativeAssertion(this *Reasoner, assertion model.RDFTerm) {
+	for _, source := range this.objects(assertion, owlSourceIndividual) {
+		for _, property := range this.objects(assertion, owlAssertionProperty) {
+			for _, target := range append(this.objects(assertion, owlTargetIndividual), this.objects(assertion, owlTargetValue)...) {
+				if this.has(source, property, target) {
+					rule := "prp-npa1"
+					if _, ok := target.(model.Literal); ok {
+						rule = "prp-npa2"
+					}
+					this.inconsistent(rule, fmt.Sprintf("%s %s %s is denied by a negative property assertion", this.format(source), this.format(property), this.format(target)),
+						triple(source, property, target), triple(assertion, owlSourceIndividual, source))
+				}
+			}
+		}
+	}
+}
+
+// classes: cls-nothing2, cls-int1, cls-int2, cls-uni, cls-com, cls-oo,
+// cax-eqc1, cax-eqc2, cax-dw and cax-adc
+func classes(this *Reasoner, statement *model.Statement) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	switch p {
+	case model.A:
+		instance(this, s, o, statement)
+		if o == owlAllDisjointClasses {
+			allDisjointClasses(this, s)
+		}
+	case owlIntersectionOf, owlUnionOf, owlComplementOf, owlEquivalentClass, owlDisjointWith:
+		// the instances of the classes of the axiom are joined with it
+		classes := []model.RDFTerm{s, o}
+		if members, complete := this.list(o); complete && p != owlComplementOf && p != owlEquivalentClass && p != owlDisjointWith {
+			classes = append(classes, members...)
+		}
+		for _, class := range classes {
+			for _, x := range this.subjects(model.A, class) {
+				instance(this, x, class, triple(x, model.A, class))
+			}
+		}
+	case owlOneOf:
+		if members, complete := this.list(o); complete {
+			for _, member := range members {
+				this.infer(member, model.A, s)
+			}
+		}
+	case owlMembers:
+		allDisjointClasses(this, s)
+	}
+}
+
+// joins the instances of the members of an owl:AllDisjointClasses with it
+func allDisjointClasses(this *Reasoner, all model.RDFTerm) {
+	if !this.has(all, model.A, owlAllDisjointClasses) {
+		return
+	}
+	for _, head := range this.objects(all, owlMembers) {
+		members, _ := this.list(head)
+		for _, class := range members {
+			for _, x := range this.subjects(model.A, class) {
+				instance(this, x, class, triple(x, model.A, class))
+			}
+		}
+	}
+}
+
+// joins x being an instance of class with the class axioms
+func instance(this *Reasoner, x, class model.RDFTerm, statement *model.Statement) {
+	if class == owlNothing {
+		this.inconsistent("cls-nothing2", fmt.Sprintf("%s is an instance of owl:Nothing", this.format(x)), statement)
+	}
+	for _, equivalent := range this.objects(class, owlEquivalentClass) {
+		this.infer(x, model.A, equivalent)
+	}
+	for _, equivalent := range this.subjects(owlEquivalentClass, class) {
+		this.infer(x, model.A, equivalent)
+	}
+	for _, members := range this.objects(class, owlIntersectionOf) {
+		if members, complete := this.list(members); complete {
+			for _, member := range members {
+				this.infer(x, model.A, member)
+			}
+		}
+	}
+	for _, intersection := range this.owners(owlIntersectionOf, class) {
+		for _, head := range this.objects(intersection, owlIntersectionOf) {
+			members, complete := this.list(head)
+			all := complete && contains(members, class)
+			for _, member := range members {
+				all = all && this.has(x, model.A, member)
+			}
+			if all {
+				this.infer(x, model.A, intersection)
+			}
+		}
+	}
+	for _, union := range this.owners(owlUnionOf, class) {
+		for _, head := range this.objects(union, owlUnionOf) {
+			if members, complete := this.list(head); complete && contains(members, class) {
+				this.infer(x, model.A, union)
+			}
+		}
+	}
+	for _, complement := range append(this.objects(class, owlComplementOf), this.subjects(owlComplementOf, class)...) {
+		if this.has(x, model.A, complement) {
+			this.inconsistent("cls-com", fmt.Sprintf("%s is an instance of %s and of its complement %s", this.format(x), this.format(class), this.format(complement)),
+				statement, triple(x, model.A, complement))
+		}
+	}
+	for _, disjoint := range append(this.objects(class, owlDisjointWith), this.subjects(owlDisjointWith, class)...) {
+		if this.has(x, model.A, disjoint) {
+			this.inconsistent("cax-dw", fmt.Sprintf("%s is an instance of the disjoint classes %s and %s", this.format(x), this.format(class), this.format(disjoint)),
+				statement, triple(x, model.A, disjoint))
+		}
+	}
+	for _, all := range this.owners(owlMembers, class) {
+		if !this.has(all, model.A, owlAllDisjointClasses) {
+			continue
+		}
+		for _, head := range this.objects(all, owlMembers) {
+			members, _ := this.list(head)
+			for _, other := range members {
+				if other != class && this.has(x, model.A, other) {
+					this.inconsistent("cax-adc", fmt.Sprintf("%s is an instance of the disjoint classes %s and %s", this.format(x), this.format(class), this.format(other)),
+						statement, triple(x, model.A, other))
+				}
+			}
+		}
+	}
+}
+
+// restrictions: cls-svf1, cls-svf2, cls-avf, cls-hv1, cls-hv2, cls-maxc1
+// and cls-maxc2
+func restrictions(this *Reasoner, statement *model.Statement) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	switch p {
+	case owlOnProperty, owlSomeValuesFrom, owlAllValuesFrom, owlHasValue, owlMaxCardinality:
+		// the restriction is new, or more complete, every statement of its
+		// property is joined with it
+		for _, property := range this.objects(s, owlOnProperty) {
+			for _, existing := range this.match(nil, property, nil) {
+				restriction(this, s, existing)
+			}
+			for _, x := range this.subjects(model.A, s) {
+				restricted(this, s, property, x)
+			}
+		}
+		return
+	case model.A:
+		for _, property := range this.objects(o, owlOnProperty) {
+			restricted(this, o, property, s)
+		}
+		// a value of the class of a someValuesFrom
+		for _, r := range this.subjects(owlSomeValuesFrom, o) {
+			for _, property := range this.objects(r, owlOnProperty) {
+				for _, u := range this.subjects(property, s) {
+					this.infer(u, model.A, r)
+				}
+			}
+		}
+	}
+	for _, r := range this.subjects(owlOnProperty, p) {
+		restriction(this, r, statement)
+	}
+}
+
+// joins the statement of the property of a restriction with it
+func restriction(this *Reasoner, r model.RDFTerm, statement *model.Statement) {
+	u, v := statement.Subject, statement.Object
+	for _, class := range this.objects(r, owlSomeValuesFrom) {
+		if class == owlThing || this.has(v, model.A, class) {
+			this.infer(u, model.A, r)
+		}
+	}
+	for _, value := range this.objects(r, owlHasValue) {
+		if value == v {
+			this.infer(u, model.A, r)
+		}
+	}
+	if this.has(u, model.A, r) {
+		restricted(this, r, statement.Predicate, u)
+	}
+}
+
+// joins u being an instance of the restriction r on property with the
+// statements of u
+func restricted(this *Reasoner, r, property, u model.RDFTerm) {
+	values := this.objects(u, property)
+	for _, class := range this.objects(r, owlAllValuesFrom) {
+		for _, v := range values {
+			this.infer(v, model.A, class)
+		}
+	}
+	for _, value := range this.objects(r, owlHasValue) {
+		this.infer(u, property, value)
+	}
+	for _, max := range this.objects(r, owlMaxCardinality) {
+		literal, ok := max.(model.Literal)
+		if !ok {
+			continue
+		}
+		switch n, err := strconv.Atoi(literal.Lexical); {
+		case err != nil:
+		case n == 0:
+			for _, v := range values {
+				this.inconsistent("cls-maxc1", fmt.Sprintf("%s has a value of %s but its maximum cardinality is 0", this.format(u), this.format(property)),
+					triple(u, model.A, r), triple(u, property, v))
+			}
+		case n == 1:
+			for i, a := range values {
+				for _, b := range values[i+1:] {
+					this.infer(a, owlSameAs, b)
+				}
+			}
+		}
+	}
+}
+
+// the schema: scm-eqc1, scm-eqc2, scm-eqp1, scm-eqp2, scm-dom1, scm-dom2,
+// scm-rng1, scm-rng2, scm-int and scm-uni
+func schemas(this *Reasoner, statement *model.Statement) {
+	s, p, o := statement.Subject, statement.Predicate, statement.Object
+	switch p {
+	case owlEquivalentClass:
+		this.infer(s, model.RDFSSubClassOf, o)
+		this.infer(o, model.RDFSSubClassOf, s)
+	case owlEquivalentProperty:
+		this.infer(s, model.RDFSSubPropertyOf, o)
+		this.infer(o, model.RDFSSubPropertyOf, s)
+	case model.RDFSSubClassOf:
+		if s != o && this.has(o, model.RDFSSubClassOf, s) {
+			this.infer(s, owlEquivalentClass, o)
+			this.infer(o, owlEquivalentClass, s)
+		}
+		for _, property := range this.subjects(model.RDFSDomain, s) {
+			this.infer(property, model.RDFSDomain, o)
+		}
+		for _, property := range this.subjects(model.RDFSRange, s) {
+			this.infer(property, model.RDFSRange, o)
+		}
+	case model.RDFSSubPropertyOf:
+		if s != o && this.has(o, model.RDFSSubPropertyOf, s) {
+			this.infer(s, owlEquivalentProperty, o)
+			this.infer(o, owlEquivalentProperty, s)
+		}
+		for _, class := range this.objects(o, model.RDFSDomain) {
+			this.infer(s, model.RDFSDomain, class)
+		}
+		for _, class := range this.objects(o, model.RDFSRange) {
+			this.infer(s, model.RDFSRange, class)
+		}
+	case model.RDFSDomain, model.RDFSRange:
+		for _, super := range this.objects(o, model.RDFSSubClassOf) {
+			this.infer(s, p, super)
+		}
+		for _, sub := range this.subjects(model.RDFSSubPropertyOf, s) {
+			this.infer(sub, p, o)
+		}
+	case owlIntersectionOf:
+		if members, complete := this.list(o); complete {
+			for _, member := range members {
+				this.infer(s, model.RDFSSubClassOf, member)
+			}
+		}
+	case owlUnionOf:
+		if members, complete := this.list(o); complete {
+			for _, member := range members {
+				this.infer(member, model.RDFSSubClassOf, s)
+			}
+		}
+	}
+}
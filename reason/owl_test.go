@@ -0,0 +1,158 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+const prefixes = `@prefix : <http://ex.org/> .
+@prefix owl: <http://www.w3.org/2002/07/owl#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+`
+
+func TestOWL(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{"sameAs", `:a owl:sameAs :b . :a :p :c . :d :q :b .`, []string{
+			`<:b> <:p> <:c>`, `<:b> <http://www.w3.org/2002/07/owl#sameAs> <:a>`, `<:d> <:q> <:a>`}},
+		{"inverse and symmetric", `:hasParent owl:inverseOf :hasChild . :a :hasParent :b . :knows a owl:SymmetricProperty . :a :knows :c .`, []string{
+			`<:b> <:hasChild> <:a>`, `<:c> <:knows> <:a>`}},
+		{"transitive", `:ancestor a owl:TransitiveProperty . :a :ancestor :b . :b :ancestor :c . :c :ancestor :d .`, []string{
+			`<:a> <:ancestor> <:c>`, `<:a> <:ancestor> <:d>`, `<:b> <:ancestor> <:d>`}},
+		{"property chain", `:uncle owl:propertyChainAxiom ( :parent :brother ) . :a :parent :b . :b :brother :c . :b :brother :d .`, []string{
+			`<:a> <:uncle> <:c>`, `<:a> <:uncle> <:d>`}},
+		// the equivalences make cycles of subclasses and subproperties
+		{"equivalent classes and properties", `:Human owl:equivalentClass :Person . :a a :Human . :name owl:equivalentProperty :label . :a :label "A" .`, []string{
+			`<:Human> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Human>`,
+			`<:Human> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Person>`,
+			`<:Person> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Human>`,
+			`<:Person> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Person>`,
+			`<:Person> <http://www.w3.org/2002/07/owl#equivalentClass> <:Human>`,
+			`<:a> <:name> "A"`,
+			`<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person>`,
+			`<:label> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:label>`,
+			`<:label> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:name>`,
+			`<:label> <http://www.w3.org/2002/07/owl#equivalentProperty> <:name>`,
+			`<:name> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:label>`,
+			`<:name> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:name>`}},
+		{"intersection and union", `:Mother owl:intersectionOf ( :Woman :Parent ) . :a a :Woman , :Parent . :b a :Mother . :Adult owl:unionOf ( :Man :Woman ) .`, []string{
+			`<:Man> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Adult>`,
+			`<:Mother> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Adult>`,
+			`<:Mother> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Parent>`,
+			`<:Mother> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Woman>`,
+			`<:Woman> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Adult>`,
+			`<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Adult>`,
+			`<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Mother>`,
+			`<:b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Adult>`,
+			`<:b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Parent>`,
+			`<:b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Woman>`}},
+		{"restrictions", `:Parent owl:onProperty :child ; owl:someValuesFrom :Person .
+:Vegan owl:onProperty :eats ; owl:allValuesFrom :Plant .
+:French owl:onProperty :country ; owl:hasValue :france .
+:a :child :b . :b a :Person . :c a :Vegan ; :eats :d . :e :country :france . :f a :French .`, []string{
+			`<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Parent>`,
+			`<:d> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Plant>`,
+			`<:e> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:French>`,
+			`<:f> <:country> <:france>`}},
+		{"functional and maximum cardinality", `:mother a owl:FunctionalProperty . :a :mother :b , :c .
+:Single owl:onProperty :spouse ; owl:maxCardinality 1 . :d a :Single ; :spouse :e , :f .`, []string{
+			`<:b> <http://www.w3.org/2002/07/owl#sameAs> <:c>`, `<:c> <http://www.w3.org/2002/07/owl#sameAs> <:b>`,
+			`<:e> <http://www.w3.org/2002/07/owl#sameAs> <:f>`, `<:f> <http://www.w3.org/2002/07/owl#sameAs> <:e>`}},
+		{"one of", `:Color owl:oneOf ( :red :green ) .`, []string{
+			`<:green> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Color>`, `<:red> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Color>`}},
+	}
+	for _, c := range cases {
+		statements := parse(t, prefixes+c.data)
+		// the inferences do not depend on the order of the premises, lists
+		// included
+		for _, reversed := range []bool{false, true} {
+			reasoner := NewOWL(Options{})
+			for i := range statements {
+				if reversed {
+					i = len(statements) - 1 - i
+				}
+				reasoner.Add(statements[i])
+			}
+			got := []string{}
+			for _, line := range lines(reasoner.Inferred()) {
+				// the other lists are not interesting
+				if !strings.Contains(line, "22-rdf-syntax-ns#first") && !strings.Contains(line, "22-rdf-syntax-ns#rest") {
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+				t.Errorf("%s, reversed %v: inferred\n%s\ninstead of\n%s", c.name, reversed, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+			}
+			if inconsistencies := reasoner.Inconsistencies(); len(inconsistencies) != 0 {
+				t.Errorf("%s: inconsistencies %v", c.name, inconsistencies)
+			}
+		}
+	}
+}
+
+func TestOWLInconsistencies(t *testing.T) {
+	cases := []struct {
+		data  string
+		rules []string
+	}{
+		{`:Cat owl:disjointWith :Dog . :Kitten rdfs:subClassOf :Cat . :felix a :Kitten , :Dog .`, []string{"cax-dw"}},
+		{`[] a owl:AllDisjointClasses ; owl:members ( :A :B :C ) . :x a :A , :C .`, []string{"cax-adc"}},
+		{`:a owl:differentFrom :b . :p a owl:InverseFunctionalProperty . :a :p :c . :b :p :c .`, []string{"eq-diff1"}},
+		{`[] a owl:AllDifferent ; owl:distinctMembers ( :a :b ) . :a owl:sameAs :b .`, []string{"eq-diff3"}},
+		{`:x a owl:Nothing .`, []string{"cls-nothing2"}},
+		{`:NotCat owl:complementOf :Cat . :x a :Cat , :NotCat .`, []string{"cls-com"}},
+		{`:p a owl:IrreflexiveProperty . :x :p :x .`, []string{"prp-irp"}},
+		{`:p a owl:AsymmetricProperty . :x :p :y . :y :p :x .`, []string{"prp-asyp"}},
+		{`:p owl:propertyDisjointWith :q . :x :p :y ; :q :y .`, []string{"prp-pdw"}},
+		{`[] a owl:AllDisjointProperties ; owl:members ( :p :q ) . :x :p :y ; :q :y .`, []string{"prp-adp"}},
+		{`[] owl:sourceIndividual :x ; owl:assertionProperty :p ; owl:targetIndividual :y . :x :p :y .`, []string{"prp-npa1"}},
+		{`[] owl:sourceIndividual :x ; owl:assertionProperty :age ; owl:targetValue 3 . :x :age 3 .`, []string{"prp-npa2"}},
+		{`:Orphan owl:onProperty :parent ; owl:maxCardinality "0"^^xsd:nonNegativeInteger . :x a :Orphan ; :parent :y .`, []string{"cls-maxc1"}},
+		{`:Cat owl:disjointWith :Dog . :x a :Cat . :y a :Dog . :p owl:propertyDisjointWith :q . :x :p :y .`, nil},
+	}
+	for _, c := range cases {
+		statements := parse(t, prefixes+c.data)
+		for _, reversed := range []bool{false, true} {
+			reasoner := NewOWL(Options{})
+			for i := range statements {
+				if reversed {
+					i = len(statements) - 1 - i
+				}
+				reasoner.Add(statements[i])
+			}
+			rules := []string{}
+			for _, inconsistency := range reasoner.Inconsistencies() {
+				rules = append(rules, inconsistency.Rule)
+				if inconsistency.Message == "" || len(inconsistency.Statements) == 0 {
+					t.Errorf("%s: incomplete %v", c.data, inconsistency)
+				}
+			}
+			sort.Strings(rules)
+			if strings.Join(rules, " ") != strings.Join(c.rules, " ") {
+				t.Errorf("%s, reversed %v: %v instead of %v", c.data, reversed, reasoner.Inconsistencies(), c.rules)
+			}
+		}
+	}
+
+	reasoner := NewOWL(Options{})
+	for _, statement := range parse(t, prefixes+`:Cat owl:disjointWith :Dog . :felix a :Cat , :Dog .`) {
+		reasoner.Add(statement)
+	}
+	expected := "cax-dw: <http://ex.org/felix> is an instance of the disjoint classes <http://ex.org/Dog> and <http://ex.org/Cat>"
+	if got := reasoner.Inconsistencies(); len(got) != 1 || got[0].String() != expected {
+		t.Errorf("%v instead of %s", got, expected)
+	}
+	reasoner.Reset()
+	if got := reasoner.Inconsistencies(); len(got) != 0 {
+		t.Errorf("%v after a reset", got)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// the rules of RDF 1.1 Semantics, named like there
+
+// NewRDFS returns a reasoner applying the rules of RDFS entailment
+func NewRDFS(options Options) *Reasoner {
+	rules := []rule{rdfs2, rdfs3, rdfs5, rdfs7, rdfs9, rdfs11}
+	var axioms []*model.Statement
+	if options.Full {
+		rules = append(rules, rdf1, rdfs1, rdfs4, rdfs6, rdfs8, rdfs10, rdfs12, rdfs13, containerMembership)
+		axioms = rdfsAxioms()
+	}
+	return newReasoner(options, rules, axioms)
+}
+
+// the domain of a property is a class of its subjects
+func rdfs2(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.RDFSDomain {
+		for _, s := range this.match(nil, statement.Subject, nil) {
+			this.infer(s.Subject, model.A, statement.Object)
+		}
+	}
+	for _, domain := range this.match(statement.Predicate, model.RDFSDomain, nil) {
+		this.infer(statement.Subject, model.A, domain.Object)
+	}
+}
+
+// the range of a property is a class of its objects
+func rdfs3(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.RDFSRange {
+		for _, s := range this.match(nil, statement.Subject, nil) {
+			this.infer(s.Object, model.A, statement.Object)
+		}
+	}
+	for _, r := range this.match(statement.Predicate, model.RDFSRange, nil) {
+		this.infer(statement.Object, model.A, r.Object)
+	}
+}
+
+// subproperties are transitive
+func rdfs5(this *Reasoner, statement *model.Statement) {
+	transitive(this, statement, model.RDFSSubPropertyOf)
+}
+
+// the statements of a property are statements of its superproperties
+func rdfs7(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.RDFSSubPropertyOf {
+		for _, s := range this.match(nil, statement.Subject, nil) {
+			this.infer(s.Subject, statement.Object, s.Object)
+		}
+	}
+	for _, super := range this.match(statement.Predicate, model.RDFSSubPropertyOf, nil) {
+		this.infer(statement.Subject, super.Object, statement.Object)
+	}
+}
+
+// the instances of a class are instances of its superclasses
+func rdfs9(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.RDFSSubClassOf {
+		for _, s := range this.match(nil, model.A, statement.Subject) {
+			this.infer(s.Subject, model.A, statement.Object)
+		}
+	}
+	if statement.Predicate == model.A {
+		for _, super := range this.match(statement.Object, model.RDFSSubClassOf, nil) {
+			this.infer(statement.Subject, model.A, super.Object)
+		}
+	}
+}
+
+// subclasses are transitive
+func rdfs11(this *Reasoner, statement *model.Statement) {
+	transitive(this, statement, model.RDFSSubClassOf)
+}
+
+// joins a statement of a transitive property with those before and after
+// it
+func transitive(this *Reasoner, statement *model.Statement, property model.IRI) {
+	if statement.Predicate != property {
+		return
+	}
+	for _, before := range this.match(nil, property, statement.Subject) {
+		this.infer(before.Subject, property, statement.Object)
+	}
+	for _, after := range this.match(statement.Object, property, nil) {
+		this.infer(statement.Subject, property, after.Object)
+	}
+}
+
+// the rules of Full
+
+// predicates are properties
+func rdf1(this *Reasoner, statement *model.Statement) {
+	this.infer(statement.Predicate, model.A, model.RDFProperty)
+}
+
+// the datatypes of literals are datatypes
+func rdfs1(this *Reasoner, statement *model.Statement) {
+	if literal, ok := statement.Object.(model.Literal); ok {
+		this.infer(literal.Datatype, model.A, model.RDFSDatatype)
+	}
+}
+
+// subjects and objects are resources
+func rdfs4(this *Reasoner, statement *model.Statement) {
+	this.infer(statement.Subject, model.A, model.RDFSResource)
+	this.infer(statement.Object, model.A, model.RDFSResource)
+}
+
+// properties are their own subproperties
+func rdfs6(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.A && statement.Object == model.RDFProperty {
+		this.infer(statement.Subject, model.RDFSSubPropertyOf, statement.Subject)
+	}
+}
+
+// classes are subclasses of rdfs:Resource
+func rdfs8(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.A && statement.Object == model.RDFSClass {
+		this.infer(statement.Subject, model.RDFSSubClassOf, model.RDFSResource)
+	}
+}
+
+// classes are their own subclasses
+func rdfs10(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.A && statement.Object == model.RDFSClass {
+		this.infer(statement.Subject, model.RDFSSubClassOf, statement.Subject)
+	}
+}
+
+// container membership properties are subproperties of rdfs:member
+func rdfs12(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.A && statement.Object == model.RDFSContainerMembershipProperty {
+		this.infer(statement.Subject, model.RDFSSubPropertyOf, model.RDFSMember)
+	}
+}
+
+// datatypes are subclasses of rdfs:Literal
+func rdfs13(this *Reasoner, statement *model.Statement) {
+	if statement.Predicate == model.A && statement.Object == model.RDFSDatatype {
+		this.infer(statement.Subject, model.RDFSSubClassOf, model.RDFSLiteral)
+	}
+}
+
+// the axioms of the infinitely many rdf:_n are inferred for those which
+// are used
+func containerMembership(this *Reasoner, statement *model.Statement) {
+	for _, term := range []model.RDFTerm{statement.Subject, statement.Predicate, statement.Object} {
+		if isContainerMembership(term) {
+			this.infer(term, model.A, model.RDFProperty)
+			this.infer(term, model.A, model.RDFSContainerMembershipProperty)
+			this.infer(term, model.RDFSDomain, model.RDFSResource)
+			this.infer(term, model.RDFSRange, model.RDFSResource)
+		}
+	}
+}
+
+func isContainerMembership(term model.RDFTerm) bool {
+	iri, ok := term.(model.IRI)
+	if !ok || !strings.HasPrefix(string(iri), string(model.RDF)+"_") {
+		return false
+	}
+	index := strings.TrimPrefix(string(iri), string(model.RDF)+"_")
+	n, err := strconv.Atoi(index)
+	return err == nil && n > 0 && strconv.Itoa(n) == index
+}
+
+// the axiomatic triples of RDF and RDFS, without those of rdf:_n
+func rdfsAxioms() []*model.Statement {
+	rdf := func(name string) model.IRI { return model.RDF + model.IRI(name) }
+	rdfs := func(name string) model.IRI { return model.RDFS + model.IRI(name) }
+	ret := []*model.Statement{}
+	add := func(subject model.IRI, predicate model.IRI, object model.IRI) {
+		ret = append(ret, &model.Statement{Subject: subject, Predicate: predicate, Object: object})
+	}
+	for _, property := range []string{"type", "subject", "predicate", "object", "first", "rest", "value"} {
+		add(rdf(property), model.A, model.RDFProperty)
+	}
+	add(model.RDFNil, model.A, rdf("List"))
+	domains := [][3]model.IRI{
+		{model.A, model.RDFSResource, model.RDFSClass},
+		{model.RDFSDomain, model.RDFProperty, model.RDFSClass},
+		{model.RDFSRange, model.RDFProperty, model.RDFSClass},
+		{model.RDFSSubPropertyOf, model.RDFProperty, model.RDFProperty},
+		{model.RDFSSubClassOf, model.RDFSClass, model.RDFSClass},
+		{rdf("subject"), rdf("Statement"), model.RDFSResource},
+		{rdf("predicate"), rdf("Statement"), model.RDFSResource},
+		{rdf("object"), rdf("Statement"), model.RDFSResource},
+		{model.RDFSMember, model.RDFSResource, model.RDFSResource},
+		{model.RDFFirst, rdf("List"), model.RDFSResource},
+		{model.RDFRest, rdf("List"), rdf("List")},
+		{rdfs("seeAlso"), model.RDFSResource, model.RDFSResource},
+		{rdfs("isDefinedBy"), model.RDFSResource, model.RDFSResource},
+		{rdfs("comment"), model.RDFSResource, model.RDFSLiteral},
+		{rdfs("label"), model.RDFSResource, model.RDFSLiteral},
+		{rdf("value"), model.RDFSResource, model.RDFSResource},
+	}
+	for _, d := range domains {
+		add(d[0], model.RDFSDomain, d[1])
+		add(d[0], model.RDFSRange, d[2])
+	}
+	for _, container := range []string{"Alt", "Bag", "Seq"} {
+		add(rdf(container), model.RDFSSubClassOf, rdfs("Container"))
+	}
+	add(model.RDFSContainerMembershipProperty, model.RDFSSubClassOf, model.RDFProperty)
+	add(rdfs("isDefinedBy"), model.RDFSSubPropertyOf, rdfs("seeAlso"))
+	add(rdfs("Datatype"), model.RDFSSubClassOf, model.RDFSClass)
+	for _, datatype := range []model.IRI{model.XSDString, model.RDFLangString, model.RDFDirLangString, rdf("HTML"), rdf("XMLLiteral")} {
+		add(datatype, model.A, model.RDFSDatatype)
+	}
+	return ret
+}
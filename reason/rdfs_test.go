@@ -0,0 +1,159 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+const ontology = `@prefix : <http://ex.org/> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+:Student rdfs:subClassOf :Person .
+:Person rdfs:subClassOf :Agent .
+:teaches rdfs:domain :Teacher ; rdfs:range :Course .
+:headOf rdfs:subPropertyOf :worksFor .
+:worksFor rdfs:subPropertyOf :memberOf ; rdfs:range :Organization .
+`
+
+func parse(t *testing.T, text string) []*model.Statement {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(text), parser.Options{Format: format.TriG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+// the statements as sorted N-Quads lines, without the namespace of the
+// tests
+func lines(statements []*model.Statement) []string {
+	ret := []string{}
+	labels := writer.NewBlankNodeLabels()
+	for _, statement := range statements {
+		line := writer.FormatTerm(statement.Subject, labels) + " " + writer.FormatTerm(statement.Predicate, labels) + " " + writer.FormatTerm(statement.Object, labels)
+		if statement.Context != nil {
+			line += " " + writer.FormatTerm(statement.Context, labels)
+		}
+		ret = append(ret, strings.ReplaceAll(line, "http://ex.org/", ":"))
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestRDFS(t *testing.T) {
+	reasoner := NewRDFS(Options{})
+	for _, statement := range parse(t, ontology+`:alice a :Student ; :teaches :rdf ; :headOf :lab .`) {
+		reasoner.Add(statement)
+	}
+	expected := []string{
+		`<:Student> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Agent>`,
+		`<:alice> <:memberOf> <:lab>`,
+		`<:alice> <:worksFor> <:lab>`,
+		`<:alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Agent>`,
+		`<:alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person>`,
+		`<:alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Teacher>`,
+		`<:headOf> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:memberOf>`,
+		`<:lab> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Organization>`,
+		`<:rdf> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Course>`,
+	}
+	if got := lines(reasoner.Inferred()); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("inferred\n%s\ninstead of\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	if reasoner.Len() != len(expected) {
+		t.Errorf("%d inferred statements instead of %d", reasoner.Len(), len(expected))
+	}
+}
+
+// the order of the premises does not matter, each one returns what it
+// entails with those before it
+func TestRDFSIncremental(t *testing.T) {
+	reasoner := NewRDFS(Options{Graph: model.IRI("http://ex.org/inferred")})
+	steps := []struct {
+		statement string
+		expected  []string
+	}{
+		{`<http://ex.org/bob> a <http://ex.org/Student> .`, []string{}},
+		{`<http://ex.org/Person> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <http://ex.org/Agent> .`, []string{}},
+		{`<http://ex.org/Student> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <http://ex.org/Person> .`, []string{
+			`<:Student> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Agent> <:inferred>`,
+			`<:bob> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Agent> <:inferred>`,
+			`<:bob> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person> <:inferred>`,
+		}},
+		{`<http://ex.org/bob> a <http://ex.org/Person> .`, []string{}},
+		{`<http://ex.org/g> { <http://ex.org/carol> a <http://ex.org/Person> }`, []string{
+			`<:carol> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Agent> <:inferred>`,
+		}},
+	}
+	for _, step := range steps {
+		got := lines(reasoner.Add(parse(t, step.statement)[0]))
+		if strings.Join(got, "\n") != strings.Join(step.expected, "\n") {
+			t.Errorf("%s inferred\n%s\ninstead of\n%s", step.statement, strings.Join(got, "\n"), strings.Join(step.expected, "\n"))
+		}
+	}
+	// premises are not inferred even when they are entailed
+	if got := lines(reasoner.Inferred()); len(got) != 3 {
+		t.Errorf("inferred %v", got)
+	}
+}
+
+func TestRDFSFull(t *testing.T) {
+	reasoner := NewRDFS(Options{Full: true})
+	for _, statement := range parse(t, ontology+`:alice a :Student ; :age 30 . :list <http://www.w3.org/1999/02/22-rdf-syntax-ns#_2> :alice .`) {
+		reasoner.Add(statement)
+	}
+	inferred := strings.Join(lines(reasoner.Inferred()), "\n")
+	for _, expected := range []string{
+		`<:alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2000/01/rdf-schema#Resource>`,
+		`<:age> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Property>`,
+		`<:age> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:age>`,
+		`<:Person> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2000/01/rdf-schema#Class>`,
+		`<:Person> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <http://www.w3.org/2000/01/rdf-schema#Resource>`,
+		`<http://www.w3.org/2001/XMLSchema#integer> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <http://www.w3.org/2000/01/rdf-schema#Literal>`,
+		`<:list> <http://www.w3.org/2000/01/rdf-schema#member> <:alice>`,
+		`<http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2000/01/rdf-schema#range> <http://www.w3.org/2000/01/rdf-schema#Class>`,
+	} {
+		if !strings.Contains(inferred, expected) {
+			t.Errorf("%s not inferred", expected)
+		}
+	}
+	// literals are never subjects
+	if strings.Contains(inferred, "\n\"") || strings.Contains(inferred, "\n30") {
+		t.Errorf("literal subjects inferred:\n%s", inferred)
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	dataset := store.NewMemory()
+	for _, statement := range parse(t, strings.Replace(ontology, ":Student", "<http://ex.org/schema> { :Student", 1)+` }
+<http://ex.org/alice> a <http://ex.org/Student> , <http://ex.org/Person> .`) {
+		dataset.Add(statement)
+	}
+	inferred := model.IRI("http://ex.org/inferred")
+	added, err := NewRDFS(Options{Graph: inferred}).Materialize(dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, _ := store.All(dataset.Match(nil, nil, nil, inferred))
+	expected := []string{
+		`<:Student> <http://www.w3.org/2000/01/rdf-schema#subClassOf> <:Agent> <:inferred>`,
+		`<:alice> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Agent> <:inferred>`,
+		`<:headOf> <http://www.w3.org/2000/01/rdf-schema#subPropertyOf> <:memberOf> <:inferred>`,
+	}
+	if got := lines(statements); added != len(expected) || strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("added %d statements\n%s\ninstead of\n%s", added, strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	// once materialized nothing more is inferred
+	if added, err := NewRDFS(Options{Graph: inferred}).Materialize(dataset); added != 0 || err != nil {
+		t.Errorf("added %d statements again, error %v", added, err)
+	}
+}
@@ -0,0 +1,324 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package reason infers the statements entailed by datasets with forward
+// chaining rules.
+//
+// The statements of every graph are premises and the inferred statements
+// all go into one graph, so that schemas can be kept apart from the data
+// they describe.
+package reason
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+type Options struct {
+	// the graph of the inferred statements, the default graph when nil
+	Graph model.RDFTerm
+	// all the rules of RDFS entailment and its axiomatic statements, not
+	// only the rules of subclasses, subproperties, domains and ranges
+	Full bool
+}
+
+// rules derive statements from a new statement and the known ones
+type rule func(this *Reasoner, statement *model.Statement)
+
+// conditional axioms derive statements from the absence of others
+type conditionalAxiom func(this *Reasoner)
+
+// Reasoner infers statements from premises given one at a time, as they
+// are read from a stream. Each statement is joined once with the known
+// ones by the rules of each stratum, which makes the inference
+// semi-naive.
+type Reasoner struct {
+	options Options
+	// the rules of a stratum apply once those of the lower ones are done
+	// with the new statements, so that their negations hold
+	strata [][]rule
+	// the conditional axioms of each stratum, which apply before its rules
+	// when deriving from all the premises at once
+	conditions [][]conditionalAxiom
+	// the statements entailed by any premises
+	axioms []*model.Statement
+	// the triples, premises and inferred, and the premises
+	known    *store.Memory
+	asserted *store.Memory
+	// the triples new to the current derivation, in the order they were
+	// known
+	delta []*model.Statement
+	// set while deriving from all the premises at once, when no inference
+	// can be invalidated
+	fresh bool
+	// set by the rules when a new triple invalidates former inferences
+	stale bool
+	// the triples inferred by the current call to Add
+	inferred []*model.Statement
+	// the contradictions found, and their keys
+	inconsistencies []Inconsistency
+	contradictions  map[string]struct{}
+	labels          *writer.BlankNodeLabels
+}
+
+// Inconsistency is a contradiction between statements, found by the rule
+// named like in OWL 2 RL
+type Inconsistency struct {
+	Rule    string
+	Message string
+	// the premises, as triples
+	Statements []*model.Statement
+}
+
+func (this Inconsistency) String() string {
+	return this.Rule + ": " + this.Message
+}
+
+func newReasoner(options Options, rules []rule, axioms []*model.Statement) *Reasoner {
+	this := &Reasoner{options: options, strata: [][]rule{rules}, axioms: axioms}
+	this.Reset()
+	return this
+}
+
+// Reset forgets the premises
+func (this *Reasoner) Reset() {
+	dictionary := model.NewDictionary()
+	this.known = store.NewMemoryWithDictionary(dictionary)
+	this.asserted = store.NewMemoryWithDictionary(dictionary)
+	this.inconsistencies = nil
+	this.contradictions = make(map[string]struct{})
+	this.labels = writer.NewBlankNodeLabels()
+	this.fresh = true
+	this.derive(this.axioms...)
+	this.fresh = false
+}
+
+// Add adds a premise, whatever its graph, and returns the statements
+// inferred since, in the graph of the options. A premise may invalidate
+// inferences drawn from the absence of statements, they then start over:
+// Inferred tells which remain.
+func (this *Reasoner) Add(statement *model.Statement) []*model.Statement {
+	triple := &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object}
+	this.asserted.Add(triple)
+	this.inferred = nil
+	this.derive(triple)
+	if this.stale {
+		this.restart()
+	}
+	return this.inGraph(this.inferred)
+}
+
+// derives again from the premises, the triples inferred are those which
+// were not known before
+func (this *Reasoner) restart() {
+	before := this.known
+	premises, _ := store.All(this.asserted.Match(nil, nil, nil, nil))
+	this.known = store.NewMemoryWithDictionary(before.Dictionary())
+	this.inconsistencies = nil
+	this.contradictions = make(map[string]struct{})
+	this.stale = false
+	this.inferred = nil
+	this.fresh = true
+	this.derive(append(append([]*model.Statement{}, this.axioms...), premises...)...)
+	this.fresh = false
+	inferred := []*model.Statement{}
+	for _, triple := range this.inferred {
+		if known, _ := before.Contains(triple); !known {
+			inferred = append(inferred, triple)
+		}
+	}
+	this.inferred = inferred
+}
+
+// Inferred returns the inferred statements which are not premises, in the
+// graph of the options
+func (this *Reasoner) Inferred() []*model.Statement {
+	ret := []*model.Statement{}
+	for _, triple := range this.match(nil, nil, nil) {
+		if asserted, _ := this.asserted.Contains(triple); !asserted {
+			ret = append(ret, triple)
+		}
+	}
+	sort.Slice(ret, func(a, b int) bool {
+		return model.CompareStatements(ret[a], ret[b]) < 0
+	})
+	return this.inGraph(ret)
+}
+
+// the number of inferred statements which are not premises
+func (this *Reasoner) Len() int {
+	return this.known.Len() - this.asserted.Len()
+}
+
+// a known triple, nil when it is not entailed or is a premise
+func (this *Reasoner) lookup(statement *model.Statement) *model.Statement {
+	triple := &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object}
+	if known, _ := this.known.Contains(triple); !known {
+		return nil
+	}
+	if asserted, _ := this.asserted.Contains(triple); asserted {
+		return nil
+	}
+	return triple
+}
+
+func (this *Reasoner) inGraph(triples []*model.Statement) []*model.Statement {
+	ret := make([]*model.Statement, len(triples))
+	for i, triple := range triples {
+		ret[i] = &model.Statement{Subject: triple.Subject, Predicate: triple.Predicate, Object: triple.Object, Context: this.options.Graph}
+	}
+	return ret
+}
+
+// adds triples to the known ones and joins them, and the triples inferred
+// from them, with the known ones, stratum after stratum
+func (this *Reasoner) derive(triples ...*model.Statement) {
+	this.delta = this.delta[:0]
+	for _, triple := range triples {
+		if known, _ := this.known.Contains(triple); !known {
+			this.known.Add(triple)
+			this.delta = append(this.delta, triple)
+		}
+	}
+	for level, rules := range this.strata {
+		if this.fresh && level < len(this.conditions) {
+			for _, condition := range this.conditions[level] {
+				condition(this)
+			}
+		}
+		// the rules infer more as they go
+		for i := 0; i < len(this.delta) && !this.stale; i++ {
+			for _, r := range rules {
+				r(this, this.delta[i])
+			}
+		}
+	}
+	this.delta = nil
+}
+
+// called by the rules, statements with literal subjects or predicates which
+// are not IRIs are not inferred
+func (this *Reasoner) infer(subject, predicate, object model.RDFTerm) {
+	if _, ok := subject.(model.Literal); ok {
+		return
+	}
+	if _, ok := predicate.(model.IRI); !ok {
+		return
+	}
+	// everything is the same as itself, which is not worth saying
+	if predicate == owlSameAs && subject == object {
+		return
+	}
+	triple := &model.Statement{Subject: subject, Predicate: predicate, Object: object}
+	if known, _ := this.known.Contains(triple); known {
+		return
+	}
+	this.known.Add(triple)
+	this.delta = append(this.delta, triple)
+	this.inferred = append(this.inferred, triple)
+}
+
+// Inconsistencies returns the contradictions found among the premises and
+// the inferred statements, in the order they were found
+func (this *Reasoner) Inconsistencies() []Inconsistency {
+	return this.inconsistencies
+}
+
+// called by the rules, each contradiction is reported once whichever
+// premise comes last and whichever way its symmetric statements go: it is
+// known by the terms of its premises
+func (this *Reasoner) inconsistent(rule string, message string, premises ...*model.Statement) {
+	ids := []string{}
+	for _, premise := range premises {
+		for _, term := range []model.RDFTerm{premise.Subject, premise.Predicate, premise.Object} {
+			ids = append(ids, strconv.FormatUint(uint64(this.known.Dictionary().Encode(term)), 10))
+		}
+	}
+	sort.Strings(ids)
+	key := rule
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			key += " " + id
+		}
+	}
+	if _, found := this.contradictions[key]; found {
+		return
+	}
+	this.contradictions[key] = struct{}{}
+	this.inconsistencies = append(this.inconsistencies, Inconsistency{Rule: rule, Message: message, Statements: premises})
+}
+
+// the N-Triples form of a term, for messages
+func (this *Reasoner) format(term model.RDFTerm) string {
+	return writer.FormatTerm(term, this.labels)
+}
+
+// the known triples matching a pattern
+func (this *Reasoner) match(subject, predicate, object model.RDFTerm) []*model.Statement {
+	// memory stores do not fail
+	ret, _ := store.All(this.known.Match(subject, predicate, object, nil))
+	return ret
+}
+
+// Load adds the statements of a store as premises, all at once
+func (this *Reasoner) Load(dataset store.Store) error {
+	iterator := dataset.Match(nil, nil, nil, nil)
+	defer iterator.Close()
+	triples := []*model.Statement{}
+	for iterator.Next() {
+		statement := iterator.Statement()
+		triples = append(triples, &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object})
+	}
+	if err := iterator.Err(); err != nil {
+		return err
+	}
+	// without former premises, everything is derived at once
+	initial := this.asserted.Len() == 0
+	for _, triple := range triples {
+		this.asserted.Add(triple)
+	}
+	this.inferred = nil
+	if initial {
+		this.restart()
+	} else {
+		this.derive(triples...)
+		if this.stale {
+			this.restart()
+		}
+	}
+	this.inferred = nil
+	return nil
+}
+
+// Materialize adds to a store the statements its statements entail, all
+// or none, and returns how many were added
+func (this *Reasoner) Materialize(dataset store.Store) (int, error) {
+	if err := this.Load(dataset); err != nil {
+		return 0, err
+	}
+	transaction := store.NewTransaction(dataset)
+	added := 0
+	for _, statement := range this.Inferred() {
+		present, err := transaction.Contains(statement)
+		if err != nil {
+			return 0, err
+		}
+		if present {
+			continue
+		}
+		if err := transaction.Add(statement); err != nil {
+			return 0, err
+		}
+		added++
+	}
+	if err := transaction.Commit(); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
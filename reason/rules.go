@@ -0,0 +1,280 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// NewRules returns a reasoner applying rules read by parser.ParseRules.
+//
+// The rules are stratified: a rule comes after those whose conclusions may
+// match its premises, strictly after those whose conclusions may match its
+// negations, an error tells when they cannot be. The rules without
+// premises state axioms, which only hold in the absence of their negations
+// when they have some. The blank nodes of the conclusions are new nodes,
+// one for each match of the premises, recursive rules making some may not
+// end. The Full option does not apply.
+func NewRules(options Options, rules []*parser.Rule) (*Reasoner, error) {
+	levels, err := stratify(rules)
+	if err != nil {
+		return nil, err
+	}
+	strata := [][]rule{}
+	conditions := [][]conditionalAxiom{}
+	var axioms []*model.Statement
+	for i, r := range rules {
+		program := &program{Rule: r, nodes: make(map[node]model.RDFTerm), labels: writer.NewBlankNodeLabels()}
+		if len(r.Premises) == 0 && len(r.Negations) == 0 {
+			for _, conclusion := range r.Conclusions {
+				axioms = append(axioms, &model.Statement{
+					Subject:   program.instantiate(conclusion.Subject, binding{}),
+					Predicate: program.instantiate(conclusion.Predicate, binding{}),
+					Object:    program.instantiate(conclusion.Object, binding{}),
+				})
+			}
+			continue
+		}
+		for len(strata) <= levels[i] {
+			strata = append(strata, nil)
+			conditions = append(conditions, nil)
+		}
+		strata[levels[i]] = append(strata[levels[i]], program.apply)
+		if len(r.Premises) == 0 {
+			conditions[levels[i]] = append(conditions[levels[i]], program.axiom)
+		}
+	}
+	this := &Reasoner{options: options, strata: strata, conditions: conditions, axioms: axioms}
+	this.Reset()
+	return this, nil
+}
+
+// the stratum of each rule
+func stratify(rules []*parser.Rule) ([]int, error) {
+	// the rules each one depends on, and those it depends on negatively
+	positive := make([][]int, len(rules))
+	negative := make([][]int, len(rules))
+	for i, r := range rules {
+		for j, other := range rules {
+			if concludes(other, r.Premises) {
+				positive[i] = append(positive[i], j)
+			}
+			for _, group := range r.Negations {
+				if concludes(other, group) {
+					negative[i] = append(negative[i], j)
+					break
+				}
+			}
+		}
+	}
+	levels := make([]int, len(rules))
+	for changed := true; changed; {
+		changed = false
+		for i := range rules {
+			for _, j := range positive[i] {
+				if levels[i] < levels[j] {
+					levels[i] = levels[j]
+					changed = true
+				}
+			}
+			for _, j := range negative[i] {
+				if levels[i] <= levels[j] {
+					levels[i] = levels[j] + 1
+					changed = true
+				}
+			}
+			// only a cycle through a negation raises a rule that much
+			if levels[i] >= len(rules) {
+				return nil, fmt.Errorf("the rule at line %d depends on its own negation, the rules cannot be stratified", rules[i].Line)
+			}
+		}
+	}
+	return levels, nil
+}
+
+// true when a conclusion of a rule may match one of the patterns
+func concludes(r *parser.Rule, patterns []*model.Statement) bool {
+	for _, conclusion := range r.Conclusions {
+		for _, pattern := range patterns {
+			if unifiable(conclusion.Subject, pattern.Subject) && unifiable(conclusion.Predicate, pattern.Predicate) && unifiable(conclusion.Object, pattern.Object) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unifiable(a, b model.RDFTerm) bool {
+	return isVariable(a) || isVariable(b) || a == b
+}
+
+// the blank nodes of the patterns are variables
+func isVariable(term model.RDFTerm) bool {
+	_, ok := term.(parser.RuleVariable)
+	return ok || model.IsBlankNode(term)
+}
+
+// the values of the variables of a rule
+type binding map[model.RDFTerm]model.RDFTerm
+
+// a blank node of the conclusions for a match of the premises, the values
+// of their variables in N-Triples
+type node struct {
+	blank model.RDFTerm
+	key   string
+}
+
+type program struct {
+	*parser.Rule
+	nodes  map[node]model.RDFTerm
+	labels *writer.BlankNodeLabels
+}
+
+// joins a new statement with the known ones through each premise it
+// matches, and tells the reasoner when it matches a negation which former
+// inferences may have relied on
+func (this *program) apply(reasoner *Reasoner, statement *model.Statement) {
+	for i, premise := range this.Premises {
+		b, ok := unify(premise, statement, binding{})
+		if !ok {
+			continue
+		}
+		others := append(append([]*model.Statement{}, this.Premises[:i]...), this.Premises[i+1:]...)
+		for _, solution := range solutions(reasoner, others, b) {
+			this.conclude(reasoner, solution)
+		}
+	}
+	if reasoner.fresh {
+		return
+	}
+	for _, group := range this.Negations {
+		for _, pattern := range group {
+			b, ok := unify(pattern, statement, binding{})
+			if !ok {
+				continue
+			}
+			for _, solution := range solutions(reasoner, this.Premises, b) {
+				if len(solutions(reasoner, group, solution)) > 0 {
+					reasoner.stale = true
+					return
+				}
+			}
+		}
+	}
+}
+
+// infers the conclusions of a rule without premises, unless a negation
+// matches
+func (this *program) axiom(reasoner *Reasoner) {
+	this.conclude(reasoner, binding{})
+}
+
+// infers the conclusions of a match of the premises, unless a negation
+// matches too
+func (this *program) conclude(reasoner *Reasoner, b binding) {
+	for _, group := range this.Negations {
+		if len(solutions(reasoner, group, b)) > 0 {
+			return
+		}
+	}
+	for _, conclusion := range this.Conclusions {
+		reasoner.infer(this.instantiate(conclusion.Subject, b), this.instantiate(conclusion.Predicate, b), this.instantiate(conclusion.Object, b))
+	}
+}
+
+func (this *program) instantiate(term model.RDFTerm, b binding) model.RDFTerm {
+	if _, ok := term.(parser.RuleVariable); ok {
+		return b[term]
+	}
+	if !model.IsBlankNode(term) {
+		return term
+	}
+	values := []string{}
+	for _, premise := range this.Premises {
+		for _, t := range []model.RDFTerm{premise.Subject, premise.Predicate, premise.Object} {
+			if isVariable(t) {
+				values = append(values, writer.FormatTerm(b[t], this.labels))
+			}
+		}
+	}
+	key := node{blank: term, key: strings.Join(values, " ")}
+	ret, found := this.nodes[key]
+	if !found {
+		ret = model.NewAnonymousBlankNode()
+		this.nodes[key] = ret
+	}
+	return ret
+}
+
+// extends a binding so that a pattern is a statement, false when it cannot
+func unify(pattern, statement *model.Statement, b binding) (binding, bool) {
+	ret := b
+	for _, pair := range [3][2]model.RDFTerm{{pattern.Subject, statement.Subject}, {pattern.Predicate, statement.Predicate}, {pattern.Object, statement.Object}} {
+		term, value := pair[0], pair[1]
+		if !isVariable(term) {
+			if term != value {
+				return nil, false
+			}
+			continue
+		}
+		if bound, found := ret[term]; found {
+			if bound != value {
+				return nil, false
+			}
+			continue
+		}
+		if len(ret) == len(b) {
+			// the binding given is left alone
+			ret = make(binding, len(b)+3)
+			for k, v := range b {
+				ret[k] = v
+			}
+		}
+		ret[term] = value
+	}
+	return ret, true
+}
+
+// the extensions of a binding matching all the patterns with known
+// statements, the pattern with the most bound terms is joined first
+func solutions(reasoner *Reasoner, patterns []*model.Statement, b binding) []binding {
+	if len(patterns) == 0 {
+		return []binding{b}
+	}
+	best, bestBound := 0, -1
+	for i, pattern := range patterns {
+		bound := 0
+		for _, term := range []model.RDFTerm{pattern.Subject, pattern.Predicate, pattern.Object} {
+			if value := substitute(term, b); value != nil {
+				bound++
+			}
+		}
+		if bound > bestBound {
+			best, bestBound = i, bound
+		}
+	}
+	pattern := patterns[best]
+	others := append(append([]*model.Statement{}, patterns[:best]...), patterns[best+1:]...)
+	ret := []binding{}
+	for _, statement := range reasoner.match(substitute(pattern.Subject, b), substitute(pattern.Predicate, b), substitute(pattern.Object, b)) {
+		if extended, ok := unify(pattern, statement, b); ok {
+			ret = append(ret, solutions(reasoner, others, extended)...)
+		}
+	}
+	return ret
+}
+
+// the value of a term, nil for unbound variables
+func substitute(term model.RDFTerm, b binding) model.RDFTerm {
+	if isVariable(term) {
+		return b[term]
+	}
+	return term
+}
@@ -0,0 +1,150 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+func rules(t *testing.T, text string) *Reasoner {
+	t.Helper()
+	parsed, err := parser.ParseRules(strings.NewReader("@prefix : <http://ex.org/> .\n"+text), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasoner, err := NewRules(Options{}, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reasoner
+}
+
+func TestRules(t *testing.T) {
+	cases := []struct {
+		name     string
+		rules    string
+		data     string
+		expected []string
+	}{
+		{"recursive", `{ ?x :parent ?y } => { ?x :ancestor ?y } .
+{ ?x :parent ?y . ?y :ancestor ?z } => { ?x :ancestor ?z } .`, `:a :parent :b . :b :parent :c . :c :parent :d .`, []string{
+			`<:a> <:ancestor> <:b>`, `<:a> <:ancestor> <:c>`, `<:a> <:ancestor> <:d>`,
+			`<:b> <:ancestor> <:c>`, `<:b> <:ancestor> <:d>`, `<:c> <:ancestor> <:d>`}},
+		{"negation", `{ ?x a :Person . NOT { ?x :spouse ?y } } => { ?x a :Single } .
+{ ?x :married ?y } => { ?x :spouse ?y . ?y :spouse ?x } .`, `:a a :Person . :b a :Person . :c a :Person . :c :married :b .`, []string{
+			`<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Single>`, `<:b> <:spouse> <:c>`, `<:c> <:spouse> <:b>`}},
+		{"variable predicate", `{ ?p :inverse ?q . ?x ?p ?y } => { ?y ?q ?x } .`, `:parent :inverse :child . :a :parent :b .`, []string{
+			`<:b> <:child> <:a>`}},
+		{"axioms", `{} => { :Single :label "single" } .
+{ ?c :label ?l . ?x a ?c } => { ?x :label ?l } .`, `:a a :Single .`, []string{
+			`<:Single> <:label> "single"`, `<:a> <:label> "single"`}},
+		{"blank nodes", `{ ?x :parent _:p . _:p :parent ?z } => { ?x :grandparent ?z } .`, `:a :parent :b . :b :parent :c .`, []string{
+			`<:a> <:grandparent> <:c>`}},
+		{"negated axiom without data", `{ NOT { :z :z :z } } => { :y :y :y } .
+{ :y :y :y } => { :x :x :x } .`, ``, []string{
+			`<:x> <:x> <:x>`, `<:y> <:y> <:y>`}},
+		{"negated axiom", `{ NOT { :z :z :z } } => { :y :y :y } .
+{ ?s :p ?o } => { ?s :z ?o } .`, `:a :p :b . :z :p :z .`, []string{
+			`<:a> <:z> <:b>`, `<:z> <:z> <:z>`}},
+	}
+	for _, c := range cases {
+		statements := parse(t, prefixes+c.data)
+		// the order of the premises does not matter, negations included
+		for _, reversed := range []bool{false, true} {
+			reasoner := rules(t, c.rules)
+			for i := range statements {
+				if reversed {
+					i = len(statements) - 1 - i
+				}
+				reasoner.Add(statements[i])
+			}
+			if got := lines(reasoner.Inferred()); strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+				t.Errorf("%s, reversed %v: inferred\n%s\ninstead of\n%s", c.name, reversed, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+			}
+		}
+		// and all at once
+		dataset := store.NewMemory()
+		for _, statement := range statements {
+			dataset.Add(statement)
+		}
+		reasoner := rules(t, c.rules)
+		if err := reasoner.Load(dataset); err != nil {
+			t.Fatal(err)
+		}
+		if got := lines(reasoner.Inferred()); strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%s, loaded: inferred\n%s\ninstead of\n%s", c.name, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+		}
+	}
+}
+
+func TestRulesIncremental(t *testing.T) {
+	reasoner := rules(t, `{ ?x a :Person . NOT { ?x :spouse [] } } => { ?x a :Single } .
+{ ?x a :Single } => { ?x :status "single" } .`)
+	steps := []struct {
+		statement string
+		expected  []string
+		inferred  int
+	}{
+		{`<http://ex.org/a> a <http://ex.org/Person> .`, []string{
+			`<:a> <:status> "single"`, `<:a> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Single>`}, 2},
+		{`<http://ex.org/b> a <http://ex.org/Person> .`, []string{
+			`<:b> <:status> "single"`, `<:b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Single>`}, 4},
+		// the inferences about a start over
+		{`<http://ex.org/a> <http://ex.org/spouse> <http://ex.org/c> .`, []string{}, 2},
+		{`<http://ex.org/c> <http://ex.org/spouse> <http://ex.org/a> .`, []string{}, 2},
+	}
+	for _, step := range steps {
+		got := lines(reasoner.Add(parse(t, step.statement)[0]))
+		if strings.Join(got, "\n") != strings.Join(step.expected, "\n") {
+			t.Errorf("%s inferred\n%s\ninstead of\n%s", step.statement, strings.Join(got, "\n"), strings.Join(step.expected, "\n"))
+		}
+		if reasoner.Len() != step.inferred {
+			t.Errorf("%s: %d inferred statements instead of %d: %v", step.statement, reasoner.Len(), step.inferred, lines(reasoner.Inferred()))
+		}
+	}
+}
+
+// the blank nodes of the conclusions are the same whenever the premises
+// match the same way
+func TestRulesExistential(t *testing.T) {
+	reasoner := rules(t, `{ ?x a :Person } => { ?x :mother [ a :Woman ] } .
+{ ?x :mother ?m . NOT { ?x :orphan true } } => { ?m :child ?x } .`)
+	for _, statement := range parse(t, prefixes+`:a a :Person . :b a :Person .`) {
+		reasoner.Add(statement)
+	}
+	before := lines(reasoner.Inferred())
+	if len(before) != 6 {
+		t.Errorf("inferred %v", before)
+	}
+	reasoner.Add(parse(t, prefixes+`:b :orphan true .`)[0])
+	after := lines(reasoner.Inferred())
+	if len(after) != 5 || after[0] != before[0] {
+		t.Errorf("inferred\n%s\nthen\n%s", strings.Join(before, "\n"), strings.Join(after, "\n"))
+	}
+}
+
+func TestRulesStratification(t *testing.T) {
+	for text, message := range map[string]string{
+		`{ ?x a :A . NOT { ?x a :B } } => { ?x a :C } .
+{ ?x a :C } => { ?x a :B } .`: "the rule at line 2 depends on its own negation, the rules cannot be stratified",
+		`{ ?x a :A . NOT { ?x ?p ?y } } => { ?x a :B } .`: "the rule at line 2 depends on its own negation, the rules cannot be stratified",
+		// the classes tell the statements apart
+		`{ ?x a :A . NOT { ?x a :B } } => { ?x a :C } .
+{ ?x a :D } => { ?x a :B } .`: "",
+	} {
+		parsed, err := parser.ParseRules(strings.NewReader("@prefix : <http://ex.org/> .\n"+text), parser.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewRules(Options{}, parsed)
+		if (err == nil && message != "") || (err != nil && err.Error() != message) {
+			t.Errorf("%s: error %v instead of %q", text, err, message)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"sync"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// View is a store holding the statements of another one and those they
+// entail, which are inferred when reading after changes and never added to
+// the other store. Adding statements extends the inferences, removing some
+// makes them start over. The other store must only be changed through the
+// view.
+type View struct {
+	store    store.Store
+	lock     sync.Mutex
+	reasoner *Reasoner
+	// the premises of the reasoner are the statements of the store
+	loaded bool
+}
+
+func NewView(dataset store.Store, reasoner *Reasoner) *View {
+	return &View{store: dataset, reasoner: reasoner}
+}
+
+// the reasoner, loaded with the statements of the store
+func (this *View) load() (*Reasoner, error) {
+	if !this.loaded {
+		this.reasoner.Reset()
+		if err := this.reasoner.Load(this.store); err != nil {
+			return nil, err
+		}
+		this.loaded = true
+	}
+	return this.reasoner, nil
+}
+
+func (this *View) Add(statement *model.Statement) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if err := this.store.Add(statement); err != nil {
+		return err
+	}
+	if this.loaded {
+		this.reasoner.Add(statement)
+	}
+	return nil
+}
+
+func (this *View) Remove(statement *model.Statement) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if err := this.store.Remove(statement); err != nil {
+		return err
+	}
+	this.loaded = false
+	return nil
+}
+
+func (this *View) Contains(statement *model.Statement) (bool, error) {
+	if present, err := this.store.Contains(statement); present || err != nil {
+		return present, err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	reasoner, err := this.load()
+	if err != nil {
+		return false, err
+	}
+	return statement.Context == reasoner.options.Graph && reasoner.lookup(statement) != nil, nil
+}
+
+// the statements of the store, then the inferred ones
+func (this *View) Match(subject, predicate, object, graph model.RDFTerm) store.Iterator {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	reasoner, err := this.load()
+	if err != nil {
+		return &viewIterator{err: err}
+	}
+	inferred := []*model.Statement{}
+	target := reasoner.options.Graph
+	if graph == nil || graph == target || (graph == store.DefaultGraph && target == nil) {
+		for _, triple := range reasoner.match(subject, predicate, object) {
+			if asserted, _ := reasoner.asserted.Contains(triple); !asserted {
+				inferred = append(inferred, &model.Statement{Subject: triple.Subject, Predicate: triple.Predicate, Object: triple.Object, Context: target})
+			}
+		}
+	}
+	return &viewIterator{store: this.store.Match(subject, predicate, object, graph), inferred: inferred}
+}
+
+// the inferred statements are never statements of the store
+func (this *View) Len() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	reasoner, err := this.load()
+	if err != nil {
+		return this.store.Len()
+	}
+	return this.store.Len() + reasoner.Len()
+}
+
+type viewIterator struct {
+	// nil once its statements are all read
+	store    store.Iterator
+	inferred []*model.Statement
+	current  *model.Statement
+	err      error
+}
+
+func (this *viewIterator) Next() bool {
+	this.current = nil
+	if this.err != nil {
+		return false
+	}
+	if this.store != nil {
+		if this.store.Next() {
+			this.current = this.store.Statement()
+			return true
+		}
+		this.err = this.store.Err()
+		this.store.Close()
+		this.store = nil
+		if this.err != nil {
+			return false
+		}
+	}
+	if len(this.inferred) == 0 {
+		return false
+	}
+	this.current = this.inferred[0]
+	this.inferred = this.inferred[1:]
+	return true
+}
+
+func (this *viewIterator) Statement() *model.Statement {
+	return this.current
+}
+
+func (this *viewIterator) Err() error {
+	return this.err
+}
+
+func (this *viewIterator) Close() error {
+	if this.store != nil {
+		err := this.store.Close()
+		this.store = nil
+		return err
+	}
+	return nil
+}
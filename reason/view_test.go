@@ -0,0 +1,72 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package reason
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+func TestView(t *testing.T) {
+	dataset := store.NewMemory()
+	for _, statement := range parse(t, ontology) {
+		dataset.Add(statement)
+	}
+	view := NewView(dataset, NewRDFS(Options{}))
+	alice := model.IRI("http://ex.org/alice")
+	agents := func() []string {
+		statements, err := store.All(view.Match(nil, model.A, model.IRI("http://ex.org/Agent"), store.DefaultGraph))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lines(statements)
+	}
+	if got := agents(); len(got) != 0 {
+		t.Errorf("agents %v before adding any", got)
+	}
+
+	// additions extend the inferences, which are not in the store
+	view.Add(&model.Statement{Subject: alice, Predicate: model.A, Object: model.IRI("http://ex.org/Student")})
+	if got := agents(); len(got) != 1 || !strings.HasPrefix(got[0], "<:alice>") {
+		t.Errorf("agents %v", got)
+	}
+	agent := &model.Statement{Subject: alice, Predicate: model.A, Object: model.IRI("http://ex.org/Agent")}
+	if present, _ := view.Contains(agent); !present {
+		t.Errorf("inferred statement not contained")
+	}
+	if present, _ := dataset.Contains(agent); present {
+		t.Errorf("inferred statement added to the store")
+	}
+	if view.Len() != dataset.Len()+4 {
+		t.Errorf("%d statements in the view, %d in the store", view.Len(), dataset.Len())
+	}
+
+	// removals start over
+	view.Remove(&model.Statement{Subject: model.IRI("http://ex.org/Person"), Predicate: model.RDFSSubClassOf, Object: model.IRI("http://ex.org/Agent")})
+	if got := agents(); len(got) != 0 {
+		t.Errorf("agents %v after removing the subclass", got)
+	}
+
+	// the inferred statements can be queried, in their graph only
+	query, err := sparql.ParseQuery(`SELECT ?c { <http://ex.org/alice> a ?c } ORDER BY ?c`, sparql.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := sparql.Evaluate(query, view)
+	if err != nil || len(result.Solutions) != 2 {
+		t.Errorf("classes %v, error %v", result, err)
+	}
+	named := NewView(dataset, NewRDFS(Options{Graph: model.IRI("http://ex.org/inferred")}))
+	if statements, _ := store.All(named.Match(alice, nil, nil, store.DefaultGraph)); len(statements) != 1 {
+		t.Errorf("%d statements of alice in the default graph instead of 1", len(statements))
+	}
+	if statements, _ := store.All(named.Match(alice, nil, nil, model.IRI("http://ex.org/inferred"))); len(statements) != 1 {
+		t.Errorf("%d statements of alice in the inferred graph instead of 1", len(statements))
+	}
+}
@@ -0,0 +1,582 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/sparql"
+)
+
+// constraints validate the value nodes of a focus node, reporting the
+// results to the validator
+type constraint func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error
+
+// the parameters which make constraints, in the order they are checked.
+// The other parameters of their components are read with them.
+var parameters = []model.IRI{
+	shClass, shDatatype, shNodeKind,
+	shMinCount, shMaxCount,
+	shMinExclusive, shMinInclusive, shMaxExclusive, shMaxInclusive,
+	shMinLength, shMaxLength, shPattern, shLanguageIn, shUniqueLang,
+	shEquals, shDisjoint, shLessThan, shLessThanOrEquals,
+	shNot, shAnd, shOr, shXone,
+	shNode, shProperty, shQualifiedValueShape,
+	shClosed, shHasValue, shIn,
+}
+
+// a constraint reporting the value nodes which fail a test
+func eachValue(component model.IRI, message string, test func(this *validator, value model.RDFTerm) (bool, error)) constraint {
+	return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		for _, value := range values {
+			ok, err := test(this, value)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				this.report(shape, focus, value, component, message)
+			}
+		}
+		return nil
+	}
+}
+
+// the constraint of a parameter of a shape, nil when the parameter does
+// not constrain anything
+func (this *Shapes) constraint(owner *shape, parameter model.IRI, value model.RDFTerm) (constraint, error) {
+	switch parameter {
+	case shClass:
+		return eachValue(SH+"ClassConstraintComponent", "Value does not have class "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+			return this.data.isInstance(node, value)
+		}), nil
+
+	case shDatatype:
+		datatype, ok := value.(model.IRI)
+		if !ok {
+			return nil, fmt.Errorf("not an IRI")
+		}
+		return eachValue(SH+"DatatypeConstraintComponent", "Value does not have datatype "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+			literal, ok := node.(model.Literal)
+			return ok && literal.Datatype == datatype && !literal.IllTyped(), nil
+		}), nil
+
+	case shNodeKind:
+		var test func(node model.RDFTerm) bool
+		isIRI := func(node model.RDFTerm) bool { _, ok := node.(model.IRI); return ok }
+		isLiteral := func(node model.RDFTerm) bool { _, ok := node.(model.Literal); return ok }
+		switch value {
+		case shIRI:
+			test = isIRI
+		case shBlankNode:
+			test = model.IsBlankNode
+		case shLiteral:
+			test = isLiteral
+		case shBlankNodeOrIRI:
+			test = func(node model.RDFTerm) bool { return model.IsBlankNode(node) || isIRI(node) }
+		case shBlankNodeOrLiteral:
+			test = func(node model.RDFTerm) bool { return model.IsBlankNode(node) || isLiteral(node) }
+		case shIRIOrLiteral:
+			test = func(node model.RDFTerm) bool { return isIRI(node) || isLiteral(node) }
+		default:
+			return nil, fmt.Errorf("not a node kind")
+		}
+		return eachValue(SH+"NodeKindConstraintComponent", "Value does not have node kind "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+			return test(node), nil
+		}), nil
+
+	case shMinCount, shMaxCount:
+		count, err := integer(value)
+		if err != nil {
+			return nil, err
+		}
+		if parameter == shMinCount {
+			return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+				if len(values) < count {
+					this.report(shape, focus, nil, SH+"MinCountConstraintComponent", fmt.Sprintf("Less than %d values", count))
+				}
+				return nil
+			}, nil
+		}
+		return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+			if len(values) > count {
+				this.report(shape, focus, nil, SH+"MaxCountConstraintComponent", fmt.Sprintf("More than %d values", count))
+			}
+			return nil
+		}, nil
+
+	case shMinExclusive, shMinInclusive, shMaxExclusive, shMaxInclusive:
+		if _, ok := value.(model.Literal); !ok {
+			return nil, fmt.Errorf("not a literal")
+		}
+		name := strings.TrimPrefix(string(parameter), string(SH))
+		operator := map[model.IRI]string{shMinExclusive: ">", shMinInclusive: ">=", shMaxExclusive: "<", shMaxInclusive: "<="}[parameter]
+		component := SH + model.IRI(strings.ToUpper(name[:1])+name[1:]) + "ConstraintComponent"
+		return eachValue(component, "Value is not "+operator+" "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+			c, ok := sparql.Compare(node, value)
+			if !ok {
+				return false, nil
+			}
+			switch parameter {
+			case shMinExclusive:
+				return c > 0, nil
+			case shMinInclusive:
+				return c >= 0, nil
+			case shMaxExclusive:
+				return c < 0, nil
+			}
+			return c <= 0, nil
+		}), nil
+
+	case shMinLength, shMaxLength:
+		length, err := integer(value)
+		if err != nil {
+			return nil, err
+		}
+		if parameter == shMinLength {
+			return eachValue(SH+"MinLengthConstraintComponent", fmt.Sprintf("Value has less than %d characters", length), func(this *validator, node model.RDFTerm) (bool, error) {
+				text, ok := lexicalForm(node)
+				return ok && utf8.RuneCountInString(text) >= length, nil
+			}), nil
+		}
+		return eachValue(SH+"MaxLengthConstraintComponent", fmt.Sprintf("Value has more than %d characters", length), func(this *validator, node model.RDFTerm) (bool, error) {
+			text, ok := lexicalForm(node)
+			return ok && utf8.RuneCountInString(text) <= length, nil
+		}), nil
+
+	case shPattern:
+		pattern, ok := value.(model.Literal)
+		if !ok {
+			return nil, fmt.Errorf("not a literal")
+		}
+		flags, err := this.parameter(owner, shFlags)
+		if err != nil {
+			return nil, err
+		}
+		expression, err := compilePattern(pattern.Lexical, flags)
+		if err != nil {
+			return nil, err
+		}
+		return eachValue(SH+"PatternConstraintComponent", "Value does not match pattern "+strconv.Quote(pattern.Lexical), func(this *validator, node model.RDFTerm) (bool, error) {
+			text, ok := lexicalForm(node)
+			return ok && expression.MatchString(text), nil
+		}), nil
+
+	case shLanguageIn:
+		ranges, err := this.graph.list(value)
+		if err != nil {
+			return nil, err
+		}
+		return eachValue(SH+"LanguageInConstraintComponent", "Language does not match any of "+this.graph.formatList(ranges), func(this *validator, node model.RDFTerm) (bool, error) {
+			literal, ok := node.(model.Literal)
+			if !ok || literal.Language == "" {
+				return false, nil
+			}
+			for _, r := range ranges {
+				if r, ok := r.(model.Literal); ok && languageMatches(literal.Language, r.Lexical) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}), nil
+
+	case shUniqueLang:
+		if value != model.NewTypedLiteral("true", model.XSDBoolean) {
+			return nil, nil
+		}
+		return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+			counts := map[string]int{}
+			languages := []string{}
+			for _, value := range values {
+				if literal, ok := value.(model.Literal); ok && literal.Language != "" {
+					language := strings.ToLower(literal.Language)
+					if counts[language]++; counts[language] == 2 {
+						languages = append(languages, language)
+					}
+				}
+			}
+			for _, language := range languages {
+				this.report(shape, focus, nil, SH+"UniqueLangConstraintComponent", "Language \""+language+"\" used more than once")
+			}
+			return nil
+		}, nil
+
+	case shEquals, shDisjoint, shLessThan, shLessThanOrEquals:
+		return this.pairConstraint(parameter, value)
+
+	case shNot, shNode:
+		other, err := this.shape(value)
+		if err != nil {
+			return nil, err
+		}
+		if parameter == shNot {
+			return eachValue(SH+"NotConstraintComponent", "Value conforms to shape "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+				conforms, err := this.conforms(other, node)
+				return !conforms, err
+			}), nil
+		}
+		return eachValue(SH+"NodeConstraintComponent", "Value does not conform to shape "+this.graph.describe(value), func(this *validator, node model.RDFTerm) (bool, error) {
+			return this.conforms(other, node)
+		}), nil
+
+	case shAnd, shOr, shXone:
+		members, err := this.shapeList(value)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(string(parameter), string(SH))
+		component := SH + model.IRI(strings.ToUpper(name[:1])+name[1:]) + "ConstraintComponent"
+		messages := map[model.IRI]string{
+			shAnd:  "Value does not conform to every shape of ",
+			shOr:   "Value does not conform to any shape of ",
+			shXone: "Value does not conform to exactly one shape of ",
+		}
+		return eachValue(component, messages[parameter]+this.graph.formatShapes(members), func(this *validator, node model.RDFTerm) (bool, error) {
+			conforming := 0
+			for _, member := range members {
+				conforms, err := this.conforms(member, node)
+				if err != nil {
+					return false, err
+				}
+				if conforms {
+					conforming++
+				}
+			}
+			switch parameter {
+			case shAnd:
+				return conforming == len(members), nil
+			case shOr:
+				return conforming > 0, nil
+			}
+			return conforming == 1, nil
+		}), nil
+
+	case shProperty:
+		property, err := this.shape(value)
+		if err != nil {
+			return nil, err
+		}
+		if property.path == nil {
+			return nil, fmt.Errorf("not a property shape")
+		}
+		// the results of the property shape are those of the shape
+		return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+			for _, value := range values {
+				if err := this.validate(property, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, nil
+
+	case shQualifiedValueShape:
+		return this.qualifiedConstraint(owner, value)
+
+	case shClosed:
+		if value != model.NewTypedLiteral("true", model.XSDBoolean) {
+			return nil, nil
+		}
+		return this.closedConstraint(owner)
+
+	case shHasValue:
+		return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+			for _, node := range values {
+				if node == value {
+					return nil
+				}
+			}
+			this.report(shape, focus, nil, SH+"HasValueConstraintComponent", "Missing expected value "+this.data.describe(value))
+			return nil
+		}, nil
+
+	case shIn:
+		members, err := this.graph.list(value)
+		if err != nil {
+			return nil, err
+		}
+		return eachValue(SH+"InConstraintComponent", "Value is not one of "+this.graph.formatList(members), func(this *validator, node model.RDFTerm) (bool, error) {
+			for _, member := range members {
+				if member == node {
+					return true, nil
+				}
+			}
+			return false, nil
+		}), nil
+	}
+	return nil, nil
+}
+
+// sh:equals, sh:disjoint, sh:lessThan and sh:lessThanOrEquals compare the
+// value nodes with the values of a predicate of the focus node
+func (this *Shapes) pairConstraint(parameter model.IRI, value model.RDFTerm) (constraint, error) {
+	predicate, ok := value.(model.IRI)
+	if !ok {
+		return nil, fmt.Errorf("not an IRI")
+	}
+	return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		others, err := this.data.objects(focus, predicate)
+		if err != nil {
+			return err
+		}
+		switch parameter {
+		case shEquals:
+			for _, node := range values {
+				if !contains(others, node) {
+					this.report(shape, focus, node, SH+"EqualsConstraintComponent", "Value is not a value of "+this.data.describe(predicate))
+				}
+			}
+			for _, other := range others {
+				if !contains(values, other) {
+					this.report(shape, focus, other, SH+"EqualsConstraintComponent", "Value of "+this.data.describe(predicate)+" is not a value")
+				}
+			}
+		case shDisjoint:
+			for _, node := range values {
+				if contains(others, node) {
+					this.report(shape, focus, node, SH+"DisjointConstraintComponent", "Value is a value of "+this.data.describe(predicate))
+				}
+			}
+		default:
+			component, operator := SH+"LessThanConstraintComponent", "<"
+			if parameter == shLessThanOrEquals {
+				component, operator = SH+"LessThanOrEqualsConstraintComponent", "<="
+			}
+			for _, node := range values {
+				for _, other := range others {
+					c, ok := sparql.Compare(node, other)
+					if !ok || c > 0 || (c == 0 && parameter == shLessThan) {
+						this.report(shape, focus, node, component, "Value is not "+operator+" "+this.data.describe(other))
+					}
+				}
+			}
+		}
+		return nil
+	}, nil
+}
+
+// the number of value nodes conforming to a shape, and to none of the
+// qualified value shapes of the sibling property shapes when they are
+// disjoint
+func (this *Shapes) qualifiedConstraint(owner *shape, value model.RDFTerm) (constraint, error) {
+	qualified, err := this.shape(value)
+	if err != nil {
+		return nil, err
+	}
+	bounds := [2]int{-1, -1}
+	for i, parameter := range []model.IRI{shQualifiedMinCount, shQualifiedMaxCount} {
+		bound, err := this.parameter(owner, parameter)
+		if err != nil {
+			return nil, err
+		}
+		if bound != nil {
+			if bounds[i], err = integer(bound); err != nil {
+				return nil, err
+			}
+		}
+	}
+	disjoint, err := this.parameter(owner, shQualifiedValueShapesDisjoint)
+	if err != nil {
+		return nil, err
+	}
+	siblings := []*shape{}
+	if disjoint == model.NewTypedLiteral("true", model.XSDBoolean) {
+		parents, err := this.graph.subjects(shProperty, owner.node)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			properties, err := this.graph.objects(parent, shProperty)
+			if err != nil {
+				return nil, err
+			}
+			for _, property := range properties {
+				others, err := this.graph.objects(property, shQualifiedValueShape)
+				if err != nil {
+					return nil, err
+				}
+				for _, other := range others {
+					if other == value {
+						continue
+					}
+					sibling, err := this.shape(other)
+					if err != nil {
+						return nil, err
+					}
+					siblings = append(siblings, sibling)
+				}
+			}
+		}
+	}
+	return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		count := 0
+	values:
+		for _, node := range values {
+			conforms, err := this.conforms(qualified, node)
+			if err != nil {
+				return err
+			}
+			if !conforms {
+				continue
+			}
+			for _, sibling := range siblings {
+				conforms, err := this.conforms(sibling, node)
+				if err != nil {
+					return err
+				}
+				if conforms {
+					continue values
+				}
+			}
+			count++
+		}
+		if bounds[0] >= 0 && count < bounds[0] {
+			this.report(shape, focus, nil, SH+"QualifiedMinCountConstraintComponent", fmt.Sprintf("Less than %d values conform to shape %s", bounds[0], this.data.describe(qualified.node)))
+		}
+		if bounds[1] >= 0 && count > bounds[1] {
+			this.report(shape, focus, nil, SH+"QualifiedMaxCountConstraintComponent", fmt.Sprintf("More than %d values conform to shape %s", bounds[1], this.data.describe(qualified.node)))
+		}
+		return nil
+	}, nil
+}
+
+// the value nodes only have the predicates of the property shapes of the
+// shape, and those ignored
+func (this *Shapes) closedConstraint(owner *shape) (constraint, error) {
+	allowed := map[model.RDFTerm]struct{}{}
+	ignored, err := this.parameter(owner, shIgnoredProperties)
+	if err != nil {
+		return nil, err
+	}
+	if ignored != nil {
+		predicates, err := this.graph.list(ignored)
+		if err != nil {
+			return nil, err
+		}
+		for _, predicate := range predicates {
+			allowed[predicate] = struct{}{}
+		}
+	}
+	properties, err := this.graph.objects(owner.node, shProperty)
+	if err != nil {
+		return nil, err
+	}
+	for _, property := range properties {
+		paths, err := this.graph.objects(property, shPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if predicate, ok := path.(model.IRI); ok {
+				allowed[predicate] = struct{}{}
+			}
+		}
+	}
+	return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		for _, node := range values {
+			iterator := this.data.store.Match(node, nil, nil, nil)
+			seen := map[[2]model.RDFTerm]struct{}{}
+			for iterator.Next() {
+				statement := iterator.Statement()
+				if _, found := allowed[statement.Predicate]; found {
+					continue
+				}
+				key := [2]model.RDFTerm{statement.Predicate, statement.Object}
+				if _, found := seen[key]; found {
+					continue
+				}
+				seen[key] = struct{}{}
+				result := this.report(shape, focus, statement.Object, SH+"ClosedConstraintComponent", "Predicate "+this.data.describe(statement.Predicate)+" is not allowed (closed shape)")
+				result.Path = statement.Predicate
+			}
+			iterator.Close()
+			if err := iterator.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// the value of a parameter which is a non-negative xsd:integer
+func integer(value model.RDFTerm) (int, error) {
+	literal, ok := value.(model.Literal)
+	if !ok || literal.Datatype != model.XSDInteger {
+		return 0, fmt.Errorf("not an xsd:integer")
+	}
+	ret, err := strconv.Atoi(literal.Lexical)
+	if err != nil || ret < 0 {
+		return 0, fmt.Errorf("not a non-negative integer")
+	}
+	return ret, nil
+}
+
+// the string of IRIs and literals, blank nodes have none
+func lexicalForm(node model.RDFTerm) (string, bool) {
+	switch t := node.(type) {
+	case model.IRI:
+		return string(t), true
+	case model.Literal:
+		return t.Lexical, true
+	}
+	return "", false
+}
+
+// the flags of XPath regular expressions which Go knows
+func compilePattern(pattern string, flags model.RDFTerm) (*regexp.Regexp, error) {
+	prefix := ""
+	if flags != nil {
+		literal, ok := flags.(model.Literal)
+		if !ok {
+			return nil, fmt.Errorf("the flags are not a literal")
+		}
+		for _, flag := range literal.Lexical {
+			if !strings.ContainsRune("ism", flag) {
+				return nil, fmt.Errorf("unsupported flag %q", flag)
+			}
+		}
+		if literal.Lexical != "" {
+			prefix = "(?" + literal.Lexical + ")"
+		}
+	}
+	return regexp.Compile(prefix + pattern)
+}
+
+// the basic filtering of RFC 4647, like the SPARQL function langMatches
+func languageMatches(language, r string) bool {
+	if r == "*" {
+		return true
+	}
+	language, r = strings.ToLower(language), strings.ToLower(r)
+	return language == r || strings.HasPrefix(language, r+"-")
+}
+
+func contains(terms []model.RDFTerm, term model.RDFTerm) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
+
+func (this graph) formatList(terms []model.RDFTerm) string {
+	ret := []string{}
+	for _, term := range terms {
+		ret = append(ret, this.describe(term))
+	}
+	return "(" + strings.Join(ret, " ") + ")"
+}
+
+func (this graph) formatShapes(shapes []*shape) string {
+	ret := []string{}
+	for _, shape := range shapes {
+		ret = append(ret, this.describe(shape.node))
+	}
+	return "(" + strings.Join(ret, " ") + ")"
+}
@@ -0,0 +1,365 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// a graph read through a store, the union of its graphs
+type graph struct {
+	store  store.Store
+	labels *labels
+}
+
+// the N-Triples form of a term, for errors
+func (this graph) format(term model.RDFTerm) string {
+	return this.labels.format(term)
+}
+
+// the form of a term in the messages of results: blank nodes are written
+// [], a label would not be the one of the node in the written report
+func (this graph) describe(term model.RDFTerm) string {
+	switch t := term.(type) {
+	case model.BlankNode:
+		return "[]"
+	case model.TripleTerm:
+		return "<<( " + this.describe(t.Subject) + " " + this.describe(t.Predicate) + " " + this.describe(t.Object) + " )>>"
+	}
+	return this.format(term)
+}
+
+// the distinct terms matching the nil term of a pattern
+func (this graph) terms(subject, predicate, object model.RDFTerm) ([]model.RDFTerm, error) {
+	iterator := this.store.Match(subject, predicate, object, nil)
+	defer iterator.Close()
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	for iterator.Next() {
+		statement := iterator.Statement()
+		term := statement.Object
+		switch {
+		case subject == nil:
+			term = statement.Subject
+		case predicate == nil:
+			term = statement.Predicate
+		}
+		if _, found := seen[term]; !found {
+			seen[term] = struct{}{}
+			ret = append(ret, term)
+		}
+	}
+	return ret, iterator.Err()
+}
+
+func (this graph) objects(subject, predicate model.RDFTerm) ([]model.RDFTerm, error) {
+	return this.terms(subject, predicate, nil)
+}
+
+func (this graph) subjects(predicate, object model.RDFTerm) ([]model.RDFTerm, error) {
+	return this.terms(nil, predicate, object)
+}
+
+// the value of a predicate of a node, nil when there is none and an error
+// when there are several
+func (this graph) one(subject, predicate model.RDFTerm) (model.RDFTerm, error) {
+	values, err := this.objects(subject, predicate)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	if len(values) > 1 {
+		return nil, fmt.Errorf("%s has several values of %s", this.format(subject), this.format(predicate))
+	}
+	return values[0], nil
+}
+
+// the items of an RDF list
+func (this graph) list(head model.RDFTerm) ([]model.RDFTerm, error) {
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	for head != model.RDFNil {
+		if _, found := seen[head]; found {
+			return nil, fmt.Errorf("the list %s is cyclic", this.format(head))
+		}
+		seen[head] = struct{}{}
+		first, err := this.objects(head, model.RDFFirst)
+		if err != nil {
+			return nil, err
+		}
+		rest, err := this.objects(head, model.RDFRest)
+		if err != nil {
+			return nil, err
+		}
+		if len(first) != 1 || len(rest) != 1 {
+			return nil, fmt.Errorf("%s is not a well-formed list", this.format(head))
+		}
+		ret = append(ret, first[0])
+		head = rest[0]
+	}
+	return ret, nil
+}
+
+// the instances of a class and of its subclasses
+func (this graph) instances(class model.RDFTerm) ([]model.RDFTerm, error) {
+	classes := []model.RDFTerm{class}
+	seen := map[model.RDFTerm]struct{}{class: {}}
+	ret := []model.RDFTerm{}
+	found := map[model.RDFTerm]struct{}{}
+	for len(classes) > 0 {
+		class, classes = classes[0], classes[1:]
+		instances, err := this.subjects(model.A, class)
+		if err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if _, ok := found[instance]; !ok {
+				found[instance] = struct{}{}
+				ret = append(ret, instance)
+			}
+		}
+		subclasses, err := this.subjects(model.RDFSSubClassOf, class)
+		if err != nil {
+			return nil, err
+		}
+		for _, subclass := range subclasses {
+			if _, ok := seen[subclass]; !ok {
+				seen[subclass] = struct{}{}
+				classes = append(classes, subclass)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// true when a node has a type which is the class or one of its subclasses
+func (this graph) isInstance(node, class model.RDFTerm) (bool, error) {
+	classes, err := this.objects(node, model.A)
+	if err != nil {
+		return false, err
+	}
+	seen := map[model.RDFTerm]struct{}{}
+	for len(classes) > 0 {
+		var next model.RDFTerm
+		next, classes = classes[0], classes[1:]
+		if next == class {
+			return true, nil
+		}
+		if _, found := seen[next]; found {
+			continue
+		}
+		seen[next] = struct{}{}
+		superclasses, err := this.objects(next, model.RDFSSubClassOf)
+		if err != nil {
+			return false, err
+		}
+		classes = append(classes, superclasses...)
+	}
+	return false, nil
+}
+
+// Paths
+
+// a property path, read from the shapes graph
+type path interface {
+	// the distinct nodes reached from a node of the data graph
+	values(data graph, node model.RDFTerm) ([]model.RDFTerm, error)
+}
+
+type predicatePath model.IRI
+
+// the inverse of a predicate, the inverses of the other paths are made of
+// inverse predicates
+type inversePath model.IRI
+
+type sequencePath []path
+
+type alternativePath []path
+
+// zero or more, one or more or zero or one
+type repeatPath struct {
+	path path
+	zero bool
+	many bool
+}
+
+func (this predicatePath) values(data graph, node model.RDFTerm) ([]model.RDFTerm, error) {
+	return data.objects(node, model.IRI(this))
+}
+
+func (this inversePath) values(data graph, node model.RDFTerm) ([]model.RDFTerm, error) {
+	return data.subjects(model.IRI(this), node)
+}
+
+func (this sequencePath) values(data graph, node model.RDFTerm) ([]model.RDFTerm, error) {
+	current := []model.RDFTerm{node}
+	for _, step := range this {
+		next := []model.RDFTerm{}
+		seen := map[model.RDFTerm]struct{}{}
+		for _, from := range current {
+			reached, err := step.values(data, from)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range reached {
+				if _, found := seen[value]; !found {
+					seen[value] = struct{}{}
+					next = append(next, value)
+				}
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+func (this alternativePath) values(data graph, node model.RDFTerm) ([]model.RDFTerm, error) {
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	for _, alternative := range this {
+		reached, err := alternative.values(data, node)
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range reached {
+			if _, found := seen[value]; !found {
+				seen[value] = struct{}{}
+				ret = append(ret, value)
+			}
+		}
+	}
+	return ret, nil
+}
+
+func (this *repeatPath) values(data graph, node model.RDFTerm) ([]model.RDFTerm, error) {
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	if this.zero {
+		seen[node] = struct{}{}
+		ret = append(ret, node)
+	}
+	current := []model.RDFTerm{node}
+	for len(current) > 0 {
+		next := []model.RDFTerm{}
+		for _, from := range current {
+			reached, err := this.path.values(data, from)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range reached {
+				if _, found := seen[value]; !found {
+					seen[value] = struct{}{}
+					ret = append(ret, value)
+					next = append(next, value)
+				}
+			}
+		}
+		if !this.many {
+			break
+		}
+		current = next
+	}
+	return ret, nil
+}
+
+// reads the path of a node of the shapes graph
+func (this *Shapes) path(node model.RDFTerm) (path, error) {
+	if iri, ok := node.(model.IRI); ok {
+		return predicatePath(iri), nil
+	}
+	if !model.IsBlankNode(node) {
+		return nil, fmt.Errorf("%s is not a path", this.graph.format(node))
+	}
+	if first, err := this.graph.objects(node, model.RDFFirst); err != nil || len(first) > 0 {
+		if err != nil {
+			return nil, err
+		}
+		items, err := this.graph.list(node)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) < 2 {
+			return nil, fmt.Errorf("the sequence path %s has less than two items", this.graph.format(node))
+		}
+		ret := sequencePath{}
+		for _, item := range items {
+			step, err := this.path(item)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, step)
+		}
+		return ret, nil
+	}
+	for _, predicate := range []model.IRI{shInversePath, shAlternativePath, shZeroOrMorePath, shOneOrMorePath, shZeroOrOnePath} {
+		objects, err := this.graph.objects(node, predicate)
+		if err != nil {
+			return nil, err
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		if len(objects) > 1 {
+			return nil, fmt.Errorf("the path %s has several values of %s", this.graph.format(node), this.graph.format(predicate))
+		}
+		if predicate == shAlternativePath {
+			items, err := this.graph.list(objects[0])
+			if err != nil {
+				return nil, err
+			}
+			if len(items) < 2 {
+				return nil, fmt.Errorf("the alternative path %s has less than two items", this.graph.format(node))
+			}
+			ret := alternativePath{}
+			for _, item := range items {
+				alternative, err := this.path(item)
+				if err != nil {
+					return nil, err
+				}
+				ret = append(ret, alternative)
+			}
+			return ret, nil
+		}
+		inner, err := this.path(objects[0])
+		if err != nil {
+			return nil, err
+		}
+		switch predicate {
+		case shInversePath:
+			return invert(inner), nil
+		case shZeroOrMorePath:
+			return &repeatPath{path: inner, zero: true, many: true}, nil
+		case shOneOrMorePath:
+			return &repeatPath{path: inner, many: true}, nil
+		}
+		return &repeatPath{path: inner, zero: true}, nil
+	}
+	return nil, fmt.Errorf("%s is not a path", this.graph.format(node))
+}
+
+func invert(p path) path {
+	switch p := p.(type) {
+	case predicatePath:
+		return inversePath(p)
+	case inversePath:
+		return predicatePath(p)
+	case sequencePath:
+		ret := sequencePath{}
+		for i := len(p) - 1; i >= 0; i-- {
+			ret = append(ret, invert(p[i]))
+		}
+		return ret
+	case alternativePath:
+		ret := alternativePath{}
+		for _, alternative := range p {
+			ret = append(ret, invert(alternative))
+		}
+		return ret
+	}
+	repeat := p.(*repeatPath)
+	return &repeatPath{path: invert(repeat.path), zero: repeat.zero, many: repeat.many}
+}
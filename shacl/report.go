@@ -0,0 +1,116 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Report is the outcome of a validation
+type Report struct {
+	// true when there are no results, whatever their severity
+	Conforms bool
+	Results  []*Result
+	// the shapes graph, which describes the paths of the results
+	shapes graph
+}
+
+// Result is a validation result
+type Result struct {
+	Focus model.RDFTerm
+	// the path of the property shape, a node of the shapes graph, or the
+	// predicate of closed shapes. Nil for node shapes.
+	Path model.RDFTerm
+	// nil when the constraint is not about a value node
+	Value model.RDFTerm
+	Shape model.RDFTerm
+	// the SPARQL constraint of sh:sparql, nil for the other components
+	Constraint model.RDFTerm
+	Component  model.IRI
+	Severity   model.IRI
+	Messages   []model.RDFTerm
+}
+
+// Statements are the validation report graph, an sh:ValidationReport with
+// its sh:ValidationResult. The paths which are blank nodes are described as
+// in the shapes graph, with fresh blank nodes for each result so that every
+// sh:resultPath can be nested.
+func (this *Report) Statements() ([]*model.Statement, error) {
+	report := model.NewAnonymousBlankNode()
+	conforms := "false"
+	if this.Conforms {
+		conforms = "true"
+	}
+	ret := []*model.Statement{
+		{Subject: report, Predicate: model.A, Object: shValidationReport},
+		{Subject: report, Predicate: shConforms, Object: model.NewTypedLiteral(conforms, model.XSDBoolean)},
+	}
+	for _, result := range this.Results {
+		node := model.NewAnonymousBlankNode()
+		ret = append(ret,
+			&model.Statement{Subject: report, Predicate: shResult, Object: node},
+			&model.Statement{Subject: node, Predicate: model.A, Object: shValidationResult},
+			&model.Statement{Subject: node, Predicate: shFocusNode, Object: result.Focus},
+		)
+		if result.Path != nil {
+			path, description, err := this.describe(result.Path, map[model.RDFTerm]model.RDFTerm{})
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, &model.Statement{Subject: node, Predicate: shResultPath, Object: path})
+			ret = append(ret, description...)
+		}
+		if result.Value != nil {
+			ret = append(ret, &model.Statement{Subject: node, Predicate: shValue, Object: result.Value})
+		}
+		ret = append(ret, &model.Statement{Subject: node, Predicate: shSourceShape, Object: result.Shape})
+		if result.Constraint != nil {
+			ret = append(ret, &model.Statement{Subject: node, Predicate: shSourceConstraint, Object: result.Constraint})
+		}
+		ret = append(ret,
+			&model.Statement{Subject: node, Predicate: shSourceConstraintComponent, Object: result.Component},
+			&model.Statement{Subject: node, Predicate: shResultSeverity, Object: result.Severity},
+		)
+		for _, message := range result.Messages {
+			ret = append(ret, &model.Statement{Subject: node, Predicate: shResultMessage, Object: message})
+		}
+	}
+	return ret, nil
+}
+
+// a copy of node with fresh blank nodes and the statements of the shapes
+// graph about it and the blank nodes it refers to, copies keeps the blank
+// nodes already copied
+func (this *Report) describe(node model.RDFTerm, copies map[model.RDFTerm]model.RDFTerm) (model.RDFTerm, []*model.Statement, error) {
+	if !model.IsBlankNode(node) {
+		return node, nil, nil
+	}
+	if fresh, found := copies[node]; found {
+		return fresh, nil, nil
+	}
+	fresh := model.NewAnonymousBlankNode()
+	copies[node] = fresh
+	iterator := this.shapes.store.Match(node, nil, nil, nil)
+	statements := []*model.Statement{}
+	for iterator.Next() {
+		statement := iterator.Statement()
+		statements = append(statements, &model.Statement{Subject: fresh, Predicate: statement.Predicate, Object: statement.Object})
+	}
+	iterator.Close()
+	if err := iterator.Err(); err != nil {
+		return nil, nil, err
+	}
+	ret := []*model.Statement{}
+	for _, statement := range statements {
+		object, nested, err := this.describe(statement.Object, copies)
+		if err != nil {
+			return nil, nil, err
+		}
+		statement.Object = object
+		ret = append(ret, statement)
+		ret = append(ret, nested...)
+	}
+	return fresh, ret, nil
+}
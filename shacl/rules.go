@@ -0,0 +1,219 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// a SHACL rule, a triple rule or a SPARQL rule
+type rule struct {
+	node  model.RDFTerm
+	order float64
+	// the shapes the focus nodes conform to
+	conditions []*shape
+	// the statements inferred from a focus node
+	infer func(data graph, focus model.RDFTerm) ([]*model.Statement, error)
+}
+
+// the nodes a node expression gives for a focus node
+type nodeExpression func(data graph, focus model.RDFTerm) ([]model.RDFTerm, error)
+
+// Infer executes the rules of the shapes with targets on their focus nodes,
+// the rules of a shape in the order of sh:order. The statements inferred are
+// added to the default graph of the data, later rules see them, and are
+// returned.
+func (this *Shapes) Infer(data store.Store) ([]*model.Statement, error) {
+	validator := &validator{data: graph{store: data, labels: this.graph.labels}, active: make(map[[2]model.RDFTerm]struct{})}
+	ret := []*model.Statement{}
+	for _, shape := range this.targeted {
+		if shape.deactivated || len(shape.rules) == 0 {
+			continue
+		}
+		focusNodes, err := validator.focusNodes(shape)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range shape.rules {
+		nodes:
+			for _, focus := range focusNodes {
+				for _, condition := range rule.conditions {
+					conforms, err := validator.conforms(condition, focus)
+					if err != nil {
+						return nil, err
+					}
+					if !conforms {
+						continue nodes
+					}
+				}
+				statements, err := rule.infer(validator.data, focus)
+				if err != nil {
+					return nil, err
+				}
+				for _, statement := range statements {
+					present, err := data.Contains(statement)
+					if err != nil {
+						return nil, err
+					}
+					if !present {
+						if err := data.Add(statement); err != nil {
+							return nil, err
+						}
+						ret = append(ret, statement)
+					}
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+// reads the rules of a shape, nil when deactivated
+func (this *Shapes) rule(node model.RDFTerm) (*rule, error) {
+	deactivated, err := this.graph.one(node, shDeactivated)
+	if err != nil || deactivated == model.NewTypedLiteral("true", model.XSDBoolean) {
+		return nil, err
+	}
+	ret := &rule{node: node}
+	order, err := this.graph.one(node, shOrder)
+	if err != nil {
+		return nil, err
+	}
+	if order != nil {
+		literal, ok := order.(model.Literal)
+		if ret.order, err = strconv.ParseFloat(literal.Lexical, 64); !ok || err != nil {
+			return nil, fmt.Errorf("the order of %s is not a number", this.graph.format(node))
+		}
+	}
+	conditions, err := this.graph.objects(node, shCondition)
+	if err != nil {
+		return nil, err
+	}
+	for _, condition := range conditions {
+		shape, err := this.shape(condition)
+		if err != nil {
+			return nil, err
+		}
+		ret.conditions = append(ret.conditions, shape)
+	}
+
+	if construct, err := this.graph.one(node, shConstruct); err != nil {
+		return nil, err
+	} else if construct != nil {
+		validator, err := this.sparqlValidator(node, shConstruct)
+		if err != nil {
+			return nil, err
+		}
+		query, err := sparql.ParseQuery(validator.text, sparql.Options{Namespaces: validator.namespaces})
+		if err != nil {
+			return nil, fmt.Errorf("the query of %s: %w", this.graph.format(node), err)
+		}
+		if query.Form != sparql.Construct {
+			return nil, fmt.Errorf("the query of %s is not a CONSTRUCT query", this.graph.format(node))
+		}
+		ret.infer = func(data graph, focus model.RDFTerm) ([]*model.Statement, error) {
+			result, err := sparql.Evaluate(sparql.Substitute(query, sparql.Solution{"this": focus}), data.store)
+			if err != nil {
+				return nil, err
+			}
+			return result.Statements, nil
+		}
+		return ret, nil
+	}
+
+	expressions := [3]nodeExpression{}
+	for i, predicate := range []model.IRI{shSubject, shPredicate, shObject} {
+		value, err := this.graph.one(node, predicate)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			return nil, fmt.Errorf("the rule %s has no %s", this.graph.format(node), this.graph.format(predicate))
+		}
+		if expressions[i], err = this.nodeExpression(value); err != nil {
+			return nil, err
+		}
+	}
+	ret.infer = func(data graph, focus model.RDFTerm) ([]*model.Statement, error) {
+		terms := [3][]model.RDFTerm{}
+		for i, expression := range expressions {
+			var err error
+			if terms[i], err = expression(data, focus); err != nil {
+				return nil, err
+			}
+		}
+		statements := []*model.Statement{}
+		for _, subject := range terms[0] {
+			if _, ok := subject.(model.Literal); ok {
+				continue
+			}
+			for _, predicate := range terms[1] {
+				if _, ok := predicate.(model.IRI); !ok {
+					continue
+				}
+				for _, object := range terms[2] {
+					statements = append(statements, &model.Statement{Subject: subject, Predicate: predicate, Object: object})
+				}
+			}
+		}
+		return statements, nil
+	}
+	return ret, nil
+}
+
+// sh:this, a constant or the nodes reached from the focus node by a path
+func (this *Shapes) nodeExpression(node model.RDFTerm) (nodeExpression, error) {
+	if node == shThis {
+		return func(data graph, focus model.RDFTerm) ([]model.RDFTerm, error) {
+			return []model.RDFTerm{focus}, nil
+		}, nil
+	}
+	if !model.IsBlankNode(node) {
+		return func(data graph, focus model.RDFTerm) ([]model.RDFTerm, error) {
+			return []model.RDFTerm{node}, nil
+		}, nil
+	}
+	value, err := this.graph.one(node, shPath)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%s is not a supported node expression", this.graph.format(node))
+	}
+	path, err := this.path(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(data graph, focus model.RDFTerm) ([]model.RDFTerm, error) {
+		return path.values(data, focus)
+	}, nil
+}
+
+// reads the rules of a shape in their order
+func (this *Shapes) rules(shape *shape) error {
+	nodes, err := this.graph.objects(shape.node, shRule)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		rule, err := this.rule(node)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", this.graph.format(shRule), this.graph.format(node), err)
+		}
+		if rule != nil {
+			shape.rules = append(shape.rules, rule)
+		}
+	}
+	sort.SliceStable(shape.rules, func(i, j int) bool {
+		return shape.rules[i].order < shape.rules[j].order
+	})
+	return nil
+}
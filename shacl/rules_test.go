@@ -0,0 +1,65 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+func TestInfer(t *testing.T) {
+	shapes, err := Parse(strings.NewReader(prefixes+declarations+`
+:Rectangle sh:targetClass :Rectangle ;
+  sh:rule [ a sh:SPARQLRule ; sh:order 2 ; sh:prefixes :prefixes ; sh:construct """
+CONSTRUCT { $this ex:area ?area } WHERE { $this ex:width ?w ; ex:height ?h . BIND (?w * ?h AS ?area) }""" ] ;
+  sh:rule [ a sh:TripleRule ; sh:order 1 ; sh:subject sh:this ; sh:predicate <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> ; sh:object :Square ;
+    sh:condition [ sh:property [ sh:path :width ; sh:equals :height ] ] ] ;
+  sh:rule [ a sh:TripleRule ; sh:order 3 ; sh:subject [ sh:path :owner ] ; sh:predicate :owns ; sh:object sh:this ] ;
+  sh:rule [ a sh:TripleRule ; sh:deactivated true ; sh:subject sh:this ; sh:predicate :p ; sh:object :o ] .
+`), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := data(t, `:a a :Rectangle ; :width 2 ; :height 3 ; :owner :x . :b a :Rectangle ; :width 4 ; :height 4 .`)
+	inferred, err := shapes.Infer(dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, statement := range inferred {
+		line := writer.FormatTerm(statement.Subject, nil) + " " + writer.FormatTerm(statement.Predicate, nil) + " " + writer.FormatTerm(statement.Object, nil)
+		got = append(got, strings.NewReplacer("http://ex.org/", ":", "http://www.w3.org/2001/XMLSchema#", "xsd:").Replace(line))
+	}
+	sort.Strings(got)
+	expected := []string{
+		`<:a> <:area> "6"^^<xsd:integer>`,
+		`<:b> <:area> "16"^^<xsd:integer>`,
+		`<:b> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Square>`,
+		`<:x> <:owns> <:a>`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("inferred\n%s\ninstead of\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+	// the data holds them, a second execution infers nothing new
+	if again, err := shapes.Infer(dataset); err != nil || len(again) != 0 {
+		t.Errorf("inferred %d statements again, %v", len(again), err)
+	}
+
+	for text, message := range map[string]string{
+		`:s sh:targetNode :a ; sh:rule [ sh:subject sh:this ; sh:predicate :p ] .`:                                   "has no <http://www.w3.org/ns/shacl#object>",
+		`:s sh:targetNode :a ; sh:rule [ sh:construct "SELECT * { }" ] .`:                                            "is not a CONSTRUCT query",
+		`:s sh:targetNode :a ; sh:rule [ sh:subject [ :x :y ] ; sh:predicate :p ; sh:object :o ] .`:                  "is not a supported node expression",
+		`:s sh:targetNode :a ; sh:rule [ sh:order "first" ; sh:subject sh:this ; sh:predicate :p ; sh:object :o ] .`: "is not a number",
+	} {
+		_, err := Parse(strings.NewReader(prefixes+text), parser.Options{})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", text, err, message)
+		}
+	}
+}
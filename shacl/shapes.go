@@ -0,0 +1,265 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package shacl validates RDF data against the shapes of the Shapes
+// Constraint Language (SHACL), with the constraint components of SHACL
+// Core and SHACL-SPARQL, and executes the triple and SPARQL rules of the
+// SHACL Advanced Features.
+//
+// The shapes and data graphs are read through stores, each the union of
+// the graphs of its store.
+package shacl
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// Shapes are the shapes of a shapes graph
+type Shapes struct {
+	graph graph
+	// all the shapes read, by node
+	shapes map[model.RDFTerm]*shape
+	// the shapes with targets, in the order they were read
+	targeted []*shape
+	// the SPARQL-based constraint components
+	components []*component
+}
+
+// a node shape, or a property shape when it has a path
+type shape struct {
+	node model.RDFTerm
+	// the node of the path in the shapes graph, nil for node shapes
+	pathNode    model.RDFTerm
+	path        path
+	severity    model.IRI
+	messages    []model.RDFTerm
+	deactivated bool
+	targets     []target
+	constraints []constraint
+	rules       []*rule
+}
+
+// a target of a shape, the predicate telling how the object selects focus
+// nodes
+type target struct {
+	predicate model.IRI
+	object    model.RDFTerm
+}
+
+// Parse reads a shapes graph
+func Parse(reader io.Reader, options parser.Options) (*Shapes, error) {
+	statements, err := parser.ParseAll(reader, options)
+	if err != nil {
+		return nil, err
+	}
+	shapesGraph := store.NewMemory()
+	for _, statement := range statements {
+		shapesGraph.Add(statement)
+	}
+	return NewShapes(shapesGraph)
+}
+
+// NewShapes reads the shapes of a shapes graph: the instances of
+// sh:NodeShape and sh:PropertyShape, the subjects of targets, of constraint
+// parameters and of rules, and the shapes they refer to, with the
+// constraint components defined by SPARQL queries. Ill-formed shapes are
+// errors.
+func NewShapes(shapesGraph store.Store) (*Shapes, error) {
+	this := &Shapes{graph: graph{store: shapesGraph, labels: &labels{labels: writer.NewBlankNodeLabels()}}, shapes: make(map[model.RDFTerm]*shape)}
+	if err := this.readComponents(); err != nil {
+		return nil, err
+	}
+	nodes := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	patterns := [][2]model.RDFTerm{
+		{model.A, shNodeShape}, {model.A, shPropertyShape},
+		{shTargetNode, nil}, {shTargetClass, nil}, {shTargetSubjectsOf, nil}, {shTargetObjectsOf, nil},
+	}
+	for _, parameter := range parameters {
+		patterns = append(patterns, [2]model.RDFTerm{parameter, nil})
+	}
+	patterns = append(patterns, [2]model.RDFTerm{shSPARQL, nil}, [2]model.RDFTerm{shRule, nil})
+	for _, component := range this.components {
+		for _, parameter := range component.parameters {
+			patterns = append(patterns, [2]model.RDFTerm{parameter.path, nil})
+		}
+	}
+	for _, pattern := range patterns {
+		subjects, err := this.graph.subjects(pattern[0], pattern[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, subject := range subjects {
+			if _, found := seen[subject]; !found {
+				seen[subject] = struct{}{}
+				nodes = append(nodes, subject)
+			}
+		}
+	}
+	for _, node := range nodes {
+		shape, err := this.shape(node)
+		if err != nil {
+			return nil, err
+		}
+		if len(shape.targets) > 0 {
+			this.targeted = append(this.targeted, shape)
+		}
+	}
+	return this, nil
+}
+
+// the shape of a node, read once. Shapes may refer to themselves, the
+// shape is known before its constraints are read.
+func (this *Shapes) shape(node model.RDFTerm) (*shape, error) {
+	if ret, found := this.shapes[node]; found {
+		return ret, nil
+	}
+	ret := &shape{node: node, severity: Violation}
+	this.shapes[node] = ret
+	if err := this.read(ret); err != nil {
+		return nil, fmt.Errorf("shape %s: %w", this.graph.format(node), err)
+	}
+	return ret, nil
+}
+
+func (this *Shapes) read(shape *shape) error {
+	node := shape.node
+	paths, err := this.graph.objects(node, shPath)
+	if err != nil {
+		return err
+	}
+	switch len(paths) {
+	case 0:
+	case 1:
+		shape.pathNode = paths[0]
+		if shape.path, err = this.path(paths[0]); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("several values of sh:path")
+	}
+	if shape.messages, err = this.graph.objects(node, shMessage); err != nil {
+		return err
+	}
+	severities, err := this.graph.objects(node, shSeverity)
+	if err != nil {
+		return err
+	}
+	if len(severities) > 0 {
+		severity, ok := severities[0].(model.IRI)
+		if len(severities) > 1 || !ok {
+			return fmt.Errorf("the severity is not a single IRI")
+		}
+		shape.severity = severity
+	}
+	deactivated, err := this.graph.objects(node, shDeactivated)
+	if err != nil {
+		return err
+	}
+	for _, value := range deactivated {
+		if value == model.NewTypedLiteral("true", model.XSDBoolean) {
+			shape.deactivated = true
+		}
+	}
+
+	for _, predicate := range []model.IRI{shTargetNode, shTargetClass, shTargetSubjectsOf, shTargetObjectsOf} {
+		objects, err := this.graph.objects(node, predicate)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			shape.targets = append(shape.targets, target{predicate: predicate, object: object})
+		}
+	}
+	// shapes which are classes target their instances
+	for _, class := range []model.RDFTerm{model.RDFSClass, owlClass} {
+		if present, err := this.graph.store.Contains(&model.Statement{Subject: node, Predicate: model.A, Object: class}); err != nil {
+			return err
+		} else if present {
+			shape.targets = append(shape.targets, target{predicate: shTargetClass, object: node})
+			break
+		}
+	}
+
+	for _, parameter := range parameters {
+		values, err := this.graph.objects(node, parameter)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			constraint, err := this.constraint(shape, parameter, value)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", this.graph.format(parameter), this.graph.format(value), err)
+			}
+			if constraint != nil {
+				shape.constraints = append(shape.constraints, constraint)
+			}
+		}
+	}
+	nodes, err := this.graph.objects(node, shSPARQL)
+	if err != nil {
+		return err
+	}
+	for _, value := range nodes {
+		constraint, err := this.sparqlConstraint(shape, value)
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", this.graph.format(shSPARQL), this.graph.format(value), err)
+		}
+		if constraint != nil {
+			shape.constraints = append(shape.constraints, constraint)
+		}
+	}
+	constraints, err := this.componentConstraints(shape)
+	if err != nil {
+		return err
+	}
+	shape.constraints = append(shape.constraints, constraints...)
+	return this.rules(shape)
+}
+
+// the shapes of the members of a list
+func (this *Shapes) shapeList(head model.RDFTerm) ([]*shape, error) {
+	members, err := this.graph.list(head)
+	if err != nil {
+		return nil, err
+	}
+	ret := []*shape{}
+	for _, member := range members {
+		shape, err := this.shape(member)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, shape)
+	}
+	return ret, nil
+}
+
+// the value of a parameter of the shape, when it has one
+func (this *Shapes) parameter(shape *shape, predicate model.IRI) (model.RDFTerm, error) {
+	return this.graph.one(shape.node, predicate)
+}
+
+const owlClass model.IRI = "http://www.w3.org/2002/07/owl#Class"
+
+// labels are the blank node labels of the errors of a Shapes,
+// shared by its validations which may run concurrently
+type labels struct {
+	lock   sync.Mutex
+	labels *writer.BlankNodeLabels
+}
+
+// the N-Triples form of a term, for errors
+func (this *labels) format(term model.RDFTerm) string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return writer.FormatTerm(term, this.labels)
+}
@@ -0,0 +1,391 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// a constraint component defined by SPARQL queries
+type component struct {
+	node       model.IRI
+	parameters []parameter
+	// the ASK validator of sh:validator and the SELECT validators of node
+	// and property shapes, nil when missing
+	validator         *sparqlValidator
+	nodeValidator     *sparqlValidator
+	propertyValidator *sparqlValidator
+	messages          []model.RDFTerm
+}
+
+// the parameters are pre-bound to the variables of their local names
+type parameter struct {
+	path     model.IRI
+	name     sparql.Variable
+	optional bool
+}
+
+type sparqlValidator struct {
+	node       model.RDFTerm
+	text       string
+	namespaces []model.Namespace
+	messages   []model.RDFTerm
+}
+
+// reads the SPARQL-based constraint components of the shapes graph
+func (this *Shapes) readComponents() error {
+	nodes, err := this.graph.subjects(model.A, shConstraintComponent)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		iri, ok := node.(model.IRI)
+		if !ok {
+			return fmt.Errorf("the constraint component %s is not an IRI", this.graph.format(node))
+		}
+		component := &component{node: iri}
+		if component.messages, err = this.graph.objects(node, shMessage); err != nil {
+			return err
+		}
+		parameters, err := this.graph.objects(node, shParameter)
+		if err != nil {
+			return err
+		}
+		for _, declaration := range parameters {
+			path, err := this.graph.one(declaration, shPath)
+			if err != nil {
+				return fmt.Errorf("constraint component %s: %w", this.graph.format(node), err)
+			}
+			predicate, ok := path.(model.IRI)
+			if !ok {
+				return fmt.Errorf("constraint component %s: the path of a parameter is not an IRI", this.graph.format(node))
+			}
+			optional, err := this.graph.one(declaration, shOptional)
+			if err != nil {
+				return err
+			}
+			component.parameters = append(component.parameters, parameter{
+				path:     predicate,
+				name:     sparql.Variable(localName(predicate)),
+				optional: optional == model.NewTypedLiteral("true", model.XSDBoolean),
+			})
+		}
+		for _, validator := range []struct {
+			predicate model.IRI
+			query     model.IRI
+			into      **sparqlValidator
+		}{
+			{shValidator, shAsk, &component.validator},
+			{shNodeValidator, shSelect, &component.nodeValidator},
+			{shPropertyValidator, shSelect, &component.propertyValidator},
+		} {
+			node, err := this.graph.one(component.node, validator.predicate)
+			if err != nil || node == nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if *validator.into, err = this.sparqlValidator(node, validator.query); err != nil {
+				return fmt.Errorf("constraint component %s: %w", this.graph.format(component.node), err)
+			}
+		}
+		this.components = append(this.components, component)
+	}
+	return nil
+}
+
+// the query, prefixes and messages of a node with a SELECT, ASK or
+// CONSTRUCT query
+func (this *Shapes) sparqlValidator(node model.RDFTerm, form model.IRI) (*sparqlValidator, error) {
+	text, err := this.graph.one(node, form)
+	if err != nil {
+		return nil, err
+	}
+	literal, ok := text.(model.Literal)
+	if !ok {
+		return nil, fmt.Errorf("%s has no %s string", this.graph.format(node), this.graph.format(form))
+	}
+	ret := &sparqlValidator{node: node, text: literal.Lexical}
+	if ret.namespaces, err = this.prefixes(node); err != nil {
+		return nil, err
+	}
+	if ret.messages, err = this.graph.objects(node, shMessage); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// the namespaces declared by the values of sh:prefixes
+func (this *Shapes) prefixes(node model.RDFTerm) ([]model.Namespace, error) {
+	ret := []model.Namespace{}
+	ontologies, err := this.graph.objects(node, shPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	for _, ontology := range ontologies {
+		declarations, err := this.graph.objects(ontology, shDeclare)
+		if err != nil {
+			return nil, err
+		}
+		for _, declaration := range declarations {
+			prefix, err := this.graph.one(declaration, shPrefix)
+			if err != nil {
+				return nil, err
+			}
+			namespace, err := this.graph.one(declaration, shNamespace)
+			if err != nil {
+				return nil, err
+			}
+			p, ok := prefix.(model.Literal)
+			n, ok2 := namespace.(model.Literal)
+			if !ok || !ok2 {
+				return nil, fmt.Errorf("the prefix declaration %s has no prefix or namespace", this.graph.format(declaration))
+			}
+			ret = append(ret, model.Namespace{Prefix: model.Prefix(p.Lexical), IRI: model.IRI(n.Lexical)})
+		}
+	}
+	return ret, nil
+}
+
+var pathVariable = regexp.MustCompile(`\$PATH\b`)
+
+// parses the query of a validator for a shape of the shapes graph, $PATH
+// standing for the path of property shapes
+func (this *sparqlValidator) query(shape *shape, shapes graph) (*sparql.Query, error) {
+	text := this.text
+	if pathVariable.MatchString(text) {
+		if shape.path == nil {
+			return nil, fmt.Errorf("the query of %s uses $PATH in a node shape", shapes.format(this.node))
+		}
+		text = pathVariable.ReplaceAllLiteralString(text, pathSyntax(shape.path))
+	}
+	ret, err := sparql.ParseQuery(text, sparql.Options{Namespaces: this.namespaces})
+	if err != nil {
+		return nil, fmt.Errorf("the query of %s: %w", shapes.format(this.node), err)
+	}
+	return ret, nil
+}
+
+// the constraint of a value of sh:sparql, nil when deactivated
+func (this *Shapes) sparqlConstraint(owner *shape, node model.RDFTerm) (constraint, error) {
+	deactivated, err := this.graph.one(node, shDeactivated)
+	if err != nil || deactivated == model.NewTypedLiteral("true", model.XSDBoolean) {
+		return nil, err
+	}
+	definition, err := this.sparqlValidator(node, shSelect)
+	if err != nil {
+		return nil, err
+	}
+	query, err := definition.query(owner, this.graph)
+	if err != nil {
+		return nil, err
+	}
+	if query.Form != sparql.Select {
+		return nil, fmt.Errorf("the query of %s is not a SELECT query", this.graph.format(node))
+	}
+	return func(this *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		bindings := sparql.Solution{"this": focus, "currentShape": shape.node}
+		results, err := this.solutions(shape, focus, query, bindings, shSPARQLConstraintComponent, definition.messages)
+		for _, result := range results {
+			result.Constraint = node
+		}
+		return err
+	}, nil
+}
+
+// the constraints of the SPARQL-based constraint components of a shape,
+// one for each combination of the values of their parameters
+func (this *Shapes) componentConstraints(shape *shape) ([]constraint, error) {
+	ret := []constraint{}
+	for _, component := range this.components {
+		validator := component.validator
+		if shape.path == nil && component.nodeValidator != nil {
+			validator = component.nodeValidator
+		} else if shape.path != nil && component.propertyValidator != nil {
+			validator = component.propertyValidator
+		}
+		if validator == nil {
+			continue
+		}
+		combinations := []sparql.Solution{{}}
+		for _, parameter := range component.parameters {
+			values, err := this.graph.objects(shape.node, parameter.path)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) == 0 {
+				if !parameter.optional {
+					combinations = nil
+					break
+				}
+				continue
+			}
+			next := []sparql.Solution{}
+			for _, combination := range combinations {
+				for _, value := range values {
+					bindings := sparql.Solution{parameter.name: value}
+					for variable, bound := range combination {
+						bindings[variable] = bound
+					}
+					next = append(next, bindings)
+				}
+			}
+			combinations = next
+		}
+		if len(combinations) == 0 {
+			continue
+		}
+		query, err := validator.query(shape, this.graph)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", this.graph.format(component.node), err)
+		}
+		messages := validator.messages
+		if len(messages) == 0 {
+			messages = component.messages
+		}
+		for _, combination := range combinations {
+			ret = append(ret, component.constraint(query, combination, messages))
+		}
+	}
+	return ret, nil
+}
+
+// ASK validators tell whether each value node conforms, SELECT validators
+// return the results
+func (this *component) constraint(query *sparql.Query, parameters sparql.Solution, messages []model.RDFTerm) constraint {
+	return func(v *validator, shape *shape, focus model.RDFTerm, values []model.RDFTerm) error {
+		bindings := sparql.Solution{"this": focus, "currentShape": shape.node}
+		for variable, value := range parameters {
+			bindings[variable] = value
+		}
+		if query.Form == sparql.Select {
+			_, err := v.solutions(shape, focus, query, bindings, this.node, messages)
+			return err
+		}
+		for _, value := range values {
+			bindings["value"] = value
+			answer, err := sparql.Evaluate(sparql.Substitute(query, bindings), v.data.store)
+			if err != nil {
+				return err
+			}
+			if !answer.Boolean {
+				result := v.report(shape, focus, value, this.node, "Value does not conform to "+v.data.describe(this.node))
+				if len(shape.messages) == 0 && len(messages) > 0 {
+					result.Messages = v.fill(messages, bindings)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// reports a result for each solution of a pre-bound SELECT query. ?this,
+// ?path and ?value give the focus node, path and value of the results,
+// ?message their message, and a true ?failure fails the validation.
+func (this *validator) solutions(shape *shape, focus model.RDFTerm, query *sparql.Query, bindings sparql.Solution, component model.IRI, messages []model.RDFTerm) ([]*Result, error) {
+	answer, err := sparql.Evaluate(sparql.Substitute(query, bindings), this.data.store)
+	if err != nil {
+		return nil, err
+	}
+	ret := []*Result{}
+	for _, solution := range answer.Solutions {
+		if solution["failure"] == model.NewTypedLiteral("true", model.XSDBoolean) {
+			return ret, fmt.Errorf("the query of shape %s failed for %s", this.data.format(shape.node), this.data.format(focus))
+		}
+		node := focus
+		if value, ok := solution["this"]; ok {
+			node = value
+		}
+		value := solution["value"]
+		if value == nil && shape.path == nil {
+			value = node
+		}
+		result := this.report(shape, node, value, component, "Value does not conform to "+this.data.describe(component))
+		if path, ok := solution["path"].(model.IRI); ok {
+			result.Path = path
+		}
+		for variable, value := range bindings {
+			if _, found := solution[variable]; !found {
+				solution[variable] = value
+			}
+		}
+		if message, ok := solution["message"]; ok {
+			result.Messages = []model.RDFTerm{message}
+		} else if len(shape.messages) == 0 && len(messages) > 0 {
+			result.Messages = this.fill(messages, solution)
+		}
+		ret = append(ret, result)
+	}
+	return ret, nil
+}
+
+var messageVariable = regexp.MustCompile(`\{[?$]([A-Za-z0-9_]+)\}`)
+
+// the messages with {?name} and {$name} replaced by the values of the
+// variables
+func (this *validator) fill(messages []model.RDFTerm, solution sparql.Solution) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	for _, message := range messages {
+		literal, ok := message.(model.Literal)
+		if !ok {
+			ret = append(ret, message)
+			continue
+		}
+		literal.Lexical = messageVariable.ReplaceAllStringFunc(literal.Lexical, func(match string) string {
+			value, ok := solution[sparql.Variable(match[2:len(match)-1])]
+			if !ok {
+				return match
+			}
+			if value, ok := value.(model.Literal); ok {
+				return value.Lexical
+			}
+			return this.data.describe(value)
+		})
+		ret = append(ret, literal)
+	}
+	return ret
+}
+
+// the SPARQL syntax of a path
+func pathSyntax(p path) string {
+	switch p := p.(type) {
+	case predicatePath:
+		return writer.FormatTerm(model.IRI(p), nil)
+	case inversePath:
+		return "^" + writer.FormatTerm(model.IRI(p), nil)
+	case sequencePath, alternativePath:
+		separator, steps := "/", []path(nil)
+		if alternatives, ok := p.(alternativePath); ok {
+			separator, steps = "|", alternatives
+		} else {
+			steps = p.(sequencePath)
+		}
+		parts := []string{}
+		for _, step := range steps {
+			parts = append(parts, pathSyntax(step))
+		}
+		return "(" + strings.Join(parts, separator) + ")"
+	}
+	repeat := p.(*repeatPath)
+	modifier := "?"
+	if repeat.many && repeat.zero {
+		modifier = "*"
+	} else if repeat.many {
+		modifier = "+"
+	}
+	return "(" + pathSyntax(repeat.path) + ")" + modifier
+}
+
+// the part of an IRI after its last # or /
+func localName(iri model.IRI) string {
+	return string(iri[strings.LastIndexAny(string(iri), "#/")+1:])
+}
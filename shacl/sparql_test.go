@@ -0,0 +1,94 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+const declarations = `:prefixes sh:declare [ sh:prefix "ex" ; sh:namespace "http://ex.org/"^^xsd:anyURI ] .
+`
+
+func TestSPARQLConstraints(t *testing.T) {
+	cases := []struct {
+		name     string
+		shapes   string
+		data     string
+		expected []string
+	}{
+		{"node shape", `[] sh:targetClass :Person ; sh:sparql [ sh:prefixes :prefixes ; sh:select """
+SELECT $this ?value WHERE { $this ex:born ?value . FILTER NOT EXISTS { $this ex:name ?name } }""" ] .`,
+			`:a a :Person ; :born 1990 ; :name "a" . :b a :Person ; :born 2000 .`, []string{
+				`<:b> SPARQLConstraintComponent "2000"^^<http://www.w3.org/2001/XMLSchema#integer>`}},
+		{"property shape", `[] sh:targetNode :a, :b ; sh:property [ sh:path ( :parent :name ) ; sh:sparql [ sh:prefixes :prefixes ; sh:select """
+SELECT $this ?value WHERE { $this $PATH ?value . FILTER (STRLEN(?value) < 2) }""" ] ] .`,
+			`:a :parent :c . :c :name "c" . :b :parent :d . :d :name "dd" .`, []string{
+				`<:a> SPARQLConstraintComponent "c"`}},
+		{"deactivated", `[] sh:targetNode :a ; sh:sparql [ sh:deactivated true ; sh:select "SELECT $this WHERE { }" ] .`,
+			`:a :p :b .`, []string{}},
+		{"ask component", `:MaxAgeComponent a sh:ConstraintComponent ; sh:parameter [ sh:path :maxAge ] ;
+  sh:validator [ sh:ask "ASK { FILTER (?value <= $maxAge) }" ; sh:message "{$value} is older than {$maxAge}" ] .
+[] sh:targetNode :a ; sh:property [ sh:path :age ; :maxAge 10 ] .`,
+			`:a :age 5, 12 .`, []string{
+				`<:a> :MaxAgeComponent "12"^^<http://www.w3.org/2001/XMLSchema#integer>`}},
+		{"select component", `:LinkComponent a sh:ConstraintComponent ; sh:parameter [ sh:path :linkedBy ] ; sh:parameter [ sh:path :except ; sh:optional true ] ;
+  sh:nodeValidator [ sh:select "SELECT $this WHERE { FILTER NOT EXISTS { ?x $linkedBy $this } }" ] ;
+  sh:propertyValidator [ sh:select "SELECT $this ?value WHERE { $this $PATH ?value . FILTER NOT EXISTS { ?x $linkedBy ?value } }" ] .
+[] sh:targetNode :a, :b ; :linkedBy :link ; sh:property [ sh:path :p ; :linkedBy :link ] .`,
+			`:x :link :a . :a :p :b, :c . :c :p :d . :y :link :c .`, []string{
+				`<:a> :LinkComponent <:b>`,
+				`<:b> :LinkComponent <:b>`}},
+	}
+	for _, c := range cases {
+		report := validate(t, declarations+c.shapes, c.data)
+		got := summary(report)
+		sort.Strings(c.expected)
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%s: results\n%s\ninstead of\n%s", c.name, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+		}
+	}
+
+	report := validate(t, `:MaxAgeComponent a sh:ConstraintComponent ; sh:parameter [ sh:path :maxAge ] ;
+  sh:validator [ sh:ask "ASK { FILTER (?value <= $maxAge) }" ; sh:message "{$value} is older than {$maxAge}" ] .
+[] sh:targetNode :a ; sh:property [ sh:path :age ; :maxAge 10 ] .
+:s sh:targetNode :a ; sh:sparql :constraint .
+:constraint sh:select "SELECT $this (<http://ex.org/age> AS ?path) (\"too old\" AS ?message) WHERE { $this <http://ex.org/age> 12 }" .`, `:a :age 12 .`)
+	messages := map[model.RDFTerm]model.RDFTerm{}
+	for _, result := range report.Results {
+		messages[result.Component] = result.Messages[0]
+		if result.Component == shSPARQLConstraintComponent && (result.Constraint != model.IRI("http://ex.org/constraint") || result.Path != model.IRI("http://ex.org/age")) {
+			t.Errorf("result %+v", result)
+		}
+	}
+	if messages[model.IRI("http://ex.org/MaxAgeComponent")] != model.NewStringLiteral("12 is older than 10") || messages[shSPARQLConstraintComponent] != model.NewStringLiteral("too old") {
+		t.Errorf("messages %v", messages)
+	}
+}
+
+func TestSPARQLErrors(t *testing.T) {
+	for shapes, message := range map[string]string{
+		`:s sh:targetNode :a ; sh:sparql [ sh:select "ASK { }" ] .`:                               "is not a SELECT query",
+		`:s sh:targetNode :a ; sh:sparql [ sh:select "SELECT $this WHERE { $this $PATH ?o }" ] .`: "uses $PATH in a node shape",
+		`:s sh:targetNode :a ; sh:sparql [ sh:select "SELECT WHERE" ] .`:                          "the query of",
+		`:s sh:targetNode :a ; sh:sparql [ ] .`:                                                   "has no <http://www.w3.org/ns/shacl#select> string",
+	} {
+		_, err := Parse(strings.NewReader(prefixes+shapes), parser.Options{})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", shapes, err, message)
+		}
+	}
+	parsed, err := Parse(strings.NewReader(prefixes+`:s sh:targetNode :a ; sh:sparql [ sh:select "SELECT $this ?failure WHERE { BIND (true AS ?failure) }" ] .`), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parsed.Validate(data(t, ``)); err == nil || !strings.Contains(err.Error(), "failed for <http://ex.org/a>") {
+		t.Errorf("error %v", err)
+	}
+}
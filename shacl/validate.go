@@ -0,0 +1,139 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// Validate validates a data graph against the shapes with targets
+func (this *Shapes) Validate(data store.Store) (*Report, error) {
+	validator := &validator{data: graph{store: data, labels: this.graph.labels}, active: make(map[[2]model.RDFTerm]struct{})}
+	for _, shape := range this.targeted {
+		if shape.deactivated {
+			continue
+		}
+		focusNodes, err := validator.focusNodes(shape)
+		if err != nil {
+			return nil, err
+		}
+		for _, focus := range focusNodes {
+			if err := validator.validate(shape, focus); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Report{Conforms: len(validator.results) == 0, Results: validator.results, shapes: this.graph}, nil
+}
+
+type validator struct {
+	data    graph
+	results []*Result
+	// the shapes being validated, with their focus nodes. A shape referring
+	// to itself through the same focus node does not validate it again.
+	active map[[2]model.RDFTerm]struct{}
+}
+
+// the distinct focus nodes of the targets of a shape
+func (this *validator) focusNodes(shape *shape) ([]model.RDFTerm, error) {
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	for _, target := range shape.targets {
+		var nodes []model.RDFTerm
+		var err error
+		switch target.predicate {
+		case shTargetNode:
+			nodes = []model.RDFTerm{target.object}
+		case shTargetClass:
+			nodes, err = this.data.instances(target.object)
+		case shTargetSubjectsOf:
+			nodes, err = this.data.subjects(target.object, nil)
+		case shTargetObjectsOf:
+			nodes, err = this.objectsOf(target.object)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes {
+			if _, found := seen[node]; !found {
+				seen[node] = struct{}{}
+				ret = append(ret, node)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// the distinct objects of a predicate
+func (this *validator) objectsOf(predicate model.RDFTerm) ([]model.RDFTerm, error) {
+	iterator := this.data.store.Match(nil, predicate, nil, nil)
+	defer iterator.Close()
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]struct{}{}
+	for iterator.Next() {
+		object := iterator.Statement().Object
+		if _, found := seen[object]; !found {
+			seen[object] = struct{}{}
+			ret = append(ret, object)
+		}
+	}
+	return ret, iterator.Err()
+}
+
+// validates a focus node against a shape, adding the results
+func (this *validator) validate(shape *shape, focus model.RDFTerm) error {
+	if shape.deactivated {
+		return nil
+	}
+	key := [2]model.RDFTerm{shape.node, focus}
+	if _, found := this.active[key]; found {
+		return nil
+	}
+	this.active[key] = struct{}{}
+	defer delete(this.active, key)
+	values := []model.RDFTerm{focus}
+	if shape.path != nil {
+		var err error
+		if values, err = shape.path.values(this.data, focus); err != nil {
+			return err
+		}
+	}
+	for _, constraint := range shape.constraints {
+		if err := constraint(this, shape, focus, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// true when a node conforms to a shape, which is when validating it has no
+// results. The results are not kept.
+func (this *validator) conforms(shape *shape, node model.RDFTerm) (bool, error) {
+	count := len(this.results)
+	err := this.validate(shape, node)
+	conforms := len(this.results) == count
+	this.results = this.results[:count]
+	return conforms, err
+}
+
+// adds a result of a shape, with its messages or the message of the
+// component
+func (this *validator) report(shape *shape, focus, value model.RDFTerm, component model.IRI, message string) *Result {
+	ret := &Result{
+		Focus:     focus,
+		Path:      shape.pathNode,
+		Value:     value,
+		Shape:     shape.node,
+		Component: component,
+		Severity:  shape.severity,
+		Messages:  shape.messages,
+	}
+	if len(ret.Messages) == 0 {
+		ret.Messages = []model.RDFTerm{model.NewStringLiteral(message)}
+	}
+	this.results = append(this.results, ret)
+	return ret
+}
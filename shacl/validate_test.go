@@ -0,0 +1,228 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+const prefixes = `@prefix sh: <http://www.w3.org/ns/shacl#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix : <http://ex.org/> .
+`
+
+func data(t *testing.T, text string) store.Store {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(prefixes+text), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := store.NewMemory()
+	for _, statement := range statements {
+		ret.Add(statement)
+	}
+	return ret
+}
+
+func validate(t *testing.T, shapes, text string) *Report {
+	t.Helper()
+	parsed, err := Parse(strings.NewReader(prefixes+shapes), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := parsed.Validate(data(t, text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+// the focus node, component and value of the results, sorted
+func summary(report *Report) []string {
+	labels := writer.NewBlankNodeLabels()
+	format := func(term model.RDFTerm) string {
+		return writer.FormatTerm(term, labels)
+	}
+	ret := []string{}
+	for _, result := range report.Results {
+		line := format(result.Focus) + " " + strings.TrimPrefix(string(result.Component), string(SH))
+		if result.Value != nil {
+			line += " " + format(result.Value)
+		}
+		ret = append(ret, strings.ReplaceAll(line, "http://ex.org/", ":"))
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		shapes   string
+		data     string
+		expected []string
+	}{
+		{"cardinality and datatype", `:Person a sh:NodeShape ; sh:targetClass :Person ;
+  sh:property [ sh:path :name ; sh:minCount 1 ; sh:maxCount 1 ; sh:datatype xsd:string ] ;
+  sh:property [ sh:path :age ; sh:datatype xsd:integer ; sh:minInclusive 0 ; sh:maxExclusive 150 ] .`,
+			`:a a :Person ; :name "A" ; :age 30 .
+:b a :Person ; :name "B", "Bee" ; :age "old" .
+:c a :Person ; :age 200, -1 .
+:d a :Person ; :name 4 ; :age "12"^^xsd:integer, "1x"^^xsd:integer .`, []string{
+				`<:b> MaxCountConstraintComponent`,
+				`<:b> DatatypeConstraintComponent "old"`,
+				`<:b> MinInclusiveConstraintComponent "old"`,
+				`<:b> MaxExclusiveConstraintComponent "old"`,
+				`<:c> MinCountConstraintComponent`,
+				`<:c> MinInclusiveConstraintComponent "-1"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:c> MaxExclusiveConstraintComponent "200"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:d> DatatypeConstraintComponent "4"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:d> DatatypeConstraintComponent "1x"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:d> MinInclusiveConstraintComponent "1x"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:d> MaxExclusiveConstraintComponent "1x"^^<http://www.w3.org/2001/XMLSchema#integer>`}},
+		{"ill-typed dates", `[] sh:targetSubjectsOf :born ; sh:property [ sh:path :born ; sh:datatype xsd:date ] .`,
+			`:a :born "2024-02-29"^^xsd:date . :b :born "2023-02-29"^^xsd:date .`, []string{
+				`<:b> DatatypeConstraintComponent "2023-02-29"^^<http://www.w3.org/2001/XMLSchema#date>`}},
+		{"subclasses and node kinds", `[] sh:targetClass :Animal ; sh:nodeKind sh:IRI ; sh:property [ sh:path :owner ; sh:class :Person ] .`,
+			`:Dog rdfs:subClassOf :Animal . :Student rdfs:subClassOf :Person .
+:rex a :Dog ; :owner :a . :a a :Student .
+[ a :Animal ; :owner :rex ] .`, []string{
+				`_:b0 NodeKindConstraintComponent _:b0`,
+				`_:b0 ClassConstraintComponent <:rex>`}},
+		{"strings", `[] sh:targetSubjectsOf :code ; sh:property [ sh:path :code ; sh:pattern "^[a-z]+$" ; sh:flags "i" ; sh:minLength 2 ; sh:maxLength 3 ] ;
+  sh:property [ sh:path :label ; sh:languageIn ( "en" "fr" ) ; sh:uniqueLang true ] .`,
+			`:a :code "ab" ; :label "a"@en-GB, "b"@fr .
+:b :code "A1", "abcd", "Q" ; :label "x"@EN, "y"@en, "z"@de, "w" .`, []string{
+				`<:b> MinLengthConstraintComponent "Q"`,
+				`<:b> MaxLengthConstraintComponent "abcd"`,
+				`<:b> PatternConstraintComponent "A1"`,
+				`<:b> LanguageInConstraintComponent "z"@de`,
+				`<:b> LanguageInConstraintComponent "w"`,
+				`<:b> UniqueLangConstraintComponent`}},
+		{"property pairs", `[] sh:targetNode :a ; sh:property [ sh:path :start ; sh:lessThan :end ; sh:disjoint :end ; sh:equals :begin ] .`,
+			`:a :start 1, 5 ; :end 3 ; :begin 1, 2 .`, []string{
+				`<:a> EqualsConstraintComponent "5"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:a> EqualsConstraintComponent "2"^^<http://www.w3.org/2001/XMLSchema#integer>`,
+				`<:a> LessThanConstraintComponent "5"^^<http://www.w3.org/2001/XMLSchema#integer>`}},
+		{"logical", `:Named sh:property [ sh:path :name ; sh:minCount 1 ] .
+:Labelled sh:property [ sh:path :label ; sh:minCount 1 ] .
+[] sh:targetObjectsOf :knows ; sh:or ( :Named :Labelled ) ; sh:not [ sh:in ( :x ) ] ; sh:xone ( :Named :Labelled ) .`,
+			`:a :knows :b, :c, :d, :x . :b :name "b" . :c :name "c" ; :label "c" . :x :label "x" .`, []string{
+				`<:c> XoneConstraintComponent <:c>`,
+				`<:d> OrConstraintComponent <:d>`,
+				`<:d> XoneConstraintComponent <:d>`,
+				`<:x> NotConstraintComponent <:x>`}},
+		{"paths", `[] sh:targetNode :a ; sh:property [ sh:path ( :parent [ sh:oneOrMorePath :parent ] ) ; sh:hasValue :c ; sh:in ( :c :d ) ] ;
+  sh:property [ sh:path [ sh:inversePath ( :parent :parent ) ] ; sh:maxCount 0 ] .`,
+			`:a :parent :b . :b :parent :c . :c :parent :e . :z :parent :y . :y :parent :a .`, []string{
+				`<:a> InConstraintComponent <:e>`,
+				`<:a> MaxCountConstraintComponent`}},
+		{"recursive and qualified", `:Person a rdfs:Class ; sh:property [ sh:path :friend ; sh:node :Person ] ;
+  sh:property [ sh:path :name ; sh:minCount 1 ] ;
+  sh:property [ sh:path :parent ; sh:qualifiedValueShape [ sh:class :Man ] ; sh:qualifiedMinCount 1 ; sh:qualifiedValueShapesDisjoint true ] ;
+  sh:property [ sh:path :parent ; sh:qualifiedValueShape [ sh:class :Parent ] ; sh:qualifiedMaxCount 1 ; sh:qualifiedValueShapesDisjoint true ] .`,
+			`:a a :Person ; :name "a" ; :friend :b ; :parent :m, :f .
+:b a :Person ; :friend :a ; :friend :c ; :parent :m .
+:c :name "c" .
+:m a :Parent . :f a :Man, :Parent .`, []string{
+				// :f is a :Man and a :Parent, it counts for neither
+				`<:b> NodeConstraintComponent <:c>`,
+				`<:b> NodeConstraintComponent <:a>`,
+				`<:b> MinCountConstraintComponent`,
+				`<:b> QualifiedMinCountConstraintComponent`,
+				`<:a> NodeConstraintComponent <:b>`,
+				`<:a> QualifiedMinCountConstraintComponent`}},
+		{"closed", `[] sh:targetNode :a ; sh:closed true ; sh:ignoredProperties ( rdf:type ) ; sh:property [ sh:path :name ] .
+[] sh:targetNode :a ; sh:deactivated true ; sh:property [ sh:path :name ; sh:minCount 5 ] .`,
+			`:a a :Thing ; :name "a" ; :age 3 .`, []string{
+				`<:a> ClosedConstraintComponent "3"^^<http://www.w3.org/2001/XMLSchema#integer>`}},
+	}
+	for _, c := range cases {
+		shapes := strings.ReplaceAll(c.shapes, "rdf:type", "<http://www.w3.org/1999/02/22-rdf-syntax-ns#type>")
+		report := validate(t, shapes, c.data)
+		got := summary(report)
+		sort.Strings(c.expected)
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%s: results\n%s\ninstead of\n%s", c.name, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+		}
+		if report.Conforms != (len(c.expected) == 0) {
+			t.Errorf("%s: conforms %v", c.name, report.Conforms)
+		}
+	}
+}
+
+func TestShapesErrors(t *testing.T) {
+	for shapes, message := range map[string]string{
+		`:s sh:targetNode :a ; sh:minCount "one" .`:                          "shape <http://ex.org/s>: <http://www.w3.org/ns/shacl#minCount> \"one\": not an xsd:integer",
+		`:s sh:targetNode :a ; sh:path ( :p ) .`:                             "shape <http://ex.org/s>: the sequence path",
+		`:s sh:targetNode :a ; sh:pattern "(" .`:                             "shape <http://ex.org/s>: <http://www.w3.org/ns/shacl#pattern> \"(\": error parsing regexp",
+		`:s sh:targetNode :a ; sh:property [ sh:minCount 1 ] .`:              "not a property shape",
+		`:s sh:targetNode :a ; sh:severity sh:Warning, sh:Info .`:            "shape <http://ex.org/s>: the severity is not a single IRI",
+		`:s sh:targetNode :a ; sh:in ( :a :b ) . _:l :x :y . :t sh:in _:l .`: "is not a well-formed list",
+	} {
+		_, err := Parse(strings.NewReader(prefixes+shapes), parser.Options{})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", shapes, err, message)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	report := validate(t, `:s sh:targetNode :a ; sh:property [ sh:path [ sh:inversePath :p ] ; sh:minCount 1 ; sh:severity sh:Warning ; sh:message "no p"@en ] .`, `:b :p :c .`)
+	statements, err := report.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var builder strings.Builder
+	options := writer.Options{Namespaces: []model.Namespace{{Prefix: "sh", IRI: SH}, {Prefix: "", IRI: "http://ex.org/"}}}
+	if err := writer.WriteAll(writer.NewTurtleWriter(&builder, options), statements); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"a sh:ValidationReport",
+		"sh:conforms false",
+		"sh:focusNode :a",
+		"sh:resultPath [ sh:inversePath :p ]",
+		"sh:sourceConstraintComponent sh:MinCountConstraintComponent",
+		"sh:resultSeverity sh:Warning",
+		`sh:resultMessage "no p"@en`,
+	} {
+		if !strings.Contains(builder.String(), expected) {
+			t.Errorf("the report has no %q:\n%s", expected, builder.String())
+		}
+	}
+	// each result has its own copy of a shared path, which is nested
+	report = validate(t, `:s sh:targetNode :a, :b ; sh:property [ sh:path ( :p :q ) ; sh:minCount 1 ] .`, ``)
+	statements, err = report.Statements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder.Reset()
+	if err := writer.WriteAll(writer.NewTurtleWriter(&builder, options), statements); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(builder.String(), "sh:resultPath ( :p :q )"); got != 2 {
+		t.Errorf("%d nested paths:\n%s", got, builder.String())
+	}
+	// the blank nodes of the messages are not labelled
+	report = validate(t, `:s sh:targetNode :a ; sh:or ( [ sh:class :C ] [ sh:class :D ] ) .`, ``)
+	if len(report.Results) != 1 || len(report.Results[0].Messages) != 1 {
+		t.Fatalf("results %v", summary(report))
+	}
+	if message := report.Results[0].Messages[0].(model.Literal).Lexical; !strings.HasSuffix(message, "([] [])") {
+		t.Errorf("message %q", message)
+	}
+	if report := validate(t, `:s sh:targetNode :a ; sh:class :C .`, `:a a :C .`); !report.Conforms {
+		t.Errorf("results %v", summary(report))
+	}
+}
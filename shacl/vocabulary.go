@@ -0,0 +1,119 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shacl
+
+import "github.com/nfreundl/rdf-tools/model"
+
+const SH model.IRI = "http://www.w3.org/ns/shacl#"
+
+// the severities of the results
+const (
+	Violation model.IRI = SH + "Violation"
+	Warning   model.IRI = SH + "Warning"
+	Info      model.IRI = SH + "Info"
+)
+
+const (
+	shNodeShape     = SH + "NodeShape"
+	shPropertyShape = SH + "PropertyShape"
+
+	shTargetNode       = SH + "targetNode"
+	shTargetClass      = SH + "targetClass"
+	shTargetSubjectsOf = SH + "targetSubjectsOf"
+	shTargetObjectsOf  = SH + "targetObjectsOf"
+
+	shPath            = SH + "path"
+	shInversePath     = SH + "inversePath"
+	shAlternativePath = SH + "alternativePath"
+	shZeroOrMorePath  = SH + "zeroOrMorePath"
+	shOneOrMorePath   = SH + "oneOrMorePath"
+	shZeroOrOnePath   = SH + "zeroOrOnePath"
+
+	shDeactivated = SH + "deactivated"
+	shSeverity    = SH + "severity"
+	shMessage     = SH + "message"
+
+	shClass                        = SH + "class"
+	shDatatype                     = SH + "datatype"
+	shNodeKind                     = SH + "nodeKind"
+	shMinCount                     = SH + "minCount"
+	shMaxCount                     = SH + "maxCount"
+	shMinExclusive                 = SH + "minExclusive"
+	shMinInclusive                 = SH + "minInclusive"
+	shMaxExclusive                 = SH + "maxExclusive"
+	shMaxInclusive                 = SH + "maxInclusive"
+	shMinLength                    = SH + "minLength"
+	shMaxLength                    = SH + "maxLength"
+	shPattern                      = SH + "pattern"
+	shFlags                        = SH + "flags"
+	shLanguageIn                   = SH + "languageIn"
+	shUniqueLang                   = SH + "uniqueLang"
+	shEquals                       = SH + "equals"
+	shDisjoint                     = SH + "disjoint"
+	shLessThan                     = SH + "lessThan"
+	shLessThanOrEquals             = SH + "lessThanOrEquals"
+	shNot                          = SH + "not"
+	shAnd                          = SH + "and"
+	shOr                           = SH + "or"
+	shXone                         = SH + "xone"
+	shNode                         = SH + "node"
+	shProperty                     = SH + "property"
+	shQualifiedValueShape          = SH + "qualifiedValueShape"
+	shQualifiedMinCount            = SH + "qualifiedMinCount"
+	shQualifiedMaxCount            = SH + "qualifiedMaxCount"
+	shQualifiedValueShapesDisjoint = SH + "qualifiedValueShapesDisjoint"
+	shClosed                       = SH + "closed"
+	shIgnoredProperties            = SH + "ignoredProperties"
+	shHasValue                     = SH + "hasValue"
+	shIn                           = SH + "in"
+
+	// SHACL-SPARQL
+	shSPARQL                    = SH + "sparql"
+	shSelect                    = SH + "select"
+	shAsk                       = SH + "ask"
+	shPrefixes                  = SH + "prefixes"
+	shDeclare                   = SH + "declare"
+	shPrefix                    = SH + "prefix"
+	shNamespace                 = SH + "namespace"
+	shParameter                 = SH + "parameter"
+	shOptional                  = SH + "optional"
+	shValidator                 = SH + "validator"
+	shNodeValidator             = SH + "nodeValidator"
+	shPropertyValidator         = SH + "propertyValidator"
+	shConstraintComponent       = SH + "ConstraintComponent"
+	shSPARQLConstraintComponent = SH + "SPARQLConstraintComponent"
+
+	// SHACL rules
+	shRule      = SH + "rule"
+	shConstruct = SH + "construct"
+	shSubject   = SH + "subject"
+	shPredicate = SH + "predicate"
+	shObject    = SH + "object"
+	shCondition = SH + "condition"
+	shOrder     = SH + "order"
+	shThis      = SH + "this"
+
+	// the values of sh:nodeKind
+	shIRI                = SH + "IRI"
+	shBlankNode          = SH + "BlankNode"
+	shLiteral            = SH + "Literal"
+	shBlankNodeOrIRI     = SH + "BlankNodeOrIRI"
+	shBlankNodeOrLiteral = SH + "BlankNodeOrLiteral"
+	shIRIOrLiteral       = SH + "IRIOrLiteral"
+
+	// the report
+	shValidationReport          = SH + "ValidationReport"
+	shValidationResult          = SH + "ValidationResult"
+	shConforms                  = SH + "conforms"
+	shResult                    = SH + "result"
+	shFocusNode                 = SH + "focusNode"
+	shResultPath                = SH + "resultPath"
+	shValue                     = SH + "value"
+	shSourceShape               = SH + "sourceShape"
+	shSourceConstraint          = SH + "sourceConstraint"
+	shSourceConstraintComponent = SH + "sourceConstraintComponent"
+	shResultSeverity            = SH + "resultSeverity"
+	shResultMessage             = SH + "resultMessage"
+)
@@ -0,0 +1,573 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+type tokenType int
+
+const (
+	tEOF tokenType = iota
+	tIRI
+	tPNameNS
+	tPNameLN
+	// @prefix: and @prefix:local, their values come without the @
+	tATPNameNS
+	tATPNameLN
+	tBlankNode
+	tLangTag
+	tString
+	tInteger
+	tDecimal
+	tDouble
+	// keywords, 'a', true and false as written
+	tName
+	tPunctuation
+	// /pattern/flags, the value is the pattern
+	tRegexp
+	// {m,n}, the value as written
+	tRepeat
+)
+
+var tokenTypeNames = map[tokenType]string{
+	tEOF: "end of input", tIRI: "IRI", tPNameNS: "prefix", tPNameLN: "prefixed name",
+	tATPNameNS: "shape reference", tATPNameLN: "shape reference", tBlankNode: "blank node label",
+	tLangTag: "language tag", tString: "string", tInteger: "integer", tDecimal: "decimal",
+	tDouble: "double", tName: "name", tPunctuation: "punctuation", tRegexp: "regular expression",
+	tRepeat: "repetition",
+}
+
+type token struct {
+	tokenType tokenType
+	value     string
+	// the flags of regular expressions
+	flags     string
+	line, col int
+}
+
+func (this *token) String() string {
+	if this.tokenType == tEOF {
+		return tokenTypeNames[tEOF]
+	}
+	if this.tokenType == tName || this.tokenType == tPunctuation {
+		return fmt.Sprintf("%q", this.value)
+	}
+	return fmt.Sprintf("%s %q", tokenTypeNames[this.tokenType], this.value)
+}
+
+// the lexer of ShExC and of the shape maps, tokens are read on demand so
+// that the parser can read code blocks itself
+type lexer struct {
+	input     []rune
+	position  int
+	line, col int
+}
+
+var repeatPattern = regexp.MustCompile(`^\{[0-9]+(,([0-9]+|\*)?)?\}`)
+
+var langTagPattern = regexp.MustCompile(`^[a-zA-Z]+(-[a-zA-Z0-9]+)*$`)
+
+func newLexer(text string) *lexer {
+	return &lexer{input: []rune(text), line: 1, col: 1}
+}
+
+const eof = rune(-1)
+
+func (this *lexer) peek(offset int) rune {
+	if this.position+offset >= len(this.input) {
+		return eof
+	}
+	return this.input[this.position+offset]
+}
+
+func (this *lexer) next() rune {
+	ret := this.peek(0)
+	if ret == eof {
+		return eof
+	}
+	this.position++
+	if ret == '\n' {
+		this.line++
+		this.col = 1
+	} else {
+		this.col++
+	}
+	return ret
+}
+
+func (this *lexer) errorf(format string, args ...interface{}) error {
+	return &parser.SyntaxError{Line: this.line, Col: this.col, Message: fmt.Sprintf(format, args...)}
+}
+
+// skips white spaces and comments
+func (this *lexer) skip() error {
+	for {
+		val := this.peek(0)
+		switch {
+		case parser.WS.Contains(val):
+			this.next()
+		case val == '#':
+			for val != '\n' && val != eof {
+				val = this.next()
+			}
+		case val == '/' && this.peek(1) == '*':
+			this.next()
+			this.next()
+			for !(this.peek(0) == '*' && this.peek(1) == '/') {
+				if this.next() == eof {
+					return this.errorf("unterminated comment")
+				}
+			}
+			this.next()
+			this.next()
+		default:
+			return nil
+		}
+	}
+}
+
+func (this *lexer) token() (*token, error) {
+	if err := this.skip(); err != nil {
+		return nil, err
+	}
+	ret, err := this.read()
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (this *lexer) read() (*token, error) {
+	line, col := this.line, this.col
+	ret := &token{line: line, col: col}
+	val := this.peek(0)
+	switch {
+	case val == eof:
+		ret.tokenType = tEOF
+		return ret, nil
+	case val == '<':
+		this.next()
+		value, err := this.readIRI()
+		ret.tokenType, ret.value = tIRI, value
+		return ret, err
+	case val == '"' || val == '\'':
+		this.next()
+		value, err := this.readString(val)
+		ret.tokenType, ret.value = tString, value
+		return ret, err
+	case val == '_' && this.peek(1) == ':':
+		this.next()
+		this.next()
+		if !parser.BLANK_NODE_LABEL_START.Contains(this.peek(0)) {
+			return nil, this.errorf("invalid blank node label")
+		}
+		ret.tokenType, ret.value = tBlankNode, this.readChars(parser.PN_CHARS, true)
+		return ret, nil
+	case val == '@':
+		this.next()
+		return this.readAt(ret)
+	case val == '/' && this.peek(1) == '/':
+		this.next()
+		this.next()
+		ret.tokenType, ret.value = tPunctuation, "//"
+		return ret, nil
+	case val == '/':
+		this.next()
+		return this.readRegexp(ret)
+	case val == '{':
+		if match := repeatPattern.FindString(string(this.input[this.position:minimum(len(this.input), this.position+64)])); match != "" {
+			for range match {
+				this.next()
+			}
+			ret.tokenType, ret.value = tRepeat, match
+			return ret, nil
+		}
+	case this.numberAhead():
+		return this.readNumber(ret)
+	case val == ':' || parser.PN_CHARS_BASE.Contains(val):
+		return this.readName(ret)
+	}
+	this.next()
+	if strings.ContainsRune("{}()[]|;,.=*+?^&$%~-", val) {
+		ret.tokenType, ret.value = tPunctuation, string(val)
+		return ret, nil
+	}
+	return nil, &parser.SyntaxError{Line: line, Col: col, Message: fmt.Sprintf("unexpected character %q", val)}
+}
+
+func minimum(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// '<' has been read
+func (this *lexer) readIRI() (string, error) {
+	var ret strings.Builder
+	for {
+		val := this.next()
+		switch {
+		case val == '>':
+			return ret.String(), nil
+		case val == '\\':
+			escaped, err := this.readUchar()
+			if err != nil {
+				return "", err
+			}
+			ret.WriteRune(escaped)
+		case val == eof || val == '\n':
+			return "", this.errorf("unterminated IRI")
+		case val <= 0x20 || strings.ContainsRune("<\"{}|^`", val):
+			return "", this.errorf("invalid character %q in IRI", val)
+		default:
+			ret.WriteRune(val)
+		}
+	}
+}
+
+// '\' has been read
+func (this *lexer) readUchar() (rune, error) {
+	length := 0
+	switch this.next() {
+	case 'u':
+		length = 4
+	case 'U':
+		length = 8
+	default:
+		return 0, this.errorf("invalid escape sequence")
+	}
+	var ret rune
+	for i := 0; i < length; i++ {
+		val := this.next()
+		if !parser.HEX.Contains(val) {
+			return 0, this.errorf("invalid escape sequence")
+		}
+		ret = ret<<4 | hexValue(val)
+	}
+	return ret, nil
+}
+
+func hexValue(val rune) rune {
+	switch {
+	case val >= '0' && val <= '9':
+		return val - '0'
+	case val >= 'a' && val <= 'f':
+		return val - 'a' + 10
+	default:
+		return val - 'A' + 10
+	}
+}
+
+// the opening quote has been read
+func (this *lexer) readString(quote rune) (string, error) {
+	long := false
+	if this.peek(0) == quote && this.peek(1) == quote {
+		this.next()
+		this.next()
+		long = true
+	}
+	var ret strings.Builder
+	for {
+		val := this.next()
+		switch {
+		case val == quote && !long:
+			return ret.String(), nil
+		case val == quote && this.peek(0) == quote && this.peek(1) == quote:
+			this.next()
+			this.next()
+			// the quotes before the last three are part of the string
+			for this.peek(0) == quote {
+				ret.WriteRune(this.next())
+			}
+			return ret.String(), nil
+		case val == '\\':
+			escaped, err := this.readEscape()
+			if err != nil {
+				return "", err
+			}
+			ret.WriteRune(escaped)
+		case val == eof || !long && (val == '\n' || val == '\r'):
+			return "", this.errorf("unterminated string")
+		default:
+			ret.WriteRune(val)
+		}
+	}
+}
+
+// '\' has been read, either an ECHAR or an UCHAR
+func (this *lexer) readEscape() (rune, error) {
+	switch val := this.peek(0); val {
+	case 't':
+		this.next()
+		return '\t', nil
+	case 'b':
+		this.next()
+		return '\b', nil
+	case 'n':
+		this.next()
+		return '\n', nil
+	case 'r':
+		this.next()
+		return '\r', nil
+	case 'f':
+		this.next()
+		return '\f', nil
+	case '"', '\'', '\\':
+		return this.next(), nil
+	}
+	return this.readUchar()
+}
+
+// reads runes of chars, and '.' when followed by one of chars. PLX escapes
+// are accepted in local names.
+func (this *lexer) readChars(chars *parser.RuneSet, first bool) string {
+	var ret strings.Builder
+	if first {
+		ret.WriteRune(this.next())
+	}
+	for {
+		val := this.peek(0)
+		offset := 0
+		for val == '.' {
+			offset++
+			val = this.peek(offset)
+		}
+		if !chars.Contains(val) {
+			return ret.String()
+		}
+		for ; offset >= 0; offset-- {
+			ret.WriteRune(this.next())
+		}
+	}
+}
+
+// reads keywords and prefixed names, val is ':' or in PN_CHARS_BASE
+func (this *lexer) readName(ret *token) (*token, error) {
+	prefix := ""
+	if this.peek(0) != ':' {
+		prefix = this.readChars(parser.PN_CHARS, true)
+		if this.peek(0) != ':' {
+			ret.tokenType, ret.value = tName, prefix
+			return ret, nil
+		}
+	}
+	this.next()
+	local, err := this.readLocal()
+	if err != nil {
+		return nil, err
+	}
+	ret.value = prefix + ":" + local
+	ret.tokenType = tPNameLN
+	if local == "" {
+		ret.tokenType = tPNameNS
+	}
+	return ret, nil
+}
+
+// the local part of a prefixed name, empty when there is none
+func (this *lexer) readLocal() (string, error) {
+	var ret strings.Builder
+	val := this.peek(0)
+	if !parser.PN_LOCAL_START.Contains(val) && val != '\\' && val != '%' {
+		return "", nil
+	}
+	for {
+		val = this.peek(0)
+		offset := 0
+		for val == '.' {
+			offset++
+			val = this.peek(offset)
+		}
+		if !parser.PN_LOCAL_CHARS.Contains(val) && val != '\\' && val != '%' {
+			return ret.String(), nil
+		}
+		for ; offset > 0; offset-- {
+			ret.WriteRune(this.next())
+		}
+		switch val = this.next(); val {
+		case '\\':
+			if !parser.PN_LOCAL_ESC.Contains(this.peek(0)) {
+				return "", this.errorf("invalid escape sequence in local name")
+			}
+			ret.WriteRune(this.next())
+		case '%':
+			ret.WriteRune(val)
+			for i := 0; i < 2; i++ {
+				if !parser.HEX.Contains(this.peek(0)) {
+					return "", this.errorf("invalid percent encoding in local name")
+				}
+				ret.WriteRune(this.next())
+			}
+		default:
+			ret.WriteRune(val)
+		}
+	}
+}
+
+// '@' has been read: a language tag, a prefixed shape reference or '@'
+// alone
+func (this *lexer) readAt(ret *token) (*token, error) {
+	val := this.peek(0)
+	if val != ':' && !parser.PN_CHARS_BASE.Contains(val) {
+		ret.tokenType, ret.value = tPunctuation, "@"
+		return ret, nil
+	}
+	name, err := this.readName(ret)
+	if err != nil {
+		return nil, err
+	}
+	switch name.tokenType {
+	case tPNameNS:
+		name.tokenType = tATPNameNS
+	case tPNameLN:
+		name.tokenType = tATPNameLN
+	default:
+		if !langTagPattern.MatchString(name.value) {
+			return nil, this.errorf("invalid language tag %q", name.value)
+		}
+		name.tokenType = tLangTag
+	}
+	return name, nil
+}
+
+func isDigit(val rune) bool {
+	return val >= '0' && val <= '9'
+}
+
+// whether a number starts here, signs and dots are punctuation otherwise
+func (this *lexer) numberAhead() bool {
+	offset := 0
+	if val := this.peek(0); val == '+' || val == '-' {
+		offset++
+	}
+	if this.peek(offset) == '.' {
+		offset++
+	}
+	return isDigit(this.peek(offset))
+}
+
+// INTEGER, DECIMAL and DOUBLE
+func (this *lexer) readNumber(ret *token) (*token, error) {
+	var value strings.Builder
+	if val := this.peek(0); val == '+' || val == '-' {
+		value.WriteRune(this.next())
+	}
+	digits := func() int {
+		n := 0
+		for isDigit(this.peek(0)) {
+			value.WriteRune(this.next())
+			n++
+		}
+		return n
+	}
+	ret.tokenType = tInteger
+	n := digits()
+	if this.peek(0) == '.' && isDigit(this.peek(1)) {
+		value.WriteRune(this.next())
+		n += digits()
+		ret.tokenType = tDecimal
+	}
+	if val := this.peek(0); (val == 'e' || val == 'E') && n > 0 {
+		value.WriteRune(this.next())
+		if val := this.peek(0); val == '+' || val == '-' {
+			value.WriteRune(this.next())
+		}
+		if digits() == 0 {
+			return nil, this.errorf("invalid exponent")
+		}
+		ret.tokenType = tDouble
+	}
+	if n == 0 {
+		return nil, this.errorf("invalid number")
+	}
+	ret.value = value.String()
+	return ret, nil
+}
+
+// '/' has been read. Escaped slashes are unescaped and UCHAR decoded, the
+// other escapes are the ones of the regular expressions.
+func (this *lexer) readRegexp(ret *token) (*token, error) {
+	var pattern strings.Builder
+	for {
+		val := this.next()
+		switch val {
+		case '/':
+			if pattern.Len() == 0 {
+				return nil, this.errorf("empty regular expression")
+			}
+			ret.tokenType, ret.value = tRegexp, pattern.String()
+			var flags strings.Builder
+			for strings.ContainsRune("smix", this.peek(0)) && this.peek(0) != eof {
+				flags.WriteRune(this.next())
+			}
+			ret.flags = flags.String()
+			return ret, nil
+		case '\\':
+			switch escaped := this.peek(0); escaped {
+			case '/':
+				pattern.WriteRune(this.next())
+			case 'u', 'U':
+				r, err := this.readUchar()
+				if err != nil {
+					return nil, err
+				}
+				pattern.WriteString(regexp.QuoteMeta(string(r)))
+			default:
+				if !strings.ContainsRune(`nrt\|.?*+(){}$-[]^`, escaped) {
+					return nil, this.errorf("invalid escape sequence in regular expression")
+				}
+				pattern.WriteRune('\\')
+				pattern.WriteRune(this.next())
+			}
+		case '\n', '\r', eof:
+			return nil, this.errorf("unterminated regular expression")
+		default:
+			pattern.WriteRune(val)
+		}
+	}
+}
+
+// reads the code of a semantic action after its IRI, '%' alone or
+// '{' code '%}'
+func (this *lexer) code() (string, error) {
+	if err := this.skip(); err != nil {
+		return "", err
+	}
+	switch this.next() {
+	case '%':
+		return "", nil
+	case '{':
+	default:
+		return "", this.errorf("expected code or '%%'")
+	}
+	var ret strings.Builder
+	for {
+		val := this.next()
+		switch {
+		case val == '%' && this.peek(0) == '}':
+			this.next()
+			return ret.String(), nil
+		case val == '\\' && (this.peek(0) == '%' || this.peek(0) == '\\'):
+			ret.WriteRune(this.next())
+		case val == '\\':
+			r, err := this.readUchar()
+			if err != nil {
+				return "", err
+			}
+			ret.WriteRune(r)
+		case val == eof:
+			return "", this.errorf("unterminated code")
+		default:
+			ret.WriteRune(val)
+		}
+	}
+}
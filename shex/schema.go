@@ -0,0 +1,484 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package shex reads Shape Expressions (ShEx 2.1) schemas, written in the
+// compact syntax ShExC or in the JSON syntax ShExJ, and validates the nodes
+// of a fixed shape map against the data of a store.
+//
+// The schema types follow the abstract syntax of ShExJ. Imports and
+// external shapes are not supported, semantic actions and annotations are
+// read and ignored.
+package shex
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Unbounded is the maximum of the cardinalities without one
+const Unbounded = -1
+
+// Schema is a ShEx schema
+type Schema struct {
+	// the shape expression of START, nil when there is none
+	Start  ShapeExpr
+	Shapes []*ShapeDecl
+	// the prefixes declared in ShExC, in their order
+	Namespaces []model.Namespace
+	// set by index
+	declarations map[model.RDFTerm]ShapeExpr
+	tripleExprs  map[model.RDFTerm]TripleExpr
+	patterns     map[string]*regexp.Regexp
+}
+
+// ShapeDecl is a shape expression with its label, an IRI or a
+// *model.LabelledBlankNode
+type ShapeDecl struct {
+	Label model.RDFTerm
+	Expr  ShapeExpr
+}
+
+// ShapeExpr is one of ShapeOr, ShapeAnd, ShapeNot, NodeConstraint, Shape,
+// ShapeExternal and ShapeRef
+type ShapeExpr interface {
+	isShapeExpr()
+}
+
+type ShapeOr struct {
+	ShapeExprs []ShapeExpr
+}
+
+type ShapeAnd struct {
+	ShapeExprs []ShapeExpr
+}
+
+type ShapeNot struct {
+	ShapeExpr ShapeExpr
+}
+
+// ShapeRef is a reference to the shape expression of a label
+type ShapeRef struct {
+	Label model.RDFTerm
+}
+
+// ShapeExternal is a shape expression defined out of the schema
+type ShapeExternal struct{}
+
+// NodeConstraint constrains a node on its own. The zero values are no
+// constraint: Values is nil without a value set, an empty slice is an empty
+// value set.
+type NodeConstraint struct {
+	// "iri", "bnode", "nonliteral" or "literal"
+	NodeKind string
+	Datatype model.IRI
+	Facets   []Facet
+	Values   []ValueSetValue
+}
+
+// Facet is an XML Schema facet, named as in ShExJ: length, minlength,
+// maxlength, pattern, mininclusive, minexclusive, maxinclusive,
+// maxexclusive, totaldigits or fractiondigits
+type Facet struct {
+	Name string
+	// an xsd:integer for the lengths and digits, a numeric literal for the
+	// bounds, the regular expression for pattern
+	Value model.Literal
+	// the flags of pattern
+	Flags string
+}
+
+// Shape constrains the neighbourhood of a node
+type Shape struct {
+	Closed bool
+	// the predicates whose triples may not match the triple expression
+	Extra []model.IRI
+	// nil for an empty shape
+	Expression TripleExpr
+}
+
+// TripleExpr is one of EachOf, OneOf, TripleConstraint and TripleExprRef
+type TripleExpr interface {
+	isTripleExpr()
+}
+
+// EachOf matches its expressions together. Label is nil unless the
+// expression is labelled.
+type EachOf struct {
+	Label       model.RDFTerm
+	Expressions []TripleExpr
+	Min, Max    int
+}
+
+// OneOf matches one of its expressions each time
+type OneOf struct {
+	Label       model.RDFTerm
+	Expressions []TripleExpr
+	Min, Max    int
+}
+
+// TripleConstraint matches the triples of a predicate whose objects, or
+// subjects when it is inverse, satisfy ValueExpr. A nil ValueExpr is
+// satisfied by any node.
+type TripleConstraint struct {
+	Label     model.RDFTerm
+	Inverse   bool
+	Predicate model.IRI
+	ValueExpr ShapeExpr
+	Min, Max  int
+}
+
+// TripleExprRef is the inclusion of the triple expression of a label
+type TripleExprRef struct {
+	Label model.RDFTerm
+}
+
+// ValueSetValue is one of ObjectValue, Language, Stem and StemRange
+type ValueSetValue interface {
+	isValueSetValue()
+}
+
+// ObjectValue is an IRI or a literal
+type ObjectValue struct {
+	Term model.RDFTerm
+}
+
+// Language matches the literals of a language tag
+type Language struct {
+	Tag string
+}
+
+type StemKind int
+
+const (
+	IRIStem StemKind = iota
+	LiteralStem
+	LanguageStem
+)
+
+var stemKindNames = map[StemKind]string{IRIStem: "IriStem", LiteralStem: "LiteralStem", LanguageStem: "LanguageStem"}
+
+// Stem matches the IRIs, the lexical forms of the literals or the language
+// tags starting with Value. Language stems only match whole subtags, an
+// empty one matches any language tag.
+type Stem struct {
+	Kind  StemKind
+	Value string
+}
+
+// StemRange matches what its stem matches, or anything of its kind when it
+// is a wildcard, but its exclusions
+type StemRange struct {
+	Kind     StemKind
+	Stem     string
+	Wildcard bool
+	// the values excluded, and the stems of the values excluded
+	Exclusions []Exclusion
+}
+
+type Exclusion struct {
+	Value string
+	Stem  bool
+}
+
+func (this *ShapeOr) isShapeExpr()           {}
+func (this *ShapeAnd) isShapeExpr()          {}
+func (this *ShapeNot) isShapeExpr()          {}
+func (this *ShapeRef) isShapeExpr()          {}
+func (this *ShapeExternal) isShapeExpr()     {}
+func (this *NodeConstraint) isShapeExpr()    {}
+func (this *Shape) isShapeExpr()             {}
+func (this *EachOf) isTripleExpr()           {}
+func (this *OneOf) isTripleExpr()            {}
+func (this *TripleConstraint) isTripleExpr() {}
+func (this *TripleExprRef) isTripleExpr()    {}
+func (this ObjectValue) isValueSetValue()    {}
+func (this Language) isValueSetValue()       {}
+func (this Stem) isValueSetValue()           {}
+func (this StemRange) isValueSetValue()      {}
+
+// indexes the shape expressions and the labelled triple expressions, and
+// checks the references, the cycles of references which do not go through
+// a shape, the negations in cycles and the patterns
+func (this *Schema) index() error {
+	this.declarations = make(map[model.RDFTerm]ShapeExpr)
+	this.tripleExprs = make(map[model.RDFTerm]TripleExpr)
+	this.patterns = make(map[string]*regexp.Regexp)
+	for _, declaration := range this.Shapes {
+		if _, found := this.declarations[declaration.Label]; found {
+			return fmt.Errorf("the shape %s is declared twice", formatLabel(declaration.Label))
+		}
+		this.declarations[declaration.Label] = declaration.Expr
+	}
+	expressions := []ShapeExpr{}
+	if this.Start != nil {
+		expressions = append(expressions, this.Start)
+	}
+	for _, declaration := range this.Shapes {
+		expressions = append(expressions, declaration.Expr)
+	}
+	// the labelled triple expressions first, references may come before
+	// their definitions
+	for _, expression := range expressions {
+		if err := this.walk(expression, this.indexTripleExpr); err != nil {
+			return err
+		}
+	}
+	for _, expression := range expressions {
+		if err := this.walk(expression, this.check); err != nil {
+			return err
+		}
+	}
+	for _, declaration := range this.Shapes {
+		if err := this.checkCycles(declaration.Label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// calls visit on the shape and triple expressions of a shape expression,
+// without following references
+func (this *Schema) walk(expression ShapeExpr, visit func(expression interface{}) error) error {
+	if err := visit(expression); err != nil {
+		return err
+	}
+	switch e := expression.(type) {
+	case *ShapeOr:
+		for _, child := range e.ShapeExprs {
+			if err := this.walk(child, visit); err != nil {
+				return err
+			}
+		}
+	case *ShapeAnd:
+		for _, child := range e.ShapeExprs {
+			if err := this.walk(child, visit); err != nil {
+				return err
+			}
+		}
+	case *ShapeNot:
+		return this.walk(e.ShapeExpr, visit)
+	case *Shape:
+		if e.Expression != nil {
+			return this.walkTripleExpr(e.Expression, visit)
+		}
+	}
+	return nil
+}
+
+func (this *Schema) walkTripleExpr(expression TripleExpr, visit func(expression interface{}) error) error {
+	if err := visit(expression); err != nil {
+		return err
+	}
+	var children []TripleExpr
+	switch e := expression.(type) {
+	case *EachOf:
+		children = e.Expressions
+	case *OneOf:
+		children = e.Expressions
+	case *TripleConstraint:
+		if e.ValueExpr != nil {
+			return this.walk(e.ValueExpr, visit)
+		}
+	}
+	for _, child := range children {
+		if err := this.walkTripleExpr(child, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Schema) indexTripleExpr(expression interface{}) error {
+	var label model.RDFTerm
+	switch e := expression.(type) {
+	case *EachOf:
+		label = e.Label
+	case *OneOf:
+		label = e.Label
+	case *TripleConstraint:
+		label = e.Label
+	}
+	if label == nil {
+		return nil
+	}
+	if _, found := this.tripleExprs[label]; found {
+		return fmt.Errorf("the triple expression %s is declared twice", formatLabel(label))
+	}
+	this.tripleExprs[label] = expression.(TripleExpr)
+	return nil
+}
+
+func (this *Schema) check(expression interface{}) error {
+	switch e := expression.(type) {
+	case *ShapeRef:
+		if _, found := this.declarations[e.Label]; !found {
+			return fmt.Errorf("the shape %s is not declared", formatLabel(e.Label))
+		}
+	case *TripleExprRef:
+		if _, found := this.tripleExprs[e.Label]; !found {
+			return fmt.Errorf("the triple expression %s is not declared", formatLabel(e.Label))
+		}
+		if this.includes(this.tripleExprs[e.Label], e.Label, map[model.RDFTerm]bool{}) {
+			return fmt.Errorf("the triple expression %s includes itself", formatLabel(e.Label))
+		}
+	case *NodeConstraint:
+		for _, facet := range e.Facets {
+			if facet.Name == "pattern" {
+				if _, err := this.pattern(facet); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// whether a triple expression includes a label, directly or not
+func (this *Schema) includes(expression TripleExpr, label model.RDFTerm, visited map[model.RDFTerm]bool) bool {
+	var children []TripleExpr
+	switch e := expression.(type) {
+	case *TripleExprRef:
+		if e.Label == label {
+			return true
+		}
+		if visited[e.Label] {
+			return false
+		}
+		visited[e.Label] = true
+		return this.includes(this.tripleExprs[e.Label], label, visited)
+	case *EachOf:
+		children = e.Expressions
+	case *OneOf:
+		children = e.Expressions
+	}
+	for _, child := range children {
+		if this.includes(child, label, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// the references of a shape expression, with whether they are negated and
+// whether they go through a shape
+type reference struct {
+	label                 model.RDFTerm
+	negated, throughShape bool
+}
+
+// fails when a shape expression refers to itself without going through a
+// shape, or through a negation
+func (this *Schema) checkCycles(label model.RDFTerm) error {
+	visited := map[reference]bool{}
+	var visit func(current model.RDFTerm, negated, throughShape bool) error
+	visit = func(current model.RDFTerm, negated, throughShape bool) error {
+		for _, ref := range this.references(this.declarations[current], false, false, map[model.RDFTerm]bool{}) {
+			n, s := negated || ref.negated, throughShape || ref.throughShape
+			if ref.label == label {
+				if !s {
+					return fmt.Errorf("the shape %s refers to itself without going through a shape", formatLabel(label))
+				}
+				if n {
+					return fmt.Errorf("the shape %s refers to itself through a negation", formatLabel(label))
+				}
+				continue
+			}
+			key := reference{ref.label, n, s}
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			if err := visit(ref.label, n, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(label, false, false)
+}
+
+func (this *Schema) references(expression ShapeExpr, negated, throughShape bool, included map[model.RDFTerm]bool) []reference {
+	ret := []reference{}
+	switch e := expression.(type) {
+	case *ShapeRef:
+		ret = append(ret, reference{e.Label, negated, throughShape})
+	case *ShapeOr:
+		for _, child := range e.ShapeExprs {
+			ret = append(ret, this.references(child, negated, throughShape, included)...)
+		}
+	case *ShapeAnd:
+		for _, child := range e.ShapeExprs {
+			ret = append(ret, this.references(child, negated, throughShape, included)...)
+		}
+	case *ShapeNot:
+		ret = append(ret, this.references(e.ShapeExpr, true, throughShape, included)...)
+	case *Shape:
+		for _, constraint := range this.tripleConstraints(e.Expression, included) {
+			if constraint.ValueExpr != nil {
+				ret = append(ret, this.references(constraint.ValueExpr, negated, true, included)...)
+			}
+		}
+	}
+	return ret
+}
+
+// the triple constraints of a triple expression, the included ones too
+func (this *Schema) tripleConstraints(expression TripleExpr, included map[model.RDFTerm]bool) []*TripleConstraint {
+	switch e := expression.(type) {
+	case *TripleConstraint:
+		return []*TripleConstraint{e}
+	case *TripleExprRef:
+		if included[e.Label] {
+			return nil
+		}
+		included[e.Label] = true
+		return this.tripleConstraints(this.tripleExprs[e.Label], included)
+	}
+	var children []TripleExpr
+	if each, ok := expression.(*EachOf); ok {
+		children = each.Expressions
+	} else if one, ok := expression.(*OneOf); ok {
+		children = one.Expressions
+	}
+	ret := []*TripleConstraint{}
+	for _, child := range children {
+		ret = append(ret, this.tripleConstraints(child, included)...)
+	}
+	return ret
+}
+
+// the compiled regular expression of a pattern facet, the flags of XPath
+// which Go knows
+func (this *Schema) pattern(facet Facet) (*regexp.Regexp, error) {
+	key := facet.Flags + "/" + facet.Value.Lexical
+	if ret, found := this.patterns[key]; found {
+		return ret, nil
+	}
+	prefix := ""
+	for _, flag := range facet.Flags {
+		if flag != 'i' && flag != 's' && flag != 'm' {
+			return nil, fmt.Errorf("unsupported regular expression flag %q", flag)
+		}
+	}
+	if facet.Flags != "" {
+		prefix = "(?" + facet.Flags + ")"
+	}
+	ret, err := regexp.Compile(prefix + facet.Value.Lexical)
+	if err != nil {
+		return nil, fmt.Errorf("pattern %q: %w", facet.Value.Lexical, err)
+	}
+	this.patterns[key] = ret
+	return ret, nil
+}
+
+// the labels as terms, START for the start shape
+func formatLabel(label model.RDFTerm) string {
+	if label == nil {
+		return "START"
+	}
+	return format(label)
+}
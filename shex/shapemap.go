@@ -0,0 +1,71 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// ParseShapeMap parses a fixed shape map, comma separated associations of
+// an IRI or a literal with a shape label or START:
+//
+//	<http://ex.org/a>@<http://ex.org/S>, ex:b@ex:S, "1"@START
+//
+// Syntax errors are *parser.SyntaxError.
+func ParseShapeMap(text string, options Options) (ret []Association, err error) {
+	this := newShExCParser(text, options)
+	defer this.recover(&err)
+	this.advance()
+	ret = []Association{}
+	for {
+		association := Association{}
+		if this.isIRI() {
+			association.Node = this.iri()
+			association.Shape = this.shapeSpec()
+		} else if literal := this.literal(); strings.EqualFold(literal.Language, "START") && (this.is(tEOF) || this.isPunctuation(",")) {
+			// "1"@START reads as a literal with a language tag
+			association.Node = model.NewStringLiteral(literal.Lexical)
+		} else {
+			association.Node = literal
+			association.Shape = this.shapeSpec()
+		}
+		ret = append(ret, association)
+		if this.is(tEOF) {
+			return ret, nil
+		}
+		this.expect(",")
+	}
+}
+
+// '@' and a shape label, nil for START
+func (this *shexcParser) shapeSpec() model.RDFTerm {
+	switch {
+	case this.is(tATPNameNS), this.is(tATPNameLN):
+		return this.iri()
+	case this.is(tLangTag) && strings.EqualFold(this.token.value, "START"):
+		// @START reads as a language tag
+		this.advance()
+		return nil
+	case this.isPunctuation("@"):
+		this.advance()
+		if this.isName("START") {
+			this.advance()
+			return nil
+		}
+		if !this.isIRI() {
+			this.fail("expected a shape label, got %s", this.token)
+		}
+		return this.iri()
+	}
+	this.fail("expected '@', got %s", this.token)
+	return nil
+}
+
+// FormatAssociation writes an association as in shape maps
+func FormatAssociation(association Association) string {
+	return format(association.Node) + "@" + formatLabel(association.Shape)
+}
@@ -0,0 +1,48 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+func TestParseShapeMap(t *testing.T) {
+	associations, err := ParseShapeMap(`<http://ex.org/a>@<http://ex.org/S>, ex:b@ex:S, "c"@en@START, "1"@START , 2 @ <http://ex.org/S>, <d>@ex:`,
+		Options{Base: "http://ex.org/", Namespaces: []model.Namespace{{Prefix: "ex", IRI: "http://ex.org/"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, association := range associations {
+		got = append(got, FormatAssociation(association))
+	}
+	expected := []string{
+		`<http://ex.org/a>@<http://ex.org/S>`,
+		`<http://ex.org/b>@<http://ex.org/S>`,
+		`"c"@en@START`,
+		`"1"@START`,
+		`"2"^^<http://www.w3.org/2001/XMLSchema#integer>@<http://ex.org/S>`,
+		`<http://ex.org/d>@<http://ex.org/>`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("associations\n%s", strings.Join(got, "\n"))
+	}
+
+	for text, message := range map[string]string{
+		`<a>`:             "expected '@'",
+		`<a>@<S> <b>@<S>`: "expected ','",
+		`_:a@<S>`:         "expected a literal",
+		`<a>@_:s`:         "expected a shape label",
+		`<a>@<S>,`:        "expected a literal",
+	} {
+		_, err := ParseShapeMap(text, Options{})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", text, err, message)
+		}
+	}
+}
@@ -0,0 +1,755 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+type Options struct {
+	// the IRI relative IRIs are resolved against until a BASE
+	Base model.IRI
+	// prefixes known without being declared
+	Namespaces []model.Namespace
+}
+
+// ParseShExC parses a schema written in ShExC, syntax errors are
+// *parser.SyntaxError
+func ParseShExC(text string, options Options) (ret *Schema, err error) {
+	this := newShExCParser(text, options)
+	defer this.recover(&err)
+	this.advance()
+	ret = this.schema()
+	if err := ret.index(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+type shexcParser struct {
+	lexer      *lexer
+	token      *token
+	base       model.IRI
+	namespaces map[model.Prefix]model.IRI
+	blankNodes map[string]*model.LabelledBlankNode
+}
+
+func newShExCParser(text string, options Options) *shexcParser {
+	this := &shexcParser{
+		lexer:      newLexer(text),
+		base:       options.Base,
+		namespaces: make(map[model.Prefix]model.IRI),
+		blankNodes: make(map[string]*model.LabelledBlankNode),
+	}
+	for _, namespace := range options.Namespaces {
+		this.namespaces[namespace.Prefix] = namespace.IRI
+	}
+	return this
+}
+
+// turns the panics of fail into errors
+func (this *shexcParser) recover(err *error) {
+	if r := recover(); r != nil {
+		syntaxError, ok := r.(*parser.SyntaxError)
+		if !ok {
+			panic(r)
+		}
+		*err = syntaxError
+	}
+}
+
+func (this *shexcParser) fail(format string, args ...interface{}) {
+	line, col := 1, 1
+	if this.token != nil {
+		line, col = this.token.line, this.token.col
+	}
+	panic(&parser.SyntaxError{Line: line, Col: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func (this *shexcParser) unexpected() {
+	this.fail("unexpected %s", this.token)
+}
+
+func (this *shexcParser) advance() {
+	token, err := this.lexer.token()
+	if err != nil {
+		panic(err)
+	}
+	this.token = token
+}
+
+func (this *shexcParser) is(tokenType tokenType) bool {
+	return this.token.tokenType == tokenType
+}
+
+func (this *shexcParser) isPunctuation(values ...string) bool {
+	if this.token.tokenType != tPunctuation {
+		return false
+	}
+	for _, value := range values {
+		if this.token.value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// keywords are case insensitive
+func (this *shexcParser) isName(names ...string) bool {
+	if this.token.tokenType != tName {
+		return false
+	}
+	for _, name := range names {
+		if strings.EqualFold(this.token.value, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *shexcParser) expect(punctuation string) {
+	if !this.isPunctuation(punctuation) {
+		this.fail("expected '%s', got %s", punctuation, this.token)
+	}
+	this.advance()
+}
+
+func (this *shexcParser) isIRI() bool {
+	return this.is(tIRI) || this.is(tPNameLN) || this.is(tPNameNS)
+}
+
+// directives and statements
+func (this *shexcParser) schema() *Schema {
+	ret := &Schema{}
+	for !this.is(tEOF) {
+		switch {
+		case this.isName("BASE"):
+			this.advance()
+			if !this.is(tIRI) {
+				this.fail("expected an IRI, got %s", this.token)
+			}
+			this.base = this.iri()
+		case this.isName("PREFIX"):
+			this.advance()
+			if !this.is(tPNameNS) {
+				this.fail("expected a prefix, got %s", this.token)
+			}
+			prefix := model.Prefix(strings.TrimSuffix(this.token.value, ":"))
+			this.advance()
+			if !this.is(tIRI) {
+				this.fail("expected an IRI, got %s", this.token)
+			}
+			iri := this.iri()
+			this.namespaces[prefix] = iri
+			ret.Namespaces = append(ret.Namespaces, model.Namespace{Prefix: prefix, IRI: iri})
+		case this.isName("IMPORT"):
+			this.fail("imports are not supported")
+		case this.isPunctuation("%"):
+			this.semanticActions()
+		case this.isName("start"):
+			this.advance()
+			this.expect("=")
+			if ret.Start != nil {
+				this.fail("start is declared twice")
+			}
+			ret.Start = orAny(this.shapeExpression(true))
+		default:
+			label := this.label()
+			var expression ShapeExpr
+			if this.isName("EXTERNAL") {
+				this.advance()
+				expression = &ShapeExternal{}
+			} else {
+				expression = orAny(this.shapeExpression(false))
+			}
+			ret.Shapes = append(ret.Shapes, &ShapeDecl{Label: label, Expr: expression})
+		}
+	}
+	return ret
+}
+
+// the shape expression '.', nil, where a shape expression is required
+func orAny(expression ShapeExpr) ShapeExpr {
+	if expression == nil {
+		return &NodeConstraint{}
+	}
+	return expression
+}
+
+// shapeExpression and inlineShapeExpression, nil for '.'
+func (this *shexcParser) shapeExpression(inline bool) ShapeExpr {
+	operands := []ShapeExpr{this.shapeAnd(inline)}
+	for this.isName("OR") {
+		this.advance()
+		operands = append(operands, this.shapeAnd(inline))
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	for i := range operands {
+		operands[i] = orAny(operands[i])
+	}
+	return &ShapeOr{ShapeExprs: operands}
+}
+
+func (this *shexcParser) shapeAnd(inline bool) ShapeExpr {
+	first := this.shapeNot(inline)
+	if !this.isName("AND") {
+		return first
+	}
+	ret := &ShapeAnd{}
+	ret.add(first)
+	for this.isName("AND") {
+		this.advance()
+		ret.add(this.shapeNot(inline))
+	}
+	return ret
+}
+
+// the operands of conjunctions are flattened
+func (this *ShapeAnd) add(operand ShapeExpr) {
+	if and, ok := operand.(*ShapeAnd); ok {
+		this.ShapeExprs = append(this.ShapeExprs, and.ShapeExprs...)
+		return
+	}
+	this.ShapeExprs = append(this.ShapeExprs, orAny(operand))
+}
+
+func (this *shexcParser) shapeNot(inline bool) ShapeExpr {
+	if this.isName("NOT") {
+		this.advance()
+		return &ShapeNot{ShapeExpr: orAny(this.shapeAtom(inline))}
+	}
+	return this.shapeAtom(inline)
+}
+
+func (this *shexcParser) shapeAtom(inline bool) ShapeExpr {
+	switch {
+	case this.isPunctuation("("):
+		this.advance()
+		ret := this.shapeExpression(false)
+		this.expect(")")
+		return ret
+	case this.isPunctuation("."):
+		this.advance()
+		return nil
+	case this.startsNonLiteralConstraint():
+		constraint := this.nonLiteralConstraint()
+		if this.startsShapeOrRef() {
+			ret := &ShapeAnd{ShapeExprs: []ShapeExpr{constraint}}
+			ret.add(this.shapeOrRef(inline))
+			return ret
+		}
+		return constraint
+	case this.startsShapeOrRef():
+		reference := this.shapeOrRef(inline)
+		if this.startsNonLiteralConstraint() {
+			return &ShapeAnd{ShapeExprs: []ShapeExpr{reference, this.nonLiteralConstraint()}}
+		}
+		return reference
+	}
+	return this.literalConstraint()
+}
+
+func (this *shexcParser) startsNonLiteralConstraint() bool {
+	return this.isName("IRI", "BNODE", "NONLITERAL") || this.startsStringFacet()
+}
+
+func (this *shexcParser) startsStringFacet() bool {
+	return this.isName("LENGTH", "MINLENGTH", "MAXLENGTH") || this.is(tRegexp)
+}
+
+func (this *shexcParser) startsNumericFacet() bool {
+	return this.isName("MININCLUSIVE", "MINEXCLUSIVE", "MAXINCLUSIVE", "MAXEXCLUSIVE", "TOTALDIGITS", "FRACTIONDIGITS")
+}
+
+func (this *shexcParser) startsShapeOrRef() bool {
+	return this.isPunctuation("{", "@") || this.isName("EXTRA", "CLOSED") || this.is(tATPNameNS) || this.is(tATPNameLN)
+}
+
+func (this *shexcParser) nonLiteralConstraint() *NodeConstraint {
+	ret := &NodeConstraint{}
+	if this.isName("IRI", "BNODE", "NONLITERAL") {
+		ret.NodeKind = strings.ToLower(this.token.value)
+		this.advance()
+	}
+	for this.startsStringFacet() {
+		ret.Facets = append(ret.Facets, this.facet())
+	}
+	return ret
+}
+
+// LITERAL, a datatype, a value set or numeric facets, followed by facets
+func (this *shexcParser) literalConstraint() *NodeConstraint {
+	ret := &NodeConstraint{}
+	switch {
+	case this.isName("LITERAL"):
+		this.advance()
+		ret.NodeKind = "literal"
+	case this.isIRI():
+		ret.Datatype = this.iri()
+	case this.isPunctuation("["):
+		ret.Values = this.valueSet()
+	case this.startsNumericFacet():
+	default:
+		this.unexpected()
+	}
+	for this.startsStringFacet() || this.startsNumericFacet() {
+		ret.Facets = append(ret.Facets, this.facet())
+	}
+	return ret
+}
+
+func (this *shexcParser) facet() Facet {
+	if this.is(tRegexp) {
+		ret := Facet{Name: "pattern", Value: model.NewStringLiteral(this.token.value), Flags: this.token.flags}
+		this.advance()
+		return ret
+	}
+	ret := Facet{Name: strings.ToLower(this.token.value)}
+	this.advance()
+	switch ret.Name {
+	case "mininclusive", "minexclusive", "maxinclusive", "maxexclusive":
+		if !this.is(tInteger) && !this.is(tDecimal) && !this.is(tDouble) {
+			this.fail("expected a number, got %s", this.token)
+		}
+	default:
+		if !this.is(tInteger) {
+			this.fail("expected an integer, got %s", this.token)
+		}
+	}
+	ret.Value = this.literal()
+	return ret
+}
+
+// shapeDefinition or shapeRef
+func (this *shexcParser) shapeOrRef(inline bool) ShapeExpr {
+	switch {
+	case this.is(tATPNameNS), this.is(tATPNameLN):
+		return &ShapeRef{Label: this.iri()}
+	case this.isPunctuation("@"):
+		this.advance()
+		return &ShapeRef{Label: this.label()}
+	}
+	ret := &Shape{}
+	for !this.isPunctuation("{") {
+		switch {
+		case this.isName("CLOSED"):
+			this.advance()
+			ret.Closed = true
+		case this.isName("EXTRA"):
+			this.advance()
+			if !this.isIRI() && !this.isName("a") {
+				this.fail("expected a predicate, got %s", this.token)
+			}
+			for this.isIRI() || this.is(tName) && this.token.value == "a" {
+				ret.Extra = append(ret.Extra, this.predicate())
+			}
+		default:
+			this.unexpected()
+		}
+	}
+	this.advance()
+	if !this.isPunctuation("}") {
+		ret.Expression = this.tripleExpression()
+	}
+	this.expect("}")
+	if !inline {
+		this.annotations()
+		this.semanticActions()
+	}
+	return ret
+}
+
+// oneOfTripleExpr
+func (this *shexcParser) tripleExpression() TripleExpr {
+	operands := []TripleExpr{this.groupTripleExpression()}
+	for this.isPunctuation("|") {
+		this.advance()
+		operands = append(operands, this.groupTripleExpression())
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &OneOf{Expressions: operands, Min: 1, Max: 1}
+}
+
+func (this *shexcParser) groupTripleExpression() TripleExpr {
+	operands := []TripleExpr{this.unaryTripleExpression()}
+	for this.isPunctuation(";") {
+		this.advance()
+		if !this.startsUnaryTripleExpression() {
+			break
+		}
+		operands = append(operands, this.unaryTripleExpression())
+	}
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	return &EachOf{Expressions: operands, Min: 1, Max: 1}
+}
+
+func (this *shexcParser) startsUnaryTripleExpression() bool {
+	return this.isPunctuation("$", "&", "(", "^") || this.isIRI() || this.is(tName) && this.token.value == "a"
+}
+
+func (this *shexcParser) unaryTripleExpression() TripleExpr {
+	if this.isPunctuation("&") {
+		this.advance()
+		return &TripleExprRef{Label: this.label()}
+	}
+	var label model.RDFTerm
+	if this.isPunctuation("$") {
+		this.advance()
+		label = this.label()
+	}
+	if !this.isPunctuation("(") {
+		ret := this.tripleConstraint()
+		ret.Label = label
+		return ret
+	}
+	this.advance()
+	ret := this.tripleExpression()
+	this.expect(")")
+	if this.startsCardinality() {
+		min, max := this.cardinality()
+		ret = withCardinality(ret, min, max)
+	}
+	this.annotations()
+	this.semanticActions()
+	if label != nil {
+		ret = withLabel(ret, label)
+	}
+	return ret
+}
+
+// sets the cardinality of a bracketed triple expression, wrapping it when
+// it has one already
+func withCardinality(expression TripleExpr, min, max int) TripleExpr {
+	switch e := expression.(type) {
+	case *EachOf:
+		if e.Min == 1 && e.Max == 1 {
+			e.Min, e.Max = min, max
+			return e
+		}
+	case *OneOf:
+		if e.Min == 1 && e.Max == 1 {
+			e.Min, e.Max = min, max
+			return e
+		}
+	case *TripleConstraint:
+		if e.Min == 1 && e.Max == 1 {
+			e.Min, e.Max = min, max
+			return e
+		}
+	}
+	return &EachOf{Expressions: []TripleExpr{expression}, Min: min, Max: max}
+}
+
+func withLabel(expression TripleExpr, label model.RDFTerm) TripleExpr {
+	switch e := expression.(type) {
+	case *EachOf:
+		if e.Label == nil {
+			e.Label = label
+			return e
+		}
+	case *OneOf:
+		if e.Label == nil {
+			e.Label = label
+			return e
+		}
+	case *TripleConstraint:
+		if e.Label == nil {
+			e.Label = label
+			return e
+		}
+	}
+	return &EachOf{Label: label, Expressions: []TripleExpr{expression}, Min: 1, Max: 1}
+}
+
+func (this *shexcParser) tripleConstraint() *TripleConstraint {
+	ret := &TripleConstraint{Min: 1, Max: 1}
+	if this.isPunctuation("^") {
+		this.advance()
+		ret.Inverse = true
+	}
+	if !this.isIRI() && !(this.is(tName) && this.token.value == "a") {
+		this.fail("expected a predicate, got %s", this.token)
+	}
+	ret.Predicate = this.predicate()
+	ret.ValueExpr = this.shapeExpression(true)
+	if this.startsCardinality() {
+		ret.Min, ret.Max = this.cardinality()
+	}
+	this.annotations()
+	this.semanticActions()
+	return ret
+}
+
+func (this *shexcParser) startsCardinality() bool {
+	return this.isPunctuation("*", "+", "?") || this.is(tRepeat)
+}
+
+func (this *shexcParser) cardinality() (int, int) {
+	token := this.token
+	this.advance()
+	switch token.value {
+	case "*":
+		return 0, Unbounded
+	case "+":
+		return 1, Unbounded
+	case "?":
+		return 0, 1
+	}
+	bounds := strings.SplitN(token.value[1:len(token.value)-1], ",", 2)
+	min, err := strconv.Atoi(bounds[0])
+	max := min
+	if err == nil && len(bounds) == 2 {
+		if bounds[1] == "" || bounds[1] == "*" {
+			max = Unbounded
+		} else {
+			max, err = strconv.Atoi(bounds[1])
+		}
+	}
+	if err != nil || max != Unbounded && max < min {
+		this.token = token
+		this.fail("invalid cardinality %s", token.value)
+	}
+	return min, max
+}
+
+func (this *shexcParser) valueSet() []ValueSetValue {
+	this.expect("[")
+	ret := []ValueSetValue{}
+	for !this.isPunctuation("]") {
+		ret = append(ret, this.valueSetValue())
+	}
+	this.advance()
+	return ret
+}
+
+func (this *shexcParser) valueSetValue() ValueSetValue {
+	switch {
+	case this.isPunctuation("."):
+		this.advance()
+		if !this.isPunctuation("-") {
+			this.fail("expected an exclusion, got %s", this.token)
+		}
+		// the kind of the first exclusion is the kind of the range
+		this.advance()
+		ret := StemRange{Kind: LiteralStem, Wildcard: true}
+		if this.isIRI() {
+			ret.Kind = IRIStem
+		} else if this.is(tLangTag) {
+			ret.Kind = LanguageStem
+		}
+		ret.Exclusions = append(ret.Exclusions, this.exclusion(ret.Kind))
+		ret.Exclusions = append(ret.Exclusions, this.exclusions(ret.Kind)...)
+		return ret
+	case this.isIRI():
+		iri := this.iri()
+		if !this.isPunctuation("~") {
+			return ObjectValue{Term: iri}
+		}
+		return this.stem(IRIStem, string(iri))
+	case this.is(tLangTag):
+		tag := this.token.value
+		this.advance()
+		if !this.isPunctuation("~") {
+			return Language{Tag: tag}
+		}
+		return this.stem(LanguageStem, tag)
+	case this.isPunctuation("@"):
+		this.advance()
+		if !this.isPunctuation("~") {
+			this.fail("expected '~', got %s", this.token)
+		}
+		return this.stem(LanguageStem, "")
+	}
+	literal := this.literal()
+	if !this.isPunctuation("~") {
+		return ObjectValue{Term: literal}
+	}
+	return this.stem(LiteralStem, literal.Lexical)
+}
+
+// '~' is the current token
+func (this *shexcParser) stem(kind StemKind, stem string) ValueSetValue {
+	this.advance()
+	exclusions := this.exclusions(kind)
+	if len(exclusions) == 0 {
+		return Stem{Kind: kind, Value: stem}
+	}
+	return StemRange{Kind: kind, Stem: stem, Exclusions: exclusions}
+}
+
+func (this *shexcParser) exclusions(kind StemKind) []Exclusion {
+	ret := []Exclusion{}
+	for this.isPunctuation("-") {
+		this.advance()
+		ret = append(ret, this.exclusion(kind))
+	}
+	return ret
+}
+
+// the '-' has been read
+func (this *shexcParser) exclusion(kind StemKind) Exclusion {
+	ret := Exclusion{}
+	switch kind {
+	case IRIStem:
+		if !this.isIRI() {
+			this.fail("expected an IRI, got %s", this.token)
+		}
+		ret.Value = string(this.iri())
+	case LanguageStem:
+		if !this.is(tLangTag) {
+			this.fail("expected a language tag, got %s", this.token)
+		}
+		ret.Value = this.token.value
+		this.advance()
+	default:
+		ret.Value = this.literal().Lexical
+	}
+	if this.isPunctuation("~") {
+		this.advance()
+		ret.Stem = true
+	}
+	return ret
+}
+
+// annotations are read and ignored
+func (this *shexcParser) annotations() {
+	for this.isPunctuation("//") {
+		this.advance()
+		this.predicate()
+		if this.isIRI() {
+			this.iri()
+		} else {
+			this.literal()
+		}
+	}
+}
+
+// semantic actions are read and ignored
+func (this *shexcParser) semanticActions() {
+	for this.isPunctuation("%") {
+		this.advance()
+		if !this.isIRI() {
+			this.fail("expected an IRI, got %s", this.token)
+		}
+		this.expand(this.token)
+		if _, err := this.lexer.code(); err != nil {
+			panic(err)
+		}
+		this.advance()
+	}
+}
+
+// shapeExprLabel and tripleExprLabel
+func (this *shexcParser) label() model.RDFTerm {
+	if this.is(tBlankNode) {
+		label := this.token.value
+		this.advance()
+		node, found := this.blankNodes[label]
+		if !found {
+			node = &model.LabelledBlankNode{Label: label}
+			this.blankNodes[label] = node
+		}
+		return node
+	}
+	if !this.isIRI() {
+		this.fail("expected a label, got %s", this.token)
+	}
+	return this.iri()
+}
+
+func (this *shexcParser) predicate() model.IRI {
+	if this.is(tName) && this.token.value == "a" {
+		this.advance()
+		return model.A
+	}
+	return this.iri()
+}
+
+// IRIs, prefixed names and the prefixed names of shape references
+func (this *shexcParser) iri() model.IRI {
+	ret := this.expand(this.token)
+	this.advance()
+	return ret
+}
+
+func (this *shexcParser) expand(token *token) model.IRI {
+	switch token.tokenType {
+	case tIRI:
+		return resolve(this.base, token.value)
+	case tPNameLN, tPNameNS, tATPNameLN, tATPNameNS:
+		separator := strings.IndexRune(token.value, ':')
+		prefix := model.Prefix(token.value[:separator])
+		namespace, ok := this.namespaces[prefix]
+		if !ok {
+			this.fail("undefined prefix %q", prefix)
+		}
+		return namespace + model.IRI(token.value[separator+1:])
+	}
+	this.fail("expected an IRI, got %s", token)
+	return ""
+}
+
+func (this *shexcParser) literal() model.Literal {
+	token := this.token
+	switch token.tokenType {
+	case tInteger:
+		this.advance()
+		return model.NewTypedLiteral(token.value, model.XSDInteger)
+	case tDecimal:
+		this.advance()
+		return model.NewTypedLiteral(token.value, model.XSDDecimal)
+	case tDouble:
+		this.advance()
+		return model.NewTypedLiteral(token.value, model.XSDDouble)
+	case tName:
+		if token.value == "true" || token.value == "false" {
+			this.advance()
+			return model.NewTypedLiteral(token.value, model.XSDBoolean)
+		}
+	case tString:
+		this.advance()
+		if this.is(tLangTag) {
+			language := this.token.value
+			this.advance()
+			return model.NewLangLiteral(token.value, language, "")
+		}
+		if this.isPunctuation("^") {
+			this.advance()
+			this.expect("^")
+			return model.NewTypedLiteral(token.value, this.iri())
+		}
+		return model.NewStringLiteral(token.value)
+	}
+	this.fail("expected a literal, got %s", token)
+	return model.Literal{}
+}
+
+// resolves iri against base, as per RFC 3986
+func resolve(base model.IRI, iri string) model.IRI {
+	if base == "" {
+		return model.IRI(iri)
+	}
+	reference, err := url.Parse(iri)
+	if err != nil || reference.IsAbs() {
+		return model.IRI(iri)
+	}
+	parsed, err := url.Parse(string(base))
+	if err != nil {
+		return model.IRI(iri)
+	}
+	return model.IRI(parsed.ResolveReference(reference).String())
+}
@@ -0,0 +1,133 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+// the ShExJ of a schema, decoded
+func shexj(t *testing.T, schema *Schema) interface{} {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := schema.WriteShExJ(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	var ret interface{}
+	if err := json.Unmarshal(buffer.Bytes(), &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func decode(t *testing.T, text string) interface{} {
+	t.Helper()
+	var ret interface{}
+	if err := json.Unmarshal([]byte(text), &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestParseShExC(t *testing.T) {
+	cases := []struct {
+		name     string
+		schema   string
+		expected string
+	}{
+		{"triple constraints", `BASE <http://ex.org/>
+PREFIX ex: <http://ex.org/>
+<S> CLOSED EXTRA a { ex:name LITERAL MINLENGTH 1 ; ^ex:knows @ex:S* ; a [ex:Person] {1,} // ex:comment "type" }`, `{
+  "@context": "http://www.w3.org/ns/shex.jsonld", "type": "Schema",
+  "shapes": [{"id": "http://ex.org/S", "type": "Shape", "closed": true, "extra": ["http://www.w3.org/1999/02/22-rdf-syntax-ns#type"],
+    "expression": {"type": "EachOf", "expressions": [
+      {"type": "TripleConstraint", "predicate": "http://ex.org/name", "valueExpr": {"type": "NodeConstraint", "nodeKind": "literal", "minlength": 1}},
+      {"type": "TripleConstraint", "inverse": true, "predicate": "http://ex.org/knows", "valueExpr": "http://ex.org/S", "min": 0, "max": -1},
+      {"type": "TripleConstraint", "predicate": "http://www.w3.org/1999/02/22-rdf-syntax-ns#type",
+        "valueExpr": {"type": "NodeConstraint", "values": ["http://ex.org/Person"]}, "min": 1, "max": -1}]}}]}`},
+		{"shape expressions", `PREFIX : <http://ex.org/>
+start = @:S
+:S IRI /^http/i AND NOT (@_:b OR LITERAL)
+_:b { } %:action{ code %}
+:E EXTERNAL`, `{
+  "@context": "http://www.w3.org/ns/shex.jsonld", "type": "Schema", "start": "http://ex.org/S",
+  "shapes": [
+    {"id": "http://ex.org/S", "type": "ShapeAnd", "shapeExprs": [
+      {"type": "NodeConstraint", "nodeKind": "iri", "pattern": "^http", "flags": "i"},
+      {"type": "ShapeNot", "shapeExpr": {"type": "ShapeOr", "shapeExprs": ["_:b", {"type": "NodeConstraint", "nodeKind": "literal"}]}}]},
+    {"id": "_:b", "type": "Shape"},
+    {"id": "http://ex.org/E", "type": "ShapeExternal"}]}`},
+		{"triple expressions", `PREFIX : <http://ex.org/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+:S { $:id ( :a . | :b xsd:integer MAXEXCLUSIVE 10.5 ){2} ; &:id ; :c . ? }`, `{
+  "@context": "http://www.w3.org/ns/shex.jsonld", "type": "Schema",
+  "shapes": [{"id": "http://ex.org/S", "type": "Shape", "expression": {"type": "EachOf", "expressions": [
+    {"type": "OneOf", "id": "http://ex.org/id", "min": 2, "max": 2, "expressions": [
+      {"type": "TripleConstraint", "predicate": "http://ex.org/a"},
+      {"type": "TripleConstraint", "predicate": "http://ex.org/b",
+        "valueExpr": {"type": "NodeConstraint", "datatype": "http://www.w3.org/2001/XMLSchema#integer", "maxexclusive": 10.5}}]},
+    "http://ex.org/id",
+    {"type": "TripleConstraint", "predicate": "http://ex.org/c", "min": 0, "max": 1}]}}]}`},
+		{"value sets", `PREFIX : <http://ex.org/>
+:S [ :a :b~ - :bc - :bd~ "x" "y"@en 1 true @fr @en~ @~ - @de . - "z"~ ]`, `{
+  "@context": "http://www.w3.org/ns/shex.jsonld", "type": "Schema",
+  "shapes": [{"id": "http://ex.org/S", "type": "NodeConstraint", "values": [
+    "http://ex.org/a",
+    {"type": "IriStemRange", "stem": "http://ex.org/b", "exclusions": ["http://ex.org/bc", {"type": "IriStem", "stem": "http://ex.org/bd"}]},
+    {"value": "x"}, {"value": "y", "language": "en"},
+    {"value": "1", "type": "http://www.w3.org/2001/XMLSchema#integer"},
+    {"value": "true", "type": "http://www.w3.org/2001/XMLSchema#boolean"},
+    {"type": "Language", "languageTag": "fr"},
+    {"type": "LanguageStem", "stem": "en"},
+    {"type": "LanguageStemRange", "stem": "", "exclusions": ["de"]},
+    {"type": "LiteralStemRange", "stem": {"type": "Wildcard"}, "exclusions": [{"type": "LiteralStem", "stem": "z"}]}]}]}`},
+	}
+	for _, c := range cases {
+		schema, err := ParseShExC(c.schema, Options{})
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		got, expected := shexj(t, schema), decode(t, c.expected)
+		if !reflect.DeepEqual(got, expected) {
+			text, _ := json.Marshal(got)
+			t.Errorf("%s: ShExJ\n%s", c.name, text)
+		}
+	}
+}
+
+func TestShExCErrors(t *testing.T) {
+	for text, message := range map[string]string{
+		`:S { }`:                           "1:1: undefined prefix",
+		`<S> { <p> . ; }  <S> { }`:         "the shape <S> is declared twice",
+		`<S> { <p> @<T> }`:                 "the shape <T> is not declared",
+		`<S> @<T> <T> @<S>`:                "refers to itself without going through a shape",
+		`<S> { <p> NOT @<S> }`:             "refers to itself through a negation",
+		`<S> { $<e> (<p> . ; &<e>) }`:      "the triple expression <e> includes itself",
+		`<S> { <p> . {3,1} }`:              "invalid cardinality {3,1}",
+		`<S> { <p> /a(/ }`:                 "pattern",
+		`<S> { <p> LENGTH "1" }`:           "expected an integer",
+		`IMPORT <other>`:                   "imports are not supported",
+		`<S> { <p> [ <a> }`:                "expected a literal",
+		`<S> { <p> "unterminated }`:        "unterminated string",
+		`<S> { <p> . } // <p> "x" %<a>{ x`: "unterminated code",
+	} {
+		_, err := ParseShExC(text, Options{})
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", text, err, message)
+		}
+	}
+	if _, err := ParseShExC("<S> {\n  <p> ] }", Options{}); err == nil {
+		t.Errorf("no error")
+	} else if syntaxError, ok := err.(*parser.SyntaxError); !ok || syntaxError.Line != 2 || syntaxError.Col != 7 {
+		t.Errorf("error %v", err)
+	}
+}
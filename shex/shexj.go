@@ -0,0 +1,500 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+const shexContext = "http://www.w3.org/ns/shex.jsonld"
+
+// WriteShExJ writes a schema in ShExJ, the shape expressions with an id
+// as in ShEx 2.1
+func (this *Schema) WriteShExJ(w io.Writer) error {
+	document := map[string]interface{}{"@context": shexContext, "type": "Schema"}
+	if this.Start != nil {
+		document["start"] = shapeExprJSON(this.Start)
+	}
+	if len(this.Shapes) > 0 {
+		shapes := []interface{}{}
+		for _, declaration := range this.Shapes {
+			shape := shapeExprJSON(declaration.Expr)
+			object, ok := shape.(map[string]interface{})
+			if !ok {
+				// a reference, which ShEx 2.1 cannot label
+				object = map[string]interface{}{"type": "ShapeAnd", "shapeExprs": []interface{}{shape}}
+			}
+			object["id"] = labelJSON(declaration.Label)
+			shapes = append(shapes, object)
+		}
+		document["shapes"] = shapes
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+func labelJSON(label model.RDFTerm) string {
+	if node, ok := label.(*model.LabelledBlankNode); ok {
+		return "_:" + node.Label
+	}
+	return string(label.(model.IRI))
+}
+
+func shapeExprJSON(expression ShapeExpr) interface{} {
+	switch e := expression.(type) {
+	case *ShapeRef:
+		return labelJSON(e.Label)
+	case *ShapeOr:
+		return map[string]interface{}{"type": "ShapeOr", "shapeExprs": shapeExprsJSON(e.ShapeExprs)}
+	case *ShapeAnd:
+		return map[string]interface{}{"type": "ShapeAnd", "shapeExprs": shapeExprsJSON(e.ShapeExprs)}
+	case *ShapeNot:
+		return map[string]interface{}{"type": "ShapeNot", "shapeExpr": shapeExprJSON(e.ShapeExpr)}
+	case *ShapeExternal:
+		return map[string]interface{}{"type": "ShapeExternal"}
+	case *Shape:
+		ret := map[string]interface{}{"type": "Shape"}
+		if e.Closed {
+			ret["closed"] = true
+		}
+		if len(e.Extra) > 0 {
+			ret["extra"] = e.Extra
+		}
+		if e.Expression != nil {
+			ret["expression"] = tripleExprJSON(e.Expression)
+		}
+		return ret
+	}
+	e := expression.(*NodeConstraint)
+	ret := map[string]interface{}{"type": "NodeConstraint"}
+	if e.NodeKind != "" {
+		ret["nodeKind"] = e.NodeKind
+	}
+	if e.Datatype != "" {
+		ret["datatype"] = e.Datatype
+	}
+	for _, facet := range e.Facets {
+		switch facet.Name {
+		case "pattern":
+			ret["pattern"] = facet.Value.Lexical
+			if facet.Flags != "" {
+				ret["flags"] = facet.Flags
+			}
+		default:
+			ret[facet.Name] = json.Number(numberJSON(facet.Value.Lexical))
+		}
+	}
+	if e.Values != nil {
+		values := []interface{}{}
+		for _, value := range e.Values {
+			values = append(values, valueJSON(value))
+		}
+		ret["values"] = values
+	}
+	return ret
+}
+
+func shapeExprsJSON(expressions []ShapeExpr) []interface{} {
+	ret := []interface{}{}
+	for _, expression := range expressions {
+		ret = append(ret, shapeExprJSON(expression))
+	}
+	return ret
+}
+
+// the lexical forms of ShExC which are not JSON numbers, like +1 or 1.
+func numberJSON(lexical string) string {
+	lexical = strings.TrimPrefix(lexical, "+")
+	if strings.HasPrefix(lexical, ".") || strings.HasPrefix(lexical, "-.") {
+		lexical = strings.Replace(lexical, ".", "0.", 1)
+	}
+	return strings.Replace(strings.Replace(lexical, ".e", ".0e", 1), ".E", ".0E", 1)
+}
+
+func tripleExprJSON(expression TripleExpr) interface{} {
+	var ret map[string]interface{}
+	var label model.RDFTerm
+	min, max := 1, 1
+	switch e := expression.(type) {
+	case *TripleExprRef:
+		return labelJSON(e.Label)
+	case *EachOf, *OneOf:
+		var children []TripleExpr
+		if each, ok := e.(*EachOf); ok {
+			ret = map[string]interface{}{"type": "EachOf"}
+			children, label, min, max = each.Expressions, each.Label, each.Min, each.Max
+		} else {
+			one := e.(*OneOf)
+			ret = map[string]interface{}{"type": "OneOf"}
+			children, label, min, max = one.Expressions, one.Label, one.Min, one.Max
+		}
+		expressions := []interface{}{}
+		for _, child := range children {
+			expressions = append(expressions, tripleExprJSON(child))
+		}
+		ret["expressions"] = expressions
+	case *TripleConstraint:
+		ret = map[string]interface{}{"type": "TripleConstraint", "predicate": e.Predicate}
+		if e.Inverse {
+			ret["inverse"] = true
+		}
+		if e.ValueExpr != nil {
+			ret["valueExpr"] = shapeExprJSON(e.ValueExpr)
+		}
+		label, min, max = e.Label, e.Min, e.Max
+	}
+	if label != nil {
+		ret["id"] = labelJSON(label)
+	}
+	if min != 1 || max != 1 {
+		ret["min"], ret["max"] = min, max
+	}
+	return ret
+}
+
+func valueJSON(value ValueSetValue) interface{} {
+	switch v := value.(type) {
+	case ObjectValue:
+		return termJSON(v.Term)
+	case Language:
+		return map[string]interface{}{"type": "Language", "languageTag": v.Tag}
+	case Stem:
+		return map[string]interface{}{"type": stemKindNames[v.Kind], "stem": v.Value}
+	}
+	v := value.(StemRange)
+	ret := map[string]interface{}{"type": stemKindNames[v.Kind] + "Range"}
+	if v.Wildcard {
+		ret["stem"] = map[string]interface{}{"type": "Wildcard"}
+	} else {
+		ret["stem"] = v.Stem
+	}
+	exclusions := []interface{}{}
+	for _, exclusion := range v.Exclusions {
+		if exclusion.Stem {
+			exclusions = append(exclusions, map[string]interface{}{"type": stemKindNames[v.Kind], "stem": exclusion.Value})
+		} else {
+			exclusions = append(exclusions, exclusion.Value)
+		}
+	}
+	ret["exclusions"] = exclusions
+	return ret
+}
+
+// IRIs are strings, literals objects
+func termJSON(term model.RDFTerm) interface{} {
+	if iri, ok := term.(model.IRI); ok {
+		return string(iri)
+	}
+	literal := term.(model.Literal)
+	ret := map[string]interface{}{"value": literal.Lexical}
+	if literal.Language != "" {
+		ret["language"] = literal.Language
+	} else if literal.Datatype != model.XSDString {
+		ret["type"] = literal.Datatype
+	}
+	return ret
+}
+
+// ParseShExJ reads a schema written in ShExJ, the shape expressions with
+// an id of ShEx 2.1 or the shape declarations of ShEx 2.2
+func ParseShExJ(r io.Reader) (ret *Schema, err error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	this := &shexjReader{blankNodes: make(map[string]*model.LabelledBlankNode)}
+	defer func() {
+		if r := recover(); r != nil {
+			message, ok := r.(shexjError)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("ShExJ: %s", string(message))
+		}
+	}()
+	ret = this.schema(this.object(document, "Schema"))
+	if err := ret.index(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+type shexjError string
+
+type shexjReader struct {
+	blankNodes map[string]*model.LabelledBlankNode
+}
+
+func (this *shexjReader) fail(format string, args ...interface{}) {
+	panic(shexjError(fmt.Sprintf(format, args...)))
+}
+
+// an object of a type, "" for any
+func (this *shexjReader) object(value interface{}, types ...string) map[string]interface{} {
+	ret, ok := value.(map[string]interface{})
+	if !ok {
+		this.fail("%v is not an object", value)
+	}
+	if len(types) == 0 {
+		return ret
+	}
+	for _, t := range types {
+		if ret["type"] == t {
+			return ret
+		}
+	}
+	this.fail("the type %v is not one of %s", ret["type"], strings.Join(types, ", "))
+	return nil
+}
+
+func (this *shexjReader) string(object map[string]interface{}, key string) string {
+	value, found := object[key]
+	if !found {
+		return ""
+	}
+	ret, ok := value.(string)
+	if !ok {
+		this.fail("the %s %v is not a string", key, value)
+	}
+	return ret
+}
+
+func (this *shexjReader) array(object map[string]interface{}, key string) []interface{} {
+	value, found := object[key]
+	if !found {
+		return nil
+	}
+	ret, ok := value.([]interface{})
+	if !ok {
+		this.fail("the %s %v is not an array", key, value)
+	}
+	return ret
+}
+
+func (this *shexjReader) integer(value interface{}) int {
+	number, ok := value.(json.Number)
+	if !ok {
+		this.fail("%v is not an integer", value)
+	}
+	ret, err := number.Int64()
+	if err != nil {
+		this.fail("%v is not an integer", value)
+	}
+	return int(ret)
+}
+
+func (this *shexjReader) label(value interface{}) model.RDFTerm {
+	label, ok := value.(string)
+	if !ok || label == "" {
+		this.fail("%v is not a label", value)
+	}
+	if strings.HasPrefix(label, "_:") {
+		node, found := this.blankNodes[label[2:]]
+		if !found {
+			node = &model.LabelledBlankNode{Label: label[2:]}
+			this.blankNodes[label[2:]] = node
+		}
+		return node
+	}
+	return model.IRI(label)
+}
+
+func (this *shexjReader) schema(object map[string]interface{}) *Schema {
+	ret := &Schema{}
+	if _, found := object["imports"]; found {
+		this.fail("imports are not supported")
+	}
+	if start, found := object["start"]; found {
+		ret.Start = this.shapeExpr(start)
+	}
+	for _, value := range this.array(object, "shapes") {
+		shape := this.object(value)
+		declaration := &ShapeDecl{Label: this.label(shape["id"])}
+		if shape["type"] == "ShapeDecl" {
+			declaration.Expr = this.shapeExpr(shape["shapeExpr"])
+		} else {
+			declaration.Expr = this.shapeExpr(shape)
+		}
+		ret.Shapes = append(ret.Shapes, declaration)
+	}
+	return ret
+}
+
+func (this *shexjReader) shapeExpr(value interface{}) ShapeExpr {
+	if _, ok := value.(string); ok {
+		return &ShapeRef{Label: this.label(value)}
+	}
+	object := this.object(value, "ShapeOr", "ShapeAnd", "ShapeNot", "ShapeExternal", "Shape", "NodeConstraint")
+	switch object["type"] {
+	case "ShapeOr", "ShapeAnd":
+		operands := []ShapeExpr{}
+		for _, operand := range this.array(object, "shapeExprs") {
+			operands = append(operands, this.shapeExpr(operand))
+		}
+		if object["type"] == "ShapeOr" {
+			return &ShapeOr{ShapeExprs: operands}
+		}
+		return &ShapeAnd{ShapeExprs: operands}
+	case "ShapeNot":
+		return &ShapeNot{ShapeExpr: this.shapeExpr(object["shapeExpr"])}
+	case "ShapeExternal":
+		return &ShapeExternal{}
+	case "Shape":
+		ret := &Shape{Closed: object["closed"] == true}
+		for _, extra := range this.array(object, "extra") {
+			iri, ok := extra.(string)
+			if !ok {
+				this.fail("the extra predicate %v is not an IRI", extra)
+			}
+			ret.Extra = append(ret.Extra, model.IRI(iri))
+		}
+		if expression, found := object["expression"]; found {
+			ret.Expression = this.tripleExpr(expression)
+		}
+		return ret
+	}
+	ret := &NodeConstraint{NodeKind: this.string(object, "nodeKind"), Datatype: model.IRI(this.string(object, "datatype"))}
+	switch ret.NodeKind {
+	case "", "iri", "bnode", "nonliteral", "literal":
+	default:
+		this.fail("unknown node kind %q", ret.NodeKind)
+	}
+	for _, name := range []string{"length", "minlength", "maxlength", "pattern", "mininclusive", "minexclusive", "maxinclusive", "maxexclusive", "totaldigits", "fractiondigits"} {
+		value, found := object[name]
+		if !found {
+			continue
+		}
+		facet := Facet{Name: name}
+		switch name {
+		case "pattern":
+			facet.Value = model.NewStringLiteral(this.string(object, name))
+			facet.Flags = this.string(object, "flags")
+		case "mininclusive", "minexclusive", "maxinclusive", "maxexclusive":
+			number, ok := value.(json.Number)
+			if !ok {
+				this.fail("the %s %v is not a number", name, value)
+			}
+			facet.Value = numberLiteral(string(number))
+		default:
+			facet.Value = model.NewTypedLiteral(fmt.Sprint(this.integer(value)), model.XSDInteger)
+		}
+		ret.Facets = append(ret.Facets, facet)
+	}
+	if values, found := object["values"]; found {
+		ret.Values = []ValueSetValue{}
+		list, ok := values.([]interface{})
+		if !ok {
+			this.fail("the values %v are not an array", values)
+		}
+		for _, value := range list {
+			ret.Values = append(ret.Values, this.value(value))
+		}
+	}
+	return ret
+}
+
+// the literal of a JSON number, an xsd:integer, xsd:decimal or xsd:double
+func numberLiteral(number string) model.Literal {
+	switch {
+	case strings.ContainsAny(number, "eE"):
+		return model.NewTypedLiteral(number, model.XSDDouble)
+	case strings.Contains(number, "."):
+		return model.NewTypedLiteral(number, model.XSDDecimal)
+	}
+	return model.NewTypedLiteral(number, model.XSDInteger)
+}
+
+func (this *shexjReader) tripleExpr(value interface{}) TripleExpr {
+	if _, ok := value.(string); ok {
+		return &TripleExprRef{Label: this.label(value)}
+	}
+	object := this.object(value, "EachOf", "OneOf", "TripleConstraint")
+	var label model.RDFTerm
+	if id, found := object["id"]; found {
+		label = this.label(id)
+	}
+	min, max := 1, 1
+	if value, found := object["min"]; found {
+		min = this.integer(value)
+	}
+	if value, found := object["max"]; found {
+		max = this.integer(value)
+	}
+	if min < 0 || max != Unbounded && max < min {
+		this.fail("invalid cardinality {%d,%d}", min, max)
+	}
+	if object["type"] == "TripleConstraint" {
+		ret := &TripleConstraint{Label: label, Inverse: object["inverse"] == true, Predicate: model.IRI(this.string(object, "predicate")), Min: min, Max: max}
+		if ret.Predicate == "" {
+			this.fail("a triple constraint has no predicate")
+		}
+		if valueExpr, found := object["valueExpr"]; found {
+			ret.ValueExpr = this.shapeExpr(valueExpr)
+		}
+		return ret
+	}
+	expressions := []TripleExpr{}
+	for _, expression := range this.array(object, "expressions") {
+		expressions = append(expressions, this.tripleExpr(expression))
+	}
+	if object["type"] == "EachOf" {
+		return &EachOf{Label: label, Expressions: expressions, Min: min, Max: max}
+	}
+	return &OneOf{Label: label, Expressions: expressions, Min: min, Max: max}
+}
+
+var stemKinds = map[string]StemKind{"IriStem": IRIStem, "LiteralStem": LiteralStem, "LanguageStem": LanguageStem}
+
+func (this *shexjReader) value(value interface{}) ValueSetValue {
+	if iri, ok := value.(string); ok {
+		return ObjectValue{Term: model.IRI(iri)}
+	}
+	object := this.object(value)
+	if _, found := object["value"]; found {
+		lexical := this.string(object, "value")
+		if language := this.string(object, "language"); language != "" {
+			return ObjectValue{Term: model.NewLangLiteral(lexical, language, "")}
+		}
+		if datatype := this.string(object, "type"); datatype != "" {
+			return ObjectValue{Term: model.NewTypedLiteral(lexical, model.IRI(datatype))}
+		}
+		return ObjectValue{Term: model.NewStringLiteral(lexical)}
+	}
+	kind := this.string(object, "type")
+	if kind == "Language" {
+		return Language{Tag: this.string(object, "languageTag")}
+	}
+	if stemKind, ok := stemKinds[kind]; ok {
+		return Stem{Kind: stemKind, Value: this.string(object, "stem")}
+	}
+	stemKind, ok := stemKinds[strings.TrimSuffix(kind, "Range")]
+	if !ok || !strings.HasSuffix(kind, "Range") {
+		this.fail("unknown value set value %q", kind)
+	}
+	ret := StemRange{Kind: stemKind}
+	if stem, ok := object["stem"].(string); ok {
+		ret.Stem = stem
+	} else {
+		this.object(object["stem"], "Wildcard")
+		ret.Wildcard = true
+	}
+	for _, exclusion := range this.array(object, "exclusions") {
+		if value, ok := exclusion.(string); ok {
+			ret.Exclusions = append(ret.Exclusions, Exclusion{Value: value})
+			continue
+		}
+		stem := this.object(exclusion, stemKindNames[stemKind])
+		ret.Exclusions = append(ret.Exclusions, Exclusion{Value: this.string(stem, "stem"), Stem: true})
+	}
+	return ret
+}
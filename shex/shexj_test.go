@@ -0,0 +1,69 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShExJ(t *testing.T) {
+	// ShExC, written in ShExJ and read back
+	schema, err := ParseShExC(`PREFIX : <http://ex.org/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+start = @:S
+:S CLOSED { $:id ( :a [:x~ - :xy . - @en] ; ^:b IRI MAXLENGTH 10 ){0,3} | &:id ; :c xsd:decimal MININCLUSIVE +.5 TOTALDIGITS 3 }
+:T NOT @_:u OR BNODE
+_:u { :d [ "v"^^:dt "w"~ ] }
+:E EXTERNAL`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buffer bytes.Buffer
+	if err := schema.WriteShExJ(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), `"mininclusive": 0.5`) {
+		t.Errorf("ShExJ\n%s", buffer.String())
+	}
+	read, err := ParseShExJ(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, expected := shexj(t, read), shexj(t, schema); !reflect.DeepEqual(got, expected) {
+		text, _ := json.Marshal(got)
+		t.Errorf("read back\n%s", text)
+	}
+
+	// the shape declarations of ShEx 2.2
+	read, err = ParseShExJ(strings.NewReader(`{"type": "Schema", "shapes": [
+  {"type": "ShapeDecl", "id": "http://ex.org/S", "shapeExpr": {"type": "Shape", "expression": {"type": "TripleConstraint", "predicate": "http://ex.org/p", "min": 1, "max": -1}}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraint, ok := read.Shapes[0].Expr.(*Shape).Expression.(*TripleConstraint)
+	if !ok || constraint.Predicate != "http://ex.org/p" || constraint.Min != 1 || constraint.Max != Unbounded {
+		t.Errorf("shapes %+v", read.Shapes[0].Expr)
+	}
+
+	for text, message := range map[string]string{
+		`{"type": "Shape"}`: "the type Shape is not one of Schema",
+		`{"type": "Schema", "shapes": [{"type": "Shape", "id": "http://ex.org/S", "expression": {"type": "TripleConstraint"}}]}`:               "has no predicate",
+		`{"type": "Schema", "shapes": [{"type": "NodeConstraint", "id": "http://ex.org/S", "nodeKind": "uri"}]}`:                               "unknown node kind",
+		`{"type": "Schema", "shapes": [{"type": "ShapeNot", "id": "http://ex.org/S", "shapeExpr": "http://ex.org/T"}]}`:                        "the shape <http://ex.org/T> is not declared",
+		`{"type": "Schema", "shapes": [{"type": "NodeConstraint", "id": "http://ex.org/S", "values": [{"type": "IriStemRange", "stem": 1}]}]}`: "is not an object",
+		`{"type": "Schema", "shapes": [{"type": "Shape", "id": "http://ex.org/S", "expression": {"type": "EachOf", "min": 2, "max": 1}}]}`:     "invalid cardinality",
+		`{"type": "Schema", "imports": ["http://ex.org/other"]}`:                                                                               "imports are not supported",
+		`{"type": "Schema"`: "unexpected EOF",
+	} {
+		_, err := ParseShExJ(strings.NewReader(text))
+		if err == nil || !strings.Contains(err.Error(), message) {
+			t.Errorf("%s: error %v instead of %q", text, err, message)
+		}
+	}
+}
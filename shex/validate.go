@@ -0,0 +1,517 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/sparql"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// Association associates a node with a shape label, or with the start
+// shape when Shape is nil
+type Association struct {
+	Node  model.RDFTerm
+	Shape model.RDFTerm
+}
+
+// Result is the conformance of the node of an association
+type Result struct {
+	Association
+	Conforms bool
+	// why the node does not conform
+	Reason string
+}
+
+// Validate checks the associations of a fixed shape map against the data,
+// the union of the graphs of the store. The references to the shapes being
+// checked for a node are assumed to hold.
+func (this *Schema) Validate(data store.Store, shapeMap []Association) ([]*Result, error) {
+	if this.declarations == nil {
+		if err := this.index(); err != nil {
+			return nil, err
+		}
+	}
+	validator := &validator{schema: this, data: data, active: make(map[[2]model.RDFTerm]struct{})}
+	ret := []*Result{}
+	for _, association := range shapeMap {
+		expression := this.Start
+		if association.Shape != nil {
+			if _, found := this.declarations[association.Shape]; !found {
+				return nil, fmt.Errorf("the shape %s is not declared", formatLabel(association.Shape))
+			}
+			expression = &ShapeRef{Label: association.Shape}
+		} else if expression == nil {
+			return nil, fmt.Errorf("the schema has no start shape")
+		}
+		reason, err := validator.satisfies(association.Node, expression)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &Result{Association: association, Conforms: reason == "", Reason: reason})
+	}
+	return ret, nil
+}
+
+type validator struct {
+	schema *Schema
+	data   store.Store
+	// the nodes and shape labels being checked
+	active map[[2]model.RDFTerm]struct{}
+}
+
+// an empty reason when the node satisfies the shape expression, nil
+// standing for any node
+func (this *validator) satisfies(node model.RDFTerm, expression ShapeExpr) (string, error) {
+	switch e := expression.(type) {
+	case nil:
+		return "", nil
+	case *ShapeOr:
+		reasons := []string{}
+		for _, operand := range e.ShapeExprs {
+			reason, err := this.satisfies(node, operand)
+			if err != nil || reason == "" {
+				return "", err
+			}
+			reasons = append(reasons, reason)
+		}
+		return "none of the alternatives hold: " + strings.Join(reasons, "; "), nil
+	case *ShapeAnd:
+		for _, operand := range e.ShapeExprs {
+			reason, err := this.satisfies(node, operand)
+			if err != nil || reason != "" {
+				return reason, err
+			}
+		}
+		return "", nil
+	case *ShapeNot:
+		reason, err := this.satisfies(node, e.ShapeExpr)
+		if err != nil || reason != "" {
+			return "", err
+		}
+		return fmt.Sprintf("%s satisfies a negated shape expression", format(node)), nil
+	case *ShapeRef:
+		key := [2]model.RDFTerm{node, e.Label}
+		if _, found := this.active[key]; found {
+			return "", nil
+		}
+		this.active[key] = struct{}{}
+		defer delete(this.active, key)
+		reason, err := this.satisfies(node, this.schema.declarations[e.Label])
+		if reason != "" {
+			reason = fmt.Sprintf("%s does not conform to %s: %s", format(node), formatLabel(e.Label), reason)
+		}
+		return reason, err
+	case *ShapeExternal:
+		return "", fmt.Errorf("external shapes are not supported")
+	case *NodeConstraint:
+		return this.nodeSatisfies(node, e)
+	}
+	return this.shapeSatisfies(node, expression.(*Shape))
+}
+
+// a triple of the neighbourhood of a node
+type arc struct {
+	predicate model.IRI
+	// the object, or the subject of the incoming arcs
+	node    model.RDFTerm
+	inverse bool
+}
+
+func (this arc) String() string {
+	if this.inverse {
+		return "^" + format(this.predicate) + " " + format(this.node)
+	}
+	return format(this.predicate) + " " + format(this.node)
+}
+
+// the outgoing arcs of a node, and its incoming arcs of some predicates
+func (this *validator) neighbourhood(node model.RDFTerm, incoming map[model.IRI]bool) ([]arc, error) {
+	ret := []arc{}
+	seen := map[arc]struct{}{}
+	add := func(iterator store.Iterator, inverse bool) error {
+		defer iterator.Close()
+		for iterator.Next() {
+			statement := iterator.Statement()
+			predicate, ok := statement.Predicate.(model.IRI)
+			if !ok {
+				continue
+			}
+			a := arc{predicate: predicate, node: statement.Object, inverse: inverse}
+			if inverse {
+				a.node = statement.Subject
+			}
+			if _, found := seen[a]; !found {
+				seen[a] = struct{}{}
+				ret = append(ret, a)
+			}
+		}
+		return iterator.Err()
+	}
+	if _, ok := node.(model.Literal); !ok {
+		if err := add(this.data.Match(node, nil, nil, nil), false); err != nil {
+			return nil, err
+		}
+	}
+	for predicate := range incoming {
+		if err := add(this.data.Match(nil, predicate, node, nil), true); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// the arcs with the predicates of the triple constraints are each assigned
+// to a triple constraint their node satisfies, the assignments are tried
+// until the numbers of arcs of the triple constraints match the triple
+// expression. Arcs whose predicate is extra may stay unassigned, the
+// incoming arcs which satisfy no triple constraint are ignored.
+func (this *validator) shapeSatisfies(node model.RDFTerm, shape *Shape) (string, error) {
+	constraints := this.schema.tripleConstraints(shape.Expression, map[model.RDFTerm]bool{})
+	outgoing, incoming := map[model.IRI]bool{}, map[model.IRI]bool{}
+	for _, constraint := range constraints {
+		if constraint.Inverse {
+			incoming[constraint.Predicate] = true
+		} else {
+			outgoing[constraint.Predicate] = true
+		}
+	}
+	arcs, err := this.neighbourhood(node, incoming)
+	if err != nil {
+		return "", err
+	}
+	extra := map[model.IRI]bool{}
+	for _, predicate := range shape.Extra {
+		extra[predicate] = true
+	}
+	type choice struct {
+		candidates []*TripleConstraint
+		optional   bool
+	}
+	choices := []choice{}
+	for _, a := range arcs {
+		if !a.inverse && !outgoing[a.predicate] {
+			if shape.Closed {
+				return fmt.Sprintf("the triple %s is not allowed by a closed shape", a), nil
+			}
+			continue
+		}
+		candidates := []*TripleConstraint{}
+		for _, constraint := range constraints {
+			if constraint.Inverse != a.inverse || constraint.Predicate != a.predicate {
+				continue
+			}
+			reason, err := this.satisfies(a.node, constraint.ValueExpr)
+			if err != nil {
+				return "", err
+			}
+			if reason == "" {
+				candidates = append(candidates, constraint)
+			}
+		}
+		optional := !a.inverse && extra[a.predicate]
+		if len(candidates) == 0 {
+			if optional || a.inverse {
+				continue
+			}
+			return fmt.Sprintf("the triple %s satisfies no triple constraint", a), nil
+		}
+		choices = append(choices, choice{candidates: candidates, optional: optional})
+	}
+	counts := map[*TripleConstraint]int{}
+	var assign func(i int) bool
+	assign = func(i int) bool {
+		if i == len(choices) {
+			if shape.Expression == nil {
+				return true
+			}
+			min, max := this.interval(shape.Expression, counts)
+			return min <= 1 && 1 <= max
+		}
+		for _, candidate := range choices[i].candidates {
+			counts[candidate]++
+			matches := assign(i + 1)
+			counts[candidate]--
+			if matches {
+				return true
+			}
+		}
+		return choices[i].optional && assign(i+1)
+	}
+	if !assign(0) {
+		return fmt.Sprintf("the triples of %s do not match the triple expression", format(node)), nil
+	}
+	return "", nil
+}
+
+// stands for the unbounded maximums of the intervals
+const unbounded = math.MaxInt32
+
+// the numbers of times a triple expression matches the arcs assigned to its
+// triple constraints, an interval as the triple constraints occur once.
+// min > max when there is none.
+func (this *validator) interval(expression TripleExpr, counts map[*TripleConstraint]int) (int, int) {
+	var min, max, cardinalityMin, cardinalityMax int
+	switch e := expression.(type) {
+	case *TripleExprRef:
+		return this.interval(this.schema.tripleExprs[e.Label], counts)
+	case *TripleConstraint:
+		min, max = counts[e], counts[e]
+		cardinalityMin, cardinalityMax = e.Min, e.Max
+	case *EachOf:
+		// each expression matches as many times
+		min, max = 0, unbounded
+		for _, child := range e.Expressions {
+			childMin, childMax := this.interval(child, counts)
+			if childMin > min {
+				min = childMin
+			}
+			if childMax < max {
+				max = childMax
+			}
+		}
+		cardinalityMin, cardinalityMax = e.Min, e.Max
+	case *OneOf:
+		// the expressions share the matches
+		for _, child := range e.Expressions {
+			childMin, childMax := this.interval(child, counts)
+			if childMin > childMax {
+				return 1, 0
+			}
+			min, max = saturatedSum(min, childMin), saturatedSum(max, childMax)
+		}
+		cardinalityMin, cardinalityMax = e.Min, e.Max
+	}
+	if cardinalityMax == Unbounded {
+		cardinalityMax = unbounded
+	}
+	return repeat(min, max, cardinalityMin, cardinalityMax)
+}
+
+func saturatedSum(a, b int) int {
+	if a >= unbounded || b >= unbounded {
+		return unbounded
+	}
+	return a + b
+}
+
+// the numbers k of matches of e{m,n} when e matches between min and max
+// times: k·m ≤ max and k·n ≥ min
+func repeat(min, max, m, n int) (int, int) {
+	if min > max {
+		return 1, 0
+	}
+	low := 0
+	switch {
+	case min == 0:
+	case n == 0:
+		return 1, 0
+	case n == unbounded:
+		low = 1
+	default:
+		low = (min + n - 1) / n
+	}
+	high := unbounded
+	if m > 0 && max < unbounded {
+		high = max / m
+	}
+	return low, high
+}
+
+func (this *validator) nodeSatisfies(node model.RDFTerm, constraint *NodeConstraint) (string, error) {
+	literal, isLiteral := node.(model.Literal)
+	switch constraint.NodeKind {
+	case "iri":
+		if _, ok := node.(model.IRI); !ok {
+			return fmt.Sprintf("%s is not an IRI", format(node)), nil
+		}
+	case "bnode":
+		if !model.IsBlankNode(node) {
+			return fmt.Sprintf("%s is not a blank node", format(node)), nil
+		}
+	case "nonliteral":
+		if isLiteral {
+			return fmt.Sprintf("%s is a literal", format(node)), nil
+		}
+	case "literal":
+		if !isLiteral {
+			return fmt.Sprintf("%s is not a literal", format(node)), nil
+		}
+	}
+	if constraint.Datatype != "" && (!isLiteral || literal.Datatype != constraint.Datatype) {
+		return fmt.Sprintf("%s is not a literal of datatype %s", format(node), format(constraint.Datatype)), nil
+	}
+	if constraint.Datatype != "" && literal.IllTyped() {
+		return fmt.Sprintf("%s is ill-typed", format(node)), nil
+	}
+	for _, facet := range constraint.Facets {
+		reason, err := this.facetSatisfied(node, facet)
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	if constraint.Values == nil {
+		return "", nil
+	}
+	for _, value := range constraint.Values {
+		if valueMatches(node, value) {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("%s is not in the value set", format(node)), nil
+}
+
+func (this *validator) facetSatisfied(node model.RDFTerm, facet Facet) (string, error) {
+	literal, isLiteral := node.(model.Literal)
+	switch facet.Name {
+	case "length", "minlength", "maxlength", "pattern":
+		lexical := literal.Lexical
+		if iri, ok := node.(model.IRI); ok {
+			lexical = string(iri)
+		} else if !isLiteral {
+			return fmt.Sprintf("%s has no lexical form", format(node)), nil
+		}
+		if facet.Name == "pattern" {
+			pattern, err := this.schema.pattern(facet)
+			if err != nil {
+				return "", err
+			}
+			if !pattern.MatchString(lexical) {
+				return fmt.Sprintf("%s does not match /%s/%s", format(node), facet.Value.Lexical, facet.Flags), nil
+			}
+			return "", nil
+		}
+		bound, _ := strconv.Atoi(facet.Value.Lexical)
+		length := utf8.RuneCountInString(lexical)
+		if facet.Name == "length" && length != bound || facet.Name == "minlength" && length < bound || facet.Name == "maxlength" && length > bound {
+			return fmt.Sprintf("the length of %s is %d, %s %d", format(node), length, facet.Name, bound), nil
+		}
+	case "mininclusive", "minexclusive", "maxinclusive", "maxexclusive":
+		c, ok := sparql.Compare(node, facet.Value)
+		if !ok || !isNumber(node) {
+			return fmt.Sprintf("%s is not a number", format(node)), nil
+		}
+		if facet.Name == "mininclusive" && c < 0 || facet.Name == "minexclusive" && c <= 0 || facet.Name == "maxinclusive" && c > 0 || facet.Name == "maxexclusive" && c >= 0 {
+			return fmt.Sprintf("%s is out of %s %s", format(node), facet.Name, facet.Value.Lexical), nil
+		}
+	case "totaldigits", "fractiondigits":
+		total, fraction, ok := digits(literal)
+		if !isLiteral || !ok {
+			return fmt.Sprintf("%s is not a decimal", format(node)), nil
+		}
+		bound, _ := strconv.Atoi(facet.Value.Lexical)
+		if facet.Name == "totaldigits" && total > bound || facet.Name == "fractiondigits" && fraction > bound {
+			return fmt.Sprintf("%s has more than %d %s", format(node), bound, facet.Name), nil
+		}
+	}
+	return "", nil
+}
+
+// well-typed literals of the numeric datatypes
+func isNumber(node model.RDFTerm) bool {
+	literal, ok := node.(model.Literal)
+	if !ok {
+		return false
+	}
+	switch value, _ := literal.Value(); value.(type) {
+	case *big.Int, *big.Rat, float64, float32:
+		return true
+	}
+	return false
+}
+
+// the total and fraction digits of a well-typed xsd:decimal or integer,
+// counted in its canonical form
+func digits(literal model.Literal) (int, int, bool) {
+	switch value, _ := literal.Value(); value.(type) {
+	case *big.Int, *big.Rat:
+	default:
+		return 0, 0, false
+	}
+	lexical := strings.TrimPrefix(literal.Canonical().Lexical, "-")
+	integer, fraction := lexical, ""
+	if dot := strings.IndexByte(lexical, '.'); dot >= 0 {
+		integer, fraction = lexical[:dot], lexical[dot+1:]
+	}
+	integer, fraction = strings.TrimLeft(integer, "0"), strings.TrimRight(fraction, "0")
+	total := len(integer) + len(fraction)
+	if total == 0 {
+		total = 1
+	}
+	return total, len(fraction), true
+}
+
+func valueMatches(node model.RDFTerm, value ValueSetValue) bool {
+	switch v := value.(type) {
+	case ObjectValue:
+		return node == v.Term
+	case Language:
+		literal, ok := node.(model.Literal)
+		return ok && literal.Language != "" && strings.EqualFold(literal.Language, v.Tag)
+	case Stem:
+		return stemMatches(v.Kind, v.Value, node)
+	}
+	v := value.(StemRange)
+	if !(v.Wildcard && kindMatches(v.Kind, node) || !v.Wildcard && stemMatches(v.Kind, v.Stem, node)) {
+		return false
+	}
+	for _, exclusion := range v.Exclusions {
+		if exclusion.Stem && stemMatches(v.Kind, exclusion.Value, node) || !exclusion.Stem && valueEquals(v.Kind, exclusion.Value, node) {
+			return false
+		}
+	}
+	return true
+}
+
+// the IRI, lexical form or language tag of a node for a kind of stem
+func stemValue(kind StemKind, node model.RDFTerm) (string, bool) {
+	switch t := node.(type) {
+	case model.IRI:
+		return string(t), kind == IRIStem
+	case model.Literal:
+		if kind == LanguageStem {
+			return t.Language, t.Language != ""
+		}
+		return t.Lexical, kind == LiteralStem
+	}
+	return "", false
+}
+
+func kindMatches(kind StemKind, node model.RDFTerm) bool {
+	_, ok := stemValue(kind, node)
+	return ok
+}
+
+func stemMatches(kind StemKind, stem string, node model.RDFTerm) bool {
+	value, ok := stemValue(kind, node)
+	if !ok {
+		return false
+	}
+	if kind != LanguageStem {
+		return strings.HasPrefix(value, stem)
+	}
+	value, stem = strings.ToLower(value), strings.ToLower(stem)
+	return stem == "" || value == stem || strings.HasPrefix(value, stem+"-")
+}
+
+func valueEquals(kind StemKind, excluded string, node model.RDFTerm) bool {
+	value, ok := stemValue(kind, node)
+	if kind == LanguageStem {
+		return ok && strings.EqualFold(value, excluded)
+	}
+	return ok && value == excluded
+}
+
+func format(term model.RDFTerm) string {
+	return writer.FormatTerm(term, writer.NewBlankNodeLabels())
+}
@@ -0,0 +1,147 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package shex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+const prefixes = `PREFIX : <http://ex.org/>
+PREFIX xsd: <http://www.w3.org/2001/XMLSchema#>
+`
+
+func data(t *testing.T, text string) store.Store {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader("@prefix : <http://ex.org/> .\n"+text), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := store.NewMemory()
+	for _, statement := range statements {
+		ret.Add(statement)
+	}
+	return ret
+}
+
+// the nodes of the shape map which conform, and the reasons of the others
+func validate(t *testing.T, schema, text, shapeMap string) []string {
+	t.Helper()
+	parsed, err := ParseShExC(prefixes+schema, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	associations, err := ParseShapeMap(shapeMap, Options{Namespaces: parsed.Namespaces})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := parsed.Validate(data(t, text), associations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := []string{}
+	for _, result := range results {
+		line := strings.ReplaceAll(FormatAssociation(result.Association), "http://ex.org/", ":")
+		if !result.Conforms {
+			line += " " + strings.ReplaceAll(result.Reason, "http://ex.org/", ":")
+		}
+		ret = append(ret, line)
+	}
+	return ret
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		schema   string
+		data     string
+		shapeMap string
+		expected []string
+	}{
+		{"cardinality", `:S { :name LITERAL ; :knows IRI * }`,
+			`:a :name "a" ; :knows :b, :c . :b :knows :a . :c :name "c1", "c2" .`, `:a@:S, :b@:S, :c@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triples of <:b> do not match the triple expression`,
+				`<:c>@<:S> <:c> does not conform to <:S>: the triples of <:c> do not match the triple expression`}},
+		{"recursion", `:Person { :knows @:Person * ; :name xsd:string }`,
+			`:a :knows :b ; :name "a" . :b :knows :a ; :name "b" . :c :knows :d ; :name "c" . :d :name 1 .`, `:a@:Person, :c@:Person`, []string{
+				`<:a>@<:Person>`,
+				`<:c>@<:Person> <:c> does not conform to <:Person>: the triple <:knows> <:d> satisfies no triple constraint`}},
+		{"one of", `:S { :given . ; :family . | :name . }`,
+			`:a :name "a" . :b :given "b" ; :family "b" . :c :given "c" ; :name "c" .`, `:a@:S, :b@:S, :c@:S`, []string{
+				`<:a>@<:S>`, `<:b>@<:S>`,
+				`<:c>@<:S> <:c> does not conform to <:S>: the triples of <:c> do not match the triple expression`}},
+		{"repeated group", `:S { (:x . ; :y .){2,3} }`,
+			`:a :x 1, 2 ; :y 1, 2 . :b :x 1 ; :y 1 .`, `:a@:S, :b@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triples of <:b> do not match the triple expression`}},
+		{"same predicate", `:S { :p [1 2] ; :p [3] }`,
+			`:a :p 1, 3 . :b :p 1, 2 .`, `:a@:S, :b@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triples of <:b> do not match the triple expression`}},
+		{"closed and extra", `:S CLOSED EXTRA :p { :p [1] }`,
+			`:a :p 1, 2 . :b :p 1 ; :q 1 .`, `:a@:S, :b@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triple <:q> "1"^^<http://www.w3.org/2001/XMLSchema#integer> is not allowed by a closed shape`}},
+		{"inverse", `:S { ^:parent @:S ? }`,
+			`:b :parent :a . :c :parent :a .`, `:a@:S`, []string{
+				`<:a>@<:S> <:a> does not conform to <:S>: the triples of <:a> do not match the triple expression`}},
+		{"facets", `:S { :code /^[A-Z]{2}$/ MAXLENGTH 2 ; :age xsd:integer MININCLUSIVE 18 ; :price xsd:decimal TOTALDIGITS 4 FRACTIONDIGITS 2 }`,
+			`:a :code "FR" ; :age 20 ; :price 12.50 . :b :code "fr" ; :age 20 ; :price 1.5 . :c :code "FR" ; :age 12 ; :price 1.5 . :d :code "FR" ; :age 20 ; :price 123.45 .`, `:a@:S, :b@:S, :c@:S, :d@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triple <:code> "fr" satisfies no triple constraint`,
+				`<:c>@<:S> <:c> does not conform to <:S>: the triple <:age> "12"^^<http://www.w3.org/2001/XMLSchema#integer> satisfies no triple constraint`,
+				`<:d>@<:S> <:d> does not conform to <:S>: the triple <:price> "123.45"^^<http://www.w3.org/2001/XMLSchema#decimal> satisfies no triple constraint`}},
+		{"ill-typed", `:S { :born xsd:date }`,
+			`:a :born "2024-02-29"^^<http://www.w3.org/2001/XMLSchema#date> . :b :born "2023-02-29"^^<http://www.w3.org/2001/XMLSchema#date> .`, `:a@:S, :b@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triple <:born> "2023-02-29"^^<http://www.w3.org/2001/XMLSchema#date> satisfies no triple constraint`}},
+		{"value sets", `:S { :status [:active :pending~ - :pendingReview] ; :label [@en~ @fr] ; :code [. - "x" - "y"~] }`,
+			`:a :status :pendingPayment ; :label "hi"@en-GB ; :code "z" . :b :status :pendingReview ; :label "hi"@en ; :code "z" . :c :status :active ; :label "hi"@de ; :code "z" . :d :status :active ; :label "hi"@fr ; :code "yes" .`, `:a@:S, :b@:S, :c@:S, :d@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: the triple <:status> <:pendingReview> satisfies no triple constraint`,
+				`<:c>@<:S> <:c> does not conform to <:S>: the triple <:label> "hi"@de satisfies no triple constraint`,
+				`<:d>@<:S> <:d> does not conform to <:S>: the triple <:code> "yes" satisfies no triple constraint`}},
+		{"and or not", `:S IRI AND NOT @:T OR LITERAL
+:T { :p . }`,
+			`:a :q 1 . :b :p 1 .`, `:a@:S, :b@:S, "x"@:S`, []string{
+				`<:a>@<:S>`,
+				`<:b>@<:S> <:b> does not conform to <:S>: none of the alternatives hold: <:b> satisfies a negated shape expression; <:b> is not a literal`,
+				`"x"@<:S>`}},
+		{"start and includes", `start = @:Employee
+:Person { $:name :name . }
+:Employee { &:name ; :employer IRI }`,
+			`:a :name "a" ; :employer :x . :b :name "b" .`, `:a@START, :b@START`, []string{
+				`<:a>@START`,
+				`<:b>@START <:b> does not conform to <:Employee>: the triples of <:b> do not match the triple expression`}},
+	}
+	for _, c := range cases {
+		got := validate(t, c.schema, c.data, c.shapeMap)
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%s: results\n%s\ninstead of\n%s", c.name, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+		}
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	schema, err := ParseShExC(prefixes+`:S { :p @:E } :E EXTERNAL`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataset := data(t, `:a :p :b .`)
+	for association, message := range map[Association]string{
+		{Node: model.IRI("http://ex.org/a"), Shape: model.IRI("http://ex.org/T")}: "the shape <http://ex.org/T> is not declared",
+		{Node: model.IRI("http://ex.org/a")}:                                      "the schema has no start shape",
+		{Node: model.IRI("http://ex.org/a"), Shape: model.IRI("http://ex.org/S")}: "external shapes are not supported",
+	} {
+		if _, err := schema.Validate(dataset, []Association{association}); err == nil || err.Error() != message {
+			t.Errorf("%s: error %v instead of %q", FormatAssociation(association), err, message)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// the solutions of a group bind its keys and its aggregates, errors
+// leaving them unbound
+func (this *evaluator) group(op *Group, graphs []model.RDFTerm) []Solution {
+	solutions := this.evaluate(op.Input, graphs)
+	type group struct {
+		keys      []model.RDFTerm
+		solutions []Solution
+	}
+	groups := []*group{}
+	index := map[string]*group{}
+	for _, solution := range solutions {
+		keys := make([]model.RDFTerm, len(op.Keys))
+		parts := make([]string, len(op.Keys))
+		for i, key := range op.Keys {
+			keys[i], _ = this.expression(key.Expression, solution, graphs)
+			parts[i] = termKey(keys[i])
+		}
+		k := strings.Join(parts, " ")
+		g, ok := index[k]
+		if !ok {
+			g = &group{keys: keys}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.solutions = append(g.solutions, solution)
+	}
+	// without keys, there is a group even without solutions
+	if len(op.Keys) == 0 && len(groups) == 0 {
+		groups = append(groups, &group{})
+	}
+	ret := []Solution{}
+	for _, g := range groups {
+		solution := Solution{}
+		for i, key := range op.Keys {
+			if key.Variable != "" && g.keys[i] != nil {
+				solution[key.Variable] = g.keys[i]
+			}
+		}
+		for _, aggregate := range op.Aggregates {
+			if value, err := this.aggregate(aggregate, g.solutions, graphs); err == nil {
+				solution[aggregate.Variable] = value
+			}
+		}
+		ret = append(ret, solution)
+	}
+	return ret
+}
+
+func (this *evaluator) aggregate(aggregate *Aggregate, solutions []Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	if aggregate.Expression == nil {
+		// COUNT(*)
+		if aggregate.Distinct {
+			solutions = distinct(solutions)
+		}
+		return integerValue(int64(len(solutions))).literal(), nil
+	}
+	// the values of the expression, errors apart
+	values := []model.RDFTerm{}
+	seen := map[string]bool{}
+	for _, solution := range solutions {
+		value, err := this.expression(aggregate.Expression, solution, graphs)
+		if err != nil {
+			continue
+		}
+		if aggregate.Distinct {
+			key := termKey(value)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		values = append(values, value)
+	}
+	switch aggregate.Function {
+	case "COUNT":
+		return integerValue(int64(len(values))).literal(), nil
+	case "SUM", "AVG":
+		sum := integerValue(0)
+		for _, value := range values {
+			number, ok := numericValue(value)
+			if !ok {
+				return nil, errType
+			}
+			sum, _ = arithmetic("+", sum, number)
+		}
+		if aggregate.Function == "AVG" && len(values) > 0 {
+			sum, _ = arithmetic("/", sum, integerValue(int64(len(values))))
+		}
+		return sum.literal(), nil
+	case "MIN", "MAX":
+		if len(values) == 0 {
+			return nil, errUnbound
+		}
+		ret := values[0]
+		for _, value := range values[1:] {
+			c := compareOrder(value, ret)
+			if aggregate.Function == "MIN" && c < 0 || aggregate.Function == "MAX" && c > 0 {
+				ret = value
+			}
+		}
+		return ret, nil
+	case "SAMPLE":
+		if len(values) == 0 {
+			return nil, errUnbound
+		}
+		return values[0], nil
+	case "GROUP_CONCAT":
+		parts := make([]string, len(values))
+		for i, value := range values {
+			literal, ok := stringArgument(value)
+			if !ok {
+				return nil, errType
+			}
+			parts[i] = literal.Lexical
+		}
+		return model.NewStringLiteral(strings.Join(parts, aggregate.Separator)), nil
+	}
+	return nil, errType
+}
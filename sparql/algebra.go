@@ -0,0 +1,396 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// Operator is a node of the SPARQL algebra, String gives it as an
+// S-expression
+type Operator interface {
+	String() string
+}
+
+// Variables stand for terms in patterns and expressions. The blank nodes
+// of the patterns become hidden variables, as do aggregates.
+type Variable string
+
+func (this Variable) String() string {
+	return "?" + string(this)
+}
+
+// hidden variables are not returned by SELECT *
+func (this Variable) Hidden() bool {
+	return strings.HasPrefix(string(this), "_:") || strings.HasPrefix(string(this), ".")
+}
+
+// a triple of terms or variables, the predicate may be a Path
+type TriplePattern struct {
+	Subject   model.RDFTerm
+	Predicate model.RDFTerm
+	Object    model.RDFTerm
+}
+
+func (this *TriplePattern) String() string {
+	if path, ok := this.Predicate.(Path); ok {
+		return sexp("path", formatTerm(this.Subject), path.String(), formatTerm(this.Object))
+	}
+	return "(triple " + formatTerm(this.Subject) + " " + formatTerm(this.Predicate) + " " + formatTerm(this.Object) + ")"
+}
+
+// a basic graph pattern, the empty one matches once
+type BGP struct {
+	Patterns []*TriplePattern
+}
+
+type Join struct {
+	Left  Operator
+	Right Operator
+}
+
+// the solutions of Left extended by the compatible ones of Right for which
+// Expression holds, nil being true
+type LeftJoin struct {
+	Left       Operator
+	Right      Operator
+	Expression Expression
+}
+
+type Filter struct {
+	Expression Expression
+	Input      Operator
+}
+
+type Union struct {
+	Left  Operator
+	Right Operator
+}
+
+// the solutions of Left which are not compatible with a solution of Right
+// sharing a variable
+type Minus struct {
+	Left  Operator
+	Right Operator
+}
+
+// Input evaluated in the named graph Name, an IRI or a variable
+type Graph struct {
+	Name  model.RDFTerm
+	Input Operator
+}
+
+// binds Variable to the value of Expression
+type Extend struct {
+	Input      Operator
+	Variable   Variable
+	Expression Expression
+}
+
+// the solutions of VALUES, nil for UNDEF
+type Table struct {
+	Variables []Variable
+	Rows      [][]model.RDFTerm
+}
+
+// groups the solutions of Input by the values of the keys, the solutions
+// of a Group bind the key variables and the aggregates. Without keys
+// there is a single group.
+type Group struct {
+	Input      Operator
+	Keys       []*GroupKey
+	Aggregates []*Aggregate
+}
+
+type GroupKey struct {
+	Expression Expression
+	// bound to the value of the key, empty when the key is an expression
+	// without AS
+	Variable Variable
+}
+
+type Aggregate struct {
+	// the hidden variable standing for the aggregate in expressions
+	Variable Variable
+	// COUNT, SUM, MIN, MAX, AVG, SAMPLE or GROUP_CONCAT
+	Function string
+	Distinct bool
+	// nil for COUNT(*)
+	Expression Expression
+	Separator  string
+}
+
+type OrderBy struct {
+	Input      Operator
+	Conditions []*OrderCondition
+}
+
+type OrderCondition struct {
+	Expression Expression
+	Descending bool
+}
+
+type Project struct {
+	Input     Operator
+	Variables []Variable
+}
+
+type Distinct struct {
+	Input Operator
+}
+
+type Reduced struct {
+	Input Operator
+}
+
+// Limit is -1 without LIMIT
+type Slice struct {
+	Input  Operator
+	Offset int
+	Limit  int
+}
+
+func (this *BGP) String() string {
+	parts := []string{"bgp"}
+	for _, pattern := range this.Patterns {
+		parts = append(parts, pattern.String())
+	}
+	return sexp(parts...)
+}
+
+func (this *Join) String() string {
+	return sexp("join", this.Left.String(), this.Right.String())
+}
+
+func (this *LeftJoin) String() string {
+	if this.Expression == nil {
+		return sexp("leftjoin", this.Left.String(), this.Right.String())
+	}
+	return sexp("leftjoin", this.Left.String(), this.Right.String(), this.Expression.String())
+}
+
+func (this *Filter) String() string {
+	return sexp("filter", this.Expression.String(), this.Input.String())
+}
+
+func (this *Union) String() string {
+	return sexp("union", this.Left.String(), this.Right.String())
+}
+
+func (this *Minus) String() string {
+	return sexp("minus", this.Left.String(), this.Right.String())
+}
+
+func (this *Graph) String() string {
+	return sexp("graph", formatTerm(this.Name), this.Input.String())
+}
+
+func (this *Extend) String() string {
+	return sexp("extend", sexp(sexp(this.Variable.String(), this.Expression.String())), this.Input.String())
+}
+
+func (this *Table) String() string {
+	variables := []string{"vars"}
+	for _, variable := range this.Variables {
+		variables = append(variables, variable.String())
+	}
+	parts := []string{"table", sexp(variables...)}
+	for _, row := range this.Rows {
+		values := []string{"row"}
+		for _, value := range row {
+			if value == nil {
+				values = append(values, "UNDEF")
+			} else {
+				values = append(values, formatTerm(value))
+			}
+		}
+		parts = append(parts, sexp(values...))
+	}
+	return sexp(parts...)
+}
+
+func (this *Group) String() string {
+	keys := []string{}
+	for _, key := range this.Keys {
+		if key.Variable == "" {
+			keys = append(keys, key.Expression.String())
+		} else if key.Expression == key.Variable {
+			keys = append(keys, key.Variable.String())
+		} else {
+			keys = append(keys, sexp(key.Variable.String(), key.Expression.String()))
+		}
+	}
+	aggregates := []string{}
+	for _, aggregate := range this.Aggregates {
+		aggregates = append(aggregates, sexp(aggregate.Variable.String(), aggregate.String()))
+	}
+	return sexp("group", sexp(keys...), sexp(aggregates...), this.Input.String())
+}
+
+func (this *Aggregate) String() string {
+	parts := []string{strings.ToLower(this.Function)}
+	if this.Distinct {
+		parts = append(parts, "distinct")
+	}
+	if this.Expression != nil {
+		parts = append(parts, this.Expression.String())
+	}
+	if this.Function == "GROUP_CONCAT" && this.Separator != " " {
+		parts = append(parts, "separator", strconv.Quote(this.Separator))
+	}
+	return sexp(parts...)
+}
+
+func (this *OrderBy) String() string {
+	conditions := []string{}
+	for _, condition := range this.Conditions {
+		if condition.Descending {
+			conditions = append(conditions, sexp("desc", condition.Expression.String()))
+		} else {
+			conditions = append(conditions, condition.Expression.String())
+		}
+	}
+	return sexp("order", sexp(conditions...), this.Input.String())
+}
+
+func (this *Project) String() string {
+	return sexp("project", formatVariables(this.Variables), this.Input.String())
+}
+
+func (this *Distinct) String() string {
+	return sexp("distinct", this.Input.String())
+}
+
+func (this *Reduced) String() string {
+	return sexp("reduced", this.Input.String())
+}
+
+func (this *Slice) String() string {
+	limit := "_"
+	if this.Limit >= 0 {
+		limit = strconv.Itoa(this.Limit)
+	}
+	return sexp("slice", strconv.Itoa(this.Offset), limit, this.Input.String())
+}
+
+func sexp(parts ...string) string {
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+func formatVariables(variables []Variable) string {
+	parts := make([]string, len(variables))
+	for i, variable := range variables {
+		parts[i] = variable.String()
+	}
+	return sexp(parts...)
+}
+
+// the N-Triples form of terms, variables included
+func formatTerm(term model.RDFTerm) string {
+	switch t := term.(type) {
+	case Variable:
+		return t.String()
+	case model.TripleTerm:
+		return "<<( " + formatTerm(t.Subject) + " " + formatTerm(t.Predicate) + " " + formatTerm(t.Object) + " )>>"
+	case *model.LabelledBlankNode:
+		return "_:" + t.Label
+	case *model.AnonymousBlankNode:
+		return "[]"
+	case model.Literal:
+		// numbers and booleans as written in queries
+		if t.Datatype == model.XSDBoolean && (t.Lexical == "true" || t.Lexical == "false") {
+			return t.Lexical
+		}
+		if _, err := strconv.ParseFloat(t.Lexical, 64); err == nil && numberLiteral(t.Lexical) == t {
+			return t.Lexical
+		}
+	}
+	return writer.FormatTerm(term, nil)
+}
+
+// the variables bound by the solutions of operator, hidden ones included,
+// in order of appearance
+func inScope(operator Operator) []Variable {
+	ret := []Variable{}
+	seen := map[Variable]bool{}
+	add := func(variables ...Variable) {
+		for _, variable := range variables {
+			if !seen[variable] {
+				seen[variable] = true
+				ret = append(ret, variable)
+			}
+		}
+	}
+	var visit func(operator Operator)
+	visit = func(operator Operator) {
+		switch op := operator.(type) {
+		case *BGP:
+			for _, pattern := range op.Patterns {
+				add(termVariables(pattern.Subject)...)
+				add(termVariables(pattern.Predicate)...)
+				add(termVariables(pattern.Object)...)
+			}
+		case *Join:
+			visit(op.Left)
+			visit(op.Right)
+		case *LeftJoin:
+			visit(op.Left)
+			visit(op.Right)
+		case *Union:
+			visit(op.Left)
+			visit(op.Right)
+		case *Minus:
+			visit(op.Left)
+		case *Filter:
+			visit(op.Input)
+		case *Graph:
+			add(termVariables(op.Name)...)
+			visit(op.Input)
+		case *Extend:
+			visit(op.Input)
+			add(op.Variable)
+		case *Table:
+			add(op.Variables...)
+		case *Group:
+			for _, key := range op.Keys {
+				if key.Variable != "" {
+					add(key.Variable)
+				}
+			}
+			for _, aggregate := range op.Aggregates {
+				add(aggregate.Variable)
+			}
+		case *OrderBy:
+			visit(op.Input)
+		case *Project:
+			add(op.Variables...)
+		case *Distinct:
+			visit(op.Input)
+		case *Reduced:
+			visit(op.Input)
+		case *Slice:
+			visit(op.Input)
+		}
+	}
+	visit(operator)
+	return ret
+}
+
+// the variables of a term of a pattern
+func termVariables(term model.RDFTerm) []Variable {
+	switch t := term.(type) {
+	case Variable:
+		return []Variable{t}
+	case model.TripleTerm:
+		ret := termVariables(t.Subject)
+		ret = append(ret, termVariables(t.Predicate)...)
+		return append(ret, termVariables(t.Object)...)
+	}
+	return nil
+}
@@ -0,0 +1,738 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// Solution binds variables to terms, unbound variables are missing
+type Solution map[Variable]model.RDFTerm
+
+// the answer to a query
+type Result struct {
+	Form QueryForm
+	// the variables and the solutions of SELECT
+	Variables []Variable
+	Solutions []Solution
+	// the answer of ASK
+	Boolean bool
+	// the graph of CONSTRUCT and DESCRIBE
+	Statements []*model.Statement
+}
+
+// Evaluate answers a query over a dataset. Without FROM nor FROM NAMED
+// the default graph of the query is the default graph of the dataset and
+// its named graphs are those of the dataset; otherwise the default graph
+// is the merge of the graphs of FROM and the named graphs those of FROM
+// NAMED. Errors are those of the store.
+func Evaluate(query *Query, dataset store.Store) (ret *Result, err error) {
+	this := newEvaluator(dataset, query)
+	defer recoverStore(&err)
+	ret = &Result{Form: query.Form}
+	solutions := this.evaluate(query.Pattern, this.defaultGraphs)
+	switch query.Form {
+	case Select:
+		ret.Variables = query.Variables
+		ret.Solutions = solutions
+	case Ask:
+		ret.Boolean = len(solutions) > 0
+	case Construct:
+		ret.Statements = this.construct(query.Template, solutions)
+	case Describe:
+		ret.Statements = this.describe(query.Describe, solutions)
+	}
+	return ret, nil
+}
+
+// Substitute pre-binds variables of a query: they are replaced by their
+// values in its pattern, sub-queries apart, and in its template, and its
+// projection still returns them. The query is not modified.
+func Substitute(query *Query, bindings Solution) *Query {
+	ret := *query
+	ret.Pattern = substituteQuery(query.Pattern, bindings)
+	if query.Template != nil {
+		ret.Template = make([]*TriplePattern, len(query.Template))
+		for i, pattern := range query.Template {
+			ret.Template[i] = &TriplePattern{
+				Subject:   substitutePattern(pattern.Subject, bindings),
+				Predicate: substitutePattern(pattern.Predicate, bindings),
+				Object:    substitutePattern(pattern.Object, bindings),
+			}
+		}
+	}
+	return &ret
+}
+
+// substitutes the solution modifiers of a query, then its pattern
+func substituteQuery(operator Operator, solution Solution) Operator {
+	switch op := operator.(type) {
+	case *Slice:
+		return &Slice{Input: substituteQuery(op.Input, solution), Offset: op.Offset, Limit: op.Limit}
+	case *Distinct:
+		return &Distinct{Input: substituteQuery(op.Input, solution)}
+	case *Reduced:
+		return &Reduced{Input: substituteQuery(op.Input, solution)}
+	case *Project:
+		input := substituteQuery(op.Input, solution)
+		for _, variable := range op.Variables {
+			if value, ok := solution[variable]; ok {
+				input = &Extend{Input: input, Variable: variable, Expression: &Constant{Term: value}}
+			}
+		}
+		return &Project{Input: input, Variables: op.Variables}
+	case *OrderBy:
+		ret := &OrderBy{Input: substituteQuery(op.Input, solution)}
+		for _, condition := range op.Conditions {
+			ret.Conditions = append(ret.Conditions, &OrderCondition{Expression: substituteExpression(condition.Expression, solution), Descending: condition.Descending})
+		}
+		return ret
+	case *Extend:
+		return &Extend{Input: substituteQuery(op.Input, solution), Variable: op.Variable, Expression: substituteExpression(op.Expression, solution)}
+	case *Filter:
+		return &Filter{Expression: substituteExpression(op.Expression, solution), Input: substituteQuery(op.Input, solution)}
+	case *Group:
+		ret := &Group{Input: substitute(op.Input, solution)}
+		for _, key := range op.Keys {
+			ret.Keys = append(ret.Keys, &GroupKey{Expression: substituteExpression(key.Expression, solution), Variable: key.Variable})
+		}
+		for _, aggregate := range op.Aggregates {
+			copied := *aggregate
+			if aggregate.Expression != nil {
+				copied.Expression = substituteExpression(aggregate.Expression, solution)
+			}
+			ret.Aggregates = append(ret.Aggregates, &copied)
+		}
+		return ret
+	}
+	return substitute(operator, solution)
+}
+
+// the state of an evaluation
+type evaluator struct {
+	store store.Store
+	// the graphs merged into the default graph of the query, DefaultGraph
+	// standing for the one of the store
+	defaultGraphs []model.RDFTerm
+	// the named graphs of the query, found in the store when namedKnown is
+	// false
+	namedGraphs []model.RDFTerm
+	namedKnown  bool
+	base        model.IRI
+	// NOW is the same during a query
+	now time.Time
+	// the compiled patterns of REGEX and REPLACE
+	patterns map[string]*regexp.Regexp
+	// the nodes of BNODE(string) for the solution being extended
+	blankNodes map[string]model.RDFTerm
+}
+
+// the errors of the store, raised by panic until Evaluate
+type storeFailure struct {
+	err error
+}
+
+func newEvaluator(dataset store.Store, query *Query) *evaluator {
+	this := &evaluator{
+		store:         dataset,
+		defaultGraphs: []model.RDFTerm{store.DefaultGraph},
+		base:          query.base,
+		now:           time.Now(),
+		patterns:      make(map[string]*regexp.Regexp),
+		blankNodes:    make(map[string]model.RDFTerm),
+	}
+	if len(query.From) > 0 || len(query.FromNamed) > 0 {
+		this.defaultGraphs = []model.RDFTerm{}
+		for _, graph := range query.From {
+			this.defaultGraphs = append(this.defaultGraphs, graph)
+		}
+		this.namedGraphs = []model.RDFTerm{}
+		for _, graph := range query.FromNamed {
+			this.namedGraphs = append(this.namedGraphs, graph)
+		}
+		this.namedKnown = true
+	}
+	return this
+}
+
+// turns the panics of storeFailure into errors
+func recoverStore(err *error) {
+	if r := recover(); r != nil {
+		failure, ok := r.(storeFailure)
+		if !ok {
+			panic(r)
+		}
+		*err = failure.err
+	}
+}
+
+// the solutions of an operator, graphs being the active graph
+func (this *evaluator) evaluate(operator Operator, graphs []model.RDFTerm) []Solution {
+	switch op := operator.(type) {
+	case *BGP:
+		return this.bgp(op.Patterns, []Solution{{}}, graphs)
+	case *Join:
+		left := this.evaluate(op.Left, graphs)
+		// the patterns are matched with the bindings of each solution
+		if bgp, ok := op.Right.(*BGP); ok {
+			return this.bgp(bgp.Patterns, left, graphs)
+		}
+		return joinSolutions(left, this.evaluate(op.Right, graphs))
+	case *LeftJoin:
+		return this.leftJoin(op, graphs)
+	case *Filter:
+		ret := []Solution{}
+		for _, solution := range this.evaluate(op.Input, graphs) {
+			if this.holds(op.Expression, solution, graphs) {
+				ret = append(ret, solution)
+			}
+		}
+		return ret
+	case *Union:
+		return append(this.evaluate(op.Left, graphs), this.evaluate(op.Right, graphs)...)
+	case *Minus:
+		return minus(this.evaluate(op.Left, graphs), this.evaluate(op.Right, graphs))
+	case *Graph:
+		return this.graph(op)
+	case *Extend:
+		ret := []Solution{}
+		for _, solution := range this.evaluate(op.Input, graphs) {
+			this.blankNodes = map[string]model.RDFTerm{}
+			if value, err := this.expression(op.Expression, solution, graphs); err == nil {
+				solution = solution.with(op.Variable, value)
+			}
+			ret = append(ret, solution)
+		}
+		return ret
+	case *Table:
+		ret := []Solution{}
+		for _, row := range op.Rows {
+			solution := Solution{}
+			for i, value := range row {
+				if value != nil {
+					solution[op.Variables[i]] = value
+				}
+			}
+			ret = append(ret, solution)
+		}
+		return ret
+	case *Group:
+		return this.group(op, graphs)
+	case *OrderBy:
+		return this.orderBy(op, graphs)
+	case *Project:
+		ret := []Solution{}
+		for _, solution := range this.evaluate(op.Input, graphs) {
+			projected := Solution{}
+			for _, variable := range op.Variables {
+				if value, ok := solution[variable]; ok {
+					projected[variable] = value
+				}
+			}
+			ret = append(ret, projected)
+		}
+		return ret
+	case *Distinct:
+		return distinct(this.evaluate(op.Input, graphs))
+	case *Reduced:
+		return distinct(this.evaluate(op.Input, graphs))
+	case *Slice:
+		ret := this.evaluate(op.Input, graphs)
+		if op.Offset >= len(ret) {
+			return []Solution{}
+		}
+		ret = ret[op.Offset:]
+		if op.Limit >= 0 && op.Limit < len(ret) {
+			ret = ret[:op.Limit]
+		}
+		return ret
+	}
+	panic("unknown operator " + operator.String())
+}
+
+// a copy of the solution binding variable to value
+func (this Solution) with(variable Variable, value model.RDFTerm) Solution {
+	ret := make(Solution, len(this)+1)
+	for k, v := range this {
+		ret[k] = v
+	}
+	ret[variable] = value
+	return ret
+}
+
+// the solutions agree on the variables they both bind
+func compatible(a, b Solution) bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	for variable, value := range a {
+		if other, ok := b[variable]; ok && other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func merge(a, b Solution) Solution {
+	ret := make(Solution, len(a)+len(b))
+	for k, v := range a {
+		ret[k] = v
+	}
+	for k, v := range b {
+		ret[k] = v
+	}
+	return ret
+}
+
+// the variables bound by every solution
+func certain(solutions []Solution) map[Variable]bool {
+	if len(solutions) == 0 {
+		return nil
+	}
+	ret := map[Variable]bool{}
+	for variable := range solutions[0] {
+		ret[variable] = true
+	}
+	for _, solution := range solutions[1:] {
+		for variable := range ret {
+			if _, ok := solution[variable]; !ok {
+				delete(ret, variable)
+			}
+		}
+	}
+	return ret
+}
+
+// the merges of the compatible solutions of left and right, right being
+// hashed on the variables both always bind
+func joinSolutions(left, right []Solution) []Solution {
+	ret := []Solution{}
+	if len(left) == 0 || len(right) == 0 {
+		return ret
+	}
+	keys := []Variable{}
+	rightCertain := certain(right)
+	for variable := range certain(left) {
+		if rightCertain[variable] {
+			keys = append(keys, variable)
+		}
+	}
+	key := func(solution Solution) string {
+		parts := make([]string, len(keys))
+		for i, variable := range keys {
+			parts[i] = termKey(solution[variable])
+		}
+		return strings.Join(parts, " ")
+	}
+	buckets := map[string][]Solution{}
+	for _, solution := range right {
+		k := key(solution)
+		buckets[k] = append(buckets[k], solution)
+	}
+	for _, l := range left {
+		for _, r := range buckets[key(l)] {
+			if compatible(l, r) {
+				ret = append(ret, merge(l, r))
+			}
+		}
+	}
+	return ret
+}
+
+func (this *evaluator) leftJoin(op *LeftJoin, graphs []model.RDFTerm) []Solution {
+	ret := []Solution{}
+	left := this.evaluate(op.Left, graphs)
+	var right []Solution
+	bgp, seeded := op.Right.(*BGP)
+	if !seeded {
+		right = this.evaluate(op.Right, graphs)
+	}
+	for _, l := range left {
+		var candidates []Solution
+		if seeded {
+			candidates = this.bgp(bgp.Patterns, []Solution{l}, graphs)
+		} else {
+			candidates = joinSolutions([]Solution{l}, right)
+		}
+		found := false
+		for _, candidate := range candidates {
+			if op.Expression == nil || this.holds(op.Expression, candidate, graphs) {
+				ret = append(ret, candidate)
+				found = true
+			}
+		}
+		if !found {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func minus(left, right []Solution) []Solution {
+	ret := []Solution{}
+	for _, l := range left {
+		removed := false
+		for _, r := range right {
+			if compatible(l, r) && sharesVariable(l, r) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func sharesVariable(a, b Solution) bool {
+	for variable := range a {
+		if _, ok := b[variable]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// a string telling solutions apart
+func solutionKey(solution Solution) string {
+	variables := make([]string, 0, len(solution))
+	for variable := range solution {
+		variables = append(variables, string(variable))
+	}
+	sort.Strings(variables)
+	var ret strings.Builder
+	for _, variable := range variables {
+		ret.WriteString(variable)
+		ret.WriteByte('=')
+		ret.WriteString(termKey(solution[Variable(variable)]))
+		ret.WriteByte('\n')
+	}
+	return ret.String()
+}
+
+func distinct(solutions []Solution) []Solution {
+	ret := []Solution{}
+	seen := map[string]bool{}
+	for _, solution := range solutions {
+		key := solutionKey(solution)
+		if !seen[key] {
+			seen[key] = true
+			ret = append(ret, solution)
+		}
+	}
+	return ret
+}
+
+// basic graph patterns
+
+// the solutions of the patterns extending each of the seeds
+func (this *evaluator) bgp(patterns []*TriplePattern, seeds []Solution, graphs []model.RDFTerm) []Solution {
+	ret := []Solution{}
+	for _, seed := range seeds {
+		this.matchPatterns(patterns, seed, graphs, func(solution Solution) {
+			ret = append(ret, solution)
+		})
+	}
+	return ret
+}
+
+// matches the pattern with the most bound terms first
+func (this *evaluator) matchPatterns(patterns []*TriplePattern, solution Solution, graphs []model.RDFTerm, emit func(Solution)) {
+	if len(patterns) == 0 {
+		emit(solution)
+		return
+	}
+	best, bestScore := 0, -1
+	for i, pattern := range patterns {
+		score := 0
+		for j, term := range []model.RDFTerm{pattern.Subject, pattern.Predicate, pattern.Object} {
+			if _, ok := term.(Path); !ok && substituteTerm(term, solution) != nil {
+				// bound subjects and objects select more than predicates
+				score += [3]int{3, 1, 2}[j]
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	rest := make([]*TriplePattern, 0, len(patterns)-1)
+	rest = append(rest, patterns[:best]...)
+	rest = append(rest, patterns[best+1:]...)
+	pattern := patterns[best]
+	next := func(extended Solution) {
+		this.matchPatterns(rest, extended, graphs, emit)
+	}
+	if path, ok := pattern.Predicate.(Path); ok {
+		this.matchPath(pattern.Subject, path, pattern.Object, solution, graphs, next)
+	} else {
+		this.match(pattern.Subject, pattern.Predicate, pattern.Object, solution, graphs, next)
+	}
+}
+
+// the term with the variables bound by solution replaced, nil when it is
+// or has a variable which is not bound
+func substituteTerm(term model.RDFTerm, solution Solution) model.RDFTerm {
+	switch t := term.(type) {
+	case Variable:
+		return solution[t]
+	case model.TripleTerm:
+		subject := substituteTerm(t.Subject, solution)
+		predicate := substituteTerm(t.Predicate, solution)
+		object := substituteTerm(t.Object, solution)
+		if subject == nil || predicate == nil || object == nil {
+			return nil
+		}
+		return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}
+	}
+	return term
+}
+
+// binds the variables of pattern to term, false when they cannot be
+func unify(pattern model.RDFTerm, term model.RDFTerm, solution Solution) (Solution, bool) {
+	switch p := pattern.(type) {
+	case Variable:
+		if value, ok := solution[p]; ok {
+			return solution, value == term
+		}
+		return solution.with(p, term), true
+	case model.TripleTerm:
+		t, ok := term.(model.TripleTerm)
+		if !ok {
+			return solution, false
+		}
+		if solution, ok = unify(p.Subject, t.Subject, solution); !ok {
+			return solution, false
+		}
+		if solution, ok = unify(p.Predicate, t.Predicate, solution); !ok {
+			return solution, false
+		}
+		return unify(p.Object, t.Object, solution)
+	}
+	return solution, pattern == term
+}
+
+// the solutions of a triple pattern extending solution in the active
+// graph, the statements of a merge of graphs being counted once
+func (this *evaluator) match(subject, predicate, object model.RDFTerm, solution Solution, graphs []model.RDFTerm, emit func(Solution)) {
+	s, p, o := substituteTerm(subject, solution), substituteTerm(predicate, solution), substituteTerm(object, solution)
+	var seen map[[3]model.RDFTerm]bool
+	if len(graphs) > 1 {
+		seen = map[[3]model.RDFTerm]bool{}
+	}
+	for _, graph := range graphs {
+		for _, statement := range this.statements(s, p, o, graph) {
+			if seen != nil {
+				key := [3]model.RDFTerm{statement.Subject, statement.Predicate, statement.Object}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			extended, ok := unify(subject, statement.Subject, solution)
+			if ok {
+				extended, ok = unify(predicate, statement.Predicate, extended)
+			}
+			if ok {
+				extended, ok = unify(object, statement.Object, extended)
+			}
+			if ok {
+				emit(extended)
+			}
+		}
+	}
+}
+
+func (this *evaluator) statements(subject, predicate, object, graph model.RDFTerm) []*model.Statement {
+	ret, err := store.All(this.store.Match(subject, predicate, object, graph))
+	if err != nil {
+		panic(storeFailure{err})
+	}
+	return ret
+}
+
+// named graphs
+
+func (this *evaluator) named() []model.RDFTerm {
+	if !this.namedKnown {
+		seen := map[model.RDFTerm]bool{}
+		iterator := this.store.Match(nil, nil, nil, nil)
+		for iterator.Next() {
+			if graph := iterator.Statement().Context; graph != nil && !seen[graph] {
+				seen[graph] = true
+				this.namedGraphs = append(this.namedGraphs, graph)
+			}
+		}
+		err := iterator.Err()
+		iterator.Close()
+		if err != nil {
+			panic(storeFailure{err})
+		}
+		this.namedKnown = true
+	}
+	return this.namedGraphs
+}
+
+func (this *evaluator) graph(op *Graph) []Solution {
+	variable, isVariable := op.Name.(Variable)
+	ret := []Solution{}
+	for _, graph := range this.named() {
+		if !isVariable {
+			if graph == op.Name {
+				return this.evaluate(op.Input, []model.RDFTerm{graph})
+			}
+			continue
+		}
+		for _, solution := range this.evaluate(op.Input, []model.RDFTerm{graph}) {
+			if value, ok := solution[variable]; ok {
+				if value == graph {
+					ret = append(ret, solution)
+				}
+				continue
+			}
+			ret = append(ret, solution.with(variable, graph))
+		}
+	}
+	return ret
+}
+
+// solution modifiers
+
+func (this *evaluator) orderBy(op *OrderBy, graphs []model.RDFTerm) []Solution {
+	solutions := this.evaluate(op.Input, graphs)
+	keys := make([][]model.RDFTerm, len(solutions))
+	for i, solution := range solutions {
+		keys[i] = make([]model.RDFTerm, len(op.Conditions))
+		for j, condition := range op.Conditions {
+			// errors sort as unbound
+			keys[i][j], _ = this.expression(condition.Expression, solution, graphs)
+		}
+	}
+	indexes := make([]int, len(solutions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		for j, condition := range op.Conditions {
+			c := compareOrder(keys[indexes[a]][j], keys[indexes[b]][j])
+			if condition.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	ret := make([]Solution, len(solutions))
+	for i, index := range indexes {
+		ret[i] = solutions[index]
+	}
+	return ret
+}
+
+// CONSTRUCT and DESCRIBE
+
+// the statements of the template for each solution, with new blank nodes
+// for each solution; the statements which would have unbound variables or
+// be invalid are left out
+func (this *evaluator) construct(template []*TriplePattern, solutions []Solution) []*model.Statement {
+	ret := []*model.Statement{}
+	seen := map[[3]model.RDFTerm]bool{}
+	for _, solution := range solutions {
+		nodes := map[model.RDFTerm]model.RDFTerm{}
+		for _, pattern := range template {
+			subject := instantiate(pattern.Subject, solution, nodes)
+			predicate := instantiate(pattern.Predicate, solution, nodes)
+			object := instantiate(pattern.Object, solution, nodes)
+			key := [3]model.RDFTerm{subject, predicate, object}
+			if !validTriple(subject, predicate, object) || seen[key] {
+				continue
+			}
+			seen[key] = true
+			ret = append(ret, &model.Statement{Subject: subject, Predicate: predicate, Object: object})
+		}
+	}
+	return ret
+}
+
+// the value of a term of a template for a solution, nodes being the new
+// blank nodes standing for those of the template; nil when a variable is
+// unbound or a triple term invalid
+func instantiate(term model.RDFTerm, solution Solution, nodes map[model.RDFTerm]model.RDFTerm) model.RDFTerm {
+	switch t := term.(type) {
+	case Variable:
+		return solution[t]
+	case model.BlankNode:
+		node, ok := nodes[t]
+		if !ok {
+			node = model.NewAnonymousBlankNode()
+			nodes[t] = node
+		}
+		return node
+	case model.TripleTerm:
+		subject, predicate, object := instantiate(t.Subject, solution, nodes), instantiate(t.Predicate, solution, nodes), instantiate(t.Object, solution, nodes)
+		if !validTriple(subject, predicate, object) {
+			return nil
+		}
+		return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}
+	}
+	return term
+}
+
+func validTriple(subject, predicate, object model.RDFTerm) bool {
+	if _, ok := predicate.(model.IRI); !ok || object == nil {
+		return false
+	}
+	switch subject.(type) {
+	case model.IRI, model.BlankNode:
+		return true
+	}
+	return false
+}
+
+// the concise bounded descriptions of the resources, in the default graph:
+// their statements and those of the blank nodes they lead to
+func (this *evaluator) describe(resources []model.RDFTerm, solutions []Solution) []*model.Statement {
+	ret := []*model.Statement{}
+	described := map[model.RDFTerm]bool{}
+	var visit func(resource model.RDFTerm)
+	visit = func(resource model.RDFTerm) {
+		if resource == nil || described[resource] {
+			return
+		}
+		described[resource] = true
+		seen := map[[3]model.RDFTerm]bool{}
+		for _, graph := range this.defaultGraphs {
+			for _, statement := range this.statements(resource, nil, nil, graph) {
+				key := [3]model.RDFTerm{statement.Subject, statement.Predicate, statement.Object}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				ret = append(ret, &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object})
+				if model.IsBlankNode(statement.Object) {
+					visit(statement.Object)
+				}
+			}
+		}
+	}
+	for _, resource := range resources {
+		if variable, ok := resource.(Variable); ok {
+			for _, solution := range solutions {
+				visit(solution[variable])
+			}
+		} else {
+			visit(resource)
+		}
+	}
+	return ret
+}
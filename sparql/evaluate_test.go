@@ -0,0 +1,324 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+const people = `@prefix : <http://ex.org/> .
+:alice a :Person ; :name "Alice"@en ; :age 30 ; :knows :bob, :carol .
+:bob a :Person ; :name "Bob" ; :age 25 ; :knows :carol .
+:carol a :Person ; :name "Carol" ; :address [ :city "Paris" ; :zip [ :code "75001" ] ] .
+:g1 { :alice :likes :tea . }
+:g2 { :bob :likes :coffee . :alice :likes :coffee . }
+`
+
+var ex = []model.Namespace{{Prefix: "", IRI: "http://ex.org/"}}
+
+func peopleStore(t *testing.T) store.Store {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(people), parser.Options{Format: format.TriG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := store.NewMemory()
+	for _, statement := range statements {
+		if err := ret.Add(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ret
+}
+
+func run(t *testing.T, dataset store.Store, text string) *Result {
+	t.Helper()
+	query, err := ParseQuery(text, Options{Namespaces: ex})
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+	ret, err := Evaluate(query, dataset)
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+	return ret
+}
+
+// the solutions as lines of values, UNDEF for unbound variables
+func rows(result *Result) []string {
+	ret := []string{}
+	for _, solution := range result.Solutions {
+		values := []string{}
+		for _, variable := range result.Variables {
+			if value, ok := solution[variable]; ok {
+				values = append(values, strings.ReplaceAll(formatTerm(value), "http://ex.org/", ":"))
+			} else {
+				values = append(values, "UNDEF")
+			}
+		}
+		ret = append(ret, strings.Join(values, " "))
+	}
+	return ret
+}
+
+func TestEvaluateSelect(t *testing.T) {
+	dataset := peopleStore(t)
+	cases := []struct {
+		query string
+		rows  string
+		// the order of the rows matters
+		ordered bool
+	}{
+		{`SELECT ?p ?a { ?p a :Person ; :age ?a } ORDER BY DESC(?a)`, "<:alice> 30|<:bob> 25", true},
+		{`SELECT ?p ?a { ?p a :Person OPTIONAL { ?p :age ?a FILTER(?a > 26) } } ORDER BY ?p`, "<:alice> 30|<:bob> UNDEF|<:carol> UNDEF", true},
+		{`SELECT ?x { { :alice :knows ?x } UNION { :bob :knows ?x } }`, "<:bob>|<:carol>|<:carol>", false},
+		{`SELECT DISTINCT ?x { { :alice :knows ?x } UNION { :bob :knows ?x } }`, "<:bob>|<:carol>", false},
+		{`SELECT ?n { ?p :name ?n FILTER(langMatches(lang(?n), "EN")) }`, `"Alice"@en`, false},
+		{`SELECT ?n { ?p :name ?n FILTER(regex(?n, "^b", "i") || strstarts(?n, "C")) }`, `"Bob"|"Carol"`, false},
+		{`SELECT ?p ?next { ?p :age ?a BIND(?a + 1 AS ?next) FILTER(?next < 30) }`, "<:bob> 26", false},
+		{`SELECT ?p ?c { ?p :address [ :city ?c ] }`, `<:carol> "Paris"`, false},
+		{`SELECT ?p ?n { VALUES ?p { :bob :nobody } OPTIONAL { ?p :name ?n } }`, `<:bob> "Bob"|<:nobody> UNDEF`, false},
+		{`SELECT ?p { ?p a :Person MINUS { ?p :knows :bob } }`, "<:bob>|<:carol>", false},
+		{`SELECT ?p { ?p a :Person FILTER NOT EXISTS { ?p :knows ?q . ?q :age ?a } }`, "<:bob>|<:carol>", false},
+		{`SELECT ?p ?q { ?p :knows ?q FILTER EXISTS { ?q :knows ?r } }`, "<:alice> <:bob>", false},
+		{`SELECT ?p ?n { ?p :knows ?q { SELECT ?q (COUNT(*) AS ?n) { ?x :knows ?q } GROUP BY ?q } }`, "<:alice> 1|<:alice> 2|<:bob> 2", false},
+		{`SELECT ?q (COUNT(*) AS ?n) { ?p :knows ?q } GROUP BY ?q HAVING (COUNT(*) > 1)`, "<:carol> 2", false},
+		{`SELECT (SUM(?a) AS ?s) (AVG(?a) AS ?m) (MIN(?a) AS ?l) (MAX(?a) AS ?h) (COUNT(DISTINCT ?p) AS ?n) { ?p :age ?a }`, "55 27.5 25 30 2", false},
+		{`SELECT (GROUP_CONCAT(?n; SEPARATOR="/") AS ?all) { SELECT ?n { ?p :name ?n } ORDER BY ?n }`, `"Alice/Bob/Carol"`, false},
+		{`SELECT (COUNT(*) AS ?n) (SUM(?a) AS ?s) { ?p :nothing ?a }`, "0 0", false},
+		{`SELECT ?n { ?p :name ?n } ORDER BY ?n LIMIT 1 OFFSET 1`, `"Bob"`, true},
+		{`SELECT ?g ?what { GRAPH ?g { :alice :likes ?what } }`, "<:g1> <:tea>|<:g2> <:coffee>", false},
+		{`SELECT ?who { GRAPH :g2 { ?who :likes :coffee } }`, "<:alice>|<:bob>", false},
+		{`SELECT ?who ?what FROM :g1 FROM :g2 { ?who :likes ?what }`, "<:alice> <:tea>|<:bob> <:coffee>|<:alice> <:coffee>", false},
+		{`SELECT ?g FROM NAMED :g1 { GRAPH ?g { ?s ?p ?o } }`, "<:g1>", false},
+		{`SELECT ?s FROM NAMED :g1 { ?s ?p ?o }`, "", false},
+		{`SELECT ?x ?y { VALUES (?x ?y) { (1 UNDEF) (UNDEF 2) } VALUES ?x { 1 } }`, "1 UNDEF|1 2", false},
+	}
+	for _, c := range cases {
+		result := run(t, dataset, c.query)
+		got := rows(result)
+		expected := []string{}
+		if c.rows != "" {
+			expected = strings.Split(c.rows, "|")
+		}
+		if !c.ordered {
+			sort.Strings(got)
+			sort.Strings(expected)
+		}
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("%s:\n%s\ninstead of\n%s", c.query, strings.Join(got, "|"), strings.Join(expected, "|"))
+		}
+	}
+}
+
+func TestEvaluateExpressions(t *testing.T) {
+	dataset := store.NewMemory()
+	cases := []struct {
+		expression string
+		// UNDEF for errors
+		value string
+	}{
+		{`1 + 2 * 3`, "7"},
+		{`7 / 2`, "3.5"},
+		{`1 / 3`, "0.333333333333333333333333"},
+		{`1 / 0`, "UNDEF"},
+		{`1.0e0 / 0`, `"INF"^^<http://www.w3.org/2001/XMLSchema#double>`},
+		{`1.5 + 1.5`, "3.0"},
+		{`2 * 1.5e0`, "3.0E0"},
+		{`-(3)`, "-3"},
+		{`1 = 1.0`, "true"},
+		{`"a" = "a"@en`, "false"},
+		{`"1"^^<http://ex.org/t> = "2"^^<http://ex.org/t>`, "UNDEF"},
+		{`"b" > "a"`, "true"},
+		{`true && "x" > 1`, "UNDEF"},
+		{`false && "x" > 1`, "false"},
+		{`true || "x" > 1`, "true"},
+		{`2 IN (1, 2)`, "true"},
+		{`3 NOT IN (1, 2)`, "true"},
+		{`?unbound`, "UNDEF"},
+		{`BOUND(?unbound)`, "false"},
+		{`COALESCE(?unbound, 1 / 0, "x")`, `"x"`},
+		{`IF(1 < 2, "yes", "no")`, `"yes"`},
+		{`STR(<a>)`, `":a"`},
+		{`DATATYPE("a")`, "<http://www.w3.org/2001/XMLSchema#string>"},
+		{`DATATYPE("a"@en)`, "<http://www.w3.org/1999/02/22-rdf-syntax-ns#langString>"},
+		{`IRI("b")`, "<:b>"},
+		{`STRLEN("chat😺")`, "5"},
+		{`SUBSTR("foobar", 4)`, `"bar"`},
+		{`SUBSTR("foobar"@en, 2, 3)`, `"oob"@en`},
+		{`UCASE("abc"@fr)`, `"ABC"@fr`},
+		{`CONCAT("a"@en, "b"@en)`, `"ab"@en`},
+		{`CONCAT("a"@en, "b")`, `"ab"`},
+		{`STRBEFORE("abc", "b")`, `"a"`},
+		{`STRAFTER("abc"@en, "b")`, `"c"@en`},
+		{`STRAFTER("abc", "x")`, `""`},
+		{`CONTAINS("abc"@en, "b"@fr)`, "UNDEF"},
+		{`STRENDS("abc", "bc")`, "true"},
+		{`ENCODE_FOR_URI("Los Angeles/é")`, `"Los%20Angeles%2F%C3%A9"`},
+		{`REPLACE("abcd", "(b)(c)", "$2$1")`, `"acbd"`},
+		{`REPLACE("a.b", ".", "-", "q")`, `"a-b"`},
+		{`REGEX("ABC", "b", "i")`, "true"},
+		{`ABS(-2.5)`, "2.5"},
+		{`ROUND(-2.5)`, "-2.0"},
+		{`ROUND(2.5)`, "3.0"},
+		{`CEIL(1.2)`, "2.0"},
+		{`FLOOR(-1.2)`, "-2.0"},
+		{`YEAR("2011-01-10T14:45:13.815-05:00"^^xsd:dateTime)`, "2011"},
+		{`SECONDS("2011-01-10T14:45:13.815-05:00"^^xsd:dateTime)`, "13.815"},
+		{`TIMEZONE("2011-01-10T14:45:13.815-05:00"^^xsd:dateTime)`, `"-PT5H"^^<http://www.w3.org/2001/XMLSchema#dayTimeDuration>`},
+		{`TIMEZONE("2011-01-10T14:45:13"^^xsd:dateTime)`, "UNDEF"},
+		{`TZ("2011-01-10T14:45:13Z"^^xsd:dateTime)`, `"Z"`},
+		{`"2011-01-10T14:00:00Z"^^xsd:dateTime = "2011-01-10T09:00:00-05:00"^^xsd:dateTime`, "true"},
+		{`MD5("abc")`, `"900150983cd24fb0d6963f7d28e17f72"`},
+		{`SHA1("abc")`, `"a9993e364706816aba3e25717850c26c9cd0d89d"`},
+		{`SHA256("abc")`, `"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"`},
+		{`SHA1("abc"@en)`, "UNDEF"},
+		{`STRLANG("chat", "fr")`, `"chat"@fr`},
+		{`STRDT("1", xsd:integer) + 1`, "2"},
+		{`sameTerm(1, 1.0)`, "false"},
+		{`isNumeric("12"^^xsd:integer)`, "true"},
+		{`isNumeric("x"^^xsd:integer)`, "false"},
+		{`xsd:integer("12") + 1`, "13"},
+		{`xsd:integer(2.7)`, "2"},
+		{`xsd:boolean("0")`, "false"},
+		{`xsd:double("1.5")`, "1.5E0"},
+		{`xsd:decimal(true)`, "1.0"},
+		{`xsd:integer("x")`, "UNDEF"},
+		{`<http://ex.org/unknown>(1)`, "UNDEF"},
+		{`isTRIPLE(<<( :a :b :c )>>)`, "true"},
+		{`OBJECT(<<( :a :b "c" )>>)`, `"c"`},
+		{`STRLEN(STRUUID())`, "36"},
+		{`isIRI(UUID())`, "true"},
+		{`isBLANK(BNODE())`, "true"},
+		{`DATATYPE(NOW())`, "<http://www.w3.org/2001/XMLSchema#dateTime>"},
+	}
+	for _, c := range cases {
+		text := "BASE <http://ex.org/> PREFIX xsd: <http://www.w3.org/2001/XMLSchema#> SELECT (" + c.expression + " AS ?v) {}"
+		got := rows(run(t, dataset, text))
+		if len(got) != 1 || got[0] != c.value {
+			t.Errorf("%s: %v instead of %s", c.expression, got, c.value)
+		}
+	}
+}
+
+func TestEvaluatePaths(t *testing.T) {
+	dataset := store.NewMemory()
+	statements, err := parser.ParseAll(strings.NewReader(`@prefix : <http://ex.org/> .
+:A :sub :B . :B :sub :C . :C :sub :A . :D :sub :C .
+:x a :D .
+`), parser.Options{Format: format.Turtle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range statements {
+		dataset.Add(statement)
+	}
+	cases := []struct {
+		query string
+		rows  string
+	}{
+		{`SELECT ?c { :D :sub* ?c }`, "<:A>|<:B>|<:C>|<:D>"},
+		{`SELECT ?c { :D :sub+ ?c }`, "<:A>|<:B>|<:C>"},
+		{`SELECT ?c { :A :sub+ ?c }`, "<:A>|<:B>|<:C>"},
+		{`SELECT ?c { :D :sub? ?c }`, "<:C>|<:D>"},
+		{`SELECT ?c { ?c :sub* :B }`, "<:A>|<:B>|<:C>|<:D>"},
+		{`SELECT ?c { :nowhere :sub* ?c }`, "<:nowhere>"},
+		{`SELECT ?x { ?x a/:sub* :A }`, "<:x>"},
+		{`SELECT ?x { :B ^:sub/^a ?x }`, ""},
+		{`SELECT ?x { :C ^(a/:sub) ?x }`, "<:x>"},
+		{`SELECT ?s ?o { ?s :sub/:sub ?o }`, "<:A> <:C>|<:B> <:A>|<:C> <:B>|<:D> <:A>"},
+		{`SELECT ?o { :D :sub|:sub ?o }`, "<:C>|<:C>"},
+		{`SELECT ?o { :D (:sub|:sub)+ ?o }`, "<:A>|<:B>|<:C>"},
+		{`SELECT ?o { :x !:sub ?o }`, "<:D>"},
+		{`SELECT ?s { :D !^:sub ?s }`, "<:x>"},
+		{`SELECT ?s { :D !(a|^a) ?s }`, "<:C>"},
+		{`SELECT (COUNT(*) AS ?n) { ?s :sub* ?o }`, "14"},
+		{`SELECT ?s { ?s :sub* ?s }`, "<:A>|<:B>|<:C>|<:D>|<:x>"},
+	}
+	for _, c := range cases {
+		got := rows(run(t, dataset, c.query))
+		sort.Strings(got)
+		if strings.Join(got, "|") != c.rows {
+			t.Errorf("%s:\n%s\ninstead of\n%s", c.query, strings.Join(got, "|"), c.rows)
+		}
+	}
+}
+
+func TestEvaluateForms(t *testing.T) {
+	dataset := peopleStore(t)
+	if result := run(t, dataset, `ASK { :alice :knows :bob }`); !result.Boolean {
+		t.Error("ASK should be true")
+	}
+	if result := run(t, dataset, `ASK { :bob :knows :alice }`); result.Boolean {
+		t.Error("ASK should be false")
+	}
+
+	result := run(t, dataset, `CONSTRUCT { ?q :knownBy ?p . ?q :tag [ :by ?p ] } WHERE { ?p :knows ?q }`)
+	if len(result.Statements) != 9 {
+		t.Errorf("%d statements constructed instead of 9", len(result.Statements))
+	}
+	nodes := map[model.RDFTerm]bool{}
+	for _, statement := range result.Statements {
+		if model.IsBlankNode(statement.Subject) {
+			nodes[statement.Subject] = true
+		}
+	}
+	if len(nodes) != 3 {
+		t.Errorf("%d blank nodes instead of one per solution", len(nodes))
+	}
+
+	result = run(t, dataset, `CONSTRUCT WHERE { ?p :age ?a }`)
+	if len(result.Statements) != 2 {
+		t.Errorf("%d statements constructed instead of 2", len(result.Statements))
+	}
+
+	// the description of carol follows her address and its zip code
+	result = run(t, dataset, `DESCRIBE ?p { ?p :name "Carol" }`)
+	lines := []string{}
+	for _, statement := range result.Statements {
+		if !model.IsBlankNode(statement.Object) {
+			lines = append(lines, strings.ReplaceAll(writer.FormatTerm(statement.Object, nil), "http://ex.org/", ":"))
+		}
+	}
+	sort.Strings(lines)
+	if len(result.Statements) != 6 || strings.Join(lines, " ") != `"75001" "Carol" "Paris" <:Person>` {
+		t.Errorf("unexpected description %v", lines)
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	dataset := peopleStore(t)
+	query, err := ParseQuery(`SELECT $this ?friend (COUNT(?x) AS ?n) WHERE { $this :knows ?friend OPTIONAL { ?friend :knows ?x } FILTER ($this != ?friend) } GROUP BY $this ?friend`, Options{Namespaces: ex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Evaluate(Substitute(query, Solution{"this": model.IRI("http://ex.org/alice")}), dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.ReplaceAll(strings.Join(rows(result), "\n"), "http://ex.org/", ":")
+	if !strings.Contains(got, "<:alice> <:bob> 1") || !strings.Contains(got, "<:alice> <:carol> 0") || len(result.Solutions) != 2 {
+		t.Errorf("solutions\n%s", got)
+	}
+	construct, err := ParseQuery(`CONSTRUCT { $this :friendOf ?p } WHERE { ?p :knows $this }`, Options{Namespaces: ex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = Evaluate(Substitute(construct, Solution{"this": model.IRI("http://ex.org/carol")}), dataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Statements) != 2 || result.Statements[0].Subject != model.IRI("http://ex.org/carol") {
+		t.Errorf("constructed %v", result.Statements)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Expression is a node of the expressions of FILTER, BIND, SELECT, ORDER
+// BY and GROUP BY: a Variable, a Constant, an operator, a function call or
+// EXISTS
+type Expression interface {
+	String() string
+}
+
+type Constant struct {
+	Term model.RDFTerm
+}
+
+// ! + -
+type Unary struct {
+	Operator string
+	Operand  Expression
+}
+
+// || && = != < > <= >= + - * /
+type Binary struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+type In struct {
+	Operand Expression
+	List    []Expression
+	Not     bool
+}
+
+// Function is the upper case name of a built-in function, as in STRLEN,
+// or the IRI of a cast or extension function
+type Call struct {
+	Function  string
+	Arguments []Expression
+}
+
+type Exists struct {
+	Pattern Operator
+	Not     bool
+}
+
+func (this *Constant) String() string {
+	return formatTerm(this.Term)
+}
+
+func (this *Unary) String() string {
+	return sexp(this.Operator, this.Operand.String())
+}
+
+func (this *Binary) String() string {
+	return sexp(this.Operator, this.Left.String(), this.Right.String())
+}
+
+func (this *In) String() string {
+	parts := []string{"in", this.Operand.String()}
+	if this.Not {
+		parts[0] = "notin"
+	}
+	for _, expression := range this.List {
+		parts = append(parts, expression.String())
+	}
+	return sexp(parts...)
+}
+
+func (this *Call) String() string {
+	name := strings.ToLower(this.Function)
+	if IsExtensionFunction(this.Function) {
+		name = "<" + this.Function + ">"
+	}
+	parts := []string{name}
+	for _, argument := range this.Arguments {
+		parts = append(parts, argument.String())
+	}
+	return sexp(parts...)
+}
+
+func (this *Exists) String() string {
+	if this.Not {
+		return sexp("notexists", this.Pattern.String())
+	}
+	return sexp("exists", this.Pattern.String())
+}
+
+// true for the functions named by an IRI, casts included
+func IsExtensionFunction(function string) bool {
+	return strings.ContainsRune(function, ':')
+}
+
+// the number of arguments of the built-in functions, -1 when there is no
+// maximum
+var builtins = map[string][2]int{
+	"STR": {1, 1}, "LANG": {1, 1}, "LANGMATCHES": {2, 2}, "DATATYPE": {1, 1},
+	"BOUND": {1, 1}, "IRI": {1, 1}, "BNODE": {0, 1}, "RAND": {0, 0},
+	"ABS": {1, 1}, "CEIL": {1, 1}, "FLOOR": {1, 1}, "ROUND": {1, 1},
+	"CONCAT": {0, -1}, "SUBSTR": {2, 3}, "STRLEN": {1, 1}, "REPLACE": {3, 4},
+	"UCASE": {1, 1}, "LCASE": {1, 1}, "ENCODE_FOR_URI": {1, 1},
+	"CONTAINS": {2, 2}, "STRSTARTS": {2, 2}, "STRENDS": {2, 2},
+	"STRBEFORE": {2, 2}, "STRAFTER": {2, 2},
+	"YEAR": {1, 1}, "MONTH": {1, 1}, "DAY": {1, 1}, "HOURS": {1, 1},
+	"MINUTES": {1, 1}, "SECONDS": {1, 1}, "TIMEZONE": {1, 1}, "TZ": {1, 1},
+	"NOW": {0, 0}, "UUID": {0, 0}, "STRUUID": {0, 0},
+	"MD5": {1, 1}, "SHA1": {1, 1}, "SHA256": {1, 1}, "SHA384": {1, 1}, "SHA512": {1, 1},
+	"COALESCE": {0, -1}, "IF": {3, 3}, "STRLANG": {2, 2}, "STRDT": {2, 2},
+	"SAMETERM": {2, 2}, "ISIRI": {1, 1}, "ISBLANK": {1, 1}, "ISLITERAL": {1, 1},
+	"ISNUMERIC": {1, 1}, "REGEX": {2, 3},
+	// RDF 1.2
+	"LANGDIR": {1, 1}, "STRLANGDIR": {3, 3}, "HASLANG": {1, 1}, "HASLANGDIR": {1, 1},
+	"ISTRIPLE": {1, 1}, "TRIPLE": {3, 3}, "SUBJECT": {1, 1}, "PREDICATE": {1, 1}, "OBJECT": {1, 1},
+}
+
+// other names of built-in functions
+var builtinAliases = map[string]string{
+	"URI":   "IRI",
+	"ISURI": "ISIRI",
+}
+
+var aggregateFunctions = map[string]bool{
+	"COUNT": true, "SUM": true, "MIN": true, "MAX": true, "AVG": true, "SAMPLE": true, "GROUP_CONCAT": true,
+}
+
+// the variables used by an expression outside of EXISTS
+func expressionVariables(expression Expression) []Variable {
+	switch e := expression.(type) {
+	case Variable:
+		return []Variable{e}
+	case *Unary:
+		return expressionVariables(e.Operand)
+	case *Binary:
+		return append(expressionVariables(e.Left), expressionVariables(e.Right)...)
+	case *In:
+		ret := expressionVariables(e.Operand)
+		for _, item := range e.List {
+			ret = append(ret, expressionVariables(item)...)
+		}
+		return ret
+	case *Call:
+		ret := []Variable{}
+		for _, argument := range e.Arguments {
+			ret = append(ret, expressionVariables(argument)...)
+		}
+		return ret
+	}
+	return nil
+}
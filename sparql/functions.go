@@ -0,0 +1,901 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"math/big"
+	mathrand "math/rand"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// the value of an expression for a solution, the errors being type errors
+// or unbound variables
+func (this *evaluator) expression(expression Expression, solution Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	switch e := expression.(type) {
+	case Variable:
+		if value, ok := solution[e]; ok {
+			return value, nil
+		}
+		return nil, errUnbound
+	case *Constant:
+		return e.Term, nil
+	case *Unary:
+		return this.unary(e, solution, graphs)
+	case *Binary:
+		return this.binary(e, solution, graphs)
+	case *In:
+		return this.in(e, solution, graphs)
+	case *Exists:
+		return booleanLiteral(this.exists(e.Pattern, solution, graphs) != e.Not), nil
+	case *Call:
+		return this.call(e, solution, graphs)
+	}
+	return nil, fmt.Errorf("unknown expression %s", expression)
+}
+
+// the effective boolean value of the expression, false on errors
+func (this *evaluator) holds(expression Expression, solution Solution, graphs []model.RDFTerm) bool {
+	value, err := this.expression(expression, solution, graphs)
+	if err != nil {
+		return false
+	}
+	ret, err := effectiveBoolean(value)
+	return err == nil && ret
+}
+
+func (this *evaluator) boolean(expression Expression, solution Solution, graphs []model.RDFTerm) (bool, error) {
+	value, err := this.expression(expression, solution, graphs)
+	if err != nil {
+		return false, err
+	}
+	return effectiveBoolean(value)
+}
+
+func (this *evaluator) unary(e *Unary, solution Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	if e.Operator == "!" {
+		value, err := this.boolean(e.Operand, solution, graphs)
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(!value), nil
+	}
+	operand, err := this.expression(e.Operand, solution, graphs)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := numericValue(operand)
+	if !ok {
+		return nil, errType
+	}
+	if e.Operator == "-" {
+		value = negate(value)
+	}
+	return value.literal(), nil
+}
+
+func (this *evaluator) binary(e *Binary, solution Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	switch e.Operator {
+	case "||", "&&":
+		// an error on one side is forgiven when the other side decides
+		left, leftErr := this.boolean(e.Left, solution, graphs)
+		right, rightErr := this.boolean(e.Right, solution, graphs)
+		decisive := e.Operator == "||"
+		switch {
+		case leftErr == nil && left == decisive, rightErr == nil && right == decisive:
+			return booleanLiteral(decisive), nil
+		case leftErr != nil:
+			return nil, leftErr
+		case rightErr != nil:
+			return nil, rightErr
+		}
+		return booleanLiteral(!decisive), nil
+	}
+	left, err := this.expression(e.Left, solution, graphs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := this.expression(e.Right, solution, graphs)
+	if err != nil {
+		return nil, err
+	}
+	switch e.Operator {
+	case "=", "!=":
+		equal, err := equalTerms(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(equal == (e.Operator == "=")), nil
+	case "<", ">", "<=", ">=":
+		c, err := compareValues(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Operator {
+		case "<":
+			return booleanLiteral(c == -1), nil
+		case ">":
+			return booleanLiteral(c == 1), nil
+		case "<=":
+			return booleanLiteral(c == -1 || c == 0), nil
+		}
+		return booleanLiteral(c == 1 || c == 0), nil
+	}
+	a, aok := numericValue(left)
+	b, bok := numericValue(right)
+	if !aok || !bok {
+		return nil, errType
+	}
+	value, err := arithmetic(e.Operator, a, b)
+	if err != nil {
+		return nil, err
+	}
+	return value.literal(), nil
+}
+
+func (this *evaluator) in(e *In, solution Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	operand, err := this.expression(e.Operand, solution, graphs)
+	if err != nil {
+		return nil, err
+	}
+	var failure error
+	for _, item := range e.List {
+		value, err := this.expression(item, solution, graphs)
+		if err == nil {
+			var equal bool
+			if equal, err = equalTerms(operand, value); err == nil && equal {
+				return booleanLiteral(!e.Not), nil
+			}
+		}
+		if err != nil {
+			failure = err
+		}
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return booleanLiteral(e.Not), nil
+}
+
+// EXISTS: whether the pattern, with the variables of the solution replaced
+// by their values, has a solution compatible with it
+func (this *evaluator) exists(pattern Operator, solution Solution, graphs []model.RDFTerm) bool {
+	for _, candidate := range this.evaluate(substitute(pattern, solution), graphs) {
+		if compatible(candidate, solution) {
+			return true
+		}
+	}
+	return false
+}
+
+// the operator with the variables bound by solution replaced by their
+// values, sub-queries apart
+func substitute(operator Operator, solution Solution) Operator {
+	switch op := operator.(type) {
+	case *BGP:
+		ret := &BGP{Patterns: make([]*TriplePattern, len(op.Patterns))}
+		for i, pattern := range op.Patterns {
+			ret.Patterns[i] = &TriplePattern{
+				Subject:   substitutePattern(pattern.Subject, solution),
+				Predicate: substitutePattern(pattern.Predicate, solution),
+				Object:    substitutePattern(pattern.Object, solution),
+			}
+		}
+		return ret
+	case *Join:
+		return &Join{Left: substitute(op.Left, solution), Right: substitute(op.Right, solution)}
+	case *LeftJoin:
+		ret := &LeftJoin{Left: substitute(op.Left, solution), Right: substitute(op.Right, solution)}
+		if op.Expression != nil {
+			ret.Expression = substituteExpression(op.Expression, solution)
+		}
+		return ret
+	case *Filter:
+		return &Filter{Expression: substituteExpression(op.Expression, solution), Input: substitute(op.Input, solution)}
+	case *Union:
+		return &Union{Left: substitute(op.Left, solution), Right: substitute(op.Right, solution)}
+	case *Minus:
+		return &Minus{Left: substitute(op.Left, solution), Right: substitute(op.Right, solution)}
+	case *Graph:
+		return &Graph{Name: substitutePattern(op.Name, solution), Input: substitute(op.Input, solution)}
+	case *Extend:
+		return &Extend{Input: substitute(op.Input, solution), Variable: op.Variable, Expression: substituteExpression(op.Expression, solution)}
+	}
+	return operator
+}
+
+// a term of a pattern with the bound variables replaced
+func substitutePattern(term model.RDFTerm, solution Solution) model.RDFTerm {
+	switch t := term.(type) {
+	case Variable:
+		if value, ok := solution[t]; ok {
+			return value
+		}
+	case model.TripleTerm:
+		return model.TripleTerm{
+			Subject:   substitutePattern(t.Subject, solution),
+			Predicate: substitutePattern(t.Predicate, solution),
+			Object:    substitutePattern(t.Object, solution),
+		}
+	}
+	return term
+}
+
+func substituteExpression(expression Expression, solution Solution) Expression {
+	switch e := expression.(type) {
+	case Variable:
+		if value, ok := solution[e]; ok {
+			return &Constant{Term: value}
+		}
+	case *Unary:
+		return &Unary{Operator: e.Operator, Operand: substituteExpression(e.Operand, solution)}
+	case *Binary:
+		return &Binary{Operator: e.Operator, Left: substituteExpression(e.Left, solution), Right: substituteExpression(e.Right, solution)}
+	case *In:
+		ret := &In{Operand: substituteExpression(e.Operand, solution), Not: e.Not}
+		for _, item := range e.List {
+			ret.List = append(ret.List, substituteExpression(item, solution))
+		}
+		return ret
+	case *Call:
+		ret := &Call{Function: e.Function}
+		for _, argument := range e.Arguments {
+			ret.Arguments = append(ret.Arguments, substituteExpression(argument, solution))
+		}
+		return ret
+	case *Exists:
+		return &Exists{Pattern: substitute(e.Pattern, solution), Not: e.Not}
+	}
+	return expression
+}
+
+// function calls
+
+func (this *evaluator) call(e *Call, solution Solution, graphs []model.RDFTerm) (model.RDFTerm, error) {
+	// the functions which do not evaluate all their arguments
+	switch e.Function {
+	case "BOUND":
+		switch argument := e.Arguments[0].(type) {
+		case Variable:
+			_, ok := solution[argument]
+			return booleanLiteral(ok), nil
+		case *Constant:
+			// substituted by EXISTS
+			return booleanLiteral(true), nil
+		}
+		return nil, errType
+	case "IF":
+		condition, err := this.boolean(e.Arguments[0], solution, graphs)
+		if err != nil {
+			return nil, err
+		}
+		if condition {
+			return this.expression(e.Arguments[1], solution, graphs)
+		}
+		return this.expression(e.Arguments[2], solution, graphs)
+	case "COALESCE":
+		for _, argument := range e.Arguments {
+			if value, err := this.expression(argument, solution, graphs); err == nil {
+				return value, nil
+			}
+		}
+		return nil, errType
+	}
+	arguments := make([]model.RDFTerm, len(e.Arguments))
+	for i, argument := range e.Arguments {
+		value, err := this.expression(argument, solution, graphs)
+		if err != nil {
+			return nil, err
+		}
+		arguments[i] = value
+	}
+	if IsExtensionFunction(e.Function) {
+		if cast, ok := casts[model.IRI(e.Function)]; ok && len(arguments) == 1 {
+			return cast(arguments[0])
+		}
+		return nil, fmt.Errorf("unknown function <%s>", e.Function)
+	}
+	switch e.Function {
+	case "IRI":
+		switch argument := arguments[0].(type) {
+		case model.IRI:
+			return argument, nil
+		case model.Literal:
+			if isString(argument) {
+				return resolveIRI(this.base, argument.Lexical), nil
+			}
+		}
+		return nil, errType
+	case "BNODE":
+		if len(arguments) == 0 {
+			return model.NewAnonymousBlankNode(), nil
+		}
+		if !isString(arguments[0]) {
+			return nil, errType
+		}
+		// the same string gives the same node for a solution
+		label := arguments[0].(model.Literal).Lexical
+		if node, ok := this.blankNodes[label]; ok {
+			return node, nil
+		}
+		node := model.NewAnonymousBlankNode()
+		this.blankNodes[label] = node
+		return node, nil
+	case "REGEX", "REPLACE":
+		return this.regex(e.Function, arguments)
+	case "NOW":
+		return model.NewTypedLiteral(this.now.Format(time.RFC3339Nano), model.XSDDateTime), nil
+	}
+	function, ok := functions[e.Function]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", e.Function)
+	}
+	return function(arguments)
+}
+
+// the built-in functions which evaluate all their arguments
+var functions = map[string]func(arguments []model.RDFTerm) (model.RDFTerm, error){
+	"STR": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		switch argument := arguments[0].(type) {
+		case model.IRI:
+			return model.NewStringLiteral(string(argument)), nil
+		case model.Literal:
+			return model.NewStringLiteral(argument.Lexical), nil
+		}
+		return nil, errType
+	},
+	"LANG": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := arguments[0].(model.Literal)
+		if !ok {
+			return nil, errType
+		}
+		return model.NewStringLiteral(literal.Language), nil
+	},
+	"LANGMATCHES": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		if !isString(arguments[0]) || !isString(arguments[1]) {
+			return nil, errType
+		}
+		tag := strings.ToLower(arguments[0].(model.Literal).Lexical)
+		pattern := strings.ToLower(arguments[1].(model.Literal).Lexical)
+		if pattern == "*" {
+			return booleanLiteral(tag != ""), nil
+		}
+		return booleanLiteral(tag == pattern || strings.HasPrefix(tag, pattern+"-")), nil
+	},
+	"DATATYPE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := arguments[0].(model.Literal)
+		if !ok {
+			return nil, errType
+		}
+		if literal.Datatype == "" {
+			return model.XSDString, nil
+		}
+		return literal.Datatype, nil
+	},
+	"RAND": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return numeric{kind: doubleType, float: mathrand.Float64()}.literal(), nil
+	},
+	"ABS":   numericFunction(absNumber),
+	"CEIL":  numericFunction(ceilNumber),
+	"FLOOR": numericFunction(floorNumber),
+	"ROUND": numericFunction(roundNumber),
+	"CONCAT": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		var ret strings.Builder
+		language, sameLanguage := "", true
+		for i, argument := range arguments {
+			literal, ok := argument.(model.Literal)
+			if !ok || (!isString(literal) && !isLanguageString(literal)) {
+				return nil, errType
+			}
+			if i == 0 {
+				language = literal.Language
+			} else if literal.Language != language {
+				sameLanguage = false
+			}
+			ret.WriteString(literal.Lexical)
+		}
+		if sameLanguage && language != "" {
+			return model.NewLangLiteral(ret.String(), language, ""), nil
+		}
+		return model.NewStringLiteral(ret.String()), nil
+	},
+	"SUBSTR": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := stringArgument(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		start, ok := numericValue(arguments[1])
+		if !ok {
+			return nil, errType
+		}
+		runes := []rune(literal.Lexical)
+		// the characters at the positions p, counted from 1, such that
+		// round(start) <= p < round(start) + round(length)
+		from := roundNumber(start.promote(doubleType)).float
+		to := math.Inf(1)
+		if len(arguments) == 3 {
+			length, ok := numericValue(arguments[2])
+			if !ok {
+				return nil, errType
+			}
+			to = from + roundNumber(length.promote(doubleType)).float
+		}
+		var ret strings.Builder
+		for i, r := range runes {
+			if position := float64(i + 1); position >= from && position < to {
+				ret.WriteRune(r)
+			}
+		}
+		return withLexical(literal, ret.String()), nil
+	},
+	"STRLEN": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := stringArgument(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		return integerValue(int64(utf8.RuneCountInString(literal.Lexical))).literal(), nil
+	},
+	"UCASE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := stringArgument(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		return withLexical(literal, strings.ToUpper(literal.Lexical)), nil
+	},
+	"LCASE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := stringArgument(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		return withLexical(literal, strings.ToLower(literal.Lexical)), nil
+	},
+	"ENCODE_FOR_URI": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := stringArgument(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		var ret strings.Builder
+		for _, b := range []byte(literal.Lexical) {
+			if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("-_.~", b) >= 0 {
+				ret.WriteByte(b)
+			} else {
+				fmt.Fprintf(&ret, "%%%02X", b)
+			}
+		}
+		return model.NewStringLiteral(ret.String()), nil
+	},
+	"CONTAINS":  stringTest(strings.Contains),
+	"STRSTARTS": stringTest(strings.HasPrefix),
+	"STRENDS":   stringTest(strings.HasSuffix),
+	"STRBEFORE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, search, err := compatibleArguments(arguments)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(literal.Lexical, search)
+		if i < 0 {
+			return model.NewStringLiteral(""), nil
+		}
+		return withLexical(literal, literal.Lexical[:i]), nil
+	},
+	"STRAFTER": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, search, err := compatibleArguments(arguments)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(literal.Lexical, search)
+		if i < 0 {
+			return model.NewStringLiteral(""), nil
+		}
+		return withLexical(literal, literal.Lexical[i+len(search):]), nil
+	},
+	"YEAR":    dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return integerValue(int64(value.year)).literal(), true }),
+	"MONTH":   dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return integerValue(int64(value.month)).literal(), true }),
+	"DAY":     dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return integerValue(int64(value.day)).literal(), true }),
+	"HOURS":   dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return integerValue(int64(value.hours)).literal(), true }),
+	"MINUTES": dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return integerValue(int64(value.minutes)).literal(), true }),
+	"SECONDS": dateFunction(func(value *dateTime) (model.RDFTerm, bool) {
+		return numeric{kind: decimalType, rational: value.seconds}.literal(), true
+	}),
+	"TIMEZONE": dateFunction(func(value *dateTime) (model.RDFTerm, bool) { return value.timezoneDuration() }),
+	"TZ": dateFunction(func(value *dateTime) (model.RDFTerm, bool) {
+		return model.NewStringLiteral(value.timezone), true
+	}),
+	"UUID": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return model.IRI("urn:uuid:" + newUUID()), nil
+	},
+	"STRUUID": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return model.NewStringLiteral(newUUID()), nil
+	},
+	"MD5":    hashFunction(md5.New),
+	"SHA1":   hashFunction(sha1.New),
+	"SHA256": hashFunction(sha256.New),
+	"SHA384": hashFunction(sha512.New384),
+	"SHA512": hashFunction(sha512.New),
+	"STRLANG": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		if !isString(arguments[0]) || !isString(arguments[1]) || arguments[1].(model.Literal).Lexical == "" {
+			return nil, errType
+		}
+		return model.NewLangLiteral(arguments[0].(model.Literal).Lexical, arguments[1].(model.Literal).Lexical, ""), nil
+	},
+	"STRLANGDIR": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		if !isString(arguments[0]) || !isString(arguments[1]) || !isString(arguments[2]) || arguments[1].(model.Literal).Lexical == "" {
+			return nil, errType
+		}
+		direction := arguments[2].(model.Literal).Lexical
+		if direction != "ltr" && direction != "rtl" {
+			return nil, errType
+		}
+		return model.NewLangLiteral(arguments[0].(model.Literal).Lexical, arguments[1].(model.Literal).Lexical, direction), nil
+	},
+	"STRDT": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		datatype, ok := arguments[1].(model.IRI)
+		if !isString(arguments[0]) || !ok {
+			return nil, errType
+		}
+		return model.NewTypedLiteral(arguments[0].(model.Literal).Lexical, datatype), nil
+	},
+	"SAMETERM": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return booleanLiteral(arguments[0] == arguments[1]), nil
+	},
+	"ISIRI": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		_, ok := arguments[0].(model.IRI)
+		return booleanLiteral(ok), nil
+	},
+	"ISBLANK": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return booleanLiteral(model.IsBlankNode(arguments[0])), nil
+	},
+	"ISLITERAL": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		_, ok := arguments[0].(model.Literal)
+		return booleanLiteral(ok), nil
+	},
+	"ISNUMERIC": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		_, ok := numericValue(arguments[0])
+		return booleanLiteral(ok), nil
+	},
+	"LANGDIR": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := arguments[0].(model.Literal)
+		if !ok {
+			return nil, errType
+		}
+		return model.NewStringLiteral(literal.Direction), nil
+	},
+	"HASLANG": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		return booleanLiteral(isLanguageString(arguments[0])), nil
+	},
+	"HASLANGDIR": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := arguments[0].(model.Literal)
+		return booleanLiteral(ok && literal.Direction != ""), nil
+	},
+	"ISTRIPLE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		_, ok := arguments[0].(model.TripleTerm)
+		return booleanLiteral(ok), nil
+	},
+	"TRIPLE": func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		if !validTriple(arguments[0], arguments[1], arguments[2]) {
+			return nil, errType
+		}
+		return model.TripleTerm{Subject: arguments[0], Predicate: arguments[1], Object: arguments[2]}, nil
+	},
+	"SUBJECT":   tripleFunction(func(triple model.TripleTerm) model.RDFTerm { return triple.Subject }),
+	"PREDICATE": tripleFunction(func(triple model.TripleTerm) model.RDFTerm { return triple.Predicate }),
+	"OBJECT":    tripleFunction(func(triple model.TripleTerm) model.RDFTerm { return triple.Object }),
+}
+
+func numericFunction(f func(numeric) numeric) func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+	return func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		value, ok := numericValue(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		return f(value).literal(), nil
+	}
+}
+
+func dateFunction(f func(*dateTime) (model.RDFTerm, bool)) func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+	return func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		value, ok := dateTimeValue(arguments[0])
+		if !ok {
+			return nil, errType
+		}
+		ret, ok := f(value)
+		if !ok {
+			return nil, errType
+		}
+		return ret, nil
+	}
+}
+
+func hashFunction(algorithm func() hash.Hash) func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+	return func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		if !isString(arguments[0]) {
+			return nil, errType
+		}
+		digest := algorithm()
+		digest.Write([]byte(arguments[0].(model.Literal).Lexical))
+		return model.NewStringLiteral(hex.EncodeToString(digest.Sum(nil))), nil
+	}
+}
+
+func tripleFunction(f func(model.TripleTerm) model.RDFTerm) func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+	return func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		triple, ok := arguments[0].(model.TripleTerm)
+		if !ok {
+			return nil, errType
+		}
+		return f(triple), nil
+	}
+}
+
+// CONTAINS, STRSTARTS and STRENDS
+func stringTest(test func(s, search string) bool) func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+	return func(arguments []model.RDFTerm) (model.RDFTerm, error) {
+		literal, search, err := compatibleArguments(arguments)
+		if err != nil {
+			return nil, err
+		}
+		return booleanLiteral(test(literal.Lexical, search)), nil
+	}
+}
+
+// the string literals and language strings taken by the string functions
+func stringArgument(term model.RDFTerm) (model.Literal, bool) {
+	literal, ok := term.(model.Literal)
+	return literal, ok && (isString(literal) || isLanguageString(literal))
+}
+
+// the arguments of the string functions taking two strings: the second
+// one is a simple literal or has the language of the first one
+func compatibleArguments(arguments []model.RDFTerm) (model.Literal, string, error) {
+	literal, ok := stringArgument(arguments[0])
+	search, searchOk := stringArgument(arguments[1])
+	if !ok || !searchOk || (search.Language != "" && search.Language != literal.Language) {
+		return model.Literal{}, "", errType
+	}
+	return literal, search.Lexical, nil
+}
+
+// a literal with the language or the datatype of literal
+func withLexical(literal model.Literal, lexical string) model.Literal {
+	literal.Lexical = lexical
+	return literal
+}
+
+// a random version 4 UUID
+func newUUID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	bytes[6] = bytes[6]&0x0f | 0x40
+	bytes[8] = bytes[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:])
+}
+
+// REGEX and REPLACE, with the flags of XPath
+func (this *evaluator) regex(function string, arguments []model.RDFTerm) (model.RDFTerm, error) {
+	literal, ok := stringArgument(arguments[0])
+	flagsIndex := 2
+	if function == "REPLACE" {
+		flagsIndex = 3
+	}
+	for i := 1; i < len(arguments); i++ {
+		if !isString(arguments[i]) {
+			return nil, errType
+		}
+	}
+	if !ok {
+		return nil, errType
+	}
+	flags := ""
+	if len(arguments) > flagsIndex {
+		flags = arguments[flagsIndex].(model.Literal).Lexical
+	}
+	pattern, err := this.compile(arguments[1].(model.Literal).Lexical, flags)
+	if err != nil {
+		return nil, err
+	}
+	if function == "REGEX" {
+		return booleanLiteral(pattern.MatchString(literal.Lexical)), nil
+	}
+	replacement, err := replacementTemplate(arguments[2].(model.Literal).Lexical)
+	if err != nil {
+		return nil, err
+	}
+	return withLexical(literal, pattern.ReplaceAllString(literal.Lexical, replacement)), nil
+}
+
+func (this *evaluator) compile(pattern string, flags string) (*regexp.Regexp, error) {
+	key := flags + "/" + pattern
+	if ret, ok := this.patterns[key]; ok {
+		return ret, nil
+	}
+	options := ""
+	for _, flag := range flags {
+		switch flag {
+		case 'i', 'm', 's':
+			options += string(flag)
+		case 'x':
+			pattern = strings.Map(func(r rune) rune {
+				if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+					return -1
+				}
+				return r
+			}, pattern)
+		case 'q':
+			pattern = regexp.QuoteMeta(pattern)
+		default:
+			return nil, fmt.Errorf("unknown regular expression flag %c", flag)
+		}
+	}
+	if options != "" {
+		pattern = "(?" + options + ")" + pattern
+	}
+	ret, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	this.patterns[key] = ret
+	return ret, nil
+}
+
+// the replacement of fn:replace as a template of regexp: $n stands for a
+// group, \$ and \\ for $ and \
+func replacementTemplate(replacement string) (string, error) {
+	var ret strings.Builder
+	for i := 0; i < len(replacement); i++ {
+		switch c := replacement[i]; {
+		case c == '\\' && i+1 < len(replacement) && (replacement[i+1] == '$' || replacement[i+1] == '\\'):
+			i++
+			if replacement[i] == '$' {
+				ret.WriteString("$$")
+			} else {
+				ret.WriteByte('\\')
+			}
+		case c == '\\':
+			return "", errType
+		case c == '$':
+			j := i + 1
+			for j < len(replacement) && replacement[j] >= '0' && replacement[j] <= '9' {
+				j++
+			}
+			if j == i+1 {
+				return "", errType
+			}
+			ret.WriteString("${" + replacement[i+1:j] + "}")
+			i = j - 1
+		default:
+			ret.WriteByte(c)
+		}
+	}
+	return ret.String(), nil
+}
+
+// casts
+
+var casts = map[model.IRI]func(term model.RDFTerm) (model.RDFTerm, error){
+	model.XSDString: func(term model.RDFTerm) (model.RDFTerm, error) {
+		switch t := term.(type) {
+		case model.IRI:
+			return model.NewStringLiteral(string(t)), nil
+		case model.Literal:
+			return model.NewStringLiteral(t.Lexical), nil
+		}
+		return nil, errType
+	},
+	model.XSDBoolean: func(term model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := castable(term)
+		if !ok {
+			return nil, errType
+		}
+		if value, ok := numericValue(literal); ok {
+			return booleanLiteral(!value.isZero() && !value.isNaN()), nil
+		}
+		if literal.Datatype == model.XSDBoolean || isString(literal) {
+			if value, ok := booleanValue(model.Literal{Lexical: strings.TrimSpace(literal.Lexical)}); ok {
+				return booleanLiteral(value), nil
+			}
+		}
+		return nil, errType
+	},
+	model.XSDInteger: func(term model.RDFTerm) (model.RDFTerm, error) {
+		value, err := castNumber(term, integerType)
+		if err != nil {
+			return nil, err
+		}
+		if value.kind >= floatType {
+			if math.IsInf(value.float, 0) || math.IsNaN(value.float) {
+				return nil, errType
+			}
+			value = numeric{kind: decimalType, rational: new(big.Rat).SetFloat64(math.Trunc(value.float))}
+		}
+		// truncates towards zero
+		truncated := new(big.Int).Quo(value.rational.Num(), value.rational.Denom())
+		return numeric{kind: integerType, rational: new(big.Rat).SetInt(truncated)}.literal(), nil
+	},
+	model.XSDDecimal: func(term model.RDFTerm) (model.RDFTerm, error) {
+		value, err := castNumber(term, decimalType)
+		if err != nil {
+			return nil, err
+		}
+		if value.kind >= floatType {
+			if math.IsInf(value.float, 0) || math.IsNaN(value.float) {
+				return nil, errType
+			}
+			value.rational = new(big.Rat).SetFloat64(value.float)
+		}
+		return numeric{kind: decimalType, rational: value.rational}.literal(), nil
+	},
+	model.XSDFloat: func(term model.RDFTerm) (model.RDFTerm, error) {
+		value, err := castNumber(term, floatType)
+		if err != nil {
+			return nil, err
+		}
+		return value.promote(doubleType).promote(floatType).literal(), nil
+	},
+	model.XSDDouble: func(term model.RDFTerm) (model.RDFTerm, error) {
+		value, err := castNumber(term, doubleType)
+		if err != nil {
+			return nil, err
+		}
+		return numeric{kind: doubleType, float: value.promote(doubleType).float}.literal(), nil
+	},
+	model.XSDDateTime: func(term model.RDFTerm) (model.RDFTerm, error) {
+		literal, ok := castable(term)
+		if !ok || (literal.Datatype != model.XSDDateTime && !isString(literal)) {
+			return nil, errType
+		}
+		ret := model.NewTypedLiteral(strings.TrimSpace(literal.Lexical), model.XSDDateTime)
+		if _, ok := dateTimeValue(ret); !ok {
+			return nil, errType
+		}
+		return ret, nil
+	},
+}
+
+// the literals which may be cast
+func castable(term model.RDFTerm) (model.Literal, bool) {
+	literal, ok := term.(model.Literal)
+	return literal, ok && literal.Language == ""
+}
+
+// the value of a number, a boolean or a string read as a number of kind
+func castNumber(term model.RDFTerm, kind int) (numeric, error) {
+	literal, ok := castable(term)
+	if !ok {
+		return numeric{}, errType
+	}
+	if value, ok := numericValue(literal); ok {
+		return value, nil
+	}
+	switch {
+	case literal.Datatype == model.XSDBoolean:
+		value, ok := booleanValue(literal)
+		if !ok {
+			return numeric{}, errType
+		}
+		if value {
+			return integerValue(1), nil
+		}
+		return integerValue(0), nil
+	case isString(literal):
+		lexical := strings.TrimSpace(literal.Lexical)
+		if value, ok := numericValue(model.NewTypedLiteral(lexical, numericDatatypes[kind])); ok {
+			return value, nil
+		}
+	}
+	return numeric{}, errType
+}
@@ -0,0 +1,1651 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+type QueryForm int
+
+const (
+	Select QueryForm = iota
+	Construct
+	Ask
+	Describe
+)
+
+func (this QueryForm) String() string {
+	return [...]string{"SELECT", "CONSTRUCT", "ASK", "DESCRIBE"}[this]
+}
+
+type Query struct {
+	Form QueryForm
+	// the graphs of FROM and FROM NAMED
+	From      []model.IRI
+	FromNamed []model.IRI
+	// the algebra of the query, solution modifiers included
+	Pattern Operator
+	// the variables of the solutions of SELECT
+	Variables []Variable
+	// the triples built for each solution by CONSTRUCT, with blank nodes
+	// standing for new ones
+	Template []*TriplePattern
+	// the IRIs and variables of DESCRIBE
+	Describe []model.RDFTerm
+	// the prefixes and the base declared by the query
+	Namespaces []model.Namespace
+	Base       model.IRI
+	// the IRI relative IRIs are resolved against, for IRI()
+	base model.IRI
+}
+
+type Options struct {
+	// the IRI relative IRIs are resolved against until a BASE
+	Base model.IRI
+	// prefixes known without being declared
+	Namespaces []model.Namespace
+}
+
+// ParseQuery parses a SPARQL 1.1 query into its algebra, syntax errors
+// are *parser.SyntaxError
+func ParseQuery(text string, options Options) (ret *Query, err error) {
+	this := newQueryParser(text, options)
+	defer this.recover(&err)
+	return this.query(), nil
+}
+
+// ParseUpdate parses a SPARQL 1.1 update request, syntax errors are
+// *parser.SyntaxError
+func ParseUpdate(text string, options Options) (ret *Update, err error) {
+	this := newQueryParser(text, options)
+	defer this.recover(&err)
+	return this.update(), nil
+}
+
+type queryParser struct {
+	tokenizer *parser.Tokenizer
+	token     *parser.Token
+	base      model.IRI
+	// the base declared by the query
+	declaredBase model.IRI
+	namespaces   map[model.Prefix]model.IRI
+	prefixes     []model.Prefix
+	// in CONSTRUCT templates and data blocks blank nodes stay blank nodes,
+	// elsewhere they are hidden variables
+	template   bool
+	blankNodes map[string]*model.LabelledBlankNode
+	// numbers the hidden variables
+	counter int
+	// the aggregates of the SELECT being parsed, and whether they may
+	// appear where the parser is
+	aggregates        []*Aggregate
+	aggregatesAllowed bool
+}
+
+func newQueryParser(text string, options Options) *queryParser {
+	this := &queryParser{
+		tokenizer:  parser.NewSPARQLTokenizer(text),
+		base:       options.Base,
+		namespaces: make(map[model.Prefix]model.IRI),
+		blankNodes: make(map[string]*model.LabelledBlankNode),
+	}
+	for _, namespace := range options.Namespaces {
+		this.namespaces[namespace.Prefix] = namespace.IRI
+	}
+	return this
+}
+
+// turns the panics of fail into errors
+func (this *queryParser) recover(err *error) {
+	if r := recover(); r != nil {
+		syntaxError, ok := r.(*parser.SyntaxError)
+		if !ok {
+			panic(r)
+		}
+		*err = syntaxError
+	}
+}
+
+func (this *queryParser) fail(format string, args ...interface{}) {
+	line, col := 1, 1
+	if this.token != nil {
+		line, col = this.token.Line(), this.token.Col()
+	}
+	panic(&parser.SyntaxError{Line: line, Col: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func (this *queryParser) unexpected() {
+	this.fail("unexpected %s", this.token)
+}
+
+func (this *queryParser) advance() {
+	this.token = this.tokenizer.Next()
+	if this.token.Type() == parser.Error {
+		this.fail("%s", this.token.Value())
+	}
+}
+
+func (this *queryParser) is(tokenType parser.TokenType) bool {
+	return this.token.Type() == tokenType
+}
+
+// keywords are case insensitive
+func (this *queryParser) isName(names ...string) bool {
+	if !this.is(parser.Name) {
+		return false
+	}
+	name := strings.ToUpper(this.token.Value())
+	for _, candidate := range names {
+		if name == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *queryParser) isOperator(operator string) bool {
+	return this.is(parser.Operator) && this.token.Value() == operator
+}
+
+func (this *queryParser) expect(tokenType parser.TokenType) *parser.Token {
+	token := this.token
+	if token.Type() != tokenType {
+		this.fail("expected %s, got %s", tokenType, token)
+	}
+	this.advance()
+	return token
+}
+
+func (this *queryParser) expectName(name string) {
+	if !this.isName(name) {
+		this.fail("expected %s, got %s", name, this.token)
+	}
+	this.advance()
+}
+
+func (this *queryParser) expectOperator(operator string) {
+	if !this.isOperator(operator) {
+		this.fail("expected '%s', got %s", operator, this.token)
+	}
+	this.advance()
+}
+
+// a new hidden variable, or a blank node in templates
+func (this *queryParser) newBlankNode() model.RDFTerm {
+	this.counter++
+	if this.template {
+		return model.NewAnonymousBlankNode()
+	}
+	return Variable(".b" + strconv.Itoa(this.counter))
+}
+
+// prologue
+
+func (this *queryParser) prologue() {
+	for {
+		switch this.token.Type() {
+		case parser.Base:
+			this.advance()
+			this.base = this.resolve(this.expect(parser.IRI).Value())
+			this.declaredBase = this.base
+		case parser.Prefix:
+			this.advance()
+			name := this.expect(parser.PNameNS).Value()
+			prefix := model.Prefix(strings.TrimSuffix(name, ":"))
+			if _, ok := this.namespaces[prefix]; !ok {
+				this.prefixes = append(this.prefixes, prefix)
+			}
+			this.namespaces[prefix] = this.resolve(this.expect(parser.IRI).Value())
+		default:
+			return
+		}
+	}
+}
+
+func (this *queryParser) declarations() []model.Namespace {
+	ret := make([]model.Namespace, 0, len(this.prefixes))
+	for _, prefix := range this.prefixes {
+		ret = append(ret, model.Namespace{Prefix: prefix, IRI: this.namespaces[prefix]})
+	}
+	return ret
+}
+
+// queries
+
+func (this *queryParser) query() *Query {
+	this.advance()
+	this.prologue()
+	ret := &Query{}
+	switch {
+	case this.isName("SELECT"):
+		ret.Form = Select
+		ret.Pattern, ret.Variables = this.selectQuery(ret)
+	case this.isName("CONSTRUCT"):
+		ret.Form = Construct
+		this.constructQuery(ret)
+	case this.isName("ASK"):
+		ret.Form = Ask
+		this.advance()
+		this.datasetClauses(ret)
+		ret.Pattern = this.whereClause(false)
+		ret.Pattern, _ = this.modifiers(ret.Pattern, this.solutionModifier(true), Ask)
+	case this.isName("DESCRIBE"):
+		ret.Form = Describe
+		this.describeQuery(ret)
+	default:
+		this.fail("expected SELECT, CONSTRUCT, ASK or DESCRIBE, got %s", this.token)
+	}
+	if !this.is(parser.EOF) {
+		this.unexpected()
+	}
+	ret.Namespaces = this.declarations()
+	ret.Base = this.declaredBase
+	ret.base = this.base
+	return ret
+}
+
+// an expression of the SELECT clause
+type selectItem struct {
+	variable   Variable
+	expression Expression
+}
+
+// the clauses following WHERE
+type solutionModifier struct {
+	distinct bool
+	reduced  bool
+	// nil for *
+	items  []*selectItem
+	keys   []*GroupKey
+	having []Expression
+	order  []*OrderCondition
+	offset int
+	limit  int
+	values *Table
+}
+
+// query is nil for sub-queries
+func (this *queryParser) selectQuery(query *Query) (Operator, []Variable) {
+	aggregates := this.aggregates
+	defer func() { this.aggregates = aggregates }()
+	this.aggregates = nil
+
+	this.expectName("SELECT")
+	modifier := &solutionModifier{}
+	if this.isName("DISTINCT") {
+		modifier.distinct = true
+		this.advance()
+	} else if this.isName("REDUCED") {
+		modifier.reduced = true
+		this.advance()
+	}
+	if this.isOperator("*") {
+		this.advance()
+	} else {
+		modifier.items = this.selectItems()
+	}
+	if query != nil {
+		this.datasetClauses(query)
+	}
+	pattern := this.whereClause(false)
+	this.solutionModifierInto(modifier, true)
+	return this.modifiers(pattern, modifier, Select)
+}
+
+func (this *queryParser) selectItems() []*selectItem {
+	ret := []*selectItem{}
+	for {
+		switch {
+		case this.is(parser.Variable):
+			ret = append(ret, &selectItem{variable: Variable(this.token.Value())})
+			this.advance()
+			continue
+		case this.is(parser.CollectionOpening):
+			this.advance()
+			this.aggregatesAllowed = true
+			expression := this.expression()
+			this.aggregatesAllowed = false
+			this.expectName("AS")
+			variable := this.variable()
+			this.expect(parser.CollectionClosing)
+			ret = append(ret, &selectItem{variable: variable, expression: expression})
+			continue
+		}
+		if len(ret) == 0 {
+			this.fail("expected variables or *, got %s", this.token)
+		}
+		return ret
+	}
+}
+
+func (this *queryParser) variable() Variable {
+	return Variable(this.expect(parser.Variable).Value())
+}
+
+func (this *queryParser) constructQuery(query *Query) {
+	this.expectName("CONSTRUCT")
+	if this.is(parser.GraphOpening) {
+		this.advance()
+		this.template = true
+		query.Template = this.triplesTemplate()
+		this.template = false
+		this.expect(parser.GraphClosing)
+		this.datasetClauses(query)
+		query.Pattern = this.whereClause(false)
+	} else {
+		// CONSTRUCT WHERE { triples }, the template is the pattern
+		this.datasetClauses(query)
+		this.expectName("WHERE")
+		this.expect(parser.GraphOpening)
+		query.Template = this.triplesTemplate()
+		this.expect(parser.GraphClosing)
+		query.Pattern = &BGP{Patterns: query.Template}
+	}
+	query.Pattern, _ = this.modifiers(query.Pattern, this.solutionModifier(true), Construct)
+}
+
+// triples separated by dots, up to }
+func (this *queryParser) triplesTemplate() []*TriplePattern {
+	ret := []*TriplePattern{}
+	for this.startsTriples() {
+		this.triplesSameSubject(&ret)
+		if !this.is(parser.Dot) {
+			break
+		}
+		this.advance()
+	}
+	return ret
+}
+
+func (this *queryParser) describeQuery(query *Query) {
+	this.expectName("DESCRIBE")
+	star := this.isOperator("*")
+	if star {
+		this.advance()
+	} else {
+		for this.is(parser.Variable) || this.is(parser.IRI) || this.is(parser.PNameLN) || this.is(parser.PNameNS) {
+			query.Describe = append(query.Describe, this.varOrTerm())
+		}
+		if len(query.Describe) == 0 {
+			this.fail("expected IRIs, variables or *, got %s", this.token)
+		}
+	}
+	this.datasetClauses(query)
+	query.Pattern = this.whereClause(true)
+	query.Pattern, _ = this.modifiers(query.Pattern, this.solutionModifier(true), Describe)
+	if star {
+		for _, variable := range visible(inScope(query.Pattern)) {
+			query.Describe = append(query.Describe, variable)
+		}
+	}
+}
+
+func (this *queryParser) datasetClauses(query *Query) {
+	for this.isName("FROM") {
+		this.advance()
+		if this.isName("NAMED") {
+			this.advance()
+			query.FromNamed = append(query.FromNamed, this.iri())
+		} else {
+			query.From = append(query.From, this.iri())
+		}
+	}
+}
+
+// WHERE is optional, so is the clause with optional set
+func (this *queryParser) whereClause(optional bool) Operator {
+	if this.isName("WHERE") {
+		this.advance()
+	} else if optional && !this.is(parser.GraphOpening) {
+		return &BGP{}
+	}
+	return this.groupGraphPattern()
+}
+
+func (this *queryParser) solutionModifier(values bool) *solutionModifier {
+	ret := &solutionModifier{}
+	this.solutionModifierInto(ret, values)
+	return ret
+}
+
+// GROUP BY, HAVING, ORDER BY, LIMIT, OFFSET and VALUES
+func (this *queryParser) solutionModifierInto(modifier *solutionModifier, values bool) {
+	modifier.limit = -1
+	if this.isName("GROUP") {
+		this.advance()
+		this.expectName("BY")
+		for {
+			key := this.groupCondition()
+			if key == nil {
+				break
+			}
+			modifier.keys = append(modifier.keys, key)
+		}
+		if len(modifier.keys) == 0 {
+			this.fail("expected a group condition, got %s", this.token)
+		}
+	}
+	this.aggregatesAllowed = true
+	if this.isName("HAVING") {
+		this.advance()
+		for this.startsConstraint() {
+			modifier.having = append(modifier.having, this.constraint())
+		}
+		if len(modifier.having) == 0 {
+			this.fail("expected a constraint, got %s", this.token)
+		}
+	}
+	if this.isName("ORDER") {
+		this.advance()
+		this.expectName("BY")
+		for {
+			condition := this.orderCondition()
+			if condition == nil {
+				break
+			}
+			modifier.order = append(modifier.order, condition)
+		}
+		if len(modifier.order) == 0 {
+			this.fail("expected an order condition, got %s", this.token)
+		}
+	}
+	this.aggregatesAllowed = false
+	for i := 0; i < 2; i++ {
+		switch {
+		case this.isName("LIMIT") && modifier.limit < 0:
+			this.advance()
+			modifier.limit = this.integer()
+		case this.isName("OFFSET") && modifier.offset == 0:
+			this.advance()
+			modifier.offset = this.integer()
+		}
+	}
+	if values && this.isName("VALUES") {
+		modifier.values = this.dataBlock()
+	}
+}
+
+func (this *queryParser) integer() int {
+	token := this.expect(parser.Number)
+	ret, err := strconv.Atoi(token.Value())
+	if err != nil || ret < 0 || strings.HasPrefix(token.Value(), "+") {
+		this.fail("invalid integer %q", token.Value())
+	}
+	return ret
+}
+
+// nil when there are no more conditions
+func (this *queryParser) groupCondition() *GroupKey {
+	switch {
+	case this.is(parser.Variable):
+		variable := this.variable()
+		return &GroupKey{Expression: variable, Variable: variable}
+	case this.is(parser.CollectionOpening):
+		this.advance()
+		expression := this.expression()
+		key := &GroupKey{Expression: expression}
+		if this.isName("AS") {
+			this.advance()
+			key.Variable = this.variable()
+		} else if variable, ok := expression.(Variable); ok {
+			key.Variable = variable
+		}
+		this.expect(parser.CollectionClosing)
+		return key
+	case this.startsConstraint():
+		return &GroupKey{Expression: this.constraint()}
+	}
+	return nil
+}
+
+// nil when there are no more conditions
+func (this *queryParser) orderCondition() *OrderCondition {
+	switch {
+	case this.isName("ASC", "DESC"):
+		descending := this.isName("DESC")
+		this.advance()
+		this.expect(parser.CollectionOpening)
+		expression := this.expression()
+		this.expect(parser.CollectionClosing)
+		return &OrderCondition{Expression: expression, Descending: descending}
+	case this.is(parser.Variable):
+		return &OrderCondition{Expression: this.variable()}
+	case this.startsConstraint():
+		return &OrderCondition{Expression: this.constraint()}
+	}
+	return nil
+}
+
+// the algebra of the solution modifiers over pattern, and the projected
+// variables of SELECT
+func (this *queryParser) modifiers(pattern Operator, modifier *solutionModifier, form QueryForm) (Operator, []Variable) {
+	grouped := len(modifier.keys) > 0 || len(this.aggregates) > 0
+	if grouped {
+		pattern = &Group{Input: pattern, Keys: modifier.keys, Aggregates: this.aggregates}
+	}
+	if len(modifier.having) > 0 {
+		pattern = &Filter{Expression: conjunction(modifier.having), Input: pattern}
+	}
+	if modifier.values != nil {
+		pattern = &Join{Left: pattern, Right: modifier.values}
+	}
+	variables := []Variable{}
+	if form == Select {
+		if modifier.items == nil {
+			if grouped {
+				this.fail("SELECT * cannot be used with GROUP BY")
+			}
+			variables = visible(inScope(pattern))
+		}
+		for _, item := range modifier.items {
+			scope := inScope(pattern)
+			if item.expression != nil {
+				if contains(scope, item.variable) {
+					this.fail("%s is already bound", item.variable)
+				}
+				if grouped {
+					for _, variable := range expressionVariables(item.expression) {
+						if !contains(scope, variable) {
+							this.fail("%s is not a group key", variable)
+						}
+					}
+				}
+				pattern = &Extend{Input: pattern, Variable: item.variable, Expression: item.expression}
+			} else if grouped && !contains(scope, item.variable) {
+				this.fail("%s is not a group key", item.variable)
+			}
+			variables = append(variables, item.variable)
+		}
+	}
+	if len(modifier.order) > 0 {
+		pattern = &OrderBy{Input: pattern, Conditions: modifier.order}
+	}
+	if form == Select {
+		pattern = &Project{Input: pattern, Variables: variables}
+	}
+	if modifier.distinct {
+		pattern = &Distinct{Input: pattern}
+	} else if modifier.reduced {
+		pattern = &Reduced{Input: pattern}
+	}
+	if modifier.offset > 0 || modifier.limit >= 0 {
+		pattern = &Slice{Input: pattern, Offset: modifier.offset, Limit: modifier.limit}
+	}
+	return pattern, variables
+}
+
+func conjunction(expressions []Expression) Expression {
+	ret := expressions[0]
+	for _, expression := range expressions[1:] {
+		ret = &Binary{Operator: "&&", Left: ret, Right: expression}
+	}
+	return ret
+}
+
+func contains(variables []Variable, variable Variable) bool {
+	for _, candidate := range variables {
+		if candidate == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// the variables which are not hidden
+func visible(variables []Variable) []Variable {
+	ret := []Variable{}
+	for _, variable := range variables {
+		if !variable.Hidden() {
+			ret = append(ret, variable)
+		}
+	}
+	return ret
+}
+
+// updates
+
+func (this *queryParser) update() *Update {
+	this.advance()
+	ret := &Update{}
+	for {
+		this.prologue()
+		if this.is(parser.EOF) {
+			break
+		}
+		// the labels of blank nodes are those of one operation
+		this.blankNodes = make(map[string]*model.LabelledBlankNode)
+		ret.Operations = append(ret.Operations, this.updateOperation())
+		if !this.is(parser.SemiColumn) {
+			break
+		}
+		this.advance()
+	}
+	if !this.is(parser.EOF) {
+		this.unexpected()
+	}
+	ret.Namespaces = this.declarations()
+	ret.Base = this.declaredBase
+	return ret
+}
+
+func (this *queryParser) updateOperation() UpdateOperation {
+	switch {
+	case this.isName("LOAD"):
+		this.advance()
+		ret := &Load{Silent: this.silentKeyword(), Source: this.iri()}
+		if this.isName("INTO") {
+			this.advance()
+			ret.Into = this.graphRef()
+		}
+		return ret
+	case this.isName("CLEAR"):
+		this.advance()
+		ret := &Clear{Silent: this.silentKeyword()}
+		ret.Scope, ret.Graph = this.graphRefAll()
+		return ret
+	case this.isName("DROP"):
+		this.advance()
+		ret := &Drop{Silent: this.silentKeyword()}
+		ret.Scope, ret.Graph = this.graphRefAll()
+		return ret
+	case this.isName("CREATE"):
+		this.advance()
+		return &Create{Silent: this.silentKeyword(), Graph: this.graphRef()}
+	case this.isName("ADD", "COPY", "MOVE"):
+		name := strings.ToUpper(this.token.Value())
+		this.advance()
+		silent := this.silentKeyword()
+		source := this.graphOrDefault()
+		this.expectName("TO")
+		destination := this.graphOrDefault()
+		switch name {
+		case "ADD":
+			return &Add{Silent: silent, Source: source, Destination: destination}
+		case "COPY":
+			return &Copy{Silent: silent, Source: source, Destination: destination}
+		}
+		return &Move{Silent: silent, Source: source, Destination: destination}
+	case this.isName("INSERT"):
+		this.advance()
+		if this.isName("DATA") {
+			this.advance()
+			return &InsertData{Quads: this.quadData("INSERT DATA")}
+		}
+		return this.modify(&Modify{}, false)
+	case this.isName("DELETE"):
+		this.advance()
+		if this.isName("DATA") {
+			this.advance()
+			quads := this.quadData("DELETE DATA")
+			if anyTerm(quads, model.IsBlankNode) {
+				this.fail("blank nodes are not allowed in DELETE DATA")
+			}
+			return &DeleteData{Quads: quads}
+		}
+		if this.isName("WHERE") {
+			// the pattern is also the template
+			this.advance()
+			quads := this.quadPattern(false)
+			return &Modify{Delete: quads, Pattern: quadsPattern(quads), base: this.base}
+		}
+		return this.modify(&Modify{}, true)
+	case this.isName("WITH"):
+		this.advance()
+		ret := &Modify{With: this.iri()}
+		if this.isName("DELETE") {
+			this.advance()
+			return this.modify(ret, true)
+		}
+		this.expectName("INSERT")
+		return this.modify(ret, false)
+	}
+	this.fail("expected an update operation, got %s", this.token)
+	return nil
+}
+
+func (this *queryParser) silentKeyword() bool {
+	if this.isName("SILENT") {
+		this.advance()
+		return true
+	}
+	return false
+}
+
+// GRAPH iri
+func (this *queryParser) graphRef() model.IRI {
+	this.expect(parser.Graph)
+	return this.iri()
+}
+
+// GRAPH iri, DEFAULT, NAMED or ALL
+func (this *queryParser) graphRefAll() (GraphScope, model.IRI) {
+	switch {
+	case this.isName("DEFAULT"):
+		this.advance()
+		return ScopeDefault, ""
+	case this.isName("NAMED"):
+		this.advance()
+		return ScopeNamed, ""
+	case this.isName("ALL"):
+		this.advance()
+		return ScopeAll, ""
+	}
+	return ScopeGraph, this.graphRef()
+}
+
+// the empty IRI for DEFAULT
+func (this *queryParser) graphOrDefault() model.IRI {
+	if this.isName("DEFAULT") {
+		this.advance()
+		return ""
+	}
+	if this.is(parser.Graph) {
+		this.advance()
+	}
+	return this.iri()
+}
+
+// the clauses of DELETE/INSERT following the keyword, which was DELETE
+// when deleting is set
+func (this *queryParser) modify(ret *Modify, deleting bool) *Modify {
+	if deleting {
+		ret.Delete = this.quadPattern(false)
+		if this.isName("INSERT") {
+			this.advance()
+			ret.Insert = this.quadPattern(true)
+		}
+	} else {
+		ret.Insert = this.quadPattern(true)
+	}
+	for this.isName("USING") {
+		this.advance()
+		if this.isName("NAMED") {
+			this.advance()
+			ret.UsingNamed = append(ret.UsingNamed, this.iri())
+		} else {
+			ret.Using = append(ret.Using, this.iri())
+		}
+	}
+	this.expectName("WHERE")
+	ret.Pattern = this.groupGraphPattern()
+	ret.base = this.base
+	return ret
+}
+
+// the quads of a template, blank nodes being allowed when blankNodes is
+// set
+func (this *queryParser) quadPattern(blankNodes bool) []*QuadPattern {
+	this.expect(parser.GraphOpening)
+	this.template = true
+	ret := this.quads()
+	this.template = false
+	this.expect(parser.GraphClosing)
+	if !blankNodes && anyTerm(ret, model.IsBlankNode) {
+		this.fail("blank nodes are not allowed in templates of deletions")
+	}
+	return ret
+}
+
+// the quads of INSERT DATA and DELETE DATA, without variables
+func (this *queryParser) quadData(operation string) []*QuadPattern {
+	ret := this.quadPattern(true)
+	if anyTerm(ret, isVariable) {
+		this.fail("variables are not allowed in %s", operation)
+	}
+	return ret
+}
+
+// triples, and triples in GRAPH blocks
+func (this *queryParser) quads() []*QuadPattern {
+	ret := []*QuadPattern{}
+	add := func(graph model.RDFTerm, patterns []*TriplePattern) {
+		for _, pattern := range patterns {
+			ret = append(ret, &QuadPattern{Graph: graph, TriplePattern: *pattern})
+		}
+	}
+	for {
+		switch {
+		case this.startsTriples():
+			add(nil, this.triplesTemplate())
+			if !this.is(parser.Graph) {
+				return ret
+			}
+		case this.is(parser.Graph):
+			this.advance()
+			graph := this.varOrIRI()
+			this.expect(parser.GraphOpening)
+			add(graph, this.triplesTemplate())
+			this.expect(parser.GraphClosing)
+			if this.is(parser.Dot) {
+				this.advance()
+			}
+		default:
+			return ret
+		}
+	}
+}
+
+func isVariable(term model.RDFTerm) bool {
+	_, ok := term.(Variable)
+	return ok
+}
+
+// whether test holds for a term of the quads, those of triple terms
+// included
+func anyTerm(quads []*QuadPattern, test func(model.RDFTerm) bool) bool {
+	var holds func(term model.RDFTerm) bool
+	holds = func(term model.RDFTerm) bool {
+		if triple, ok := term.(model.TripleTerm); ok {
+			return holds(triple.Subject) || holds(triple.Predicate) || holds(triple.Object)
+		}
+		return term != nil && test(term)
+	}
+	for _, quad := range quads {
+		if holds(quad.Graph) || holds(quad.Subject) || holds(quad.Predicate) || holds(quad.Object) {
+			return true
+		}
+	}
+	return false
+}
+
+// the pattern of DELETE WHERE: the triples of the default graph joined
+// with those of each named graph
+func quadsPattern(quads []*QuadPattern) Operator {
+	var ret Operator = &BGP{}
+	graphs := []model.RDFTerm{}
+	patterns := map[model.RDFTerm]*BGP{}
+	for _, quad := range quads {
+		pattern := &TriplePattern{Subject: quad.Subject, Predicate: quad.Predicate, Object: quad.Object}
+		if quad.Graph == nil {
+			ret.(*BGP).Patterns = append(ret.(*BGP).Patterns, pattern)
+			continue
+		}
+		if _, ok := patterns[quad.Graph]; !ok {
+			graphs = append(graphs, quad.Graph)
+			patterns[quad.Graph] = &BGP{}
+		}
+		patterns[quad.Graph].Patterns = append(patterns[quad.Graph].Patterns, pattern)
+	}
+	for _, graph := range graphs {
+		ret = join(ret, &Graph{Name: graph, Input: patterns[graph]})
+	}
+	return ret
+}
+
+// graph patterns
+
+// { ... }, translated as per the section 18.2.2 of the recommendation
+func (this *queryParser) groupGraphPattern() Operator {
+	this.expect(parser.GraphOpening)
+	if this.isName("SELECT") {
+		pattern, _ := this.selectQuery(nil)
+		this.expect(parser.GraphClosing)
+		return pattern
+	}
+	var ret Operator
+	var bgp *BGP
+	filters := []Expression{}
+	// the triples up to the next graph pattern are one BGP
+	flush := func() {
+		if bgp != nil {
+			ret = join(ret, bgp)
+			bgp = nil
+		}
+	}
+	for !this.is(parser.GraphClosing) {
+		switch {
+		case this.startsTriples():
+			if bgp == nil {
+				bgp = &BGP{}
+			}
+			this.triplesSameSubject(&bgp.Patterns)
+		case this.isName("FILTER"):
+			this.advance()
+			filters = append(filters, this.constraint())
+		case this.isName("OPTIONAL"):
+			flush()
+			this.advance()
+			right := this.groupGraphPattern()
+			if filter, ok := right.(*Filter); ok {
+				ret = &LeftJoin{Left: orEmpty(ret), Right: filter.Input, Expression: filter.Expression}
+			} else {
+				ret = &LeftJoin{Left: orEmpty(ret), Right: right}
+			}
+		case this.isName("MINUS"):
+			flush()
+			this.advance()
+			ret = &Minus{Left: orEmpty(ret), Right: this.groupGraphPattern()}
+		case this.isName("BIND"):
+			flush()
+			this.advance()
+			this.expect(parser.CollectionOpening)
+			expression := this.expression()
+			this.expectName("AS")
+			variable := this.variable()
+			this.expect(parser.CollectionClosing)
+			ret = orEmpty(ret)
+			if contains(inScope(ret), variable) {
+				this.fail("%s is already bound", variable)
+			}
+			ret = &Extend{Input: ret, Variable: variable, Expression: expression}
+		case this.isName("VALUES"):
+			flush()
+			ret = join(ret, this.dataBlock())
+		case this.is(parser.Graph):
+			flush()
+			this.advance()
+			name := this.varOrIRI()
+			ret = join(ret, &Graph{Name: name, Input: this.groupGraphPattern()})
+		case this.isName("SERVICE"):
+			this.fail("SERVICE is not supported")
+		case this.is(parser.GraphOpening):
+			flush()
+			union := this.groupGraphPattern()
+			for this.isName("UNION") {
+				this.advance()
+				union = &Union{Left: union, Right: this.groupGraphPattern()}
+			}
+			ret = join(ret, union)
+		default:
+			this.unexpected()
+		}
+		if this.is(parser.Dot) {
+			this.advance()
+		}
+	}
+	this.advance()
+	flush()
+	ret = orEmpty(ret)
+	if len(filters) > 0 {
+		ret = &Filter{Expression: conjunction(filters), Input: ret}
+	}
+	return ret
+}
+
+// the empty group is the empty BGP, which is the identity of Join
+func join(left Operator, right Operator) Operator {
+	if left == nil {
+		return right
+	}
+	if bgp, ok := left.(*BGP); ok && len(bgp.Patterns) == 0 {
+		return right
+	}
+	return &Join{Left: left, Right: right}
+}
+
+func orEmpty(operator Operator) Operator {
+	if operator == nil {
+		return &BGP{}
+	}
+	return operator
+}
+
+// VALUES
+func (this *queryParser) dataBlock() *Table {
+	this.expectName("VALUES")
+	ret := &Table{}
+	single := this.is(parser.Variable)
+	if single {
+		ret.Variables = []Variable{this.variable()}
+	} else if this.is(parser.EmptyCollection) {
+		this.advance()
+	} else {
+		this.expect(parser.CollectionOpening)
+		for this.is(parser.Variable) {
+			ret.Variables = append(ret.Variables, this.variable())
+		}
+		this.expect(parser.CollectionClosing)
+	}
+	this.expect(parser.GraphOpening)
+	for !this.is(parser.GraphClosing) {
+		row := []model.RDFTerm{}
+		if single {
+			row = append(row, this.dataValue())
+		} else if this.is(parser.EmptyCollection) {
+			this.advance()
+		} else {
+			this.expect(parser.CollectionOpening)
+			for !this.is(parser.CollectionClosing) {
+				row = append(row, this.dataValue())
+			}
+			this.advance()
+		}
+		if len(row) != len(ret.Variables) {
+			this.fail("%d values for %d variables", len(row), len(ret.Variables))
+		}
+		ret.Rows = append(ret.Rows, row)
+	}
+	this.advance()
+	return ret
+}
+
+// nil for UNDEF
+func (this *queryParser) dataValue() model.RDFTerm {
+	switch this.token.Type() {
+	case parser.IRI, parser.PNameLN, parser.PNameNS:
+		return this.iri()
+	case parser.String, parser.Number, parser.Boolean:
+		return this.literal()
+	case parser.TripleTermOpening:
+		template := this.template
+		this.template = true
+		defer func() { this.template = template }()
+		return this.tripleTerm()
+	case parser.Name:
+		if this.isName("UNDEF") {
+			this.advance()
+			return nil
+		}
+	}
+	this.unexpected()
+	return nil
+}
+
+// triples
+
+func (this *queryParser) startsTriples() bool {
+	switch this.token.Type() {
+	case parser.Variable, parser.IRI, parser.PNameLN, parser.PNameNS, parser.BlankNodeLabel, parser.BlankNodeAnonymous,
+		parser.BlankNodeOpening, parser.CollectionOpening, parser.EmptyCollection, parser.String, parser.Number,
+		parser.Boolean, parser.TripleTermOpening:
+		return true
+	}
+	return false
+}
+
+func (this *queryParser) triplesSameSubject(patterns *[]*TriplePattern) {
+	if this.is(parser.BlankNodeOpening) || this.is(parser.CollectionOpening) {
+		subject := this.triplesNode(patterns)
+		if this.startsVerb() {
+			this.propertyList(subject, patterns)
+		}
+		return
+	}
+	subject := this.varOrTerm()
+	this.propertyList(subject, patterns)
+}
+
+func (this *queryParser) startsVerb() bool {
+	switch this.token.Type() {
+	case parser.Variable, parser.A, parser.IRI, parser.PNameLN, parser.PNameNS:
+		return true
+	case parser.CollectionOpening:
+		return !this.template
+	}
+	return !this.template && (this.isOperator("^") || this.isOperator("!"))
+}
+
+func (this *queryParser) propertyList(subject model.RDFTerm, patterns *[]*TriplePattern) {
+	for {
+		var predicate model.RDFTerm
+		if this.template || this.is(parser.Variable) {
+			predicate = this.verb()
+		} else {
+			predicate = this.path()
+		}
+		this.objectList(subject, predicate, patterns)
+		if !this.is(parser.SemiColumn) {
+			return
+		}
+		for this.is(parser.SemiColumn) {
+			this.advance()
+		}
+		if !this.startsVerb() {
+			return
+		}
+	}
+}
+
+func (this *queryParser) verb() model.RDFTerm {
+	switch this.token.Type() {
+	case parser.Variable:
+		return this.variable()
+	case parser.A:
+		this.advance()
+		return model.A
+	case parser.IRI, parser.PNameLN, parser.PNameNS:
+		return this.iri()
+	}
+	this.fail("expected a predicate, got %s", this.token)
+	return nil
+}
+
+func (this *queryParser) objectList(subject model.RDFTerm, predicate model.RDFTerm, patterns *[]*TriplePattern) {
+	for {
+		object := this.graphNode(patterns)
+		this.addPattern(subject, predicate, object, patterns)
+		if !this.is(parser.Coma) {
+			return
+		}
+		this.advance()
+	}
+}
+
+// adds a triple pattern, or the patterns of a path: sequences are joined
+// by hidden variables, inverse paths reversed and IRIs are no more paths
+func (this *queryParser) addPattern(subject, predicate, object model.RDFTerm, patterns *[]*TriplePattern) {
+	switch p := predicate.(type) {
+	case Link:
+		predicate = model.IRI(p)
+	case *Inverse:
+		this.addPattern(object, p.Path, subject, patterns)
+		return
+	case *Sequence:
+		this.counter++
+		middle := Variable(".p" + strconv.Itoa(this.counter))
+		this.addPattern(subject, p.Left, middle, patterns)
+		this.addPattern(middle, p.Right, object, patterns)
+		return
+	}
+	*patterns = append(*patterns, &TriplePattern{Subject: subject, Predicate: predicate, Object: object})
+}
+
+// paths
+
+// alternatives of sequences
+func (this *queryParser) path() Path {
+	ret := this.pathSequence()
+	for this.isOperator("|") {
+		this.advance()
+		ret = &Alternative{Left: ret, Right: this.pathSequence()}
+	}
+	return ret
+}
+
+func (this *queryParser) pathSequence() Path {
+	ret := this.pathElement()
+	for this.isOperator("/") {
+		this.advance()
+		ret = &Sequence{Left: ret, Right: this.pathElement()}
+	}
+	return ret
+}
+
+// a primary path, inverse with ^, followed by ?, * or +
+func (this *queryParser) pathElement() Path {
+	inverse := this.isOperator("^")
+	if inverse {
+		this.advance()
+	}
+	var ret Path
+	switch {
+	case this.is(parser.CollectionOpening):
+		this.advance()
+		ret = this.path()
+		this.expect(parser.CollectionClosing)
+	case this.isOperator("!"):
+		this.advance()
+		ret = this.negatedSet()
+	default:
+		ret = this.link()
+	}
+	switch {
+	case this.isOperator("?"):
+		ret = &ZeroOrOne{Path: ret}
+		this.advance()
+	case this.isOperator("*"):
+		ret = &ZeroOrMore{Path: ret}
+		this.advance()
+	case this.isOperator("+"):
+		ret = &OneOrMore{Path: ret}
+		this.advance()
+	}
+	if inverse {
+		return &Inverse{Path: ret}
+	}
+	return ret
+}
+
+func (this *queryParser) link() Link {
+	if this.is(parser.A) {
+		this.advance()
+		return Link(model.A)
+	}
+	if this.is(parser.IRI) || this.is(parser.PNameLN) || this.is(parser.PNameNS) {
+		return Link(this.iri())
+	}
+	this.fail("expected a path, got %s", this.token)
+	return ""
+}
+
+// the IRIs of !iri, !^iri or !(iri|^iri...)
+func (this *queryParser) negatedSet() Path {
+	ret := &NegatedSet{}
+	one := func() {
+		if this.isOperator("^") {
+			this.advance()
+			ret.Inverse = append(ret.Inverse, model.IRI(this.link()))
+		} else {
+			ret.IRIs = append(ret.IRIs, model.IRI(this.link()))
+		}
+	}
+	switch {
+	case this.is(parser.EmptyCollection):
+		this.advance()
+	case this.is(parser.CollectionOpening):
+		this.advance()
+		one()
+		for this.isOperator("|") {
+			this.advance()
+			one()
+		}
+		this.expect(parser.CollectionClosing)
+	default:
+		one()
+	}
+	return ret
+}
+
+func (this *queryParser) graphNode(patterns *[]*TriplePattern) model.RDFTerm {
+	if this.is(parser.BlankNodeOpening) || this.is(parser.CollectionOpening) {
+		return this.triplesNode(patterns)
+	}
+	return this.varOrTerm()
+}
+
+// [ ... ] and ( ... ), their triples are added to patterns
+func (this *queryParser) triplesNode(patterns *[]*TriplePattern) model.RDFTerm {
+	if this.is(parser.BlankNodeOpening) {
+		this.advance()
+		node := this.newBlankNode()
+		this.propertyList(node, patterns)
+		this.expect(parser.BlankNodeClosing)
+		return node
+	}
+	this.expect(parser.CollectionOpening)
+	var head, last model.RDFTerm
+	for !this.is(parser.CollectionClosing) {
+		node := this.newBlankNode()
+		if last == nil {
+			head = node
+		} else {
+			*patterns = append(*patterns, &TriplePattern{Subject: last, Predicate: model.RDFRest, Object: node})
+		}
+		*patterns = append(*patterns, &TriplePattern{Subject: node, Predicate: model.RDFFirst, Object: this.graphNode(patterns)})
+		last = node
+	}
+	this.advance()
+	*patterns = append(*patterns, &TriplePattern{Subject: last, Predicate: model.RDFRest, Object: model.RDFNil})
+	return head
+}
+
+func (this *queryParser) varOrTerm() model.RDFTerm {
+	token := this.token
+	switch token.Type() {
+	case parser.Variable:
+		return this.variable()
+	case parser.IRI, parser.PNameLN, parser.PNameNS:
+		return this.iri()
+	case parser.BlankNodeLabel:
+		this.advance()
+		if !this.template {
+			return Variable("_:" + token.Value())
+		}
+		node, ok := this.blankNodes[token.Value()]
+		if !ok {
+			node = &model.LabelledBlankNode{Label: token.Value()}
+			this.blankNodes[token.Value()] = node
+		}
+		return node
+	case parser.BlankNodeAnonymous:
+		this.advance()
+		return this.newBlankNode()
+	case parser.EmptyCollection:
+		this.advance()
+		return model.RDFNil
+	case parser.String, parser.Number, parser.Boolean:
+		return this.literal()
+	case parser.TripleTermOpening:
+		return this.tripleTerm()
+	}
+	this.fail("expected a term, got %s", this.token)
+	return nil
+}
+
+func (this *queryParser) varOrIRI() model.RDFTerm {
+	if this.is(parser.Variable) {
+		return this.variable()
+	}
+	return this.iri()
+}
+
+// <<( s p o )>>
+func (this *queryParser) tripleTerm() model.RDFTerm {
+	this.expect(parser.TripleTermOpening)
+	subject := this.varOrTerm()
+	predicate := this.verb()
+	object := this.varOrTerm()
+	this.expect(parser.TripleTermClosing)
+	return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}
+}
+
+// terms
+
+func (this *queryParser) iri() model.IRI {
+	token := this.token
+	switch token.Type() {
+	case parser.IRI:
+		this.advance()
+		return this.resolve(token.Value())
+	case parser.PNameLN, parser.PNameNS:
+		separator := strings.IndexRune(token.Value(), ':')
+		prefix := model.Prefix(token.Value()[:separator])
+		namespace, ok := this.namespaces[prefix]
+		if !ok {
+			this.fail("undefined prefix %q", prefix)
+		}
+		this.advance()
+		return namespace + model.IRI(token.Value()[separator+1:])
+	}
+	this.fail("expected an IRI, got %s", token)
+	return ""
+}
+
+func (this *queryParser) literal() model.Literal {
+	token := this.token
+	switch token.Type() {
+	case parser.Number:
+		this.advance()
+		return numberLiteral(token.Value())
+	case parser.Boolean:
+		this.advance()
+		return model.NewTypedLiteral(token.Value(), model.XSDBoolean)
+	case parser.String:
+		this.advance()
+		switch this.token.Type() {
+		case parser.LangTag:
+			language := this.token.Value()
+			direction := ""
+			if separator := strings.Index(language, "--"); separator >= 0 {
+				direction = language[separator+2:]
+				language = language[:separator]
+				if direction != "ltr" && direction != "rtl" {
+					this.fail("invalid language direction %q", direction)
+				}
+			}
+			this.advance()
+			return model.NewLangLiteral(token.Value(), language, direction)
+		case parser.DoubleCaret:
+			this.advance()
+			return model.NewTypedLiteral(token.Value(), this.iri())
+		}
+		return model.NewStringLiteral(token.Value())
+	}
+	this.fail("expected a literal, got %s", token)
+	return model.Literal{}
+}
+
+func numberLiteral(value string) model.Literal {
+	if strings.ContainsAny(value, "eE") {
+		return model.NewTypedLiteral(value, model.XSDDouble)
+	}
+	if strings.ContainsRune(value, '.') {
+		return model.NewTypedLiteral(value, model.XSDDecimal)
+	}
+	return model.NewTypedLiteral(value, model.XSDInteger)
+}
+
+func (this *queryParser) resolve(iri string) model.IRI {
+	return resolveIRI(this.base, iri)
+}
+
+// resolves iri against base, as per RFC 3986
+func resolveIRI(base model.IRI, iri string) model.IRI {
+	if base == "" {
+		return model.IRI(iri)
+	}
+	reference, err := url.Parse(iri)
+	if err != nil || reference.IsAbs() {
+		return model.IRI(iri)
+	}
+	parsed, err := url.Parse(string(base))
+	if err != nil {
+		return model.IRI(iri)
+	}
+	return model.IRI(parsed.ResolveReference(reference).String())
+}
+
+// expressions
+
+func (this *queryParser) startsConstraint() bool {
+	switch this.token.Type() {
+	case parser.CollectionOpening, parser.Name, parser.IRI, parser.PNameLN, parser.PNameNS:
+		return !this.isName("AS", "LIMIT", "OFFSET", "VALUES", "ORDER", "HAVING", "GROUP")
+	}
+	return false
+}
+
+// a bracketted expression or a function call
+func (this *queryParser) constraint() Expression {
+	if this.is(parser.CollectionOpening) {
+		this.advance()
+		ret := this.expression()
+		this.expect(parser.CollectionClosing)
+		return ret
+	}
+	if this.is(parser.Name) {
+		return this.builtinCall()
+	}
+	function := this.iri()
+	if !this.is(parser.CollectionOpening) && !this.is(parser.EmptyCollection) {
+		this.fail("expected arguments, got %s", this.token)
+	}
+	return &Call{Function: string(function), Arguments: this.arguments()}
+}
+
+func (this *queryParser) expression() Expression {
+	left := this.and()
+	for this.isOperator("||") {
+		this.advance()
+		left = &Binary{Operator: "||", Left: left, Right: this.and()}
+	}
+	return left
+}
+
+func (this *queryParser) and() Expression {
+	left := this.relational()
+	for this.isOperator("&&") {
+		this.advance()
+		left = &Binary{Operator: "&&", Left: left, Right: this.relational()}
+	}
+	return left
+}
+
+func (this *queryParser) relational() Expression {
+	left := this.additive()
+	if this.is(parser.Operator) {
+		switch operator := this.token.Value(); operator {
+		case "=", "!=", "<", ">", "<=", ">=":
+			this.advance()
+			return &Binary{Operator: operator, Left: left, Right: this.additive()}
+		}
+	}
+	if this.isName("IN") {
+		this.advance()
+		return &In{Operand: left, List: this.arguments()}
+	}
+	if this.isName("NOT") {
+		this.advance()
+		this.expectName("IN")
+		return &In{Operand: left, List: this.arguments(), Not: true}
+	}
+	return left
+}
+
+func (this *queryParser) additive() Expression {
+	left := this.multiplicative()
+	for {
+		switch {
+		case this.isOperator("+") || this.isOperator("-"):
+			operator := this.token.Value()
+			this.advance()
+			left = &Binary{Operator: operator, Left: left, Right: this.multiplicative()}
+		case this.is(parser.Number) && strings.ContainsAny(this.token.Value()[:1], "+-"):
+			// 1 -2 is read as 1 - 2
+			operator := this.token.Value()[:1]
+			var right Expression = &Constant{Term: numberLiteral(this.token.Value()[1:])}
+			this.advance()
+			for this.isOperator("*") || this.isOperator("/") {
+				multiplicative := this.token.Value()
+				this.advance()
+				right = &Binary{Operator: multiplicative, Left: right, Right: this.unary()}
+			}
+			left = &Binary{Operator: operator, Left: left, Right: right}
+		default:
+			return left
+		}
+	}
+}
+
+func (this *queryParser) multiplicative() Expression {
+	left := this.unary()
+	for this.isOperator("*") || this.isOperator("/") {
+		operator := this.token.Value()
+		this.advance()
+		left = &Binary{Operator: operator, Left: left, Right: this.unary()}
+	}
+	return left
+}
+
+func (this *queryParser) unary() Expression {
+	if this.isOperator("!") || this.isOperator("+") || this.isOperator("-") {
+		operator := this.token.Value()
+		this.advance()
+		return &Unary{Operator: operator, Operand: this.primary()}
+	}
+	return this.primary()
+}
+
+func (this *queryParser) primary() Expression {
+	switch this.token.Type() {
+	case parser.CollectionOpening:
+		this.advance()
+		ret := this.expression()
+		this.expect(parser.CollectionClosing)
+		return ret
+	case parser.Variable:
+		return this.variable()
+	case parser.IRI, parser.PNameLN, parser.PNameNS:
+		iri := this.iri()
+		if this.is(parser.CollectionOpening) || this.is(parser.EmptyCollection) {
+			return &Call{Function: string(iri), Arguments: this.arguments()}
+		}
+		return &Constant{Term: iri}
+	case parser.String, parser.Number, parser.Boolean:
+		return &Constant{Term: this.literal()}
+	case parser.TripleTermOpening:
+		this.advance()
+		arguments := []Expression{}
+		for i := 0; i < 3; i++ {
+			if i == 1 {
+				arguments = append(arguments, termExpression(this.verb()))
+			} else {
+				arguments = append(arguments, termExpression(this.varOrTerm()))
+			}
+		}
+		this.expect(parser.TripleTermClosing)
+		return &Call{Function: "TRIPLE", Arguments: arguments}
+	case parser.Name:
+		return this.builtinCall()
+	}
+	this.fail("expected an expression, got %s", this.token)
+	return nil
+}
+
+func termExpression(term model.RDFTerm) Expression {
+	if variable, ok := term.(Variable); ok {
+		return variable
+	}
+	return &Constant{Term: term}
+}
+
+// built-in functions, aggregates and EXISTS
+func (this *queryParser) builtinCall() Expression {
+	name := strings.ToUpper(this.token.Value())
+	if alias, ok := builtinAliases[name]; ok {
+		name = alias
+	}
+	switch {
+	case name == "EXISTS":
+		this.advance()
+		return &Exists{Pattern: this.groupGraphPattern()}
+	case name == "NOT":
+		this.advance()
+		this.expectName("EXISTS")
+		return &Exists{Pattern: this.groupGraphPattern(), Not: true}
+	case aggregateFunctions[name]:
+		return this.aggregate(name)
+	}
+	arity, ok := builtins[name]
+	if !ok {
+		this.fail("unknown function %s", this.token.Value())
+	}
+	this.advance()
+	arguments := this.arguments()
+	if len(arguments) < arity[0] || (arity[1] >= 0 && len(arguments) > arity[1]) {
+		this.fail("wrong number of arguments for %s", name)
+	}
+	if name == "BOUND" {
+		if _, ok := arguments[0].(Variable); !ok {
+			this.fail("BOUND takes a variable")
+		}
+	}
+	return &Call{Function: name, Arguments: arguments}
+}
+
+// ( expression, ... ) or ()
+func (this *queryParser) arguments() []Expression {
+	if this.is(parser.EmptyCollection) {
+		this.advance()
+		return []Expression{}
+	}
+	this.expect(parser.CollectionOpening)
+	ret := []Expression{this.expression()}
+	for this.is(parser.Coma) {
+		this.advance()
+		ret = append(ret, this.expression())
+	}
+	this.expect(parser.CollectionClosing)
+	return ret
+}
+
+// the aggregate is replaced by its hidden variable
+func (this *queryParser) aggregate(name string) Expression {
+	if !this.aggregatesAllowed {
+		this.fail("%s is only allowed in SELECT, HAVING and ORDER BY", name)
+	}
+	this.advance()
+	this.expect(parser.CollectionOpening)
+	this.counter++
+	ret := &Aggregate{Variable: Variable(".agg" + strconv.Itoa(this.counter)), Function: name, Separator: " "}
+	if this.isName("DISTINCT") {
+		ret.Distinct = true
+		this.advance()
+	}
+	if name == "COUNT" && this.isOperator("*") {
+		this.advance()
+	} else {
+		// no nested aggregates
+		this.aggregatesAllowed = false
+		ret.Expression = this.expression()
+		this.aggregatesAllowed = true
+	}
+	if name == "GROUP_CONCAT" && this.is(parser.SemiColumn) {
+		this.advance()
+		this.expectName("SEPARATOR")
+		this.expectOperator("=")
+		ret.Separator = this.expect(parser.String).Value()
+	}
+	this.expect(parser.CollectionClosing)
+	this.aggregates = append(this.aggregates, ret)
+	return ret.Variable
+}
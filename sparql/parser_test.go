@@ -0,0 +1,122 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		query     string
+		algebra   string
+		variables int
+	}{
+		{
+			`PREFIX : <http://ex.org/> SELECT * WHERE { ?s :p ?o ; a :C . OPTIONAL { ?o :q ?x FILTER(?x > 2) } FILTER(?o != "a"@en) }`,
+			`(project (?s ?o ?x) (filter (!= ?o "a"@en) (leftjoin (bgp (triple ?s <http://ex.org/p> ?o) (triple ?s <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex.org/C>)) (bgp (triple ?o <http://ex.org/q> ?x)) (> ?x 2))))`,
+			3,
+		},
+		{
+			`BASE <http://ex.org/> SELECT ?s (COUNT(DISTINCT ?o) AS ?n) { { ?s <p> ?o } UNION { ?s <q> ?o } } GROUP BY ?s HAVING (COUNT(?o) > 1) ORDER BY DESC(?n) LIMIT 10 OFFSET 5`,
+			`(slice 5 10 (project (?s ?n) (order ((desc ?n)) (extend ((?n ?.agg1)) (filter (> ?.agg2 1) (group (?s) ((?.agg1 (count distinct ?o)) (?.agg2 (count ?o))) (union (bgp (triple ?s <http://ex.org/p> ?o)) (bgp (triple ?s <http://ex.org/q> ?o)))))))))`,
+			2,
+		},
+		{
+			`SELECT ?x { ?x <p> ?y . BIND(?y-1 AS ?z) MINUS { ?x <r> _:b } GRAPH ?g { ?x ?p [] } } VALUES (?x ?z) { (<a> UNDEF) }`,
+			`(project (?x) (join (join (minus (extend ((?z (- ?y 1))) (bgp (triple ?x <p> ?y))) (bgp (triple ?x <r> ?_:b))) (graph ?g (bgp (triple ?x ?p ?.b1)))) (table (vars ?x ?z) (row <a> UNDEF))))`,
+			1,
+		},
+		{
+			`ASK { ?s ?p ( 1 ) FILTER NOT EXISTS { ?o ?p ?s } FILTER(?o IN (1, 2.5) || regex(str(?s), "^a", "i")) }`,
+			`(filter (&& (notexists (bgp (triple ?o ?p ?s))) (|| (in ?o 1 2.5) (regex (str ?s) "^a" "i"))) (bgp (triple ?.b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> 1) (triple ?.b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil>) (triple ?s ?p ?.b1)))`,
+			0,
+		},
+		{
+			`SELECT * { SELECT DISTINCT ?s (MAX(?o) AS ?m) { ?s ?p ?o } GROUP BY ?s }`,
+			`(project (?s ?m) (distinct (project (?s ?m) (extend ((?m ?.agg1)) (group (?s) ((?.agg1 (max ?o))) (bgp (triple ?s ?p ?o)))))))`,
+			2,
+		},
+		{
+			`SELECT (GROUP_CONCAT(?o; SEPARATOR=", ") AS ?all) { ?s ?p ?o FILTER(?o - 1 > -2 * 3 && !bound(?s)) } GROUP BY (str(?s) AS ?k)`,
+			`(project (?all) (extend ((?all ?.agg1)) (group ((?k (str ?s))) ((?.agg1 (group_concat ?o separator ", "))) (filter (&& (> (- ?o 1) (* -2 3)) (! (bound ?s))) (bgp (triple ?s ?p ?o))))))`,
+			1,
+		},
+		{
+			`PREFIX : <http://ex.org/> SELECT ?s { ?s :p/^:q ?o ; (:a|^:b)* ?x ; !(a|^:c)? 1 }`,
+			`(project (?s) (bgp (triple ?s <http://ex.org/p> ?.p1) (triple ?o <http://ex.org/q> ?.p1) (path ?s (path* (alt <http://ex.org/a> (reverse <http://ex.org/b>))) ?x) (path ?s (path? (notoneof <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> (reverse <http://ex.org/c>))) 1)))`,
+			1,
+		},
+	}
+	for _, c := range cases {
+		query, err := ParseQuery(c.query, Options{})
+		if err != nil {
+			t.Errorf("%s: %v", c.query, err)
+			continue
+		}
+		if query.Pattern.String() != c.algebra {
+			t.Errorf("%s:\n%s\ninstead of\n%s", c.query, query.Pattern, c.algebra)
+		}
+		if len(query.Variables) != c.variables {
+			t.Errorf("%s: variables %v", c.query, query.Variables)
+		}
+	}
+}
+
+func TestParseConstruct(t *testing.T) {
+	query, err := ParseQuery(`PREFIX ex: <http://ex.org/>
+CONSTRUCT { ?s ex:p _:b . _:b ex:q [] } FROM <http://g> FROM NAMED <http://h> WHERE { ?s ?p ?o }`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Form != Construct || len(query.Template) != 2 || len(query.From) != 1 || len(query.FromNamed) != 1 {
+		t.Fatalf("unexpected query %+v", query)
+	}
+	if query.Template[0].Object != query.Template[1].Subject || !model.IsBlankNode(query.Template[1].Object) {
+		t.Errorf("unexpected template %v", query.Template)
+	}
+	if len(query.Namespaces) != 1 || query.Namespaces[0].Prefix != "ex" {
+		t.Errorf("unexpected namespaces %v", query.Namespaces)
+	}
+
+	query, err = ParseQuery(`DESCRIBE * WHERE { ?s ?p <http://o> }`, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Form != Describe || len(query.Describe) != 2 || query.Describe[1] != Variable("p") {
+		t.Errorf("unexpected DESCRIBE %v", query.Describe)
+	}
+}
+
+func TestQuerySyntaxErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		line  int
+		col   int
+	}{
+		{"SELECT * WHERE { ?s ?p ?o ", 1, 27},
+		{"SELECT ?p WHERE { ?s ?p ?o } GROUP BY ?s", 1, 41},
+		{"SELECT *\nWHERE { ?s un:known ?o }", 2, 12},
+		{"SELECT (1 AS ?x) { BIND(2 AS ?x) }", 1, 35},
+		{"SELECT * { ?s ?p ?o FILTER(COUNT(?o) > 1) }", 1, 28},
+		{"SELECT * { ?s ?p ?o FILTER(strlen(?o, 1)) }", 1, 41},
+		{"SELECT * { ?s <p>/ ?o }", 1, 20},
+		{"CONSTRUCT { ?s <p>* ?o } { }", 1, 19},
+	}
+	for _, c := range cases {
+		_, err := ParseQuery(c.query, Options{})
+		syntaxError, ok := err.(*parser.SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error, got %v", c.query, err)
+			continue
+		}
+		if syntaxError.Line != c.line || syntaxError.Col != c.col {
+			t.Errorf("%q: error %v, expected at %d:%d", c.query, syntaxError, c.line, c.col)
+		}
+	}
+}
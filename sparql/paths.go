@@ -0,0 +1,290 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Property paths
+//
+// the paths of the predicates of triple patterns. As in the section
+// 18.2.2.4 of the recommendation, the parser turns sequences into patterns
+// joined by hidden variables and inverse paths into reversed patterns,
+// unless they are inside another path, and single IRIs into IRIs. The
+// pairs of nodes matched by alternatives and sequences are counted as
+// often as they are reached, those of *, + and ? once.
+
+// Path is a property path, the predicate of a TriplePattern which is
+// neither an IRI nor a variable
+type Path interface {
+	String() string
+	path()
+}
+
+// an IRI inside a path
+type Link model.IRI
+
+// ^path
+type Inverse struct {
+	Path Path
+}
+
+// left/right
+type Sequence struct {
+	Left  Path
+	Right Path
+}
+
+// left|right
+type Alternative struct {
+	Left  Path
+	Right Path
+}
+
+// path*
+type ZeroOrMore struct {
+	Path Path
+}
+
+// path+
+type OneOrMore struct {
+	Path Path
+}
+
+// path?
+type ZeroOrOne struct {
+	Path Path
+}
+
+// !(iri|^iri...), the links by predicates which are not in IRIs, and the
+// inverse links by predicates which are not in Inverse
+type NegatedSet struct {
+	IRIs    []model.IRI
+	Inverse []model.IRI
+}
+
+func (this Link) path()         {}
+func (this *Inverse) path()     {}
+func (this *Sequence) path()    {}
+func (this *Alternative) path() {}
+func (this *ZeroOrMore) path()  {}
+func (this *OneOrMore) path()   {}
+func (this *ZeroOrOne) path()   {}
+func (this *NegatedSet) path()  {}
+
+func (this Link) String() string {
+	return formatTerm(model.IRI(this))
+}
+
+func (this *Inverse) String() string {
+	return sexp("reverse", this.Path.String())
+}
+
+func (this *Sequence) String() string {
+	return sexp("seq", this.Left.String(), this.Right.String())
+}
+
+func (this *Alternative) String() string {
+	return sexp("alt", this.Left.String(), this.Right.String())
+}
+
+func (this *ZeroOrMore) String() string {
+	return sexp("path*", this.Path.String())
+}
+
+func (this *OneOrMore) String() string {
+	return sexp("path+", this.Path.String())
+}
+
+func (this *ZeroOrOne) String() string {
+	return sexp("path?", this.Path.String())
+}
+
+func (this *NegatedSet) String() string {
+	parts := []string{"notoneof"}
+	for _, iri := range this.IRIs {
+		parts = append(parts, formatTerm(iri))
+	}
+	for _, iri := range this.Inverse {
+		parts = append(parts, sexp("reverse", formatTerm(iri)))
+	}
+	return sexp(parts...)
+}
+
+// evaluation
+
+// a pair of nodes linked by a path
+type pathPair struct {
+	subject model.RDFTerm
+	object  model.RDFTerm
+}
+
+// the solutions of a pattern whose predicate is a path
+func (this *evaluator) matchPath(subject model.RDFTerm, path Path, object model.RDFTerm, solution Solution, graphs []model.RDFTerm, emit func(Solution)) {
+	s, o := substituteTerm(subject, solution), substituteTerm(object, solution)
+	this.pathPairs(path, s, o, graphs, func(pair pathPair) {
+		extended, ok := unify(subject, pair.subject, solution)
+		if ok {
+			extended, ok = unify(object, pair.object, extended)
+		}
+		if ok {
+			emit(extended)
+		}
+	})
+}
+
+// the pairs of nodes linked by path, starting at subject and ending at
+// object when they are not nil
+func (this *evaluator) pathPairs(path Path, subject, object model.RDFTerm, graphs []model.RDFTerm, emit func(pathPair)) {
+	switch p := path.(type) {
+	case Link:
+		this.links(subject, model.IRI(p), object, graphs, emit)
+	case *Inverse:
+		this.pathPairs(p.Path, object, subject, graphs, func(pair pathPair) {
+			emit(pathPair{subject: pair.object, object: pair.subject})
+		})
+	case *Sequence:
+		if subject == nil && object != nil {
+			// from the bound end
+			this.pathPairs(p.Right, nil, object, graphs, func(right pathPair) {
+				this.pathPairs(p.Left, nil, right.subject, graphs, func(left pathPair) {
+					emit(pathPair{subject: left.subject, object: right.object})
+				})
+			})
+			return
+		}
+		this.pathPairs(p.Left, subject, nil, graphs, func(left pathPair) {
+			this.pathPairs(p.Right, left.object, object, graphs, func(right pathPair) {
+				emit(pathPair{subject: left.subject, object: right.object})
+			})
+		})
+	case *Alternative:
+		this.pathPairs(p.Left, subject, object, graphs, emit)
+		this.pathPairs(p.Right, subject, object, graphs, emit)
+	case *ZeroOrOne:
+		this.closure(p.Path, subject, object, true, false, graphs, emit)
+	case *ZeroOrMore:
+		this.closure(p.Path, subject, object, true, true, graphs, emit)
+	case *OneOrMore:
+		this.closure(p.Path, subject, object, false, true, graphs, emit)
+	case *NegatedSet:
+		if len(p.IRIs) > 0 || len(p.Inverse) == 0 {
+			this.negatedLinks(subject, object, p.IRIs, graphs, emit)
+		}
+		if len(p.Inverse) > 0 {
+			this.negatedLinks(object, subject, p.Inverse, graphs, func(pair pathPair) {
+				emit(pathPair{subject: pair.object, object: pair.subject})
+			})
+		}
+	}
+}
+
+// the statements of the active graph by predicate, counted once in a
+// merge of graphs
+func (this *evaluator) links(subject model.RDFTerm, predicate model.IRI, object model.RDFTerm, graphs []model.RDFTerm, emit func(pathPair)) {
+	this.match(variableOr(subject, ".s"), predicate, variableOr(object, ".o"), Solution{}, graphs, func(solution Solution) {
+		emit(pathPair{subject: valueOr(solution, subject, ".s"), object: valueOr(solution, object, ".o")})
+	})
+}
+
+// the links by predicates which are not excluded
+func (this *evaluator) negatedLinks(subject model.RDFTerm, object model.RDFTerm, excluded []model.IRI, graphs []model.RDFTerm, emit func(pathPair)) {
+	this.match(variableOr(subject, ".s"), Variable(".p"), variableOr(object, ".o"), Solution{}, graphs, func(solution Solution) {
+		predicate := solution[".p"]
+		for _, iri := range excluded {
+			if predicate == iri {
+				return
+			}
+		}
+		emit(pathPair{subject: valueOr(solution, subject, ".s"), object: valueOr(solution, object, ".o")})
+	})
+}
+
+func variableOr(term model.RDFTerm, variable Variable) model.RDFTerm {
+	if term == nil {
+		return variable
+	}
+	return term
+}
+
+func valueOr(solution Solution, term model.RDFTerm, variable Variable) model.RDFTerm {
+	if term == nil {
+		return solution[variable]
+	}
+	return term
+}
+
+// the pairs of nodes linked by path once, or zero times as well when zero
+// is set, or more times when many is set. Every pair is given once, the
+// nodes reached being remembered to stop on cycles.
+func (this *evaluator) closure(path Path, subject, object model.RDFTerm, zero, many bool, graphs []model.RDFTerm, emit func(pathPair)) {
+	if subject == nil && object != nil {
+		// from the bound end, along the inverse path
+		this.closure(&Inverse{Path: path}, object, nil, zero, many, graphs, func(pair pathPair) {
+			emit(pathPair{subject: pair.object, object: pair.subject})
+		})
+		return
+	}
+	starts := []model.RDFTerm{subject}
+	if subject == nil {
+		starts = this.nodes(graphs)
+	}
+	for _, start := range starts {
+		for _, end := range this.reachable(path, start, zero, many, graphs) {
+			if object == nil || end == object {
+				emit(pathPair{subject: start, object: end})
+			}
+		}
+	}
+}
+
+// the nodes reached from start, each once
+func (this *evaluator) reachable(path Path, start model.RDFTerm, zero, many bool, graphs []model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	reached := map[model.RDFTerm]bool{}
+	if zero {
+		reached[start] = true
+		ret = append(ret, start)
+	}
+	frontier := []model.RDFTerm{start}
+	// the start is only visited once, reaching it again adds it when it
+	// was not added as the path of length zero
+	visited := map[model.RDFTerm]bool{start: true}
+	for len(frontier) > 0 {
+		next := []model.RDFTerm{}
+		for _, node := range frontier {
+			this.pathPairs(path, node, nil, graphs, func(pair pathPair) {
+				if !reached[pair.object] {
+					reached[pair.object] = true
+					ret = append(ret, pair.object)
+				}
+				if many && !visited[pair.object] {
+					visited[pair.object] = true
+					next = append(next, pair.object)
+				}
+			})
+		}
+		frontier = next
+	}
+	return ret
+}
+
+// the subjects and objects of the active graph
+func (this *evaluator) nodes(graphs []model.RDFTerm) []model.RDFTerm {
+	ret := []model.RDFTerm{}
+	seen := map[model.RDFTerm]bool{}
+	for _, graph := range graphs {
+		for _, statement := range this.statements(nil, nil, nil, graph) {
+			for _, node := range []model.RDFTerm{statement.Subject, statement.Object} {
+				if !seen[node] {
+					seen[node] = true
+					ret = append(ret, node)
+				}
+			}
+		}
+	}
+	return ret
+}
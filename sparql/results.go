@@ -0,0 +1,653 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// the serializations of the results of SELECT and ASK
+type ResultFormat int
+
+const (
+	JSON ResultFormat = iota
+	XML
+	CSV
+	TSV
+)
+
+type resultDescription struct {
+	name      string
+	mediaType string
+	extension string
+}
+
+var resultDescriptions = map[ResultFormat]resultDescription{
+	JSON: {"json", "application/sparql-results+json", ".srj"},
+	XML:  {"xml", "application/sparql-results+xml", ".srx"},
+	CSV:  {"csv", "text/csv", ".csv"},
+	TSV:  {"tsv", "text/tab-separated-values", ".tsv"},
+}
+
+func AllResultFormats() []ResultFormat {
+	return []ResultFormat{JSON, XML, CSV, TSV}
+}
+
+func (this ResultFormat) String() string {
+	return resultDescriptions[this].name
+}
+
+func (this ResultFormat) MediaType() string {
+	return resultDescriptions[this].mediaType
+}
+
+func (this ResultFormat) Extension() string {
+	return resultDescriptions[this].extension
+}
+
+func ResultFormatByName(name string) (ResultFormat, error) {
+	name = strings.ToLower(name)
+	for _, f := range AllResultFormats() {
+		if resultDescriptions[f].name == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown result format %q", name)
+}
+
+func ResultFormatByExtension(path string) (ResultFormat, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range AllResultFormats() {
+		if resultDescriptions[f].extension == ext {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// media type parameters like charset are ignored
+func ResultFormatByMediaType(mediaType string) (ResultFormat, bool) {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	for _, f := range AllResultFormats() {
+		if resultDescriptions[f].mediaType == mediaType {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// WriteResult writes the solutions of SELECT or the answer of ASK. CSV
+// and TSV have no form for ASK; CSV only keeps the IRIs and the lexical
+// forms of literals.
+func WriteResult(w io.Writer, result *Result, f ResultFormat) error {
+	if result.Form != Select && result.Form != Ask {
+		return fmt.Errorf("the results of %s are graphs", result.Form)
+	}
+	if result.Form == Ask && (f == CSV || f == TSV) {
+		return fmt.Errorf("the results of ASK cannot be written as %s", f)
+	}
+	buffered := bufio.NewWriter(w)
+	var err error
+	switch f {
+	case JSON:
+		err = writeJSON(buffered, result)
+	case XML:
+		err = writeXML(buffered, result)
+	case CSV:
+		err = writeCSV(buffered, result)
+	case TSV:
+		err = writeTSV(buffered, result)
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// ParseResult reads the results of SELECT or ASK. The terms of CSV are
+// guessed: values with a scheme are IRIs, values starting with _: blank
+// nodes and the others literals.
+func ParseResult(r io.Reader, f ResultFormat) (*Result, error) {
+	switch f {
+	case JSON:
+		return parseJSON(r)
+	case XML:
+		return parseXML(r)
+	case CSV:
+		return parseCSV(r)
+	}
+	return parseTSV(r)
+}
+
+// JSON
+
+type jsonResult struct {
+	Head    jsonHead     `json:"head"`
+	Results *jsonResults `json:"results,omitempty"`
+	Boolean *bool        `json:"boolean,omitempty"`
+}
+
+type jsonHead struct {
+	Variables []string `json:"vars,omitempty"`
+	Link      []string `json:"link,omitempty"`
+}
+
+type jsonResults struct {
+	Bindings []map[string]*jsonTerm `json:"bindings"`
+}
+
+// the value of triple terms is an object with their subject, predicate
+// and object
+type jsonTerm struct {
+	Type      string          `json:"type"`
+	Value     json.RawMessage `json:"value"`
+	Language  string          `json:"xml:lang,omitempty"`
+	Direction string          `json:"its:dir,omitempty"`
+	Datatype  string          `json:"datatype,omitempty"`
+}
+
+type jsonTriple struct {
+	Subject   *jsonTerm `json:"subject"`
+	Predicate *jsonTerm `json:"predicate"`
+	Object    *jsonTerm `json:"object"`
+}
+
+func writeJSON(w io.Writer, result *Result) error {
+	document := jsonResult{}
+	if result.Form == Ask {
+		document.Boolean = &result.Boolean
+	} else {
+		document.Head.Variables = variableNames(result.Variables)
+		labels := writer.NewBlankNodeLabels()
+		document.Results = &jsonResults{Bindings: []map[string]*jsonTerm{}}
+		for _, solution := range result.Solutions {
+			binding := map[string]*jsonTerm{}
+			for _, variable := range result.Variables {
+				if value, ok := solution[variable]; ok {
+					binding[string(variable)] = newJSONTerm(value, labels)
+				}
+			}
+			document.Results.Bindings = append(document.Results.Bindings, binding)
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(document)
+}
+
+func newJSONTerm(term model.RDFTerm, labels *writer.BlankNodeLabels) *jsonTerm {
+	value := func(s string) json.RawMessage {
+		ret, _ := json.Marshal(s)
+		return ret
+	}
+	switch t := term.(type) {
+	case model.IRI:
+		return &jsonTerm{Type: "uri", Value: value(string(t))}
+	case model.BlankNode:
+		return &jsonTerm{Type: "bnode", Value: value(labels.Label(t))}
+	case model.Literal:
+		ret := &jsonTerm{Type: "literal", Value: value(t.Lexical), Language: t.Language, Direction: t.Direction}
+		if t.Language == "" && t.Datatype != model.XSDString && t.Datatype != "" {
+			ret.Datatype = string(t.Datatype)
+		}
+		return ret
+	case model.TripleTerm:
+		triple, _ := json.Marshal(jsonTriple{
+			Subject:   newJSONTerm(t.Subject, labels),
+			Predicate: newJSONTerm(t.Predicate, labels),
+			Object:    newJSONTerm(t.Object, labels),
+		})
+		return &jsonTerm{Type: "triple", Value: triple}
+	}
+	return nil
+}
+
+func parseJSON(r io.Reader) (*Result, error) {
+	document := jsonResult{}
+	if err := json.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	if document.Boolean != nil {
+		return &Result{Form: Ask, Boolean: *document.Boolean}, nil
+	}
+	if document.Results == nil {
+		return nil, fmt.Errorf("no results nor boolean")
+	}
+	ret := &Result{Form: Select, Variables: variables(document.Head.Variables), Solutions: []Solution{}}
+	nodes := map[string]*model.LabelledBlankNode{}
+	for _, binding := range document.Results.Bindings {
+		solution := Solution{}
+		for name, value := range binding {
+			term, err := value.term(nodes)
+			if err != nil {
+				return nil, err
+			}
+			solution[Variable(name)] = term
+		}
+		ret.Solutions = append(ret.Solutions, solution)
+	}
+	return ret, nil
+}
+
+func (this *jsonTerm) term(nodes map[string]*model.LabelledBlankNode) (model.RDFTerm, error) {
+	if this == nil {
+		return nil, fmt.Errorf("missing term")
+	}
+	if this.Type == "triple" {
+		triple := jsonTriple{}
+		if err := json.Unmarshal(this.Value, &triple); err != nil {
+			return nil, err
+		}
+		subject, err := triple.Subject.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		predicate, err := triple.Predicate.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		object, err := triple.Object.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}, nil
+	}
+	var value string
+	if err := json.Unmarshal(this.Value, &value); err != nil {
+		return nil, err
+	}
+	switch this.Type {
+	case "uri":
+		return model.IRI(value), nil
+	case "bnode":
+		return blankNode(nodes, value), nil
+	case "literal", "typed-literal":
+		return newLiteral(value, this.Language, this.Direction, this.Datatype), nil
+	}
+	return nil, fmt.Errorf("unknown term type %q", this.Type)
+}
+
+// XML
+
+const resultsNamespace = "http://www.w3.org/2005/sparql-results#"
+
+type xmlResult struct {
+	XMLName   xml.Name      `xml:"http://www.w3.org/2005/sparql-results# sparql"`
+	Variables []xmlVariable `xml:"head>variable"`
+	Boolean   *bool         `xml:"boolean"`
+	Results   []xmlSolution `xml:"results>result"`
+}
+
+type xmlVariable struct {
+	Name string `xml:"name,attr"`
+}
+
+type xmlSolution struct {
+	Bindings []xmlBinding `xml:"binding"`
+}
+
+type xmlBinding struct {
+	Name string `xml:"name,attr"`
+	xmlTerm
+}
+
+type xmlTerm struct {
+	IRI       *string     `xml:"uri"`
+	BlankNode *string     `xml:"bnode"`
+	Literal   *xmlLiteral `xml:"literal"`
+	Triple    *xmlTriple  `xml:"triple"`
+}
+
+type xmlLiteral struct {
+	Lexical   string `xml:",chardata"`
+	Language  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Direction string `xml:"http://www.w3.org/2005/11/its dir,attr"`
+	Datatype  string `xml:"datatype,attr"`
+}
+
+type xmlTriple struct {
+	Subject   xmlTerm `xml:"subject"`
+	Predicate xmlTerm `xml:"predicate"`
+	Object    xmlTerm `xml:"object"`
+}
+
+func writeXML(w io.Writer, result *Result) error {
+	out := &xmlWriter{writer: w}
+	out.printf("<?xml version=\"1.0\"?>\n<sparql xmlns=\"%s\" xmlns:its=\"http://www.w3.org/2005/11/its\">\n  <head>\n", resultsNamespace)
+	for _, name := range variableNames(result.Variables) {
+		out.printf("    <variable name=\"%s\"/>\n", escapeXML(name))
+	}
+	out.printf("  </head>\n")
+	if result.Form == Ask {
+		out.printf("  <boolean>%t</boolean>\n", result.Boolean)
+	} else {
+		labels := writer.NewBlankNodeLabels()
+		out.printf("  <results>\n")
+		for _, solution := range result.Solutions {
+			out.printf("    <result>\n")
+			for _, variable := range result.Variables {
+				if value, ok := solution[variable]; ok {
+					out.printf("      <binding name=\"%s\">", escapeXML(string(variable)))
+					out.term(value, labels)
+					out.printf("</binding>\n")
+				}
+			}
+			out.printf("    </result>\n")
+		}
+		out.printf("  </results>\n")
+	}
+	out.printf("</sparql>\n")
+	return out.err
+}
+
+// keeps the first error
+type xmlWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (this *xmlWriter) printf(format string, args ...interface{}) {
+	if this.err == nil {
+		_, this.err = fmt.Fprintf(this.writer, format, args...)
+	}
+}
+
+func (this *xmlWriter) term(term model.RDFTerm, labels *writer.BlankNodeLabels) {
+	switch t := term.(type) {
+	case model.IRI:
+		this.printf("<uri>%s</uri>", escapeXML(string(t)))
+	case model.BlankNode:
+		this.printf("<bnode>%s</bnode>", escapeXML(labels.Label(t)))
+	case model.Literal:
+		attributes := ""
+		switch {
+		case t.Language != "":
+			attributes = fmt.Sprintf(" xml:lang=\"%s\"", escapeXML(t.Language))
+			if t.Direction != "" {
+				attributes += fmt.Sprintf(" its:dir=\"%s\"", escapeXML(t.Direction))
+			}
+		case t.Datatype != model.XSDString && t.Datatype != "":
+			attributes = fmt.Sprintf(" datatype=\"%s\"", escapeXML(string(t.Datatype)))
+		}
+		this.printf("<literal%s>%s</literal>", attributes, escapeXML(t.Lexical))
+	case model.TripleTerm:
+		this.printf("<triple><subject>")
+		this.term(t.Subject, labels)
+		this.printf("</subject><predicate>")
+		this.term(t.Predicate, labels)
+		this.printf("</predicate><object>")
+		this.term(t.Object, labels)
+		this.printf("</object></triple>")
+	}
+}
+
+func escapeXML(s string) string {
+	var ret strings.Builder
+	xml.EscapeText(&ret, []byte(s))
+	return ret.String()
+}
+
+func parseXML(r io.Reader) (*Result, error) {
+	document := xmlResult{}
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	if document.Boolean != nil {
+		return &Result{Form: Ask, Boolean: *document.Boolean}, nil
+	}
+	ret := &Result{Form: Select, Solutions: []Solution{}}
+	for _, variable := range document.Variables {
+		ret.Variables = append(ret.Variables, Variable(variable.Name))
+	}
+	nodes := map[string]*model.LabelledBlankNode{}
+	for _, result := range document.Results {
+		solution := Solution{}
+		for _, binding := range result.Bindings {
+			term, err := binding.term(nodes)
+			if err != nil {
+				return nil, err
+			}
+			solution[Variable(binding.Name)] = term
+		}
+		ret.Solutions = append(ret.Solutions, solution)
+	}
+	return ret, nil
+}
+
+func (this *xmlTerm) term(nodes map[string]*model.LabelledBlankNode) (model.RDFTerm, error) {
+	switch {
+	case this.IRI != nil:
+		return model.IRI(*this.IRI), nil
+	case this.BlankNode != nil:
+		return blankNode(nodes, *this.BlankNode), nil
+	case this.Literal != nil:
+		return newLiteral(this.Literal.Lexical, this.Literal.Language, this.Literal.Direction, this.Literal.Datatype), nil
+	case this.Triple != nil:
+		subject, err := this.Triple.Subject.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		predicate, err := this.Triple.Predicate.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		object, err := this.Triple.Object.term(nodes)
+		if err != nil {
+			return nil, err
+		}
+		return model.TripleTerm{Subject: subject, Predicate: predicate, Object: object}, nil
+	}
+	return nil, fmt.Errorf("missing term")
+}
+
+// CSV and TSV
+
+func writeCSV(w io.Writer, result *Result) error {
+	out := csv.NewWriter(w)
+	out.UseCRLF = true
+	if err := out.Write(variableNames(result.Variables)); err != nil {
+		return err
+	}
+	labels := writer.NewBlankNodeLabels()
+	var value func(term model.RDFTerm) string
+	value = func(term model.RDFTerm) string {
+		switch t := term.(type) {
+		case model.IRI:
+			return string(t)
+		case model.BlankNode:
+			return "_:" + labels.Label(t)
+		case model.Literal:
+			return t.Lexical
+		case model.TripleTerm:
+			return "<<( " + writer.FormatTerm(t.Subject, labels) + " " + writer.FormatTerm(t.Predicate, labels) + " " + writer.FormatTerm(t.Object, labels) + " )>>"
+		}
+		return ""
+	}
+	for _, solution := range result.Solutions {
+		record := make([]string, len(result.Variables))
+		for i, variable := range result.Variables {
+			record[i] = value(solution[variable])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// an absolute IRI in CSV
+var schemePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:[^\s"<>]*$`)
+
+func parseCSV(r io.Reader) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+	ret := &Result{Form: Select, Variables: variables(records[0]), Solutions: []Solution{}}
+	nodes := map[string]*model.LabelledBlankNode{}
+	for i, record := range records[1:] {
+		if len(record) != len(ret.Variables) {
+			return nil, fmt.Errorf("line %d: %d values for %d variables", i+2, len(record), len(ret.Variables))
+		}
+		solution := Solution{}
+		for j, value := range record {
+			switch {
+			case value == "":
+			case strings.HasPrefix(value, "_:"):
+				solution[ret.Variables[j]] = blankNode(nodes, value[2:])
+			case schemePattern.MatchString(value):
+				solution[ret.Variables[j]] = model.IRI(value)
+			default:
+				solution[ret.Variables[j]] = model.NewStringLiteral(value)
+			}
+		}
+		ret.Solutions = append(ret.Solutions, solution)
+	}
+	return ret, nil
+}
+
+// the values are written as in N-Triples
+func writeTSV(w io.Writer, result *Result) error {
+	out := &xmlWriter{writer: w}
+	names := make([]string, len(result.Variables))
+	for i, variable := range result.Variables {
+		names[i] = variable.String()
+	}
+	out.printf("%s\n", strings.Join(names, "\t"))
+	labels := writer.NewBlankNodeLabels()
+	for _, solution := range result.Solutions {
+		values := make([]string, len(result.Variables))
+		for i, variable := range result.Variables {
+			if value, ok := solution[variable]; ok {
+				values[i] = writer.FormatTerm(value, labels)
+			}
+		}
+		out.printf("%s\n", strings.Join(values, "\t"))
+	}
+	return out.err
+}
+
+// the values are read as terms of queries, numbers and booleans included
+func parseTSV(r io.Reader) (*Result, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("missing header")
+	}
+	ret := &Result{Form: Select, Solutions: []Solution{}}
+	if header := strings.TrimRight(scanner.Text(), "\r"); header != "" {
+		for _, name := range strings.Split(header, "\t") {
+			if !strings.HasPrefix(name, "?") && !strings.HasPrefix(name, "$") {
+				return nil, &parser.SyntaxError{Line: 1, Col: 1, Message: fmt.Sprintf("expected a variable, got %q", name)}
+			}
+			ret.Variables = append(ret.Variables, Variable(name[1:]))
+		}
+	}
+	blankNodes := map[string]*model.LabelledBlankNode{}
+	for line := 2; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" && len(ret.Variables) != 1 {
+			continue
+		}
+		values := strings.Split(text, "\t")
+		if len(values) != len(ret.Variables) {
+			return nil, &parser.SyntaxError{Line: line, Col: 1, Message: fmt.Sprintf("%d values for %d variables", len(values), len(ret.Variables))}
+		}
+		solution := Solution{}
+		col := 1
+		for i, value := range values {
+			if strings.TrimSpace(value) != "" {
+				term, err := parseTSVTerm(value, blankNodes)
+				if err != nil {
+					syntaxError := *err.(*parser.SyntaxError)
+					syntaxError.Line, syntaxError.Col = line, col+syntaxError.Col-1
+					return nil, &syntaxError
+				}
+				solution[ret.Variables[i]] = term
+			}
+			col += len(value) + 1
+		}
+		ret.Solutions = append(ret.Solutions, solution)
+	}
+	return ret, scanner.Err()
+}
+
+// a term with the parser of queries, the blank nodes being shared by the
+// values of a document
+func parseTSVTerm(value string, blankNodes map[string]*model.LabelledBlankNode) (ret model.RDFTerm, err error) {
+	this := newQueryParser(value, Options{})
+	this.template = true
+	this.blankNodes = blankNodes
+	defer this.recover(&err)
+	this.advance()
+	ret = this.varOrTerm()
+	if _, ok := ret.(Variable); ok {
+		this.fail("expected a term, got a variable")
+	}
+	if !this.is(parser.EOF) {
+		this.unexpected()
+	}
+	return ret, nil
+}
+
+// helpers
+
+func variableNames(variables []Variable) []string {
+	ret := make([]string, len(variables))
+	for i, variable := range variables {
+		ret[i] = string(variable)
+	}
+	return ret
+}
+
+func variables(names []string) []Variable {
+	ret := make([]Variable, len(names))
+	for i, name := range names {
+		ret[i] = Variable(name)
+	}
+	return ret
+}
+
+// the node of a label in a document
+func blankNode(nodes map[string]*model.LabelledBlankNode, label string) *model.LabelledBlankNode {
+	node, ok := nodes[label]
+	if !ok {
+		node = &model.LabelledBlankNode{Label: label}
+		nodes[label] = node
+	}
+	return node
+}
+
+func newLiteral(lexical, language, direction, datatype string) model.Literal {
+	if language != "" {
+		return model.NewLangLiteral(lexical, language, direction)
+	}
+	if datatype == "" {
+		return model.NewStringLiteral(lexical)
+	}
+	return model.NewTypedLiteral(lexical, model.IRI(datatype))
+}
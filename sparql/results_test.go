@@ -0,0 +1,210 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+func sampleResult() *Result {
+	node := &model.LabelledBlankNode{Label: "b"}
+	return &Result{
+		Form:      Select,
+		Variables: []Variable{"x", "y"},
+		Solutions: []Solution{
+			{"x": model.IRI("http://ex.org/alice"), "y": model.NewLangLiteral("Alice", "en", "")},
+			{"x": node, "y": model.NewTypedLiteral("30", model.XSDInteger)},
+			{"x": model.IRI("http://ex.org/a&b"), "y": model.NewStringLiteral("tab\there \"quoted\"\nnew line <&>")},
+			{"y": model.NewLangLiteral("שלום", "he", "rtl")},
+			{"x": model.TripleTerm{Subject: node, Predicate: model.IRI("http://ex.org/p"), Object: model.NewStringLiteral("o")}},
+		},
+	}
+}
+
+func TestResultRoundTrip(t *testing.T) {
+	expected := rows(sampleResult())
+	for _, f := range []ResultFormat{JSON, XML, TSV} {
+		buffer := &bytes.Buffer{}
+		if err := WriteResult(buffer, sampleResult(), f); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		result, err := ParseResult(buffer, f)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", f, err, buffer)
+		}
+		if strings.Join(rows(result), "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s: got\n%s\ninstead of\n%s", f, strings.Join(rows(result), "\n"), strings.Join(expected, "\n"))
+			continue
+		}
+		// the label names the same node in the whole document
+		if result.Solutions[1]["x"] != result.Solutions[4]["x"].(model.TripleTerm).Subject {
+			t.Errorf("%s: the blank nodes of a label differ", f)
+		}
+	}
+}
+
+func TestResultAsk(t *testing.T) {
+	for _, f := range []ResultFormat{JSON, XML} {
+		for _, answer := range []bool{true, false} {
+			buffer := &bytes.Buffer{}
+			if err := WriteResult(buffer, &Result{Form: Ask, Boolean: answer}, f); err != nil {
+				t.Fatalf("%s: %v", f, err)
+			}
+			result, err := ParseResult(buffer, f)
+			if err != nil {
+				t.Fatalf("%s: %v", f, err)
+			}
+			if result.Form != Ask || result.Boolean != answer {
+				t.Errorf("%s: got %v instead of %v", f, result.Boolean, answer)
+			}
+		}
+	}
+	for _, f := range []ResultFormat{CSV, TSV} {
+		if err := WriteResult(&bytes.Buffer{}, &Result{Form: Ask}, f); err == nil {
+			t.Errorf("%s: ASK written", f)
+		}
+	}
+	if err := WriteResult(&bytes.Buffer{}, &Result{Form: Construct}, JSON); err == nil {
+		t.Error("CONSTRUCT written as results")
+	}
+}
+
+func TestResultDocuments(t *testing.T) {
+	result := sampleResult()
+	result.Solutions = result.Solutions[:2]
+	tests := []struct {
+		format   ResultFormat
+		expected string
+	}{
+		{JSON, `{
+  "head": {
+    "vars": [
+      "x",
+      "y"
+    ]
+  },
+  "results": {
+    "bindings": [
+      {
+        "x": {
+          "type": "uri",
+          "value": "http://ex.org/alice"
+        },
+        "y": {
+          "type": "literal",
+          "value": "Alice",
+          "xml:lang": "en"
+        }
+      },
+      {
+        "x": {
+          "type": "bnode",
+          "value": "b"
+        },
+        "y": {
+          "type": "literal",
+          "value": "30",
+          "datatype": "http://www.w3.org/2001/XMLSchema#integer"
+        }
+      }
+    ]
+  }
+}
+`},
+		{XML, `<?xml version="1.0"?>
+<sparql xmlns="http://www.w3.org/2005/sparql-results#" xmlns:its="http://www.w3.org/2005/11/its">
+  <head>
+    <variable name="x"/>
+    <variable name="y"/>
+  </head>
+  <results>
+    <result>
+      <binding name="x"><uri>http://ex.org/alice</uri></binding>
+      <binding name="y"><literal xml:lang="en">Alice</literal></binding>
+    </result>
+    <result>
+      <binding name="x"><bnode>b</bnode></binding>
+      <binding name="y"><literal datatype="http://www.w3.org/2001/XMLSchema#integer">30</literal></binding>
+    </result>
+  </results>
+</sparql>
+`},
+		{CSV, "x,y\r\nhttp://ex.org/alice,Alice\r\n_:b,30\r\n"},
+		{TSV, "?x\t?y\n<http://ex.org/alice>\t\"Alice\"@en\n_:b\t\"30\"^^<http://www.w3.org/2001/XMLSchema#integer>\n"},
+	}
+	for _, test := range tests {
+		buffer := &bytes.Buffer{}
+		if err := WriteResult(buffer, result, test.format); err != nil {
+			t.Fatalf("%s: %v", test.format, err)
+		}
+		if buffer.String() != test.expected {
+			t.Errorf("%s: got\n%s\ninstead of\n%s", test.format, buffer, test.expected)
+		}
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		format   ResultFormat
+		text     string
+		expected []string
+	}{
+		{CSV, "x,y\r\nhttp://ex.org/a,\"a, b\"\r\n_:n,\r\nurn:x,12\r\n", []string{`<:a> "a, b"`, `_:n UNDEF`, `<urn:x> "12"`}},
+		{TSV, "?x\t?y\n1\ttrue\n1.5\t<<( <http://ex.org/a> <http://ex.org/p> _:n )>>\n\t\"x\"@en--ltr\n", []string{`1 true`, `1.5 <<( <:a> <:p> _:n )>>`, `UNDEF "x"@en--ltr`}},
+		{JSON, `{"head": {"vars": ["x"]}, "results": {"bindings": [{"x": {"type": "typed-literal", "value": "1", "datatype": "http://www.w3.org/2001/XMLSchema#integer"}}, {}]}}`, []string{`1`, `UNDEF`}},
+		{JSON, `{"head": {"vars": ["x"]}, "results": {"bindings": [{"x": {"type": "triple", "value": {"subject": {"type": "uri", "value": "http://ex.org/a"}, "predicate": {"type": "uri", "value": "http://ex.org/p"}, "object": {"type": "literal", "value": "o", "xml:lang": "ar", "its:dir": "rtl"}}}}]}}`, []string{`<<( <:a> <:p> "o"@ar--rtl )>>`}},
+		{XML, `<sparql xmlns="http://www.w3.org/2005/sparql-results#"><head><variable name="x"/></head><results><result><binding name="x"><triple><subject><bnode>n</bnode></subject><predicate><uri>http://ex.org/p</uri></predicate><object><literal>o</literal></object></triple></binding></result></results></sparql>`, []string{`<<( _:n <:p> "o" )>>`}},
+	}
+	for _, test := range tests {
+		result, err := ParseResult(strings.NewReader(test.text), test.format)
+		if err != nil {
+			t.Errorf("%s: %v", test.format, err)
+			continue
+		}
+		if strings.Join(rows(result), "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: got %q instead of %q", test.format, rows(result), test.expected)
+		}
+	}
+}
+
+func TestParseResultErrors(t *testing.T) {
+	tests := []struct {
+		format ResultFormat
+		text   string
+	}{
+		{JSON, `{"head": {"vars": ["x"]}}`},
+		{JSON, `{"head": {"vars": ["x"]}, "results": {"bindings": [{"x": {"type": "term", "value": "a"}}]}}`},
+		{XML, `<sparql xmlns="http://www.w3.org/2005/sparql-results#"><results><result><binding name="x"/></result></results></sparql>`},
+		{CSV, "x,y\r\na\r\n"},
+		{TSV, "x\n<a>\n"},
+		{TSV, "?x\t?y\n<a>\n"},
+		{TSV, "?x\n?y\n"},
+		{TSV, "?x\n<a> <b>\n"},
+	}
+	for _, test := range tests {
+		if _, err := ParseResult(strings.NewReader(test.text), test.format); err == nil {
+			t.Errorf("%s: no error for %q", test.format, test.text)
+		}
+	}
+}
+
+func TestResultFormats(t *testing.T) {
+	if f, ok := ResultFormatByMediaType("application/sparql-results+json; charset=utf-8"); !ok || f != JSON {
+		t.Error("the media type of JSON results was not recognized")
+	}
+	if f, ok := ResultFormatByExtension("results.srx"); !ok || f != XML {
+		t.Error("the extension of XML results was not recognized")
+	}
+	if f, err := ResultFormatByName("TSV"); err != nil || f != TSV {
+		t.Error("the name of TSV results was not recognized")
+	}
+	if _, err := ResultFormatByName("yaml"); err == nil {
+		t.Error("an unknown result format was recognized")
+	}
+}
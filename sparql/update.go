@@ -0,0 +1,525 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+// Update
+//
+// the requests of SPARQL 1.1 Update. Stores only hold the graphs which
+// have statements, so graphs exist when they are not empty: CREATE fails
+// on graphs with statements and does nothing otherwise, and the operations
+// on empty graphs do nothing.
+
+// Update is a sequence of operations separated by semicolons
+type Update struct {
+	Operations []UpdateOperation
+	// the prefixes and the base declared by the request
+	Namespaces []model.Namespace
+	Base       model.IRI
+}
+
+// UpdateOperation is one of InsertData, DeleteData, Modify, Load, Clear,
+// Drop, Create, Add, Copy and Move, String gives it as an S-expression
+type UpdateOperation interface {
+	String() string
+	updateOperation()
+}
+
+// a triple pattern of a named graph, an IRI or a variable, or of the
+// default graph when Graph is nil
+type QuadPattern struct {
+	Graph model.RDFTerm
+	TriplePattern
+}
+
+// INSERT DATA, the blank nodes of Quads stand for new ones
+type InsertData struct {
+	Quads []*QuadPattern
+}
+
+// DELETE DATA
+type DeleteData struct {
+	Quads []*QuadPattern
+}
+
+// DELETE/INSERT ... WHERE, DELETE WHERE included: the Delete quads of each
+// solution of Pattern are removed, then the Insert ones are added
+type Modify struct {
+	// the default graph of the templates and of Pattern, unless there are
+	// USING clauses
+	With model.IRI
+	// the graphs of USING and USING NAMED
+	Using      []model.IRI
+	UsingNamed []model.IRI
+	Delete     []*QuadPattern
+	Insert     []*QuadPattern
+	Pattern    Operator
+	// the IRI relative IRIs are resolved against, for IRI()
+	base model.IRI
+}
+
+// LOAD, into the default graph when Into is empty
+type Load struct {
+	Silent bool
+	Source model.IRI
+	Into   model.IRI
+}
+
+// the graphs of CLEAR and DROP
+type GraphScope int
+
+const (
+	// the graph named by the operation
+	ScopeGraph GraphScope = iota
+	ScopeDefault
+	ScopeNamed
+	ScopeAll
+)
+
+func (this GraphScope) String() string {
+	return [...]string{"graph", "default", "named", "all"}[this]
+}
+
+// CLEAR, Graph is the graph of ScopeGraph
+type Clear struct {
+	Silent bool
+	Scope  GraphScope
+	Graph  model.IRI
+}
+
+// DROP, the same as CLEAR as graphs exist when they are not empty
+type Drop struct {
+	Silent bool
+	Scope  GraphScope
+	Graph  model.IRI
+}
+
+type Create struct {
+	Silent bool
+	Graph  model.IRI
+}
+
+// ADD, the graphs are the default graph when empty
+type Add struct {
+	Silent      bool
+	Source      model.IRI
+	Destination model.IRI
+}
+
+// COPY, ADD to a cleared destination
+type Copy struct {
+	Silent      bool
+	Source      model.IRI
+	Destination model.IRI
+}
+
+// MOVE, COPY then clearing the source
+type Move struct {
+	Silent      bool
+	Source      model.IRI
+	Destination model.IRI
+}
+
+func (this *InsertData) updateOperation() {}
+func (this *DeleteData) updateOperation() {}
+func (this *Modify) updateOperation()     {}
+func (this *Load) updateOperation()       {}
+func (this *Clear) updateOperation()      {}
+func (this *Drop) updateOperation()       {}
+func (this *Create) updateOperation()     {}
+func (this *Add) updateOperation()        {}
+func (this *Copy) updateOperation()       {}
+func (this *Move) updateOperation()       {}
+
+func (this *Update) String() string {
+	parts := []string{"update"}
+	for _, operation := range this.Operations {
+		parts = append(parts, operation.String())
+	}
+	return sexp(parts...)
+}
+
+func (this *QuadPattern) String() string {
+	if this.Graph == nil {
+		return this.TriplePattern.String()
+	}
+	return sexp("quad", formatTerm(this.Graph), formatTerm(this.Subject), formatTerm(this.Predicate), formatTerm(this.Object))
+}
+
+func formatQuads(name string, quads []*QuadPattern) string {
+	parts := []string{name}
+	for _, quad := range quads {
+		parts = append(parts, quad.String())
+	}
+	return sexp(parts...)
+}
+
+func (this *InsertData) String() string {
+	return formatQuads("insertData", this.Quads)
+}
+
+func (this *DeleteData) String() string {
+	return formatQuads("deleteData", this.Quads)
+}
+
+func (this *Modify) String() string {
+	parts := []string{"modify"}
+	if this.With != "" {
+		parts = append(parts, sexp("with", formatTerm(this.With)))
+	}
+	if len(this.Using) > 0 || len(this.UsingNamed) > 0 {
+		using := []string{"using"}
+		for _, iri := range this.Using {
+			using = append(using, formatTerm(iri))
+		}
+		for _, iri := range this.UsingNamed {
+			using = append(using, sexp("named", formatTerm(iri)))
+		}
+		parts = append(parts, sexp(using...))
+	}
+	if len(this.Delete) > 0 {
+		parts = append(parts, formatQuads("delete", this.Delete))
+	}
+	if len(this.Insert) > 0 {
+		parts = append(parts, formatQuads("insert", this.Insert))
+	}
+	return sexp(append(parts, this.Pattern.String())...)
+}
+
+// the graph of a transfer or of LOAD
+func formatGraph(graph model.IRI) string {
+	if graph == "" {
+		return "default"
+	}
+	return formatTerm(graph)
+}
+
+func formatOperation(name string, silent bool, operands ...string) string {
+	parts := []string{name}
+	if silent {
+		parts = append(parts, "silent")
+	}
+	return sexp(append(parts, operands...)...)
+}
+
+func (this *Load) String() string {
+	if this.Into == "" {
+		return formatOperation("load", this.Silent, formatTerm(this.Source))
+	}
+	return formatOperation("load", this.Silent, formatTerm(this.Source), formatTerm(this.Into))
+}
+
+func formatScope(scope GraphScope, graph model.IRI) string {
+	if scope == ScopeGraph {
+		return formatTerm(graph)
+	}
+	return scope.String()
+}
+
+func (this *Clear) String() string {
+	return formatOperation("clear", this.Silent, formatScope(this.Scope, this.Graph))
+}
+
+func (this *Drop) String() string {
+	return formatOperation("drop", this.Silent, formatScope(this.Scope, this.Graph))
+}
+
+func (this *Create) String() string {
+	return formatOperation("create", this.Silent, formatTerm(this.Graph))
+}
+
+func (this *Add) String() string {
+	return formatOperation("add", this.Silent, formatGraph(this.Source), formatGraph(this.Destination))
+}
+
+func (this *Copy) String() string {
+	return formatOperation("copy", this.Silent, formatGraph(this.Source), formatGraph(this.Destination))
+}
+
+func (this *Move) String() string {
+	return formatOperation("move", this.Silent, formatGraph(this.Source), formatGraph(this.Destination))
+}
+
+// evaluation
+
+type UpdateOptions struct {
+	// reads the documents of LOAD, LoadFile when nil
+	Load func(source model.IRI) ([]*model.Statement, error)
+}
+
+// OperationError is the failure of an update operation, such as creating a
+// graph which exists, as opposed to the failures of the store
+type OperationError struct {
+	Err error
+}
+
+func (this *OperationError) Error() string {
+	return this.Err.Error()
+}
+
+func (this *OperationError) Unwrap() error {
+	return this.Err
+}
+
+// ApplyUpdate applies the operations of an update in order, each seeing
+// the changes of the previous ones. The dataset is only changed once they
+// all succeed, the failures of the SILENT ones being ignored. The failures
+// of the operations are *OperationError.
+func ApplyUpdate(update *Update, dataset store.Store, options UpdateOptions) (err error) {
+	if options.Load == nil {
+		options.Load = LoadFile
+	}
+	this := &updater{store: store.NewTransaction(dataset), options: options}
+	defer recoverStore(&err)
+	for _, operation := range update.Operations {
+		if err := this.apply(operation); err != nil && !silent(operation) {
+			return &OperationError{Err: err}
+		}
+	}
+	return this.store.Commit()
+}
+
+// LoadFile reads a local file, named by a file: IRI or by its path, in the
+// format of its extension
+func LoadFile(source model.IRI) ([]*model.Statement, error) {
+	path := string(source)
+	if parsed, err := url.Parse(path); err == nil && parsed.Scheme == "file" {
+		path = parsed.Path
+	} else if err == nil && len(parsed.Scheme) > 1 {
+		// a single letter is a drive
+		return nil, fmt.Errorf("cannot load <%s>: only local files can be loaded", source)
+	}
+	f, ok := format.ByExtension(path)
+	if !ok {
+		return nil, fmt.Errorf("cannot load <%s>: unknown format", source)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	base := source
+	if absolute, err := filepath.Abs(path); err == nil {
+		base = model.IRI((&url.URL{Scheme: "file", Path: filepath.ToSlash(absolute)}).String())
+	}
+	ret, err := parser.ParseAll(file, parser.Options{Format: f, Base: base})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ret, nil
+}
+
+func silent(operation UpdateOperation) bool {
+	switch op := operation.(type) {
+	case *Load:
+		return op.Silent
+	case *Clear:
+		return op.Silent
+	case *Drop:
+		return op.Silent
+	case *Create:
+		return op.Silent
+	case *Add:
+		return op.Silent
+	case *Copy:
+		return op.Silent
+	case *Move:
+		return op.Silent
+	}
+	return false
+}
+
+// the state of an update, the errors of the store being raised by panic
+type updater struct {
+	store   *store.Transaction
+	options UpdateOptions
+}
+
+func (this *updater) apply(operation UpdateOperation) error {
+	switch op := operation.(type) {
+	case *InsertData:
+		nodes := map[model.RDFTerm]model.RDFTerm{}
+		for _, quad := range op.Quads {
+			this.add(this.quad(quad, Solution{}, nodes, ""))
+		}
+	case *DeleteData:
+		for _, quad := range op.Quads {
+			this.remove(this.quad(quad, Solution{}, nil, ""))
+		}
+	case *Modify:
+		this.modify(op)
+	case *Load:
+		statements, err := this.options.Load(op.Source)
+		if err != nil {
+			return err
+		}
+		for _, statement := range statements {
+			if op.Into != "" {
+				statement = &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object, Context: op.Into}
+			}
+			this.add(statement)
+		}
+	case *Clear:
+		this.clear(op.Scope, op.Graph)
+	case *Drop:
+		this.clear(op.Scope, op.Graph)
+	case *Create:
+		if len(this.statements(op.Graph)) > 0 {
+			return fmt.Errorf("the graph <%s> already exists", op.Graph)
+		}
+	case *Add:
+		this.transfer(op.Source, op.Destination, false, false)
+	case *Copy:
+		this.transfer(op.Source, op.Destination, true, false)
+	case *Move:
+		this.transfer(op.Source, op.Destination, true, true)
+	}
+	return nil
+}
+
+// the statements to delete and insert are those of the solutions over
+// the dataset as it was before the operation
+func (this *updater) modify(op *Modify) {
+	evaluator := newEvaluator(this.store, &Query{From: op.Using, FromNamed: op.UsingNamed, base: op.base})
+	if op.With != "" && len(op.Using) == 0 && len(op.UsingNamed) == 0 {
+		evaluator.defaultGraphs = []model.RDFTerm{op.With}
+	}
+	deleted, inserted := []*model.Statement{}, []*model.Statement{}
+	for _, solution := range evaluator.evaluate(op.Pattern, evaluator.defaultGraphs) {
+		for _, quad := range op.Delete {
+			if statement := this.quad(quad, solution, nil, op.With); statement != nil {
+				deleted = append(deleted, statement)
+			}
+		}
+		nodes := map[model.RDFTerm]model.RDFTerm{}
+		for _, quad := range op.Insert {
+			if statement := this.quad(quad, solution, nodes, op.With); statement != nil {
+				inserted = append(inserted, statement)
+			}
+		}
+	}
+	for _, statement := range deleted {
+		this.remove(statement)
+	}
+	for _, statement := range inserted {
+		this.add(statement)
+	}
+}
+
+// the statement of a quad for a solution, with new blank nodes, nil when
+// a variable is unbound or the statement invalid; quads of the default
+// graph go to graph when it is not empty
+func (this *updater) quad(quad *QuadPattern, solution Solution, nodes map[model.RDFTerm]model.RDFTerm, graph model.IRI) *model.Statement {
+	ret := &model.Statement{
+		Subject:   instantiate(quad.Subject, solution, nodes),
+		Predicate: instantiate(quad.Predicate, solution, nodes),
+		Object:    instantiate(quad.Object, solution, nodes),
+	}
+	if !validTriple(ret.Subject, ret.Predicate, ret.Object) {
+		return nil
+	}
+	switch g := quad.Graph.(type) {
+	case nil:
+		if graph != "" {
+			ret.Context = graph
+		}
+	case Variable:
+		iri, ok := solution[g].(model.IRI)
+		if !ok {
+			return nil
+		}
+		ret.Context = iri
+	default:
+		ret.Context = g
+	}
+	return ret
+}
+
+func (this *updater) add(statement *model.Statement) {
+	if statement == nil {
+		return
+	}
+	if err := this.store.Add(statement); err != nil {
+		panic(storeFailure{err})
+	}
+}
+
+func (this *updater) remove(statement *model.Statement) {
+	if statement == nil {
+		return
+	}
+	if err := this.store.Remove(statement); err != nil {
+		panic(storeFailure{err})
+	}
+}
+
+// the statements of a graph, the default one when empty
+func (this *updater) statements(graph model.IRI) []*model.Statement {
+	var pattern model.RDFTerm = store.DefaultGraph
+	if graph != "" {
+		pattern = graph
+	}
+	ret, err := store.All(this.store.Match(nil, nil, nil, pattern))
+	if err != nil {
+		panic(storeFailure{err})
+	}
+	return ret
+}
+
+func (this *updater) clear(scope GraphScope, graph model.IRI) {
+	var statements []*model.Statement
+	switch scope {
+	case ScopeGraph:
+		statements = this.statements(graph)
+	case ScopeDefault:
+		statements = this.statements("")
+	default:
+		all, err := store.All(this.store.Match(nil, nil, nil, nil))
+		if err != nil {
+			panic(storeFailure{err})
+		}
+		for _, statement := range all {
+			if scope == ScopeAll || statement.Context != nil {
+				statements = append(statements, statement)
+			}
+		}
+	}
+	for _, statement := range statements {
+		this.remove(statement)
+	}
+}
+
+// adds the statements of source to destination, which is cleared first
+// when clear is set, the source being cleared after when move is set
+func (this *updater) transfer(source, destination model.IRI, clear, move bool) {
+	if source == destination {
+		return
+	}
+	statements := this.statements(source)
+	if clear {
+		this.clear(ScopeGraph, destination)
+	}
+	for _, statement := range statements {
+		copied := &model.Statement{Subject: statement.Subject, Predicate: statement.Predicate, Object: statement.Object}
+		if destination != "" {
+			copied.Context = destination
+		}
+		this.add(copied)
+	}
+	if move {
+		this.clear(ScopeGraph, source)
+	}
+}
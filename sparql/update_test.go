@@ -0,0 +1,224 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+	"github.com/nfreundl/rdf-tools/store"
+)
+
+func TestParseUpdate(t *testing.T) {
+	cases := []struct {
+		update  string
+		algebra string
+	}{
+		{
+			`PREFIX : <http://ex.org/> INSERT DATA { :a :p "x" . GRAPH :g { :a :q 1 } } ; DELETE DATA { :a :p "y" }`,
+			`(update (insertData (triple <http://ex.org/a> <http://ex.org/p> "x") (quad <http://ex.org/g> <http://ex.org/a> <http://ex.org/q> 1)) (deleteData (triple <http://ex.org/a> <http://ex.org/p> "y")))`,
+		},
+		{
+			`BASE <http://ex.org/> WITH <g> DELETE { ?s <p> ?o } INSERT { ?s <q> [ <r> ?o ] } USING <h> USING NAMED <i> WHERE { ?s <p> ?o }`,
+			`(update (modify (with <http://ex.org/g>) (using <http://ex.org/h> (named <http://ex.org/i>)) (delete (triple ?s <http://ex.org/p> ?o)) (insert (triple [] <http://ex.org/r> ?o) (triple ?s <http://ex.org/q> [])) (bgp (triple ?s <http://ex.org/p> ?o))))`,
+		},
+		{
+			`DELETE WHERE { ?s <p> ?o . GRAPH ?g { ?o <q> ?x } }`,
+			`(update (modify (delete (triple ?s <p> ?o) (quad ?g ?o <q> ?x)) (join (bgp (triple ?s <p> ?o)) (graph ?g (bgp (triple ?o <q> ?x))))))`,
+		},
+		{
+			`INSERT { GRAPH ?g { ?s a <C> } } WHERE { GRAPH ?g { ?s ?p ?o } }`,
+			`(update (modify (insert (quad ?g ?s <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <C>)) (graph ?g (bgp (triple ?s ?p ?o)))))`,
+		},
+		{
+			`LOAD <data.ttl> ; LOAD SILENT <file:///data.nq> INTO GRAPH <g> ; CLEAR DEFAULT ; DROP SILENT GRAPH <g> ; CLEAR ALL ; DROP NAMED ; CREATE GRAPH <h> ;`,
+			`(update (load <data.ttl>) (load silent <file:///data.nq> <g>) (clear default) (drop silent <g>) (clear all) (drop named) (create <h>))`,
+		},
+		{
+			`ADD <a> TO DEFAULT ; COPY SILENT GRAPH <a> TO <b> ; MOVE DEFAULT TO GRAPH <c>`,
+			`(update (add <a> default) (copy silent <a> <b>) (move default <c>))`,
+		},
+		{
+			``,
+			`(update)`,
+		},
+	}
+	for _, c := range cases {
+		update, err := ParseUpdate(c.update, Options{})
+		if err != nil {
+			t.Errorf("%s: %v", c.update, err)
+			continue
+		}
+		if update.String() != c.algebra {
+			t.Errorf("%s:\n%s\ninstead of\n%s", c.update, update, c.algebra)
+		}
+	}
+}
+
+func TestUpdateSyntaxErrors(t *testing.T) {
+	cases := []struct {
+		update string
+		line   int
+		col    int
+	}{
+		{"INSERT DATA { ?s <p> <o> }", 1, 27},
+		{"DELETE DATA { _:b <p> <o> }", 1, 28},
+		{"DELETE { [] <p> ?o } WHERE { ?s <p> ?o }", 1, 22},
+		{"INSERT DATA { <s> <p> <o> } INSERT DATA { <s> <p> <o> }", 1, 29},
+		{"INSERT DATA { <s> <p> <o> <t> <p> <o> }", 1, 27},
+		{"CLEAR <g>", 1, 7},
+		{"COPY <a> <b>", 1, 10},
+		{"WITH <g> WHERE { }", 1, 10},
+		{"DELETE { ?s <p> ?o }", 1, 21},
+		{"SELECT * { }", 1, 1},
+	}
+	for _, c := range cases {
+		_, err := ParseUpdate(c.update, Options{})
+		syntaxError, ok := err.(*parser.SyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error, got %v", c.update, err)
+			continue
+		}
+		if syntaxError.Line != c.line || syntaxError.Col != c.col {
+			t.Errorf("%q: error %v, expected at %d:%d", c.update, syntaxError, c.line, c.col)
+		}
+	}
+}
+
+func applyUpdate(t *testing.T, dataset store.Store, text string) error {
+	t.Helper()
+	update, err := ParseUpdate(text, Options{Namespaces: ex})
+	if err != nil {
+		t.Fatalf("%s: %v", text, err)
+	}
+	return ApplyUpdate(update, dataset, UpdateOptions{})
+}
+
+// the statements of the dataset as sorted lines
+func quads(t *testing.T, dataset store.Store, graph model.RDFTerm) []string {
+	t.Helper()
+	result := run(t, dataset, `SELECT ?s ?p ?o { ?s ?p ?o }`)
+	if graph != nil {
+		result = run(t, dataset, `SELECT ?s ?p ?o { GRAPH `+formatTerm(graph)+` { ?s ?p ?o } }`)
+	}
+	ret := rows(result)
+	sort.Strings(ret)
+	return ret
+}
+
+func TestApplyUpdate(t *testing.T) {
+	cases := []struct {
+		update   string
+		graph    model.RDFTerm
+		expected []string
+	}{
+		{`INSERT DATA { GRAPH :g1 { :bob :likes :tea . :alice :likes :tea } }`, model.IRI("http://ex.org/g1"), []string{
+			`<:alice> <:likes> <:tea>`, `<:bob> <:likes> <:tea>`}},
+		{`DELETE DATA { GRAPH :g2 { :bob :likes :coffee . :bob :likes :milk } }`, model.IRI("http://ex.org/g2"), []string{
+			`<:alice> <:likes> <:coffee>`}},
+		{`DELETE { ?p :age ?a } INSERT { ?p :age ?b } WHERE { ?p :age ?a BIND(?a + 1 AS ?b) } ; DELETE WHERE { ?p a ?c ; :knows ?q . ?q :name ?n } ; DELETE WHERE { ?p :name ?n ; :address ?a }`, nil, []string{
+			`<:alice> <:age> 31`, `<:alice> <:name> "Alice"@en`, `<:bob> <:age> 26`, `<:carol> <:address> []`,
+			`<:carol> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <:Person>`, `[] <:city> "Paris"`, `[] <:code> "75001"`, `[] <:zip> []`}},
+		{`WITH :g2 DELETE { ?p :likes ?d } INSERT { GRAPH :g3 { ?p :likes ?d } } WHERE { ?p :likes ?d }`, model.IRI("http://ex.org/g3"), []string{
+			`<:alice> <:likes> <:coffee>`, `<:bob> <:likes> <:coffee>`}},
+		{`INSERT { GRAPH :g1 { ?p :drinks ?d } } USING NAMED :g2 WHERE { GRAPH ?g { ?p :likes ?d } }`, model.IRI("http://ex.org/g1"), []string{
+			`<:alice> <:drinks> <:coffee>`, `<:alice> <:likes> <:tea>`, `<:bob> <:drinks> <:coffee>`}},
+		{`COPY :g1 TO :g2`, model.IRI("http://ex.org/g2"), []string{
+			`<:alice> <:likes> <:tea>`}},
+		{`ADD :g1 TO :g2 ; DROP DEFAULT`, model.IRI("http://ex.org/g2"), []string{
+			`<:alice> <:likes> <:coffee>`, `<:alice> <:likes> <:tea>`, `<:bob> <:likes> <:coffee>`}},
+		{`MOVE :g1 TO :g2 ; ADD :g2 TO :g2`, model.IRI("http://ex.org/g2"), []string{
+			`<:alice> <:likes> <:tea>`}},
+		{`MOVE :g1 TO DEFAULT ; CLEAR NAMED`, nil, []string{
+			`<:alice> <:likes> <:tea>`}},
+		{`CREATE SILENT GRAPH :g1 ; CREATE GRAPH :g4 ; DROP ALL`, nil, []string{}},
+	}
+	for _, c := range cases {
+		dataset := peopleStore(t)
+		if err := applyUpdate(t, dataset, c.update); err != nil {
+			t.Errorf("%s: %v", c.update, err)
+			continue
+		}
+		got := quads(t, dataset, c.graph)
+		if strings.Join(got, "\n") != strings.Join(c.expected, "\n") {
+			t.Errorf("%s: got\n%s\ninstead of\n%s", c.update, strings.Join(got, "\n"), strings.Join(c.expected, "\n"))
+		}
+	}
+}
+
+func TestUpdateBlankNodes(t *testing.T) {
+	dataset := store.NewMemory()
+	// each application and each solution makes new blank nodes
+	for i := 0; i < 2; i++ {
+		if err := applyUpdate(t, dataset, `INSERT DATA { _:a :p _:a . [] :q 1 }`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := applyUpdate(t, dataset, `INSERT { [] :r ?o } WHERE { ?s :p ?o }`); err != nil {
+		t.Fatal(err)
+	}
+	if got := rows(run(t, dataset, `SELECT (COUNT(DISTINCT ?s) AS ?n) { ?s ?p ?o }`)); got[0] != "6" {
+		t.Errorf("%s subjects instead of 6", got[0])
+	}
+	if got := rows(run(t, dataset, `SELECT (COUNT(*) AS ?n) { ?s :p ?s }`)); got[0] != "2" {
+		t.Errorf("%s reflexive statements instead of 2", got[0])
+	}
+}
+
+func TestUpdateAtomicity(t *testing.T) {
+	dataset := peopleStore(t)
+	size := dataset.Len()
+	for _, text := range []string{
+		`INSERT DATA { :dave :name "Dave" } ; LOAD <missing.ttl>`,
+		`DROP DEFAULT ; CREATE GRAPH :g1`,
+		`DELETE DATA { :bob :name "Bob" } ; LOAD <http://ex.org/data.ttl>`,
+	} {
+		if err := applyUpdate(t, dataset, text); err == nil {
+			t.Errorf("%s: no error", text)
+		}
+		if dataset.Len() != size {
+			t.Errorf("%s: %d statements instead of %d", text, dataset.Len(), size)
+		}
+	}
+	if err := applyUpdate(t, dataset, `CLEAR DEFAULT ; LOAD SILENT <missing.ttl> ; CREATE SILENT GRAPH :g1`); err != nil {
+		t.Error(err)
+	}
+	if dataset.Len() != 3 {
+		t.Errorf("%d statements instead of 3", dataset.Len())
+	}
+}
+
+func TestUpdateLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.trig")
+	if err := os.WriteFile(path, []byte(`@prefix : <http://ex.org/> . :a :p <b> . :g { :a :q 1 }`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataset := store.NewMemory()
+	if err := applyUpdate(t, dataset, `LOAD <file://`+filepath.ToSlash(path)+`> ; LOAD <`+filepath.ToSlash(path)+`> INTO GRAPH :h`); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{`<:a> <:p> <file://` + filepath.ToSlash(dir) + `/b>`}
+	if got := quads(t, dataset, nil); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("got %v instead of %v", got, expected)
+	}
+	if got := quads(t, dataset, model.IRI("http://ex.org/h")); len(got) != 2 {
+		t.Errorf("%d statements loaded into the graph instead of 2", len(got))
+	}
+
+	// the loader is replaceable
+	update, _ := ParseUpdate(`LOAD <http://ex.org/data>`, Options{})
+	err := ApplyUpdate(update, dataset, UpdateOptions{Load: func(source model.IRI) ([]*model.Statement, error) {
+		return []*model.Statement{{Subject: source, Predicate: model.A, Object: model.IRI("http://ex.org/Document")}}, nil
+	}})
+	if err != nil || dataset.Len() != 5 {
+		t.Errorf("%d statements after loading, error %v", dataset.Len(), err)
+	}
+}
@@ -0,0 +1,617 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package sparql
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/writer"
+)
+
+// Values
+//
+// the expressions work on terms, literals being read into numbers, booleans
+// and dates when an operator or a function needs their value. Errors are
+// the type errors of the recommendation: they make FILTER drop a solution
+// and BIND leave a variable unbound.
+
+var errType = errors.New("type error")
+
+var errUnbound = errors.New("unbound variable")
+
+// the derived types of xsd:integer
+var integerTypes = map[model.IRI]bool{
+	model.XSDInteger:                 true,
+	model.XSD + "nonPositiveInteger": true, model.XSD + "negativeInteger": true,
+	model.XSD + "long": true, model.XSD + "int": true, model.XSD + "short": true, model.XSD + "byte": true,
+	model.XSD + "nonNegativeInteger": true, model.XSD + "positiveInteger": true,
+	model.XSD + "unsignedLong": true, model.XSD + "unsignedInt": true,
+	model.XSD + "unsignedShort": true, model.XSD + "unsignedByte": true,
+}
+
+var (
+	integerPattern = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	decimalPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`)
+	doublePattern  = regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`)
+	// year, month, day, hours, minutes, seconds and time zone
+	dateTimePattern = regexp.MustCompile(`^(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})T([0-9]{2}):([0-9]{2}):([0-9]{2}(?:\.[0-9]+)?)(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+	datePattern     = regexp.MustCompile(`^(-?[0-9]{4,})-([0-9]{2})-([0-9]{2})(Z|[+-][0-9]{2}:[0-9]{2})?$`)
+)
+
+// numeric types, in the order of type promotion
+const (
+	integerType = iota
+	decimalType
+	floatType
+	doubleType
+)
+
+var numericDatatypes = [...]model.IRI{model.XSDInteger, model.XSDDecimal, model.XSDFloat, model.XSDDouble}
+
+// the value of a numeric literal: rational for integers and decimals,
+// float64 for floats and doubles
+type numeric struct {
+	kind     int
+	rational *big.Rat
+	float    float64
+}
+
+// the value of a numeric literal with a valid lexical form
+func numericValue(term model.RDFTerm) (numeric, bool) {
+	literal, ok := term.(model.Literal)
+	if !ok {
+		return numeric{}, false
+	}
+	switch {
+	case integerTypes[literal.Datatype]:
+		if !integerPattern.MatchString(literal.Lexical) {
+			return numeric{}, false
+		}
+		ret, _ := new(big.Rat).SetString(strings.TrimPrefix(literal.Lexical, "+"))
+		return numeric{kind: integerType, rational: ret}, true
+	case literal.Datatype == model.XSDDecimal:
+		if !decimalPattern.MatchString(literal.Lexical) {
+			return numeric{}, false
+		}
+		ret, _ := new(big.Rat).SetString(strings.TrimPrefix(literal.Lexical, "+"))
+		return numeric{kind: decimalType, rational: ret}, true
+	case literal.Datatype == model.XSDFloat || literal.Datatype == model.XSDDouble:
+		value, ok := parseDouble(literal.Lexical)
+		if !ok {
+			return numeric{}, false
+		}
+		if literal.Datatype == model.XSDFloat {
+			return numeric{kind: floatType, float: float64(float32(value))}, true
+		}
+		return numeric{kind: doubleType, float: value}, true
+	}
+	return numeric{}, false
+}
+
+func parseDouble(lexical string) (float64, bool) {
+	if !doublePattern.MatchString(lexical) {
+		return 0, false
+	}
+	switch lexical {
+	case "INF", "+INF":
+		return math.Inf(1), true
+	case "-INF":
+		return math.Inf(-1), true
+	case "NaN":
+		return math.NaN(), true
+	}
+	value, err := strconv.ParseFloat(lexical, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return value, true
+}
+
+func integerValue(value int64) numeric {
+	return numeric{kind: integerType, rational: new(big.Rat).SetInt64(value)}
+}
+
+// the value as kind, which is not lower than its own kind
+func (this numeric) promote(kind int) numeric {
+	if kind == this.kind {
+		return this
+	}
+	ret := numeric{kind: kind, rational: this.rational, float: this.float}
+	if this.kind <= decimalType && kind >= floatType {
+		ret.float, _ = this.rational.Float64()
+	}
+	if kind == floatType {
+		ret.float = float64(float32(ret.float))
+	}
+	return ret
+}
+
+func (this numeric) isZero() bool {
+	if this.kind <= decimalType {
+		return this.rational.Sign() == 0
+	}
+	return this.float == 0
+}
+
+func (this numeric) isNaN() bool {
+	return this.kind >= floatType && math.IsNaN(this.float)
+}
+
+// the numeric literal of the value, in canonical form
+func (this numeric) literal() model.Literal {
+	datatype := numericDatatypes[this.kind]
+	switch this.kind {
+	case integerType:
+		return model.NewTypedLiteral(this.rational.Num().String(), datatype)
+	case decimalType:
+		return model.NewTypedLiteral(formatDecimal(this.rational), datatype)
+	case floatType:
+		return model.NewTypedLiteral(formatDouble(this.float, 32), datatype)
+	}
+	return model.NewTypedLiteral(formatDouble(this.float, 64), datatype)
+}
+
+// the digits of a decimal, with at least one after the point; decimals
+// which cannot be written exactly keep 24 digits after the point
+func formatDecimal(value *big.Rat) string {
+	if value.IsInt() {
+		return value.Num().String() + ".0"
+	}
+	// the fraction is exact when the denominator only has the factors 2
+	// and 5
+	denominator := new(big.Int).Set(value.Denom())
+	twos, fives := 0, 0
+	for denominator.Bit(0) == 0 {
+		denominator.Rsh(denominator, 1)
+		twos++
+	}
+	five, quotient, remainder := big.NewInt(5), new(big.Int), new(big.Int)
+	for {
+		quotient.QuoRem(denominator, five, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		denominator.Set(quotient)
+		fives++
+	}
+	if denominator.Cmp(big.NewInt(1)) != 0 {
+		ret := strings.TrimRight(value.FloatString(24), "0")
+		if strings.HasSuffix(ret, ".") {
+			ret += "0"
+		}
+		return ret
+	}
+	if fives > twos {
+		return value.FloatString(fives)
+	}
+	return value.FloatString(twos)
+}
+
+// the canonical form of doubles, as in 1.5E1, INF or NaN
+func formatDouble(value float64, bits int) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "INF"
+	case math.IsInf(value, -1):
+		return "-INF"
+	}
+	ret := strconv.FormatFloat(value, 'E', -1, bits)
+	mantissa, exponent := ret, "0"
+	if i := strings.IndexByte(ret, 'E'); i >= 0 {
+		mantissa, exponent = ret[:i], ret[i+1:]
+	}
+	if !strings.ContainsRune(mantissa, '.') {
+		mantissa += ".0"
+	}
+	number, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(number)
+}
+
+// + - * /, dividing integers gives a decimal
+func arithmetic(operator string, a, b numeric) (numeric, error) {
+	kind := a.kind
+	if b.kind > kind {
+		kind = b.kind
+	}
+	if operator == "/" && kind == integerType {
+		kind = decimalType
+	}
+	a, b = a.promote(kind), b.promote(kind)
+	ret := numeric{kind: kind}
+	if kind >= floatType {
+		switch operator {
+		case "+":
+			ret.float = a.float + b.float
+		case "-":
+			ret.float = a.float - b.float
+		case "*":
+			ret.float = a.float * b.float
+		case "/":
+			ret.float = a.float / b.float
+		}
+		if kind == floatType {
+			ret.float = float64(float32(ret.float))
+		}
+		return ret, nil
+	}
+	ret.rational = new(big.Rat)
+	switch operator {
+	case "+":
+		ret.rational.Add(a.rational, b.rational)
+	case "-":
+		ret.rational.Sub(a.rational, b.rational)
+	case "*":
+		ret.rational.Mul(a.rational, b.rational)
+	case "/":
+		if b.rational.Sign() == 0 {
+			return numeric{}, fmt.Errorf("division by zero")
+		}
+		ret.rational.Quo(a.rational, b.rational)
+	}
+	return ret, nil
+}
+
+func negate(value numeric) numeric {
+	if value.kind >= floatType {
+		return numeric{kind: value.kind, float: -value.float}
+	}
+	return numeric{kind: value.kind, rational: new(big.Rat).Neg(value.rational)}
+}
+
+// -1, 0 or 1, or unordered when a value is NaN
+func compareNumbers(a, b numeric) int {
+	kind := a.kind
+	if b.kind > kind {
+		kind = b.kind
+	}
+	a, b = a.promote(kind), b.promote(kind)
+	if kind <= decimalType {
+		return a.rational.Cmp(b.rational)
+	}
+	switch {
+	case math.IsNaN(a.float) || math.IsNaN(b.float):
+		return unordered
+	case a.float < b.float:
+		return -1
+	case a.float > b.float:
+		return 1
+	}
+	return 0
+}
+
+// floor, ceil and round keep the type of their argument
+func floorNumber(value numeric) numeric {
+	if value.kind >= floatType {
+		return numeric{kind: value.kind, float: math.Floor(value.float)}
+	}
+	// Div rounds towards minus infinity for positive divisors
+	floor := new(big.Int).Div(value.rational.Num(), value.rational.Denom())
+	return numeric{kind: value.kind, rational: new(big.Rat).SetInt(floor)}
+}
+
+func ceilNumber(value numeric) numeric {
+	return negate(floorNumber(negate(value)))
+}
+
+// rounds halves towards positive infinity, as does fn:round
+func roundNumber(value numeric) numeric {
+	if value.kind >= floatType {
+		if math.IsInf(value.float, 0) || math.IsNaN(value.float) {
+			return value
+		}
+		return numeric{kind: value.kind, float: math.Floor(value.float + 0.5)}
+	}
+	half := numeric{kind: value.kind, rational: new(big.Rat).Add(value.rational, big.NewRat(1, 2))}
+	return floorNumber(half)
+}
+
+func absNumber(value numeric) numeric {
+	if value.kind >= floatType {
+		return numeric{kind: value.kind, float: math.Abs(value.float)}
+	}
+	return numeric{kind: value.kind, rational: new(big.Rat).Abs(value.rational)}
+}
+
+// the result of comparing values which have no order, such as NaN
+const unordered = 2
+
+// simple literals and xsd:string
+func isString(term model.RDFTerm) bool {
+	literal, ok := term.(model.Literal)
+	return ok && (literal.Datatype == model.XSDString || literal.Datatype == "") && literal.Language == ""
+}
+
+func isLanguageString(term model.RDFTerm) bool {
+	literal, ok := term.(model.Literal)
+	return ok && literal.Language != ""
+}
+
+func booleanLiteral(value bool) model.Literal {
+	return model.NewTypedLiteral(strconv.FormatBool(value), model.XSDBoolean)
+}
+
+func booleanValue(literal model.Literal) (bool, bool) {
+	switch literal.Lexical {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// the effective boolean value, which FILTER keeps solutions on
+func effectiveBoolean(term model.RDFTerm) (bool, error) {
+	literal, ok := term.(model.Literal)
+	if !ok {
+		return false, errType
+	}
+	switch {
+	case literal.Datatype == model.XSDBoolean:
+		value, _ := booleanValue(literal)
+		return value, nil
+	case isString(literal):
+		return literal.Lexical != "", nil
+	case integerTypes[literal.Datatype] || literal.Datatype == model.XSDDecimal ||
+		literal.Datatype == model.XSDFloat || literal.Datatype == model.XSDDouble:
+		value, ok := numericValue(literal)
+		return ok && !value.isZero() && !value.isNaN(), nil
+	}
+	return false, errType
+}
+
+// the instant of an xsd:dateTime or an xsd:date, dates and times without
+// time zone being taken as UTC
+type dateTime struct {
+	year, month, day int
+	hours, minutes   int
+	seconds          *big.Rat
+	timezone         string
+	date             bool
+}
+
+func dateTimeValue(term model.RDFTerm) (*dateTime, bool) {
+	literal, ok := term.(model.Literal)
+	if !ok {
+		return nil, false
+	}
+	ret := &dateTime{seconds: new(big.Rat)}
+	var parts []string
+	switch literal.Datatype {
+	case model.XSDDateTime:
+		parts = dateTimePattern.FindStringSubmatch(literal.Lexical)
+		if parts == nil {
+			return nil, false
+		}
+		ret.hours, _ = strconv.Atoi(parts[4])
+		ret.minutes, _ = strconv.Atoi(parts[5])
+		ret.seconds.SetString(parts[6])
+		ret.timezone = parts[7]
+	case model.XSDDate:
+		parts = datePattern.FindStringSubmatch(literal.Lexical)
+		if parts == nil {
+			return nil, false
+		}
+		ret.timezone = parts[4]
+		ret.date = true
+	default:
+		return nil, false
+	}
+	ret.year, _ = strconv.Atoi(parts[1])
+	ret.month, _ = strconv.Atoi(parts[2])
+	ret.day, _ = strconv.Atoi(parts[3])
+	if ret.month < 1 || ret.month > 12 || ret.day < 1 || ret.day > 31 || ret.hours > 24 || ret.minutes > 59 {
+		return nil, false
+	}
+	return ret, true
+}
+
+// the offset of the time zone in minutes
+func (this *dateTime) offset() int {
+	if this.timezone == "" || this.timezone == "Z" {
+		return 0
+	}
+	hours, _ := strconv.Atoi(this.timezone[1:3])
+	minutes, _ := strconv.Atoi(this.timezone[4:])
+	ret := hours*60 + minutes
+	if this.timezone[0] == '-' {
+		return -ret
+	}
+	return ret
+}
+
+// the instant in seconds since the epoch
+func (this *dateTime) instant() *big.Rat {
+	whole := time.Date(this.year, time.Month(this.month), this.day, this.hours, this.minutes, 0, 0, time.UTC)
+	seconds := whole.Unix() - int64(this.offset())*60
+	return new(big.Rat).Add(new(big.Rat).SetInt64(seconds), this.seconds)
+}
+
+// the xsd:dayTimeDuration of the time zone, as in -PT5H
+func (this *dateTime) timezoneDuration() (model.Literal, bool) {
+	if this.timezone == "" {
+		return model.Literal{}, false
+	}
+	offset := this.offset()
+	ret := "PT"
+	if offset < 0 {
+		ret, offset = "-PT", -offset
+	}
+	if offset == 0 {
+		ret += "0S"
+	}
+	if offset/60 > 0 {
+		ret += strconv.Itoa(offset/60) + "H"
+	}
+	if offset%60 > 0 {
+		ret += strconv.Itoa(offset%60) + "M"
+	}
+	return model.NewTypedLiteral(ret, model.XSDDayTimeDuration), true
+}
+
+// compares the values of literals which have an order: numbers, strings,
+// booleans and dates. The result is -1, 0, 1 or unordered.
+func compareValues(a, b model.RDFTerm) (int, error) {
+	if x, ok := numericValue(a); ok {
+		if y, ok := numericValue(b); ok {
+			return compareNumbers(x, y), nil
+		}
+		return 0, errType
+	}
+	if isString(a) && isString(b) {
+		return strings.Compare(a.(model.Literal).Lexical, b.(model.Literal).Lexical), nil
+	}
+	la, aok := a.(model.Literal)
+	lb, bok := b.(model.Literal)
+	if !aok || !bok {
+		return 0, errType
+	}
+	if la.Datatype == model.XSDBoolean && lb.Datatype == model.XSDBoolean {
+		x, xok := booleanValue(la)
+		y, yok := booleanValue(lb)
+		if !xok || !yok {
+			return 0, errType
+		}
+		switch {
+		case x == y:
+			return 0, nil
+		case !x:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	if la.Datatype == lb.Datatype && (la.Datatype == model.XSDDateTime || la.Datatype == model.XSDDate) {
+		x, xok := dateTimeValue(la)
+		y, yok := dateTimeValue(lb)
+		if !xok || !yok {
+			return 0, errType
+		}
+		return x.instant().Cmp(y.instant()), nil
+	}
+	return 0, errType
+}
+
+// Compare compares literals by value like the operators < and >, false
+// when they have no order: numbers, strings, booleans and dates are
+// ordered among themselves
+func Compare(a, b model.RDFTerm) (int, bool) {
+	ret, err := compareValues(a, b)
+	return ret, err == nil && ret != unordered
+}
+
+// the datatypes whose values the operators know
+func knownDatatype(literal model.Literal) bool {
+	switch literal.Datatype {
+	case model.XSDString, model.XSDBoolean, model.XSDDecimal, model.XSDFloat, model.XSDDouble,
+		model.XSDDateTime, model.XSDDate, model.RDFLangString, model.RDFDirLangString, "":
+		return true
+	}
+	return integerTypes[literal.Datatype]
+}
+
+// the = operator: literals are compared by value, other terms must be the
+// same
+func equalTerms(a, b model.RDFTerm) (bool, error) {
+	la, aok := a.(model.Literal)
+	lb, bok := b.(model.Literal)
+	if aok && bok {
+		if c, err := compareValues(a, b); err == nil {
+			return c == 0, nil
+		}
+		if la == lb {
+			return true, nil
+		}
+		if knownDatatype(la) && knownDatatype(lb) {
+			// numbers and strings for instance are different values,
+			// unless a lexical form is invalid
+			_, na := numericValue(la)
+			_, nb := numericValue(lb)
+			if integerTypes[la.Datatype] && !na || integerTypes[lb.Datatype] && !nb {
+				return false, errType
+			}
+			return false, nil
+		}
+		return false, errType
+	}
+	ta, aok := a.(model.TripleTerm)
+	tb, bok := b.(model.TripleTerm)
+	if aok && bok {
+		for _, pair := range [][2]model.RDFTerm{{ta.Subject, tb.Subject}, {ta.Predicate, tb.Predicate}, {ta.Object, tb.Object}} {
+			if equal, err := equalTerms(pair[0], pair[1]); err != nil || !equal {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return a == b, nil
+}
+
+// the rank of terms in ORDER BY
+func orderKind(term model.RDFTerm) int {
+	switch term.(type) {
+	case nil:
+		return 0
+	case model.BlankNode:
+		return 1
+	case model.IRI:
+		return 2
+	case model.Literal:
+		return 3
+	}
+	return 4
+}
+
+// the order of ORDER BY, MIN and MAX: unbound, blank nodes, IRIs, literals
+// then triple terms; literals are compared by value when they can be
+func compareOrder(a, b model.RDFTerm) int {
+	if ka, kb := orderKind(a), orderKind(b); ka != kb {
+		if ka < kb {
+			return -1
+		}
+		return 1
+	}
+	switch ta := a.(type) {
+	case model.Literal:
+		// literals without comparable values by lexical form
+		if c, err := compareValues(a, b); err == nil && c != unordered {
+			return c
+		}
+	case model.BlankNode:
+		return strings.Compare(termKey(a), termKey(b))
+	case model.TripleTerm:
+		tb := b.(model.TripleTerm)
+		if c := compareOrder(ta.Subject, tb.Subject); c != 0 {
+			return c
+		}
+		if c := compareOrder(ta.Predicate, tb.Predicate); c != 0 {
+			return c
+		}
+		return compareOrder(ta.Object, tb.Object)
+	}
+	return model.CompareTerms(a, b)
+}
+
+// a string telling terms apart, blank nodes by their address
+func termKey(term model.RDFTerm) string {
+	switch t := term.(type) {
+	case nil:
+		return ""
+	case model.BlankNode:
+		return fmt.Sprintf("_:%p", t)
+	case model.TripleTerm:
+		return "<<( " + termKey(t.Subject) + " " + termKey(t.Predicate) + " " + termKey(t.Object) + " )>>"
+	}
+	return writer.FormatTerm(term, nil)
+}
@@ -0,0 +1,171 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package stats profiles a stream of statements.
+package stats
+
+import (
+	"sort"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Collector gathers the statistics one statement at a time
+type Collector struct {
+	statements int
+	subjects   map[model.RDFTerm]struct{}
+	predicates map[model.RDFTerm]int
+	objects    map[model.RDFTerm]struct{}
+	graphs     map[model.RDFTerm]struct{}
+	classes    map[model.RDFTerm]int
+	datatypes  map[model.IRI]int
+	languages  map[string]int
+	literals   int
+	// subjects and objects which are not literals
+	nodes      map[model.RDFTerm]struct{}
+	blankNodes int
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		subjects:   make(map[model.RDFTerm]struct{}),
+		predicates: make(map[model.RDFTerm]int),
+		objects:    make(map[model.RDFTerm]struct{}),
+		graphs:     make(map[model.RDFTerm]struct{}),
+		classes:    make(map[model.RDFTerm]int),
+		datatypes:  make(map[model.IRI]int),
+		languages:  make(map[string]int),
+		nodes:      make(map[model.RDFTerm]struct{}),
+	}
+}
+
+func (this *Collector) Add(statement *model.Statement) {
+	this.statements++
+	this.subjects[statement.Subject] = struct{}{}
+	this.predicates[statement.Predicate]++
+	this.objects[statement.Object] = struct{}{}
+	if statement.Context != nil {
+		this.graphs[statement.Context] = struct{}{}
+	}
+	if statement.Predicate == model.A {
+		this.classes[statement.Object]++
+	}
+	this.addNode(statement.Subject)
+	if literal, ok := statement.Object.(model.Literal); ok {
+		this.literals++
+		this.datatypes[literal.Datatype]++
+		if literal.Language != "" {
+			this.languages[literal.Language]++
+		}
+	} else {
+		this.addNode(statement.Object)
+	}
+}
+
+func (this *Collector) addNode(term model.RDFTerm) {
+	if _, ok := this.nodes[term]; ok {
+		return
+	}
+	this.nodes[term] = struct{}{}
+	if model.IsBlankNode(term) {
+		this.blankNodes++
+	}
+}
+
+// a term and how many times it was seen
+type Count struct {
+	Term  model.RDFTerm
+	Count int
+}
+
+type Stats struct {
+	Statements int
+	Subjects   int
+	Predicates int
+	Objects    int
+	// distinct named graphs
+	Graphs int
+	// literal objects
+	Literals int
+	// distinct subjects and non literal objects
+	Nodes      int
+	BlankNodes int
+
+	// sorted by decreasing count
+	PredicateCounts []Count
+	// rdf:type statements per class
+	ClassCounts []Count
+	// literal objects per datatype, the terms are model.IRI
+	DatatypeCounts []Count
+	// literal objects per language tag, the terms are strings
+	LanguageCounts []Count
+}
+
+// the share of the nodes which are blank
+func (this *Stats) BlankNodeRatio() float64 {
+	if this.Nodes == 0 {
+		return 0
+	}
+	return float64(this.BlankNodes) / float64(this.Nodes)
+}
+
+func (this *Collector) Stats() *Stats {
+	ret := &Stats{
+		Statements:      this.statements,
+		Subjects:        len(this.subjects),
+		Predicates:      len(this.predicates),
+		Objects:         len(this.objects),
+		Graphs:          len(this.graphs),
+		Literals:        this.literals,
+		Nodes:           len(this.nodes),
+		BlankNodes:      this.blankNodes,
+		PredicateCounts: sortedCounts(this.predicates),
+		ClassCounts:     sortedCounts(this.classes),
+		DatatypeCounts:  []Count{},
+		LanguageCounts:  []Count{},
+	}
+	for datatype, count := range this.datatypes {
+		ret.DatatypeCounts = append(ret.DatatypeCounts, Count{Term: datatype, Count: count})
+	}
+	sortCounts(ret.DatatypeCounts)
+	for language, count := range this.languages {
+		ret.LanguageCounts = append(ret.LanguageCounts, Count{Term: language, Count: count})
+	}
+	sortCounts(ret.LanguageCounts)
+	return ret
+}
+
+func sortedCounts(counts map[model.RDFTerm]int) []Count {
+	ret := make([]Count, 0, len(counts))
+	for term, count := range counts {
+		ret = append(ret, Count{Term: term, Count: count})
+	}
+	sortCounts(ret)
+	return ret
+}
+
+// by decreasing count, then by term for a stable output
+func sortCounts(counts []Count) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return sortKey(counts[i].Term) < sortKey(counts[j].Term)
+	})
+}
+
+func sortKey(term model.RDFTerm) string {
+	switch t := term.(type) {
+	case model.IRI:
+		return string(t)
+	case string:
+		return t
+	case *model.LabelledBlankNode:
+		return "_:" + t.Label
+	case model.Literal:
+		return t.Lexical
+	}
+	return ""
+}
@@ -0,0 +1,46 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package stats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func TestStats(t *testing.T) {
+	doc := `@prefix ex: <http://ex.org/> .
+ex:a a ex:Person ; ex:name "A"@en, "Á"@fr ; ex:knows ex:b, _:c .
+ex:b a ex:Person ; ex:age 42 .
+_:c a ex:Robot ; ex:name "C" .
+`
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := NewCollector()
+	for _, statement := range statements {
+		collector.Add(statement)
+	}
+	stats := collector.Stats()
+	if stats.Statements != 9 || stats.Subjects != 3 || stats.Predicates != 4 || stats.Objects != 8 || stats.Literals != 4 {
+		t.Errorf("unexpected counts %+v", stats)
+	}
+	if stats.Nodes != 5 || stats.BlankNodes != 1 || stats.BlankNodeRatio() != 0.2 {
+		t.Errorf("unexpected nodes %d %d", stats.Nodes, stats.BlankNodes)
+	}
+	expected := []Count{{model.IRI("http://ex.org/Person"), 2}, {model.IRI("http://ex.org/Robot"), 1}}
+	if len(stats.ClassCounts) != 2 || stats.ClassCounts[0] != expected[0] || stats.ClassCounts[1] != expected[1] {
+		t.Errorf("unexpected classes %v", stats.ClassCounts)
+	}
+	if stats.PredicateCounts[1] != (Count{model.A, 3}) {
+		t.Errorf("unexpected predicates %v", stats.PredicateCounts)
+	}
+	if stats.DatatypeCounts[0] != (Count{model.RDFLangString, 2}) || len(stats.LanguageCounts) != 2 {
+		t.Errorf("unexpected literals %v %v", stats.DatatypeCounts, stats.LanguageCounts)
+	}
+}
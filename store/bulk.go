@@ -0,0 +1,461 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+type LoadOptions struct {
+	Format format.Format
+	Base   model.IRI
+	// the number of chunks parsed at once, the number of CPUs when 0
+	Workers int
+	// the size of the chunks of N-Triples and N-Quads documents, 4 MiB
+	// when 0; the other formats are parsed as a whole
+	ChunkSize int
+	// the number of statements sorted in memory before being written to
+	// a temporary file, 1M when 0
+	RunSize int
+	// called while parsing, at most every ProgressInterval, and once the
+	// indexes are written
+	Progress         func(LoadProgress)
+	ProgressInterval time.Duration
+}
+
+type LoadProgress struct {
+	// read from the input
+	Bytes int64
+	// parsed, duplicates included
+	Statements int64
+	Elapsed    time.Duration
+	// the indexes are written
+	Done bool
+}
+
+// the statements parsed per second
+func (this LoadProgress) Rate() float64 {
+	if this.Elapsed <= 0 {
+		return 0
+	}
+	return float64(this.Statements) / this.Elapsed.Seconds()
+}
+
+// Load adds the statements of a document, bypassing the log: the chunks
+// of the document are parsed and encoded in parallel, sorted on disk in
+// the order of each index, then merged into a new segment. Nothing is
+// added when it fails. The number of statements parsed is returned.
+func (this *Disk) Load(reader io.Reader, options LoadOptions) (int64, error) {
+	if options.Workers <= 0 {
+		options.Workers = runtime.NumCPU()
+	}
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = 4 << 20
+	}
+	if options.RunSize <= 0 {
+		options.RunSize = 1 << 20
+	}
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = time.Second
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return 0, os.ErrClosed
+	}
+	if err := this.flush(); err != nil {
+		return 0, err
+	}
+	temporary, err := os.MkdirTemp(this.dir, "load")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(temporary)
+
+	loader := &loader{
+		store:      this,
+		options:    options,
+		input:      &countingReader{reader: reader},
+		dir:        temporary,
+		start:      time.Now(),
+		blankNodes: make(map[string]*model.LabelledBlankNode),
+		batches:    make(chan [][4]model.ID, options.Workers),
+		done:       make(chan struct{}),
+		runs:       make(map[permutation][]string),
+	}
+	if err := loader.run(); err != nil {
+		return loader.statements, err
+	}
+	return loader.statements, loader.index()
+}
+
+// the state of a Load
+type loader struct {
+	store   *Disk
+	options LoadOptions
+	input   *countingReader
+	// the temporary files
+	dir   string
+	start time.Time
+	// the nodes standing for the blank node labels of the document, which
+	// is parsed by several parsers
+	blankNodes map[string]*model.LabelledBlankNode
+	batches    chan [][4]model.ID
+	// closed on the first error
+	done     chan struct{}
+	failure  error
+	failOnce sync.Once
+	// the sorted runs of each permutation
+	runs       map[permutation][]string
+	statements int64
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (this *countingReader) Read(p []byte) (int, error) {
+	n, err := this.reader.Read(p)
+	atomic.AddInt64(&this.count, int64(n))
+	return n, err
+}
+
+// a part of a line based document
+type chunk struct {
+	data []byte
+	// the line of the document where data starts
+	line int
+}
+
+const loadBatch = 4096
+
+func (this *loader) fail(err error) {
+	this.failOnce.Do(func() {
+		this.failure = err
+		close(this.done)
+	})
+}
+
+// parses the input into sorted runs
+func (this *loader) run() error {
+	var workers sync.WaitGroup
+	if this.options.Format.IsLineBased() {
+		chunks := make(chan chunk, this.options.Workers)
+		go this.split(chunks)
+		for i := 0; i < this.options.Workers; i++ {
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				cache := make(map[model.RDFTerm]model.ID)
+				for chunk := range chunks {
+					this.parse(bytes.NewReader(chunk.data), chunk.line, cache)
+				}
+			}()
+		}
+	} else {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			this.parse(this.input, 1, make(map[model.RDFTerm]model.ID))
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(this.batches)
+	}()
+
+	run := make([][4]model.ID, 0, this.options.RunSize)
+	reported := time.Now()
+	for batch := range this.batches {
+		select {
+		case <-this.done:
+			// the workers stop
+			continue
+		default:
+		}
+		this.statements += int64(len(batch))
+		run = append(run, batch...)
+		if len(run) >= this.options.RunSize {
+			if err := this.writeRun(run); err != nil {
+				this.fail(err)
+			}
+			run = run[:0]
+		}
+		if this.options.Progress != nil && time.Since(reported) >= this.options.ProgressInterval {
+			reported = time.Now()
+			this.options.Progress(this.progress(false))
+		}
+	}
+	if this.failure != nil {
+		return this.failure
+	}
+	if len(run) > 0 {
+		return this.writeRun(run)
+	}
+	return nil
+}
+
+func (this *loader) progress(done bool) LoadProgress {
+	return LoadProgress{
+		Bytes:      atomic.LoadInt64(&this.input.count),
+		Statements: this.statements,
+		Elapsed:    time.Since(this.start),
+		Done:       done,
+	}
+}
+
+// cuts the input at line ends
+func (this *loader) split(chunks chan<- chunk) {
+	defer close(chunks)
+	reader := bufio.NewReaderSize(this.input, 64<<10)
+	line := 1
+	for {
+		data := make([]byte, this.options.ChunkSize)
+		n, err := io.ReadFull(reader, data)
+		data = data[:n]
+		if err == nil {
+			var rest []byte
+			rest, err = reader.ReadBytes('\n')
+			data = append(data, rest...)
+		}
+		if len(data) > 0 {
+			select {
+			case chunks <- chunk{data: data, line: line}:
+			case <-this.done:
+				return
+			}
+			line += bytes.Count(data, []byte{'\n'})
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return
+		}
+		if err != nil {
+			this.fail(err)
+			return
+		}
+	}
+}
+
+// parses a document or a chunk starting at line and sends its statements
+// by batches; cache keeps the IDs of the terms met before
+func (this *loader) parse(reader io.Reader, line int, cache map[model.RDFTerm]model.ID) {
+	source := parser.Parse(reader, parser.Options{Format: this.options.Format, Base: this.options.Base})
+	// the parser stops once its statements are read
+	defer func() {
+		for range source.Statements() {
+		}
+	}()
+	batch := make([]*model.Statement, 0, loadBatch)
+	for statement := range source.Statements() {
+		batch = append(batch, statement)
+		if len(batch) == loadBatch {
+			if !this.send(batch, cache) {
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := source.Err(); err != nil {
+		// the errors of the reader are not syntax errors
+		if syntaxError, ok := err.(*parser.SyntaxError); ok {
+			copy := *syntaxError
+			copy.Line += line - 1
+			this.fail(&copy)
+		} else {
+			this.fail(err)
+		}
+		return
+	}
+	this.send(batch, cache)
+}
+
+// encodes statements, false when the load failed
+func (this *loader) send(statements []*model.Statement, cache map[model.RDFTerm]model.ID) bool {
+	ids, err := this.encode(statements, cache)
+	if err != nil {
+		this.fail(err)
+		return false
+	}
+	select {
+	case this.batches <- ids:
+		return true
+	case <-this.done:
+		return false
+	}
+}
+
+func (this *loader) encode(statements []*model.Statement, cache map[model.RDFTerm]model.ID) ([][4]model.ID, error) {
+	if len(cache) >= termCacheSize {
+		for term := range cache {
+			delete(cache, term)
+		}
+	}
+	ret := make([][4]model.ID, len(statements))
+	terms := this.store.terms
+	terms.lock.Lock()
+	defer terms.lock.Unlock()
+	for i, statement := range statements {
+		if err := checkStatement(statement); err != nil {
+			return nil, err
+		}
+		for j, term := range []model.RDFTerm{statement.Subject, statement.Predicate, statement.Object, statement.Context} {
+			if id, ok := cache[term]; ok {
+				ret[i][j] = id
+				continue
+			}
+			id, _, err := terms.id(this.documentNodes(term), true)
+			if err != nil {
+				return nil, err
+			}
+			if !model.IsBlankNode(term) {
+				cache[term] = id
+			}
+			ret[i][j] = id
+		}
+	}
+	return ret, nil
+}
+
+// term with the blank nodes of the chunks replaced by those of the
+// document, under the lock of the terms
+func (this *loader) documentNodes(term model.RDFTerm) model.RDFTerm {
+	switch t := term.(type) {
+	case *model.LabelledBlankNode:
+		node, ok := this.blankNodes[t.Label]
+		if !ok {
+			node = t
+			this.blankNodes[t.Label] = node
+		}
+		return node
+	case model.TripleTerm:
+		return model.TripleTerm{Subject: this.documentNodes(t.Subject), Predicate: t.Predicate, Object: this.documentNodes(t.Object)}
+	}
+	return term
+}
+
+// sorts run in the order of every permutation and writes it to temporary
+// files, without duplicates
+func (this *loader) writeRun(run [][4]model.ID) error {
+	number := len(this.runs[spog])
+	errors := make([]error, len(diskPermutations))
+	names := make([]string, len(diskPermutations))
+	var group sync.WaitGroup
+	for i, order := range diskPermutations {
+		group.Add(1)
+		go func(i int, order permutation) {
+			defer group.Done()
+			entries := make([]entry, len(run))
+			for j, ids := range run {
+				entries[j] = entry{key: order.apply(ids), present: true}
+			}
+			sort.Slice(entries, func(a, b int) bool {
+				return compareKeys(entries[a].key, entries[b].key) < 0
+			})
+			names[i] = filepath.Join(this.dir, fmt.Sprintf("%d.%s", number, order.name()))
+			next := 0
+			errors[i] = writeSegmentFile(names[i], func() (entry, bool, error) {
+				for next < len(entries) && next > 0 && entries[next].key == entries[next-1].key {
+					next++
+				}
+				if next == len(entries) {
+					return entry{}, false, nil
+				}
+				next++
+				return entries[next-1], true, nil
+			})
+		}(i, order)
+	}
+	group.Wait()
+	for i, order := range diskPermutations {
+		if errors[i] != nil {
+			return errors[i]
+		}
+		this.runs[order] = append(this.runs[order], names[i])
+	}
+	return nil
+}
+
+// merges the runs of every permutation into a new segment
+func (this *loader) index() error {
+	store := this.store
+	if err := store.terms.sync(); err != nil {
+		return err
+	}
+	if len(this.runs[spog]) > 0 {
+		id := store.nextSegment
+		errors := make([]error, len(diskPermutations))
+		var group sync.WaitGroup
+		for i, order := range diskPermutations {
+			group.Add(1)
+			go func(i int, order permutation) {
+				defer group.Done()
+				errors[i] = this.merge(this.runs[order], segmentFileName(store.dir, id, order))
+			}(i, order)
+		}
+		group.Wait()
+		for _, err := range errors {
+			if err != nil {
+				return err
+			}
+		}
+		segment, err := openSegment(store.dir, id)
+		if err != nil {
+			return err
+		}
+		store.nextSegment++
+		store.segments = append(store.segments, segment)
+		// the new statements may be in the older segments, merging tells
+		// how many statements there are
+		if len(store.segments) > 1 {
+			err = store.compact()
+		} else {
+			store.size = int(segment.size)
+			err = store.writeManifest()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if this.options.Progress != nil {
+		this.options.Progress(this.progress(true))
+	}
+	return nil
+}
+
+func (this *loader) merge(runs []string, name string) error {
+	cursors := []entryCursor{}
+	for _, run := range runs {
+		file, err := os.Open(run)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		cursors = append(cursors, &segmentCursor{file: file, end: info.Size() / entrySize})
+	}
+	merged, err := newMerger(cursors)
+	if err != nil {
+		return err
+	}
+	return writeSegmentFile(name, merged.next)
+}
@@ -0,0 +1,92 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func TestLoad(t *testing.T) {
+	var document strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&document, "<http://s%d> <http://p%d> \"%d\" .\n", i%100, i%7, i)
+		// the blank nodes are shared by chunks
+		fmt.Fprintf(&document, "_:b%d <http://next> _:b%d .\n", i%50, (i+1)%50)
+	}
+	expected, err := parser.ParseAll(strings.NewReader(document.String()), parser.Options{Format: format.NTriples})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := openDisk(t, t.TempDir(), DiskOptions{})
+	defer store.Close()
+	// a statement of the document is already there
+	if err := store.Add(&model.Statement{Subject: model.IRI("http://s0"), Predicate: model.IRI("http://p0"), Object: model.NewStringLiteral("0")}); err != nil {
+		t.Fatal(err)
+	}
+	progress := []LoadProgress{}
+	count, err := store.Load(strings.NewReader(document.String()), LoadOptions{
+		Format:    format.NTriples,
+		Workers:   4,
+		ChunkSize: 256,
+		RunSize:   300,
+		Progress:  func(p LoadProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2000 || store.Len() != 1050 {
+		t.Errorf("%d statements loaded, %d stored", count, store.Len())
+	}
+	if len(progress) == 0 || !progress[len(progress)-1].Done || progress[len(progress)-1].Bytes != int64(document.Len()) {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	loaded, err := All(store.Match(nil, nil, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, _ := model.Isomorphic(loaded, expected); !same {
+		t.Errorf("the loaded statements differ from the document")
+	}
+	if ok, _ := store.Contains(expected[0]); !ok {
+		t.Errorf("%v is missing", expected[0])
+	}
+}
+
+func TestLoadSyntaxError(t *testing.T) {
+	document := strings.Repeat("<http://s> <http://p> <http://o> .\n", 100) + "<http://s> <http://p> .\n"
+	store := openDisk(t, t.TempDir(), DiskOptions{})
+	defer store.Close()
+	_, err := store.Load(strings.NewReader(document), LoadOptions{Format: format.NTriples, ChunkSize: 100})
+	syntaxError, ok := err.(*parser.SyntaxError)
+	if !ok || syntaxError.Line != 101 {
+		t.Errorf("unexpected error %v", err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("%d statements were added", store.Len())
+	}
+}
+
+func TestLoadReadError(t *testing.T) {
+	broken := errors.New("broken")
+	reader := io.MultiReader(strings.NewReader("<http://s> <http://p> <http://o> .\n"), iotest.ErrReader(broken))
+	store := openDisk(t, t.TempDir(), DiskOptions{})
+	defer store.Close()
+	if _, err := store.Load(reader, LoadOptions{Format: format.Turtle}); err != broken {
+		t.Errorf("unexpected error %v", err)
+	}
+	if store.Len() != 0 {
+		t.Errorf("%d statements were added", store.Len())
+	}
+}
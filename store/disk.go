@@ -0,0 +1,679 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+type DiskOptions struct {
+	// sync the files to the disk after every change, otherwise the
+	// changes survive a crash of the process but not of the system
+	Sync bool
+	// the number of changes kept in memory before they are written to a
+	// new segment, 65536 when 0
+	MemorySize int
+	// the number of segments above which they are merged into one, 8 when
+	// 0
+	MaxSegments int
+}
+
+// Disk is a Store kept in a directory:
+//
+//   - terms: the dictionary, see termFile
+//   - wal: the log of the changes which are not yet in a segment
+//   - 00000001.spog ...: the segments, see segment
+//   - manifest: the segments in use and the number of statements
+//
+// The changes go to the log and to memory, and are written to a new
+// segment once there are enough of them. Matching merges the changes in
+// memory with the segments. A directory must only be opened once at a
+// time. Disk is safe for concurrent use.
+type Disk struct {
+	lock    sync.RWMutex
+	dir     string
+	options DiskOptions
+	terms   *termFile
+	wal     *os.File
+	log     *bufio.Writer
+
+	// the changes since the last segment, keys in the subject, predicate,
+	// object and graph order
+	memory map[[4]model.ID]bool
+	// the changes in memory sorted for each permutation, built on demand
+	// by the readers under sortLock
+	sortLock sync.Mutex
+	sorted   map[permutation][]entry
+	// from the oldest to the newest
+	segments    []*segment
+	nextSegment int
+	size        int
+	closed      bool
+}
+
+type manifest struct {
+	Segments    []int `json:"segments"`
+	NextSegment int   `json:"nextSegment"`
+	Statements  int   `json:"statements"`
+}
+
+// the size of a log record: the operation, four IDs and a CRC
+const logRecordSize = 1 + 4*8 + 4
+
+const (
+	addOperation    byte = 'A'
+	removeOperation byte = 'R'
+)
+
+// Open opens the store of a directory, which is created if needed, and
+// replays the changes of the log
+func Open(dir string, options DiskOptions) (*Disk, error) {
+	if options.MemorySize <= 0 {
+		options.MemorySize = 1 << 16
+	}
+	if options.MaxSegments <= 0 {
+		options.MaxSegments = 8
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	this := &Disk{dir: dir, options: options, memory: make(map[[4]model.ID]bool), nextSegment: 1}
+	if err := this.readManifest(); err != nil {
+		this.closeFiles()
+		return nil, err
+	}
+	terms, err := openTermFile(filepath.Join(dir, "terms"))
+	if err != nil {
+		this.closeFiles()
+		return nil, err
+	}
+	this.terms = terms
+	if err := this.replay(); err != nil {
+		this.closeFiles()
+		return nil, err
+	}
+	return this, nil
+}
+
+func (this *Disk) readManifest() error {
+	content, err := os.ReadFile(filepath.Join(this.dir, "manifest"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current := manifest{}
+	if err := json.Unmarshal(content, &current); err != nil {
+		return err
+	}
+	for _, id := range current.Segments {
+		segment, err := openSegment(this.dir, id)
+		if err != nil {
+			return err
+		}
+		this.segments = append(this.segments, segment)
+	}
+	this.nextSegment = current.NextSegment
+	this.size = current.Statements
+	return nil
+}
+
+// the manifest is replaced at once
+func (this *Disk) writeManifest() error {
+	current := manifest{Segments: []int{}, NextSegment: this.nextSegment, Statements: this.size}
+	for _, segment := range this.segments {
+		current.Segments = append(current.Segments, segment.id)
+	}
+	content, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(this.dir, "manifest")
+	file, err := os.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return err
+	}
+	return syncDir(this.dir)
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// not every system can sync a directory
+	file.Sync()
+	return nil
+}
+
+// applies the records of the log up to the first incomplete one, which is
+// dropped
+func (this *Disk) replay() error {
+	wal, err := os.OpenFile(filepath.Join(this.dir, "wal"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	this.wal = wal
+	reader := bufio.NewReader(wal)
+	record := make([]byte, logRecordSize)
+	valid := int64(0)
+	for {
+		if _, err := io.ReadFull(reader, record); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(record[:logRecordSize-4]) != binary.LittleEndian.Uint32(record[logRecordSize-4:]) {
+			break
+		}
+		ids := [4]model.ID{}
+		known := true
+		for i := range ids {
+			ids[i] = model.ID(binary.BigEndian.Uint64(record[1+i*8:]))
+			known = known && int(ids[i]) <= len(this.terms.offsets)
+		}
+		if !known {
+			break
+		}
+		present, err := this.contains(ids)
+		if err != nil {
+			return err
+		}
+		switch {
+		case record[0] == addOperation && !present:
+			this.setMemory(ids, true)
+			this.size++
+		case record[0] == removeOperation && present:
+			this.setMemory(ids, false)
+			this.size--
+		}
+		valid += logRecordSize
+	}
+	if err := wal.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := wal.Seek(valid, io.SeekStart); err != nil {
+		return err
+	}
+	this.log = bufio.NewWriter(wal)
+	return nil
+}
+
+func (this *Disk) setMemory(ids [4]model.ID, present bool) {
+	this.memory[ids] = present
+	this.sorted = nil
+}
+
+// writes a change to the log, the terms it uses first
+func (this *Disk) logChange(operation byte, ids [4]model.ID) error {
+	record := make([]byte, logRecordSize)
+	record[0] = operation
+	for i, id := range ids {
+		binary.BigEndian.PutUint64(record[1+i*8:], uint64(id))
+	}
+	binary.LittleEndian.PutUint32(record[logRecordSize-4:], crc32.ChecksumIEEE(record[:logRecordSize-4]))
+	if this.options.Sync {
+		if err := this.terms.sync(); err != nil {
+			return err
+		}
+	} else if err := this.terms.flush(); err != nil {
+		return err
+	}
+	if _, err := this.log.Write(record); err != nil {
+		return err
+	}
+	if err := this.log.Flush(); err != nil {
+		return err
+	}
+	if this.options.Sync {
+		return this.wal.Sync()
+	}
+	return nil
+}
+
+func (this *Disk) encode(statement *model.Statement, create bool) ([4]model.ID, bool, error) {
+	ret := [4]model.ID{}
+	this.terms.lock.Lock()
+	defer this.terms.lock.Unlock()
+	for i, term := range []model.RDFTerm{statement.Subject, statement.Predicate, statement.Object, statement.Context} {
+		id, ok, err := this.terms.id(term, create)
+		if !ok || err != nil {
+			return ret, false, err
+		}
+		ret[i] = id
+	}
+	return ret, true, nil
+}
+
+func (this *Disk) decode(id model.ID) (model.RDFTerm, error) {
+	this.terms.lock.Lock()
+	defer this.terms.lock.Unlock()
+	return this.terms.decode(id)
+}
+
+func (this *Disk) Add(statement *model.Statement) error {
+	if err := checkStatement(statement); err != nil {
+		return err
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return os.ErrClosed
+	}
+	ids, _, err := this.encode(statement, true)
+	if err != nil {
+		return err
+	}
+	present, err := this.contains(ids)
+	if present || err != nil {
+		return err
+	}
+	if err := this.logChange(addOperation, ids); err != nil {
+		return err
+	}
+	this.setMemory(ids, true)
+	this.size++
+	return this.flushIfFull()
+}
+
+func (this *Disk) Remove(statement *model.Statement) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return os.ErrClosed
+	}
+	ids, known, err := this.encode(statement, false)
+	if !known || err != nil {
+		return err
+	}
+	present, err := this.contains(ids)
+	if !present || err != nil {
+		return err
+	}
+	if err := this.logChange(removeOperation, ids); err != nil {
+		return err
+	}
+	this.setMemory(ids, false)
+	this.size--
+	return this.flushIfFull()
+}
+
+func (this *Disk) Contains(statement *model.Statement) (bool, error) {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if this.closed {
+		return false, os.ErrClosed
+	}
+	ids, known, err := this.encode(statement, false)
+	if !known || err != nil {
+		return false, err
+	}
+	return this.contains(ids)
+}
+
+// the newest change of ids decides
+func (this *Disk) contains(ids [4]model.ID) (bool, error) {
+	if present, ok := this.memory[ids]; ok {
+		return present, nil
+	}
+	for i := len(this.segments) - 1; i >= 0; i-- {
+		cursor, err := this.segments[i].cursor(spog, ids[:])
+		if err != nil {
+			return false, err
+		}
+		e, ok, err := cursor.next()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return e.present, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *Disk) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.size
+}
+
+func (this *Disk) flushIfFull() error {
+	if len(this.memory) < this.options.MemorySize {
+		return nil
+	}
+	return this.flush()
+}
+
+// Flush writes the changes kept in memory to a new segment and empties
+// the log
+func (this *Disk) Flush() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return os.ErrClosed
+	}
+	return this.flush()
+}
+
+func (this *Disk) flush() error {
+	if len(this.memory) == 0 {
+		return nil
+	}
+	if err := this.terms.sync(); err != nil {
+		return err
+	}
+	id := this.nextSegment
+	for _, permutation := range diskPermutations {
+		entries := this.sortedMemory(permutation)
+		if len(this.segments) == 0 {
+			// nothing older to hide
+			entries = presentOnly(entries)
+		}
+		cursor := &sliceCursor{entries: entries}
+		if err := writeSegmentFile(segmentFileName(this.dir, id, permutation), cursor.next); err != nil {
+			return err
+		}
+	}
+	segment, err := openSegment(this.dir, id)
+	if err != nil {
+		return err
+	}
+	this.segments = append(this.segments, segment)
+	this.nextSegment++
+	if err := this.writeManifest(); err != nil {
+		return err
+	}
+	// the log is replayed over the segments until it is emptied, which is
+	// harmless
+	if err := this.log.Flush(); err != nil {
+		return err
+	}
+	if err := this.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := this.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	this.log.Reset(this.wal)
+	this.memory = make(map[[4]model.ID]bool)
+	this.sorted = nil
+	if len(this.segments) > this.options.MaxSegments {
+		return this.compact()
+	}
+	return nil
+}
+
+func presentOnly(entries []entry) []entry {
+	ret := make([]entry, 0, len(entries))
+	for _, e := range entries {
+		if e.present {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// the changes in memory in the order of permutation
+func (this *Disk) sortedMemory(order permutation) []entry {
+	this.sortLock.Lock()
+	defer this.sortLock.Unlock()
+	if entries, ok := this.sorted[order]; ok {
+		return entries
+	}
+	entries := make([]entry, 0, len(this.memory))
+	for ids, present := range this.memory {
+		entries = append(entries, entry{key: order.apply(ids), present: present})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compareKeys(entries[i].key, entries[j].key) < 0
+	})
+	if this.sorted == nil {
+		this.sorted = make(map[permutation][]entry)
+	}
+	this.sorted[order] = entries
+	return entries
+}
+
+// merges every segment into one, without the removed statements
+func (this *Disk) compact() error {
+	id := this.nextSegment
+	for _, permutation := range diskPermutations {
+		cursors := []entryCursor{}
+		for i := len(this.segments) - 1; i >= 0; i-- {
+			cursor, err := this.segments[i].cursor(permutation, nil)
+			if err != nil {
+				return err
+			}
+			cursors = append(cursors, cursor)
+		}
+		merged, err := newMerger(cursors)
+		if err != nil {
+			return err
+		}
+		next := func() (entry, bool, error) {
+			for {
+				e, ok, err := merged.next()
+				if !ok || err != nil || e.present {
+					return e, ok, err
+				}
+			}
+		}
+		if err := writeSegmentFile(segmentFileName(this.dir, id, permutation), next); err != nil {
+			return err
+		}
+	}
+	merged, err := openSegment(this.dir, id)
+	if err != nil {
+		return err
+	}
+	old := this.segments
+	this.segments = []*segment{merged}
+	this.nextSegment++
+	this.size = int(merged.size)
+	if err := this.writeManifest(); err != nil {
+		return err
+	}
+	for _, segment := range old {
+		atomic.StoreInt32(&segment.obsolete, 1)
+		segment.release()
+	}
+	return nil
+}
+
+// Close writes the changes to a segment and closes the files, the
+// iterators must be closed before
+func (this *Disk) Close() error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.closed {
+		return nil
+	}
+	err := this.flush()
+	this.closed = true
+	if closeErr := this.closeFiles(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (this *Disk) closeFiles() error {
+	var err error
+	if this.terms != nil {
+		err = this.terms.close()
+	}
+	if this.wal != nil {
+		if this.log != nil {
+			if flushErr := this.log.Flush(); err == nil {
+				err = flushErr
+			}
+		}
+		if closeErr := this.wal.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	for _, segment := range this.segments {
+		segment.release()
+	}
+	this.segments = nil
+	return err
+}
+
+func (this *Disk) Match(subject, predicate, object, graph model.RDFTerm) Iterator {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	if this.closed {
+		return &diskIterator{err: os.ErrClosed}
+	}
+	ids := [4]model.ID{}
+	bound := [4]bool{}
+	for i, term := range []model.RDFTerm{subject, predicate, object, graph} {
+		if term == nil {
+			continue
+		}
+		if term == DefaultGraph {
+			term = nil
+		}
+		this.terms.lock.Lock()
+		id, ok, err := this.terms.id(term, false)
+		this.terms.lock.Unlock()
+		if err != nil {
+			return &diskIterator{err: err}
+		}
+		if !ok {
+			return &diskIterator{}
+		}
+		ids[i] = id
+		bound[i] = true
+	}
+
+	// the permutation with the longest prefix of bound terms, the others
+	// are checked on each entry
+	permutation, length := diskPermutations[0], -1
+	for _, candidate := range diskPermutations {
+		n := 0
+		for n < 4 && bound[candidate[n]] {
+			n++
+		}
+		if n > length {
+			permutation, length = candidate, n
+		}
+	}
+	prefix := permutation.apply(ids)
+	ret := &diskIterator{store: this, ids: ids, bound: bound, permutation: permutation}
+	cursors := []entryCursor{newSliceCursor(this.sortedMemory(permutation), prefix[:length])}
+	for i := len(this.segments) - 1; i >= 0; i-- {
+		segment := this.segments[i]
+		segment.acquire()
+		ret.segments = append(ret.segments, segment)
+		cursor, err := segment.cursor(permutation, prefix[:length])
+		if err != nil {
+			ret.fail(err)
+			return ret
+		}
+		cursors = append(cursors, cursor)
+	}
+	merged, err := newMerger(cursors)
+	if err != nil {
+		ret.fail(err)
+		return ret
+	}
+	ret.merger = merged
+	return ret
+}
+
+// goes over the changes in memory and the segments at the time of Match
+type diskIterator struct {
+	store       *Disk
+	ids         [4]model.ID
+	bound       [4]bool
+	permutation permutation
+	merger      *merger
+	segments    []*segment
+	current     *model.Statement
+	err         error
+}
+
+func (this *diskIterator) fail(err error) {
+	this.err = err
+	this.Close()
+}
+
+func (this *diskIterator) Next() bool {
+	for this.merger != nil {
+		e, ok, err := this.merger.next()
+		if err != nil {
+			this.fail(err)
+			return false
+		}
+		if !ok {
+			this.Close()
+			return false
+		}
+		if !e.present {
+			continue
+		}
+		ids := this.permutation.unapply(e.key)
+		matches := true
+		for i, bound := range this.bound {
+			matches = matches && (!bound || ids[i] == this.ids[i])
+		}
+		if !matches {
+			continue
+		}
+		terms := [4]model.RDFTerm{}
+		for i, id := range ids {
+			if terms[i], err = this.store.decode(id); err != nil {
+				this.fail(err)
+				return false
+			}
+		}
+		this.current = &model.Statement{Subject: terms[0], Predicate: terms[1], Object: terms[2], Context: terms[3]}
+		return true
+	}
+	return false
+}
+
+func (this *diskIterator) Statement() *model.Statement {
+	return this.current
+}
+
+func (this *diskIterator) Err() error {
+	return this.err
+}
+
+func (this *diskIterator) Close() error {
+	for _, segment := range this.segments {
+		segment.release()
+	}
+	this.segments = nil
+	this.merger = nil
+	this.current = nil
+	return nil
+}
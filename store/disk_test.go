@@ -0,0 +1,104 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openDisk(t *testing.T, dir string, options DiskOptions) *Disk {
+	t.Helper()
+	store, err := Open(dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	// small enough to write segments and compact them
+	options := DiskOptions{MemorySize: 2, MaxSegments: 2}
+	store := openDisk(t, dir, options)
+	statements := load(t, store)
+	if store.Len() != 6 {
+		t.Errorf("%d statements instead of 6", store.Len())
+	}
+	testMatch(t, store)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openDisk(t, dir, options)
+	if store.Len() != 6 {
+		t.Errorf("%d statements after reopening", store.Len())
+	}
+	testMatch(t, store)
+	iterator := store.Match(nil, nil, nil, nil)
+	removed := 0
+	// removing while iterating
+	for iterator.Next() {
+		if err := store.Remove(iterator.Statement()); err != nil {
+			t.Fatal(err)
+		}
+		removed++
+	}
+	if err := iterator.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if removed != 6 || store.Len() != 0 {
+		t.Errorf("%d statements removed, %d left", removed, store.Len())
+	}
+	if err := store.Add(statements[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = openDisk(t, dir, options)
+	defer store.Close()
+	left, err := All(store.Match(nil, nil, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || *left[0] != *statements[0] {
+		t.Errorf("unexpected statements %v", left)
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.spog"))
+	if len(segments) > options.MaxSegments {
+		t.Errorf("segments were not compacted: %v", segments)
+	}
+}
+
+func TestDiskRecovery(t *testing.T) {
+	dir := t.TempDir()
+	store := openDisk(t, dir, DiskOptions{})
+	statements := load(t, store)
+	if err := store.Remove(statements[1]); err != nil {
+		t.Fatal(err)
+	}
+	// a crash: the changes are only in the log, whose last record is
+	// incomplete
+	store.closeFiles()
+	wal, err := os.OpenFile(filepath.Join(dir, "wal"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.Write([]byte{addOperation, 0, 0})
+	wal.Close()
+
+	store = openDisk(t, dir, DiskOptions{})
+	defer store.Close()
+	if store.Len() != 5 {
+		t.Errorf("%d statements instead of 5", store.Len())
+	}
+	if err := store.Add(statements[1]); err != nil {
+		t.Fatal(err)
+	}
+	testMatch(t, store)
+}
@@ -0,0 +1,311 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"sync"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// an index level, the leaves are empty
+type trie map[model.ID]trie
+
+// the order of the terms of the statements in the tries of an index
+type permutation [4]int
+
+const (
+	subjectPosition = iota
+	predicatePosition
+	objectPosition
+	graphPosition
+)
+
+// the graph always comes first
+var (
+	gspo = permutation{graphPosition, subjectPosition, predicatePosition, objectPosition}
+	gpos = permutation{graphPosition, predicatePosition, objectPosition, subjectPosition}
+	gosp = permutation{graphPosition, objectPosition, subjectPosition, predicatePosition}
+)
+
+// Memory is an in-memory Store indexed by subject, predicate and object
+// and by predicate, object and subject and by object, subject and
+// predicate, each within graphs. The indexes hold the IDs of the terms in a
+// dictionary. It is safe for concurrent use.
+type Memory struct {
+	lock       sync.RWMutex
+	dictionary *model.Dictionary
+	indexes    map[permutation]trie
+	size       int
+}
+
+func NewMemory() *Memory {
+	return NewMemoryWithDictionary(model.NewDictionary())
+}
+
+// the dictionary can be shared with other stores or with parsers
+func NewMemoryWithDictionary(dictionary *model.Dictionary) *Memory {
+	return &Memory{dictionary: dictionary, indexes: map[permutation]trie{gspo: {}, gpos: {}, gosp: {}}}
+}
+
+func (this *Memory) Dictionary() *model.Dictionary {
+	return this.dictionary
+}
+
+// the IDs of the terms of statement, the default graph is 0
+func (this *Memory) encode(statement *model.Statement) [4]model.ID {
+	return [4]model.ID{
+		this.dictionary.Encode(statement.Subject),
+		this.dictionary.Encode(statement.Predicate),
+		this.dictionary.Encode(statement.Object),
+		this.dictionary.Encode(statement.Context),
+	}
+}
+
+// the IDs of the terms of statement if they are all known
+func (this *Memory) lookup(statement *model.Statement) ([4]model.ID, bool) {
+	ret := [4]model.ID{}
+	for i, term := range []model.RDFTerm{statement.Subject, statement.Predicate, statement.Object, statement.Context} {
+		id, ok := this.dictionary.Lookup(term)
+		if !ok {
+			return ret, false
+		}
+		ret[i] = id
+	}
+	return ret, true
+}
+
+func (this permutation) apply(ids [4]model.ID) [4]model.ID {
+	return [4]model.ID{ids[this[0]], ids[this[1]], ids[this[2]], ids[this[3]]}
+}
+
+// the IDs of the keys of an index in the subject, predicate, object and
+// graph order
+func (this permutation) unapply(keys [4]model.ID) [4]model.ID {
+	ids := [4]model.ID{}
+	for i, position := range this {
+		ids[position] = keys[i]
+	}
+	return ids
+}
+
+func decodeStatement(ids [4]model.ID, decode func(id model.ID) model.RDFTerm) *model.Statement {
+	return &model.Statement{
+		Subject:   decode(ids[subjectPosition]),
+		Predicate: decode(ids[predicatePosition]),
+		Object:    decode(ids[objectPosition]),
+		Context:   decode(ids[graphPosition]),
+	}
+}
+
+func (this *Memory) Add(statement *model.Statement) error {
+	if err := checkStatement(statement); err != nil {
+		return err
+	}
+	terms := this.encode(statement)
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.contains(terms) {
+		return nil
+	}
+	for permutation, index := range this.indexes {
+		node := index
+		for _, key := range permutation.apply(terms) {
+			child, ok := node[key]
+			if !ok {
+				child = trie{}
+				node[key] = child
+			}
+			node = child
+		}
+	}
+	this.size++
+	return nil
+}
+
+func (this *Memory) Remove(statement *model.Statement) error {
+	terms, ok := this.lookup(statement)
+	if !ok {
+		return nil
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if !this.contains(terms) {
+		return nil
+	}
+	for permutation, index := range this.indexes {
+		remove(index, permutation.apply(terms))
+	}
+	this.size--
+	return nil
+}
+
+// removes the path of keys and the levels it leaves empty
+func remove(node trie, keys [4]model.ID) {
+	path := []trie{node}
+	for _, key := range keys[:3] {
+		node = node[key]
+		path = append(path, node)
+	}
+	for i := 3; i >= 0; i-- {
+		delete(path[i], keys[i])
+		if len(path[i]) > 0 {
+			return
+		}
+	}
+}
+
+func (this *Memory) Contains(statement *model.Statement) (bool, error) {
+	terms, ok := this.lookup(statement)
+	if !ok {
+		return false, nil
+	}
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.contains(terms), nil
+}
+
+func (this *Memory) contains(terms [4]model.ID) bool {
+	node := this.indexes[gspo]
+	for _, key := range gspo.apply(terms) {
+		child, ok := node[key]
+		if !ok {
+			return false
+		}
+		node = child
+	}
+	return true
+}
+
+func (this *Memory) Len() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.size
+}
+
+func (this *Memory) Match(subject, predicate, object, graph model.RDFTerm) Iterator {
+	pattern := [4]model.RDFTerm{subject, predicate, object, graph}
+	ids := [4]model.ID{}
+	bound := [4]bool{}
+	for i, term := range pattern {
+		if term == nil {
+			continue
+		}
+		if term == DefaultGraph {
+			term = nil
+		}
+		id, ok := this.dictionary.Lookup(term)
+		if !ok {
+			// nothing can match an unknown term
+			return &memoryIterator{}
+		}
+		ids[i] = id
+		bound[i] = true
+	}
+	permutation := choosePermutation(bound)
+	ret := &memoryIterator{
+		lock:        &this.lock,
+		dictionary:  this.dictionary,
+		permutation: permutation,
+		pattern:     permutation.apply(ids),
+	}
+	for i, position := range permutation {
+		ret.bound[i] = bound[position]
+	}
+	ret.push(this.indexes[permutation])
+	return ret
+}
+
+// the index whose first levels are the bound terms
+func choosePermutation(bound [4]bool) permutation {
+	subject, predicate, object := bound[subjectPosition], bound[predicatePosition], bound[objectPosition]
+	switch {
+	case subject && !predicate && object:
+		return gosp
+	case subject:
+		return gspo
+	case predicate:
+		return gpos
+	case object:
+		return gosp
+	}
+	return gspo
+}
+
+// the keys of a level still to visit
+type cursor struct {
+	node trie
+	keys []model.ID
+}
+
+// walks down an index, the keys of each level are copied when it is
+// reached so that the index can change in between
+type memoryIterator struct {
+	lock        *sync.RWMutex
+	dictionary  *model.Dictionary
+	permutation permutation
+	pattern     [4]model.ID
+	bound       [4]bool
+	stack       []cursor
+	keys        [4]model.ID
+	current     *model.Statement
+}
+
+func (this *memoryIterator) push(node trie) {
+	depth := len(this.stack)
+	keys := []model.ID{}
+	this.lock.RLock()
+	if this.bound[depth] {
+		if _, ok := node[this.pattern[depth]]; ok {
+			keys = append(keys, this.pattern[depth])
+		}
+	} else {
+		for key := range node {
+			keys = append(keys, key)
+		}
+	}
+	this.lock.RUnlock()
+	this.stack = append(this.stack, cursor{node: node, keys: keys})
+}
+
+func (this *memoryIterator) Next() bool {
+	for len(this.stack) > 0 {
+		depth := len(this.stack) - 1
+		top := &this.stack[depth]
+		if len(top.keys) == 0 {
+			this.stack = this.stack[:depth]
+			continue
+		}
+		key := top.keys[0]
+		top.keys = top.keys[1:]
+		this.lock.RLock()
+		child, ok := top.node[key]
+		this.lock.RUnlock()
+		if !ok {
+			continue
+		}
+		this.keys[depth] = key
+		if depth == 3 {
+			this.current = decodeStatement(this.permutation.unapply(this.keys), this.dictionary.Decode)
+			return true
+		}
+		this.push(child)
+	}
+	this.current = nil
+	return false
+}
+
+func (this *memoryIterator) Statement() *model.Statement {
+	return this.current
+}
+
+func (this *memoryIterator) Err() error {
+	return nil
+}
+
+func (this *memoryIterator) Close() error {
+	this.stack = nil
+	return nil
+}
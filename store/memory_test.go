@@ -0,0 +1,109 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+const dataset = `<http://s> <http://p> <http://o> .
+<http://s> <http://p> "x" .
+<http://s> <http://q> <http://o> .
+<http://t> <http://p> <http://o> <http://g> .
+<http://s> <http://p> <http://o> <http://g> .
+_:b <http://q> <http://s> <http://h> .
+`
+
+func load(t *testing.T, store Store) []*model.Statement {
+	t.Helper()
+	statements, err := parser.ParseAll(strings.NewReader(dataset), parser.Options{Format: format.NQuads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range statements {
+		if err := store.Add(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// duplicates are ignored
+	if err := store.Add(statements[0]); err != nil {
+		t.Fatal(err)
+	}
+	return statements
+}
+
+// checks the patterns against a store holding dataset
+func testMatch(t *testing.T, store Store) {
+	s, p, q, o := model.IRI("http://s"), model.IRI("http://p"), model.IRI("http://q"), model.IRI("http://o")
+	cases := []struct {
+		pattern [4]model.RDFTerm
+		count   int
+	}{
+		{[4]model.RDFTerm{nil, nil, nil, nil}, 6},
+		{[4]model.RDFTerm{nil, nil, nil, DefaultGraph}, 3},
+		{[4]model.RDFTerm{s, nil, nil, nil}, 4},
+		{[4]model.RDFTerm{s, p, nil, nil}, 3},
+		{[4]model.RDFTerm{s, nil, o, nil}, 3},
+		{[4]model.RDFTerm{nil, p, o, nil}, 3},
+		{[4]model.RDFTerm{nil, nil, o, model.IRI("http://g")}, 2},
+		{[4]model.RDFTerm{nil, q, nil, nil}, 2},
+		{[4]model.RDFTerm{nil, nil, s, nil}, 1},
+		{[4]model.RDFTerm{s, q, o, DefaultGraph}, 1},
+		{[4]model.RDFTerm{o, nil, nil, nil}, 0},
+	}
+	for _, c := range cases {
+		statements, err := All(store.Match(c.pattern[0], c.pattern[1], c.pattern[2], c.pattern[3]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statements) != c.count {
+			t.Errorf("%v: %d statements instead of %d", c.pattern, len(statements), c.count)
+		}
+		for _, statement := range statements {
+			terms := [4]model.RDFTerm{statement.Subject, statement.Predicate, statement.Object, statement.Context}
+			for i, term := range c.pattern {
+				if term != nil && term != terms[i] && !(term == DefaultGraph && terms[i] == nil) {
+					t.Errorf("%v: %v does not match", c.pattern, statement)
+				}
+			}
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	store := NewMemory()
+	statements := load(t, store)
+	if store.Len() != 6 {
+		t.Errorf("%d statements instead of 6", store.Len())
+	}
+	testMatch(t, store)
+
+	if ok, _ := store.Contains(statements[5]); !ok {
+		t.Errorf("%v is missing", statements[5])
+	}
+	iterator := store.Match(nil, nil, nil, nil)
+	removed := 0
+	// removing while iterating
+	for iterator.Next() {
+		if err := store.Remove(iterator.Statement()); err != nil {
+			t.Fatal(err)
+		}
+		removed++
+	}
+	if removed != 6 || store.Len() != 0 {
+		t.Errorf("%d statements removed, %d left", removed, store.Len())
+	}
+	if ok, _ := store.Contains(statements[5]); ok {
+		t.Errorf("%v was not removed", statements[5])
+	}
+	if len(store.indexes[gpos]) != 0 {
+		t.Errorf("empty levels are left")
+	}
+}
@@ -0,0 +1,317 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Segments
+//
+// a segment is a set of changes sorted in the order of each permutation,
+// one file per permutation. Entries are four big endian IDs, so that the
+// byte order is the order of the IDs, and a byte telling whether the
+// statement is present or removed. Segments are never modified: newer
+// segments hide the entries of older ones.
+
+const entrySize = 4*8 + 1
+
+type entry struct {
+	key     [4]model.ID
+	present bool
+}
+
+func (this entry) encode(buffer []byte) {
+	for i, id := range this.key {
+		binary.BigEndian.PutUint64(buffer[i*8:], uint64(id))
+	}
+	buffer[32] = 0
+	if this.present {
+		buffer[32] = 1
+	}
+}
+
+func decodeEntry(buffer []byte) entry {
+	ret := entry{present: buffer[32] == 1}
+	for i := range ret.key {
+		ret.key[i] = model.ID(binary.BigEndian.Uint64(buffer[i*8:]))
+	}
+	return ret
+}
+
+func compareKeys(a, b [4]model.ID) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// compares the first len(prefix) IDs of key to prefix
+func comparePrefix(key [4]model.ID, prefix []model.ID) int {
+	for i, id := range prefix {
+		switch {
+		case key[i] < id:
+			return -1
+		case key[i] > id:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (this permutation) name() string {
+	letters := "spog"
+	ret := []byte{}
+	for _, position := range this {
+		ret = append(ret, letters[position])
+	}
+	return string(ret)
+}
+
+// the permutations of the files of a segment, the graph last except for
+// the patterns on a graph
+var (
+	spog             = permutation{subjectPosition, predicatePosition, objectPosition, graphPosition}
+	posg             = permutation{predicatePosition, objectPosition, subjectPosition, graphPosition}
+	ospg             = permutation{objectPosition, subjectPosition, predicatePosition, graphPosition}
+	diskPermutations = []permutation{spog, posg, ospg, gspo}
+)
+
+type segment struct {
+	id    int
+	dir   string
+	files map[permutation]*os.File
+	// the number of entries
+	size int64
+	// the users of the segment, the store being one of them until the
+	// segment is compacted; the files are closed and removed at 0
+	refs     int32
+	obsolete int32
+}
+
+func segmentFileName(dir string, id int, permutation permutation) string {
+	return filepath.Join(dir, fmt.Sprintf("%08d.%s", id, permutation.name()))
+}
+
+func openSegment(dir string, id int) (*segment, error) {
+	this := &segment{id: id, dir: dir, files: make(map[permutation]*os.File), refs: 1}
+	for _, permutation := range diskPermutations {
+		file, err := os.Open(segmentFileName(dir, id, permutation))
+		if err != nil {
+			this.close()
+			return nil, err
+		}
+		this.files[permutation] = file
+		info, err := file.Stat()
+		if err != nil {
+			this.close()
+			return nil, err
+		}
+		if info.Size()%entrySize != 0 {
+			this.close()
+			return nil, fmt.Errorf("%s: %w", file.Name(), errCorrupted)
+		}
+		this.size = info.Size() / entrySize
+	}
+	return this, nil
+}
+
+// writes the entries given by next, in the order of permutation, to a
+// new file which replaces name once it is on disk
+func writeSegmentFile(name string, next func() (entry, bool, error)) error {
+	temporary := name + ".tmp"
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	buffer := make([]byte, entrySize)
+	for err == nil {
+		var e entry
+		var ok bool
+		if e, ok, err = next(); !ok || err != nil {
+			break
+		}
+		e.encode(buffer)
+		_, err = writer.Write(buffer)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporary)
+		return err
+	}
+	return os.Rename(temporary, name)
+}
+
+func (this *segment) acquire() {
+	atomic.AddInt32(&this.refs, 1)
+}
+
+func (this *segment) release() {
+	if atomic.AddInt32(&this.refs, -1) > 0 {
+		return
+	}
+	this.close()
+	if atomic.LoadInt32(&this.obsolete) == 1 {
+		for permutation := range this.files {
+			os.Remove(segmentFileName(this.dir, this.id, permutation))
+		}
+	}
+}
+
+func (this *segment) close() {
+	for _, file := range this.files {
+		file.Close()
+	}
+}
+
+// the entries of a permutation file starting with prefix
+func (this *segment) cursor(permutation permutation, prefix []model.ID) (*segmentCursor, error) {
+	ret := &segmentCursor{file: this.files[permutation], prefix: prefix, end: this.size}
+	buffer := make([]byte, entrySize)
+	var err error
+	ret.index = int64(sort.Search(int(this.size), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		if _, err = ret.file.ReadAt(buffer, int64(i)*entrySize); err != nil {
+			return true
+		}
+		return comparePrefix(decodeEntry(buffer).key, prefix) >= 0
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// reads the entries of a segment file by blocks
+type segmentCursor struct {
+	file   *os.File
+	prefix []model.ID
+	index  int64
+	end    int64
+	block  []byte
+}
+
+const cursorBlock = 256
+
+func (this *segmentCursor) next() (entry, bool, error) {
+	if len(this.block) == 0 {
+		if this.index >= this.end {
+			return entry{}, false, nil
+		}
+		count := this.end - this.index
+		if count > cursorBlock {
+			count = cursorBlock
+		}
+		this.block = make([]byte, count*entrySize)
+		if _, err := this.file.ReadAt(this.block, this.index*entrySize); err != nil && err != io.EOF {
+			return entry{}, false, err
+		}
+		this.index += count
+	}
+	ret := decodeEntry(this.block)
+	this.block = this.block[entrySize:]
+	if comparePrefix(ret.key, this.prefix) != 0 {
+		this.index, this.block = this.end, nil
+		return entry{}, false, nil
+	}
+	return ret, true, nil
+}
+
+// the entries of a sorted slice starting with prefix
+type sliceCursor struct {
+	entries []entry
+	prefix  []model.ID
+}
+
+func newSliceCursor(entries []entry, prefix []model.ID) *sliceCursor {
+	start := sort.Search(len(entries), func(i int) bool {
+		return comparePrefix(entries[i].key, prefix) >= 0
+	})
+	return &sliceCursor{entries: entries[start:], prefix: prefix}
+}
+
+func (this *sliceCursor) next() (entry, bool, error) {
+	if len(this.entries) == 0 || comparePrefix(this.entries[0].key, this.prefix) != 0 {
+		return entry{}, false, nil
+	}
+	ret := this.entries[0]
+	this.entries = this.entries[1:]
+	return ret, true, nil
+}
+
+type entryCursor interface {
+	next() (entry, bool, error)
+}
+
+// merges sorted cursors, the first cursors hide the entries of the next
+// ones with the same keys
+type merger struct {
+	cursors []entryCursor
+	heads   []entry
+	live    []bool
+}
+
+func newMerger(cursors []entryCursor) (*merger, error) {
+	this := &merger{cursors: cursors, heads: make([]entry, len(cursors)), live: make([]bool, len(cursors))}
+	for i := range cursors {
+		if err := this.advance(i); err != nil {
+			return nil, err
+		}
+	}
+	return this, nil
+}
+
+func (this *merger) advance(i int) error {
+	head, ok, err := this.cursors[i].next()
+	this.heads[i], this.live[i] = head, ok
+	return err
+}
+
+// the next entry, removed statements included
+func (this *merger) next() (entry, bool, error) {
+	first := -1
+	for i, live := range this.live {
+		if live && (first < 0 || compareKeys(this.heads[i].key, this.heads[first].key) < 0) {
+			first = i
+		}
+	}
+	if first < 0 {
+		return entry{}, false, nil
+	}
+	ret := this.heads[first]
+	for i, live := range this.live {
+		if live && this.heads[i].key == ret.key {
+			if err := this.advance(i); err != nil {
+				return entry{}, false, err
+			}
+		}
+	}
+	return ret, true, nil
+}
@@ -0,0 +1,78 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+
+// Package store keeps datasets and finds their statements by pattern.
+package store
+
+import (
+	"fmt"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Stores are datasets: sets of statements in graphs, the default graph
+// being the statements whose Context is nil.
+type Store interface {
+	// adding a statement twice keeps one
+	Add(statement *model.Statement) error
+	// removing a missing statement is not an error
+	Remove(statement *model.Statement) error
+	Contains(statement *model.Statement) (bool, error)
+	// the statements matching a pattern, nil matches any term and
+	// DefaultGraph only the default graph
+	Match(subject, predicate, object, graph model.RDFTerm) Iterator
+	// the number of statements
+	Len() int
+}
+
+// Iterators go over the statements matched at the time of each call to
+// Next, statements added or removed meanwhile may or may not be seen.
+type Iterator interface {
+	Next() bool
+	Statement() *model.Statement
+	// the error which stopped the iteration, nil at the end of the
+	// statements
+	Err() error
+	Close() error
+}
+
+type defaultGraph struct{}
+
+// DefaultGraph selects the default graph in Match patterns
+var DefaultGraph model.RDFTerm = defaultGraph{}
+
+// All reads the statements of an iterator and closes it
+func All(iterator Iterator) ([]*model.Statement, error) {
+	defer iterator.Close()
+	ret := []*model.Statement{}
+	for iterator.Next() {
+		ret = append(ret, iterator.Statement())
+	}
+	return ret, iterator.Err()
+}
+
+// AddAll adds the statements of a channel, such as the one of a parser,
+// until it is closed
+func AddAll(store Store, statements <-chan *model.Statement) error {
+	for statement := range statements {
+		if err := store.Add(statement); err != nil {
+			// the channel is drained so that its sender is not blocked
+			for range statements {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func checkStatement(statement *model.Statement) error {
+	if statement.Subject == nil || statement.Predicate == nil || statement.Object == nil {
+		return fmt.Errorf("incomplete statement %v", *statement)
+	}
+	if statement.Context == DefaultGraph {
+		return fmt.Errorf("the default graph is a nil Context")
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// the kinds of term records
+const (
+	iriRecord byte = iota + 1
+	literalRecord
+	blankNodeRecord
+	tripleTermRecord
+)
+
+// the terms read before are kept up to this number
+const termCacheSize = 1 << 16
+
+var errCorrupted = errors.New("corrupted record")
+
+// termFile is the dictionary of a Disk store: an append only file of term
+// records, the ID of a term being its rank. Only the offsets of the
+// records and the hashes of the terms stay in memory.
+//
+// Blank nodes are records of their own, the pointers read or written
+// during a session are remembered so that a blank node keeps its ID.
+type termFile struct {
+	lock   sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	// the size of the file, buffered bytes included, and of what reached
+	// the file
+	size    int64
+	flushed int64
+	// the offset of the record of each ID, from ID 1
+	offsets []int64
+	// the IDs of the hashes of the records, blank nodes excepted
+	hashes     map[uint64]model.ID
+	collisions map[uint64][]model.ID
+	blankIDs   map[model.RDFTerm]model.ID
+	blankNodes map[model.ID]model.RDFTerm
+	cache      map[model.ID]model.RDFTerm
+}
+
+// opens the file and drops a record left incomplete by a crash
+func openTermFile(name string) (*termFile, error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	this := &termFile{
+		file:       file,
+		hashes:     make(map[uint64]model.ID),
+		collisions: make(map[uint64][]model.ID),
+		blankIDs:   make(map[model.RDFTerm]model.ID),
+		blankNodes: make(map[model.ID]model.RDFTerm),
+		cache:      make(map[model.ID]model.RDFTerm),
+	}
+	reader := bufio.NewReader(file)
+	for {
+		payload, n, err := readRecord(reader)
+		if err != nil {
+			break
+		}
+		this.offsets = append(this.offsets, this.size)
+		this.size += int64(n)
+		if payload[0] != blankNodeRecord {
+			this.index(hashBytes(payload), model.ID(len(this.offsets)))
+		}
+	}
+	if err := file.Truncate(this.size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(this.size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	this.flushed = this.size
+	this.writer = bufio.NewWriter(file)
+	return this, nil
+}
+
+// a record is the length of its payload, the payload and its CRC, n is
+// the size of the whole record
+func readRecord(reader *bufio.Reader) (payload []byte, n int, err error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length == 0 || length > 1<<30 {
+		return nil, 0, errCorrupted
+	}
+	payload = make([]byte, length+4)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, 0, err
+	}
+	sum := binary.LittleEndian.Uint32(payload[length:])
+	payload = payload[:length]
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, 0, errCorrupted
+	}
+	var header [binary.MaxVarintLen64]byte
+	return payload, binary.PutUvarint(header[:], length) + int(length) + 4, nil
+}
+
+func appendRecord(buffer []byte, payload []byte) []byte {
+	buffer = appendUvarint(buffer, uint64(len(payload)))
+	buffer = append(buffer, payload...)
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(payload))
+	return append(buffer, sum[:]...)
+}
+
+func appendUvarint(buffer []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buffer, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func hashBytes(b []byte) uint64 {
+	hash := fnv.New64a()
+	hash.Write(b)
+	return hash.Sum64()
+}
+
+func (this *termFile) index(hash uint64, id model.ID) {
+	if _, ok := this.hashes[hash]; !ok {
+		this.hashes[hash] = id
+		return
+	}
+	this.collisions[hash] = append(this.collisions[hash], id)
+}
+
+func appendString(buffer []byte, s string) []byte {
+	buffer = appendUvarint(buffer, uint64(len(s)))
+	return append(buffer, s...)
+}
+
+// the payload of a term other than a blank node, the terms of triple terms
+// are written as IDs, ok is false when one of them is unknown and create
+// is false
+func (this *termFile) payload(term model.RDFTerm, create bool) ([]byte, bool, error) {
+	switch t := term.(type) {
+	case model.IRI:
+		return appendString([]byte{iriRecord}, string(t)), true, nil
+	case model.Literal:
+		ret := []byte{literalRecord}
+		for _, s := range []string{t.Lexical, string(t.Datatype), t.Language, t.Direction} {
+			ret = appendString(ret, s)
+		}
+		return ret, true, nil
+	case model.TripleTerm:
+		ret := []byte{tripleTermRecord}
+		for _, nested := range []model.RDFTerm{t.Subject, t.Predicate, t.Object} {
+			id, ok, err := this.id(nested, create)
+			if !ok || err != nil {
+				return nil, false, err
+			}
+			ret = appendUvarint(ret, uint64(id))
+		}
+		return ret, true, nil
+	}
+	return nil, false, fmt.Errorf("cannot store %#v", term)
+}
+
+// the ID of term, a new one if create is set
+func (this *termFile) id(term model.RDFTerm, create bool) (model.ID, bool, error) {
+	if term == nil {
+		return 0, true, nil
+	}
+	if model.IsBlankNode(term) {
+		if id, ok := this.blankIDs[term]; ok {
+			return id, true, nil
+		}
+		if !create {
+			return 0, false, nil
+		}
+		label := ""
+		if labelled, ok := term.(*model.LabelledBlankNode); ok {
+			label = labelled.Label
+		}
+		id, err := this.append(appendString([]byte{blankNodeRecord}, label))
+		if err != nil {
+			return 0, false, err
+		}
+		this.blankIDs[term] = id
+		this.blankNodes[id] = term
+		return id, true, nil
+	}
+
+	payload, ok, err := this.payload(term, create)
+	if !ok || err != nil {
+		return 0, false, err
+	}
+	hash := hashBytes(payload)
+	if id, ok := this.hashes[hash]; ok {
+		for _, candidate := range append([]model.ID{id}, this.collisions[hash]...) {
+			stored, err := this.read(candidate)
+			if err != nil {
+				return 0, false, err
+			}
+			if bytes.Equal(stored, payload) {
+				return candidate, true, nil
+			}
+		}
+	}
+	if !create {
+		return 0, false, nil
+	}
+	id, err := this.append(payload)
+	if err != nil {
+		return 0, false, err
+	}
+	this.index(hash, id)
+	return id, true, nil
+}
+
+func (this *termFile) append(payload []byte) (model.ID, error) {
+	record := appendRecord(nil, payload)
+	if _, err := this.writer.Write(record); err != nil {
+		return 0, err
+	}
+	this.offsets = append(this.offsets, this.size)
+	this.size += int64(len(record))
+	return model.ID(len(this.offsets)), nil
+}
+
+// the payload of the record of id
+func (this *termFile) read(id model.ID) ([]byte, error) {
+	if id == 0 || int(id) > len(this.offsets) {
+		return nil, fmt.Errorf("unknown term %d", id)
+	}
+	offset := this.offsets[id-1]
+	if offset >= this.flushed {
+		if err := this.flush(); err != nil {
+			return nil, err
+		}
+	}
+	end := this.size
+	if int(id) < len(this.offsets) {
+		end = this.offsets[id]
+	}
+	record := make([]byte, end-offset)
+	if _, err := this.file.ReadAt(record, offset); err != nil {
+		return nil, err
+	}
+	length, n := binary.Uvarint(record)
+	if n <= 0 || int64(n)+int64(length)+4 != int64(len(record)) {
+		return nil, errCorrupted
+	}
+	return record[n : n+int(length)], nil
+}
+
+func (this *termFile) decode(id model.ID) (model.RDFTerm, error) {
+	if id == 0 {
+		return nil, nil
+	}
+	if term, ok := this.blankNodes[id]; ok {
+		return term, nil
+	}
+	if term, ok := this.cache[id]; ok {
+		return term, nil
+	}
+	payload, err := this.read(id)
+	if err != nil {
+		return nil, err
+	}
+	reader := bytes.NewReader(payload[1:])
+	var term model.RDFTerm
+	switch payload[0] {
+	case iriRecord:
+		iri, err := readString(reader)
+		if err != nil {
+			return nil, err
+		}
+		term = model.IRI(iri)
+	case literalRecord:
+		fields := [4]string{}
+		for i := range fields {
+			if fields[i], err = readString(reader); err != nil {
+				return nil, err
+			}
+		}
+		term = model.Literal{Lexical: fields[0], Datatype: model.IRI(fields[1]), Language: fields[2], Direction: fields[3]}
+	case blankNodeRecord:
+		label, err := readString(reader)
+		if err != nil {
+			return nil, err
+		}
+		if label == "" {
+			label = fmt.Sprintf("b%d", id)
+		}
+		node := &model.LabelledBlankNode{Label: label}
+		this.blankIDs[node] = id
+		this.blankNodes[id] = node
+		return node, nil
+	case tripleTermRecord:
+		terms := [3]model.RDFTerm{}
+		for i := range terms {
+			nested, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, err
+			}
+			if terms[i], err = this.decode(model.ID(nested)); err != nil {
+				return nil, err
+			}
+		}
+		term = model.TripleTerm{Subject: terms[0], Predicate: terms[1], Object: terms[2]}
+	default:
+		return nil, errCorrupted
+	}
+	if len(this.cache) >= termCacheSize {
+		this.cache = make(map[model.ID]model.RDFTerm)
+	}
+	this.cache[id] = term
+	return term, nil
+}
+
+func readString(reader *bytes.Reader) (string, error) {
+	length, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(reader.Len()) {
+		return "", errCorrupted
+	}
+	ret := make([]byte, length)
+	reader.Read(ret)
+	return string(ret), nil
+}
+
+// writes the buffered records to the file
+func (this *termFile) flush() error {
+	if err := this.writer.Flush(); err != nil {
+		return err
+	}
+	this.flushed = this.size
+	return nil
+}
+
+func (this *termFile) sync() error {
+	if err := this.flush(); err != nil {
+		return err
+	}
+	return this.file.Sync()
+}
+
+func (this *termFile) close() error {
+	err := this.flush()
+	if closeErr := this.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
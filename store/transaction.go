@@ -0,0 +1,171 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Transaction gathers changes to a store in memory, its methods seeing
+// them as if they were made, and makes them all on Commit. The store must
+// not be changed by others until then.
+type Transaction struct {
+	store Store
+	// the statements added which are not in the store, and those removed
+	// which are
+	added   *Memory
+	removed *Memory
+}
+
+func NewTransaction(store Store) *Transaction {
+	return &Transaction{store: store, added: NewMemory(), removed: NewMemory()}
+}
+
+func (this *Transaction) Add(statement *model.Statement) error {
+	if err := checkStatement(statement); err != nil {
+		return err
+	}
+	if removed, _ := this.removed.Contains(statement); removed {
+		return this.removed.Remove(statement)
+	}
+	present, err := this.store.Contains(statement)
+	if present || err != nil {
+		return err
+	}
+	return this.added.Add(statement)
+}
+
+func (this *Transaction) Remove(statement *model.Statement) error {
+	if added, _ := this.added.Contains(statement); added {
+		return this.added.Remove(statement)
+	}
+	present, err := this.store.Contains(statement)
+	if !present || err != nil {
+		return err
+	}
+	return this.removed.Add(statement)
+}
+
+func (this *Transaction) Contains(statement *model.Statement) (bool, error) {
+	if added, _ := this.added.Contains(statement); added {
+		return true, nil
+	}
+	if removed, _ := this.removed.Contains(statement); removed {
+		return false, nil
+	}
+	return this.store.Contains(statement)
+}
+
+// the statements of the store which are not removed, then those added
+func (this *Transaction) Match(subject, predicate, object, graph model.RDFTerm) Iterator {
+	return &transactionIterator{
+		store:   this.store.Match(subject, predicate, object, graph),
+		removed: this.removed,
+		added:   this.added.Match(subject, predicate, object, graph),
+	}
+}
+
+func (this *Transaction) Len() int {
+	return this.store.Len() + this.added.Len() - this.removed.Len()
+}
+
+// Commit makes the changes to the store. When the store fails the changes
+// already made are undone, as far as the store allows, and the transaction
+// is left as it was.
+func (this *Transaction) Commit() error {
+	removed, err := All(this.removed.Match(nil, nil, nil, nil))
+	if err != nil {
+		return err
+	}
+	added, err := All(this.added.Match(nil, nil, nil, nil))
+	if err != nil {
+		return err
+	}
+	for i, statement := range removed {
+		if err := this.store.Remove(statement); err != nil {
+			this.undo(removed[:i], nil)
+			return err
+		}
+	}
+	for i, statement := range added {
+		if err := this.store.Add(statement); err != nil {
+			this.undo(removed, added[:i])
+			return err
+		}
+	}
+	this.Rollback()
+	return nil
+}
+
+// the errors of the store are those which made the commit fail
+func (this *Transaction) undo(removed, added []*model.Statement) {
+	for _, statement := range added {
+		this.store.Remove(statement)
+	}
+	for _, statement := range removed {
+		this.store.Add(statement)
+	}
+}
+
+// Rollback forgets the changes
+func (this *Transaction) Rollback() {
+	this.added = NewMemory()
+	this.removed = NewMemory()
+}
+
+type transactionIterator struct {
+	// nil once its statements are all read
+	store   Iterator
+	removed *Memory
+	added   Iterator
+	current *model.Statement
+	err     error
+}
+
+func (this *transactionIterator) Next() bool {
+	this.current = nil
+	if this.err != nil {
+		return false
+	}
+	for this.store != nil {
+		if !this.store.Next() {
+			this.err = this.store.Err()
+			this.store.Close()
+			this.store = nil
+			if this.err != nil {
+				return false
+			}
+			break
+		}
+		statement := this.store.Statement()
+		if removed, _ := this.removed.Contains(statement); !removed {
+			this.current = statement
+			return true
+		}
+	}
+	if this.added.Next() {
+		this.current = this.added.Statement()
+		return true
+	}
+	return false
+}
+
+func (this *transactionIterator) Statement() *model.Statement {
+	return this.current
+}
+
+func (this *transactionIterator) Err() error {
+	return this.err
+}
+
+func (this *transactionIterator) Close() error {
+	var err error
+	if this.store != nil {
+		err = this.store.Close()
+		this.store = nil
+	}
+	this.added.Close()
+	return err
+}
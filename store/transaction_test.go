@@ -0,0 +1,97 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// fails to add the statements of a subject
+type failingStore struct {
+	*Memory
+	subject model.RDFTerm
+}
+
+func (this *failingStore) Add(statement *model.Statement) error {
+	if statement.Subject == this.subject {
+		return errors.New("refused")
+	}
+	return this.Memory.Add(statement)
+}
+
+func TestTransaction(t *testing.T) {
+	// the statements of a transaction over an empty store
+	transaction := NewTransaction(NewMemory())
+	load(t, transaction)
+	testMatch(t, transaction)
+
+	base := NewMemory()
+	statements := load(t, base)
+	transaction = NewTransaction(base)
+	extra := &model.Statement{Subject: model.IRI("http://x"), Predicate: model.IRI("http://p"), Object: model.IRI("http://o")}
+	for _, statement := range statements[:3] {
+		if err := transaction.Remove(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// added back, and a new one added then removed
+	if err := transaction.Add(statements[0]); err != nil {
+		t.Fatal(err)
+	}
+	transaction.Add(extra)
+	transaction.Remove(extra)
+	transaction.Add(extra)
+	if transaction.Len() != 5 || base.Len() != 6 {
+		t.Errorf("%d statements in the transaction and %d in the store", transaction.Len(), base.Len())
+	}
+	matched, _ := All(transaction.Match(nil, nil, nil, DefaultGraph))
+	if len(matched) != 2 {
+		t.Errorf("%d statements in the default graph instead of 2", len(matched))
+	}
+	if ok, _ := transaction.Contains(statements[1]); ok {
+		t.Errorf("%v was not removed", statements[1])
+	}
+	if ok, _ := transaction.Contains(extra); !ok {
+		t.Errorf("%v was not added", extra)
+	}
+	if err := transaction.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if base.Len() != 5 || transaction.Len() != 5 {
+		t.Errorf("%d statements in the store instead of 5", base.Len())
+	}
+	if ok, _ := base.Contains(extra); !ok {
+		t.Errorf("%v was not committed", extra)
+	}
+}
+
+func TestTransactionFailure(t *testing.T) {
+	base := &failingStore{Memory: NewMemory(), subject: model.IRI("http://y")}
+	statements := load(t, base)
+	transaction := NewTransaction(base)
+	transaction.Remove(statements[0])
+	for _, subject := range []model.IRI{"http://x", "http://y"} {
+		transaction.Add(&model.Statement{Subject: subject, Predicate: model.IRI("http://p"), Object: model.IRI("http://o")})
+	}
+	// the statement removed and the one added are undone
+	if err := transaction.Commit(); err == nil {
+		t.Fatal("the commit succeeded")
+	}
+	if base.Len() != 6 {
+		t.Errorf("%d statements in the store instead of 6", base.Len())
+	}
+	if ok, _ := base.Contains(statements[0]); !ok {
+		t.Errorf("%v was not added back", statements[0])
+	}
+	if matched, _ := All(base.Match(model.IRI("http://x"), nil, nil, nil)); len(matched) != 0 {
+		t.Errorf("%v was not removed", matched)
+	}
+	if transaction.Len() != 7 {
+		t.Errorf("the transaction has %d statements instead of 7", transaction.Len())
+	}
+}
@@ -0,0 +1,65 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package writer
+
+import (
+	"strconv"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// BlankNodeLabels hands out a label per blank node. The labels read from
+// the documents are kept unless two distinct nodes share one.
+type BlankNodeLabels struct {
+	labels  map[model.RDFTerm]string
+	used    map[string]struct{}
+	counter int
+}
+
+func NewBlankNodeLabels() *BlankNodeLabels {
+	return &BlankNodeLabels{
+		labels: make(map[model.RDFTerm]string),
+		used:   make(map[string]struct{}),
+	}
+}
+
+func (this *BlankNodeLabels) Label(node model.RDFTerm) string {
+	if label, ok := this.labels[node]; ok {
+		return label
+	}
+	label := ""
+	if labelled, ok := node.(*model.LabelledBlankNode); ok && isPlainLabel(labelled.Label) {
+		if _, taken := this.used[labelled.Label]; !taken {
+			label = labelled.Label
+		}
+	}
+	for label == "" {
+		candidate := "b" + strconv.Itoa(this.counter)
+		this.counter++
+		if _, taken := this.used[candidate]; !taken {
+			label = candidate
+		}
+	}
+	this.labels[node] = label
+	this.used[label] = struct{}{}
+	return label
+}
+
+// labels that can be written as they are in every syntax
+func isPlainLabel(label string) bool {
+	if label == "" || label[len(label)-1] == '.' {
+		return false
+	}
+	for i, r := range label {
+		ok := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_'
+		if i > 0 {
+			ok = ok || r == '-' || r == '.'
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+type NTriplesWriter struct {
+	writer *bufio.Writer
+	labels *BlankNodeLabels
+	// write the graph names
+	quads bool
+}
+
+func NewNTriplesWriter(w io.Writer) *NTriplesWriter {
+	return &NTriplesWriter{writer: bufio.NewWriter(w), labels: NewBlankNodeLabels()}
+}
+
+func NewNQuadsWriter(w io.Writer) *NTriplesWriter {
+	return &NTriplesWriter{writer: bufio.NewWriter(w), labels: NewBlankNodeLabels(), quads: true}
+}
+
+func (this *NTriplesWriter) Write(statement *model.Statement) error {
+	if err := checkStatement(statement); err != nil {
+		return err
+	}
+	line := FormatTerm(statement.Subject, this.labels) + " " +
+		FormatTerm(statement.Predicate, this.labels) + " " +
+		FormatTerm(statement.Object, this.labels)
+	if this.quads && statement.Context != nil {
+		line += " " + FormatTerm(statement.Context, this.labels)
+	}
+	_, err := this.writer.WriteString(line + " .\n")
+	return err
+}
+
+func (this *NTriplesWriter) Close() error {
+	return this.writer.Flush()
+}
+
+// FormatTerm returns the N-Triples form of term
+func FormatTerm(term model.RDFTerm, labels *BlankNodeLabels) string {
+	switch t := term.(type) {
+	case model.IRI:
+		return "<" + EscapeIRI(string(t)) + ">"
+	case model.BlankNode:
+		return "_:" + labels.Label(t)
+	case model.Literal:
+		ret := "\"" + EscapeString(t.Lexical) + "\""
+		switch {
+		case t.Language != "" && t.Direction != "":
+			ret += "@" + t.Language + "--" + t.Direction
+		case t.Language != "":
+			ret += "@" + t.Language
+		case t.Datatype != "" && t.Datatype != model.XSDString:
+			ret += "^^<" + EscapeIRI(string(t.Datatype)) + ">"
+		}
+		return ret
+	case model.TripleTerm:
+		return "<<( " + FormatTerm(t.Subject, labels) + " " + FormatTerm(t.Predicate, labels) + " " + FormatTerm(t.Object, labels) + " )>>"
+	}
+	return fmt.Sprintf("%v", term)
+}
+
+// EscapeString escapes a literal lexical form for a double quoted string,
+// as per the canonical N-Triples form: ", \, backspaces, tabs, line feeds,
+// form feeds and carriage returns are escaped with a backslash, the other
+// control characters with \u
+func EscapeString(lexical string) string {
+	var ret strings.Builder
+	for _, r := range lexical {
+		switch r {
+		case '"':
+			ret.WriteString(`\"`)
+		case '\\':
+			ret.WriteString(`\\`)
+		case '\b':
+			ret.WriteString(`\b`)
+		case '\t':
+			ret.WriteString(`\t`)
+		case '\n':
+			ret.WriteString(`\n`)
+		case '\f':
+			ret.WriteString(`\f`)
+		case '\r':
+			ret.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7F {
+				fmt.Fprintf(&ret, `\u%04X`, r)
+			} else {
+				ret.WriteRune(r)
+			}
+		}
+	}
+	return ret.String()
+}
+
+// EscapeIRI escapes the characters which are not allowed between < and >
+func EscapeIRI(iri string) string {
+	var ret strings.Builder
+	for _, r := range iri {
+		if r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r) {
+			fmt.Fprintf(&ret, `\u%04X`, r)
+		} else {
+			ret.WriteRune(r)
+		}
+	}
+	return ret.String()
+}
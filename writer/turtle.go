@@ -0,0 +1,565 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package writer
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// TurtleWriter keeps the statements until Close, where they are grouped by
+// subject and predicate. Blank nodes used once as object are nested with
+// [ ] and well formed lists are written as collections, unreferenced blank
+// subjects are written with [ ] and unreferenced lists as collection subjects
+// when they have other properties.
+type TurtleWriter struct {
+	writer     *bufio.Writer
+	namespaces []model.Namespace
+	options    Options
+	labels     *BlankNodeLabels
+	// write graphs with TriG blocks
+	trig bool
+
+	statements []*model.Statement
+	seen       map[model.Statement]struct{}
+}
+
+func NewTurtleWriter(w io.Writer, options Options) *TurtleWriter {
+	return &TurtleWriter{
+		writer:     bufio.NewWriter(w),
+		namespaces: options.Namespaces,
+		options:    options,
+		labels:     NewBlankNodeLabels(),
+		seen:       make(map[model.Statement]struct{}),
+	}
+}
+
+func NewTriGWriter(w io.Writer, options Options) *TurtleWriter {
+	ret := NewTurtleWriter(w, options)
+	ret.trig = true
+	return ret
+}
+
+func (this *TurtleWriter) Write(statement *model.Statement) error {
+	if err := checkStatement(statement); err != nil {
+		return err
+	}
+	key := *statement
+	if !this.trig {
+		// turtle merges the graphs
+		key.Context = nil
+	}
+	if _, ok := this.seen[key]; ok {
+		return nil
+	}
+	this.seen[key] = struct{}{}
+	this.statements = append(this.statements, &key)
+	return nil
+}
+
+func (this *TurtleWriter) Close() error {
+	if this.options.Sort {
+		this.namespaces = append([]model.Namespace{}, this.namespaces...)
+		sort.SliceStable(this.namespaces, func(i, j int) bool {
+			return this.namespaces[i].Prefix < this.namespaces[j].Prefix
+		})
+	}
+	for _, line := range this.options.Header {
+		this.writer.WriteString(line + "\n")
+	}
+	if this.options.Base != "" {
+		this.writer.WriteString("@base <" + EscapeIRI(string(this.options.Base)) + "> .\n")
+	}
+	for _, namespace := range this.namespaces {
+		this.writer.WriteString("@prefix " + string(namespace.Prefix) + ": <" + EscapeIRI(string(namespace.IRI)) + "> .\n")
+	}
+	directives := len(this.namespaces) > 0 || this.options.Base != ""
+	if directives && len(this.statements) > 0 {
+		this.writer.WriteString("\n")
+	}
+
+	renderer := newTurtleRenderer(this, this.statements)
+	for i, graph := range renderer.graphs {
+		if i > 0 {
+			this.writer.WriteString("\n")
+		}
+		if graph == nil {
+			renderer.writeGraph(graph, 0)
+			continue
+		}
+		this.writer.WriteString(renderer.term(graph) + " {\n")
+		renderer.writeGraph(graph, 1)
+		this.writer.WriteString("}\n")
+	}
+	if len(this.options.Footer) > 0 && (directives || len(this.statements) > 0) {
+		this.writer.WriteString("\n")
+	}
+	for _, line := range this.options.Footer {
+		this.writer.WriteString(line + "\n")
+	}
+	return this.writer.Flush()
+}
+
+type predicateObjects struct {
+	predicate model.RDFTerm
+	objects   []model.RDFTerm
+}
+
+type turtleRenderer struct {
+	parent *TurtleWriter
+
+	graphs     []model.RDFTerm
+	subjects   map[model.RDFTerm][]model.RDFTerm
+	properties map[model.RDFTerm]map[model.RDFTerm][]*predicateObjects
+
+	// blank nodes which can be nested with [ ] in the graph they belong to
+	nestable map[model.RDFTerm]model.RDFTerm
+	// blank nodes which are not referenced at all
+	unreferenced map[model.RDFTerm]bool
+	written      map[model.RDFTerm]bool
+}
+
+func newTurtleRenderer(parent *TurtleWriter, statements []*model.Statement) *turtleRenderer {
+	this := &turtleRenderer{
+		parent:       parent,
+		subjects:     make(map[model.RDFTerm][]model.RDFTerm),
+		properties:   make(map[model.RDFTerm]map[model.RDFTerm][]*predicateObjects),
+		nestable:     make(map[model.RDFTerm]model.RDFTerm),
+		unreferenced: make(map[model.RDFTerm]bool),
+		written:      make(map[model.RDFTerm]bool),
+	}
+
+	objectUses := make(map[model.RDFTerm]int)
+	objectGraph := make(map[model.RDFTerm]model.RDFTerm)
+	subjectGraphs := make(map[model.RDFTerm]map[model.RDFTerm]struct{})
+	excluded := make(map[model.RDFTerm]bool)
+
+	for _, statement := range statements {
+		graph := statement.Context
+		properties, ok := this.properties[graph]
+		if !ok {
+			this.graphs = append(this.graphs, graph)
+			properties = make(map[model.RDFTerm][]*predicateObjects)
+			this.properties[graph] = properties
+		}
+		if _, ok := properties[statement.Subject]; !ok {
+			this.subjects[graph] = append(this.subjects[graph], statement.Subject)
+		}
+		properties[statement.Subject] = appendObject(properties[statement.Subject], statement.Predicate, statement.Object)
+
+		if subjectGraphs[statement.Subject] == nil {
+			subjectGraphs[statement.Subject] = make(map[model.RDFTerm]struct{})
+		}
+		subjectGraphs[statement.Subject][graph] = struct{}{}
+		if model.IsBlankNode(statement.Object) {
+			objectUses[statement.Object]++
+			objectGraph[statement.Object] = graph
+		}
+		if tripleTerm, ok := statement.Object.(model.TripleTerm); ok {
+			excludeTripleTerm(tripleTerm, excluded)
+		}
+		if graph != nil {
+			excluded[graph] = true
+		}
+	}
+
+	for subject, graphs := range subjectGraphs {
+		if !model.IsBlankNode(subject) || excluded[subject] || len(graphs) != 1 {
+			continue
+		}
+		switch objectUses[subject] {
+		case 0:
+			this.unreferenced[subject] = true
+		case 1:
+			// comments are written above subject blocks
+			if this.hasComments(graphs, subject) {
+				continue
+			}
+			if _, ok := graphs[objectGraph[subject]]; ok {
+				this.nestable[subject] = objectGraph[subject]
+			}
+		}
+	}
+	if parent.options.Sort {
+		this.sort()
+	}
+	return this
+}
+
+func (this *turtleRenderer) hasComments(graphs map[model.RDFTerm]struct{}, subject model.RDFTerm) bool {
+	for graph := range graphs {
+		if len(this.parent.options.Comments[GraphSubject{Graph: graph, Subject: subject}]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// the default graph comes first and rdf:type is still the first predicate
+func (this *turtleRenderer) sort() {
+	sortTerms(this.graphs)
+	for graph, subjects := range this.subjects {
+		sortTerms(subjects)
+		for _, properties := range this.properties[graph] {
+			sort.SliceStable(properties, func(i, j int) bool {
+				if properties[i].predicate == model.A || properties[j].predicate == model.A {
+					return properties[i].predicate == model.A && properties[j].predicate != model.A
+				}
+				return model.CompareTerms(properties[i].predicate, properties[j].predicate) < 0
+			})
+			for _, property := range properties {
+				sortTerms(property.objects)
+			}
+		}
+	}
+}
+
+func sortTerms(terms []model.RDFTerm) {
+	sort.SliceStable(terms, func(i, j int) bool {
+		return model.CompareTerms(terms[i], terms[j]) < 0
+	})
+}
+
+func appendObject(properties []*predicateObjects, predicate model.RDFTerm, object model.RDFTerm) []*predicateObjects {
+	for _, property := range properties {
+		if property.predicate == predicate {
+			property.objects = append(property.objects, object)
+			return properties
+		}
+	}
+	property := &predicateObjects{predicate: predicate, objects: []model.RDFTerm{object}}
+	if predicate == model.A {
+		// rdf:type comes first
+		return append([]*predicateObjects{property}, properties...)
+	}
+	return append(properties, property)
+}
+
+func excludeTripleTerm(tripleTerm model.TripleTerm, excluded map[model.RDFTerm]bool) {
+	for _, term := range []model.RDFTerm{tripleTerm.Subject, tripleTerm.Object} {
+		if model.IsBlankNode(term) {
+			excluded[term] = true
+		}
+		if nested, ok := term.(model.TripleTerm); ok {
+			excludeTripleTerm(nested, excluded)
+		}
+	}
+}
+
+func (this *turtleRenderer) write(s string) {
+	this.parent.writer.WriteString(s)
+}
+
+func (this *turtleRenderer) indent(level int) {
+	this.write(strings.Repeat("    ", level))
+}
+
+func (this *turtleRenderer) writeGraph(graph model.RDFTerm, level int) {
+	properties := this.properties[graph]
+	subjects := this.subjects[graph]
+	// subjects can be written once per graph
+	this.written = make(map[model.RDFTerm]bool)
+	blocks := 0
+	for pass := 0; pass < 2; pass++ {
+		for _, subject := range subjects {
+			if this.written[subject] {
+				continue
+			}
+			// nested nodes are written with their parent, but the members
+			// of a cycle of nested nodes have none, they are written on the
+			// second pass
+			if _, ok := this.nestable[subject]; ok && pass == 0 {
+				continue
+			}
+			this.written[subject] = true
+			if blocks > 0 {
+				this.write("\n")
+			}
+			blocks++
+			for _, line := range this.parent.options.Comments[GraphSubject{Graph: graph, Subject: subject}] {
+				this.indent(level)
+				this.write(line + "\n")
+			}
+			this.indent(level)
+			if !this.unreferenced[subject] {
+				this.write(this.term(subject) + " ")
+				this.predicateObjectList(graph, properties[subject], level+1)
+				this.write(" .\n")
+			} else if items, others, ok := this.subjectCollection(graph, subject); ok {
+				this.write("(")
+				for _, item := range items {
+					this.write(" ")
+					this.object(graph, item, level)
+				}
+				this.write(" ) ")
+				this.predicateObjectList(graph, others, level+1)
+				this.write(" .\n")
+			} else if this.isShort(properties[subject]) {
+				this.write("[ ")
+				this.predicateObjectList(graph, properties[subject], level+1)
+				this.write(" ] .\n")
+			} else {
+				this.write("[\n")
+				this.indent(level + 1)
+				this.predicateObjectList(graph, properties[subject], level+1)
+				this.write("\n")
+				this.indent(level)
+				this.write("] .\n")
+			}
+		}
+	}
+}
+
+// writes properties, the first predicate on the current line and the next
+// ones on new lines indented at level
+func (this *turtleRenderer) predicateObjectList(graph model.RDFTerm, properties []*predicateObjects, level int) {
+	for i, property := range properties {
+		if i > 0 {
+			this.write(" ;\n")
+			this.indent(level)
+		}
+		if property.predicate == model.A {
+			this.write("a")
+		} else {
+			this.write(this.term(property.predicate))
+		}
+		for j, object := range property.objects {
+			if j > 0 {
+				this.write(",")
+			}
+			this.write(" ")
+			this.object(graph, object, level)
+		}
+	}
+}
+
+// a single predicate with a single object which does not nest anything
+func (this *turtleRenderer) isShort(properties []*predicateObjects) bool {
+	if len(properties) != 1 || len(properties[0].objects) != 1 {
+		return false
+	}
+	_, nested := this.nestable[properties[0].objects[0]]
+	return !nested
+}
+
+func (this *turtleRenderer) object(graph model.RDFTerm, object model.RDFTerm, level int) {
+	if nestGraph, ok := this.nestable[object]; !ok || nestGraph != graph || this.written[object] {
+		this.write(this.term(object))
+		return
+	}
+	if items, ok := this.collection(graph, object); ok {
+		this.write("(")
+		for _, item := range items {
+			this.write(" ")
+			this.object(graph, item, level)
+		}
+		this.write(" )")
+		return
+	}
+	this.written[object] = true
+	properties := this.properties[graph][object]
+	if len(properties) == 0 {
+		this.write("[]")
+	} else if this.isShort(properties) {
+		this.write("[ ")
+		this.predicateObjectList(graph, properties, level+1)
+		this.write(" ]")
+	} else {
+		this.write("[\n")
+		this.indent(level + 1)
+		this.predicateObjectList(graph, properties, level+1)
+		this.write("\n")
+		this.indent(level)
+		this.write("]")
+	}
+}
+
+// the items of the list starting at head if it can be written as a
+// collection, the list nodes are then marked as written
+func (this *turtleRenderer) collection(graph model.RDFTerm, head model.RDFTerm) ([]model.RDFTerm, bool) {
+	items := []model.RDFTerm{}
+	nodes := []model.RDFTerm{}
+	visited := make(map[model.RDFTerm]bool)
+	node := head
+	for node != model.RDFNil {
+		if nestGraph, ok := this.nestable[node]; !ok || nestGraph != graph || visited[node] || this.written[node] {
+			return nil, false
+		}
+		visited[node] = true
+		properties := this.properties[graph][node]
+		if len(properties) != 2 {
+			return nil, false
+		}
+		var first, rest []model.RDFTerm
+		for _, property := range properties {
+			switch property.predicate {
+			case model.RDFFirst:
+				first = property.objects
+			case model.RDFRest:
+				rest = property.objects
+			}
+		}
+		if len(first) != 1 || len(rest) != 1 {
+			return nil, false
+		}
+		items = append(items, first[0])
+		nodes = append(nodes, node)
+		node = rest[0]
+	}
+	for _, node := range nodes {
+		this.written[node] = true
+	}
+	return items, true
+}
+
+// the items of the unreferenced list starting at head and the other
+// properties of head, when it can be written as a collection subject. A
+// collection subject needs a predicate object list, so head must have other
+// properties than rdf:first and rdf:rest.
+func (this *turtleRenderer) subjectCollection(graph model.RDFTerm, head model.RDFTerm) ([]model.RDFTerm, []*predicateObjects, bool) {
+	var first, rest []model.RDFTerm
+	others := []*predicateObjects{}
+	for _, property := range this.properties[graph][head] {
+		switch property.predicate {
+		case model.RDFFirst:
+			first = property.objects
+		case model.RDFRest:
+			rest = property.objects
+		default:
+			others = append(others, property)
+		}
+	}
+	if len(first) != 1 || len(rest) != 1 || len(others) == 0 {
+		return nil, nil, false
+	}
+	items := []model.RDFTerm{first[0]}
+	if rest[0] != model.RDFNil {
+		tail, ok := this.collection(graph, rest[0])
+		if !ok {
+			return nil, nil, false
+		}
+		items = append(items, tail...)
+	}
+	return items, others, true
+}
+
+var integerPattern = regexp.MustCompile(`^[+-]?[0-9]+$`)
+var decimalPattern = regexp.MustCompile(`^[+-]?[0-9]*\.[0-9]+$`)
+var doublePattern = regexp.MustCompile(`^[+-]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)[eE][+-]?[0-9]+$`)
+var localNamePattern = regexp.MustCompile(`^([A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_-])?)?$`)
+
+func (this *turtleRenderer) term(term model.RDFTerm) string {
+	switch t := term.(type) {
+	case model.IRI:
+		return this.iri(t)
+	case model.BlankNode:
+		return "_:" + this.parent.labels.Label(t)
+	case model.Literal:
+		switch t.Datatype {
+		case model.XSDInteger:
+			if integerPattern.MatchString(t.Lexical) {
+				return t.Lexical
+			}
+		case model.XSDDecimal:
+			if decimalPattern.MatchString(t.Lexical) {
+				return t.Lexical
+			}
+		case model.XSDDouble:
+			if doublePattern.MatchString(t.Lexical) {
+				return t.Lexical
+			}
+		case model.XSDBoolean:
+			if t.Lexical == "true" || t.Lexical == "false" {
+				return t.Lexical
+			}
+		}
+		ret := "\"" + EscapeString(t.Lexical) + "\""
+		if strings.Contains(t.Lexical, "\n") {
+			// long strings keep the line breaks
+			lines := strings.Split(t.Lexical, "\n")
+			for i, line := range lines {
+				lines[i] = EscapeString(line)
+			}
+			ret = "\"\"\"" + strings.Join(lines, "\n") + "\"\"\""
+		}
+		switch {
+		case t.Language != "" && t.Direction != "":
+			ret += "@" + t.Language + "--" + t.Direction
+		case t.Language != "":
+			ret += "@" + t.Language
+		case t.Datatype != "" && t.Datatype != model.XSDString:
+			ret += "^^" + this.iri(t.Datatype)
+		}
+		return ret
+	case model.TripleTerm:
+		predicate := "a"
+		if t.Predicate != model.A {
+			predicate = this.term(t.Predicate)
+		}
+		return "<<( " + this.term(t.Subject) + " " + predicate + " " + this.term(t.Object) + " )>>"
+	}
+	return FormatTerm(term, this.parent.labels)
+}
+
+func (this *turtleRenderer) iri(iri model.IRI) string {
+	ret := AbbreviateIRI(iri, this.parent.namespaces)
+	if strings.HasPrefix(ret, "<") && this.parent.options.Base != "" {
+		if relative, ok := relativeIRI(iri, this.parent.options.Base); ok {
+			return "<" + EscapeIRI(relative) + ">"
+		}
+	}
+	return ret
+}
+
+// iri relative to base, either a fragment or query of base or a path in
+// the directory of base, checked by resolving it back
+func relativeIRI(iri model.IRI, base model.IRI) (string, bool) {
+	baseURL, err := url.Parse(string(base))
+	if err != nil {
+		return "", false
+	}
+	candidates := []string{}
+	if strings.HasPrefix(string(iri), string(base)) {
+		candidates = append(candidates, string(iri[len(base):]))
+	}
+	if slash := strings.LastIndex(string(base), "/"); slash >= 0 && strings.HasPrefix(string(iri), string(base[:slash+1])) {
+		candidates = append(candidates, string(iri[slash+1:]))
+	}
+	for _, candidate := range candidates {
+		reference, err := url.Parse(candidate)
+		if err == nil && baseURL.ResolveReference(reference).String() == string(iri) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// AbbreviateIRI returns the prefixed name of iri with the longest matching
+// namespace, or <iri> when none fits
+func AbbreviateIRI(iri model.IRI, namespaces []model.Namespace) string {
+	best := -1
+	for i, namespace := range namespaces {
+		if !strings.HasPrefix(string(iri), string(namespace.IRI)) {
+			continue
+		}
+		if !localNamePattern.MatchString(string(iri[len(namespace.IRI):])) {
+			continue
+		}
+		if best < 0 || len(namespace.IRI) > len(namespaces[best].IRI) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "<" + EscapeIRI(string(iri)) + ">"
+	}
+	namespace := namespaces[best]
+	return string(namespace.Prefix) + ":" + string(iri[len(namespace.IRI):])
+}
@@ -0,0 +1,94 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package writer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+)
+
+// Writers serialize statements. Nothing is guaranteed to reach the
+// underlying io.Writer before Close, which does not close it.
+type Writer interface {
+	Write(statement *model.Statement) error
+	Close() error
+}
+
+type Options struct {
+	// prefixes used to abbreviate IRIs in turtle and TriG
+	Namespaces []model.Namespace
+	// written as @base in turtle and TriG, IRIs are made relative to it
+	// when they have no prefix
+	Base model.IRI
+	// order the prefixes, graphs, subjects, predicates and objects instead
+	// of keeping the order of the statements
+	Sort bool
+	// comment lines with their leading #, written before the directives,
+	// after the statements and above the subjects in turtle and TriG
+	Header   []string
+	Footer   []string
+	Comments map[GraphSubject][]string
+}
+
+// a subject in a graph, the default graph is nil
+type GraphSubject struct {
+	Graph   model.RDFTerm
+	Subject model.RDFTerm
+}
+
+func NewWriter(w io.Writer, f format.Format, options Options) Writer {
+	switch f {
+	case format.NTriples:
+		return NewNTriplesWriter(w)
+	case format.NQuads:
+		return NewNQuadsWriter(w)
+	case format.TriG:
+		return NewTriGWriter(w, options)
+	}
+	return NewTurtleWriter(w, options)
+}
+
+// WriteAll writes statements and closes the writer
+func WriteAll(this Writer, statements []*model.Statement) error {
+	for _, statement := range statements {
+		if err := this.Write(statement); err != nil {
+			return err
+		}
+	}
+	return this.Close()
+}
+
+func checkStatement(statement *model.Statement) error {
+	switch statement.Subject.(type) {
+	case model.IRI, model.BlankNode:
+	default:
+		return fmt.Errorf("invalid subject %#v", statement.Subject)
+	}
+	if _, ok := statement.Predicate.(model.IRI); !ok {
+		return fmt.Errorf("invalid predicate %#v", statement.Predicate)
+	}
+	if err := checkObject(statement.Object); err != nil {
+		return err
+	}
+	switch statement.Context.(type) {
+	case nil, model.IRI, model.BlankNode:
+	default:
+		return fmt.Errorf("invalid graph name %#v", statement.Context)
+	}
+	return nil
+}
+
+func checkObject(object model.RDFTerm) error {
+	switch term := object.(type) {
+	case model.IRI, model.BlankNode, model.Literal:
+		return nil
+	case model.TripleTerm:
+		return checkStatement(&model.Statement{Subject: term.Subject, Predicate: term.Predicate, Object: term.Object})
+	}
+	return fmt.Errorf("invalid object %#v", object)
+}
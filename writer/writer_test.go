@@ -0,0 +1,169 @@
+/*
+* This work is released under CC BY-NC-SA 4.0
+* Copyright © 2025 Nicolas Edouard Martin Freundler
+ */
+package writer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nfreundl/rdf-tools/format"
+	"github.com/nfreundl/rdf-tools/model"
+	"github.com/nfreundl/rdf-tools/parser"
+)
+
+func write(t *testing.T, statements []*model.Statement, f format.Format, options Options) string {
+	t.Helper()
+	buffer := &bytes.Buffer{}
+	if err := WriteAll(NewWriter(buffer, f, options), statements); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return buffer.String()
+}
+
+func TestNTriples(t *testing.T) {
+	node := &model.LabelledBlankNode{Label: "x"}
+	statements := []*model.Statement{
+		{Subject: model.IRI("http://s"), Predicate: model.IRI("http://p"), Object: model.NewStringLiteral("a \"quoted\"\nline\u0001")},
+		{Subject: node, Predicate: model.IRI("http://p"), Object: model.NewLangLiteral("b", "en", "ltr"), Context: model.IRI("http://g")},
+		{Subject: &model.LabelledBlankNode{Label: "x"}, Predicate: model.IRI("http://p"), Object: model.TripleTerm{Subject: node, Predicate: model.IRI("http://q"), Object: model.NewTypedLiteral("1", model.XSDInteger)}},
+	}
+	expected := `<http://s> <http://p> "a \"quoted\"\nline\u0001" .
+_:x <http://p> "b"@en--ltr <http://g> .
+_:b0 <http://p> <<( _:x <http://q> "1"^^<http://www.w3.org/2001/XMLSchema#integer> )>> .
+`
+	if got := write(t, statements, format.NQuads, Options{}); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+	err := NewNTriplesWriter(&bytes.Buffer{}).Write(&model.Statement{Subject: model.NewStringLiteral("s"), Predicate: model.IRI("http://p"), Object: model.IRI("http://o")})
+	if err == nil {
+		t.Errorf("a literal subject was accepted")
+	}
+}
+
+func TestTurtle(t *testing.T) {
+	doc := `@prefix ex: <http://ex.org/> .
+
+ex:s a ex:C ;
+    ex:p "x"@en, ( 1 2 ), [
+        ex:q ex:r ;
+        ex:z 3.5
+    ] ;
+    ex:t [ ex:u true ], <http://other.org/x> .
+
+[ ex:p ex:o ] .
+
+_:loop ex:p _:loop .
+`
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := write(t, statements, format.Turtle, Options{Namespaces: []model.Namespace{{Prefix: "ex", IRI: "http://ex.org/"}}})
+	if got != doc {
+		t.Errorf("got\n%s\nexpected\n%s", got, doc)
+	}
+}
+
+func TestTurtleSubjects(t *testing.T) {
+	doc := `@prefix ex: <http://ex.org/> .
+@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+
+( 1 2 ) ex:p ex:o .
+
+[
+    ex:p ex:o ;
+    ex:q [ ex:r 1 ]
+] .
+
+( 3 ) ex:p ( 4 5 ) .
+
+[
+    rdf:first 6 ;
+    rdf:rest rdf:nil
+] .
+`
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commented model.RDFTerm
+	for _, statement := range statements {
+		if statement.Predicate == model.IRI("http://ex.org/q") {
+			commented = statement.Subject
+		}
+	}
+	options := Options{
+		Namespaces: []model.Namespace{{Prefix: "ex", IRI: "http://ex.org/"}, {Prefix: "rdf", IRI: model.RDF}},
+		Comments:   map[GraphSubject][]string{{Subject: commented}: {"# commented"}},
+	}
+	expected := strings.Replace(doc, "\n[\n", "\n# commented\n[\n", 1)
+	if got := write(t, statements, format.Turtle, options); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+func TestTriG(t *testing.T) {
+	doc := `<http://s> <http://p> <http://o> .
+
+<http://g> {
+    <http://s> <http://p> [ <http://q> <http://o> ] .
+}
+`
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{Format: format.TriG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := write(t, statements, format.TriG, Options{}); got != doc {
+		t.Errorf("got\n%s\nexpected\n%s", got, doc)
+	}
+}
+
+func TestTurtleSortAndComments(t *testing.T) {
+	doc := `<http://ex.org/dir/b> <http://ex.org/z> "2" ;
+	<http://ex.org/p> "multi\nline", "1" ;
+	a <http://ex.org/C> .
+<http://ex.org/dir/a> <http://ex.org/p> _:n .
+_:n <http://ex.org/p> <http://other.org/x#y> .
+`
+	statements, err := parser.ParseAll(strings.NewReader(doc), parser.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var node model.RDFTerm
+	for _, statement := range statements {
+		if model.IsBlankNode(statement.Subject) {
+			node = statement.Subject
+		}
+	}
+	options := Options{
+		Namespaces: []model.Namespace{{Prefix: "z", IRI: "http://other.org/"}, {Prefix: "ex", IRI: "http://ex.org/"}},
+		Base:       "http://ex.org/dir/doc",
+		Sort:       true,
+		Header:     []string{"# header"},
+		Footer:     []string{"# footer"},
+		Comments:   map[GraphSubject][]string{{Subject: node}: {"# about n"}},
+	}
+	expected := `# header
+@base <http://ex.org/dir/doc> .
+@prefix ex: <http://ex.org/> .
+@prefix z: <http://other.org/> .
+
+<a> ex:p _:n .
+
+<b> a ex:C ;
+    ex:p "1", """multi
+line""" ;
+    ex:z "2" .
+
+# about n
+_:n ex:p <http://other.org/x#y> .
+
+# footer
+`
+	if got := write(t, statements, format.Turtle, options); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}